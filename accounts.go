@@ -0,0 +1,285 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/auth"
+)
+
+// Name of the cookie carrying the login session token
+const authCookieName = "playground_auth"
+
+// Path of the SQLite file holding user accounts
+const credentialStorePath = "./users.sqlite"
+
+type CredentialsRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// loadCurrentUser resolves the login session cookie into the request context
+func loadCurrentUser(c *gin.Context) {
+	if token, err := c.Cookie(authCookieName); err == nil {
+		if user, err := auth.UserForToken(token); err == nil {
+			c.Set("user", user)
+		}
+	}
+	c.Next()
+}
+
+// currentUser returns the logged-in user, or nil for anonymous requests
+func currentUser(c *gin.Context) *auth.User {
+	if value, ok := c.Get("user"); ok {
+		if user, ok := value.(*auth.User); ok {
+			return user
+		}
+	}
+	return nil
+}
+
+// requireAdmin rejects requests that are not made by an admin user
+func requireAdmin(c *gin.Context) {
+	if !hasAdminClientCertificate(c.Request) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "A client certificate is required for the admin API",
+		})
+		return
+	}
+	user := currentUser(c)
+	if user == nil || !user.IsAdmin {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "Admin access required",
+		})
+		return
+	}
+	c.Next()
+}
+
+// requireUser rejects anonymous requests
+func requireUser(c *gin.Context) {
+	if currentUser(c) == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Login required",
+		})
+		return
+	}
+	c.Next()
+}
+
+// setAuthCookie stores the login session token in a cookie
+func setAuthCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(authCookieName, token, int(auth.SessionDuration.Seconds()), "/", "", secureCookies(), true)
+}
+
+// signup creates a new account and logs it in
+func signup(c *gin.Context) {
+	var req CredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	user, err := auth.Signup(req.Username, req.Password)
+	if err == auth.ErrUserExists {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	token, err := auth.StartSession(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start session: " + err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, user.Username, "signup", "", "")
+	setAuthCookie(c, token)
+	c.JSON(http.StatusCreated, user)
+}
+
+// login checks credentials and sets the session cookie
+func login(c *gin.Context) {
+	var req CredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	token, user, err := auth.Login(req.Username, req.Password)
+	if err == auth.ErrInvalidCredentials {
+		recordAudit(c, req.Username, "login_failed", "", "")
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Login failed: " + err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, user.Username, "login", "", "")
+	setAuthCookie(c, token)
+	c.JSON(http.StatusOK, user)
+}
+
+// logout ends the current login session
+func logout(c *gin.Context) {
+	if token, err := c.Cookie(authCookieName); err == nil {
+		if currentUser(c) != nil {
+			recordAudit(c, "", "logout", "", "")
+		}
+		auth.Logout(token)
+	}
+	c.SetCookie(authCookieName, "", -1, "/", "", secureCookies(), true)
+	c.JSON(http.StatusOK, gin.H{
+		"logged_out": true,
+	})
+}
+
+// me returns the logged-in user
+func me(c *gin.Context) {
+	user := currentUser(c)
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Not logged in",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// Name of the cookie holding the OAuth state between redirect and callback
+const oauthStateCookieName = "playground_oauth_state"
+
+// Path of the OAuth routes of the current API version, which providers
+// call back and the state cookie is scoped to
+const oauthPath = legacyAPIPrefix + "/" + currentAPIVersion + "/auth/oauth"
+
+// publicBaseURL returns the externally visible server URL used for OAuth callbacks
+func publicBaseURL() string {
+	if url := os.Getenv("PUBLIC_BASE_URL"); url != "" {
+		return url
+	}
+	scheme, addr, defaultPort := "http", serverTLS.httpAddr, "80"
+	if serverTLS.enabled {
+		scheme, addr, defaultPort = "https", serverTLS.httpsAddr, "443"
+	}
+	if _, port, _ := net.SplitHostPort(addr); port != defaultPort {
+		return scheme + "://localhost:" + port
+	}
+	return scheme + "://localhost"
+}
+
+// loginRequired reports whether the API is restricted to logged-in users
+func loginRequired() bool {
+	return os.Getenv("REQUIRE_LOGIN") == "true"
+}
+
+// requireLogin rejects anonymous requests to the API served under prefix
+// when REQUIRE_LOGIN is enabled. Its authentication endpoints stay
+// reachable so users can log in.
+func requireLogin(prefix string) gin.HandlerFunc {
+	authPrefix := prefix + "/auth/"
+	return func(c *gin.Context) {
+		if !loginRequired() || currentUser(c) != nil || strings.HasPrefix(c.Request.URL.Path, authPrefix) {
+			c.Next()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "Login required",
+		})
+	}
+}
+
+// listAuthProviders returns the configured external identity providers
+func listAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers":      auth.Providers(),
+		"login_required": loginRequired(),
+	})
+}
+
+// oauthLogin redirects to the identity provider login page
+func oauthLogin(c *gin.Context) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to generate login state",
+		})
+		return
+	}
+	state := hex.EncodeToString(b)
+
+	url, err := auth.AuthCodeURL(c.Param("provider"), state)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookieName, state, 600, oauthPath, "", secureCookies(), true)
+	c.Redirect(http.StatusFound, url)
+}
+
+// oauthCallback completes an identity provider login and sets the session cookie
+func oauthCallback(c *gin.Context) {
+	state, err := c.Cookie(oauthStateCookieName)
+	if err != nil || state == "" || state != c.Query("state") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid login state",
+		})
+		return
+	}
+	c.SetCookie(oauthStateCookieName, "", -1, oauthPath, "", secureCookies(), true)
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Login was rejected by the identity provider: " + providerError,
+		})
+		return
+	}
+
+	token, user, err := auth.CompleteLogin(c.Request.Context(), c.Param("provider"), c.Query("code"))
+	if err == auth.ErrUnknownProvider {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error": "Login failed: " + err.Error(),
+		})
+		return
+	}
+
+	recordAudit(c, user.Username, "login", c.Param("provider"), "")
+	setAuthCookie(c, token)
+	c.Redirect(http.StatusFound, "/")
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireLoginExemptsAuthOfEveryVersion(t *testing.T) {
+	t.Setenv("REQUIRE_LOGIN", "true")
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	registerAPIVersions(r)
+
+	for _, prefix := range []string{"/api", "/api/v1", "/api/v2"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, prefix+"/auth/login", strings.NewReader("{")))
+		if w.Code == http.StatusUnauthorized {
+			t.Errorf("expected %s/auth/login to be reachable without a login, got %d: %s", prefix, w.Code, w.Body)
+		}
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, prefix+"/init-progress", nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected %s/init-progress to require a login, got %d", prefix, w.Code)
+		}
+	}
+}
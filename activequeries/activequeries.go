@@ -0,0 +1,65 @@
+package activequeries
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Query is a statement this instance is executing
+type Query struct {
+	ID      int64  `json:"id"`
+	Dialect string `json:"dialect"`
+	// Statement with its string literals masked
+	SQL string `json:"sql"`
+	// Hash of the session, as in the slow query log, never its cookie
+	Session   string    `json:"session"`
+	StartedAt time.Time `json:"started_at"`
+	ElapsedMs float64   `json:"elapsed_ms"`
+}
+
+var (
+	// Queries in flight keyed by ID
+	running = make(map[int64]*Query)
+
+	// ID of the last query started
+	lastID int64
+
+	// Guards running and lastID
+	mu sync.Mutex
+)
+
+// Start records that a session started executing a statement and returns
+// the function to call once it finished. Callers pass the session's hash
+// and the masked statement, as the list is shown to admins.
+func Start(session string, dialect string, sql string) func() {
+	mu.Lock()
+	lastID++
+	id := lastID
+	running[id] = &Query{ID: id, Dialect: dialect, SQL: sql, Session: session, StartedAt: time.Now()}
+	mu.Unlock()
+
+	return func() {
+		mu.Lock()
+		delete(running, id)
+		mu.Unlock()
+	}
+}
+
+// List returns the queries in flight, longest running first
+func List() []Query {
+	now := time.Now()
+	mu.Lock()
+	queries := make([]Query, 0, len(running))
+	for _, query := range running {
+		q := *query
+		q.ElapsedMs = float64(now.Sub(q.StartedAt).Microseconds()) / 1000
+		queries = append(queries, q)
+	}
+	mu.Unlock()
+
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].ID < queries[j].ID
+	})
+	return queries
+}
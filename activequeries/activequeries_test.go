@@ -0,0 +1,29 @@
+package activequeries
+
+import (
+	"testing"
+)
+
+func TestStartAndList(t *testing.T) {
+	first := Start("session:a", "sqlite", "SELECT 1")
+	second := Start("session:b", "mysql", "SELECT SLEEP(1)")
+
+	queries := List()
+	if len(queries) != 2 || queries[0].SQL != "SELECT 1" || queries[1].Session != "session:b" {
+		t.Fatalf("expected both queries oldest first, got %+v", queries)
+	}
+	if queries[0].ElapsedMs < 0 || queries[0].StartedAt.IsZero() {
+		t.Errorf("expected a start time and elapsed time, got %+v", queries[0])
+	}
+
+	first()
+	if queries := List(); len(queries) != 1 || queries[0].Dialect != "mysql" {
+		t.Errorf("expected only the second query after the first finished, got %+v", queries)
+	}
+	second()
+	// Finishing twice is harmless
+	second()
+	if queries := List(); len(queries) != 0 {
+		t.Errorf("expected no queries, got %+v", queries)
+	}
+}
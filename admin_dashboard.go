@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/activequeries"
+)
+
+// getAdminDashboard serves the admin page showing the health of the
+// backends and the queries running on them, with switches for feature flags
+func getAdminDashboard(c *gin.Context) {
+	c.File("./static/admin.html")
+}
+
+// getActiveQueries lists the queries this instance is executing
+func getActiveQueries(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"queries": activequeries.List(),
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/openapi"
+)
+
+// Operations of the current API version, recorded as its routes are
+// registered
+var apiSpec = openapi.New()
+
+// route documents an endpoint in the OpenAPI document
+type route struct {
+	summary string
+	// Zero value of the JSON request body, nil without a body
+	request interface{}
+	// Query string parameters
+	query []string
+}
+
+// apiRoutes registers routes on a group and, with a spec, describes them
+// in it, so the document cannot drift from the routes actually served
+type apiRoutes struct {
+	group *gin.RouterGroup
+	spec  *openapi.Spec
+	// Tag of the routes registered next
+	tag string
+}
+
+func (r *apiRoutes) GET(path string, doc route, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodGet, path, doc, handlers)
+}
+
+func (r *apiRoutes) POST(path string, doc route, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodPost, path, doc, handlers)
+}
+
+func (r *apiRoutes) PUT(path string, doc route, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodPut, path, doc, handlers)
+}
+
+func (r *apiRoutes) DELETE(path string, doc route, handlers ...gin.HandlerFunc) {
+	r.handle(http.MethodDelete, path, doc, handlers)
+}
+
+// handle registers a route and records its operation
+func (r *apiRoutes) handle(method string, path string, doc route, handlers []gin.HandlerFunc) {
+	r.group.Handle(method, path, handlers...)
+	if r.spec == nil {
+		return
+	}
+	r.spec.Add(openapi.Operation{
+		Method:  method,
+		Path:    path,
+		Summary: doc.summary,
+		Tag:     r.tag,
+		Request: doc.request,
+		Query:   doc.query,
+		Admin:   hasHandler(handlers, requireAdmin),
+	})
+}
+
+// hasHandler reports whether a handler chain includes a given handler
+func hasHandler(handlers []gin.HandlerFunc, handler gin.HandlerFunc) bool {
+	target := reflect.ValueOf(handler).Pointer()
+	for _, h := range handlers {
+		if reflect.ValueOf(h).Pointer() == target {
+			return true
+		}
+	}
+	return false
+}
+
+// getOpenAPI serves the OpenAPI 3 document of the current API version
+func getOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, apiSpec.Document("SQL Playground API", currentAPIVersion, legacyAPIPrefix+"/"+currentAPIVersion))
+}
+
+// getAPIDocs serves a Swagger UI page exploring the OpenAPI document
+func getAPIDocs(c *gin.Context) {
+	c.File("./static/api-docs.html")
+}
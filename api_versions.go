@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/features"
+	"example/user/playground/openapi"
+	"example/user/playground/querybuilder"
+)
+
+// Version of the API contract served under /api/v1
+const currentAPIVersion = "v1"
+
+// Prefix of the unversioned routes, kept as aliases of v1
+const legacyAPIPrefix = "/api"
+
+// APIVersion describes a route prefix of the API and its lifecycle
+type APIVersion struct {
+	Version string `json:"version"`
+	Prefix  string `json:"prefix"`
+	// stable or deprecated
+	Status string `json:"status"`
+	// Version that replaces a deprecated one
+	Successor string `json:"successor,omitempty"`
+	// When a deprecated version stops being served, from API_LEGACY_SUNSET
+	Sunset *time.Time `json:"sunset,omitempty"`
+}
+
+// registerAPIVersions registers the API under /api/v1, with the response
+// envelope under /api/v2 and, unless API_LEGACY_ROUTES is false, the same
+// routes under /api as a deprecated alias of v1. Responses name the
+// version that served them in the API-Version header; the alias also sends
+// Deprecation, Link and, with API_LEGACY_SUNSET set, Sunset headers. A
+// breaking change to a payload goes into a new version while the earlier
+// ones keep their contract.
+func registerAPIVersions(r *gin.Engine) {
+	r.GET("/api/versions", listAPIVersions)
+	r.GET("/api/openapi.json", getOpenAPI)
+	r.GET("/api/docs", getAPIDocs)
+
+	v1Prefix := legacyAPIPrefix + "/" + currentAPIVersion
+	v1 := r.Group(v1Prefix, validatePayload, requireLogin(v1Prefix), apiVersion(currentAPIVersion))
+	registerAPIRoutes(v1, apiSpec)
+
+	// v2 serves the same routes with the response envelope and error
+	// statuses
+	v2Prefix := legacyAPIPrefix + "/" + envelopeAPIVersion
+	v2 := r.Group(v2Prefix, envelopeResponses, validatePayload, requireLogin(v2Prefix), apiVersion(envelopeAPIVersion))
+	registerAPIRoutes(v2, nil)
+
+	if os.Getenv("API_LEGACY_ROUTES") != "false" {
+		legacy := r.Group(legacyAPIPrefix, validatePayload, requireLogin(legacyAPIPrefix), deprecatedAPI(currentAPIVersion))
+		registerAPIRoutes(legacy, nil)
+	}
+}
+
+// apiVersion names the API version serving a request
+func apiVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("API-Version", version)
+		c.Next()
+	}
+}
+
+// deprecatedAPI marks responses of the unversioned routes as deprecated
+// (RFC 9745) and points to the same route of the successor version
+func deprecatedAPI(successor string) gin.HandlerFunc {
+	sunset := legacyAPISunset()
+	return func(c *gin.Context) {
+		successorPath := legacyAPIPrefix + "/" + successor + strings.TrimPrefix(c.Request.URL.Path, legacyAPIPrefix)
+		c.Header("API-Version", successor)
+		c.Header("Deprecation", "true")
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successorPath))
+		if sunset != nil {
+			c.Header("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
+
+// legacyAPISunset returns the date from API_LEGACY_SUNSET (YYYY-MM-DD or
+// RFC 3339) after which the unversioned routes may go away, if set
+func legacyAPISunset() *time.Time {
+	value := os.Getenv("API_LEGACY_SUNSET")
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if sunset, err := time.Parse(layout, value); err == nil {
+			return &sunset
+		}
+	}
+	fmt.Printf("Ignoring invalid API_LEGACY_SUNSET %q\n", value)
+	return nil
+}
+
+// listAPIVersions returns the API versions the server offers
+func listAPIVersions(c *gin.Context) {
+	versions := []APIVersion{{
+		Version: currentAPIVersion,
+		Prefix:  legacyAPIPrefix + "/" + currentAPIVersion,
+		Status:  "stable",
+	}, {
+		Version: envelopeAPIVersion,
+		Prefix:  legacyAPIPrefix + "/" + envelopeAPIVersion,
+		Status:  "stable",
+	}}
+	if os.Getenv("API_LEGACY_ROUTES") != "false" {
+		versions = append(versions, APIVersion{
+			Version:   "legacy",
+			Prefix:    legacyAPIPrefix,
+			Status:    "deprecated",
+			Successor: currentAPIVersion,
+			Sunset:    legacyAPISunset(),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"current":  currentAPIVersion,
+		"versions": versions,
+	})
+}
+
+// registerAPIRoutes registers the routes of the v1 contract on a group,
+// describing them in spec unless it is nil
+func registerAPIRoutes(api *gin.RouterGroup, spec *openapi.Spec) {
+	routes := &apiRoutes{group: api, spec: spec}
+
+	routes.tag = "Queries"
+	routes.POST("/validate-sql", route{summary: "Validate and execute a query", request: SQLValidationRequest{}}, limitQueryRate, validateAndExecuteSQL)
+	routes.GET("/query-jobs/:id", route{summary: "Get a query running in the background"}, getQueryJob)
+	routes.GET("/binary/:id", route{summary: "Download a binary value of a query result"}, downloadBinary)
+	routes.GET("/cell/:id", route{summary: "Get the whole value of a truncated result cell"}, getFullCell)
+	routes.GET("/result/:queryId/row/:n", route{summary: "Get one row of a retained or background query's result as a record"}, getResultRow)
+	resultRetention := requireFeature(features.ResultRetention)
+	routes.GET("/result/:queryId", route{summary: "Get a page of a retained query result", query: []string{"offset", "limit"}}, resultRetention, getRetainedResult)
+	routes.GET("/result/:queryId/export", route{summary: "Export a retained query result", query: []string{"format", "name", "table", "targetDialect", "batchSize"}}, resultRetention, exportRetainedResult)
+	routes.GET("/result/:queryId/diff/:otherId", route{summary: "Compare a retained query result with a later one", query: []string{"ignoreRowOrder", "ignoreColumnOrder"}}, resultRetention, diffRetainedResults)
+	routes.POST("/execute-multi", route{summary: "Execute a query on several dialects side by side", request: MultiExecutionRequest{}}, limitQueryRate, executeMulti)
+	routes.POST("/validate", route{summary: "Validate a query without executing it", request: SQLValidationRequest{}}, validateOnly)
+	routes.POST("/format", route{summary: "Format a query", request: FormatRequest{}}, formatSQL)
+	routes.POST("/tokenize", route{summary: "Split a query into typed tokens for syntax highlighting", request: TokenizeRequest{}}, tokenizeSQL)
+	routes.POST("/build-query", route{summary: "Generate a query from a structured specification", request: querybuilder.Spec{}}, buildQuery)
+	routes.POST("/explain-text", route{summary: "Explain in plain English what a query does", request: ExplainTextRequest{}}, explainText)
+	routes.POST("/lint", route{summary: "Review a query for warnings or constructs other dialects reject", request: LintRequest{}}, lintSQL)
+	routes.POST("/lineage", route{summary: "Trace the source columns of a query's output columns", request: LineageRequest{}}, traceLineage)
+	routes.POST("/nl2sql", route{summary: "Generate candidate queries from a natural language question", request: NLToSQLRequest{}}, requireFeature(features.NLToSQL), limitQueryRate, limitNL2SQLRate, generateSQL)
+
+	routes.tag = "Status"
+	routes.GET("/db-status", route{summary: "Get the connection status of every backend", query: []string{"check"}}, getDatabaseStatus)
+	routes.GET("/init-progress", route{summary: "Get the startup progress of every backend"}, getInitProgress)
+	routes.GET("/pool-stats", route{summary: "Get the connection pool statistics of every backend"}, getPoolStats)
+	routes.GET("/locks/:dialect", route{summary: "List lock waits and the sessions blocking them"}, requireAdmin, getLocks)
+	routes.GET("/events", route{summary: "Stream connection, schema and query events"}, streamEvents)
+
+	// SQLite snapshot and restore
+	routes.tag = "Snapshots"
+	routes.GET("/sqlite/snapshots", route{summary: "List SQLite snapshots"}, listSnapshots)
+	routes.POST("/sqlite/snapshots", route{summary: "Snapshot the SQLite playground", request: SnapshotRequest{}}, requireAdmin, createSnapshot)
+	routes.POST("/sqlite/snapshots/:name/restore", route{summary: "Restore a SQLite snapshot"}, requireAdmin, restoreSnapshot)
+	routes.DELETE("/sqlite/snapshots/:name", route{summary: "Delete a SQLite snapshot"}, requireAdmin, deleteSnapshot)
+
+	// Query sharing links
+	routes.tag = "Sharing"
+	routes.POST("/share", route{summary: "Share a query", request: ShareRequest{}}, limitQueryRate, createShare)
+	routes.GET("/share/:id", route{summary: "Get a shared query"}, getShare)
+
+	// Editor workspaces
+	routes.tag = "Workspaces"
+	routes.GET("/workspaces", route{summary: "List workspaces"}, listWorkspaces)
+	routes.POST("/workspaces", route{summary: "Create a workspace", request: WorkspaceRequest{}}, createWorkspace)
+	routes.GET("/workspaces/:id", route{summary: "Get a workspace"}, getWorkspace)
+	routes.PUT("/workspaces/:id", route{summary: "Update a workspace", request: WorkspaceRequest{}}, updateWorkspace)
+	routes.DELETE("/workspaces/:id", route{summary: "Delete a workspace"}, deleteWorkspace)
+
+	// User accounts
+	routes.tag = "Accounts"
+	routes.POST("/auth/signup", route{summary: "Create an account", request: CredentialsRequest{}}, signup)
+	routes.POST("/auth/login", route{summary: "Log in", request: CredentialsRequest{}}, login)
+	routes.POST("/auth/logout", route{summary: "Log out"}, logout)
+	routes.GET("/auth/me", route{summary: "Get the logged-in user"}, me)
+	routes.GET("/auth/providers", route{summary: "List external identity providers"}, listAuthProviders)
+	routes.GET("/auth/oauth/:provider/login", route{summary: "Start logging in with an identity provider"}, oauthLogin)
+	routes.GET("/auth/oauth/:provider/callback", route{summary: "Finish logging in with an identity provider", query: []string{"code", "state", "error"}}, oauthCallback)
+
+	// Slow query log
+	routes.tag = "Slow queries"
+	routes.GET("/slow-queries", route{summary: "List slow queries", query: []string{"dialect", "limit"}}, requireAdmin, getSlowQueries)
+	routes.PUT("/slow-queries/threshold", route{summary: "Set the slow query threshold", request: SlowQueryThresholdRequest{}}, requireAdmin, setSlowQueryThreshold)
+
+	// Safety policy
+	routes.tag = "Administration"
+	routes.GET("/admin/policy", route{summary: "Get the safety policy", query: []string{"dialect"}}, requireAdmin, getSafetyPolicy)
+
+	// Connection pool tuning
+	routes.PUT("/admin/pools/:backend", route{summary: "Tune the connection pool of a backend", request: PoolSettingsRequest{}}, requireAdmin, setPoolSettings)
+
+	// Connection testing, ahead of registering connections at runtime
+	routes.POST("/test-connection", route{summary: "Test a connection string", request: TestConnectionRequest{}}, requireAdmin, testConnection)
+
+	// End-to-end checks of every backend after a deployment or upgrade
+	routes.POST("/selftest", route{summary: "Run canned checks against every backend"}, requireAdmin, runSelfTest)
+
+	// Query history and saved snippets of the session
+	routes.tag = "History"
+	routes.GET("/history", route{summary: "List the session's recent queries", query: []string{"limit"}}, getHistory)
+	routes.DELETE("/history", route{summary: "Clear the session's query history"}, clearHistory)
+	routes.GET("/snippets", route{summary: "List saved snippets"}, listSnippets)
+	routes.POST("/snippets", route{summary: "Save a snippet", request: SnippetRequest{}}, saveSnippet)
+	routes.PUT("/snippets/:id", route{summary: "Update a snippet", request: SnippetRequest{}}, saveSnippet)
+	routes.DELETE("/snippets/:id", route{summary: "Delete a snippet"}, deleteSnippet)
+
+	// Audit trail of logins and admin changes
+	routes.tag = "Administration"
+	routes.GET("/admin/audit", route{summary: "List audit records", query: []string{"actor", "action", "since", "limit"}}, requireAdmin, getAuditLog)
+
+	// Encryption keys of stored credentials
+	routes.GET("/admin/secrets", route{summary: "Get the status of the credential encryption keys"}, requireAdmin, getSecretsStatus)
+	routes.POST("/admin/secrets/rotate", route{summary: "Re-encrypt stored credentials with the primary key"}, requireAdmin, rotateSecrets)
+
+	// Feature flags
+	routes.GET("/admin/features", route{summary: "List feature flags"}, requireAdmin, listFeatureFlags)
+	routes.PUT("/admin/features/:name", route{summary: "Override a feature flag", request: FeatureFlagRequest{}}, requireAdmin, setFeatureFlag)
+	routes.DELETE("/admin/features/:name", route{summary: "Remove the override of a feature flag"}, requireAdmin, resetFeatureFlag)
+
+	// Large dataset seeding
+	largeDataset := requireFeature(features.LargeDataset)
+	routes.GET("/admin/large-dataset", route{summary: "List large dataset seeding jobs"}, requireAdmin, largeDataset, getLargeDatasetJobs)
+	routes.POST("/admin/large-dataset", route{summary: "Start seeding a large dataset", request: LargeDatasetRequest{}}, requireAdmin, largeDataset, startLargeDataset)
+	routes.DELETE("/admin/large-dataset/:dialect", route{summary: "Cancel seeding a large dataset"}, requireAdmin, largeDataset, cancelLargeDataset)
+
+	// Sessions terminated by the long transaction reaper
+	routes.GET("/admin/reaper", route{summary: "List sessions terminated by the reaper"}, requireAdmin, getReaperReport)
+
+	// Queries in flight, for the admin dashboard
+	routes.GET("/admin/queries", route{summary: "List the queries this instance is executing"}, requireAdmin, getActiveQueries)
+	routes.GET("/admin/execution-queues", route{summary: "List the execution queues of the backends"}, requireAdmin, getExecutionQueues)
+
+	// Dropping cached schemas and results after out-of-band changes
+	routes.POST("/admin/flush-cache", route{summary: "Flush the schema, result and autocomplete caches", request: FlushCacheRequest{}}, requireAdmin, flushCache)
+
+	// Docker containers of the backends
+	containerControl := requireFeature(features.ContainerControl)
+	routes.GET("/admin/containers", route{summary: "Get the state of the backends' containers"}, requireAdmin, containerControl, listContainers)
+	routes.POST("/admin/containers/:backend/:action", route{summary: "Start, stop or restart a backend's container"}, requireAdmin, containerControl, controlContainer)
+
+	// Schema introspection, including views and their definitions
+	routes.tag = "Schema"
+	routes.GET("/schema", route{summary: "Get the tables, views and indexes of a dialect", query: []string{"dialect"}}, getSchema)
+
+	// Character sets and collations
+	routes.GET("/collations/:dialect", route{summary: "List the character sets and collations of a dialect"}, getCollations)
+
+	// Stored routines and views of the session
+	routes.GET("/routines", route{summary: "List the session's routines, views and tables"}, listRoutines)
+	routes.GET("/dependencies/:dialect", route{summary: "List the dependencies between views, routines and tables", query: []string{"object"}}, getDependencies)
+
+	// Query templates
+	routes.tag = "Learning"
+	routes.GET("/templates", route{summary: "List query templates", query: []string{"dialect", "table"}}, listTemplates)
+
+	// Lessons
+	routes.GET("/lessons", route{summary: "List lessons", query: []string{"dialect"}}, listLessons)
+	routes.GET("/lessons/:id", route{summary: "Get a lesson"}, getLesson)
+	routes.POST("/lessons/:id/exercises/:exercise/submit", route{summary: "Submit the answer to an exercise", request: LessonAnswerRequest{}}, submitLessonAnswer)
+	routes.POST("/check-answer", route{summary: "Compare a query's result with an expected one", request: CheckAnswerRequest{}}, checkAnswer)
+
+	// Database connections registered by users
+	routes.tag = "Connections"
+	userConnections := requireFeature(features.UserConnections)
+	routes.GET("/connections", route{summary: "List the user's connections"}, userConnections, requireUser, listUserConnections)
+	routes.POST("/connections", route{summary: "Register a connection", request: UserConnectionRequest{}}, userConnections, requireUser, createUserConnection)
+	routes.DELETE("/connections/:id", route{summary: "Remove a connection"}, userConnections, requireUser, deleteUserConnection)
+
+	// Query benchmarks
+	routes.tag = "Analysis"
+	routes.POST("/benchmark", route{summary: "Benchmark a query", request: BenchmarkRequest{}}, limitQueryRate, runBenchmark)
+
+	// Column profiles of a sampled table
+	routes.POST("/profile-table", route{summary: "Profile the columns of a sampled table", request: ProfileTableRequest{}}, limitQueryRate, profileTable)
+
+	// Query results saved as session tables
+	routes.tag = "Queries"
+	routes.POST("/materialize", route{summary: "Save a query's result as a session table", request: MaterializeRequest{}}, limitQueryRate, materializeResult)
+	routes.DELETE("/materialize/:dialect/:name", route{summary: "Drop a saved result"}, dropMaterializedResult)
+
+	// Function reference
+	routes.tag = "Reference"
+	routes.GET("/reference/:dialect/functions", route{summary: "List the functions of a dialect", query: []string{"q"}}, listFunctions)
+	routes.GET("/reference/:dialect/functions/:name", route{summary: "Get a function of a dialect"}, getFunction)
+
+	// Two-session concurrency lab, only while its feature flag is on
+	routes.tag = "Concurrency lab"
+	concurrencyLab := requireFeature(features.ConcurrencyLab)
+	routes.POST("/concurrency-lab", route{summary: "Open sessions A and B on a dialect", request: ConcurrencyLabRequest{}}, concurrencyLab, openConcurrencyLab)
+	routes.GET("/concurrency-lab", route{summary: "Get the lab and the outcome of its steps"}, concurrencyLab, getConcurrencyLab)
+	routes.POST("/concurrency-lab/steps", route{summary: "Run statements in the lab's sessions", request: ConcurrencyLabStepsRequest{}}, concurrencyLab, limitQueryRate, runConcurrencyLabSteps)
+	routes.DELETE("/concurrency-lab", route{summary: "Roll back and close the lab's sessions"}, concurrencyLab, closeConcurrencyLab)
+
+	// SQL injection lab, only while its feature flag is on
+	routes.tag = "Security lab"
+	securityLab := requireFeature(features.SecurityLab)
+	routes.POST("/security-lab/login", route{summary: "Log in through the vulnerable query", request: SecurityLabLoginRequest{}}, securityLab, securityLabLogin)
+	routes.POST("/security-lab/reset", route{summary: "Reset the lab database"}, securityLab, resetSecurityLab)
+}
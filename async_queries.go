@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/execpool"
+	"example/user/playground/features"
+	"example/user/playground/queryjobs"
+	"example/user/playground/sqlvalidator"
+)
+
+// Estimated rows above which a SELECT runs in the background by default
+const defaultAsyncRowThreshold = 100000
+
+// Default number of background queries running at once
+const defaultAsyncQueryWorkers = 2
+
+// Background queries that may wait for a worker
+const asyncQueryBacklog = 32
+
+// Error code of queries turned away because the background queue is full
+const codeQueueFull = "queue_full"
+
+var (
+	// Estimated rows above which a SELECT runs in the background
+	asyncRowThreshold int64 = defaultAsyncRowThreshold
+
+	// Queue of background queries, set by configureAsyncQueries
+	asyncQueries *queryjobs.Queue
+)
+
+// configureAsyncQueries reads ASYNC_ROW_THRESHOLD and ASYNC_QUERY_WORKERS
+// and starts the workers of background queries
+func configureAsyncQueries() {
+	if value := os.Getenv("ASYNC_ROW_THRESHOLD"); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+			asyncRowThreshold = n
+		} else {
+			fmt.Printf("Ignoring ASYNC_ROW_THRESHOLD: %q is not a positive number of rows\n", value)
+		}
+	}
+	workers := defaultAsyncQueryWorkers
+	if n, err := strconv.Atoi(os.Getenv("ASYNC_QUERY_WORKERS")); err == nil && n > 0 {
+		workers = n
+	}
+	asyncQueries = queryjobs.New(workers, asyncQueryBacklog, queryjobs.DefaultRetention)
+}
+
+// queueLargeQuery estimates the rows a MySQL or PostgreSQL SELECT returns
+// from the cached row counts of its tables and, above the threshold, queues
+// execute as a background job, which a client disconnecting does not
+// cancel, and returns the 202 response naming it. It returns a nil response
+// when the query should run synchronously.
+func queueLargeQuery(owner string, req SQLValidationRequest, statementSQL string, execute func(context.Context) gin.H) (int, gin.H) {
+	if asyncQueries == nil || req.Dialect != "mysql" && req.Dialect != "postgresql" ||
+		!features.Enabled(context.Background(), features.AsyncQueries) {
+		return 0, nil
+	}
+	counts, ok := dbmanager.CachedRowCounts(dbmanager.ConnectionKey(req.Dialect, req.Version))
+	if !ok {
+		return 0, nil
+	}
+	estimate, ok := sqlvalidator.EstimateRows(statementSQL, req.Dialect, counts)
+	if !ok || estimate <= asyncRowThreshold {
+		return 0, nil
+	}
+
+	backend := dbmanager.ConnectionKey(req.Dialect, req.Version)
+	job, err := asyncQueries.Submit(owner, req.Dialect, req.SQL, estimate, func() (int, interface{}) {
+		return runOnPool(context.Background(), backend, execpool.Background, execute)
+	})
+	if err == queryjobs.ErrQueueFull {
+		return http.StatusServiceUnavailable, gin.H{
+			"valid":         false,
+			"error":         err.Error(),
+			"errorCode":     codeQueueFull,
+			"estimatedRows": estimate,
+		}
+	}
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{
+			"valid": false,
+			"error": "Failed to queue the query: " + err.Error(),
+		}
+	}
+	return http.StatusAccepted, gin.H{
+		"valid":         true,
+		"jobId":         job.ID,
+		"state":         job.State,
+		"estimatedRows": estimate,
+		"url":           legacyAPIPrefix + "/" + currentAPIVersion + "/query-jobs/" + job.ID,
+	}
+}
+
+// getQueryJob returns the state of a background query of the session and,
+// once it has finished, the response it would have been answered with
+func getQueryJob(c *gin.Context) {
+	if asyncQueries == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": queryjobs.ErrNotFound.Error(),
+		})
+		return
+	}
+	job, err := asyncQueries.Get(sessionOwner(c), c.Param("id"))
+	if err == queryjobs.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load the query job: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
+
+	metastore "example/user/playground/store"
+)
+
+// Lifetime of a login session
+const SessionDuration = 7 * 24 * time.Hour
+
+// Minimum accepted password length
+const minPasswordLength = 8
+
+var (
+	// ErrInvalidCredentials is returned when a login does not match a user
+	ErrInvalidCredentials = errors.New("invalid username or password")
+
+	// ErrUserExists is returned when signing up with a taken username
+	ErrUserExists = errors.New("username is already taken")
+
+	// ErrInvalidSession is returned for unknown or expired session tokens
+	ErrInvalidSession = errors.New("session is invalid or expired")
+
+	// Allowed usernames: letters, digits, dots, dashes and underscores
+	usernameRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{3,32}$`)
+
+	// Credential store, kept apart from the playground databases users can modify
+	store *sql.DB
+
+	// Metadata store holding login sessions, which instances sharing it
+	// share as well
+	sessions metastore.Store
+
+	// Hash compared against for unknown usernames, made on first use
+	dummyHash     []byte
+	dummyHashOnce sync.Once
+)
+
+// User is an account in the local credential store
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	IsAdmin   bool      `json:"is_admin"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Init opens the credential store at path and creates the admin user on
+// first run. A generated admin password is written to ADMIN_PASSWORD_FILE,
+// by default admin-password.txt next to the store. Login sessions are kept
+// in the metadata store.
+func Init(path string, metadata metastore.Store) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		username TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		is_admin INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	store = db
+	sessions = metadata
+	if err := ensureIdentitiesTable(); err != nil {
+		return err
+	}
+	passwordFile := os.Getenv("ADMIN_PASSWORD_FILE")
+	if passwordFile == "" {
+		passwordFile = filepath.Join(filepath.Dir(path), "admin-password.txt")
+	}
+	return bootstrapAdmin(passwordFile)
+}
+
+// Signup creates a new regular user
+func Signup(username string, password string) (*User, error) {
+	return createUser(username, password, false)
+}
+
+// Login checks credentials and starts a new session, returning its token
+func Login(username string, password string) (string, *User, error) {
+	if store == nil {
+		return "", nil, errors.New("credential store is not initialized")
+	}
+
+	var user User
+	var hash string
+	err := store.QueryRow(
+		`SELECT id, username, password_hash, is_admin, created_at FROM users WHERE username = ?`,
+		username,
+	).Scan(&user.ID, &user.Username, &hash, &user.IsAdmin, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		// Compare against a hash anyway, so unknown usernames take as long
+		// to reject as wrong passwords
+		bcrypt.CompareHashAndPassword(unknownUserHash(), []byte(password))
+		return "", nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return "", nil, ErrInvalidCredentials
+	}
+
+	token, err := StartSession(user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &user, nil
+}
+
+// unknownUserHash returns a hash of a random password at the cost of real
+// ones, which logins for unknown usernames are compared against
+func unknownUserHash() []byte {
+	dummyHashOnce.Do(func() {
+		password, err := randomToken(12)
+		if err == nil {
+			dummyHash, err = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		}
+		if err != nil {
+			dummyHash = []byte{}
+		}
+	})
+	return dummyHash
+}
+
+// StartSession creates a session for a user and returns its token
+func StartSession(userID int64) (string, error) {
+	if sessions == nil {
+		return "", errors.New("session store is not initialized")
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = sessions.PutSession(context.Background(), &metastore.Session{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(SessionDuration),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Logout ends the session identified by token
+func Logout(token string) error {
+	if sessions == nil {
+		return nil
+	}
+	return sessions.DeleteSession(context.Background(), hashToken(token))
+}
+
+// UserForToken returns the user owning a valid session token
+func UserForToken(token string) (*User, error) {
+	if store == nil || sessions == nil || token == "" {
+		return nil, ErrInvalidSession
+	}
+
+	session, err := sessions.GetSession(context.Background(), hashToken(token))
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	if time.Now().After(session.ExpiresAt) {
+		Logout(token)
+		return nil, ErrInvalidSession
+	}
+
+	var user User
+	err = store.QueryRow(
+		`SELECT id, username, is_admin, created_at FROM users WHERE id = ?`,
+		session.UserID,
+	).Scan(&user.ID, &user.Username, &user.IsAdmin, &user.CreatedAt)
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	return &user, nil
+}
+
+// createUser validates and stores a user with a bcrypt-hashed password
+func createUser(username string, password string, isAdmin bool) (*User, error) {
+	if store == nil {
+		return nil, errors.New("credential store is not initialized")
+	}
+
+	username = strings.TrimSpace(username)
+	if !usernameRegex.MatchString(username) {
+		return nil, errors.New("username must be 3-32 letters, digits, dots, dashes or underscores")
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return insertUser(store, username, hash, isAdmin)
+}
+
+// execer runs statements on the credential store or in a transaction on it
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertUser stores a user with an already hashed password, returning
+// ErrUserExists when the username is taken
+func insertUser(db execer, username string, hash []byte, isAdmin bool) (*User, error) {
+	// The UNIQUE constraint on username settles concurrent signups
+	res, err := db.Exec(
+		`INSERT INTO users (username, password_hash, is_admin) VALUES (?, ?, ?)`,
+		username, string(hash), isAdmin,
+	)
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique {
+		return nil, ErrUserExists
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &User{ID: id, Username: username, IsAdmin: isAdmin, CreatedAt: time.Now()}, nil
+}
+
+// bootstrapAdmin creates the admin user when the store has no users yet.
+// The password comes from ADMIN_PASSWORD or is generated and written to
+// passwordFile, which only the server's user can read.
+func bootstrapAdmin(passwordFile string) error {
+	var count int
+	if err := store.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	password := os.Getenv("ADMIN_PASSWORD")
+	generated := password == ""
+	if generated {
+		var err error
+		if password, err = randomToken(12); err != nil {
+			return err
+		}
+	}
+
+	// The generated password is written before the admin exists, so a
+	// failed write never leaves an admin whose password nobody knows
+	if generated {
+		if err := writePasswordFile(passwordFile, password); err != nil {
+			return fmt.Errorf("failed to write the admin password to %s: %v", passwordFile, err)
+		}
+	}
+	if _, err := createUser("admin", password, true); err != nil {
+		if generated {
+			os.Remove(passwordFile)
+		}
+		return err
+	}
+
+	if generated {
+		fmt.Printf("Created admin user; its generated password is in %s\n", passwordFile)
+	} else {
+		fmt.Println("Created admin user from ADMIN_PASSWORD")
+	}
+	return nil
+}
+
+// writePasswordFile writes a password to a file readable by its owner only,
+// replacing any earlier file
+func writePasswordFile(path string, password string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(password + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// randomToken returns a hex-encoded random token of n bytes
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken hashes a session token so raw tokens are never stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
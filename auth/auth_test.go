@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	metastore "example/user/playground/store"
+)
+
+// initStores opens a fresh credential store and metadata store for a test
+// and returns the directory they are in
+func initStores(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	metadata, err := metastore.Open(metastore.BackendSQLite, filepath.Join(dir, "metadata.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ADMIN_PASSWORD", "")
+	t.Setenv("ADMIN_PASSWORD_FILE", "")
+	if err := Init(filepath.Join(dir, "users.sqlite"), metadata); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		store.Close()
+		metadata.Close()
+		store, sessions = nil, nil
+	})
+	return dir
+}
+
+func TestSignupAndLogin(t *testing.T) {
+	initStores(t)
+
+	user, err := Signup("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "alice" || user.IsAdmin {
+		t.Errorf("expected a regular user, got %+v", user)
+	}
+
+	token, loggedIn, err := Login("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loggedIn.ID != user.ID {
+		t.Errorf("expected to log in as the new user, got %+v", loggedIn)
+	}
+	if current, err := UserForToken(token); err != nil || current.Username != "alice" {
+		t.Errorf("expected the token to identify alice, got %+v, %v", current, err)
+	}
+
+	if err := Logout(token); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UserForToken(token); err != ErrInvalidSession {
+		t.Errorf("expected the session to end on logout, got %v", err)
+	}
+}
+
+func TestSignupRejectsTakenUsernames(t *testing.T) {
+	initStores(t)
+	if _, err := Signup("alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Signup("alice", "another password"); err != ErrUserExists {
+		t.Errorf("expected ErrUserExists, got %v", err)
+	}
+	if _, err := Signup("admin", "another password"); err != ErrUserExists {
+		t.Errorf("expected the bootstrap admin's name to be taken, got %v", err)
+	}
+}
+
+func TestSignupChecksCredentials(t *testing.T) {
+	initStores(t)
+	for _, test := range []struct {
+		username string
+		password string
+		want     string
+	}{
+		{"al", "correct horse", "username must be"},
+		{"alice smith", "correct horse", "username must be"},
+		{"alice", "short", "password must be at least"},
+	} {
+		if _, err := Signup(test.username, test.password); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Signup(%q, %q) = %v, want an error containing %q", test.username, test.password, err, test.want)
+		}
+	}
+}
+
+func TestLoginRejectsWrongPasswords(t *testing.T) {
+	initStores(t)
+	if _, err := Signup("alice", "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Login("alice", "battery staple"); err != ErrInvalidCredentials {
+		t.Errorf("expected a wrong password to be rejected, got %v", err)
+	}
+	if _, _, err := Login("bob", "correct horse"); err != ErrInvalidCredentials {
+		t.Errorf("expected an unknown user to be rejected the same way, got %v", err)
+	}
+}
+
+func TestExpiredSessionsAreInvalid(t *testing.T) {
+	initStores(t)
+	user, err := Signup("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := "expired-token"
+	err = sessions.PutSession(context.Background(), &metastore.Session{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UserForToken(token); err != ErrInvalidSession {
+		t.Errorf("expected an expired session to be rejected, got %v", err)
+	}
+	if _, err := sessions.GetSession(context.Background(), hashToken(token)); err != metastore.ErrNotFound {
+		t.Errorf("expected the expired session to be removed, got %v", err)
+	}
+}
+
+func TestBootstrapAdminPasswordFile(t *testing.T) {
+	dir := initStores(t)
+	path := filepath.Join(dir, "admin-password.txt")
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("expected the password file to be private, got %v", mode)
+	}
+	password, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, admin, err := Login("admin", strings.TrimSpace(string(password)))
+	if err != nil || !admin.IsAdmin {
+		t.Errorf("expected to log in as admin with the generated password, got %+v, %v", admin, err)
+	}
+}
+
+func TestBootstrapAdminWritesThePasswordFirst(t *testing.T) {
+	dir := initStores(t)
+	if _, err := store.Exec(`DELETE FROM users`); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in the file's place cannot be replaced by the file
+	path := filepath.Join(dir, "taken")
+	if err := os.MkdirAll(filepath.Join(path, "child"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := bootstrapAdmin(path); err == nil {
+		t.Fatal("expected writing the password file to fail")
+	}
+	var count int
+	if err := store.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&count); err != nil || count != 0 {
+		t.Errorf("expected no admin without its password file, got %d users, %v", count, err)
+	}
+}
+
+func TestLoginComparesUnknownUsersAgainstAHash(t *testing.T) {
+	initStores(t)
+	if _, _, err := Login("nobody", "password123"); err != ErrInvalidCredentials {
+		t.Errorf("expected invalid credentials, got %v", err)
+	}
+	if cost, err := bcrypt.Cost(unknownUserHash()); err != nil || cost != bcrypt.DefaultCost {
+		t.Errorf("expected the dummy hash to cost as much as real ones, got %d, %v", cost, err)
+	}
+}
@@ -0,0 +1,380 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+)
+
+// ErrUnknownProvider is returned for providers that are not configured
+var ErrUnknownProvider = errors.New("identity provider is not configured")
+
+// Characters not allowed in usernames derived from provider identities
+var usernameCleanupRegex = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Identity is the external account returned by an identity provider
+type Identity struct {
+	Provider string
+	Subject  string
+	Username string
+	Email    string
+}
+
+// provider is a configured external identity provider
+type provider struct {
+	name        string
+	config      *oauth2.Config
+	userInfoURL string
+	parse       func(body []byte) (*Identity, error)
+}
+
+// Configured identity providers keyed by name
+var providers = make(map[string]*provider)
+
+// LoadProviders configures identity providers from environment variables.
+// The callback URL of each provider is <oauthURL>/<name>/callback, where
+// oauthURL is the public URL of the OAuth routes.
+func LoadProviders(oauthURL string) error {
+	oauthURL = strings.TrimSuffix(oauthURL, "/")
+	callback := func(name string) string {
+		return oauthURL + "/" + name + "/callback"
+	}
+
+	if id := os.Getenv("GOOGLE_CLIENT_ID"); id != "" {
+		providers["google"] = &provider{
+			name: "google",
+			config: &oauth2.Config{
+				ClientID:     id,
+				ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  "https://accounts.google.com/o/oauth2/v2/auth",
+					TokenURL: "https://oauth2.googleapis.com/token",
+				},
+				RedirectURL: callback("google"),
+				Scopes:      []string{"openid", "email", "profile"},
+			},
+			userInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+			parse:       parseOIDCUserInfo("google"),
+		}
+	}
+
+	if id := os.Getenv("GITHUB_CLIENT_ID"); id != "" {
+		providers["github"] = &provider{
+			name: "github",
+			config: &oauth2.Config{
+				ClientID:     id,
+				ClientSecret: os.Getenv("GITHUB_CLIENT_SECRET"),
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  "https://github.com/login/oauth/authorize",
+					TokenURL: "https://github.com/login/oauth/access_token",
+				},
+				RedirectURL: callback("github"),
+				Scopes:      []string{"read:user", "user:email"},
+			},
+			userInfoURL: "https://api.github.com/user",
+			parse:       parseGitHubUser,
+		}
+	}
+
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		discovery, err := discoverOIDC(issuer)
+		if err != nil {
+			return fmt.Errorf("OIDC discovery failed: %v", err)
+		}
+		providers["oidc"] = &provider{
+			name: "oidc",
+			config: &oauth2.Config{
+				ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+				ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+				Endpoint: oauth2.Endpoint{
+					AuthURL:  discovery.AuthorizationEndpoint,
+					TokenURL: discovery.TokenEndpoint,
+				},
+				RedirectURL: callback("oidc"),
+				Scopes:      []string{"openid", "email", "profile"},
+			},
+			userInfoURL: discovery.UserInfoEndpoint,
+			parse:       parseOIDCUserInfo("oidc"),
+		}
+	}
+
+	return nil
+}
+
+// Providers returns the names of the configured identity providers
+func Providers() []string {
+	names := []string{}
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthCodeURL returns the provider login URL for the given state value
+func AuthCodeURL(name string, state string) (string, error) {
+	p, ok := providers[name]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+	return p.config.AuthCodeURL(state), nil
+}
+
+// CompleteLogin exchanges an authorization code, maps the provider identity
+// to a local user and starts a session for it
+func CompleteLogin(ctx context.Context, name string, code string) (string, *User, error) {
+	p, ok := providers[name]
+	if !ok {
+		return "", nil, ErrUnknownProvider
+	}
+
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return "", nil, fmt.Errorf("token exchange failed: %v", err)
+	}
+
+	identity, err := fetchIdentity(ctx, p, token)
+	if err != nil {
+		return "", nil, err
+	}
+
+	user, err := userForIdentity(identity)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sessionToken, err := StartSession(user.ID)
+	if err != nil {
+		return "", nil, err
+	}
+	return sessionToken, user, nil
+}
+
+// fetchIdentity loads the user profile from the provider
+func fetchIdentity(ctx context.Context, p *provider, token *oauth2.Token) (*Identity, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.userInfoURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.config.Client(ctx, token).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user info request failed with status %d", resp.StatusCode)
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	identity, err := p.parse(body)
+	if err != nil {
+		return nil, err
+	}
+	if identity.Subject == "" {
+		return nil, errors.New("identity provider did not return a subject")
+	}
+	return identity, nil
+}
+
+// userForIdentity returns the local user linked to an identity, creating
+// and linking a new user on first login
+func userForIdentity(identity *Identity) (*User, error) {
+	if store == nil {
+		return nil, errors.New("credential store is not initialized")
+	}
+
+	var user User
+	err := store.QueryRow(
+		`SELECT u.id, u.username, u.is_admin, u.created_at
+		FROM identities i JOIN users u ON u.id = i.user_id
+		WHERE i.provider = ? AND i.subject = ?`,
+		identity.Provider, identity.Subject,
+	).Scan(&user.ID, &user.Username, &user.IsAdmin, &user.CreatedAt)
+	if err == nil {
+		return &user, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// External users never log in with a password, so store a random one
+	password, err := randomToken(24)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user and its identity are stored together. A username taken in
+	// the meantime moves on to the next candidate, and an identity stored
+	// by a concurrent login is used instead.
+	base := usernameBase(identity)
+	for i := 1; ; i++ {
+		username := base
+		if i > 1 {
+			username = base + "-" + strconv.Itoa(i)
+		}
+		created, err := createIdentityUser(identity, username, hash)
+		if err == ErrUserExists {
+			continue
+		}
+		var sqliteErr sqlite3.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			return userForIdentity(identity)
+		}
+		return created, err
+	}
+}
+
+// createIdentityUser stores a user under username together with the
+// identity it logs in with, in one transaction
+func createIdentityUser(identity *Identity, username string, hash []byte) (*User, error) {
+	tx, err := store.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	created, err := insertUser(tx, username, hash, false)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(
+		`INSERT INTO identities (provider, subject, user_id, email) VALUES (?, ?, ?, ?)`,
+		identity.Provider, identity.Subject, created.ID, identity.Email,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ensureIdentitiesTable creates the provider identity mapping table
+func ensureIdentitiesTable() error {
+	_, err := store.Exec(`CREATE TABLE IF NOT EXISTS identities (
+		provider TEXT NOT NULL,
+		subject TEXT NOT NULL,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		email TEXT,
+		PRIMARY KEY (provider, subject)
+	)`)
+	return err
+}
+
+// usernameBase derives the local username an identity's user is given, or
+// numbered after when it is taken
+func usernameBase(identity *Identity) string {
+	base := identity.Username
+	if base == "" && identity.Email != "" {
+		base = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	base = usernameCleanupRegex.ReplaceAllString(base, "")
+	if len(base) < 3 {
+		base = identity.Provider + "-user"
+	}
+	if len(base) > 28 {
+		base = base[:28]
+	}
+	return base
+}
+
+// oidcDiscovery holds the endpoints of an OpenID Connect provider
+type oidcDiscovery struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// discoverOIDC loads the OpenID configuration document of an issuer
+func discoverOIDC(issuer string) (*oidcDiscovery, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("discovery request failed with status %d", resp.StatusCode)
+	}
+
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, err
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.UserInfoEndpoint == "" {
+		return nil, errors.New("discovery document is missing required endpoints")
+	}
+	return &discovery, nil
+}
+
+// parseOIDCUserInfo parses a standard OpenID Connect userinfo response
+func parseOIDCUserInfo(providerName string) func(body []byte) (*Identity, error) {
+	return func(body []byte) (*Identity, error) {
+		var info struct {
+			Subject           string `json:"sub"`
+			PreferredUsername string `json:"preferred_username"`
+			Email             string `json:"email"`
+		}
+		if err := json.Unmarshal(body, &info); err != nil {
+			return nil, err
+		}
+		return &Identity{
+			Provider: providerName,
+			Subject:  info.Subject,
+			Username: info.PreferredUsername,
+			Email:    info.Email,
+		}, nil
+	}
+}
+
+// parseGitHubUser parses the GitHub user API response
+func parseGitHubUser(body []byte) (*Identity, error) {
+	var info struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, err
+	}
+
+	subject := ""
+	if info.ID != 0 {
+		subject = strconv.FormatInt(info.ID, 10)
+	}
+	return &Identity{
+		Provider: "github",
+		Subject:  subject,
+		Username: info.Login,
+		Email:    info.Email,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentIdentitiesGetDistinctUsernames(t *testing.T) {
+	initStores(t)
+	if _, err := Signup("octocat", "password123"); err != nil {
+		t.Fatal(err)
+	}
+
+	users := make([]*User, 5)
+	errs := make([]error, len(users))
+	var wg sync.WaitGroup
+	for i := range users {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			identity := &Identity{Provider: "github", Subject: strconv.Itoa(i), Username: "octocat"}
+			users[i], errs[i] = userForIdentity(identity)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{"octocat": true}
+	for i, user := range users {
+		if errs[i] != nil {
+			t.Fatalf("expected identity %d to get a user, got %v", i, errs[i])
+		}
+		if seen[user.Username] {
+			t.Errorf("expected distinct usernames, got %q twice", user.Username)
+		}
+		seen[user.Username] = true
+	}
+}
+
+func TestConcurrentLoginsOfAnIdentityShareItsUser(t *testing.T) {
+	initStores(t)
+
+	users := make([]*User, 4)
+	errs := make([]error, len(users))
+	var wg sync.WaitGroup
+	for i := range users {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			users[i], errs[i] = userForIdentity(&Identity{Provider: "github", Subject: "42", Username: "hubot"})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, user := range users {
+		if errs[i] != nil {
+			t.Fatalf("expected login %d to get a user, got %v", i, errs[i])
+		}
+		if user.ID != users[0].ID {
+			t.Errorf("expected one user for the identity, got %d and %d", users[0].ID, user.ID)
+		}
+	}
+	var count int
+	if err := store.QueryRow(`SELECT COUNT(*) FROM users WHERE username LIKE 'hubot%'`).Scan(&count); err != nil || count != 1 {
+		t.Errorf("expected no orphaned users, got %d, %v", count, err)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/benchmark"
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/execpool"
+	"example/user/playground/routines"
+	"example/user/playground/sharedstate"
+	"example/user/playground/sqlvalidator"
+)
+
+// BenchmarkRequest runs a query, and optionally a second formulation of
+// it, several times and reports their latencies
+type BenchmarkRequest struct {
+	SQL        string `json:"sql" binding:"required"`
+	CompareSQL string `json:"compare_sql"`
+	Dialect    string `json:"dialect" binding:"required"`
+	Runs       int    `json:"runs"`
+	// Unmeasured runs before the measured ones; defaults to two
+	Warmup    *int `json:"warmup"`
+	TimeoutMs int  `json:"timeout_ms"`
+	BustCache bool `json:"bust_cache"`
+	// Tokens from requiresConfirmation responses that confirm sql and
+	// compare_sql
+	ConfirmationToken        string `json:"confirmation_token"`
+	CompareConfirmationToken string `json:"compare_confirmation_token"`
+}
+
+// runBenchmark times a read-only query over a number of runs inside a
+// transaction that is rolled back. Each session runs one benchmark at a
+// time.
+func runBenchmark(c *gin.Context) {
+	var req BenchmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	opts := benchmark.Options{
+		Runs:      req.Runs,
+		Warmup:    benchmark.DefaultWarmup,
+		Timeout:   time.Duration(req.TimeoutMs) * time.Millisecond,
+		BustCache: req.BustCache,
+	}
+	if req.Warmup != nil {
+		opts.Warmup = *req.Warmup
+	}
+	opts, err := opts.Normalize()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	owner := sessionOwner(c)
+	queries := []string{req.SQL}
+	tokens := []string{req.ConfirmationToken}
+	if req.CompareSQL != "" {
+		queries = append(queries, req.CompareSQL)
+		tokens = append(tokens, req.CompareConfirmationToken)
+	}
+	written := append([]string{}, queries...)
+	for i, query := range queries {
+		resolved, response, status := prepareBenchmarkQuery(query, req.Dialect, owner, tokens[i])
+		if response != nil {
+			c.JSON(status, response)
+			return
+		}
+		queries[i] = resolved
+	}
+
+	db, err := dbmanager.GetDatabaseConnection(req.Dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection error: " + err.Error(),
+		})
+		return
+	}
+
+	// The lock lives in shared state so a session runs one benchmark at a
+	// time across instances, and expires if this one dies mid-run
+	state := sharedstate.Current()
+	lockKey := "benchmark:" + owner
+	locked, err := state.SetNX(c.Request.Context(), lockKey, []byte("1"), benchmark.MaxTotal+time.Minute)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Shared state error: " + err.Error(),
+		})
+		return
+	}
+	if !locked {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "A benchmark is already running for this session",
+		})
+		return
+	}
+	defer state.Delete(context.Background(), lockKey)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), benchmark.MaxTotal)
+	defer cancel()
+
+	// Benchmarks queue behind interactive queries on the backend's workers
+	status := http.StatusOK
+	poolStatus, response := runOnPool(ctx, req.Dialect, execpool.Background, func(ctx context.Context) gin.H {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			status = http.StatusInternalServerError
+			return gin.H{
+				"error": "Failed to start transaction: " + err.Error(),
+			}
+		}
+		// Never persist anything a benchmarked statement may have changed
+		defer tx.Rollback()
+
+		reports := []*benchmark.Report{}
+		for i, query := range queries {
+			report, err := benchmark.Run(ctx, tx, query, opts)
+			if err != nil {
+				return rewrittenErrorResponse("Benchmark error: ", err, written[i], query)
+			}
+			reports = append(reports, report)
+		}
+
+		response := gin.H{
+			"valid":     true,
+			"benchmark": reports[0],
+		}
+		if len(reports) > 1 {
+			response["compare"] = reports[1]
+			if reports[1].MedianMs > 0 {
+				response["median_ratio"] = reports[0].MedianMs / reports[1].MedianMs
+			}
+		}
+		return response
+	})
+	if poolStatus != http.StatusOK {
+		status = poolStatus
+	}
+	c.JSON(status, response)
+}
+
+// prepareBenchmarkQuery checks that a query returns rows and passes the
+// safety rules, and resolves the session's views in it. On failure it
+// returns the response and status to send.
+func prepareBenchmarkQuery(query string, dialect string, owner string, confirmationToken string) (string, gin.H, int) {
+	if !isReadQuery(query, dialect) {
+		return "", gin.H{
+			"error": "Only queries that return rows can be benchmarked",
+		}, http.StatusBadRequest
+	}
+	return prepareReadQuery(query, dialect, owner, confirmationToken)
+}
+
+// prepareReadQuery checks a query against the safety rules, the validator
+// and the cached schema, and resolves the session's views in it. A query
+// that needs confirmation runs only with a token confirming it, as in
+// executeSQLRequest.
+func prepareReadQuery(query string, dialect string, owner string, confirmationToken string) (string, gin.H, int) {
+	safetyCheck := sqlvalidator.IsSafeDDLOperation(query, dialect)
+	if safetyCheck.RequiresConfirmation {
+		req := SQLValidationRequest{SQL: query, Dialect: dialect, ConfirmationToken: confirmationToken}
+		if status, response := confirmStatement(owner, req, safetyCheck); response != nil {
+			return "", response, status
+		}
+	} else if !safetyCheck.Safe {
+		return "", gin.H{
+			"valid":     false,
+			"error":     safetyCheck.Error,
+			"errorCode": dberrors.CodeBlockedStatement,
+			"rule":      safetyCheck.Rule,
+		}, http.StatusOK
+	}
+	if valid, err := sqlvalidator.Validate(query, dialect); !valid {
+		return "", validationErrorResponse(err, query, dialect), http.StatusOK
+	}
+
+	query, _ = routines.Resolve(query, dialect, owner)
+	if schema, ok := dbmanager.CachedSchema(dialect); ok {
+		if err := sqlvalidator.CheckReferences(query, dialect, schema); err != nil {
+			return "", referenceErrorResponse(err), http.StatusOK
+		}
+	}
+	return query, nil, http.StatusOK
+}
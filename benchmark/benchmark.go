@@ -0,0 +1,191 @@
+package benchmark
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Limits on a benchmark request
+const (
+	DefaultRuns    = 10
+	MaxRuns        = 50
+	DefaultWarmup  = 2
+	MaxWarmup      = 10
+	DefaultTimeout = 5 * time.Second
+	MaxTimeout     = 10 * time.Second
+	// Longest a whole benchmark, warmup included, may take
+	MaxTotal = 60 * time.Second
+	// Rows read per run before the rest of the result is skipped
+	MaxRows = 100000
+)
+
+// Queryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Options control how often and how long a query is run
+type Options struct {
+	Runs    int
+	Warmup  int
+	Timeout time.Duration
+	// Make every run's SQL text unique so caches keyed on it miss
+	BustCache bool
+}
+
+// Report holds the timings of the measured runs of a query
+type Report struct {
+	SQL         string    `json:"sql"`
+	Runs        int       `json:"runs"`
+	Warmup      int       `json:"warmup"`
+	BustCache   bool      `json:"bust_cache"`
+	MinMs       float64   `json:"min_ms"`
+	MedianMs    float64   `json:"median_ms"`
+	P95Ms       float64   `json:"p95_ms"`
+	MeanMs      float64   `json:"mean_ms"`
+	MaxMs       float64   `json:"max_ms"`
+	DurationsMs []float64 `json:"durations_ms"`
+	RowCounts   []int     `json:"row_counts"`
+	// Some run returned more than MaxRows rows; the rest were not read
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Normalize applies the defaults to an unset number of runs and timeout
+// and checks the limits
+func (o Options) Normalize() (Options, error) {
+	if o.Runs == 0 {
+		o.Runs = DefaultRuns
+	}
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	switch {
+	case o.Runs < 1 || o.Runs > MaxRuns:
+		return o, fmt.Errorf("runs must be between 1 and %d", MaxRuns)
+	case o.Warmup < 0 || o.Warmup > MaxWarmup:
+		return o, fmt.Errorf("warmup runs must be between 0 and %d", MaxWarmup)
+	case o.Timeout < 0 || o.Timeout > MaxTimeout:
+		return o, fmt.Errorf("the per-run timeout must be at most %s", MaxTimeout)
+	}
+	return o, nil
+}
+
+// Run executes a query for the warmup runs, whose timings are discarded,
+// and then for the measured runs. It stops at the first run that fails or
+// exceeds its timeout; ctx bounds the whole benchmark.
+func Run(ctx context.Context, q Queryer, query string, opts Options) (*Report, error) {
+	report := &Report{
+		SQL:         query,
+		Runs:        opts.Runs,
+		Warmup:      opts.Warmup,
+		BustCache:   opts.BustCache,
+		DurationsMs: []float64{},
+		RowCounts:   []int{},
+	}
+
+	for i := 0; i < opts.Warmup+opts.Runs; i++ {
+		sql := query
+		if opts.BustCache {
+			sql = CacheBusted(query, i)
+		}
+		duration, rows, truncated, err := timeRun(ctx, q, sql, opts.Timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("the benchmark did not finish within %s: %w", MaxTotal, ctx.Err())
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("run %d exceeded the %s timeout: %w", i+1, opts.Timeout, err)
+			}
+			return nil, err
+		}
+		if i < opts.Warmup {
+			continue
+		}
+		report.DurationsMs = append(report.DurationsMs, milliseconds(duration))
+		report.RowCounts = append(report.RowCounts, rows)
+		report.Truncated = report.Truncated || truncated
+	}
+
+	report.summarize()
+	return report, nil
+}
+
+// CacheBusted prefixes a query with a comment naming the run, so result and
+// plan caches keyed on the statement text treat every run as a new query
+func CacheBusted(query string, run int) string {
+	return fmt.Sprintf("/* benchmark run %d */ %s", run+1, query)
+}
+
+// timeRun executes a query once, reading every row up to MaxRows, and
+// returns how long it took and how many rows it read
+func timeRun(ctx context.Context, q Queryer, query string, timeout time.Duration) (time.Duration, int, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		if count == MaxRows {
+			return time.Since(start), count, true, nil
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, false, err
+	}
+	return time.Since(start), count, false, nil
+}
+
+// summarize derives the latency statistics from the measured durations
+func (r *Report) summarize() {
+	if len(r.DurationsMs) == 0 {
+		return
+	}
+	sorted := append([]float64{}, r.DurationsMs...)
+	sort.Float64s(sorted)
+
+	total := 0.0
+	for _, d := range sorted {
+		total += d
+	}
+	r.MinMs = sorted[0]
+	r.MaxMs = sorted[len(sorted)-1]
+	r.MedianMs = Percentile(sorted, 50)
+	r.P95Ms = Percentile(sorted, 95)
+	r.MeanMs = round(total / float64(len(sorted)))
+}
+
+// Percentile returns the p-th percentile of sorted values, interpolating
+// linearly between the closest ranks
+func Percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	fraction := rank - float64(lower)
+	return round(sorted[lower] + (sorted[upper]-sorted[lower])*fraction)
+}
+
+// milliseconds converts a duration to milliseconds with microsecond
+// precision
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// round rounds milliseconds to microsecond precision
+func round(ms float64) float64 {
+	return math.Round(ms*1000) / 1000
+}
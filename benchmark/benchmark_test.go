@@ -0,0 +1,103 @@
+package benchmark
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE numbers (n INTEGER);
+		INSERT INTO numbers VALUES (1), (2), (3), (4), (5)`); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{0, 1},
+		{50, 5.5},
+		{95, 9.55},
+		{100, 10},
+	}
+	for _, test := range tests {
+		if got := Percentile(sorted, test.p); got != test.want {
+			t.Errorf("Percentile(%v) = %v, want %v", test.p, got, test.want)
+		}
+	}
+	if got := Percentile([]float64{7}, 95); got != 7 {
+		t.Errorf("Percentile of a single value = %v, want 7", got)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	opts, err := Options{}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Runs != DefaultRuns || opts.Timeout != DefaultTimeout {
+		t.Errorf("expected defaults, got %+v", opts)
+	}
+
+	for _, opts := range []Options{
+		{Runs: MaxRuns + 1},
+		{Runs: -1},
+		{Warmup: MaxWarmup + 1},
+		{Warmup: -1},
+		{Timeout: MaxTimeout + time.Second},
+	} {
+		if _, err := opts.Normalize(); err == nil {
+			t.Errorf("expected %+v to be rejected", opts)
+		}
+	}
+}
+
+func TestRunReportsTimingsAndRows(t *testing.T) {
+	db := openDB(t)
+
+	report, err := Run(context.Background(), db, "SELECT n FROM numbers WHERE n > 2", Options{Runs: 5, Warmup: 2, Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.DurationsMs) != 5 || len(report.RowCounts) != 5 {
+		t.Fatalf("expected 5 measured runs, got %+v", report)
+	}
+	for _, rows := range report.RowCounts {
+		if rows != 3 {
+			t.Errorf("expected 3 rows per run, got %v", report.RowCounts)
+		}
+	}
+	if report.MinMs > report.MedianMs || report.MedianMs > report.P95Ms || report.P95Ms > report.MaxMs {
+		t.Errorf("expected min <= median <= p95 <= max, got %+v", report)
+	}
+}
+
+func TestRunStopsOnError(t *testing.T) {
+	db := openDB(t)
+
+	if _, err := Run(context.Background(), db, "SELECT missing FROM numbers", Options{Runs: 3, Timeout: time.Second}); err == nil {
+		t.Error("expected the failing query to be reported")
+	}
+}
+
+func TestCacheBusted(t *testing.T) {
+	first := CacheBusted("SELECT 1", 0)
+	second := CacheBusted("SELECT 1", 1)
+	if first == second || !strings.HasSuffix(first, "SELECT 1") {
+		t.Errorf("expected distinct statements ending in the query, got %q and %q", first, second)
+	}
+}
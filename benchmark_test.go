@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"example/user/playground/sqlvalidator"
+)
+
+func TestReadQueryNeedingConfirmationRequiresToken(t *testing.T) {
+	policy, err := sqlvalidator.ParsePolicy([]byte(`
+rules:
+  - name: salaries
+    pattern: 'from\s+salaries'
+    message: Salaries are confidential
+    severity: confirm
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlvalidator.SetPolicy(policy)
+	defer sqlvalidator.SetPolicy(sqlvalidator.DefaultPolicy())
+
+	query := "SELECT * FROM salaries"
+	_, response, _ := prepareBenchmarkQuery(query, "sqlite", "session:test", "")
+	if response == nil || response["requiresConfirmation"] != true {
+		t.Fatalf("expected the query to need confirmation, got %v", response)
+	}
+
+	token := response["confirmationToken"].(string)
+	if _, response, _ := prepareBenchmarkQuery(query, "sqlite", "session:other", token); response == nil {
+		t.Error("expected another session's token not to confirm the query")
+	}
+
+	_, response, _ = prepareBenchmarkQuery(query, "sqlite", "session:test", "")
+	token = response["confirmationToken"].(string)
+	if resolved, response, _ := prepareBenchmarkQuery(query, "sqlite", "session:test", token); response != nil || resolved != query {
+		t.Errorf("expected the token to confirm the query, got %q %v", resolved, response)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/blobstore"
+	"example/user/playground/resultvalues"
+)
+
+// Binary values of query results, set by configureBinaryDownloads
+var binaryValues *blobstore.Store
+
+// configureBinaryDownloads reads BINARY_DOWNLOAD_MAX_BYTES, the largest
+// BLOB or BYTEA value of a result kept for download
+func configureBinaryDownloads() {
+	maxBytes := blobstore.DefaultMaxBytes
+	if value := os.Getenv("BINARY_DOWNLOAD_MAX_BYTES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			maxBytes = n
+		} else {
+			fmt.Printf("Ignoring BINARY_DOWNLOAD_MAX_BYTES: %q is not a positive number of bytes\n", value)
+		}
+	}
+	binaryValues = blobstore.New(blobstore.DefaultRetention, maxBytes)
+}
+
+// keepBinaryValues keeps the binary values of results for download and
+// sets the URL they can be downloaded from. Values too large to keep are
+// answered with their preview only.
+func keepBinaryValues(owner string, results []*QueryResult) {
+	if binaryValues == nil {
+		return
+	}
+	for _, result := range results {
+		for _, row := range result.Rows {
+			for _, value := range row {
+				binary, ok := value.(*resultvalues.Binary)
+				if !ok {
+					continue
+				}
+				id, err := binaryValues.Save(owner, binary.Data)
+				if err != nil {
+					if err != blobstore.ErrTooLarge {
+						fmt.Printf("Failed to keep a binary value: %v\n", err)
+					}
+					continue
+				}
+				binary.URL = legacyAPIPrefix + "/" + currentAPIVersion + "/binary/" + id
+			}
+		}
+	}
+}
+
+// downloadBinary sends a binary value of a result of the session whole,
+// with the content type sniffed from its first bytes
+func downloadBinary(c *gin.Context) {
+	data, err := binaryValues.Get(sessionOwner(c), c.Param("id"))
+	if err == blobstore.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the binary value: " + err.Error()})
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	filename := c.Param("id")
+	if extensions, _ := mime.ExtensionsByType(contentType); len(extensions) > 0 {
+		filename += extensions[0]
+	} else {
+		filename += ".bin"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, contentType, data)
+}
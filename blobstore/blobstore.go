@@ -0,0 +1,74 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"example/user/playground/sharedstate"
+)
+
+// How long a value stays downloadable after the query that returned it
+const DefaultRetention = time.Hour
+
+// Largest value kept for download by default
+const DefaultMaxBytes = 16 << 20
+
+// ErrNotFound is returned when a value does not exist, has expired or
+// belongs to someone else
+var ErrNotFound = errors.New("binary value not found or expired")
+
+// ErrTooLarge is returned when a value is larger than the store keeps
+var ErrTooLarge = errors.New("binary value is too large to keep")
+
+// Store keeps the binary values of query results in shared state, so any
+// instance can serve their downloads, for a limited time and up to a size
+// per value
+type Store struct {
+	retention time.Duration
+	maxBytes  int
+}
+
+// New returns a store keeping values for retention, skipping values larger
+// than maxBytes
+func New(retention time.Duration, maxBytes int) *Store {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Store{retention: retention, maxBytes: maxBytes}
+}
+
+// blobKey returns the shared state key of a value. The owner is part of
+// the key so one session cannot read another's values.
+func blobKey(owner string, id string) string {
+	return "blob:" + owner + ":" + id
+}
+
+// Save keeps a value of an owner and returns its ID. IDs are derived from
+// the content, so the same value returned twice, such as by two runs of a
+// query, keeps one copy under one ID.
+func (s *Store) Save(owner string, data []byte) (string, error) {
+	if len(data) > s.maxBytes {
+		return "", ErrTooLarge
+	}
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:12])
+	if err := sharedstate.Current().Set(context.Background(), blobKey(owner, id), data, s.retention); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Get returns a value of an owner
+func (s *Store) Get(owner string, id string) ([]byte, error) {
+	data, err := sharedstate.Current().Get(context.Background(), blobKey(owner, id))
+	if err == sharedstate.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
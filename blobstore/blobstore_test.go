@@ -0,0 +1,34 @@
+package blobstore
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSaveAndGet(t *testing.T) {
+	s := New(time.Minute, 0)
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff}
+	id, err := s.Save("session:a", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := s.Save("session:a", append([]byte(nil), data...)); again != id {
+		t.Errorf("expected the same value to keep its ID, got %q and %q", id, again)
+	}
+
+	got, err := s.Get("session:a", id)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get() = %v, %v", got, err)
+	}
+	if _, err := s.Get("session:b", id); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for another owner, got %v", err)
+	}
+}
+
+func TestSaveRejectsLargeValues(t *testing.T) {
+	s := New(time.Minute, 4)
+	if _, err := s.Save("session:a", []byte("12345")); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+}
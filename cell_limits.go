@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/blobstore"
+	"example/user/playground/resultvalues"
+)
+
+// Largest cell value kept so it can be fetched whole
+const maxFullCellBytes = 64 << 20
+
+// Largest text or JSON cell a response carries whole, set by
+// configureCellLimits
+var maxCellBytes = resultvalues.DefaultMaxCellBytes
+
+// Whole values of truncated cells, set by configureCellLimits
+var fullCells *blobstore.Store
+
+// configureCellLimits reads MAX_CELL_BYTES, the largest text or JSON value
+// a result cell carries before it is truncated
+func configureCellLimits() {
+	if value := os.Getenv("MAX_CELL_BYTES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			maxCellBytes = n
+		} else {
+			fmt.Printf("Ignoring MAX_CELL_BYTES: %q is not a positive number of bytes\n", value)
+		}
+	}
+	fullCells = blobstore.New(blobstore.DefaultRetention, maxFullCellBytes)
+}
+
+// truncateLargeCells replaces text and JSON cells larger than
+// MAX_CELL_BYTES with truncation markers, keeping their whole values so
+// they can be fetched one at a time
+func truncateLargeCells(owner string, results []*QueryResult) {
+	for _, result := range results {
+		for _, row := range result.Rows {
+			for i, value := range row {
+				marker := resultvalues.Truncate(value, maxCellBytes)
+				if marker == nil {
+					continue
+				}
+				row[i] = marker
+				if fullCells == nil {
+					continue
+				}
+				id, err := fullCells.Save(owner, []byte(marker.Text))
+				if err != nil {
+					if err != blobstore.ErrTooLarge {
+						fmt.Printf("Failed to keep a cell value: %v\n", err)
+					}
+					continue
+				}
+				marker.URL = legacyAPIPrefix + "/" + currentAPIVersion + "/cell/" + id
+			}
+		}
+	}
+}
+
+// getFullCell sends the whole value of a truncated cell of a result of the
+// session as text
+func getFullCell(c *gin.Context) {
+	data, err := fullCells.Get(sessionOwner(c), c.Param("id"))
+	if err == blobstore.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "cell value not found or expired"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the cell value: " + err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", data)
+}
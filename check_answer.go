@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/resultcompare"
+	"example/user/playground/sqlvalidator"
+)
+
+// Most rows compared per query by the answer checker
+const checkAnswerMaxRows = 1000
+
+// CheckAnswerRequest compares the result of a query with a reference query
+type CheckAnswerRequest struct {
+	SQL          string                `json:"sql" binding:"required"`
+	ReferenceSQL string                `json:"reference_sql" binding:"required"`
+	Dialect      string                `json:"dialect" binding:"required"`
+	Options      resultcompare.Options `json:"options"`
+}
+
+// checkAnswer runs a query and a reference query in a transaction that is
+// rolled back and reports how closely their results match
+func checkAnswer(c *gin.Context) {
+	var req CheckAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	for _, query := range []string{req.SQL, req.ReferenceSQL} {
+		if !isReadQuery(query, req.Dialect) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Both queries must return rows",
+			})
+			return
+		}
+		safetyCheck := sqlvalidator.IsSafeDDLOperation(query, req.Dialect)
+		if !safetyCheck.Safe && !safetyCheck.RequiresConfirmation {
+			c.JSON(http.StatusOK, gin.H{
+				"valid":     false,
+				"error":     safetyCheck.Error,
+				"errorCode": dberrors.CodeBlockedStatement,
+			})
+			return
+		}
+		if valid, err := sqlvalidator.Validate(query, req.Dialect); !valid {
+			c.JSON(http.StatusOK, validationErrorResponse(err, query, req.Dialect))
+			return
+		}
+	}
+
+	db, err := dbmanager.GetDatabaseConnection(req.Dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection error: " + err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to start transaction: " + err.Error(),
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	expected, err := resultcompare.Query(ctx, tx, req.ReferenceSQL, checkAnswerMaxRows)
+	if err != nil {
+		c.JSON(http.StatusOK, queryErrorResponse("Reference query error: ", err, req.ReferenceSQL))
+		return
+	}
+	actual, err := resultcompare.Query(ctx, tx, req.SQL, checkAnswerMaxRows)
+	if err != nil {
+		c.JSON(http.StatusOK, queryErrorResponse("Query execution error: ", err, req.SQL))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":  true,
+		"report": resultcompare.Compare(expected, actual, req.Options),
+	})
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prefix of the API version the client speaks
+const apiPrefix = "/api/v1"
+
+// Retries and the delay before the first one when no options are given
+const (
+	defaultRetries    = 3
+	defaultRetryDelay = 500 * time.Millisecond
+)
+
+// Longest wait between two attempts, whatever Retry-After asks for
+const maxRetryDelay = 30 * time.Second
+
+// Client calls the playground's v1 API. It keeps the session and login
+// cookies of the server, so history, snippets and session tables carry over
+// between calls. A Client is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	retries    int
+	retryDelay time.Duration
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient makes the client send requests through h. A cookie jar is
+// added when h has none.
+func WithHTTPClient(h *http.Client) Option {
+	return func(c *Client) {
+		c.http = h
+	}
+}
+
+// WithRetries sets how often a request is retried and the delay before the
+// first retry, which doubles on every further one. Zero retries disables
+// retrying.
+func WithRetries(retries int, delay time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryDelay = delay
+	}
+}
+
+// New returns a client of the server at baseURL, such as
+// "http://localhost:8080"
+func New(baseURL string, options ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		http:       &http.Client{},
+		retries:    defaultRetries,
+		retryDelay: defaultRetryDelay,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.http.Jar == nil {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			return nil, err
+		}
+		c.http.Jar = jar
+	}
+	return c, nil
+}
+
+// APIError is returned when the server answers with an error status
+type APIError struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+	Code    string `json:"errorCode"`
+}
+
+func (e *APIError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s (%s, status %d)", e.Message, e.Code, e.Status)
+	}
+	return fmt.Sprintf("%s (status %d)", e.Message, e.Status)
+}
+
+// Login logs in with a local account. Later calls run as that user.
+func (c *Client) Login(ctx context.Context, username string, password string) error {
+	body := map[string]string{"username": username, "password": password}
+	return c.do(ctx, http.MethodPost, "/auth/login", nil, body, nil)
+}
+
+// Logout ends the login of the client
+func (c *Client) Logout(ctx context.Context) error {
+	return c.do(ctx, http.MethodPost, "/auth/logout", nil, nil, nil)
+}
+
+// do sends a JSON request to an API path and decodes the JSON response
+// into out, unless out is nil
+func (c *Client) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send sends a request, retrying it while the server is rate limiting or
+// unavailable. Requests that may have reached a handler are only retried
+// when they are GETs, so a query is never executed twice. The caller must
+// close the body of the returned response, which has a 2xx status.
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, body interface{}, accept string) (*http.Response, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, err
+		}
+	}
+	target := c.baseURL + apiPrefix + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		req.Header.Set("Accept", accept)
+
+		resp, err := c.http.Do(req)
+		wait, retry := delay, false
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			retry = method == http.MethodGet
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return resp, nil
+		default:
+			apiErr := readAPIError(resp)
+			err = apiErr
+			retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable ||
+				(method == http.MethodGet && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout))
+			if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil && seconds >= 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+		}
+		if !retry || attempt >= c.retries {
+			return nil, err
+		}
+
+		if wait > maxRetryDelay {
+			wait = maxRetryDelay
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// readAPIError reads the error body of a response and closes it
+func readAPIError(resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	apiErr := &APIError{Status: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if json.Unmarshal(data, apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+	}
+	return apiErr
+}
+
+// IsRateLimited reports whether err is the server refusing a request over
+// the rate limit
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusTooManyRequests
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	c, err := New(server.URL, WithRetries(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestExecute(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/validate-sql" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req ExecuteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.SQL == "SELECT broken" {
+			fmt.Fprint(w, `{"valid":false,"error":"no such column: broken","errorCode":"missing_column","errorPosition":{"line":1,"column":8}}`)
+			return
+		}
+		fmt.Fprint(w, `{"valid":true,"result":{"columns":["n"],"rows":[[1]]}}`)
+	})
+
+	resp, err := c.Execute(context.Background(), ExecuteRequest{SQL: "SELECT 1 AS n", Dialect: "sqlite"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Valid || resp.Result == nil || resp.Result.Columns[0] != "n" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	resp, err = c.Execute(context.Background(), ExecuteRequest{SQL: "SELECT broken", Dialect: "sqlite"})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) || queryErr.Code != "missing_column" {
+		t.Fatalf("expected a missing_column query error, got %v", err)
+	}
+	if resp == nil || resp.Position == nil || resp.Position.Column != 8 {
+		t.Errorf("expected the response alongside the error, got %+v", resp)
+	}
+}
+
+func TestRetriesRateLimitedRequests(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":"Too many queries","errorCode":"rate_limited"}`)
+			return
+		}
+		fmt.Fprint(w, `{"valid":true}`)
+	})
+
+	if _, err := c.Execute(context.Background(), ExecuteRequest{SQL: "SELECT 1", Dialect: "sqlite"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected one retry, got %d calls", calls)
+	}
+}
+
+func TestDoesNotRetryFailedPosts(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, err := c.Execute(context.Background(), ExecuteRequest{SQL: "DELETE FROM t", Dialect: "sqlite"})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway {
+		t.Fatalf("expected a 502 API error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a statement that may have run not to be resent, got %d calls", calls)
+	}
+
+	_, err = c.GetSchema(context.Background(), "sqlite")
+	if !errors.As(err, &apiErr) || calls != 4 {
+		t.Errorf("expected GETs to be retried twice, got %d calls and %v", calls, err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/history" || r.URL.Query().Get("limit") != "5" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"history":[{"id":3,"dialect":"mysql","sql":"SELECT 1","duration_ms":1.5,"executed_at":"2024-01-02T03:04:05Z"}]}`)
+	})
+
+	entries, err := c.History(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].ID != 3 || entries[0].Dialect != "mysql" {
+		t.Errorf("unexpected history %+v", entries)
+	}
+}
+
+func TestExecuteRows(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/x-ndjson" {
+			t.Errorf("expected NDJSON to be requested, got %q", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		fmt.Fprint(w, "{\"id\":1,\"name\":\"a\"}\n{\"id\":2,\"name\":\"b\"}\n")
+	})
+
+	var names []interface{}
+	err := c.ExecuteRows(context.Background(), ExecuteRequest{SQL: "SELECT id, name FROM t", Dialect: "sqlite"}, func(row map[string]interface{}) error {
+		names = append(names, row["name"])
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[1] != "b" {
+		t.Errorf("unexpected rows %v", names)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event:query_done\ndata:{\"type\":\"query_done\",\"time\":\"2024-01-02T03:04:05Z\",\"data\":{\"dialect\":\"sqlite\"}}\n\n")
+	})
+
+	var received []Event
+	err := c.Events(context.Background(), func(e Event) error {
+		received = append(received, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 1 || received[0].Type != "query_done" {
+		t.Errorf("unexpected events %+v", received)
+	}
+}
+
+func TestNewRejectsRelativeURLs(t *testing.T) {
+	if _, err := New("localhost:8080/api"); err == nil {
+		t.Error("expected a base URL without a scheme to be rejected")
+	}
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExecuteRequest is the body of Execute and Validate
+type ExecuteRequest struct {
+	SQL     string `json:"sql"`
+	Dialect string `json:"dialect"`
+	// Pinned server version, such as "16" for PostgreSQL 16
+	Version string `json:"version,omitempty"`
+	// Registered connection to run against instead of the bundled database
+	ConnectionID string `json:"connectionId,omitempty"`
+	// Token from a response that required confirmation, confirming the
+	// statement it was issued for
+	ConfirmationToken string `json:"confirmationToken,omitempty"`
+	// Validate and plan the statement without changing any data
+	DryRun bool `json:"dryRun,omitempty"`
+	// Return JSON columns as their text instead of parsed values
+	RawJSON bool `json:"rawJson,omitempty"`
+	// Summarize each column of the returned rows
+	ComputeStats bool `json:"computeStats,omitempty"`
+}
+
+// Result is the outcome of one result-producing statement
+type Result struct {
+	Columns []string        `json:"columns"`
+	Rows    [][]interface{} `json:"rows"`
+	// Indexes of the columns holding JSON documents
+	JSONColumns []int           `json:"jsonColumns,omitempty"`
+	Stats       json.RawMessage `json:"stats,omitempty"`
+}
+
+// Rewrite is a change the server made to a statement before running it
+type Rewrite struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Position is a one-based location in the SQL text
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Response is the body returned by Execute and Validate
+type Response struct {
+	Valid        bool                   `json:"valid"`
+	Result       *Result                `json:"result,omitempty"`
+	ResultSets   []Result               `json:"resultSets,omitempty"`
+	OutParams    map[string]interface{} `json:"outParams,omitempty"`
+	Warnings     []string               `json:"warnings,omitempty"`
+	ExecutedSQL  string                 `json:"executedSql,omitempty"`
+	Rewrites     []Rewrite              `json:"rewrites,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	ErrorCode    string                 `json:"errorCode,omitempty"`
+	Position     *Position              `json:"errorPosition,omitempty"`
+	ErrorToken   string                 `json:"errorToken,omitempty"`
+	Suggestion   string                 `json:"suggestion,omitempty"`
+	Explanation  string                 `json:"explanation,omitempty"`
+	ValidateOnly bool                   `json:"validateOnly,omitempty"`
+	// Whether tables and columns were checked against the schema
+	SchemaChecked bool `json:"schemaChecked,omitempty"`
+	// Set when the statement only runs when resent with ConfirmationToken
+	RequiresConfirmation bool   `json:"requiresConfirmation,omitempty"`
+	ConfirmationToken    string `json:"confirmationToken,omitempty"`
+	Rule                 string `json:"rule,omitempty"`
+}
+
+// QueryError is returned when the server rejected or failed to run a query.
+// The response is still returned alongside it for its position, suggestion
+// and explanation.
+type QueryError struct {
+	Message string
+	Code    string
+}
+
+func (e *QueryError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("%s (%s)", e.Message, e.Code)
+	}
+	return e.Message
+}
+
+// Execute runs SQL and returns its results. A failing query returns the
+// response together with a *QueryError.
+func (c *Client) Execute(ctx context.Context, req ExecuteRequest) (*Response, error) {
+	return c.query(ctx, "/validate-sql", req)
+}
+
+// Validate checks SQL against the dialect and its schema without running it
+func (c *Client) Validate(ctx context.Context, req ExecuteRequest) (*Response, error) {
+	return c.query(ctx, "/validate", req)
+}
+
+func (c *Client) query(ctx context.Context, path string, req ExecuteRequest) (*Response, error) {
+	var resp Response
+	if err := c.do(ctx, http.MethodPost, path, nil, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return &resp, &QueryError{Message: resp.Error, Code: resp.ErrorCode}
+	}
+	return &resp, nil
+}
+
+// ExecuteRows runs SQL and decodes the rows of its result one at a time,
+// keyed by column name, without holding the whole result in memory.
+// Returning an error from fn stops reading and is returned.
+func (c *Client) ExecuteRows(ctx context.Context, req ExecuteRequest, fn func(row map[string]interface{}) error) error {
+	resp, err := c.send(ctx, http.MethodPost, "/validate-sql", nil, req, "application/x-ndjson")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Failures before the first row still come back as a JSON response
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-ndjson") {
+		var result Response
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return err
+		}
+		if result.Error != "" {
+			return &QueryError{Message: result.Error, Code: result.ErrorCode}
+		}
+		return nil
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	for decoder.More() {
+		row := map[string]interface{}{}
+		if err := decoder.Decode(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// View in the schema
+type View struct {
+	Name         string `json:"name"`
+	Materialized bool   `json:"materialized"`
+	Definition   string `json:"definition"`
+	// Whether the current session created it
+	Owned bool `json:"owned"`
+}
+
+// Schema is the tables and views of a dialect's database
+type Schema struct {
+	Dialect string `json:"dialect"`
+	// Column names by table
+	Tables map[string][]string `json:"tables"`
+	Views  []View              `json:"views"`
+}
+
+// GetSchema returns the tables and columns of a dialect
+func (c *Client) GetSchema(ctx context.Context, dialect string) (*Schema, error) {
+	var schema Schema
+	if err := c.do(ctx, http.MethodGet, "/schema", url.Values{"dialect": {dialect}}, nil, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// HistoryEntry is a query run by the current user or session
+type HistoryEntry struct {
+	ID         int64     `json:"id"`
+	Dialect    string    `json:"dialect"`
+	SQL        string    `json:"sql"`
+	DurationMs float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+// History returns the most recent queries, newest first. A limit of zero
+// uses the server's default.
+func (c *Client) History(ctx context.Context, limit int) ([]HistoryEntry, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var resp struct {
+		History []HistoryEntry `json:"history"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/history", query, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.History, nil
+}
+
+// Event is a server-sent event such as a query starting or finishing
+type Event struct {
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Events streams server events to fn until ctx is cancelled, the server
+// closes the stream or fn returns an error
+func (c *Client) Events(ctx context.Context, fn func(Event) error) error {
+	resp, err := c.send(ctx, http.MethodGet, "/events", nil, nil, "text/event-stream")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var data bytes.Buffer
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		case line == "" && data.Len() > 0:
+			// A blank line ends the event
+			var event Event
+			if err := json.Unmarshal(data.Bytes(), &event); err != nil {
+				return err
+			}
+			data.Reset()
+			if err := fn(event); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/sqlvalidator"
+)
+
+// getCollations lists the collations a dialect's database offers, with
+// their character sets, and the database's default character set and
+// collation
+func getCollations(c *gin.Context) {
+	dialect := c.Param("dialect")
+	collations, err := dbmanager.ListCollations(c.Request.Context(), dialect)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list collations: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dialect":    dialect,
+		"default":    collations.Default,
+		"collations": collations.Collations,
+	})
+}
+
+// collationWarnings warns about comparisons between columns of different
+// collations in a query against a dialect's bundled database
+func collationWarnings(sql string, dialect string) []string {
+	columns, ok := dbmanager.CachedColumnCollations(dialect)
+	if !ok {
+		return nil
+	}
+	collations := make(sqlvalidator.Collations, len(columns))
+	for table, byColumn := range columns {
+		collations[table] = make(map[string]string, len(byColumn))
+		for column, collation := range byColumn {
+			collations[table][column] = collation.Collation
+		}
+	}
+	return sqlvalidator.CollationWarnings(sql, dialect, collations)
+}
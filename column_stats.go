@@ -0,0 +1,13 @@
+package main
+
+import "example/user/playground/resultstats"
+
+// addColumnStats summarizes the columns of every result set from the rows
+// it returned
+func addColumnStats(results []*QueryResult) {
+	for _, result := range results {
+		if len(result.Columns) > 0 {
+			result.Stats = resultstats.Compute(result.Columns, result.Rows)
+		}
+	}
+}
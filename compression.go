@@ -0,0 +1,138 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/klauspost/compress/zstd"
+
+	"example/user/playground/negotiate"
+)
+
+// Response encodings in order of preference
+var responseEncodings = []string{"zstd", "gzip"}
+
+// Content types that are already compressed or must not be buffered
+var uncompressedTypes = []string{"text/event-stream", "image/", "font/woff", "application/zip", "application/gzip"}
+
+var (
+	// Reusable gzip writers
+	gzipWriters = sync.Pool{New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}}
+
+	// Reusable zstd writers
+	zstdWriters = sync.Pool{New: func() interface{} {
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// compressResponses encodes responses with zstd or gzip, whichever the
+// client's Accept-Encoding prefers. The choice is made when the body starts,
+// so event streams, already compressed files and partial content are sent
+// as they are.
+func compressResponses(c *gin.Context) {
+	encoding := negotiate.Choose(c.GetHeader("Accept-Encoding"), responseEncodings...)
+	if c.GetHeader("Accept-Encoding") == "" || encoding == "" || c.Request.Method == http.MethodHead {
+		c.Next()
+		return
+	}
+
+	c.Writer.Header().Add("Vary", "Accept-Encoding")
+	writer := &compressWriter{ResponseWriter: c.Writer, encoding: encoding}
+	c.Writer = writer
+	defer writer.close()
+	c.Next()
+}
+
+// compressWriter compresses the body written through it once it knows the
+// response is worth compressing
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+
+	// Whether the compression decision has been made
+	decided bool
+	// Encoder of the body, nil when it is sent uncompressed
+	encoder io.WriteCloser
+}
+
+// start decides whether to compress, adjusting the headers before they are
+// sent
+func (w *compressWriter) start() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	header := w.Header()
+	if w.Written() || header.Get("Content-Encoding") != "" || w.Status() == http.StatusPartialContent || w.Status() < 200 ||
+		w.Status() == http.StatusNoContent || w.Status() == http.StatusNotModified {
+		return
+	}
+	contentType := header.Get("Content-Type")
+	for _, skip := range uncompressedTypes {
+		if strings.HasPrefix(contentType, skip) {
+			return
+		}
+	}
+
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	header.Del("Accept-Ranges")
+	switch w.encoding {
+	case "zstd":
+		encoder := zstdWriters.Get().(*zstd.Encoder)
+		encoder.Reset(w.ResponseWriter)
+		w.encoder = encoder
+	default:
+		encoder := gzipWriters.Get().(*gzip.Writer)
+		encoder.Reset(w.ResponseWriter)
+		w.encoder = encoder
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.start()
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	return w.encoder.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends what has been compressed so far, for streamed responses
+func (w *compressWriter) Flush() {
+	switch encoder := w.encoder.(type) {
+	case *gzip.Writer:
+		encoder.Flush()
+	case *zstd.Encoder:
+		encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close finishes the compressed stream and returns the encoder to its pool
+func (w *compressWriter) close() {
+	switch encoder := w.encoder.(type) {
+	case *gzip.Writer:
+		encoder.Close()
+		encoder.Reset(io.Discard)
+		gzipWriters.Put(encoder)
+	case *zstd.Encoder:
+		encoder.Close()
+		encoder.Reset(nil)
+		zstdWriters.Put(encoder)
+	}
+	w.encoder = nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/concurrencylab"
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/routines"
+	"example/user/playground/sqlsplit"
+	"example/user/playground/sqlvalidator"
+)
+
+// Concurrency labs open at once on an instance by default
+const defaultConcurrencyLabs = 4
+
+// Time a step gets to finish before the next one runs, by default and at most
+const (
+	defaultStepWait = time.Second
+	maxStepWait     = 10 * time.Second
+)
+
+// Labs of this instance, set by configureConcurrencyLab
+var concurrencyLabs *concurrencylab.Manager
+
+// ConcurrencyLabRequest opens a concurrency lab on a dialect
+type ConcurrencyLabRequest struct {
+	Dialect string `json:"dialect" binding:"required"`
+}
+
+// LabStepRequest is a statement, or a savepoint operation the server writes
+// in the dialect's syntax, to run in session A or B
+type LabStepRequest struct {
+	Session   string            `json:"session" binding:"required"`
+	SQL       string            `json:"sql"`
+	Savepoint *SavepointRequest `json:"savepoint"`
+}
+
+// SavepointRequest sets, rolls back to or releases a savepoint of the
+// session's transaction
+type SavepointRequest struct {
+	// "savepoint", "rollback_to" or "release"
+	Action string `json:"action" binding:"required"`
+	Name   string `json:"name" binding:"required"`
+}
+
+// ConcurrencyLabStepsRequest runs statements in the sessions of a lab, in
+// the order given
+type ConcurrencyLabStepsRequest struct {
+	Steps []LabStepRequest `json:"steps" binding:"required,min=1,max=32,dive"`
+	// Time each step gets to finish before the next runs; a step still
+	// running then is reported as blocked. 1000 by default.
+	WaitMs int `json:"waitMs"`
+}
+
+// configureConcurrencyLab reads CONCURRENCY_LAB_MAX, the number of labs an
+// instance holds open at once, and CONCURRENCY_LAB_IDLE_TIMEOUT, after which
+// an unused lab's transactions are rolled back and its connections closed
+func configureConcurrencyLab() {
+	maxLabs := defaultConcurrencyLabs
+	if value := os.Getenv("CONCURRENCY_LAB_MAX"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			maxLabs = n
+		} else {
+			fmt.Printf("Ignoring CONCURRENCY_LAB_MAX: %q is not a positive number of labs\n", value)
+		}
+	}
+	idleTimeout := concurrencylab.DefaultIdleTimeout
+	if value := os.Getenv("CONCURRENCY_LAB_IDLE_TIMEOUT"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			idleTimeout = d
+		} else {
+			fmt.Printf("Ignoring CONCURRENCY_LAB_IDLE_TIMEOUT: %q is not a positive duration such as 10m\n", value)
+		}
+	}
+
+	// Each lab gets a pool of its own for its two sessions, so a lab left
+	// waiting on a lock never takes connections from user queries
+	concurrencyLabs = concurrencylab.New(func(dialect string) (*sql.DB, error) {
+		return dbmanager.OpenDedicated(dialect, 2)
+	}, maxLabs, idleTimeout)
+}
+
+// openConcurrencyLab opens a lab with sessions A and B for the session,
+// replacing the lab it had open
+func openConcurrencyLab(c *gin.Context) {
+	var req ConcurrencyLabRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if req.Dialect != "mysql" && req.Dialect != "postgresql" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "The concurrency lab runs on MySQL and PostgreSQL; SQLite serializes all writers",
+		})
+		return
+	}
+
+	lab, err := concurrencyLabs.Open(sessionOwner(c), req.Dialect)
+	switch {
+	case err == concurrencylab.ErrTooManyLabs:
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":     err.Error(),
+			"errorCode": codeQueueFull,
+		})
+	case err != nil:
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":     "Failed to open the lab sessions: " + err.Error(),
+			"errorCode": dberrors.CodeConnectionError,
+		})
+	default:
+		c.JSON(http.StatusCreated, lab)
+	}
+}
+
+// getConcurrencyLab returns the session's lab and the outcome of every step
+// it ran, including steps that were blocked when submitted
+func getConcurrencyLab(c *gin.Context) {
+	lab, err := concurrencyLabs.Get(sessionOwner(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, lab)
+}
+
+// runConcurrencyLabSteps checks every step like an editor query, then runs
+// them in the sessions of the lab
+func runConcurrencyLabSteps(c *gin.Context) {
+	var req ConcurrencyLabStepsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	wait := defaultStepWait
+	if req.WaitMs != 0 {
+		if req.WaitMs < 0 || time.Duration(req.WaitMs)*time.Millisecond > maxStepWait {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request: waitMs must be between 1 and %d", maxStepWait.Milliseconds()),
+			})
+			return
+		}
+		wait = time.Duration(req.WaitMs) * time.Millisecond
+	}
+
+	owner := sessionOwner(c)
+	lab, err := concurrencyLabs.Get(owner)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	steps := make([]concurrencylab.StepRequest, len(req.Steps))
+	for i, step := range req.Steps {
+		statement, response := labStepSQL(step, lab.Dialect)
+		if response == nil {
+			statement, response = prepareLabStep(statement, lab.Dialect, owner)
+		}
+		if response != nil {
+			response["step"] = i
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+		steps[i] = concurrencylab.StepRequest{Session: step.Session, SQL: statement}
+	}
+
+	lab, err = concurrencyLabs.Run(owner, steps, wait)
+	switch {
+	case err == concurrencylab.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, lab)
+	}
+}
+
+// closeConcurrencyLab rolls back the transactions of the session's lab and
+// closes its connections
+func closeConcurrencyLab(c *gin.Context) {
+	if err := concurrencyLabs.Close(sessionOwner(c)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"closed": true})
+}
+
+// labStepSQL returns the statement of a step, writing its savepoint
+// operation in the dialect's syntax
+func labStepSQL(step LabStepRequest, dialect string) (string, gin.H) {
+	if (step.SQL == "") == (step.Savepoint == nil) {
+		return "", gin.H{
+			"error":     "Each step needs either sql or a savepoint operation",
+			"errorCode": dberrors.CodeValidationError,
+		}
+	}
+	if step.Savepoint == nil {
+		return step.SQL, nil
+	}
+	statement, err := sqlvalidator.SavepointStatement(step.Savepoint.Action, step.Savepoint.Name, dialect)
+	if err != nil {
+		return "", gin.H{
+			"error":     "Invalid savepoint: " + err.Error(),
+			"errorCode": dberrors.CodeValidationError,
+		}
+	}
+	return statement, nil
+}
+
+// prepareLabStep checks a single statement against the safety rules and
+// the validator, and resolves the session's views and saved results in it.
+// Views and routines are created in the editor, where they are scoped to
+// the session, rather than in the lab.
+func prepareLabStep(statement string, dialect string, owner string) (string, gin.H) {
+	if len(sqlsplit.Split(statement, dialect)) > 1 {
+		return "", gin.H{
+			"error":     "Each step must be a single statement",
+			"errorCode": dberrors.CodeValidationError,
+		}
+	}
+	safetyCheck := sqlvalidator.IsSafeDDLOperation(statement, dialect)
+	if !safetyCheck.Safe {
+		return "", gin.H{
+			"error":     safetyCheck.Error,
+			"errorCode": dberrors.CodeBlockedStatement,
+			"rule":      safetyCheck.Rule,
+		}
+	}
+	if valid, err := sqlvalidator.Validate(statement, dialect); !valid {
+		return "", validationErrorResponse(err, statement, dialect)
+	}
+	if routine, _ := sqlvalidator.ParseRoutineStatement(statement, dialect); routine != nil {
+		return "", gin.H{
+			"error":     "Create views and routines in the editor; the lab can use them by name",
+			"errorCode": dberrors.CodeBlockedStatement,
+		}
+	}
+	statement, _ = routines.Resolve(statement, dialect, owner)
+	return statement, nil
+}
@@ -0,0 +1,606 @@
+package concurrencylab
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"example/user/playground/resultcompare"
+	"example/user/playground/secrets"
+	"example/user/playground/sqlvalidator"
+)
+
+// Sessions of every lab
+const (
+	SessionA = "A"
+	SessionB = "B"
+)
+
+// States of a step
+const (
+	Queued    = "queued"
+	Running   = "running"
+	Blocked   = "blocked"
+	Completed = "completed"
+	Failed    = "failed"
+)
+
+// Labs closed after this long without being used by default
+const DefaultIdleTimeout = 10 * time.Minute
+
+// Longest a single step may run, waiting for locks included
+const StepTimeout = time.Minute
+
+// Most rows read from a step's result
+const MaxRows = 100
+
+// Steps a session may have waiting behind the one it runs
+const maxQueuedSteps = 16
+
+// Steps kept in a lab's history; older finished steps are dropped
+const maxHistory = 200
+
+// Time allowed for opening and preparing both sessions
+const openTimeout = 10 * time.Second
+
+// How often idle labs are looked for
+const reapInterval = 30 * time.Second
+
+// ErrNotFound is returned when the owner has no open lab
+var ErrNotFound = errors.New("no concurrency lab is open, open one first")
+
+// ErrTooManyLabs is returned when the lab limit of the instance is reached
+var ErrTooManyLabs = errors.New("too many concurrency labs are open, try again later")
+
+// ErrUnknownSession is returned for a step naming a session other than A or B
+var ErrUnknownSession = errors.New("steps must run in session A or B")
+
+// ErrSessionBusy is returned when a session has too many steps waiting
+var ErrSessionBusy = errors.New("too many steps are waiting in this session, wait for them to finish")
+
+// Statements each session runs when it opens, so a step waiting for a lock
+// fails on its own before StepTimeout cancels it
+var sessionSetup = map[string][]string{
+	"mysql": {
+		"SET SESSION innodb_lock_wait_timeout = 30",
+	},
+	"postgresql": {
+		"SET lock_timeout = '30s'",
+		"SET statement_timeout = '50s'",
+	},
+}
+
+// Opener opens a dedicated connection pool for a dialect's sessions
+type Opener func(dialect string) (*sql.DB, error)
+
+// StepRequest is a statement to run in one of a lab's sessions
+type StepRequest struct {
+	Session string
+	SQL     string
+}
+
+// Step is a statement run in a session and its outcome
+type Step struct {
+	Index   int    `json:"index"`
+	Session string `json:"session"`
+	SQL     string `json:"sql"`
+	State   string `json:"state"`
+	// Result of statements returning rows, up to MaxRows
+	Columns []string        `json:"columns,omitempty"`
+	Rows    [][]interface{} `json:"rows,omitempty"`
+	// Rows changed by statements that return none
+	RowsAffected *int64     `json:"rowsAffected,omitempty"`
+	Error        string     `json:"error,omitempty"`
+	SubmittedAt  time.Time  `json:"submittedAt"`
+	StartedAt    *time.Time `json:"startedAt,omitempty"`
+	FinishedAt   *time.Time `json:"finishedAt,omitempty"`
+	DurationMs   float64    `json:"durationMs"`
+
+	done chan struct{}
+}
+
+// SessionState is the transaction of a session as its steps left it
+type SessionState struct {
+	Name          string `json:"name"`
+	InTransaction bool   `json:"inTransaction"`
+	// Savepoints of the open transaction, oldest first
+	Savepoints []string `json:"savepoints"`
+	// Whether a failed statement left the PostgreSQL transaction refusing
+	// every statement until it is rolled back, or rolled back to a savepoint
+	Aborted bool `json:"aborted,omitempty"`
+}
+
+// Snapshot describes an open lab and the steps it ran
+type Snapshot struct {
+	Dialect   string         `json:"dialect"`
+	Sessions  []SessionState `json:"sessions"`
+	OpenedAt  time.Time      `json:"openedAt"`
+	ExpiresAt time.Time      `json:"expiresAt"`
+	Steps     []Step         `json:"steps"`
+}
+
+// session is a connection of a lab and the steps waiting to run on it
+type session struct {
+	conn *sql.Conn
+	work chan *Step
+
+	// Transaction tracked from the steps that ran, guarded by the lab's mu
+	state SessionState
+	// Whether a SQLite transaction was started by SAVEPOINT, so releasing
+	// its first savepoint commits it
+	savepointStarted bool
+}
+
+// lab is a pair of sessions with their own transactions on a dedicated pool
+type lab struct {
+	dialect  string
+	db       *sql.DB
+	sessions map[string]*session
+	ctx      context.Context
+	cancel   context.CancelFunc
+	workers  sync.WaitGroup
+	openedAt time.Time
+
+	// Guards steps, nextIndex, lastUsed and the steps' outcomes
+	mu        sync.Mutex
+	steps     []*Step
+	nextIndex int
+	lastUsed  time.Time
+}
+
+// Manager keeps the labs of this instance, one per owner. Sessions hold
+// open connections, so labs live in memory on the instance that opened
+// them.
+type Manager struct {
+	open        Opener
+	maxLabs     int
+	idleTimeout time.Duration
+
+	// Guards labs
+	mu   sync.Mutex
+	labs map[string]*lab
+}
+
+// New returns a manager holding at most maxLabs labs and closes labs idle
+// for idleTimeout until the process exits
+func New(open Opener, maxLabs int, idleTimeout time.Duration) *Manager {
+	if maxLabs < 1 {
+		maxLabs = 1
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	m := &Manager{
+		open:        open,
+		maxLabs:     maxLabs,
+		idleTimeout: idleTimeout,
+		labs:        make(map[string]*lab),
+	}
+	go m.reap()
+	return m
+}
+
+// Open opens a lab with sessions A and B for an owner, closing the lab the
+// owner had open before
+func (m *Manager) Open(owner string, dialect string) (*Snapshot, error) {
+	m.Close(owner)
+
+	m.mu.Lock()
+	full := len(m.labs) >= m.maxLabs
+	m.mu.Unlock()
+	if full {
+		return nil, ErrTooManyLabs
+	}
+
+	l, err := m.openLab(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	// Another lab may have opened in the meantime
+	m.mu.Lock()
+	previous, replaced := m.labs[owner]
+	if !replaced && len(m.labs) >= m.maxLabs {
+		m.mu.Unlock()
+		l.close()
+		return nil, ErrTooManyLabs
+	}
+	m.labs[owner] = l
+	m.mu.Unlock()
+	if replaced {
+		previous.close()
+	}
+	return l.snapshot(m.idleTimeout, nil), nil
+}
+
+// openLab connects both sessions of a new lab and starts their workers
+func (m *Manager) openLab(dialect string) (*lab, error) {
+	db, err := m.open(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), openTimeout)
+	defer cancel()
+
+	now := time.Now()
+	l := &lab{
+		dialect:  dialect,
+		db:       db,
+		sessions: make(map[string]*session, 2),
+		openedAt: now,
+		lastUsed: now,
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	for _, name := range []string{SessionA, SessionB} {
+		conn, err := db.Conn(ctx)
+		if err == nil {
+			err = prepare(ctx, conn, dialect)
+		}
+		if err != nil {
+			if conn != nil {
+				discard(conn)
+			}
+			l.close()
+			return nil, err
+		}
+		s := &session{
+			conn:  conn,
+			work:  make(chan *Step, maxQueuedSteps),
+			state: SessionState{Name: name, Savepoints: []string{}},
+		}
+		l.sessions[name] = s
+		l.workers.Add(1)
+		go l.work(s)
+	}
+	return l, nil
+}
+
+// prepare runs the dialect's session setup on a new connection
+func prepare(ctx context.Context, conn *sql.Conn, dialect string) error {
+	for _, stmt := range sessionSetup[dialect] {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the owner's lab and every step it ran
+func (m *Manager) Get(owner string) (*Snapshot, error) {
+	l, err := m.lab(owner)
+	if err != nil {
+		return nil, err
+	}
+	return l.snapshot(m.idleTimeout, nil), nil
+}
+
+// Run queues steps on the sessions of the owner's lab in order. Each step
+// gets up to wait to finish before the next is queued; a step still running
+// then is reported as blocked, and its outcome shows in later snapshots.
+// Steps of one session run one after another, those of different sessions
+// concurrently.
+func (m *Manager) Run(owner string, requests []StepRequest, wait time.Duration) (*Snapshot, error) {
+	l, err := m.lab(owner)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(requests))
+	queued := make(map[string]int, 2)
+	for i, request := range requests {
+		names[i] = strings.ToUpper(strings.TrimSpace(request.Session))
+		s, ok := l.sessions[names[i]]
+		if !ok {
+			return nil, ErrUnknownSession
+		}
+		queued[names[i]]++
+		if len(s.work)+queued[names[i]] > maxQueuedSteps {
+			return nil, ErrSessionBusy
+		}
+	}
+
+	steps := make([]*Step, 0, len(requests))
+	for i, request := range requests {
+		step := l.add(names[i], request.SQL)
+		steps = append(steps, step)
+		l.sessions[names[i]].work <- step
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-step.done:
+		case <-timer.C:
+			l.markBlocked(step)
+		case <-l.ctx.Done():
+		}
+		timer.Stop()
+	}
+	return l.snapshot(m.idleTimeout, steps), nil
+}
+
+// Close rolls back the transactions of the owner's lab and closes its
+// connections
+func (m *Manager) Close(owner string) error {
+	m.mu.Lock()
+	l, ok := m.labs[owner]
+	delete(m.labs, owner)
+	m.mu.Unlock()
+	if !ok {
+		return ErrNotFound
+	}
+	l.close()
+	return nil
+}
+
+// lab returns the owner's lab and marks it used
+func (m *Manager) lab(owner string) (*lab, error) {
+	m.mu.Lock()
+	l, ok := m.labs[owner]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	l.mu.Lock()
+	l.lastUsed = time.Now()
+	l.mu.Unlock()
+	return l, nil
+}
+
+// reap closes idle labs until the process exits
+func (m *Manager) reap() {
+	ticker := time.NewTicker(reapInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.closeIdle(time.Now())
+	}
+}
+
+// closeIdle closes the labs unused since before the idle timeout
+func (m *Manager) closeIdle(now time.Time) {
+	var idle []*lab
+	m.mu.Lock()
+	for owner, l := range m.labs {
+		l.mu.Lock()
+		expired := now.Sub(l.lastUsed) > m.idleTimeout
+		l.mu.Unlock()
+		if expired {
+			idle = append(idle, l)
+			delete(m.labs, owner)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, l := range idle {
+		l.close()
+	}
+}
+
+// add records a new step of a session
+func (l *lab) add(name string, sql string) *Step {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.nextIndex++
+	step := &Step{
+		Index:       l.nextIndex,
+		Session:     name,
+		SQL:         sql,
+		State:       Queued,
+		SubmittedAt: time.Now(),
+		done:        make(chan struct{}),
+	}
+	l.steps = append(l.steps, step)
+	if len(l.steps) > maxHistory {
+		l.steps = l.steps[len(l.steps)-maxHistory:]
+	}
+	return step
+}
+
+// markBlocked reports a step that is still running after the wait as
+// blocked, most likely on a lock the other session holds
+func (l *lab) markBlocked(step *Step) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if step.State == Running {
+		step.State = Blocked
+	}
+}
+
+// work runs the steps of a session until the lab closes
+func (l *lab) work(s *session) {
+	defer l.workers.Done()
+	for {
+		select {
+		case step := <-s.work:
+			l.execute(s, step)
+		case <-l.ctx.Done():
+			return
+		}
+	}
+}
+
+// execute runs a step on its session's connection and records the outcome
+func (l *lab) execute(s *session, step *Step) {
+	defer close(step.done)
+
+	started := time.Now()
+	l.mu.Lock()
+	step.State = Running
+	step.StartedAt = &started
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(l.ctx, StepTimeout)
+	defer cancel()
+
+	var result *resultcompare.ResultSet
+	var affected int64
+	var err error
+	if returnsRows(step.SQL, l.dialect) {
+		result, err = resultcompare.Query(ctx, s.conn, step.SQL, MaxRows)
+	} else {
+		var res sql.Result
+		if res, err = s.conn.ExecContext(ctx, step.SQL); err == nil {
+			affected, _ = res.RowsAffected()
+		}
+	}
+
+	finished := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s.track(sqlvalidator.ParseTransactionControl(step.SQL, l.dialect), err == nil, l.dialect)
+	step.FinishedAt = &finished
+	step.DurationMs = float64(finished.Sub(started).Microseconds()) / 1000
+	switch {
+	case err != nil:
+		step.State = Failed
+		step.Error = secrets.MaskError(err)
+	case result != nil:
+		step.State = Completed
+		step.Columns = result.Columns
+		step.Rows = result.Rows
+	default:
+		step.State = Completed
+		step.RowsAffected = &affected
+	}
+}
+
+// track follows the transaction and savepoints of a session through a step
+// that succeeded or failed. control is nil for statements other than
+// transaction control. Savepoints follow each dialect's rules: MySQL
+// replaces a savepoint of the same name, while PostgreSQL and SQLite keep
+// both until the newer one is released.
+func (s *session) track(control *sqlvalidator.TransactionControl, succeeded bool, dialect string) {
+	state := &s.state
+	if !succeeded {
+		if dialect == "postgresql" && state.InTransaction {
+			state.Aborted = true
+		}
+		return
+	}
+	if control == nil {
+		return
+	}
+
+	switch control.Kind {
+	case sqlvalidator.TxBegin:
+		*state = SessionState{Name: state.Name, InTransaction: true, Savepoints: []string{}}
+		s.savepointStarted = false
+	case sqlvalidator.TxCommit, sqlvalidator.TxRollback:
+		*state = SessionState{Name: state.Name, Savepoints: []string{}}
+		s.savepointStarted = false
+	case sqlvalidator.TxSavepoint:
+		if dialect == "sqlite" && !state.InTransaction {
+			state.InTransaction = true
+			s.savepointStarted = true
+		}
+		if dialect == "mysql" {
+			if i := lastIndex(state.Savepoints, control.Savepoint); i >= 0 {
+				state.Savepoints = append(state.Savepoints[:i], state.Savepoints[i+1:]...)
+			}
+		}
+		state.Savepoints = append(state.Savepoints, control.Savepoint)
+	case sqlvalidator.TxRollbackTo:
+		if i := lastIndex(state.Savepoints, control.Savepoint); i >= 0 {
+			state.Savepoints = state.Savepoints[:i+1]
+		}
+		state.Aborted = false
+	case sqlvalidator.TxRelease:
+		if i := lastIndex(state.Savepoints, control.Savepoint); i >= 0 {
+			state.Savepoints = state.Savepoints[:i]
+			if i == 0 && s.savepointStarted {
+				state.InTransaction = false
+				s.savepointStarted = false
+			}
+		}
+	}
+}
+
+// lastIndex returns the position of the newest savepoint with a name, or
+// -1 when there is none
+func lastIndex(savepoints []string, name string) int {
+	for i := len(savepoints) - 1; i >= 0; i-- {
+		if savepoints[i] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// returnsRows reports whether a statement produces a result set to read
+// rather than a count of changed rows
+func returnsRows(sql string, dialect string) bool {
+	first := ""
+	for _, token := range sqlvalidator.Tokenize(sql, dialect) {
+		switch token.Kind {
+		case sqlvalidator.TokenComment:
+			continue
+		case sqlvalidator.TokenWord:
+			if first == "" {
+				first = strings.ToLower(token.Text)
+				continue
+			}
+			if strings.EqualFold(token.Text, "returning") {
+				return true
+			}
+		default:
+			if first == "" && token.Text != "(" {
+				return false
+			}
+		}
+	}
+	switch first {
+	case "select", "with", "values", "table", "show", "explain", "describe", "desc", "pragma":
+		return true
+	}
+	return false
+}
+
+// snapshot describes the lab with the given steps, or all of them
+func (l *lab) snapshot(idleTimeout time.Duration, steps []*Step) *Snapshot {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if steps == nil {
+		steps = l.steps
+	}
+	snapshot := &Snapshot{
+		Dialect:   l.dialect,
+		Sessions:  make([]SessionState, 0, len(l.sessions)),
+		OpenedAt:  l.openedAt,
+		ExpiresAt: l.lastUsed.Add(idleTimeout),
+		Steps:     make([]Step, 0, len(steps)),
+	}
+	for _, name := range []string{SessionA, SessionB} {
+		if s, ok := l.sessions[name]; ok {
+			state := s.state
+			state.Savepoints = append([]string{}, state.Savepoints...)
+			snapshot.Sessions = append(snapshot.Sessions, state)
+		}
+	}
+	for _, step := range steps {
+		snapshot.Steps = append(snapshot.Steps, *step)
+	}
+	return snapshot
+}
+
+// close cancels running steps and closes the sessions' connections, which
+// rolls back their open transactions
+func (l *lab) close() {
+	l.cancel()
+	l.workers.Wait()
+	for _, s := range l.sessions {
+		discard(s.conn)
+	}
+	l.db.Close()
+}
+
+// discard closes conn and keeps the pool from reusing its session
+func discard(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
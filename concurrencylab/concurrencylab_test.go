@@ -0,0 +1,233 @@
+package concurrencylab
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// sqliteOpener opens a SQLite database file shared by both sessions
+func sqliteOpener(t *testing.T) Opener {
+	path := filepath.Join(t.TempDir(), "lab.db")
+	return func(dialect string) (*sql.DB, error) {
+		return sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	}
+}
+
+// waitForStep polls a lab until a step leaves the queued, running and
+// blocked states
+func waitForStep(t *testing.T, m *Manager, owner string, index int) Step {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		snapshot, err := m.Get(owner)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, step := range snapshot.Steps {
+			if step.Index == index && (step.State == Completed || step.State == Failed) {
+				return step
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("step %d did not finish", index)
+	return Step{}
+}
+
+func TestRunReportsBlockedSteps(t *testing.T) {
+	m := New(sqliteOpener(t), 1, time.Minute)
+	if _, err := m.Open("session:a", "sqlite"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Close("session:a")
+
+	snapshot, err := m.Run("session:a", []StepRequest{
+		{Session: "A", SQL: "CREATE TABLE accounts (id INTEGER PRIMARY KEY, balance INTEGER)"},
+		{Session: "A", SQL: "INSERT INTO accounts VALUES (1, 100)"},
+		{Session: "A", SQL: "BEGIN IMMEDIATE"},
+		{Session: "a", SQL: "UPDATE accounts SET balance = 50 WHERE id = 1"},
+		{Session: "B", SQL: "BEGIN IMMEDIATE"},
+	}, 200*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshot.Steps) != 5 {
+		t.Fatalf("expected the 5 submitted steps, got %d", len(snapshot.Steps))
+	}
+	if update := snapshot.Steps[3]; update.State != Completed || update.Session != SessionA ||
+		update.RowsAffected == nil || *update.RowsAffected != 1 {
+		t.Errorf("unexpected update step: %+v", update)
+	}
+	if begin := snapshot.Steps[4]; begin.State != Blocked {
+		t.Errorf("expected session B to wait for session A's lock, got %+v", begin)
+	}
+
+	_, err = m.Run("session:a", []StepRequest{
+		{Session: "A", SQL: "COMMIT"},
+		{Session: "B", SQL: "SELECT balance FROM accounts WHERE id = 1"},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if begin := waitForStep(t, m, "session:a", 5); begin.State != Completed {
+		t.Errorf("expected session B's transaction to start after the commit, got %+v", begin)
+	}
+	read := waitForStep(t, m, "session:a", 7)
+	if read.State != Completed || len(read.Rows) != 1 || fmt.Sprint(read.Rows[0][0]) != "50" {
+		t.Errorf("expected session B to read the committed balance, got %+v", read)
+	}
+}
+
+func TestRunRejectsUnknownSession(t *testing.T) {
+	m := New(sqliteOpener(t), 1, time.Minute)
+	if _, err := m.Run("session:a", []StepRequest{{Session: "A", SQL: "SELECT 1"}}, time.Second); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound without a lab, got %v", err)
+	}
+	if _, err := m.Open("session:a", "sqlite"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Close("session:a")
+
+	if _, err := m.Run("session:a", []StepRequest{{Session: "C", SQL: "SELECT 1"}}, time.Second); err != ErrUnknownSession {
+		t.Errorf("expected ErrUnknownSession, got %v", err)
+	}
+}
+
+func TestOpenLimitsLabs(t *testing.T) {
+	m := New(sqliteOpener(t), 1, time.Minute)
+	if _, err := m.Open("session:a", "sqlite"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Close("session:a")
+
+	if _, err := m.Open("session:b", "sqlite"); err != ErrTooManyLabs {
+		t.Errorf("expected ErrTooManyLabs, got %v", err)
+	}
+	// Reopening replaces the owner's lab
+	if _, err := m.Open("session:a", "sqlite"); err != nil {
+		t.Errorf("expected the owner to reopen their lab, got %v", err)
+	}
+}
+
+func TestCloseIdleLabs(t *testing.T) {
+	m := New(sqliteOpener(t), 2, time.Minute)
+	if _, err := m.Open("session:a", "sqlite"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m.closeIdle(time.Now())
+	if _, err := m.Get("session:a"); err != nil {
+		t.Errorf("expected a recently used lab to stay open, got %v", err)
+	}
+	m.closeIdle(time.Now().Add(2 * time.Minute))
+	if _, err := m.Get("session:a"); err != ErrNotFound {
+		t.Errorf("expected an idle lab to be closed, got %v", err)
+	}
+}
+
+func TestReturnsRows(t *testing.T) {
+	cases := map[string]bool{
+		"SELECT 1":                                       true,
+		"  -- balance\nselect balance FROM a":            true,
+		"(SELECT 1) UNION (SELECT 2)":                    true,
+		"WITH t AS (SELECT 1) SELECT * FROM t":           true,
+		"UPDATE a SET b = 1 RETURNING b":                 true,
+		"UPDATE a SET b = 1":                             false,
+		"BEGIN":                                          false,
+		"SET TRANSACTION ISOLATION LEVEL READ COMMITTED": false,
+	}
+	for sql, want := range cases {
+		if got := returnsRows(sql, "postgresql"); got != want {
+			t.Errorf("returnsRows(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestRunTracksSavepoints(t *testing.T) {
+	m := New(sqliteOpener(t), 1, time.Minute)
+	if _, err := m.Open("session:a", "sqlite"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer m.Close("session:a")
+
+	snapshot, err := m.Run("session:a", []StepRequest{
+		{Session: "A", SQL: "CREATE TABLE items (id INTEGER PRIMARY KEY)"},
+		{Session: "A", SQL: "BEGIN"},
+		{Session: "A", SQL: "INSERT INTO items VALUES (1)"},
+		{Session: "A", SQL: "SAVEPOINT first"},
+		{Session: "A", SQL: "INSERT INTO items VALUES (2)"},
+		{Session: "A", SQL: "SAVEPOINT second"},
+		{Session: "A", SQL: "ROLLBACK TO SAVEPOINT first"},
+		{Session: "A", SQL: "SELECT COUNT(*) FROM items"},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count := snapshot.Steps[7]; count.State != Completed || fmt.Sprint(count.Rows[0][0]) != "1" {
+		t.Errorf("expected the rollback to undo the second insert, got %+v", count)
+	}
+	if a := snapshot.Sessions[0]; !a.InTransaction || len(a.Savepoints) != 1 || a.Savepoints[0] != "first" {
+		t.Errorf("expected session A in a transaction with savepoint first, got %+v", a)
+	}
+	if b := snapshot.Sessions[1]; b.InTransaction || len(b.Savepoints) != 0 {
+		t.Errorf("expected session B outside any transaction, got %+v", b)
+	}
+
+	snapshot, err = m.Run("session:a", []StepRequest{
+		{Session: "A", SQL: "RELEASE SAVEPOINT first"},
+		{Session: "A", SQL: "COMMIT"},
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a := snapshot.Sessions[0]; a.InTransaction || len(a.Savepoints) != 0 {
+		t.Errorf("expected the commit to end session A's transaction, got %+v", a)
+	}
+}
+
+func TestTrackFollowsDialectRules(t *testing.T) {
+	control := func(sql string) *sqlvalidator.TransactionControl {
+		return sqlvalidator.ParseTransactionControl(sql, "mysql")
+	}
+
+	mysql := &session{state: SessionState{Savepoints: []string{}}}
+	for _, sql := range []string{"BEGIN", "SAVEPOINT a", "SAVEPOINT b", "SAVEPOINT a"} {
+		mysql.track(control(sql), true, "mysql")
+	}
+	if got := fmt.Sprint(mysql.state.Savepoints); got != "[b a]" {
+		t.Errorf("expected MySQL to replace a savepoint of the same name, got %s", got)
+	}
+
+	postgres := &session{state: SessionState{Savepoints: []string{}}}
+	for _, sql := range []string{"BEGIN", "SAVEPOINT a", "SAVEPOINT a"} {
+		postgres.track(control(sql), true, "postgresql")
+	}
+	if got := fmt.Sprint(postgres.state.Savepoints); got != "[a a]" {
+		t.Errorf("expected PostgreSQL to keep both savepoints, got %s", got)
+	}
+	postgres.track(nil, false, "postgresql")
+	if !postgres.state.Aborted {
+		t.Error("expected a failed statement to abort the PostgreSQL transaction")
+	}
+	postgres.track(control("ROLLBACK TO a"), true, "postgresql")
+	if postgres.state.Aborted || len(postgres.state.Savepoints) != 2 {
+		t.Errorf("expected rolling back to the savepoint to recover, got %+v", postgres.state)
+	}
+
+	sqlite := &session{state: SessionState{Savepoints: []string{}}}
+	sqlite.track(control("SAVEPOINT outer"), true, "sqlite")
+	if !sqlite.state.InTransaction {
+		t.Error("expected a SQLite savepoint to start a transaction")
+	}
+	sqlite.track(control("RELEASE outer"), true, "sqlite")
+	if sqlite.state.InTransaction {
+		t.Error("expected releasing the first savepoint to commit the SQLite transaction")
+	}
+}
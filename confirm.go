@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/confirmation"
+	"example/user/playground/dberrors"
+	"example/user/playground/mockdb"
+	"example/user/playground/sqlvalidator"
+)
+
+// confirmStatement lets a statement that needs confirmation run when the
+// request carries a valid token for it, returning a nil response. Otherwise
+// it returns a response with a new one-time token and an explanation.
+func confirmStatement(owner string, req SQLValidationRequest, safetyCheck sqlvalidator.SafetyCheckResult) (int, gin.H) {
+	// Dry runs are rolled back and the mock dialect has no data to lose
+	if req.DryRun || req.Dialect == mockdb.Dialect {
+		return http.StatusOK, nil
+	}
+
+	if confirmation.Redeem(req.ConfirmationToken, owner, req.Dialect, req.SQL) {
+		return http.StatusOK, nil
+	}
+
+	token, err := confirmation.Issue(owner, req.Dialect, req.SQL)
+	if err != nil {
+		return http.StatusInternalServerError, gin.H{
+			"valid": false,
+			"error": "Failed to create confirmation token",
+		}
+	}
+
+	return http.StatusOK, withWarnings(gin.H{
+		"valid":                false,
+		"error":                safetyCheck.Error,
+		"errorCode":            dberrors.CodeNeedsConfirmation,
+		"rule":                 safetyCheck.Rule,
+		"requiresConfirmation": true,
+		"confirmationToken":    token,
+		"expiresInSeconds":     int(confirmation.TokenLifetime.Seconds()),
+	}, safetyCheck.Warnings)
+}
@@ -0,0 +1,75 @@
+package confirmation
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"example/user/playground/sharedstate"
+)
+
+// How long a confirmation token stays valid
+const TokenLifetime = 5 * time.Minute
+
+// pending is a statement waiting to be confirmed. Pending statements are
+// kept in shared state so a token can be redeemed on any instance.
+type pending struct {
+	Owner     string            `json:"owner"`
+	Dialect   string            `json:"dialect"`
+	SQLHash   [sha256.Size]byte `json:"sql_hash"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// tokenKey returns the shared state key of a token
+func tokenKey(token string) string {
+	return "confirmation:" + token
+}
+
+// Issue returns a one-time token that lets the session owner run exactly
+// this statement on this dialect within TokenLifetime
+func Issue(owner string, dialect string, sql string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	data, err := json.Marshal(pending{
+		Owner:     owner,
+		Dialect:   dialect,
+		SQLHash:   sha256.Sum256([]byte(sql)),
+		ExpiresAt: time.Now().Add(TokenLifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := sharedstate.Current().Set(context.Background(), tokenKey(token), data, TokenLifetime); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Redeem reports whether token confirms this statement for the session
+// owner. A token is consumed by its first use, matching or not.
+func Redeem(token string, owner string, dialect string, sql string) bool {
+	if token == "" {
+		return false
+	}
+
+	data, err := sharedstate.Current().GetDel(context.Background(), tokenKey(token))
+	if err != nil {
+		return false
+	}
+	var p pending
+	if err := json.Unmarshal(data, &p); err != nil {
+		return false
+	}
+
+	return time.Now().Before(p.ExpiresAt) &&
+		p.Owner == owner &&
+		p.Dialect == dialect &&
+		p.SQLHash == sha256.Sum256([]byte(sql))
+}
@@ -0,0 +1,69 @@
+package confirmation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"example/user/playground/sharedstate"
+)
+
+func TestRedeemMatchingStatementOnce(t *testing.T) {
+	token, err := Issue("session:a", "sqlite", "DELETE FROM items")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !Redeem(token, "session:a", "sqlite", "DELETE FROM items") {
+		t.Errorf("expected the token to confirm the statement")
+	}
+	if Redeem(token, "session:a", "sqlite", "DELETE FROM items") {
+		t.Errorf("expected the token to be single use")
+	}
+}
+
+func TestRedeemRejectsOtherStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		owner   string
+		dialect string
+		sql     string
+	}{
+		{"other session", "session:b", "sqlite", "DELETE FROM items"},
+		{"other dialect", "session:a", "mysql", "DELETE FROM items"},
+		{"other statement", "session:a", "sqlite", "DELETE FROM users"},
+	}
+
+	for _, tt := range tests {
+		token, err := Issue("session:a", "sqlite", "DELETE FROM items")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if Redeem(token, tt.owner, tt.dialect, tt.sql) {
+			t.Errorf("%s: expected the token to be rejected", tt.name)
+		}
+	}
+
+	if Redeem("", "session:a", "sqlite", "DELETE FROM items") {
+		t.Errorf("expected an empty token to be rejected")
+	}
+}
+
+func TestRedeemExpiredToken(t *testing.T) {
+	token, err := Issue("session:a", "sqlite", "DELETE FROM items")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state := sharedstate.Current()
+	data, _ := state.Get(context.Background(), tokenKey(token))
+	var p pending
+	json.Unmarshal(data, &p)
+	p.ExpiresAt = time.Now().Add(-time.Second)
+	data, _ = json.Marshal(p)
+	state.Set(context.Background(), tokenKey(token), data, 0)
+
+	if Redeem(token, "session:a", "sqlite", "DELETE FROM items") {
+		t.Errorf("expected an expired token to be rejected")
+	}
+}
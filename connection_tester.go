@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/connections"
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/secrets"
+)
+
+type TestConnectionRequest struct {
+	Dialect string `json:"dialect" binding:"required"`
+	DSN     string `json:"dsn" binding:"required"`
+}
+
+// testConnection connects to a user-provided DSN with a short timeout and
+// reports the server version and features without keeping the connection
+func testConnection(c *gin.Context) {
+	var req TestConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.Dialect != "mysql" && req.Dialect != "postgresql" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Only MySQL and PostgreSQL connections can be tested",
+		})
+		return
+	}
+
+	db, err := connections.OpenDSN(req.Dialect, req.DSN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Invalid DSN: " + secrets.MaskError(err),
+			"errorCode": dberrors.CodeValidationError,
+		})
+		return
+	}
+	defer db.Close()
+	probe, err := dbmanager.ProbeConnection(c.Request.Context(), req.Dialect, db)
+	recordAudit(c, "", "connection.test", req.Dialect, connections.Redact(req.Dialect, req.DSN))
+	if err != nil {
+		// Failures the database did not classify happened while connecting
+		code, _ := dberrors.Classify(err, "")
+		if code == dberrors.CodeUnknown {
+			code = dberrors.CodeConnectionError
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success":   false,
+			"error":     secrets.MaskError(err),
+			"errorCode": code,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"connection": probe,
+	})
+}
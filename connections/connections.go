@@ -0,0 +1,401 @@
+package connections
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/mattn/go-sqlite3"
+
+	"example/user/playground/secrets"
+)
+
+// Most connections a user may register
+const MaxPerOwner = 10
+
+// Longest accepted connection name
+const maxNameLength = 64
+
+// Connections opened per registered database
+const maxOpenConns = 2
+
+var (
+	// ErrNotFound is returned when a connection does not exist for the owner
+	ErrNotFound = errors.New("connection not found")
+
+	// ErrNameTaken is returned when the owner already has a connection of
+	// that name
+	ErrNameTaken = errors.New("a connection with this name already exists")
+
+	// Password settings in key=value PostgreSQL connection strings
+	pgPasswordRegex = regexp.MustCompile(`(?i)\s*\bpassword\s*=\s*('(?:[^'\\]|\\.)*'|\S+)`)
+)
+
+// Connection is an external database registered by a user. The DSN itself
+// is only kept encrypted and never returned.
+type Connection struct {
+	ID       string `json:"id"`
+	Owner    string `json:"-"`
+	Name     string `json:"name"`
+	Dialect  string `json:"dialect"`
+	ReadOnly bool   `json:"read_only"`
+	// The DSN with its password removed
+	Target    string    `json:"target"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	// Store of registered connections
+	store *sql.DB
+
+	// Open database handles keyed by connection ID
+	pools = make(map[string]*sql.DB)
+
+	// Whether every connection is used read-only regardless of its setting
+	readOnlyRequired bool
+
+	// Guards pools and readOnlyRequired
+	mu sync.Mutex
+)
+
+// Init opens the connection store at path. DSNs are encrypted with the
+// keys configured in the secrets package, which must be initialized first.
+func Init(path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS connections (
+		id TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL,
+		dialect TEXT NOT NULL,
+		dsn BLOB NOT NULL,
+		read_only INTEGER NOT NULL,
+		target TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		UNIQUE (owner, name)
+	)`)
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, pool := range pools {
+		pool.Close()
+		delete(pools, id)
+	}
+	store = db
+	return nil
+}
+
+// SetReadOnlyRequired makes every connection read-only, including those
+// registered before, when required is true
+func SetReadOnlyRequired(required bool) {
+	mu.Lock()
+	readOnlyRequired = required
+	mu.Unlock()
+}
+
+// ReadOnlyRequired reports whether every connection is used read-only
+func ReadOnlyRequired() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return readOnlyRequired
+}
+
+// Create registers a connection for an owner. The caller is expected to
+// have checked that the DSN connects.
+func Create(owner string, name string, dialect string, dsn string, readOnly bool) (*Connection, error) {
+	if store == nil {
+		return nil, errors.New("connection store is not initialized")
+	}
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "" || len(name) > maxNameLength:
+		return nil, fmt.Errorf("connection names must be 1 to %d characters", maxNameLength)
+	case dialect != "mysql" && dialect != "postgresql":
+		return nil, errors.New("only MySQL and PostgreSQL connections can be registered")
+	case strings.TrimSpace(dsn) == "":
+		return nil, errors.New("a DSN is required")
+	}
+	if err := CheckDSN(dialect, dsn); err != nil {
+		return nil, err
+	}
+
+	var count int
+	if err := store.QueryRow(`SELECT COUNT(*) FROM connections WHERE owner = ?`, owner).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count >= MaxPerOwner {
+		return nil, fmt.Errorf("at most %d connections can be registered", MaxPerOwner)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	conn := &Connection{
+		ID:        id,
+		Owner:     owner,
+		Name:      name,
+		Dialect:   dialect,
+		ReadOnly:  readOnly || ReadOnlyRequired(),
+		Target:    Redact(dialect, dsn),
+		CreatedAt: time.Now().UTC(),
+	}
+	sealed, err := seal(conn, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = store.Exec(
+		`INSERT INTO connections (id, owner, name, dialect, dsn, read_only, target, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		conn.ID, conn.Owner, conn.Name, conn.Dialect, sealed, conn.ReadOnly, conn.Target, conn.CreatedAt,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE") {
+			return nil, ErrNameTaken
+		}
+		return nil, err
+	}
+	return conn, nil
+}
+
+// List returns the connections of an owner, oldest first
+func List(owner string) ([]Connection, error) {
+	if store == nil {
+		return []Connection{}, nil
+	}
+	rows, err := store.Query(
+		`SELECT id, owner, name, dialect, read_only, target, created_at
+		FROM connections WHERE owner = ? ORDER BY created_at, name`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	required := ReadOnlyRequired()
+	result := []Connection{}
+	for rows.Next() {
+		var conn Connection
+		if err := rows.Scan(&conn.ID, &conn.Owner, &conn.Name, &conn.Dialect, &conn.ReadOnly, &conn.Target, &conn.CreatedAt); err != nil {
+			return nil, err
+		}
+		conn.ReadOnly = conn.ReadOnly || required
+		result = append(result, conn)
+	}
+	return result, rows.Err()
+}
+
+// Delete removes a connection of an owner and closes its open handle
+func Delete(owner string, id string) error {
+	if store == nil {
+		return ErrNotFound
+	}
+	res, err := store.Exec(`DELETE FROM connections WHERE id = ? AND owner = ?`, id, owner)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+
+	mu.Lock()
+	if pool, ok := pools[id]; ok {
+		pool.Close()
+		delete(pools, id)
+	}
+	mu.Unlock()
+	return nil
+}
+
+// Open returns a database handle for a connection of an owner, opening it
+// on first use. The DSN's options are checked again and its connections go
+// through the host policy.
+func Open(owner string, id string) (*sql.DB, *Connection, error) {
+	conn, dsn, err := load(owner, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if pool, ok := pools[id]; ok {
+		return pool, conn, nil
+	}
+
+	pool, err := OpenDSN(conn.Dialect, dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool.SetMaxOpenConns(maxOpenConns)
+	pool.SetMaxIdleConns(1)
+	pool.SetConnMaxIdleTime(5 * time.Minute)
+	pools[id] = pool
+	return pool, conn, nil
+}
+
+// load reads a connection of an owner and decrypts its DSN
+func load(owner string, id string) (*Connection, string, error) {
+	if store == nil {
+		return nil, "", ErrNotFound
+	}
+	var conn Connection
+	var sealed []byte
+	err := store.QueryRow(
+		`SELECT id, owner, name, dialect, dsn, read_only, target, created_at
+		FROM connections WHERE id = ? AND owner = ?`, id, owner,
+	).Scan(&conn.ID, &conn.Owner, &conn.Name, &conn.Dialect, &sealed, &conn.ReadOnly, &conn.Target, &conn.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, "", ErrNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	conn.ReadOnly = conn.ReadOnly || ReadOnlyRequired()
+
+	dsn, err := open(&conn, sealed)
+	if err != nil {
+		return nil, "", err
+	}
+	return &conn, dsn, nil
+}
+
+// seal encrypts a DSN, binding it to the connection's ID and owner so it
+// cannot be moved to another row
+func seal(conn *Connection, dsn string) ([]byte, error) {
+	return secrets.Seal([]byte(dsn), associatedData(conn))
+}
+
+// open decrypts a DSN sealed by seal
+func open(conn *Connection, sealed []byte) (string, error) {
+	dsn, err := secrets.Open(sealed, associatedData(conn))
+	if err != nil {
+		return "", fmt.Errorf("stored connection cannot be decrypted: %v", err)
+	}
+	return string(dsn), nil
+}
+
+// Rotate seals again every DSN that was encrypted with a previous key, so
+// the previous keys can be retired, and returns how many were rotated
+func Rotate() (int, error) {
+	if store == nil {
+		return 0, nil
+	}
+	rows, err := store.Query(`SELECT id, owner, dsn FROM connections`)
+	if err != nil {
+		return 0, err
+	}
+	type staleRow struct {
+		conn   Connection
+		sealed []byte
+	}
+	var stale []staleRow
+	for rows.Next() {
+		var row staleRow
+		if err := rows.Scan(&row.conn.ID, &row.conn.Owner, &row.sealed); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if secrets.Stale(row.sealed) {
+			stale = append(stale, row)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	rotated := 0
+	for _, row := range stale {
+		dsn, err := open(&row.conn, row.sealed)
+		if err != nil {
+			return rotated, fmt.Errorf("connection %s: %v", row.conn.ID, err)
+		}
+		sealed, err := seal(&row.conn, dsn)
+		if err != nil {
+			return rotated, err
+		}
+		if _, err := store.Exec(`UPDATE connections SET dsn = ? WHERE id = ?`, sealed, row.conn.ID); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+// StaleCount returns how many DSNs are still encrypted with a previous key
+func StaleCount() (int, error) {
+	if store == nil {
+		return 0, nil
+	}
+	rows, err := store.Query(`SELECT dsn FROM connections`)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	count := 0
+	for rows.Next() {
+		var sealed []byte
+		if err := rows.Scan(&sealed); err != nil {
+			return 0, err
+		}
+		if secrets.Stale(sealed) {
+			count++
+		}
+	}
+	return count, rows.Err()
+}
+
+// associatedData authenticates the row a sealed DSN belongs to
+func associatedData(conn *Connection) []byte {
+	return []byte(conn.ID + "\x00" + conn.Owner)
+}
+
+// Redact returns a DSN without its password, for display
+func Redact(dialect string, dsn string) string {
+	if dialect == "mysql" {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return ""
+		}
+		cfg.Passwd = ""
+		return cfg.FormatDSN()
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return ""
+		}
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+		query := u.Query()
+		query.Del("password")
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return strings.TrimSpace(pgPasswordRegex.ReplaceAllString(dsn, ""))
+}
+
+// newID returns a random connection identifier
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
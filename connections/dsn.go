@@ -0,0 +1,425 @@
+package connections
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+// Network registered with the MySQL driver for registered connections,
+// dialed through the host policy
+const mysqlNetwork = "playground_user_tcp"
+
+// Longest a registered connection may take to connect
+const dialTimeout = 10 * time.Second
+
+// ErrHostDenied is returned when a registered connection points at a host
+// the playground does not let users reach
+var ErrHostDenied = errors.New("connections to this host are not allowed")
+
+// Options a MySQL DSN of a registered connection may set. Others, such as
+// allowAllFiles, which lets the server read any file of the playground's
+// host, or session variables, are rejected.
+var mysqlOptions = map[string]bool{
+	"charset":          true,
+	"collation":        true,
+	"loc":              true,
+	"parseTime":        true,
+	"timeout":          true,
+	"readTimeout":      true,
+	"writeTimeout":     true,
+	"tls":              true,
+	"clientFoundRows":  true,
+	"columnsWithAlias": true,
+}
+
+// Options a PostgreSQL DSN of a registered connection may set. Others are
+// rejected, including run-time parameters.
+var postgresOptions = map[string]bool{
+	"host":             true,
+	"port":             true,
+	"dbname":           true,
+	"user":             true,
+	"password":         true,
+	"sslmode":          true,
+	"connect_timeout":  true,
+	"application_name": true,
+}
+
+// PostgreSQL options naming files on the playground's host, rejected with
+// a reason of their own
+var postgresFileOptions = map[string]bool{
+	"passfile":    true,
+	"service":     true,
+	"sslcert":     true,
+	"sslkey":      true,
+	"sslrootcert": true,
+	"sslinline":   true,
+}
+
+// SSL modes of registered PostgreSQL connections; the verifying modes read
+// certificates from the playground's host
+var postgresSSLModes = map[string]bool{
+	"disable": true,
+	"allow":   true,
+	"prefer":  true,
+	"require": true,
+}
+
+// hostPolicy lists the hosts registered connections may and may not reach
+// beyond the defaults, which deny loopback, private, link-local and
+// unspecified addresses
+type hostPolicy struct {
+	allowNames    map[string]bool
+	allowPrefixes []netip.Prefix
+	denyNames     map[string]bool
+	denyPrefixes  []netip.Prefix
+}
+
+var (
+	// Hosts registered connections may reach, set by SetHostPolicy
+	policy = hostPolicy{}
+
+	// Guards policy
+	policyMu sync.RWMutex
+)
+
+func init() {
+	mysql.RegisterDialContext(mysqlNetwork, func(ctx context.Context, addr string) (net.Conn, error) {
+		return dialAllowed(ctx, "tcp", addr)
+	})
+}
+
+// SetHostPolicy sets the hosts registered connections may reach. Each entry
+// is a host name, an IP address or a CIDR range. Denied entries add to the
+// defaults, which deny loopback, private, link-local and unspecified
+// addresses; allowed entries take precedence over both.
+func SetHostPolicy(allow []string, deny []string) error {
+	next := hostPolicy{allowNames: map[string]bool{}, denyNames: map[string]bool{}}
+	var err error
+	if next.allowPrefixes, err = parseHosts(allow, next.allowNames); err != nil {
+		return err
+	}
+	if next.denyPrefixes, err = parseHosts(deny, next.denyNames); err != nil {
+		return err
+	}
+	policyMu.Lock()
+	policy = next
+	policyMu.Unlock()
+	return nil
+}
+
+// parseHosts adds the host names of a list to names and returns its
+// addresses and ranges
+func parseHosts(entries []string, names map[string]bool) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address range %q: %v", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			} else {
+				names[strings.ToLower(entry)] = true
+			}
+		}
+	}
+	return prefixes, nil
+}
+
+// checkHost reports whether a host name may be reached, before it is
+// resolved. Allowed names skip the address checks.
+func checkHost(host string) (allowed bool, err error) {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+
+	name := strings.ToLower(strings.TrimSuffix(host, "."))
+	if policy.allowNames[name] {
+		return true, nil
+	}
+	if policy.denyNames[name] {
+		return false, ErrHostDenied
+	}
+	return false, nil
+}
+
+// checkAddr reports whether an address may be reached
+func checkAddr(addr netip.Addr) error {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+
+	addr = addr.Unmap()
+	for _, prefix := range policy.allowPrefixes {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	for _, prefix := range policy.denyPrefixes {
+		if prefix.Contains(addr) {
+			return ErrHostDenied
+		}
+	}
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsMulticast() {
+		return ErrHostDenied
+	}
+	return nil
+}
+
+// dialAllowed connects to an address after checking its host and every
+// address it resolves to against the host policy. The checked address is
+// dialed, so the name cannot resolve elsewhere in between.
+func dialAllowed(ctx context.Context, network string, address string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, ErrHostDenied
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := checkHost(host)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+	if !allowed {
+		for _, addr := range addrs {
+			if err := checkAddr(addr); err != nil {
+				return nil, err
+			}
+		}
+	}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	return dialer.DialContext(ctx, network, net.JoinHostPort(addrs[0].Unmap().String(), port))
+}
+
+// postgresDialer dials PostgreSQL servers through the host policy
+type postgresDialer struct{}
+
+func (postgresDialer) Dial(network string, address string) (net.Conn, error) {
+	return dialAllowed(context.Background(), network, address)
+}
+
+func (postgresDialer) DialTimeout(network string, address string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return dialAllowed(ctx, network, address)
+}
+
+func (postgresDialer) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	return dialAllowed(ctx, network, address)
+}
+
+// OpenDSN checks the options of a user's DSN and opens a pool on it whose
+// connections only reach hosts the policy allows. Nothing connects until
+// the pool is used.
+func OpenDSN(dialect string, dsn string) (*sql.DB, error) {
+	switch dialect {
+	case "mysql":
+		cfg, err := mysqlConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		connector, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(connector), nil
+	case "postgresql":
+		cfg, err := postgresConfig(dsn)
+		if err != nil {
+			return nil, err
+		}
+		connector, err := pq.NewConnectorConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		connector.Dialer(postgresDialer{})
+		return sql.OpenDB(connector), nil
+	}
+	return nil, errors.New("only MySQL and PostgreSQL connections can be registered")
+}
+
+// CheckDSN reports what is wrong with the options of a user's DSN, if
+// anything
+func CheckDSN(dialect string, dsn string) error {
+	db, err := OpenDSN(dialect, dsn)
+	if err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// mysqlConfig parses a MySQL DSN, rejecting options outside mysqlOptions
+// and networks other than TCP, and routes it through the host policy
+func mysqlConfig(dsn string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %v", err)
+	}
+	if cfg.Net != "tcp" {
+		return nil, fmt.Errorf("only TCP connections are allowed, not %s", cfg.Net)
+	}
+	// Options follow the ? after the database name
+	if slash := strings.LastIndex(dsn, "/"); slash >= 0 {
+		if question := strings.IndexByte(dsn[slash:], '?'); question >= 0 {
+			options, err := url.ParseQuery(dsn[slash+question+1:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid DSN: %v", err)
+			}
+			if err := checkOptions(options, mysqlOptions, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	cfg.AllowAllFiles = false
+	cfg.Net = mysqlNetwork
+	return cfg, nil
+}
+
+// postgresConfig parses a PostgreSQL URL or key=value DSN, rejecting
+// options outside postgresOptions and Unix sockets
+func postgresConfig(dsn string) (pq.Config, error) {
+	dsn = strings.TrimSpace(dsn)
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		converted, err := pq.ParseURL(dsn)
+		if err != nil {
+			return pq.Config{}, fmt.Errorf("invalid DSN: %v", err)
+		}
+		dsn = converted
+	}
+	options, err := parseKeyValues(dsn)
+	if err != nil {
+		return pq.Config{}, err
+	}
+	values := url.Values{}
+	for key, value := range options {
+		values.Set(key, value)
+	}
+	if err := checkOptions(values, postgresOptions, postgresFileOptions); err != nil {
+		return pq.Config{}, err
+	}
+	host := options["host"]
+	switch {
+	case host == "":
+		return pq.Config{}, errors.New("a host is required")
+	case strings.HasPrefix(host, "/") || strings.HasPrefix(host, "@"):
+		return pq.Config{}, errors.New("only TCP connections are allowed, not Unix sockets")
+	case strings.Contains(host, ","):
+		return pq.Config{}, errors.New("only one host is allowed")
+	}
+	if mode, ok := options["sslmode"]; ok && !postgresSSLModes[mode] {
+		return pq.Config{}, fmt.Errorf("sslmode %s is not allowed, use disable, allow, prefer or require", mode)
+	}
+
+	cfg, err := pq.NewConfig(dsn)
+	if err != nil {
+		return pq.Config{}, fmt.Errorf("invalid DSN: %v", err)
+	}
+	// Files named by the playground's own environment are not the user's
+	cfg.Passfile = ""
+	cfg.Service = ""
+	cfg.SSLCert, cfg.SSLKey, cfg.SSLRootCert = "", "", ""
+	return cfg, nil
+}
+
+// checkOptions rejects options outside allowed, with a reason of their own
+// for those naming local files
+func checkOptions(options url.Values, allowed map[string]bool, files map[string]bool) error {
+	keys := make([]string, 0, len(options))
+	for key := range options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		switch {
+		case allowed[key]:
+		case files[key]:
+			return fmt.Errorf("the %s option names a file on the playground's host and is not allowed", key)
+		default:
+			return fmt.Errorf("the %s option is not allowed", key)
+		}
+	}
+	return nil
+}
+
+// parseKeyValues splits a PostgreSQL key=value DSN. Values may be quoted
+// with single quotes, and a backslash escapes the next character.
+func parseKeyValues(dsn string) (map[string]string, error) {
+	options := map[string]string{}
+	i := 0
+	skipSpaces := func() {
+		for i < len(dsn) && strings.IndexByte(" \t\r\n", dsn[i]) >= 0 {
+			i++
+		}
+	}
+	for {
+		skipSpaces()
+		if i >= len(dsn) {
+			return options, nil
+		}
+		start := i
+		for i < len(dsn) && dsn[i] != '=' && strings.IndexByte(" \t\r\n", dsn[i]) < 0 {
+			i++
+		}
+		key := dsn[start:i]
+		skipSpaces()
+		if key == "" || i >= len(dsn) || dsn[i] != '=' {
+			return nil, fmt.Errorf("invalid DSN: expected key=value near %q", key)
+		}
+		i++
+		skipSpaces()
+
+		var value strings.Builder
+		quoted := i < len(dsn) && dsn[i] == '\''
+		if quoted {
+			i++
+		}
+		closed := !quoted
+		for i < len(dsn) {
+			ch := dsn[i]
+			if quoted && ch == '\'' {
+				i++
+				closed = true
+				break
+			}
+			if !quoted && strings.IndexByte(" \t\r\n", ch) >= 0 {
+				break
+			}
+			if ch == '\\' && i+1 < len(dsn) {
+				i++
+				ch = dsn[i]
+			}
+			value.WriteByte(ch)
+			i++
+		}
+		if !closed {
+			return nil, fmt.Errorf("invalid DSN: unterminated quote in %s", key)
+		}
+		options[key] = value.String()
+	}
+}
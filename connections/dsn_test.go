@@ -0,0 +1,142 @@
+package connections
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+// resetHostPolicy restores the default host policy after a test
+func resetHostPolicy(t *testing.T) {
+	t.Cleanup(func() { SetHostPolicy(nil, nil) })
+}
+
+func TestCheckDSNRejectsOptions(t *testing.T) {
+	tests := []struct {
+		dialect string
+		dsn     string
+		want    string
+	}{
+		{"mysql", "app:pw@tcp(db.example.com:3306)/sales?allowAllFiles=true", "allowAllFiles option is not allowed"},
+		{"mysql", "app:pw@tcp(db.example.com:3306)/sales?multiStatements=true", "multiStatements option is not allowed"},
+		{"mysql", "app:pw@tcp(db.example.com:3306)/sales?sql_mode=ANSI", "sql_mode option is not allowed"},
+		{"mysql", "app:pw@unix(/var/run/mysqld/mysqld.sock)/sales", "only TCP connections"},
+		{"postgresql", "host=db.example.com user=app sslkey=/app/secrets.key", "sslkey option names a file"},
+		{"postgresql", "host=db.example.com user=app sslrootcert=/etc/ssl/root.crt", "sslrootcert option names a file"},
+		{"postgresql", "host=db.example.com user=app passfile=/app/connections.sqlite", "passfile option names a file"},
+		{"postgresql", "postgres://app@db.example.com/sales?sslcert=/app/cert.pem", "sslcert option names a file"},
+		{"postgresql", "postgres://app@db.example.com/sales?search_path=other", "search_path option is not allowed"},
+		{"postgresql", "host=/var/run/postgresql user=app", "Unix sockets"},
+		{"postgresql", "host=a.example.com,b.example.com user=app", "only one host"},
+		{"postgresql", "host=db.example.com user=app sslmode=verify-full", "sslmode verify-full is not allowed"},
+		{"postgresql", "user=app dbname=sales", "a host is required"},
+		{"postgresql", "host=db.example.com password='unterminated", "unterminated quote"},
+	}
+	for _, test := range tests {
+		err := CheckDSN(test.dialect, test.dsn)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("CheckDSN(%q) = %v, want an error containing %q", test.dsn, err, test.want)
+		}
+	}
+}
+
+func TestCheckDSNAcceptsPlainDSNs(t *testing.T) {
+	for dialect, dsn := range map[string]string{
+		"mysql":      "app:pw@tcp(db.example.com:3306)/sales?parseTime=true&charset=utf8mb4",
+		"postgresql": "host=db.example.com port=5432 user=app password='a b\\'c' dbname=sales sslmode=require",
+	} {
+		if err := CheckDSN(dialect, dsn); err != nil {
+			t.Errorf("CheckDSN(%q) = %v, want no error", dsn, err)
+		}
+	}
+	if err := CheckDSN("postgresql", testDSN); err != nil {
+		t.Errorf("expected a URL DSN to be accepted, got %v", err)
+	}
+}
+
+func TestMySQLConfigDisablesLocalFiles(t *testing.T) {
+	cfg, err := mysqlConfig("app:pw@tcp(db.example.com:3306)/sales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AllowAllFiles || cfg.Net != mysqlNetwork {
+		t.Errorf("expected local files off and the guarded network, got %+v", cfg)
+	}
+}
+
+func TestCreateRejectsUnsafeDSN(t *testing.T) {
+	initStore(t)
+	if _, err := Create("user:1", "files", "mysql", "app:pw@tcp(db.example.com:3306)/sales?allowAllFiles=true", false); err == nil {
+		t.Error("expected a DSN allowing local files to be rejected")
+	}
+}
+
+func TestDefaultPolicyDeniesInternalAddresses(t *testing.T) {
+	resetHostPolicy(t)
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.17.0.2", "192.168.1.1", "169.254.169.254", "0.0.0.0", "fd00::1", "::ffff:127.0.0.1"} {
+		if err := checkAddr(netip.MustParseAddr(addr)); err != ErrHostDenied {
+			t.Errorf("expected %s to be denied, got %v", addr, err)
+		}
+	}
+	if err := checkAddr(netip.MustParseAddr("93.184.216.34")); err != nil {
+		t.Errorf("expected a public address to be allowed, got %v", err)
+	}
+}
+
+func TestHostPolicyLists(t *testing.T) {
+	resetHostPolicy(t)
+	if err := SetHostPolicy([]string{"10.20.0.0/16", "warehouse.internal"}, []string{"mysql", "93.184.216.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkAddr(netip.MustParseAddr("10.20.1.1")); err != nil {
+		t.Errorf("expected an allowed range to override the defaults, got %v", err)
+	}
+	if err := checkAddr(netip.MustParseAddr("10.21.1.1")); err != ErrHostDenied {
+		t.Errorf("expected other private addresses to stay denied, got %v", err)
+	}
+	if err := checkAddr(netip.MustParseAddr("93.184.216.34")); err != ErrHostDenied {
+		t.Errorf("expected a denied range to be denied, got %v", err)
+	}
+	if _, err := checkHost("MySQL"); err != ErrHostDenied {
+		t.Errorf("expected a denied backend name to be denied, got %v", err)
+	}
+	if allowed, err := checkHost("warehouse.internal"); !allowed || err != nil {
+		t.Errorf("expected an allowed name to be allowed, got %v %v", allowed, err)
+	}
+
+	if err := SetHostPolicy([]string{"10.0.0.0/33"}, nil); err == nil {
+		t.Error("expected an invalid range to be rejected")
+	}
+}
+
+func TestDialDeniesLoopback(t *testing.T) {
+	resetHostPolicy(t)
+	for _, address := range []string{"127.0.0.1:3306", "localhost:5432", "[::1]:5432"} {
+		if _, err := dialAllowed(context.Background(), "tcp", address); err != ErrHostDenied {
+			t.Errorf("expected dialing %s to be denied, got %v", address, err)
+		}
+	}
+	if _, err := dialAllowed(context.Background(), "unix", "/var/run/postgresql/.s.PGSQL.5432"); err != ErrHostDenied {
+		t.Errorf("expected Unix sockets to be denied, got %v", err)
+	}
+}
+
+func TestOpenedPoolsUseThePolicy(t *testing.T) {
+	resetHostPolicy(t)
+	for dialect, dsn := range map[string]string{
+		"mysql":      "app:pw@tcp(127.0.0.1:3306)/sales",
+		"postgresql": "host=127.0.0.1 user=app dbname=sales sslmode=disable",
+	} {
+		db, err := OpenDSN(dialect, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = db.Ping()
+		db.Close()
+		if err == nil || !strings.Contains(err.Error(), ErrHostDenied.Error()) {
+			t.Errorf("expected %s connections to loopback to be denied, got %v", dialect, err)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/containers"
+	"example/user/playground/dbmanager"
+)
+
+// Docker socket used when DOCKER_SOCKET is unset
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// Containers of the backends in docker-compose.yml
+const defaultBackendContainers = "mysql=mysql,postgresql=postgres"
+
+// configureContainerControl lets the admin API start, stop and restart the
+// backends' containers through the Docker socket, DOCKER_SOCKET or
+// /var/run/docker.sock, when the server can reach it. BACKEND_CONTAINERS
+// names the containers, such as "mysql=mysql,postgresql=postgres".
+func configureContainerControl() {
+	socket := envOr("DOCKER_SOCKET", defaultDockerSocket)
+	if _, err := os.Stat(socket); err != nil {
+		return
+	}
+
+	names := make(map[string]string)
+	for _, entry := range strings.Split(envOr("BACKEND_CONTAINERS", defaultBackendContainers), ",") {
+		backend, name, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || backend == "" || name == "" {
+			fmt.Printf("Ignoring invalid BACKEND_CONTAINERS entry %q\n", entry)
+			continue
+		}
+		names[backend] = name
+	}
+	containers.Configure(socket, names)
+}
+
+// listContainers returns the state of the backends' containers
+func listContainers(c *gin.Context) {
+	list, err := containers.List(c.Request.Context())
+	if err != nil {
+		containerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"containers": list,
+	})
+}
+
+// controlContainer starts, stops or restarts a backend's container, so a
+// wedged database can be recycled without a shell on the host
+func controlContainer(c *gin.Context) {
+	backend, action := c.Param("backend"), c.Param("action")
+	container, err := containers.Do(c.Request.Context(), backend, action)
+	if err != nil {
+		containerError(c, err)
+		return
+	}
+	recordAudit(c, "", "container."+action, backend, container.Status)
+
+	// Reconnect as soon as the database is back instead of after the
+	// supervisor's next retry
+	if container.Running {
+		dbmanager.Recheck(backend)
+	}
+	c.JSON(http.StatusOK, container)
+}
+
+// containerError answers a failed container request
+func containerError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, containers.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "The Docker socket is not available; mount it or set DOCKER_SOCKET",
+		})
+	case errors.Is(err, containers.ErrUnknownBackend), errors.Is(err, containers.ErrUnknownAction):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Container request failed: " + err.Error(),
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Actions a backend's container can be put through
+const (
+	ActionStart   = "start"
+	ActionStop    = "stop"
+	ActionRestart = "restart"
+)
+
+// Seconds Docker waits for a container to stop before killing it
+const stopTimeout = 30
+
+// Longest error body read from the Docker API
+const maxErrorBytes = 4096
+
+var (
+	// ErrNotConfigured is returned when no Docker socket has been configured
+	ErrNotConfigured = errors.New("container control is not configured")
+
+	// ErrUnknownBackend is returned for a backend without a container
+	ErrUnknownBackend = errors.New("backend has no container")
+
+	// ErrUnknownAction is returned for anything but start, stop and restart
+	ErrUnknownAction = errors.New("unknown container action")
+)
+
+// Container is the state of a backend's container as Docker reports it
+type Container struct {
+	Backend string `json:"backend"`
+	Name    string `json:"container"`
+	// Docker's status, such as running, exited or restarting
+	Status  string `json:"status"`
+	Running bool   `json:"running"`
+	// Outcome of the container's health check, if it has one
+	Health     string     `json:"health,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// docker talks to the Docker Engine API over its Unix socket
+type docker struct {
+	client *http.Client
+	// Container names by backend
+	containers map[string]string
+}
+
+var (
+	// Client set by Configure, nil when unset
+	client *docker
+
+	// Guards client
+	mu sync.RWMutex
+)
+
+// Configure controls the containers named by backend, such as
+// {"mysql": "mysql"}, through the Docker socket at socketPath
+func Configure(socketPath string, containers map[string]string) {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	d := &docker{
+		client: &http.Client{
+			// Stopping waits for the container to shut down
+			Timeout: (stopTimeout + 30) * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+		containers: containers,
+	}
+
+	mu.Lock()
+	client = d
+	mu.Unlock()
+}
+
+// Configured reports whether a Docker socket has been configured
+func Configured() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return client != nil
+}
+
+// List returns the state of every backend's container. A container Docker
+// cannot inspect is listed with the error.
+func List(ctx context.Context) ([]Container, error) {
+	d, err := current()
+	if err != nil {
+		return nil, err
+	}
+	backends := make([]string, 0, len(d.containers))
+	for backend := range d.containers {
+		backends = append(backends, backend)
+	}
+	sort.Strings(backends)
+
+	list := make([]Container, 0, len(backends))
+	for _, backend := range backends {
+		container, err := d.inspect(ctx, backend)
+		if err != nil {
+			container.Error = err.Error()
+		}
+		list = append(list, container)
+	}
+	return list, nil
+}
+
+// Do starts, stops or restarts a backend's container and returns its state
+// afterwards. Starting a running container or stopping a stopped one does
+// nothing.
+func Do(ctx context.Context, backend string, action string) (Container, error) {
+	d, err := current()
+	if err != nil {
+		return Container{}, err
+	}
+	name, ok := d.containers[backend]
+	if !ok {
+		return Container{}, ErrUnknownBackend
+	}
+
+	path := "/containers/" + url.PathEscape(name) + "/" + action
+	switch action {
+	case ActionStart:
+	case ActionStop, ActionRestart:
+		path += fmt.Sprintf("?t=%d", stopTimeout)
+	default:
+		return Container{}, ErrUnknownAction
+	}
+
+	resp, err := d.request(ctx, http.MethodPost, path)
+	if err != nil {
+		return Container{}, err
+	}
+	defer resp.Body.Close()
+	// 304 means the container already was in the requested state
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotModified {
+		return Container{}, apiError(resp)
+	}
+	return d.inspect(ctx, backend)
+}
+
+// current returns the configured client
+func current() (*docker, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if client == nil {
+		return nil, ErrNotConfigured
+	}
+	return client, nil
+}
+
+// inspect reads a container's state from Docker
+func (d *docker) inspect(ctx context.Context, backend string) (Container, error) {
+	container := Container{Backend: backend, Name: d.containers[backend]}
+	resp, err := d.request(ctx, http.MethodGet, "/containers/"+url.PathEscape(container.Name)+"/json")
+	if err != nil {
+		return container, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return container, apiError(resp)
+	}
+
+	var body struct {
+		State struct {
+			Status     string
+			Running    bool
+			StartedAt  time.Time
+			FinishedAt time.Time
+			Health     *struct {
+				Status string
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return container, fmt.Errorf("invalid response from Docker: %w", err)
+	}
+	container.Status = body.State.Status
+	container.Running = body.State.Running
+	container.StartedAt = timeOrNil(body.State.StartedAt)
+	container.FinishedAt = timeOrNil(body.State.FinishedAt)
+	if body.State.Health != nil {
+		container.Health = body.State.Health.Status
+	}
+	return container, nil
+}
+
+// request sends a request to the Docker API
+func (d *docker) request(ctx context.Context, method string, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://docker"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach Docker: %w", err)
+	}
+	return resp, nil
+}
+
+// apiError turns an error response of the Docker API into an error
+func apiError(resp *http.Response) error {
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+	if json.Unmarshal(data, &body) != nil || body.Message == "" {
+		body.Message = resp.Status
+	}
+	return fmt.Errorf("docker: %s", body.Message)
+}
+
+// timeOrNil returns nil for Docker's zero times, such as the finish time of
+// a container that never stopped
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,124 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDocker serves the container endpoints of the Docker API on a Unix
+// socket, for a single container named mysql-1
+func fakeDocker(t *testing.T) (string, *[]string) {
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("Unix sockets are unavailable: %v", err)
+	}
+
+	var mu sync.Mutex
+	running := true
+	var calls []string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+
+		if !strings.HasPrefix(r.URL.Path, "/containers/mysql-1/") {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "No such container"}`))
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/containers/mysql-1/") {
+		case "json":
+			status, finished := "exited", "2024-05-01T10:00:00Z"
+			if running {
+				status, finished = "running", "0001-01-01T00:00:00Z"
+			}
+			w.Write([]byte(`{"State": {"Status": "` + status + `", "Running": ` + map[bool]string{true: "true", false: "false"}[running] +
+				`, "StartedAt": "2024-05-01T09:00:00Z", "FinishedAt": "` + finished + `", "Health": {"Status": "healthy"}}}`))
+		case "stop":
+			if !running {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			running = false
+			w.WriteHeader(http.StatusNoContent)
+		case "start", "restart":
+			running = true
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		mu.Lock()
+		client = nil
+		mu.Unlock()
+	})
+	return socket, &calls
+}
+
+func TestNotConfigured(t *testing.T) {
+	if _, err := List(context.Background()); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("expected ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestContainerLifecycle(t *testing.T) {
+	socket, calls := fakeDocker(t)
+	Configure(socket, map[string]string{"mysql": "mysql-1", "postgresql": "missing"})
+	ctx := context.Background()
+
+	list, err := List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Backend != "mysql" || !list[0].Running || list[0].Health != "healthy" || list[0].FinishedAt != nil {
+		t.Fatalf("expected a running mysql container first, got %+v", list)
+	}
+	if list[1].Error != "docker: No such container" {
+		t.Errorf("expected the missing container's error, got %+v", list[1])
+	}
+
+	container, err := Do(ctx, "mysql", ActionStop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.Running || container.Status != "exited" || container.FinishedAt == nil {
+		t.Errorf("expected the container stopped, got %+v", container)
+	}
+	// Stopping again is not an error
+	if _, err := Do(ctx, "mysql", ActionStop); err != nil {
+		t.Errorf("expected stopping a stopped container to succeed, got %v", err)
+	}
+	if container, err := Do(ctx, "mysql", ActionRestart); err != nil || !container.Running {
+		t.Errorf("expected the container running after a restart, got %+v, %v", container, err)
+	}
+
+	found := false
+	for _, call := range *calls {
+		if call == "POST /containers/mysql-1/restart?t=30" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a restart with a stop timeout, got %v", *calls)
+	}
+
+	if _, err := Do(ctx, "mysql", "pause"); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("expected ErrUnknownAction, got %v", err)
+	}
+	if _, err := Do(ctx, "sqlite", ActionStart); !errors.Is(err, ErrUnknownBackend) {
+		t.Errorf("expected ErrUnknownBackend, got %v", err)
+	}
+	if _, err := Do(ctx, "postgresql", ActionStart); err == nil || !strings.Contains(err.Error(), "No such container") {
+		t.Errorf("expected Docker's error for a missing container, got %v", err)
+	}
+}
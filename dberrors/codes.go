@@ -0,0 +1,258 @@
+package dberrors
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// Normalized error codes shared by all dialects
+const (
+	CodeSyntaxError         = "syntax_error"
+	CodeMissingTable        = "missing_table"
+	CodeMissingColumn       = "missing_column"
+	CodeMissingFunction     = "missing_function"
+	CodePermissionDenied    = "permission_denied"
+	CodeTimeout             = "timeout"
+	CodeConstraintViolation = "constraint_violation"
+	CodeConnectionError     = "connection_error"
+	CodeBlockedStatement    = "blocked_statement"
+	CodeNeedsConfirmation   = "confirmation_required"
+	CodeValidationError     = "validation_error"
+	CodeUnknown             = "unknown_error"
+)
+
+// Position is a 1-based location in the submitted SQL
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+var (
+	// ... near 'token' at line 3
+	mysqlNearRegex = regexp.MustCompile(`near '((?s).*)' at line (\d+)`)
+
+	// near "token": syntax error
+	sqliteNearRegex = regexp.MustCompile(`near "([^"]*)": syntax error`)
+)
+
+// Classify maps a driver error to a normalized error code and, where the
+// driver reports one, the position of the offending token in query
+func Classify(err error, query string) (string, *Position) {
+	if err == nil {
+		return "", nil
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return CodeTimeout, nil
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return classifyMySQL(mysqlErr, query)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return classifyPostgres(pqErr, query)
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return classifySQLite(sqliteErr, query)
+	}
+
+	if errors.Is(err, mysql.ErrInvalidConn) {
+		return CodeConnectionError, nil
+	}
+	return CodeUnknown, nil
+}
+
+// classifyMySQL maps MySQL error numbers
+func classifyMySQL(err *mysql.MySQLError, query string) (string, *Position) {
+	switch err.Number {
+	case 1064, 1149:
+		if m := mysqlNearRegex.FindStringSubmatch(err.Message); m != nil {
+			line, _ := strconv.Atoi(m[2])
+			return CodeSyntaxError, locateInLine(query, line, m[1])
+		}
+		return CodeSyntaxError, nil
+	case 1146:
+		return CodeMissingTable, nil
+	case 1054:
+		return CodeMissingColumn, nil
+	case 1305:
+		return CodeMissingFunction, nil
+	case 1044, 1045, 1142, 1143, 1227, 1370:
+		return CodePermissionDenied, nil
+	case 1205, 3024, 1317:
+		return CodeTimeout, nil
+	case 1062, 1048, 1451, 1452, 3819:
+		return CodeConstraintViolation, nil
+	case 2006, 2013:
+		return CodeConnectionError, nil
+	}
+	return CodeUnknown, nil
+}
+
+// classifyPostgres maps PostgreSQL SQLSTATE codes
+func classifyPostgres(err *pq.Error, query string) (string, *Position) {
+	code := string(err.Code)
+
+	var position *Position
+	if offset, convErr := strconv.Atoi(err.Position); convErr == nil {
+		position = positionFromCharOffset(query, offset)
+	}
+
+	switch {
+	case code == "42601":
+		return CodeSyntaxError, position
+	case code == "42P01":
+		return CodeMissingTable, position
+	case code == "42703":
+		return CodeMissingColumn, position
+	case code == "42883":
+		return CodeMissingFunction, position
+	case code == "42501":
+		return CodePermissionDenied, position
+	case code == "57014" || code == "55P03":
+		return CodeTimeout, position
+	case strings.HasPrefix(code, "23"):
+		return CodeConstraintViolation, position
+	case strings.HasPrefix(code, "08"):
+		return CodeConnectionError, position
+	}
+	return CodeUnknown, position
+}
+
+// classifySQLite maps SQLite result codes and messages
+func classifySQLite(err sqlite3.Error, query string) (string, *Position) {
+	message := err.Error()
+
+	switch err.Code {
+	case sqlite3.ErrConstraint:
+		return CodeConstraintViolation, nil
+	case sqlite3.ErrAuth, sqlite3.ErrPerm, sqlite3.ErrReadonly:
+		return CodePermissionDenied, nil
+	case sqlite3.ErrBusy, sqlite3.ErrLocked, sqlite3.ErrInterrupt:
+		return CodeTimeout, nil
+	}
+
+	switch {
+	case strings.Contains(message, "syntax error"), strings.Contains(message, "incomplete input"):
+		if m := sqliteNearRegex.FindStringSubmatch(message); m != nil {
+			if offset, ok := sqlvalidator.SQLiteErrorOffset(query, m[1]); ok {
+				return CodeSyntaxError, positionFromCharOffset(query, utf8.RuneCountInString(query[:offset])+1)
+			}
+		}
+		return CodeSyntaxError, nil
+	case strings.Contains(message, "no such table"):
+		return CodeMissingTable, nil
+	case strings.Contains(message, "no such column"):
+		return CodeMissingColumn, nil
+	case strings.Contains(message, "no such function"):
+		return CodeMissingFunction, nil
+	case strings.Contains(message, "not authorized"):
+		return CodePermissionDenied, nil
+	}
+	return CodeUnknown, nil
+}
+
+// positionFromCharOffset converts a 1-based character offset into a line and column
+func positionFromCharOffset(query string, offset int) *Position {
+	if offset < 1 {
+		return nil
+	}
+
+	line, column := 1, 1
+	chars := 1
+	for _, r := range query {
+		if chars == offset {
+			return &Position{Line: line, Column: column}
+		}
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+		chars++
+	}
+	return &Position{Line: line, Column: column}
+}
+
+// locateInLine finds the text MySQL reports as "near" on a given line
+func locateInLine(query string, line int, near string) *Position {
+	lines := strings.Split(query, "\n")
+	if line < 1 || line > len(lines) {
+		return nil
+	}
+
+	// MySQL reports the rest of the statement; the first word is enough to locate it
+	fields := strings.Fields(near)
+	if len(fields) == 0 {
+		// An empty "near" means the statement ended unexpectedly
+		return &Position{Line: line, Column: utf8.RuneCountInString(lines[line-1]) + 1}
+	}
+
+	if index := strings.Index(lines[line-1], fields[0]); index >= 0 {
+		return &Position{Line: line, Column: utf8.RuneCountInString(lines[line-1][:index]) + 1}
+	}
+	return &Position{Line: line, Column: 1}
+}
+
+// MapPosition maps a position in executed, the SQL that ran after the
+// server rewrote query, back to the same text in query. Text before and
+// after the part the rewrites changed keeps its place; a position inside
+// rewritten text maps to where the rewrites start in query.
+func MapPosition(position *Position, executed string, query string) *Position {
+	if position == nil || executed == query {
+		return position
+	}
+	from, to := []rune(executed), []rune(query)
+	offset := charOffset(from, position)
+
+	prefix := 0
+	for prefix < len(from) && prefix < len(to) && from[prefix] == to[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(from)-prefix && suffix < len(to)-prefix && from[len(from)-1-suffix] == to[len(to)-1-suffix] {
+		suffix++
+	}
+
+	switch {
+	case offset < prefix:
+	case offset >= len(from)-suffix:
+		offset += len(to) - len(from)
+	default:
+		offset = prefix
+	}
+	return positionFromCharOffset(query, offset+1)
+}
+
+// charOffset converts a line and column into a 0-based character offset
+func charOffset(query []rune, position *Position) int {
+	line, column := 1, 1
+	for i, r := range query {
+		if line > position.Line || line == position.Line && column == position.Column {
+			return i
+		}
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return len(query)
+}
@@ -0,0 +1,75 @@
+package dberrors
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestClassifyPostgresSyntaxPosition(t *testing.T) {
+	err := &pq.Error{Code: "42601", Position: "17"}
+	code, position := Classify(err, "SELECT *\nFROM x WHER id = 1")
+	if code != CodeSyntaxError {
+		t.Fatalf("expected %s, got %s", CodeSyntaxError, code)
+	}
+	if position == nil || position.Line != 2 || position.Column != 8 {
+		t.Errorf("expected line 2 column 8, got %+v", position)
+	}
+}
+
+func TestClassifyMySQLSyntaxNear(t *testing.T) {
+	err := &mysql.MySQLError{
+		Number:  1064,
+		Message: "You have an error in your SQL syntax; check the manual that corresponds to your MySQL server version for the right syntax to use near 'FORM products' at line 2",
+	}
+	code, position := Classify(err, "SELECT *\n  FORM products")
+	if code != CodeSyntaxError {
+		t.Fatalf("expected %s, got %s", CodeSyntaxError, code)
+	}
+	if position == nil || position.Line != 2 || position.Column != 3 {
+		t.Errorf("expected line 2 column 3, got %+v", position)
+	}
+}
+
+func TestClassifySQLiteSyntaxNearTheFailingToken(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	query := "SELECT a\nFROM t WHERE a = 1 1"
+	_, err = db.Exec(query)
+	code, position := Classify(err, query)
+	if code != CodeSyntaxError {
+		t.Fatalf("expected %s, got %s", CodeSyntaxError, code)
+	}
+	if position == nil || position.Line != 2 || position.Column != 20 {
+		t.Errorf("expected the second 1 at line 2 column 20, got %+v", position)
+	}
+}
+
+func TestMapPositionThroughRewrites(t *testing.T) {
+	query := "SELECT *\nFROM x WHER id = 1"
+	tests := []struct {
+		name     string
+		executed string
+		position Position
+		want     Position
+	}{
+		{"appended limit", query + " LIMIT 100", Position{Line: 2, Column: 8}, Position{Line: 2, Column: 8}},
+		{"inserted hint", "SELECT /*+ MAX_EXECUTION_TIME(5000) */ *\nFROM x WHER id = 1", Position{Line: 2, Column: 8}, Position{Line: 2, Column: 8}},
+		{"renamed table", "SELECT *\nFROM s_1f2e_x WHER id = 1", Position{Line: 2, Column: 15}, Position{Line: 2, Column: 8}},
+		{"inside the rewrite", "SELECT *\nFROM s_1f2e_x WHER id = 1", Position{Line: 2, Column: 6}, Position{Line: 2, Column: 6}},
+	}
+	for _, tt := range tests {
+		if got := MapPosition(&tt.position, tt.executed, query); got == nil || *got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+	if MapPosition(nil, query+" LIMIT 100", query) != nil {
+		t.Error("expected no position to stay missing")
+	}
+}
@@ -0,0 +1,258 @@
+package dberrors
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Constraint kinds reported in explanations
+const (
+	ForeignKey = "foreign_key"
+	Unique     = "unique"
+	NotNull    = "not_null"
+	Check      = "check"
+)
+
+// Explanation is a human-readable description of a constraint violation
+type Explanation struct {
+	Kind       string `json:"kind"`
+	Constraint string `json:"constraint,omitempty"`
+	Table      string `json:"table,omitempty"`
+	Column     string `json:"column,omitempty"`
+	Referenced string `json:"referenced,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+var (
+	// (`db`.`child`, CONSTRAINT `fk` FOREIGN KEY (`col`) REFERENCES `parent` (`id`))
+	mysqlForeignKeyRegex = regexp.MustCompile("\\(`?[^`]*`?\\.`([^`]+)`, CONSTRAINT `([^`]+)` FOREIGN KEY \\(`([^`]+)`\\) REFERENCES `([^`]+)`")
+
+	// Duplicate entry 'x' for key 'table.key'
+	mysqlDuplicateRegex = regexp.MustCompile(`Duplicate entry '(.*)' for key '([^']+)'`)
+
+	// Column 'x' cannot be null
+	mysqlNotNullRegex = regexp.MustCompile(`Column '([^']+)' cannot be null`)
+
+	// Check constraint 'x' is violated.
+	mysqlCheckRegex = regexp.MustCompile(`Check constraint '([^']+)' is violated`)
+
+	// Key (col)=(value) is not present in table "parent".
+	postgresReferencedRegex = regexp.MustCompile(`is (?:not present in|still referenced from) table "([^"]+)"`)
+
+	// Key (col)=(value) already exists.
+	postgresKeyRegex = regexp.MustCompile(`Key \((.+)\)=\((.*)\) already exists`)
+
+	// UNIQUE constraint failed: table.column
+	sqliteTargetRegex = regexp.MustCompile(`constraint failed: ([\w]+)\.([\w]+)`)
+
+	// CHECK constraint failed: name
+	sqliteCheckRegex = regexp.MustCompile(`CHECK constraint failed: (.+)$`)
+)
+
+// ExplainConstraint returns an explanation for constraint violations reported
+// by the MySQL, PostgreSQL and SQLite drivers, or nil for other errors
+func ExplainConstraint(err error) *Explanation {
+	if err == nil {
+		return nil
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return explainMySQL(mysqlErr)
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return explainPostgres(pqErr)
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return explainSQLite(sqliteErr)
+	}
+
+	return nil
+}
+
+// explainMySQL explains MySQL constraint error numbers
+func explainMySQL(err *mysql.MySQLError) *Explanation {
+	switch err.Number {
+	case 1452, 1451:
+		e := &Explanation{Kind: ForeignKey}
+		if m := mysqlForeignKeyRegex.FindStringSubmatch(err.Message); m != nil {
+			e.Table, e.Constraint, e.Column, e.Referenced = m[1], m[2], m[3], m[4]
+		}
+		if err.Number == 1452 {
+			return foreignKeyMissingParent(e)
+		}
+		return foreignKeyHasChildren(e)
+
+	case 1062:
+		e := &Explanation{Kind: Unique}
+		if m := mysqlDuplicateRegex.FindStringSubmatch(err.Message); m != nil {
+			e.Constraint = m[2]
+			if parts := strings.SplitN(m[2], ".", 2); len(parts) == 2 {
+				e.Table, e.Constraint = parts[0], parts[1]
+			}
+			return uniqueViolation(e, m[1])
+		}
+		return uniqueViolation(e, "")
+
+	case 1048:
+		e := &Explanation{Kind: NotNull}
+		if m := mysqlNotNullRegex.FindStringSubmatch(err.Message); m != nil {
+			e.Column = m[1]
+		}
+		return notNullViolation(e)
+
+	case 3819:
+		e := &Explanation{Kind: Check}
+		if m := mysqlCheckRegex.FindStringSubmatch(err.Message); m != nil {
+			e.Constraint = m[1]
+		}
+		return checkViolation(e)
+	}
+	return nil
+}
+
+// explainPostgres explains PostgreSQL integrity constraint SQLSTATE codes
+func explainPostgres(err *pq.Error) *Explanation {
+	e := &Explanation{
+		Constraint: err.Constraint,
+		Table:      err.Table,
+		Column:     err.Column,
+	}
+
+	switch string(err.Code) {
+	case "23503":
+		e.Kind = ForeignKey
+		m := postgresReferencedRegex.FindStringSubmatch(err.Detail)
+		if strings.Contains(err.Detail, "still referenced") {
+			// The referencing table is only named in the detail
+			if m != nil {
+				e.Table = m[1]
+			}
+			return foreignKeyHasChildren(e)
+		}
+		if m != nil {
+			e.Referenced = m[1]
+		}
+		return foreignKeyMissingParent(e)
+	case "23505":
+		e.Kind = Unique
+		if m := postgresKeyRegex.FindStringSubmatch(err.Detail); m != nil {
+			e.Column = m[1]
+			return uniqueViolation(e, m[2])
+		}
+		return uniqueViolation(e, "")
+	case "23502":
+		e.Kind = NotNull
+		return notNullViolation(e)
+	case "23514":
+		e.Kind = Check
+		return checkViolation(e)
+	}
+	return nil
+}
+
+// explainSQLite explains SQLite extended constraint error codes
+func explainSQLite(err sqlite3.Error) *Explanation {
+	if err.Code != sqlite3.ErrConstraint {
+		return nil
+	}
+
+	e := &Explanation{}
+	if m := sqliteTargetRegex.FindStringSubmatch(err.Error()); m != nil {
+		e.Table, e.Column = m[1], m[2]
+	}
+
+	switch err.ExtendedCode {
+	case sqlite3.ErrConstraintForeignKey:
+		// SQLite does not report which foreign key failed
+		e.Kind = ForeignKey
+		return foreignKeyMissingParent(e)
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		e.Kind = Unique
+		return uniqueViolation(e, "")
+	case sqlite3.ErrConstraintNotNull:
+		e.Kind = NotNull
+		return notNullViolation(e)
+	case sqlite3.ErrConstraintCheck:
+		e.Kind = Check
+		if m := sqliteCheckRegex.FindStringSubmatch(err.Error()); m != nil {
+			e.Constraint = m[1]
+		}
+		return checkViolation(e)
+	}
+	return nil
+}
+
+// foreignKeyMissingParent describes an insert or update referencing a missing parent row
+func foreignKeyMissingParent(e *Explanation) *Explanation {
+	e.Message = "The row references a parent row that does not exist"
+	if e.Referenced != "" {
+		e.Message += fmt.Sprintf(" in table %q", e.Referenced)
+	}
+	if e.Column != "" {
+		e.Message += fmt.Sprintf(" (column %q)", e.Column)
+	}
+	e.Message += "."
+	e.Suggestion = "Insert the referenced parent row first, or use a value that already exists in the referenced table."
+	return e
+}
+
+// foreignKeyHasChildren describes a delete or update of a parent row still referenced by children
+func foreignKeyHasChildren(e *Explanation) *Explanation {
+	e.Message = "The row is still referenced by rows"
+	if e.Table != "" {
+		e.Message += fmt.Sprintf(" in table %q", e.Table)
+	}
+	e.Message += "."
+	e.Suggestion = "Delete or update the referencing rows first, or define the foreign key with ON DELETE CASCADE."
+	return e
+}
+
+// uniqueViolation describes a duplicate value in a unique column or key
+func uniqueViolation(e *Explanation, value string) *Explanation {
+	e.Message = "The value already exists"
+	if e.Column != "" {
+		e.Message += fmt.Sprintf(" in column %q", e.Column)
+	} else if e.Constraint != "" {
+		e.Message += fmt.Sprintf(" for unique key %q", e.Constraint)
+	}
+	if value != "" {
+		e.Message += ": " + value
+	}
+	e.Message = strings.TrimSuffix(e.Message, ".") + "."
+	e.Suggestion = "Use a different value, update the existing row instead, or use an upsert (INSERT ... ON CONFLICT / ON DUPLICATE KEY UPDATE)."
+	return e
+}
+
+// notNullViolation describes a missing value for a NOT NULL column
+func notNullViolation(e *Explanation) *Explanation {
+	e.Message = "A required column was left empty"
+	if e.Column != "" {
+		e.Message = fmt.Sprintf("Column %q does not accept NULL values", e.Column)
+	}
+	e.Message += "."
+	e.Suggestion = "Provide a value for the column in the statement or give the column a DEFAULT."
+	return e
+}
+
+// checkViolation describes a value rejected by a CHECK constraint
+func checkViolation(e *Explanation) *Explanation {
+	e.Message = "A value does not satisfy a CHECK constraint"
+	if e.Constraint != "" {
+		e.Message = fmt.Sprintf("A value does not satisfy the CHECK constraint %q", e.Constraint)
+	}
+	e.Message += "."
+	e.Suggestion = "Inspect the constraint definition and adjust the value so the condition holds."
+	return e
+}
@@ -0,0 +1,44 @@
+package dberrors
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestExplainMySQLForeignKey(t *testing.T) {
+	err := &mysql.MySQLError{
+		Number:  1452,
+		Message: "Cannot add or update a child row: a foreign key constraint fails (`testdb`.`orders`, CONSTRAINT `fk_orders_product` FOREIGN KEY (`product_id`) REFERENCES `products` (`id`))",
+	}
+
+	e := ExplainConstraint(err)
+	if e == nil || e.Kind != ForeignKey {
+		t.Fatalf("expected foreign key explanation, got %+v", e)
+	}
+	if e.Table != "orders" || e.Constraint != "fk_orders_product" || e.Column != "product_id" || e.Referenced != "products" {
+		t.Errorf("unexpected explanation fields: %+v", e)
+	}
+}
+
+func TestExplainPostgresUnique(t *testing.T) {
+	err := &pq.Error{
+		Code:       "23505",
+		Table:      "customers",
+		Constraint: "customers_email_key",
+		Detail:     "Key (email)=(john.doe@example.com) already exists.",
+	}
+
+	e := ExplainConstraint(err)
+	if e == nil || e.Kind != Unique || e.Column != "email" {
+		t.Fatalf("expected unique explanation on email, got %+v", e)
+	}
+}
+
+func TestExplainUnrelatedError(t *testing.T) {
+	if e := ExplainConstraint(errors.New("connection refused")); e != nil {
+		t.Errorf("expected no explanation, got %+v", e)
+	}
+}
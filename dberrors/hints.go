@@ -0,0 +1,219 @@
+package dberrors
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// Hint kinds
+const (
+	HintGroupBy         = "group_by"
+	HintAmbiguousColumn = "ambiguous_column"
+	HintNullComparison  = "null_comparison"
+	HintQuoteStyle      = "quote_style"
+)
+
+// Hint is an actionable suggestion for a common mistake behind an error
+type Hint struct {
+	Kind       string `json:"kind"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+// hintMatcher recognizes one common mistake from an error message, the
+// query and its dialect, and returns nil when it does not apply
+type hintMatcher func(message string, query string, dialect string) *Hint
+
+// hintMatchers are tried in order; each contributes at most one hint
+var hintMatchers = []hintMatcher{
+	groupByHint,
+	ambiguousColumnHint,
+	quoteStyleHint,
+	nullComparisonHint,
+}
+
+var (
+	// column "x" must appear in the GROUP BY clause or be used in an aggregate function
+	postgresGroupByRegex = regexp.MustCompile(`column "([^"]+)" must appear in the GROUP BY clause`)
+
+	// ... contains nonaggregated column 'db.table.x' ...
+	mysqlGroupByRegex = regexp.MustCompile(`nonaggregated column '([^']+)'`)
+
+	// column reference "x" is ambiguous
+	postgresAmbiguousRegex = regexp.MustCompile(`column reference "([^"]+)" is ambiguous`)
+
+	// Column 'x' in field list is ambiguous
+	mysqlAmbiguousRegex = regexp.MustCompile(`Column '([^']+)' in [\w ]+ is ambiguous`)
+
+	// ambiguous column name: x
+	sqliteAmbiguousRegex = regexp.MustCompile(`ambiguous column name: (\S+)`)
+
+	// column "x" does not exist / no such column: x
+	missingColumnRegex = regexp.MustCompile(`column "([^"]+)" does not exist|no such column: (\S+)`)
+
+	// FROM 'table', a string where MySQL expects a table name
+	mysqlQuotedTableRegex = regexp.MustCompile(`(?i)\b(?:FROM|JOIN|INTO|UPDATE|TABLE)\s+'([^']+)'`)
+
+	// = NULL, <> NULL and != NULL, with the operator kept to tell them apart
+	nullComparisonRegex = regexp.MustCompile(`(?i)(<>|!=|=)\s*NULL\b`)
+
+	// Keywords that start the clause a comparison belongs to
+	clauseRegex = regexp.MustCompile(`(?i)\b(SET|WHERE|HAVING|ON|WHEN|SELECT|VALUES)\b`)
+)
+
+// Hints returns suggestions for the common mistakes behind a failed query.
+// For driver errors the dialect may be left empty; the error tells it. A
+// nil error only runs the matchers that look at the query alone, such as
+// comparisons with = NULL, which succeed but never match a row.
+func Hints(err error, query string, dialect string) []Hint {
+	message := ""
+	if err != nil {
+		message = err.Error()
+		if dialect == "" {
+			dialect = errorDialect(err)
+		}
+	}
+
+	hints := []Hint{}
+	for _, match := range hintMatchers {
+		if hint := match(message, query, dialect); hint != nil {
+			hints = append(hints, *hint)
+		}
+	}
+	return hints
+}
+
+// errorDialect tells the dialect of a driver error, or "" for other errors
+func errorDialect(err error) string {
+	var mysqlErr *mysql.MySQLError
+	var pqErr *pq.Error
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.As(err, &mysqlErr):
+		return "mysql"
+	case errors.As(err, &pqErr):
+		return "postgresql"
+	case errors.As(err, &sqliteErr):
+		return "sqlite"
+	}
+	return ""
+}
+
+// groupByHint explains selecting a column that is neither grouped nor aggregated
+func groupByHint(message string, query string, dialect string) *Hint {
+	column := ""
+	if m := postgresGroupByRegex.FindStringSubmatch(message); m != nil {
+		column = m[1]
+	} else if m := mysqlGroupByRegex.FindStringSubmatch(message); m != nil {
+		// MySQL names the column as database.table.column
+		column = m[1]
+		if parts := strings.Split(column, "."); len(parts) == 3 {
+			column = parts[1] + "." + parts[2]
+		}
+	}
+	if column == "" {
+		return nil
+	}
+
+	return &Hint{
+		Kind:       HintGroupBy,
+		Message:    fmt.Sprintf("%s is selected but neither grouped nor aggregated, so each group could have several values for it.", column),
+		Suggestion: fmt.Sprintf("Add %s to the GROUP BY clause, or wrap it in an aggregate such as MAX(%s).", column, column),
+	}
+}
+
+// ambiguousColumnHint explains a column name that several joined tables have
+func ambiguousColumnHint(message string, query string, dialect string) *Hint {
+	column := ""
+	for _, regex := range []*regexp.Regexp{postgresAmbiguousRegex, mysqlAmbiguousRegex, sqliteAmbiguousRegex} {
+		if m := regex.FindStringSubmatch(message); m != nil {
+			column = m[1]
+			break
+		}
+	}
+	if column == "" {
+		return nil
+	}
+
+	return &Hint{
+		Kind:       HintAmbiguousColumn,
+		Message:    fmt.Sprintf("More than one table in the query has a column named %s.", column),
+		Suggestion: fmt.Sprintf("Qualify the column with the name or alias of the table it should come from, as in alias.%s.", column),
+	}
+}
+
+// quoteStyleHint explains strings and identifiers quoted the way another
+// dialect quotes them
+func quoteStyleHint(message string, query string, dialect string) *Hint {
+	if message == "" {
+		return nil
+	}
+	// Comments and string contents are blanked out, at the same offsets
+	code := sqlvalidator.MaskSQL(query, dialect)
+
+	switch dialect {
+	case "postgresql", "sqlite":
+		// "text" names a column; the value was meant as a string
+		if m := missingColumnRegex.FindStringSubmatch(message); m != nil {
+			name := m[1] + m[2]
+			if strings.Contains(code, `"`+name+`"`) {
+				return &Hint{
+					Kind:       HintQuoteStyle,
+					Message:    fmt.Sprintf("Double quotes make %q a column name, not a string.", name),
+					Suggestion: fmt.Sprintf("Write string values in single quotes, as in '%s'.", name),
+				}
+			}
+		}
+		// SQLite accepts MySQL's backticks, PostgreSQL does not
+		if dialect == "postgresql" && strings.Contains(code, "`") {
+			return &Hint{
+				Kind:       HintQuoteStyle,
+				Message:    "PostgreSQL does not quote identifiers with backticks.",
+				Suggestion: `Quote identifiers with double quotes, as in "order", and strings with single quotes.`,
+			}
+		}
+	case "mysql":
+		// The quoted name is read from the query, where it is not blanked
+		if m := mysqlQuotedTableRegex.FindStringSubmatchIndex(code); m != nil {
+			name := query[m[2]:m[3]]
+			return &Hint{
+				Kind:       HintQuoteStyle,
+				Message:    fmt.Sprintf("Single quotes make '%s' a string, not a table name.", name),
+				Suggestion: fmt.Sprintf("Quote identifiers with backticks, as in `%s`, or leave them unquoted.", name),
+			}
+		}
+	}
+	return nil
+}
+
+// nullComparisonHint explains comparisons with NULL, which are never true
+func nullComparisonHint(message string, query string, dialect string) *Hint {
+	code := sqlvalidator.MaskSQL(query, dialect)
+	for _, loc := range nullComparisonRegex.FindAllStringSubmatchIndex(code, -1) {
+		// SET column = NULL is an assignment, not a comparison
+		clauses := clauseRegex.FindAllString(code[:loc[0]], -1)
+		if len(clauses) > 0 && strings.EqualFold(clauses[len(clauses)-1], "SET") {
+			continue
+		}
+
+		operator := code[loc[2]:loc[3]]
+		replacement := "IS NULL"
+		if operator != "=" {
+			replacement = "IS NOT NULL"
+		}
+		return &Hint{
+			Kind:       HintNullComparison,
+			Message:    fmt.Sprintf("Comparing with %s NULL is never true, because NULL stands for an unknown value.", operator),
+			Suggestion: fmt.Sprintf("Use %s instead of %s NULL.", replacement, operator),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package dberrors
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestHints(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		query      string
+		dialect    string
+		kind       string
+		suggestion string
+	}{
+		{
+			name:       "postgres group by",
+			err:        &pq.Error{Code: "42803", Message: `column "u.name" must appear in the GROUP BY clause or be used in an aggregate function`},
+			query:      "SELECT u.name, COUNT(*) FROM users u",
+			kind:       HintGroupBy,
+			suggestion: "Add u.name to the GROUP BY clause",
+		},
+		{
+			name:       "mysql group by",
+			err:        &mysql.MySQLError{Number: 1055, Message: "Expression #1 of SELECT list is not in GROUP BY clause and contains nonaggregated column 'testdb.products.name' which is not functionally dependent on columns in GROUP BY clause"},
+			query:      "SELECT name, category FROM products GROUP BY category",
+			kind:       HintGroupBy,
+			suggestion: "MAX(products.name)",
+		},
+		{
+			name:       "postgres ambiguous column",
+			err:        &pq.Error{Code: "42702", Message: `column reference "id" is ambiguous`},
+			query:      "SELECT id FROM a JOIN b ON a.id = b.a_id",
+			kind:       HintAmbiguousColumn,
+			suggestion: "alias.id",
+		},
+		{
+			name:       "mysql ambiguous column",
+			err:        &mysql.MySQLError{Number: 1052, Message: "Column 'id' in field list is ambiguous"},
+			query:      "SELECT id FROM a JOIN b ON a.id = b.a_id",
+			kind:       HintAmbiguousColumn,
+			suggestion: "alias.id",
+		},
+		{
+			name:       "sqlite ambiguous column message",
+			err:        errors.New("ambiguous column name: id"),
+			query:      "SELECT id FROM a JOIN b ON a.id = b.a_id",
+			dialect:    "sqlite",
+			kind:       HintAmbiguousColumn,
+			suggestion: "alias.id",
+		},
+		{
+			name:       "postgres double quoted string",
+			err:        &pq.Error{Code: "42703", Message: `column "Electronics" does not exist`},
+			query:      `SELECT * FROM products WHERE category = "Electronics"`,
+			kind:       HintQuoteStyle,
+			suggestion: "'Electronics'",
+		},
+		{
+			name:       "postgres backticks",
+			err:        &pq.Error{Code: "42601", Message: "syntax error at or near \"`\""},
+			query:      "SELECT `name` FROM customers",
+			kind:       HintQuoteStyle,
+			suggestion: "double quotes",
+		},
+		{
+			name:       "mysql single quoted table",
+			err:        &mysql.MySQLError{Number: 1064, Message: "You have an error in your SQL syntax; check the manual that corresponds to your MySQL server version for the right syntax to use near ''products'' at line 1"},
+			query:      "SELECT * FROM 'products'",
+			kind:       HintQuoteStyle,
+			suggestion: "`products`",
+		},
+		{
+			name:       "mysql backticks are fine",
+			err:        &mysql.MySQLError{Number: 1146, Message: "Table 'testdb.missing' doesn't exist"},
+			query:      "SELECT `name` FROM missing",
+			kind:       "",
+			suggestion: "",
+		},
+		{
+			name:       "null comparison",
+			err:        &pq.Error{Code: "42P01", Message: `relation "missing" does not exist`},
+			query:      "SELECT * FROM missing WHERE deleted_at <> NULL",
+			kind:       HintNullComparison,
+			suggestion: "Use IS NOT NULL instead of <> NULL.",
+		},
+		{
+			name:       "null comparison without error",
+			query:      "UPDATE users SET email = NULL WHERE phone = NULL",
+			dialect:    "mysql",
+			kind:       HintNullComparison,
+			suggestion: "Use IS NULL instead of = NULL.",
+		},
+		{
+			name:    "null assignment and string",
+			query:   "UPDATE users SET email = NULL, name = 'x = NULL' WHERE id = 1 -- = NULL",
+			dialect: "mysql",
+		},
+		{
+			name:       "mysql escaped quote and hash comment",
+			query:      "SELECT * FROM notes WHERE body = 'it\\'s' AND deleted_at = NULL # <> NULL",
+			dialect:    "mysql",
+			kind:       HintNullComparison,
+			suggestion: "Use IS NULL instead of = NULL.",
+		},
+		{
+			name:    "postgres escape string",
+			query:   "SELECT E'it\\'s = NULL' AS note",
+			dialect: "postgresql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := Hints(tt.err, tt.query, tt.dialect)
+			if tt.kind == "" {
+				if len(hints) > 0 {
+					t.Errorf("expected no hints, got %+v", hints)
+				}
+				return
+			}
+			if len(hints) != 1 || hints[0].Kind != tt.kind {
+				t.Fatalf("expected one %s hint, got %+v", tt.kind, hints)
+			}
+			if !strings.Contains(hints[0].Suggestion, tt.suggestion) {
+				t.Errorf("expected the suggestion to contain %q, got %q", tt.suggestion, hints[0].Suggestion)
+			}
+		})
+	}
+}
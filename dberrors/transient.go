@@ -0,0 +1,53 @@
+package dberrors
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"syscall"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// MySQL error number of a transaction rolled back to resolve a deadlock
+const mysqlDeadlock = 1213
+
+// PostgreSQL codes of serialization failures and deadlock victims
+const (
+	postgresSerializationFailure = "40001"
+	postgresDeadlockDetected     = "40P01"
+)
+
+// IsTransient reports whether an error is likely to go away when the same
+// statement runs again: a dropped or reset connection, a deadlock the
+// database resolved by aborting the statement, a serialization failure or
+// a busy SQLite database. Timeouts are not transient, since running the
+// statement again would take as long.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDeadlock
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 holds the connection exceptions
+		return pqErr.Code == postgresSerializationFailure || pqErr.Code == postgresDeadlockDetected || pqErr.Code.Class() == "08"
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package dberrors
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+func TestIsTransient(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"bad connection", driver.ErrBadConn, true},
+		{"invalid mysql connection", mysql.ErrInvalidConn, true},
+		{"connection reset", fmt.Errorf("query failed: %w", reset), true},
+		{"mysql deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"mysql syntax error", &mysql.MySQLError{Number: 1064}, false},
+		{"postgres serialization failure", &pq.Error{Code: "40001"}, true},
+		{"postgres deadlock", &pq.Error{Code: "40P01"}, true},
+		{"postgres connection failure", &pq.Error{Code: "08006"}, true},
+		{"postgres unique violation", &pq.Error{Code: "23505"}, false},
+		{"sqlite busy", sqlite3.Error{Code: sqlite3.ErrBusy}, true},
+		{"sqlite constraint", sqlite3.Error{Code: sqlite3.ErrConstraint}, false},
+		{"timeout", context.DeadlineExceeded, false},
+		{"other", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransient(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
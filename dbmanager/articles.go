@@ -0,0 +1,193 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Articles of the full-text search dataset
+var articles = []struct {
+	title       string
+	author      string
+	topic       string
+	publishedAt string
+	body        string
+}{
+	{"Choosing the right index", "Maria Lopez", "Performance", "2024-01-08",
+		"A B-tree index speeds up equality and range lookups on the indexed columns. Put the most selective column first in a composite index, and remember that every index slows down inserts and updates a little."},
+	{"Reading query plans", "Tom Becker", "Performance", "2024-01-15",
+		"EXPLAIN shows how the database intends to run a query. Look for sequential scans over large tables, nested loops with many iterations and sorts that spill to disk."},
+	{"Window functions explained", "Aisha Khan", "Querying", "2024-01-22",
+		"Window functions such as ROW_NUMBER, RANK and running SUM compute values across related rows without collapsing them the way GROUP BY does. The OVER clause defines the partition and the ordering."},
+	{"Common table expressions", "Maria Lopez", "Querying", "2024-02-05",
+		"A WITH clause names a subquery so a long query reads top to bottom. Recursive common table expressions walk hierarchies such as org charts and category trees."},
+	{"Transactions and isolation levels", "James Carter", "Concurrency", "2024-02-12",
+		"A transaction groups statements so they succeed or fail together. Isolation levels decide which changes of concurrent transactions are visible, trading consistency for throughput."},
+	{"Avoiding deadlocks", "James Carter", "Concurrency", "2024-02-26",
+		"Deadlocks happen when two transactions wait for locks the other holds. Touch rows in a consistent order, keep transactions short and retry the one the database aborts."},
+	{"Normalization in practice", "Aisha Khan", "Design", "2024-03-04",
+		"Normal forms remove duplicated data so each fact is stored once. Denormalize deliberately, for example with a summary table, when reads vastly outnumber writes."},
+	{"Designing primary keys", "Tom Becker", "Design", "2024-03-18",
+		"Surrogate keys such as auto-increment integers or UUIDs never change, while natural keys carry meaning but may. Whichever you choose, every table needs one."},
+	{"Full-text search basics", "Nina Petrova", "Search", "2024-04-01",
+		"Full-text search splits documents into words, reduces them to stems and ranks matches by relevance. It finds running when you search for run, which LIKE patterns cannot do."},
+	{"Ranking search results", "Nina Petrova", "Search", "2024-04-15",
+		"Relevance ranking weighs how often a term appears and how rare it is across all documents. Matches in a title usually deserve more weight than matches in the body."},
+	{"Backups you can restore", "Lucas Silva", "Operations", "2024-05-06",
+		"A backup only counts once you have restored it. Combine full dumps with point-in-time recovery from the transaction log, and test the restore regularly."},
+	{"Monitoring slow queries", "Lucas Silva", "Operations", "2024-05-20",
+		"The slow query log records statements above a time threshold. Group them by normalized text to find the queries that cost the most in total, then add the missing index."},
+}
+
+// Table definitions of articles per dialect. PostgreSQL keeps a weighted
+// tsvector of the title and body in a generated column; MySQL indexes both
+// for MATCH ... AGAINST.
+var articlesTables = map[string]string{
+	"sqlite": `CREATE TABLE IF NOT EXISTS articles (
+		id INTEGER PRIMARY KEY,
+		title TEXT NOT NULL,
+		author TEXT NOT NULL,
+		topic TEXT NOT NULL,
+		published_at TEXT NOT NULL,
+		body TEXT NOT NULL
+	)`,
+	"mysql": `CREATE TABLE IF NOT EXISTS articles (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		title VARCHAR(200) NOT NULL,
+		author VARCHAR(100) NOT NULL,
+		topic VARCHAR(50) NOT NULL,
+		published_at DATE NOT NULL,
+		body TEXT NOT NULL,
+		FULLTEXT INDEX ft_articles_title_body (title, body)
+	)`,
+	"postgresql": `CREATE TABLE IF NOT EXISTS articles (
+		id SERIAL PRIMARY KEY,
+		title VARCHAR(200) NOT NULL,
+		author VARCHAR(100) NOT NULL,
+		topic VARCHAR(50) NOT NULL,
+		published_at DATE NOT NULL,
+		body TEXT NOT NULL,
+		search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', body), 'B')
+		) STORED
+	)`,
+}
+
+// Indexes created separately where CREATE TABLE cannot declare them
+var articlesIndexes = map[string]string{
+	"postgresql": `CREATE INDEX IF NOT EXISTS idx_articles_search ON articles USING GIN (search_vector)`,
+}
+
+// The SQLite full-text index of articles: an external content table over
+// articles, kept in sync by triggers. FTS5 needs the sqlite_fts5 build tag;
+// without it the index falls back to FTS4, which is always compiled in.
+var articlesFTS = []struct {
+	module   string
+	create   string
+	triggers []string
+}{
+	{"fts5", `CREATE VIRTUAL TABLE IF NOT EXISTS articles_fts USING fts5(title, body, content='articles', content_rowid='id')`, []string{
+		`CREATE TRIGGER IF NOT EXISTS articles_fts_insert AFTER INSERT ON articles BEGIN
+			INSERT INTO articles_fts (rowid, title, body) VALUES (new.id, new.title, new.body);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS articles_fts_delete AFTER DELETE ON articles BEGIN
+			INSERT INTO articles_fts (articles_fts, rowid, title, body) VALUES ('delete', old.id, old.title, old.body);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS articles_fts_update AFTER UPDATE ON articles BEGIN
+			INSERT INTO articles_fts (articles_fts, rowid, title, body) VALUES ('delete', old.id, old.title, old.body);
+			INSERT INTO articles_fts (rowid, title, body) VALUES (new.id, new.title, new.body);
+		END`,
+	}},
+	{"fts4", `CREATE VIRTUAL TABLE IF NOT EXISTS articles_fts USING fts4(content='articles', title, body)`, []string{
+		`CREATE TRIGGER IF NOT EXISTS articles_fts_insert AFTER INSERT ON articles BEGIN
+			INSERT INTO articles_fts (docid, title, body) VALUES (new.id, new.title, new.body);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS articles_fts_delete BEFORE DELETE ON articles BEGIN
+			DELETE FROM articles_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS articles_fts_update_before BEFORE UPDATE ON articles BEGIN
+			DELETE FROM articles_fts WHERE docid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS articles_fts_update_after AFTER UPDATE ON articles BEGIN
+			INSERT INTO articles_fts (docid, title, body) VALUES (new.id, new.title, new.body);
+		END`,
+	}},
+}
+
+// seedArticles creates the articles table with its full-text index and
+// fills it when it is empty
+func seedArticles(db *sql.DB, dialect string) error {
+	if _, err := db.Exec(articlesTables[dialect]); err != nil {
+		return err
+	}
+	if index, ok := articlesIndexes[dialect]; ok {
+		if _, err := db.Exec(index); err != nil {
+			return err
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM articles").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		rows := make([]string, len(articles))
+		for i, article := range articles {
+			rows[i] = fmt.Sprintf("(%s, %s, %s, %s, %s)", sqlString(article.title), sqlString(article.author),
+				sqlString(article.topic), sqlString(article.publishedAt), sqlString(article.body))
+		}
+		stmt := "INSERT INTO articles (title, author, topic, published_at, body) VALUES " +
+			strings.Join(rows, ", ")
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if dialect == "sqlite" {
+		return createArticlesFTS(db)
+	}
+	return nil
+}
+
+// createArticlesFTS creates the SQLite full-text index of articles with the
+// first module compiled in, or keeps the module of an existing index, and
+// rebuilds it from the table
+func createArticlesFTS(db *sql.DB) error {
+	var existing string
+	if err := db.QueryRow(`SELECT sql FROM sqlite_master WHERE name = 'articles_fts'`).Scan(&existing); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	for _, fts := range articlesFTS {
+		if existing != "" && !strings.Contains(strings.ToLower(existing), "using "+fts.module) {
+			continue
+		}
+		statements := append([]string{fts.create}, fts.triggers...)
+		statements = append(statements, `INSERT INTO articles_fts (articles_fts) VALUES ('rebuild')`)
+		err := execAll(db, statements)
+		if err == nil {
+			return nil
+		}
+		if !strings.Contains(err.Error(), "no such module") {
+			return err
+		}
+	}
+	fmt.Println("Warning: SQLite has neither fts5 nor fts4, articles_fts is not available")
+	return nil
+}
+
+// execAll runs statements in order and stops at the first error
+func execAll(db *sql.DB, statements []string) error {
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sqlString quotes a string literal the same way on every dialect, for
+// seed data without backslashes
+func sqlString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,181 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+
+	"example/user/playground/dbusers"
+	"example/user/playground/secrets"
+)
+
+// bootstrapTarget is the privileged connection that provisions the
+// restricted user of a backend
+type bootstrapTarget struct {
+	adminDSN string
+	account  dbusers.Account
+}
+
+var (
+	// Privileged bootstrap of each backend that has one configured
+	bootstrapTargets = map[string]bootstrapTarget{}
+
+	// Backends whose bootstrap has run
+	bootstrapped = map[string]bool{}
+
+	// Pools connected as the administrator of bootstrapped backends
+	adminPools = map[string]*sql.DB{}
+
+	// Guards bootstrapped and adminPools
+	bootstrapMu sync.Mutex
+)
+
+// discoverBootstrapTargets reads the administrator credentials used to
+// provision the playground users. MYSQL_ADMIN_DSN or MYSQL_ADMIN_USER and
+// MYSQL_ADMIN_PASSWORD (and their POSTGRES_ counterparts) enable the
+// bootstrap; without any user configuration it runs with the image
+// defaults, root and postgres with the password "example".
+func discoverBootstrapTargets(connectionStrings map[string]string) map[string]bootstrapTarget {
+	targets := map[string]bootstrapTarget{}
+
+	mysqlAdmin := os.Getenv("MYSQL_ADMIN_DSN")
+	if mysqlAdmin == "" && (os.Getenv("MYSQL_ADMIN_USER") != "" || (os.Getenv("MYSQL_DSN") == "" && os.Getenv("MYSQL_USER") == "")) {
+		mysqlAdmin = fmt.Sprintf("%s:%s@tcp(%s:%s)/",
+			envOr("MYSQL_ADMIN_USER", "root"),
+			envOr("MYSQL_ADMIN_PASSWORD", "example"),
+			envOr("MYSQL_HOST", defaultHost("mysql")),
+			envOr("MYSQL_PORT", "3306"))
+	}
+	if mysqlAdmin != "" {
+		config, err := mysql.ParseDSN(connectionStrings["mysql"])
+		admin, adminErr := mysql.ParseDSN(mysqlAdmin)
+		// Connecting as the administrator leaves nothing to provision
+		if err == nil && adminErr == nil && config.User != admin.User {
+			targets["mysql"] = bootstrapTarget{
+				adminDSN: mysqlAdmin,
+				account:  dbusers.Account{User: config.User, Password: config.Passwd, Database: config.DBName},
+			}
+		}
+	}
+
+	postgresAdmin := os.Getenv("POSTGRES_ADMIN_DSN")
+	if postgresAdmin == "" && (os.Getenv("POSTGRES_ADMIN_USER") != "" || (os.Getenv("POSTGRES_DSN") == "" && os.Getenv("POSTGRES_USER") == "")) {
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(envOr("POSTGRES_ADMIN_USER", "postgres"), envOr("POSTGRES_ADMIN_PASSWORD", "example")),
+			Host:     envOr("POSTGRES_HOST", defaultHost("postgres")) + ":" + envOr("POSTGRES_PORT", "5432"),
+			Path:     "/" + envOr("POSTGRES_DB", "testdb"),
+			RawQuery: "sslmode=" + envOr("POSTGRES_SSLMODE", "disable"),
+		}
+		postgresAdmin = dsn.String()
+	}
+	if postgresAdmin != "" {
+		dsn, err := url.Parse(connectionStrings["postgresql"])
+		admin, adminErr := url.Parse(postgresAdmin)
+		if err == nil && adminErr == nil && dsn.User != nil && admin.User != nil && dsn.User.Username() != admin.User.Username() {
+			password, _ := dsn.User.Password()
+			targets["postgresql"] = bootstrapTarget{
+				adminDSN: postgresAdmin,
+				account: dbusers.Account{
+					User:     dsn.User.Username(),
+					Password: password,
+					Database: strings.TrimPrefix(dsn.Path, "/"),
+					Schema:   searchPathSchema(dsn.Query().Get("search_path")),
+					PostGIS:  os.Getenv("POSTGRES_POSTGIS") == "true",
+				},
+			}
+		}
+	}
+	return targets
+}
+
+// bootstrapDatabase connects to a backend as its administrator, once, to
+// apply the server-wide safety settings and create the restricted user the
+// playground connects as, with the database or schema it works in. A
+// failure is reported and the playground tries its own credentials anyway,
+// in case the user was provisioned elsewhere.
+func bootstrapDatabase(dialect string, driver string) {
+	target, ok := bootstrapTargets[dialect]
+	if !ok {
+		return
+	}
+	bootstrapMu.Lock()
+	defer bootstrapMu.Unlock()
+	if bootstrapped[dialect] {
+		return
+	}
+
+	var statements []string
+	var err error
+	switch dialect {
+	case "mysql":
+		statements, err = dbusers.MySQLStatements(target.account)
+	case "postgresql":
+		statements, err = dbusers.PostgresStatements(target.account)
+	}
+	if err != nil {
+		fmt.Printf("Skipping %s bootstrap: %v\n", dialect, err)
+		return
+	}
+
+	db, err := sql.Open(driver, target.adminDSN)
+	if err != nil {
+		fmt.Printf("Skipping %s bootstrap: %v\n", dialect, secrets.MaskError(err))
+		return
+	}
+	defer db.Close()
+
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			fmt.Printf("Skipping %s bootstrap: %v\n", dialect, secrets.MaskError(err))
+			return
+		}
+	}
+	bootstrapped[dialect] = true
+	fmt.Printf("Provisioned restricted %s user %s\n", dialect, target.account.User)
+}
+
+// adminDatabase returns a pool connected as the administrator that
+// provisioned a backend's playground user, along with that user's account.
+// There is none until the bootstrap has succeeded.
+func adminDatabase(dialect string) (*sql.DB, dbusers.Account, bool) {
+	bootstrapMu.Lock()
+	defer bootstrapMu.Unlock()
+	target, ok := bootstrapTargets[dialect]
+	if !ok || !bootstrapped[dialect] {
+		return nil, dbusers.Account{}, false
+	}
+	db, ok := adminPools[dialect]
+	if !ok {
+		var err error
+		if db, err = sql.Open(dialectToDriver(dialect), target.adminDSN); err != nil {
+			return nil, dbusers.Account{}, false
+		}
+		db.SetMaxOpenConns(1)
+		adminPools[dialect] = db
+	}
+	return db, target.account, true
+}
+
+// ensureNamespace creates the PostgreSQL schema of the playground when none
+// of the connection's search_path exists yet, as happens when no bootstrap
+// provisioned it. Without it new tables would have nowhere to go.
+func ensureNamespace(db *sql.DB, dialect string) error {
+	if dialect != "postgresql" {
+		return nil
+	}
+	var schema sql.NullString
+	if err := db.QueryRow("SELECT current_schema()").Scan(&schema); err != nil {
+		return err
+	}
+	if schema.Valid {
+		return nil
+	}
+	_, err := db.Exec(fmt.Sprintf(`CREATE SCHEMA IF NOT EXISTS "%s"`, playgroundNamespace()))
+	return err
+}
@@ -0,0 +1,199 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// ColumnCollation is the character set and collation of a text column, or
+// the defaults of a database
+type ColumnCollation struct {
+	Charset   string `json:"charset"`
+	Collation string `json:"collation"`
+}
+
+// Collation is a collation a database offers
+type Collation struct {
+	Name    string `json:"name"`
+	Charset string `json:"charset,omitempty"`
+	// Whether it is the default collation of its character set, on MySQL
+	Default bool `json:"default,omitempty"`
+}
+
+// CollationList is what a database offers and what it uses by default
+type CollationList struct {
+	Default    ColumnCollation `json:"default"`
+	Collations []Collation     `json:"collations"`
+}
+
+var (
+	// Introspected collations of text columns per dialect, table and column
+	columnCollationCache = make(map[string]map[string]map[string]ColumnCollation)
+
+	// Guards columnCollationCache
+	columnCollationMu sync.RWMutex
+)
+
+// Queries listing the character set and collation of text columns. The
+// SQLite query returns each column's declared type and table SQL instead,
+// which columnCollationsFromSQL reads COLLATE clauses from.
+var columnCollationQueries = map[string]string{
+	"sqlite": `SELECT m.name, p.name, (SELECT encoding FROM pragma_encoding), p.type, m.sql
+		FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%'
+			AND m.name NOT IN (SELECT name FROM pragma_table_list WHERE type IN ('shadow', 'virtual'))
+		ORDER BY m.name, p.cid`,
+	"mysql": `SELECT table_name, column_name, character_set_name, collation_name
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE() AND collation_name IS NOT NULL
+		ORDER BY table_name, ordinal_position`,
+	// Columns using the database default report its collation by name
+	"postgresql": `SELECT c.relname, a.attname, pg_encoding_to_char(d.encoding),
+			CASE co.collname WHEN 'default' THEN d.datcollate ELSE co.collname END
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid
+		JOIN pg_catalog.pg_collation co ON co.oid = a.attcollation
+		JOIN pg_catalog.pg_database d ON d.datname = current_database()
+		WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+			AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum`,
+}
+
+// Queries listing the collations a database offers
+var collationQueries = map[string]string{
+	"sqlite": `SELECT name, '', 0 FROM pragma_collation_list ORDER BY name`,
+	"mysql": `SELECT collation_name, character_set_name, is_default = 'Yes'
+		FROM information_schema.collations
+		ORDER BY character_set_name, collation_name`,
+	"postgresql": `SELECT collname, COALESCE(pg_encoding_to_char(collencoding), ''), false
+		FROM pg_catalog.pg_collation
+		ORDER BY collname`,
+}
+
+// Queries returning the default character set and collation of a database
+var defaultCollationQueries = map[string]string{
+	"sqlite": `SELECT encoding, 'BINARY' FROM pragma_encoding`,
+	"mysql":  `SELECT @@character_set_database, @@collation_database`,
+	"postgresql": `SELECT pg_encoding_to_char(encoding), datcollate
+		FROM pg_catalog.pg_database WHERE datname = current_database()`,
+}
+
+// CachedColumnCollations returns the last introspected collations of the
+// text columns of a dialect's database, by table and column
+func CachedColumnCollations(dialect string) (map[string]map[string]ColumnCollation, bool) {
+	columnCollationMu.RLock()
+	defer columnCollationMu.RUnlock()
+
+	collations, ok := columnCollationCache[dialect]
+	return collations, ok
+}
+
+// ListCollations returns the collations a dialect's database offers and
+// its default character set and collation
+func ListCollations(ctx context.Context, dialect string) (*CollationList, error) {
+	db, ok := database(dialect)
+	if !ok {
+		return nil, fmt.Errorf("no database connection available for %s", dialect)
+	}
+	query, ok := collationQueries[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	list := &CollationList{Collations: []Collation{}}
+	if err := db.QueryRowContext(ctx, defaultCollationQueries[dialect]).Scan(&list.Default.Charset, &list.Default.Collation); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var collation Collation
+		if err := rows.Scan(&collation.Name, &collation.Charset, &collation.Default); err != nil {
+			return nil, err
+		}
+		list.Collations = append(list.Collations, collation)
+	}
+	return list, rows.Err()
+}
+
+// loadColumnCollations introspects the collations of the text columns of a
+// database and stores them in the cache
+func loadColumnCollations(ctx context.Context, db *sql.DB, dialect string) error {
+	query, ok := columnCollationQueries[dialect]
+	if !ok {
+		return fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	collations := make(map[string]map[string]ColumnCollation)
+	declared := make(map[string]map[string]string)
+	for rows.Next() {
+		var table, column string
+		var collation ColumnCollation
+		if dialect == "sqlite" {
+			var columnType, tableSQL string
+			if err := rows.Scan(&table, &column, &collation.Charset, &columnType, &tableSQL); err != nil {
+				return err
+			}
+			if _, ok := declared[table]; !ok {
+				declared[table] = sqlvalidator.ColumnCollations(tableSQL, dialect)
+			}
+			collation.Collation, ok = sqliteColumnCollation(declared[table], column, columnType)
+			if !ok {
+				continue
+			}
+		} else if err := rows.Scan(&table, &column, &collation.Charset, &collation.Collation); err != nil {
+			return err
+		}
+
+		if collations[table] == nil {
+			collations[table] = make(map[string]ColumnCollation)
+		}
+		collations[table][column] = collation
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	columnCollationMu.Lock()
+	columnCollationCache[dialect] = collations
+	columnCollationMu.Unlock()
+	return nil
+}
+
+// sqliteColumnCollation returns the collation of a SQLite column: the one
+// declared with COLLATE, or BINARY for columns with text affinity. Other
+// columns are left out.
+func sqliteColumnCollation(declared map[string]string, column string, columnType string) (string, bool) {
+	if collation, ok := declared[strings.ToLower(column)]; ok {
+		return strings.ToUpper(collation), true
+	}
+	columnType = strings.ToUpper(columnType)
+	if strings.Contains(columnType, "CHAR") || strings.Contains(columnType, "CLOB") || strings.Contains(columnType, "TEXT") {
+		return "BINARY", true
+	}
+	return "", false
+}
@@ -0,0 +1,196 @@
+package dbmanager
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"example/user/playground/dbusers"
+	"example/user/playground/querylimits"
+)
+
+// Delay before the second connection attempt; later attempts double it
+const initialRetryDelay = time.Second
+
+// Longest delay between two connection attempts
+const maxRetryDelay = 30 * time.Second
+
+// Connection attempts made at startup when DB_CONNECT_RETRIES is unset
+const defaultConnectRetries = 10
+
+// MySQL database and PostgreSQL schema holding the playground's objects
+// when PLAYGROUND_NAMESPACE is unset
+const defaultNamespace = "playground"
+
+// envOr returns the value of an environment variable or a fallback
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// defaultHost returns the Docker Compose service name inside a container
+// and localhost otherwise
+func defaultHost(service string) string {
+	if _, err := os.Stat("/.dockerenv"); os.IsNotExist(err) {
+		return "localhost"
+	}
+	return service
+}
+
+// playgroundNamespace returns the name of the MySQL database and
+// PostgreSQL schema the playground works in, from PLAYGROUND_NAMESPACE
+func playgroundNamespace() string {
+	namespace := envOr("PLAYGROUND_NAMESPACE", defaultNamespace)
+	if !dbusers.ValidName(namespace) {
+		fmt.Printf("Ignoring invalid PLAYGROUND_NAMESPACE %q, using %s\n", namespace, defaultNamespace)
+		return defaultNamespace
+	}
+	return namespace
+}
+
+// withSearchPath sets the search_path of a PostgreSQL connection string,
+// unless it already has one, so unqualified names resolve in the
+// playground's schema on every pooled connection
+func withSearchPath(dsn string, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		query := parsed.Query()
+		if query.Get("search_path") == "" {
+			query.Set("search_path", schema)
+			parsed.RawQuery = query.Encode()
+		}
+		return parsed.String()
+	}
+	if strings.Contains(dsn, "search_path=") {
+		return dsn
+	}
+	return strings.TrimSpace(dsn + " search_path=" + schema)
+}
+
+// searchPathSchema returns the first schema of a search_path setting, where
+// new tables are created, or "" when it names none
+func searchPathSchema(searchPath string) string {
+	first := strings.TrimSpace(strings.Split(searchPath, ",")[0])
+	return strings.Trim(first, `"`)
+}
+
+// withRecursionDepth sets cte_max_recursion_depth on every connection of a
+// MySQL connection string, which the driver applies when it connects
+func withRecursionDepth(dsn string) string {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return dsn
+	}
+	if config.Params == nil {
+		config.Params = map[string]string{}
+	}
+	config.Params["cte_max_recursion_depth"] = strconv.Itoa(querylimits.MySQLRecursionDepth)
+	return config.FormatDSN()
+}
+
+// discoverConnectionStrings builds the connection strings from environment
+// variables. A complete DSN in MYSQL_DSN or POSTGRES_DSN takes precedence
+// over the individual host, port and credential variables. The MySQL
+// database defaults to the playground's namespace, and PostgreSQL
+// connections search the namespace's schema.
+func discoverConnectionStrings() map[string]string {
+	namespace := playgroundNamespace()
+
+	mysqlDSN := os.Getenv("MYSQL_DSN")
+	if mysqlDSN == "" {
+		mysqlDSN = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s",
+			envOr("MYSQL_USER", "playground"),
+			envOr("MYSQL_PASSWORD", "playground"),
+			envOr("MYSQL_HOST", defaultHost("mysql")),
+			envOr("MYSQL_PORT", "3306"),
+			envOr("MYSQL_DATABASE", namespace))
+	}
+	mysqlDSN = withRecursionDepth(mysqlDSN)
+
+	postgresDSN := os.Getenv("POSTGRES_DSN")
+	if postgresDSN == "" {
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(envOr("POSTGRES_USER", "playground"), envOr("POSTGRES_PASSWORD", "playground")),
+			Host:     envOr("POSTGRES_HOST", defaultHost("postgres")) + ":" + envOr("POSTGRES_PORT", "5432"),
+			Path:     "/" + envOr("POSTGRES_DB", "testdb"),
+			RawQuery: "sslmode=" + envOr("POSTGRES_SSLMODE", "disable"),
+		}
+		postgresDSN = dsn.String()
+	}
+	postgresDSN = withSearchPath(postgresDSN, namespace)
+
+	return map[string]string{
+		"sqlite":     envOr("SQLITE_PATH", "./testdb.sqlite"),
+		"mysql":      mysqlDSN,
+		"postgresql": postgresDSN,
+	}
+}
+
+// Host settings in key=value PostgreSQL connection strings
+var pgHostRegex = regexp.MustCompile(`(?:^|\s)host\s*=\s*'?([^'\s]+)`)
+
+// BackendHosts returns the hosts of the MySQL and PostgreSQL backends the
+// playground connects to, pinned versions included
+func BackendHosts() []string {
+	hosts := []string{}
+	for key, dsn := range connectionStrings {
+		var host string
+		switch BaseDialect(key) {
+		case "mysql":
+			if config, err := mysql.ParseDSN(dsn); err == nil {
+				host = config.Addr
+				if h, _, err := net.SplitHostPort(config.Addr); err == nil {
+					host = h
+				}
+			}
+		case "postgresql":
+			if parsed, err := url.Parse(dsn); err == nil && (parsed.Scheme == "postgres" || parsed.Scheme == "postgresql") {
+				host = parsed.Hostname()
+			} else if match := pgHostRegex.FindStringSubmatch(dsn); match != nil {
+				host = match[1]
+			}
+		}
+		if host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// connectRetries returns the number of startup connection attempts from
+// DB_CONNECT_RETRIES
+func connectRetries() int {
+	if retries, err := strconv.Atoi(os.Getenv("DB_CONNECT_RETRIES")); err == nil && retries > 0 {
+		return retries
+	}
+	return defaultConnectRetries
+}
+
+// retryDelay returns the wait after a failed attempt: exponential backoff
+// capped at maxRetryDelay, with jitter so backends restarted together are
+// not hit in lockstep
+func retryDelay(attempt int) time.Duration {
+	delay := initialRetryDelay
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	// Between half and the full delay
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
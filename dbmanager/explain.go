@@ -0,0 +1,81 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExplainQuery runs the dialect's EXPLAIN for a query and returns a compact,
+// single-line summary of the plan
+func ExplainQuery(ctx context.Context, db *sql.DB, dialect string, query string) (string, error) {
+	var prefix string
+	switch dialect {
+	case "sqlite":
+		prefix = "EXPLAIN QUERY PLAN "
+	case "mysql", "postgresql":
+		prefix = "EXPLAIN "
+	default:
+		return "", fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, prefix+strings.TrimSuffix(strings.TrimSpace(query), ";"))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]sql.NullString, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	steps := []string{}
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return "", err
+		}
+		steps = append(steps, planStep(columns, values))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	return strings.Join(steps, " | "), nil
+}
+
+// planStep summarizes one row of EXPLAIN output
+func planStep(columns []string, values []sql.NullString) string {
+	byName := make(map[string]string)
+	for i, column := range columns {
+		byName[strings.ToLower(column)] = values[i].String
+	}
+
+	// SQLite and PostgreSQL describe each step in a single text column
+	if detail, ok := byName["detail"]; ok {
+		return detail
+	}
+	if plan, ok := byName["query plan"]; ok {
+		return strings.TrimSpace(plan)
+	}
+
+	// MySQL returns one row per table access
+	parts := []string{}
+	for _, name := range []string{"table", "type", "key", "rows", "extra"} {
+		if value := byName[name]; value != "" {
+			parts = append(parts, name+"="+value)
+		}
+	}
+	return strings.Join(parts, " ")
+}
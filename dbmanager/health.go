@@ -0,0 +1,175 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"example/user/playground/secrets"
+)
+
+// How often a connected backend is pinged by default
+const defaultHealthCheckInterval = 15 * time.Second
+
+// Longest a single health check ping may take
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck is the outcome of the latest ping of a backend
+type HealthCheck struct {
+	Healthy   bool      `json:"healthy"`
+	CheckedAt time.Time `json:"checked_at"`
+	LatencyMs float64   `json:"latency_ms"`
+	Error     string    `json:"error,omitempty"`
+	// When the backend last answered a ping
+	LastHealthyAt *time.Time `json:"last_healthy_at,omitempty"`
+	// Time between two pings of the backend
+	IntervalSeconds float64 `json:"interval_seconds"`
+}
+
+var (
+	// Latest health check of each backend
+	healthChecks = make(map[string]HealthCheck)
+
+	// Time between two pings of each backend, from configureHealthChecks
+	healthCheckIntervals = make(map[string]time.Duration)
+
+	// Guards healthChecks and healthCheckIntervals
+	healthChecksMu sync.RWMutex
+)
+
+// configureHealthChecks reads how often each backend is pinged from
+// HEALTH_CHECK_INTERVAL, overridden per dialect by
+// HEALTH_CHECK_INTERVAL_SQLITE, HEALTH_CHECK_INTERVAL_MYSQL and
+// HEALTH_CHECK_INTERVAL_POSTGRESQL. Pinned versions follow their dialect.
+func configureHealthChecks(keys []string) {
+	intervals := make(map[string]time.Duration, len(keys))
+	for _, key := range keys {
+		interval := defaultHealthCheckInterval
+		for _, name := range []string{"HEALTH_CHECK_INTERVAL", "HEALTH_CHECK_INTERVAL_" + strings.ToUpper(BaseDialect(key))} {
+			value := os.Getenv(name)
+			if value == "" {
+				continue
+			}
+			if parsed, err := time.ParseDuration(value); err == nil && parsed > 0 {
+				interval = parsed
+			} else if key == BaseDialect(key) {
+				fmt.Printf("Ignoring %s: %q is not a positive duration such as 30s\n", name, value)
+			}
+		}
+		intervals[key] = interval
+	}
+
+	healthChecksMu.Lock()
+	healthCheckIntervals = intervals
+	healthChecksMu.Unlock()
+}
+
+// healthCheckInterval returns how often a backend is pinged
+func healthCheckInterval(key string) time.Duration {
+	healthChecksMu.RLock()
+	defer healthChecksMu.RUnlock()
+
+	if interval, ok := healthCheckIntervals[key]; ok {
+		return interval
+	}
+	return defaultHealthCheckInterval
+}
+
+// GetHealthChecks returns the latest health check of every backend that
+// has been pinged
+func GetHealthChecks() map[string]HealthCheck {
+	healthChecksMu.RLock()
+	defer healthChecksMu.RUnlock()
+
+	checks := make(map[string]HealthCheck, len(healthChecks))
+	for key, check := range healthChecks {
+		checks[key] = check
+	}
+	return checks
+}
+
+// CheckHealth pings a backend now instead of waiting for its next health
+// check and caches the outcome. The ping ends at the deadline of ctx or
+// after the health check timeout, whichever comes first.
+func CheckHealth(ctx context.Context, key string) (HealthCheck, error) {
+	// SQLite is pinged through the pool user statements run on, as its
+	// supervisor does
+	db, ok := sandboxedDatabase()
+	if !ok || key != "sqlite" {
+		db, ok = database(key)
+	}
+	if !ok {
+		return HealthCheck{}, fmt.Errorf("no database connection available for %s", key)
+	}
+	pingBackend(ctx, key, db)
+
+	healthChecksMu.RLock()
+	defer healthChecksMu.RUnlock()
+	return healthChecks[key], nil
+}
+
+// pingBackend pings a backend's pool under the deadline of ctx, at most
+// the health check timeout, and caches the outcome
+func pingBackend(ctx context.Context, key string, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := db.PingContext(ctx)
+	recordHealth(key, start, time.Since(start), err)
+	return err
+}
+
+// recordHealth caches the outcome of a ping started at checkedAt
+func recordHealth(key string, checkedAt time.Time, latency time.Duration, err error) {
+	interval := healthCheckInterval(key)
+
+	healthChecksMu.Lock()
+	defer healthChecksMu.Unlock()
+
+	check := HealthCheck{
+		Healthy:         err == nil,
+		CheckedAt:       checkedAt,
+		LatencyMs:       float64(latency.Microseconds()) / 1000,
+		LastHealthyAt:   healthChecks[key].LastHealthyAt,
+		IntervalSeconds: interval.Seconds(),
+	}
+	if err != nil {
+		check.Error = secrets.MaskError(err)
+	} else {
+		check.LastHealthyAt = &checkedAt
+	}
+	healthChecks[key] = check
+}
+
+// healthCheckStale reports whether a backend has gone without a health
+// check for more than two intervals, such as while its supervisor is stuck
+func healthCheckStale(key string) bool {
+	interval := healthCheckInterval(key)
+
+	healthChecksMu.RLock()
+	check, ok := healthChecks[key]
+	healthChecksMu.RUnlock()
+	return ok && time.Since(check.CheckedAt) > 2*interval
+}
+
+// superviseSQLite pings the SQLite pools on their interval. SQLite needs no
+// reconnecting, but its status is cached like that of the other backends.
+func superviseSQLite() {
+	interval := healthCheckInterval("sqlite")
+	for {
+		db, ok := sandboxedDatabase()
+		if !ok {
+			db, ok = database("sqlite")
+		}
+		if ok {
+			err := pingBackend(context.Background(), "sqlite", db)
+			setConnected("sqlite", err == nil, err)
+		}
+		time.Sleep(interval)
+	}
+}
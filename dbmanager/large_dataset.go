@@ -0,0 +1,336 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bounds of the number of rows a large dataset may have
+const (
+	MinLargeDatasetRows = 1000
+	MaxLargeDatasetRows = 1000000
+)
+
+// Rows inserted per statement while seeding a large dataset
+const largeDatasetBatch = 1000
+
+// Shortest time between two progress notifications of a seeding job
+const largeDatasetReportInterval = 500 * time.Millisecond
+
+// States of a seeding job
+const (
+	SeedRunning   = "running"
+	SeedCompleted = "completed"
+	SeedCancelled = "cancelled"
+	SeedFailed    = "failed"
+)
+
+// ErrSeedRunning is returned when a dialect is already being seeded
+var ErrSeedRunning = errors.New("a large dataset is already being seeded for this dialect")
+
+// ErrNoSeedRunning is returned when cancelling a dialect that is not being
+// seeded
+var ErrNoSeedRunning = errors.New("no large dataset is being seeded for this dialect")
+
+// Table definitions of large_orders per dialect. Only the primary key is
+// indexed so indexing exercises start from a realistic slow baseline.
+var largeOrdersTables = map[string]string{
+	"sqlite": `CREATE TABLE large_orders (
+		id INTEGER PRIMARY KEY,
+		customer_id INTEGER NOT NULL,
+		product_id INTEGER NOT NULL,
+		status TEXT NOT NULL,
+		quantity INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		ordered_at TEXT NOT NULL
+	)`,
+	"mysql": `CREATE TABLE large_orders (
+		id INT PRIMARY KEY,
+		customer_id INT NOT NULL,
+		product_id INT NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		quantity INT NOT NULL,
+		amount DECIMAL(10,2) NOT NULL,
+		ordered_at DATETIME NOT NULL
+	)`,
+	"postgresql": `CREATE TABLE large_orders (
+		id INTEGER PRIMARY KEY,
+		customer_id INTEGER NOT NULL,
+		product_id INTEGER NOT NULL,
+		status VARCHAR(20) NOT NULL,
+		quantity INTEGER NOT NULL,
+		amount NUMERIC(10,2) NOT NULL,
+		ordered_at TIMESTAMP NOT NULL
+	)`,
+}
+
+// Orders are spread over the two years from this date
+var largeOrdersStart = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Order statuses, repeated to skew the distribution towards delivered
+var orderStatuses = []string{"delivered", "delivered", "delivered", "delivered", "shipped", "shipped", "pending", "cancelled", "returned"}
+
+// SeedJob describes the seeding of a large dataset into one backend
+type SeedJob struct {
+	Dialect    string     `json:"dialect"`
+	Table      string     `json:"table"`
+	State      string     `json:"state"`
+	Rows       int        `json:"rows"`
+	Inserted   int        `json:"inserted"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+var (
+	// Latest seeding job keyed by dialect
+	seedJobs = make(map[string]*SeedJob)
+
+	// Guards seedJobs
+	seedJobsMu sync.Mutex
+
+	// Functions notified of seeding progress
+	seedListeners []func(SeedJob)
+
+	// Guards seedListeners
+	seedListenersMu sync.Mutex
+)
+
+// LargeDatasetRows returns the number of rows seeded at startup, read from
+// LARGE_DATASET_ROWS. Zero means no dataset is seeded automatically.
+func LargeDatasetRows() int {
+	rows, err := strconv.Atoi(os.Getenv("LARGE_DATASET_ROWS"))
+	if err != nil || rows <= 0 {
+		return 0
+	}
+	if rows < MinLargeDatasetRows {
+		return MinLargeDatasetRows
+	}
+	if rows > MaxLargeDatasetRows {
+		return MaxLargeDatasetRows
+	}
+	return rows
+}
+
+// OnSeedProgress registers a function to be called as seeding jobs make
+// progress and when they finish. Listeners run on the seeding goroutine and
+// must not block.
+func OnSeedProgress(listener func(SeedJob)) {
+	seedListenersMu.Lock()
+	seedListeners = append(seedListeners, listener)
+	seedListenersMu.Unlock()
+}
+
+// StartLargeDataset replaces the large_orders table of a backend with the
+// given number of generated orders, inserted in batches in the background
+func StartLargeDataset(dialect string, rows int) (*SeedJob, error) {
+	if _, ok := largeOrdersTables[dialect]; !ok {
+		return nil, fmt.Errorf("large datasets are not supported for %s", dialect)
+	}
+	if rows < MinLargeDatasetRows || rows > MaxLargeDatasetRows {
+		return nil, fmt.Errorf("rows must be between %d and %d", MinLargeDatasetRows, MaxLargeDatasetRows)
+	}
+	db, ok := database(dialect)
+	if !ok {
+		return nil, fmt.Errorf("no database connection available for %s", dialect)
+	}
+
+	seedJobsMu.Lock()
+	defer seedJobsMu.Unlock()
+	if job, ok := seedJobs[dialect]; ok && job.State == SeedRunning {
+		return nil, ErrSeedRunning
+	}
+	// Other instances may be seeding a backend they share with this one
+	locked, err := acquireSeedLock(dialect)
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, ErrSeedRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &SeedJob{
+		Dialect:   dialect,
+		Table:     "large_orders",
+		State:     SeedRunning,
+		Rows:      rows,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+	}
+	seedJobs[dialect] = job
+	go runSeedJob(ctx, db, job)
+
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// CancelLargeDataset stops the running seeding job of a backend, asking the
+// instance running it when it is not this one. The rows inserted so far are
+// kept.
+func CancelLargeDataset(dialect string) error {
+	seedJobsMu.Lock()
+	defer seedJobsMu.Unlock()
+	job, ok := seedJobs[dialect]
+	if !ok || job.State != SeedRunning {
+		return requestSeedCancel(dialect)
+	}
+	job.cancel()
+	return nil
+}
+
+// LargeDatasetJobs returns the latest seeding job of every backend,
+// including shared backends seeded by other instances
+func LargeDatasetJobs() []SeedJob {
+	shared := sharedSeedJobs()
+
+	seedJobsMu.Lock()
+	defer seedJobsMu.Unlock()
+
+	result := make([]SeedJob, 0, len(seedJobs)+len(shared))
+	for dialect, job := range seedJobs {
+		if other, ok := shared[dialect]; ok && other.StartedAt.After(job.StartedAt) {
+			continue
+		}
+		delete(shared, dialect)
+		result = append(result, *job)
+	}
+	for _, job := range shared {
+		result = append(result, job)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Dialect < result[j].Dialect
+	})
+	return result
+}
+
+// seedConfiguredLargeDataset starts seeding the number of rows configured
+// by LARGE_DATASET_ROWS unless the backend already has them
+func seedConfiguredLargeDataset(dialect string) {
+	rows := LargeDatasetRows()
+	db, ok := database(dialect)
+	if rows == 0 || !ok {
+		return
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM large_orders").Scan(&count); err == nil && count >= rows {
+		return
+	}
+	if _, err := StartLargeDataset(dialect, rows); err != nil && err != ErrSeedRunning {
+		fmt.Printf("Failed to seed large dataset for %s: %v\n", dialect, err)
+	}
+}
+
+// runSeedJob recreates large_orders and fills it batch by batch until the
+// job is done, fails or is cancelled
+func runSeedJob(ctx context.Context, db *sql.DB, job *SeedJob) {
+	fmt.Printf("Seeding %d rows into %s large_orders\n", job.Rows, job.Dialect)
+	notifySeedProgress(job)
+
+	err := func() error {
+		if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS large_orders"); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, largeOrdersTables[job.Dialect]); err != nil {
+			return err
+		}
+
+		lastReport := time.Now()
+		for start := 0; start < job.Rows; start += largeDatasetBatch {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			count := largeDatasetBatch
+			if start+count > job.Rows {
+				count = job.Rows - start
+			}
+			stmt := "INSERT INTO large_orders (id, customer_id, product_id, status, quantity, amount, ordered_at) VALUES " +
+				strings.Join(largeOrderRows(start, count), ", ")
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+
+			seedJobsMu.Lock()
+			job.Inserted = start + count
+			seedJobsMu.Unlock()
+			if time.Since(lastReport) >= largeDatasetReportInterval {
+				lastReport = time.Now()
+				notifySeedProgress(job)
+			}
+		}
+		return nil
+	}()
+
+	seedJobsMu.Lock()
+	now := time.Now()
+	job.FinishedAt = &now
+	switch {
+	case err == nil:
+		job.State = SeedCompleted
+	case ctx.Err() == context.Canceled:
+		job.State = SeedCancelled
+	default:
+		job.State = SeedFailed
+		job.Error = err.Error()
+	}
+	job.cancel()
+	seedJobsMu.Unlock()
+
+	fmt.Printf("Seeding %s large_orders %s after %d rows\n", job.Dialect, job.State, job.Inserted)
+	if _, err := LoadSchema(context.Background(), job.Dialect); err != nil {
+		fmt.Printf("Warning: Failed to load %s schema: %v\n", job.Dialect, err)
+	}
+	notifySeedProgress(job)
+}
+
+// notifySeedProgress shares a job with other instances and passes a copy
+// of it to the seeding listeners
+func notifySeedProgress(job *SeedJob) {
+	seedJobsMu.Lock()
+	snapshot := *job
+	seedJobsMu.Unlock()
+
+	if publishSeedJob(snapshot) {
+		job.cancel()
+	}
+
+	seedListenersMu.Lock()
+	listeners := append([]func(SeedJob){}, seedListeners...)
+	seedListenersMu.Unlock()
+	for _, listener := range listeners {
+		listener(snapshot)
+	}
+}
+
+// largeOrderRows generates the VALUES tuples of count orders starting after
+// the first start. Each batch is seeded from its offset, so the data is the
+// same for a given row count regardless of how it was split.
+func largeOrderRows(start int, count int) []string {
+	random := rand.New(rand.NewSource(int64(start) + 1))
+	rows := make([]string, count)
+	for i := range rows {
+		id := start + i + 1
+		// Skew customers so a few of them place most orders
+		customer := 1 + int(random.ExpFloat64()*2000)%50000
+		product := 1 + random.Intn(500)
+		quantity := 1 + random.Intn(5)
+		amount := float64(quantity) * (5 + random.Float64()*195)
+		orderedAt := largeOrdersStart.Add(time.Duration(random.Int63n(int64(2 * 365 * 24 * time.Hour))))
+		rows[i] = fmt.Sprintf("(%d, %d, %d, '%s', %d, %.2f, '%s')",
+			id, customer, product, orderStatuses[random.Intn(len(orderStatuses))], quantity, amount,
+			orderedAt.Format("2006-01-02 15:04:05"))
+	}
+	return rows
+}
@@ -0,0 +1,125 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Landmarks of the locations table, with WGS 84 coordinates
+var landmarks = []struct {
+	name      string
+	category  string
+	city      string
+	country   string
+	latitude  float64
+	longitude float64
+}{
+	{"Statue of Liberty", "Monument", "New York", "USA", 40.689247, -74.044502},
+	{"Central Park", "Park", "New York", "USA", 40.782865, -73.965355},
+	{"Golden Gate Bridge", "Bridge", "San Francisco", "USA", 37.819929, -122.478255},
+	{"Griffith Observatory", "Museum", "Los Angeles", "USA", 34.118434, -118.300393},
+	{"Millennium Park", "Park", "Chicago", "USA", 41.882552, -87.622551},
+	{"CN Tower", "Tower", "Toronto", "Canada", 43.642567, -79.387054},
+	{"Tower Bridge", "Bridge", "London", "UK", 51.505456, -0.075356},
+	{"British Museum", "Museum", "London", "UK", 51.519413, -0.126957},
+	{"Eiffel Tower", "Tower", "Paris", "France", 48.858370, 2.294481},
+	{"Louvre Museum", "Museum", "Paris", "France", 48.860611, 2.337644},
+	{"Brandenburg Gate", "Monument", "Berlin", "Germany", 52.516275, 13.377704},
+	{"Colosseum", "Monument", "Rome", "Italy", 41.890210, 12.492231},
+	{"Retiro Park", "Park", "Madrid", "Spain", 40.415260, -3.684416},
+	{"Tokyo Tower", "Tower", "Tokyo", "Japan", 35.658581, 139.745433},
+	{"Sydney Opera House", "Theatre", "Sydney", "Australia", -33.856784, 151.215297},
+	{"Christ the Redeemer", "Monument", "Rio de Janeiro", "Brazil", -22.951916, -43.210487},
+}
+
+// Table definitions of locations per dialect. Every dialect has the
+// coordinates as numbers; MySQL adds a POINT column, and PostgreSQL one
+// when PostGIS is installed.
+var locationsTables = map[string]string{
+	"sqlite": `CREATE TABLE IF NOT EXISTS locations (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		category TEXT NOT NULL,
+		city TEXT NOT NULL,
+		country TEXT NOT NULL,
+		latitude REAL NOT NULL,
+		longitude REAL NOT NULL
+	)`,
+	"mysql": `CREATE TABLE IF NOT EXISTS locations (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		category VARCHAR(50) NOT NULL,
+		city VARCHAR(50) NOT NULL,
+		country VARCHAR(50) NOT NULL,
+		latitude DECIMAL(9,6) NOT NULL,
+		longitude DECIMAL(9,6) NOT NULL,
+		position POINT SRID 4326
+	)`,
+	"postgresql": `CREATE TABLE IF NOT EXISTS locations (
+		id SERIAL PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		category VARCHAR(50) NOT NULL,
+		city VARCHAR(50) NOT NULL,
+		country VARCHAR(50) NOT NULL,
+		latitude NUMERIC(9,6) NOT NULL,
+		longitude NUMERIC(9,6) NOT NULL
+	)`,
+}
+
+// Statements filling the spatial column from the coordinates. MySQL reads
+// geographic WKT latitude first unless told otherwise.
+var locationsPositions = map[string][]string{
+	"mysql": {
+		`UPDATE locations SET position = ST_PointFromText(CONCAT('POINT(', longitude, ' ', latitude, ')'), 4326, 'axis-order=long-lat') WHERE position IS NULL`,
+	},
+	"postgresql": {
+		`ALTER TABLE locations ADD COLUMN IF NOT EXISTS position geometry(Point, 4326)`,
+		`UPDATE locations SET position = ST_SetSRID(ST_MakePoint(longitude, latitude), 4326) WHERE position IS NULL`,
+	},
+}
+
+// seedLocations creates the locations table of landmarks for practicing
+// spatial SQL and fills it when it is empty
+func seedLocations(db *sql.DB, dialect string) error {
+	if _, err := db.Exec(locationsTables[dialect]); err != nil {
+		return err
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM locations").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		rows := make([]string, len(landmarks))
+		for i, landmark := range landmarks {
+			rows[i] = fmt.Sprintf("('%s', '%s', '%s', '%s', %f, %f)",
+				landmark.name, landmark.category, landmark.city, landmark.country, landmark.latitude, landmark.longitude)
+		}
+		stmt := "INSERT INTO locations (name, category, city, country, latitude, longitude) VALUES " +
+			strings.Join(rows, ", ")
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if dialect == "postgresql" && !hasPostGIS(db) {
+		return nil
+	}
+	for _, stmt := range locationsPositions[dialect] {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasPostGIS reports whether the PostGIS geometry type is visible on the
+// connection's search_path
+func hasPostGIS(db *sql.DB) bool {
+	var installed bool
+	if err := db.QueryRow("SELECT to_regtype('geometry') IS NOT NULL").Scan(&installed); err != nil {
+		return false
+	}
+	return installed
+}
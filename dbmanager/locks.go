@@ -0,0 +1,181 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// LockWait is a session waiting for a lock that another session holds
+type LockWait struct {
+	// Waiting session: backend PID on PostgreSQL, connection ID on MySQL
+	WaitingID    int64  `json:"waiting_id"`
+	WaitingQuery string `json:"waiting_query"`
+	// How long the waiting statement has been running, in seconds
+	WaitingSeconds float64 `json:"waiting_seconds"`
+	BlockingID     int64   `json:"blocking_id"`
+	// Latest statement of the blocking session, which may have finished
+	// while its transaction keeps the lock
+	BlockingQuery string `json:"blocking_query"`
+	// State of the blocking session, such as "idle in transaction" or "Sleep"
+	BlockingState string `json:"blocking_state"`
+	// Kind of lock waited for, such as "relation", "transactionid" or "RECORD"
+	LockType string `json:"lock_type"`
+	LockMode string `json:"lock_mode"`
+	// Table the lock is on, when the lock belongs to one
+	Object string `json:"object,omitempty"`
+}
+
+// LockReport describes the lock waits of a dialect's database
+type LockReport struct {
+	Dialect string     `json:"dialect"`
+	Waits   []LockWait `json:"waits"`
+	// Sessions holding locks others wait for while waiting for none
+	// themselves: the ones to commit, roll back or terminate
+	BlockingSessions []int64 `json:"blocking_sessions"`
+	// Whether a writer holds the SQLite database so no other can start
+	Busy *bool `json:"busy,omitempty"`
+	// SQLite journal mode; in wal mode readers never wait for writers
+	JournalMode string `json:"journal_mode,omitempty"`
+}
+
+// Queries listing the sessions of the playground's database user that wait
+// for a lock, with the sessions blocking them
+var lockWaitQueries = map[string]string{
+	"postgresql": `SELECT w.pid, COALESCE(w.query, ''),
+		COALESCE(EXTRACT(EPOCH FROM (now() - w.query_start)), 0),
+		b.pid, COALESCE(b.query, ''), COALESCE(b.state, ''),
+		COALESCE(l.locktype, ''), COALESCE(l.mode, ''), COALESCE(l.relation::regclass::text, '')
+		FROM pg_stat_activity w
+		CROSS JOIN LATERAL unnest(pg_blocking_pids(w.pid)) AS blocker(pid)
+		JOIN pg_stat_activity b ON b.pid = blocker.pid
+		LEFT JOIN pg_locks l ON l.pid = w.pid AND NOT l.granted
+		WHERE w.datname = current_database() AND w.usename = current_user
+		ORDER BY w.pid, b.pid`,
+	"mysql": `SELECT COALESCE(rt.PROCESSLIST_ID, 0), COALESCE(rt.PROCESSLIST_INFO, ''), COALESCE(rt.PROCESSLIST_TIME, 0),
+		COALESCE(bt.PROCESSLIST_ID, 0), COALESCE(bt.PROCESSLIST_INFO, ''), COALESCE(bt.PROCESSLIST_COMMAND, ''),
+		rl.LOCK_TYPE, rl.LOCK_MODE, COALESCE(rl.OBJECT_NAME, '')
+		FROM performance_schema.data_lock_waits w
+		JOIN performance_schema.data_locks rl ON rl.ENGINE_LOCK_ID = w.REQUESTING_ENGINE_LOCK_ID
+		JOIN performance_schema.threads rt ON rt.THREAD_ID = w.REQUESTING_THREAD_ID
+		JOIN performance_schema.threads bt ON bt.THREAD_ID = w.BLOCKING_THREAD_ID
+		WHERE rl.OBJECT_SCHEMA = DATABASE()
+		ORDER BY rt.PROCESSLIST_ID, bt.PROCESSLIST_ID`,
+}
+
+// Locks reports the lock waits of a dialect's database and the sessions
+// causing them, with the literals of their statements masked. SQLite has no
+// sessions to list, so it reports whether a writer holds the database
+// instead.
+func Locks(ctx context.Context, dialect string) (*LockReport, error) {
+	db, ok := database(dialect)
+	if !ok {
+		return nil, fmt.Errorf("no database connection available for %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if BaseDialect(dialect) == "sqlite" {
+		return sqliteLocks(ctx, connectionStrings["sqlite"], dialect)
+	}
+	query, ok := lockWaitQueries[BaseDialect(dialect)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &LockReport{Dialect: dialect, Waits: []LockWait{}, BlockingSessions: []int64{}}
+	for rows.Next() {
+		var wait LockWait
+		if err := rows.Scan(&wait.WaitingID, &wait.WaitingQuery, &wait.WaitingSeconds,
+			&wait.BlockingID, &wait.BlockingQuery, &wait.BlockingState,
+			&wait.LockType, &wait.LockMode, &wait.Object); err != nil {
+			return nil, err
+		}
+		wait.WaitingQuery = sqlvalidator.MaskSQL(wait.WaitingQuery, dialect)
+		wait.BlockingQuery = sqlvalidator.MaskSQL(wait.BlockingQuery, dialect)
+		report.Waits = append(report.Waits, wait)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	report.BlockingSessions = rootBlockers(report.Waits)
+	return report, nil
+}
+
+// rootBlockers returns the sessions that block others without waiting
+// themselves, at the heads of the wait chains
+func rootBlockers(waits []LockWait) []int64 {
+	waiting := make(map[int64]bool, len(waits))
+	for _, wait := range waits {
+		waiting[wait.WaitingID] = true
+	}
+	seen := map[int64]bool{}
+	blockers := []int64{}
+	for _, wait := range waits {
+		if !waiting[wait.BlockingID] && !seen[wait.BlockingID] {
+			seen[wait.BlockingID] = true
+			blockers = append(blockers, wait.BlockingID)
+		}
+	}
+	sort.Slice(blockers, func(i, j int) bool {
+		return blockers[i] < blockers[j]
+	})
+	return blockers
+}
+
+// sqliteLocks checks whether another connection holds the write lock of
+// the SQLite database by trying to take it without waiting. The probe opens
+// a connection of its own, so the pools' connections keep their busy
+// timeout and are never held up.
+func sqliteLocks(ctx context.Context, dsn string, dialect string) (*LockReport, error) {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	db, err := sql.Open("sqlite3", dsn+separator+"_busy_timeout=0")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	report := &LockReport{Dialect: dialect, Waits: []LockWait{}, BlockingSessions: []int64{}}
+	if err := conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&report.JournalMode); err != nil {
+		return nil, err
+	}
+
+	busy := false
+	_, err = conn.ExecContext(ctx, "BEGIN IMMEDIATE")
+	var sqliteErr sqlite3.Error
+	switch {
+	case errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked):
+		busy = true
+	case err != nil:
+		return nil, err
+	default:
+		if _, err := conn.ExecContext(ctx, "ROLLBACK"); err != nil {
+			return nil, err
+		}
+	}
+	report.Busy = &busy
+	return report, nil
+}
@@ -0,0 +1,43 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteLocksReportsAHeldWriteLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "playground.sqlite")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	report, err := sqliteLocks(ctx, path, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Busy == nil || *report.Busy {
+		t.Errorf("expected an idle database not to be busy, got %+v", report)
+	}
+
+	writer, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Rollback()
+	if _, err := writer.Exec("CREATE TABLE notes (body TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = sqliteLocks(ctx, path, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Busy == nil || !*report.Busy {
+		t.Errorf("expected a held write lock to be reported, got %+v", report)
+	}
+}
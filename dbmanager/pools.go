@@ -0,0 +1,180 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Connection pool settings of MySQL and PostgreSQL backends until tuned
+const (
+	DefaultMaxOpenConns    = 5
+	DefaultMaxIdleConns    = 2
+	DefaultConnMaxLifetime = 30 * time.Minute
+)
+
+// Most connections a tuned pool may open
+const MaxPoolConns = 100
+
+// ErrUnknownPool is returned when tuning a backend without a tunable pool
+var ErrUnknownPool = errors.New("no tunable connection pool for this backend")
+
+// PoolSettings are the limits of a backend's connection pool
+type PoolSettings struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// PoolStats reports the use of a backend's connection pool
+type PoolStats struct {
+	Backend         string  `json:"backend"`
+	OpenConnections int     `json:"open_connections"`
+	InUse           int     `json:"in_use"`
+	Idle            int     `json:"idle"`
+	WaitCount       int64   `json:"wait_count"`
+	WaitDurationMs  float64 `json:"wait_duration_ms"`
+	// Connections closed for exceeding the idle limit or their lifetime
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+	MaxOpenConns      int   `json:"max_open_conns"`
+	// Settings only tunable pools report
+	MaxIdleConns           *int   `json:"max_idle_conns,omitempty"`
+	ConnMaxLifetimeSeconds *int64 `json:"conn_max_lifetime_seconds,omitempty"`
+}
+
+var (
+	// Tuned settings of MySQL and PostgreSQL pools keyed by backend
+	poolSettings = make(map[string]PoolSettings)
+
+	// Guards poolSettings
+	poolSettingsMu sync.Mutex
+)
+
+// Validate checks that settings are within the limits a pool accepts
+func (s PoolSettings) Validate() error {
+	if s.MaxOpenConns < 1 || s.MaxOpenConns > MaxPoolConns {
+		return fmt.Errorf("max open connections must be between 1 and %d", MaxPoolConns)
+	}
+	if s.MaxIdleConns < 0 || s.MaxIdleConns > s.MaxOpenConns {
+		return fmt.Errorf("max idle connections must be between 0 and the max open connections")
+	}
+	if s.ConnMaxLifetime < 0 {
+		return fmt.Errorf("connection max lifetime must not be negative")
+	}
+	return nil
+}
+
+// GetPoolSettings returns the pool settings of a MySQL or PostgreSQL backend
+func GetPoolSettings(key string) (PoolSettings, error) {
+	if _, supervised := supervisorWake[key]; !supervised {
+		return PoolSettings{}, ErrUnknownPool
+	}
+	return poolSettingsFor(key), nil
+}
+
+// SetPoolSettings changes the pool settings of a MySQL or PostgreSQL
+// backend. They apply to the open pool at once and to the pools of later
+// reconnects, until the process exits.
+func SetPoolSettings(key string, settings PoolSettings) error {
+	if _, supervised := supervisorWake[key]; !supervised {
+		return ErrUnknownPool
+	}
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	poolSettingsMu.Lock()
+	poolSettings[key] = settings
+	poolSettingsMu.Unlock()
+
+	if db, ok := database(key); ok {
+		applyPoolSettings(db, settings)
+	}
+	return nil
+}
+
+// GetPoolStats returns the statistics of every open pool serving user
+// queries. SQLite reports its sandboxed pool.
+func GetPoolStats() []PoolStats {
+	databasesMu.RLock()
+	pools := make(map[string]*sql.DB, len(databases))
+	for key, db := range databases {
+		pools[key] = db
+	}
+	databasesMu.RUnlock()
+	if db, ok := sandboxedDatabase(); ok {
+		pools["sqlite"] = db
+	}
+
+	stats := make([]PoolStats, 0, len(pools))
+	for key, db := range pools {
+		dbStats := db.Stats()
+		entry := PoolStats{
+			Backend:           key,
+			OpenConnections:   dbStats.OpenConnections,
+			InUse:             dbStats.InUse,
+			Idle:              dbStats.Idle,
+			WaitCount:         dbStats.WaitCount,
+			WaitDurationMs:    float64(dbStats.WaitDuration.Microseconds()) / 1000,
+			MaxIdleClosed:     dbStats.MaxIdleClosed,
+			MaxLifetimeClosed: dbStats.MaxLifetimeClosed,
+			MaxOpenConns:      dbStats.MaxOpenConnections,
+		}
+		if settings, err := GetPoolSettings(key); err == nil {
+			lifetime := int64(settings.ConnMaxLifetime / time.Second)
+			entry.MaxIdleConns = &settings.MaxIdleConns
+			entry.ConnMaxLifetimeSeconds = &lifetime
+		}
+		stats = append(stats, entry)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Backend < stats[j].Backend
+	})
+	return stats
+}
+
+// poolSettingsFor returns the tuned settings of a backend or the defaults
+func poolSettingsFor(key string) PoolSettings {
+	poolSettingsMu.Lock()
+	defer poolSettingsMu.Unlock()
+
+	if settings, ok := poolSettings[key]; ok {
+		return settings
+	}
+	return PoolSettings{
+		MaxOpenConns:    DefaultMaxOpenConns,
+		MaxIdleConns:    DefaultMaxIdleConns,
+		ConnMaxLifetime: DefaultConnMaxLifetime,
+	}
+}
+
+// applyPoolSettings sets the limits of a connection pool
+func applyPoolSettings(db *sql.DB, settings PoolSettings) {
+	db.SetMaxOpenConns(settings.MaxOpenConns)
+	db.SetMaxIdleConns(settings.MaxIdleConns)
+	db.SetConnMaxLifetime(settings.ConnMaxLifetime)
+}
+
+// OpenDedicated opens a connection pool of a MySQL or PostgreSQL backend
+// that is separate from the shared one, for features that hold connections
+// for minutes and must not starve user queries. The caller closes it.
+func OpenDedicated(key string, maxConns int) (*sql.DB, error) {
+	if _, supervised := supervisorWake[key]; !supervised {
+		return nil, ErrUnknownPool
+	}
+	if _, ok := database(key); !ok {
+		return nil, fmt.Errorf("no database connection available for %s", key)
+	}
+
+	db, err := sql.Open(dialectToDriver(BaseDialect(key)), connectionStrings[key])
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(maxConns)
+	return db, nil
+}
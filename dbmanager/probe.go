@@ -0,0 +1,193 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How long a connection probe may take, from connecting to reading the
+// server version
+const ProbeTimeout = 5 * time.Second
+
+// Leading major, minor and patch numbers of a server version
+var versionNumberRegex = regexp.MustCompile(`^(\d+)(?:\.(\d+))?(?:\.(\d+))?`)
+
+// ConnectionProbe is the outcome of a successful test connection
+type ConnectionProbe struct {
+	Dialect      string          `json:"dialect"`
+	Version      string          `json:"version"`
+	Server       string          `json:"server"`
+	ReadOnly     bool            `json:"read_only"`
+	LatencyMs    float64         `json:"latency_ms"`
+	Capabilities map[string]bool `json:"capabilities"`
+}
+
+// Optional features reported for every server
+var probeFeatures = []string{
+	"cte", "recursive_cte", "window_functions", "json", "generated_columns",
+	"check_constraints", "views", "materialized_views", "stored_procedures",
+	"triggers", "returning", "full_outer_join", "lateral_joins", "intersect_except",
+	"explain_analyze", "descending_indexes", "functional_indexes",
+	"invisible_indexes", "default_expressions", "merge",
+}
+
+// Minimum versions of the features a server has, as major, minor and
+// patch; features left out are not supported at any version
+type featureVersions map[string][3]int
+
+// Features of MySQL by the version that introduced them
+var mysqlFeatures = featureVersions{
+	"json":                {5, 7, 8},
+	"generated_columns":   {5, 7, 6},
+	"cte":                 {8, 0, 1},
+	"window_functions":    {8, 0, 2},
+	"check_constraints":   {8, 0, 16},
+	"stored_procedures":   {5, 0, 0},
+	"triggers":            {5, 0, 2},
+	"views":               {5, 0, 1},
+	"lateral_joins":       {8, 0, 14},
+	"explain_analyze":     {8, 0, 18},
+	"invisible_indexes":   {8, 0, 0},
+	"descending_indexes":  {8, 0, 0},
+	"functional_indexes":  {8, 0, 13},
+	"recursive_cte":       {8, 0, 1},
+	"intersect_except":    {8, 0, 31},
+	"default_expressions": {8, 0, 13},
+}
+
+// Features of MariaDB by the version that introduced them
+var mariadbFeatures = featureVersions{
+	"json":                {10, 2, 7},
+	"generated_columns":   {5, 2, 0},
+	"cte":                 {10, 2, 1},
+	"window_functions":    {10, 2, 0},
+	"check_constraints":   {10, 2, 1},
+	"stored_procedures":   {5, 0, 0},
+	"triggers":            {5, 0, 2},
+	"views":               {5, 0, 1},
+	"returning":           {10, 5, 0},
+	"explain_analyze":     {10, 1, 0},
+	"invisible_indexes":   {10, 6, 0},
+	"descending_indexes":  {10, 8, 1},
+	"recursive_cte":       {10, 2, 2},
+	"intersect_except":    {10, 3, 0},
+	"default_expressions": {10, 2, 1},
+}
+
+// Features of PostgreSQL by the version that introduced them
+var postgresFeatures = featureVersions{
+	"json":                {9, 4, 0},
+	"generated_columns":   {12, 0, 0},
+	"cte":                 {8, 4, 0},
+	"window_functions":    {8, 4, 0},
+	"check_constraints":   {7, 0, 0},
+	"stored_procedures":   {11, 0, 0},
+	"triggers":            {7, 0, 0},
+	"views":               {7, 0, 0},
+	"materialized_views":  {9, 3, 0},
+	"returning":           {8, 2, 0},
+	"full_outer_join":     {7, 0, 0},
+	"lateral_joins":       {9, 3, 0},
+	"explain_analyze":     {7, 2, 0},
+	"descending_indexes":  {8, 3, 0},
+	"functional_indexes":  {7, 0, 0},
+	"recursive_cte":       {8, 4, 0},
+	"intersect_except":    {7, 0, 0},
+	"default_expressions": {7, 0, 0},
+	"merge":               {15, 0, 0},
+}
+
+// ProbeConnection connects to a MySQL or PostgreSQL server through a
+// throwaway pool the caller opened and closes, reads the server's version
+// and derives the features it supports
+func ProbeConnection(ctx context.Context, dialect string, db *sql.DB) (*ConnectionProbe, error) {
+	var versionQuery, readOnlyQuery string
+	switch dialect {
+	case "mysql":
+		versionQuery = "SELECT VERSION()"
+		readOnlyQuery = "SELECT @@global.read_only"
+	case "postgresql":
+		versionQuery = "SHOW server_version"
+		readOnlyQuery = "SHOW default_transaction_read_only"
+	default:
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	defer cancel()
+
+	db.SetMaxOpenConns(1)
+
+	start := time.Now()
+	if err := db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	latency := time.Since(start)
+
+	probe := &ConnectionProbe{
+		Dialect:   dialect,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+	if err := db.QueryRowContext(ctx, versionQuery).Scan(&probe.Version); err != nil {
+		return nil, err
+	}
+	var readOnly string
+	if err := db.QueryRowContext(ctx, readOnlyQuery).Scan(&readOnly); err == nil {
+		probe.ReadOnly = readOnly == "1" || readOnly == "on"
+	}
+
+	probe.Server, probe.Capabilities = identifyServer(dialect, probe.Version)
+	return probe, nil
+}
+
+// identifyServer returns the product behind a dialect's server version and
+// the features the version has
+func identifyServer(dialect string, version string) (string, map[string]bool) {
+	switch {
+	case dialect == "sqlite":
+		return "SQLite", sqliteFeatures.supported(version)
+	case dialect == "mysql" && strings.Contains(strings.ToLower(version), "mariadb"):
+		return "MariaDB", mariadbFeatures.supported(version)
+	case dialect == "mysql":
+		return "MySQL", mysqlFeatures.supported(version)
+	}
+	return "PostgreSQL", postgresFeatures.supported(version)
+}
+
+// supported reports which features a server version has. Versions that
+// cannot be parsed report no features.
+func (f featureVersions) supported(version string) map[string]bool {
+	match := versionNumberRegex.FindStringSubmatch(strings.TrimSpace(version))
+	var have [3]int
+	if match != nil {
+		for i := range have {
+			have[i], _ = strconv.Atoi(match[i+1])
+		}
+	}
+
+	result := make(map[string]bool, len(probeFeatures))
+	for _, feature := range probeFeatures {
+		want, ok := f[feature]
+		result[feature] = ok && match != nil && compareVersions(have, want) >= 0
+	}
+	return result
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, equal to or newer
+// than b
+func compareVersions(a [3]int, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
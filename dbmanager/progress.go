@@ -0,0 +1,99 @@
+package dbmanager
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"example/user/playground/secrets"
+)
+
+// Initialization states of a backend
+const (
+	StatePending    = "pending"
+	StateConnecting = "connecting"
+	StateWaiting    = "waiting"
+	StateConnected  = "connected"
+	StateFailed     = "failed"
+)
+
+// Display names used in progress messages
+var dialectNames = map[string]string{
+	"sqlite":     "SQLite",
+	"mysql":      "MySQL",
+	"postgresql": "PostgreSQL",
+}
+
+// InitProgress describes how far the connection to a backend has come
+type InitProgress struct {
+	Dialect     string     `json:"dialect"`
+	State       string     `json:"state"`
+	Attempt     int        `json:"attempt"`
+	MaxAttempts int        `json:"max_attempts"`
+	LastError   string     `json:"last_error,omitempty"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	Message     string     `json:"message"`
+}
+
+var (
+	// Initialization progress keyed by dialect
+	initProgress = make(map[string]*InitProgress)
+
+	// Guards initProgress
+	initProgressMu sync.Mutex
+)
+
+// setProgress records the state of a backend and derives its message
+func setProgress(dialect string, state string, attempt int, maxAttempts int, err error, nextRetry time.Duration) {
+	progress := &InitProgress{
+		Dialect:     dialect,
+		State:       state,
+		Attempt:     attempt,
+		MaxAttempts: maxAttempts,
+	}
+	if err != nil {
+		progress.LastError = secrets.MaskError(err)
+	}
+	if nextRetry > 0 {
+		next := time.Now().Add(nextRetry)
+		progress.NextRetryAt = &next
+	}
+
+	name := displayName(dialect)
+	switch state {
+	case StatePending:
+		progress.Message = name + " waiting to connect"
+	case StateConnecting:
+		if attempt > maxAttempts {
+			progress.Message = fmt.Sprintf("%s reconnecting… attempt %d", name, attempt)
+		} else {
+			progress.Message = fmt.Sprintf("%s connecting… %d/%d", name, attempt, maxAttempts)
+		}
+	case StateWaiting:
+		progress.Message = fmt.Sprintf("%s starting… %d/%d retries", name, attempt, maxAttempts)
+	case StateConnected:
+		progress.Message = name + " connected"
+	case StateFailed:
+		progress.Message = fmt.Sprintf("%s unavailable after %d attempts, still retrying", name, attempt)
+	}
+
+	initProgressMu.Lock()
+	initProgress[dialect] = progress
+	initProgressMu.Unlock()
+}
+
+// GetInitProgress returns the initialization progress of every backend
+func GetInitProgress() []InitProgress {
+	initProgressMu.Lock()
+	defer initProgressMu.Unlock()
+
+	result := make([]InitProgress, 0, len(initProgress))
+	for _, progress := range initProgress {
+		result = append(result, *progress)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Dialect < result[j].Dialect
+	})
+	return result
+}
@@ -0,0 +1,280 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons a session is terminated by the reaper
+const (
+	ReapIdleInTransaction = "idle_in_transaction"
+	ReapLongRunning       = "long_running"
+)
+
+// Most terminated sessions kept for the report
+const maxReapedSessions = 200
+
+// ReaperSettings are the thresholds past which the reaper terminates a
+// session. A zero threshold disables that check.
+type ReaperSettings struct {
+	Interval          time.Duration
+	IdleInTransaction time.Duration
+	LongRunning       time.Duration
+}
+
+// ReapedSession is a backend session terminated by the reaper
+type ReapedSession struct {
+	Dialect string `json:"dialect"`
+	// Backend PID on PostgreSQL, connection ID on MySQL
+	ID     int64  `json:"id"`
+	Reason string `json:"reason"`
+	// State reported by the server, such as "idle in transaction" or "Query"
+	State string `json:"state"`
+	// How long the session had been idle or running, in seconds
+	Seconds      float64   `json:"seconds"`
+	Query        string    `json:"query,omitempty"`
+	FirstSeen    time.Time `json:"first_seen"`
+	TerminatedAt time.Time `json:"terminated_at"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// backendSession is a session of the playground's database user as listed
+// by the server
+type backendSession struct {
+	id         int64
+	state      string
+	idleInTx   bool
+	active     bool
+	idleFor    float64
+	runningFor float64
+	txFor      float64
+	query      string
+}
+
+// Queries listing the other sessions the playground opened on a backend,
+// recognised by its database user and database. The MySQL query takes the
+// user and database as arguments, which default to the connection's own.
+var backendSessionQueries = map[string]string{
+	"postgresql": `SELECT pid, COALESCE(state, ''),
+		COALESCE(EXTRACT(EPOCH FROM (now() - state_change)), 0),
+		COALESCE(EXTRACT(EPOCH FROM (now() - query_start)), 0),
+		COALESCE(EXTRACT(EPOCH FROM (now() - xact_start)), 0),
+		COALESCE(query, '')
+		FROM pg_stat_activity
+		WHERE datname = current_database() AND usename = current_user
+		AND pid <> pg_backend_pid() AND backend_type = 'client backend'`,
+	"mysql": `SELECT p.ID, p.COMMAND, p.TIME, p.TIME,
+		COALESCE(TIMESTAMPDIFF(SECOND, t.trx_started, NOW()), -1),
+		COALESCE(p.INFO, '')
+		FROM information_schema.PROCESSLIST p
+		LEFT JOIN information_schema.INNODB_TRX t ON t.trx_mysql_thread_id = p.ID
+		WHERE p.USER = COALESCE(?, SUBSTRING_INDEX(CURRENT_USER(), '@', 1)) AND p.DB = COALESCE(?, DATABASE())
+		AND p.ID <> CONNECTION_ID()`,
+}
+
+var (
+	// Thresholds of the running reaper
+	reaperSettings ReaperSettings
+
+	// Sessions terminated by the reaper, oldest first
+	reapedSessions []ReapedSession
+
+	// When each tracked session was first seen, keyed by dialect and ID
+	trackedSessions = make(map[string]time.Time)
+
+	// Guards reaperSettings, reapedSessions and trackedSessions
+	reaperMu sync.Mutex
+)
+
+// StartReaper periodically terminates sessions of the MySQL and PostgreSQL
+// backends that sit idle inside a transaction, or run a statement or
+// transaction, for longer than the thresholds
+func StartReaper(settings ReaperSettings) {
+	reaperMu.Lock()
+	reaperSettings = settings
+	reaperMu.Unlock()
+
+	if settings.Interval <= 0 || (settings.IdleInTransaction <= 0 && settings.LongRunning <= 0) {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(settings.Interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for dialect := range backendSessionQueries {
+				if db, args, ok := reaperConnection(dialect); ok {
+					reapSessions(db, dialect, args, settings)
+				}
+			}
+		}
+	}()
+}
+
+// Reaper returns the reaper thresholds and the sessions it terminated,
+// newest first
+func Reaper() (ReaperSettings, []ReapedSession) {
+	reaperMu.Lock()
+	defer reaperMu.Unlock()
+
+	reaped := make([]ReapedSession, len(reapedSessions))
+	for i, session := range reapedSessions {
+		reaped[len(reapedSessions)-1-i] = session
+	}
+	return reaperSettings, reaped
+}
+
+// reaperConnection returns the pool the reaper reads and terminates a
+// backend's sessions on, with the arguments of its session query. The
+// transactions of MySQL sessions are only visible with the PROCESS
+// privilege, which the playground user lacks, so MySQL sessions are read as
+// the administrator that provisioned the user. Without a bootstrap the
+// playground's own pool is used, as when it connects as the administrator.
+func reaperConnection(dialect string) (*sql.DB, []interface{}, bool) {
+	db, ok := database(dialect)
+	if !ok || dialect != "mysql" {
+		return db, nil, ok
+	}
+	if admin, account, ok := adminDatabase(dialect); ok {
+		return admin, []interface{}{account.User, account.Database}, true
+	}
+	return db, []interface{}{nil, nil}, true
+}
+
+// reapSessions terminates the sessions of one backend that are past a
+// threshold
+func reapSessions(db *sql.DB, dialect string, args []interface{}, settings ReaperSettings) {
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	sessions, err := listBackendSessions(ctx, db, dialect, args)
+	if err != nil {
+		fmt.Printf("Reaper failed to list %s sessions: %v\n", dialect, err)
+		return
+	}
+
+	now := time.Now()
+	reaperMu.Lock()
+	seen := make(map[string]time.Time, len(sessions))
+	for _, session := range sessions {
+		key := fmt.Sprintf("%s:%d", dialect, session.id)
+		firstSeen, ok := trackedSessions[key]
+		if !ok {
+			firstSeen = now
+		}
+		seen[key] = firstSeen
+	}
+	// Forget sessions of this backend that have gone away
+	for key := range trackedSessions {
+		if _, ok := seen[key]; !ok && strings.HasPrefix(key, dialect+":") {
+			delete(trackedSessions, key)
+		}
+	}
+	for key, firstSeen := range seen {
+		trackedSessions[key] = firstSeen
+	}
+	reaperMu.Unlock()
+
+	for _, session := range sessions {
+		reason, seconds := reapReason(session, settings)
+		if reason == "" {
+			continue
+		}
+
+		reaped := ReapedSession{
+			Dialect:      dialect,
+			ID:           session.id,
+			Reason:       reason,
+			State:        session.state,
+			Seconds:      seconds,
+			Query:        session.query,
+			FirstSeen:    seen[fmt.Sprintf("%s:%d", dialect, session.id)],
+			TerminatedAt: time.Now(),
+		}
+		if err := terminateBackendSession(ctx, db, dialect, session.id); err != nil {
+			reaped.Error = err.Error()
+		}
+		fmt.Printf("Reaper terminated %s session %d (%s after %.0fs)\n", dialect, session.id, reason, seconds)
+
+		reaperMu.Lock()
+		reapedSessions = append(reapedSessions, reaped)
+		if len(reapedSessions) > maxReapedSessions {
+			reapedSessions = reapedSessions[len(reapedSessions)-maxReapedSessions:]
+		}
+		reaperMu.Unlock()
+	}
+}
+
+// reapReason returns why a session should be terminated and for how long
+// it has been in that condition, or an empty reason to leave it
+func reapReason(session backendSession, settings ReaperSettings) (string, float64) {
+	if session.idleInTx && settings.IdleInTransaction > 0 && session.idleFor >= settings.IdleInTransaction.Seconds() {
+		return ReapIdleInTransaction, session.idleFor
+	}
+	if settings.LongRunning <= 0 {
+		return "", 0
+	}
+	if session.active && session.runningFor >= settings.LongRunning.Seconds() {
+		return ReapLongRunning, session.runningFor
+	}
+	if session.txFor >= settings.LongRunning.Seconds() {
+		return ReapLongRunning, session.txFor
+	}
+	return "", 0
+}
+
+// listBackendSessions returns the other sessions the playground opened on
+// a backend
+func listBackendSessions(ctx context.Context, db *sql.DB, dialect string, args []interface{}) ([]backendSession, error) {
+	rows, err := db.QueryContext(ctx, backendSessionQueries[dialect], args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []backendSession
+	for rows.Next() {
+		var session backendSession
+		if err := rows.Scan(&session.id, &session.state, &session.idleFor, &session.runningFor, &session.txFor, &session.query); err != nil {
+			return nil, err
+		}
+		switch dialect {
+		case "postgresql":
+			session.idleInTx = session.state == "idle in transaction" || session.state == "idle in transaction (aborted)"
+			session.active = session.state == "active"
+		case "mysql":
+			// A sleeping connection with an open InnoDB transaction
+			session.idleInTx = session.state == "Sleep" && session.txFor >= 0
+			session.active = session.state == "Query" || session.state == "Execute"
+		}
+		if session.txFor < 0 {
+			session.txFor = 0
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].id < sessions[j].id
+	})
+	return sessions, rows.Err()
+}
+
+// terminateBackendSession ends a session and rolls back its transaction
+func terminateBackendSession(ctx context.Context, db *sql.DB, dialect string, id int64) error {
+	switch dialect {
+	case "postgresql":
+		_, err := db.ExecContext(ctx, "SELECT pg_terminate_backend($1)", id)
+		return err
+	case "mysql":
+		// KILL takes no placeholders; the ID is an integer
+		_, err := db.ExecContext(ctx, fmt.Sprintf("KILL %d", id))
+		return err
+	default:
+		return fmt.Errorf("sessions of %s cannot be terminated", dialect)
+	}
+}
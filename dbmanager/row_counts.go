@@ -0,0 +1,100 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"example/user/playground/sqlvalidator"
+)
+
+var (
+	// Approximate row counts of the tables of each dialect's database
+	rowCountCache = make(map[string]map[string]int64)
+
+	// Guards rowCountCache
+	rowCountMu sync.RWMutex
+)
+
+// Queries returning the approximate row count of every table. MySQL and
+// PostgreSQL answer from their statistics; tables PostgreSQL has never
+// analyzed report NULL and are left out. SQLite keeps no statistics, so
+// its query lists the tables to count.
+var rowCountQueries = map[string]string{
+	"sqlite": `SELECT name FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite_%'
+			AND name NOT IN (SELECT name FROM pragma_table_list WHERE type IN ('shadow', 'virtual'))`,
+	"mysql": `SELECT table_name, COALESCE(table_rows, 0)
+		FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'`,
+	"postgresql": `SELECT c.relname, CASE WHEN c.reltuples < 0 THEN NULL ELSE c.reltuples::bigint END
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p', 'm')`,
+}
+
+// CachedRowCounts returns the approximate row counts of the tables of a
+// dialect's database as of the last schema load
+func CachedRowCounts(dialect string) (map[string]int64, bool) {
+	rowCountMu.RLock()
+	defer rowCountMu.RUnlock()
+
+	counts, ok := rowCountCache[dialect]
+	return counts, ok
+}
+
+// loadRowCounts queries the approximate row counts of the tables of a
+// database and stores them in the cache
+func loadRowCounts(ctx context.Context, db *sql.DB, dialect string) error {
+	query, ok := rowCountQueries[dialect]
+	if !ok {
+		return fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	counts := make(map[string]int64)
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		var count sql.NullInt64
+		if dialect == "sqlite" {
+			err = rows.Scan(&table)
+			tables = append(tables, table)
+		} else {
+			err = rows.Scan(&table, &count)
+		}
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		if count.Valid {
+			counts[table] = count.Int64
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		var count int64
+		query := "SELECT COUNT(*) FROM " + sqlvalidator.QuoteIdentifier(table, dialect)
+		if err := db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+			return err
+		}
+		counts[table] = count
+	}
+
+	rowCountMu.Lock()
+	rowCountCache[dialect] = counts
+	rowCountMu.Unlock()
+	return nil
+}
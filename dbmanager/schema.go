@@ -0,0 +1,114 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// Introspected table and column names per dialect
+	schemaCache = make(map[string]map[string][]string)
+
+	// Guards schemaCache
+	schemaCacheMu sync.RWMutex
+)
+
+// Queries listing table and column names in definition order, leaving out
+// the shadow tables behind SQLite's full-text indexes
+var schemaQueries = map[string]string{
+	"sqlite": `SELECT m.name, p.name
+		FROM sqlite_master m JOIN pragma_table_info(m.name) p
+		WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%'
+			AND m.name NOT IN (SELECT name FROM pragma_table_list WHERE type = 'shadow')
+		ORDER BY m.name, p.cid`,
+	"mysql": `SELECT table_name, column_name
+		FROM information_schema.columns
+		WHERE table_schema = DATABASE()
+		ORDER BY table_name, ordinal_position`,
+	// information_schema leaves out materialized views
+	"postgresql": `SELECT c.relname, a.attname
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid
+		WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+			AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum`,
+}
+
+// LoadSchema introspects the tables and columns of a dialect's database and
+// stores them in the schema cache, along with the collations of the text
+// columns and the row counts of the tables
+func LoadSchema(ctx context.Context, dialect string) (map[string][]string, error) {
+	db, ok := database(dialect)
+	if !ok {
+		return nil, fmt.Errorf("no database connection available for %s", dialect)
+	}
+
+	// Pinned versions share the introspection queries of their dialect
+	schema, err := introspectSchema(ctx, db, BaseDialect(dialect))
+	if err != nil {
+		return nil, err
+	}
+
+	schemaCacheMu.Lock()
+	schemaCache[dialect] = schema
+	schemaCacheMu.Unlock()
+
+	// Views and search features only feed introspection endpoints, so the
+	// schema stands without them
+	if err := loadMetadata(ctx, dialect, schema); err != nil {
+		fmt.Printf("Warning: Failed to load %s views and search indexes: %v\n", dialect, err)
+	}
+
+	// Collations only feed warnings, so the schema stands without them
+	if BaseDialect(dialect) != dialect {
+		return schema, nil
+	}
+	if err := loadColumnCollations(ctx, db, dialect); err != nil {
+		fmt.Printf("Warning: Failed to load %s column collations: %v\n", dialect, err)
+	}
+	if err := loadRowCounts(ctx, db, dialect); err != nil {
+		fmt.Printf("Warning: Failed to load %s row counts: %v\n", dialect, err)
+	}
+	return schema, nil
+}
+
+// CachedSchema returns the last introspected schema of a dialect without
+// touching the database
+func CachedSchema(dialect string) (map[string][]string, bool) {
+	schemaCacheMu.RLock()
+	defer schemaCacheMu.RUnlock()
+
+	schema, ok := schemaCache[dialect]
+	return schema, ok
+}
+
+// introspectSchema queries the table and column names of a database
+func introspectSchema(ctx context.Context, db *sql.DB, dialect string) (map[string][]string, error) {
+	query, ok := schemaQueries[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schema := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		schema[table] = append(schema[table], column)
+	}
+	return schema, rows.Err()
+}
@@ -0,0 +1,103 @@
+package dbmanager
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SchemaMetadata is what introspecting a backend's schema found: its tables
+// and columns, views and full-text search features
+type SchemaMetadata struct {
+	Tables        map[string][]string
+	Views         []View
+	SearchIndexes []SearchIndex
+	// When the schema was introspected
+	LoadedAt time.Time
+}
+
+var (
+	// Introspected schema metadata per dialect
+	metadataCache = make(map[string]SchemaMetadata)
+
+	// Guards metadataCache
+	metadataCacheMu sync.RWMutex
+)
+
+// Metadata returns the schema metadata of a dialect's database from the
+// cache, introspecting the database only when nothing is cached, as after
+// InvalidateSchema
+func Metadata(ctx context.Context, dialect string) (SchemaMetadata, error) {
+	if metadata, ok := CachedMetadata(dialect); ok {
+		return metadata, nil
+	}
+	if _, err := LoadSchema(ctx, dialect); err != nil {
+		return SchemaMetadata{}, err
+	}
+	metadata, ok := CachedMetadata(dialect)
+	if !ok {
+		return SchemaMetadata{}, fmt.Errorf("the %s schema was invalidated while it loaded", dialect)
+	}
+	return metadata, nil
+}
+
+// CachedMetadata returns the schema metadata of a dialect as of the last
+// schema load without touching the database
+func CachedMetadata(dialect string) (SchemaMetadata, bool) {
+	metadataCacheMu.RLock()
+	defer metadataCacheMu.RUnlock()
+
+	metadata, ok := metadataCache[dialect]
+	return metadata, ok
+}
+
+// InvalidateSchema forgets everything cached about the schema of a
+// dialect's database: its tables and columns, views, full-text search
+// features, column collations and row counts. Until the schema is loaded
+// again, queries are not checked against it.
+func InvalidateSchema(dialect string) {
+	schemaCacheMu.Lock()
+	delete(schemaCache, dialect)
+	schemaCacheMu.Unlock()
+
+	metadataCacheMu.Lock()
+	delete(metadataCache, dialect)
+	metadataCacheMu.Unlock()
+
+	columnCollationMu.Lock()
+	delete(columnCollationCache, dialect)
+	columnCollationMu.Unlock()
+
+	rowCountMu.Lock()
+	delete(rowCountCache, dialect)
+	rowCountMu.Unlock()
+}
+
+// loadMetadata introspects the views and full-text search features of a
+// dialect's database and caches them with its tables. Pinned versions have
+// neither listed. When introspection fails the tables are cached anyway.
+func loadMetadata(ctx context.Context, dialect string, tables map[string][]string) error {
+	metadata := SchemaMetadata{
+		Tables:        tables,
+		Views:         []View{},
+		SearchIndexes: []SearchIndex{},
+		LoadedAt:      time.Now(),
+	}
+	var err error
+	if BaseDialect(dialect) == dialect {
+		var views []View
+		var indexes []SearchIndex
+		if views, err = ListViews(ctx, dialect); err == nil {
+			metadata.Views = views
+			if indexes, err = ListSearchIndexes(ctx, dialect); err == nil {
+				metadata.SearchIndexes = indexes
+			}
+		}
+	}
+
+	metadataCacheMu.Lock()
+	metadataCache[dialect] = metadata
+	metadataCacheMu.Unlock()
+	return err
+}
@@ -0,0 +1,99 @@
+package dbmanager
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SearchIndex is a full-text search feature of a database: an FTS virtual
+// table on SQLite, a FULLTEXT index on MySQL or a tsvector column on
+// PostgreSQL
+type SearchIndex struct {
+	// Table to search
+	Table string `json:"table"`
+	// Virtual table, index or tsvector column
+	Name    string   `json:"name"`
+	Kind    string   `json:"kind"`
+	Columns []string `json:"columns"`
+	// A query using the index
+	Example string `json:"example"`
+}
+
+// Queries listing full-text search features with their kind and
+// comma-separated columns
+var searchIndexQueries = map[string]string{
+	"sqlite": `SELECT name, name,
+			CASE WHEN lower(sql) LIKE '%using fts5%' THEN 'fts5'
+				WHEN lower(sql) LIKE '%using fts4%' THEN 'fts4'
+				ELSE 'fts3' END,
+			(SELECT group_concat(p.name, ',') FROM pragma_table_info(m.name) p)
+		FROM sqlite_master m
+		WHERE type = 'table' AND lower(sql) LIKE 'create virtual table%using fts%'
+		ORDER BY name`,
+	"mysql": `SELECT table_name, index_name, 'fulltext', GROUP_CONCAT(column_name ORDER BY seq_in_index)
+		FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND index_type = 'FULLTEXT'
+		GROUP BY table_name, index_name
+		ORDER BY table_name, index_name`,
+	"postgresql": `SELECT c.relname, a.attname, 'tsvector', a.attname
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_attribute a ON a.attrelid = c.oid
+		WHERE n.nspname = current_schema() AND c.relkind IN ('r', 'p', 'm')
+			AND a.atttypid = 'tsvector'::regtype AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY c.relname, a.attnum`,
+}
+
+// ListSearchIndexes returns the full-text search features of a dialect's
+// database, each with an example query
+func ListSearchIndexes(ctx context.Context, dialect string) ([]SearchIndex, error) {
+	db, ok := database(dialect)
+	if !ok {
+		return nil, fmt.Errorf("no database connection available for %s", dialect)
+	}
+	query, ok := searchIndexQueries[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := []SearchIndex{}
+	for rows.Next() {
+		var index SearchIndex
+		var columns string
+		if err := rows.Scan(&index.Table, &index.Name, &index.Kind, &columns); err != nil {
+			return nil, err
+		}
+		index.Columns = strings.Split(columns, ",")
+		index.Example = searchExample(index)
+		indexes = append(indexes, index)
+	}
+	return indexes, rows.Err()
+}
+
+// searchExample returns a query searching an index for the word "index"
+func searchExample(index SearchIndex) string {
+	switch index.Kind {
+	case "fts5":
+		return fmt.Sprintf("SELECT rowid, * FROM %s WHERE %s MATCH 'index' ORDER BY rank", index.Table, index.Table)
+	case "fts3", "fts4":
+		return fmt.Sprintf("SELECT rowid, * FROM %s WHERE %s MATCH 'index'", index.Table, index.Table)
+	case "fulltext":
+		return fmt.Sprintf("SELECT * FROM %s WHERE MATCH (%s) AGAINST ('index' IN NATURAL LANGUAGE MODE)",
+			index.Table, strings.Join(index.Columns, ", "))
+	case "tsvector":
+		return fmt.Sprintf("SELECT * FROM %s WHERE %s @@ websearch_to_tsquery('english', 'index') ORDER BY ts_rank(%s, websearch_to_tsquery('english', 'index')) DESC",
+			index.Table, index.Name, index.Name)
+	}
+	return ""
+}
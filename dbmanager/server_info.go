@@ -0,0 +1,114 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"example/user/playground/secrets"
+)
+
+// ServerInfo describes the server behind a backend, detected once each time
+// the backend connects
+type ServerInfo struct {
+	// Product, such as MySQL, MariaDB, PostgreSQL or SQLite
+	Server       string          `json:"server"`
+	Version      string          `json:"version"`
+	Capabilities map[string]bool `json:"capabilities"`
+	// Default character set or encoding of the server
+	CharacterSet string `json:"character_set,omitempty"`
+	// When the server started; for SQLite, when the database was opened
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	DetectedAt time.Time  `json:"detected_at"`
+}
+
+// Features of SQLite by the version that introduced them
+var sqliteFeatures = featureVersions{
+	"json":                {3, 38, 0},
+	"generated_columns":   {3, 31, 0},
+	"cte":                 {3, 8, 3},
+	"window_functions":    {3, 25, 0},
+	"check_constraints":   {3, 0, 0},
+	"triggers":            {3, 0, 0},
+	"views":               {3, 0, 0},
+	"returning":           {3, 35, 0},
+	"full_outer_join":     {3, 39, 0},
+	"descending_indexes":  {3, 3, 0},
+	"functional_indexes":  {3, 9, 0},
+	"recursive_cte":       {3, 8, 3},
+	"intersect_except":    {3, 0, 0},
+	"default_expressions": {3, 0, 0},
+}
+
+var (
+	// Server of each connected backend
+	serverInfos = make(map[string]ServerInfo)
+
+	// Guards serverInfos
+	serverInfosMu sync.RWMutex
+)
+
+// GetServerInfos returns the server of every backend detected since it last
+// connected
+func GetServerInfos() map[string]ServerInfo {
+	serverInfosMu.RLock()
+	defer serverInfosMu.RUnlock()
+
+	infos := make(map[string]ServerInfo, len(serverInfos))
+	for key, info := range serverInfos {
+		infos[key] = info
+	}
+	return infos
+}
+
+// detectServerInfo reads the version, character set and start time of a
+// backend's server and caches them with the features its version has. A
+// backend whose version cannot be read is left without server info.
+func detectServerInfo(key string, db *sql.DB) {
+	ctx, cancel := context.WithTimeout(context.Background(), ProbeTimeout)
+	defer cancel()
+
+	info := ServerInfo{DetectedAt: time.Now()}
+	var err error
+	switch BaseDialect(key) {
+	case "sqlite":
+		err = db.QueryRowContext(ctx, "SELECT sqlite_version()").Scan(&info.Version)
+		db.QueryRowContext(ctx, "PRAGMA encoding").Scan(&info.CharacterSet)
+		info.StartedAt = &info.DetectedAt
+	case "mysql":
+		err = db.QueryRowContext(ctx, "SELECT VERSION(), @@character_set_server").Scan(&info.Version, &info.CharacterSet)
+		var name, uptime string
+		if db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Uptime'").Scan(&name, &uptime) == nil {
+			if seconds, err := strconv.ParseInt(uptime, 10, 64); err == nil {
+				startedAt := info.DetectedAt.Add(-time.Duration(seconds) * time.Second)
+				info.StartedAt = &startedAt
+			}
+		}
+	case "postgresql":
+		err = db.QueryRowContext(ctx, "SELECT current_setting('server_version'), current_setting('server_encoding')").Scan(&info.Version, &info.CharacterSet)
+		var startedAt time.Time
+		if db.QueryRowContext(ctx, "SELECT pg_postmaster_start_time()").Scan(&startedAt) == nil {
+			info.StartedAt = &startedAt
+		}
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to detect the %s server version: %v\n", key, secrets.MaskError(err))
+		return
+	}
+	info.Server, info.Capabilities = identifyServer(BaseDialect(key), info.Version)
+
+	serverInfosMu.Lock()
+	serverInfos[key] = info
+	serverInfosMu.Unlock()
+}
+
+// forgetServerInfo drops the cached server of a backend that disconnected,
+// since it may come back as another version
+func forgetServerInfo(key string) {
+	serverInfosMu.Lock()
+	delete(serverInfos, key)
+	serverInfosMu.Unlock()
+}
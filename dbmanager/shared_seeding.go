@@ -0,0 +1,116 @@
+package dbmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"example/user/playground/sharedstate"
+)
+
+// How long a seeding lock outlives its last refresh, so a crashed instance
+// does not block seeding for good
+const seedLockTTL = time.Minute
+
+// How long the last seeding job of a backend stays visible to other
+// instances
+const seedJobTTL = 24 * time.Hour
+
+// How long shared state calls may take while seeding
+const sharedSeedTimeout = 2 * time.Second
+
+// Identifies this instance as the holder of seeding locks
+var instanceID = newInstanceID()
+
+// newInstanceID returns a random ID for this process
+func newInstanceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprint(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// sharedSeedDialect reports whether a backend is shared by every instance.
+// SQLite databases are files local to each instance.
+func sharedSeedDialect(dialect string) bool {
+	return dialect != "sqlite"
+}
+
+// acquireSeedLock claims the seeding of a shared backend for this instance
+func acquireSeedLock(dialect string) (bool, error) {
+	if !sharedSeedDialect(dialect) {
+		return true, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sharedSeedTimeout)
+	defer cancel()
+	return sharedstate.Current().SetNX(ctx, "large_dataset:lock:"+dialect, []byte(instanceID), seedLockTTL)
+}
+
+// publishSeedJob shares the progress of a job with other instances,
+// refreshing its lock while it runs. It reports whether another instance
+// asked for the job to be cancelled.
+func publishSeedJob(job SeedJob) bool {
+	if !sharedSeedDialect(job.Dialect) {
+		return false
+	}
+	state := sharedstate.Current()
+	ctx, cancel := context.WithTimeout(context.Background(), sharedSeedTimeout)
+	defer cancel()
+
+	data, _ := json.Marshal(job)
+	if err := state.Set(ctx, "large_dataset:job:"+job.Dialect, data, seedJobTTL); err != nil {
+		fmt.Printf("Failed to share %s seeding progress: %v\n", job.Dialect, err)
+	}
+	if job.State != SeedRunning {
+		state.Delete(ctx, "large_dataset:lock:"+job.Dialect)
+		state.Delete(ctx, "large_dataset:cancel:"+job.Dialect)
+		return false
+	}
+
+	state.Set(ctx, "large_dataset:lock:"+job.Dialect, []byte(instanceID), seedLockTTL)
+	_, err := state.Get(ctx, "large_dataset:cancel:"+job.Dialect)
+	return err == nil
+}
+
+// requestSeedCancel asks the instance seeding a shared backend to stop
+func requestSeedCancel(dialect string) error {
+	if !sharedSeedDialect(dialect) {
+		return ErrNoSeedRunning
+	}
+	state := sharedstate.Current()
+	ctx, cancel := context.WithTimeout(context.Background(), sharedSeedTimeout)
+	defer cancel()
+
+	if _, err := state.Get(ctx, "large_dataset:lock:"+dialect); err != nil {
+		return ErrNoSeedRunning
+	}
+	return state.Set(ctx, "large_dataset:cancel:"+dialect, []byte(instanceID), seedLockTTL)
+}
+
+// sharedSeedJobs returns the last seeding job of every shared backend,
+// whichever instance ran it
+func sharedSeedJobs() map[string]SeedJob {
+	state := sharedstate.Current()
+	ctx, cancel := context.WithTimeout(context.Background(), sharedSeedTimeout)
+	defer cancel()
+
+	jobs := make(map[string]SeedJob)
+	for dialect := range largeOrdersTables {
+		if !sharedSeedDialect(dialect) {
+			continue
+		}
+		data, err := state.Get(ctx, "large_dataset:job:"+dialect)
+		if err != nil {
+			continue
+		}
+		var job SeedJob
+		if json.Unmarshal(data, &job) == nil {
+			jobs[dialect] = job
+		}
+	}
+	return jobs
+}
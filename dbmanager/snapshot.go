@@ -0,0 +1,252 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// Directory where SQLite snapshots are stored
+	snapshotDir = "./snapshots"
+
+	// Number of automatic snapshots to keep before pruning the oldest
+	maxAutoSnapshots = 5
+
+	// Allowed snapshot names: letters, digits, dashes and underscores
+	snapshotNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+)
+
+// ErrSnapshotExists is returned when a snapshot of the requested name is
+// already stored
+var ErrSnapshotExists = errors.New("a snapshot with this name already exists")
+
+// autoSnapshotPrefix marks snapshots created by the periodic snapshotter
+const autoSnapshotPrefix = "auto-"
+
+// SnapshotInfo describes a stored SQLite snapshot
+type SnapshotInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreateSQLiteSnapshot copies the live SQLite database into a named
+// snapshot. An existing snapshot is never overwritten.
+func CreateSQLiteSnapshot(ctx context.Context, name string) (*SnapshotInfo, error) {
+	if !snapshotNameRegex.MatchString(name) {
+		return nil, errors.New("invalid snapshot name")
+	}
+
+	src, ok := database("sqlite")
+	if !ok {
+		return nil, errors.New("no database connection available for sqlite")
+	}
+
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return nil, err
+	}
+
+	// Claim the name first, so concurrent snapshots cannot share a file
+	path := snapshotPath(name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, ErrSnapshotExists
+		}
+		return nil, err
+	}
+	file.Close()
+
+	dst, err := sql.Open("sqlite3", path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	err = backupSQLite(ctx, dst, src)
+	dst.Close()
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("snapshot failed: %v", err)
+	}
+
+	return statSnapshot(name)
+}
+
+// RestoreSQLiteSnapshot overwrites the live SQLite database with a named snapshot
+func RestoreSQLiteSnapshot(ctx context.Context, name string) error {
+	if !snapshotNameRegex.MatchString(name) {
+		return errors.New("invalid snapshot name")
+	}
+
+	dst, ok := database("sqlite")
+	if !ok {
+		return errors.New("no database connection available for sqlite")
+	}
+
+	path := snapshotPath(name)
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("snapshot %s not found", name)
+	}
+
+	src, err := sql.Open("sqlite3", path+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := backupSQLite(ctx, dst, src); err != nil {
+		return fmt.Errorf("restore failed: %v", err)
+	}
+	return nil
+}
+
+// ListSQLiteSnapshots returns all stored snapshots, newest first
+func ListSQLiteSnapshots() ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []SnapshotInfo{}, nil
+		}
+		return nil, err
+	}
+
+	snapshots := []SnapshotInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sqlite") {
+			continue
+		}
+		info, err := statSnapshot(strings.TrimSuffix(entry.Name(), ".sqlite"))
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, *info)
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt)
+	})
+	return snapshots, nil
+}
+
+// DeleteSQLiteSnapshot removes a named snapshot
+func DeleteSQLiteSnapshot(name string) error {
+	if !snapshotNameRegex.MatchString(name) {
+		return errors.New("invalid snapshot name")
+	}
+	if err := os.Remove(snapshotPath(name)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("snapshot %s not found", name)
+		}
+		return err
+	}
+	return nil
+}
+
+// StartPeriodicSnapshots takes an automatic SQLite snapshot on every interval
+// and keeps only the most recent ones
+func StartPeriodicSnapshots(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			name := autoSnapshotPrefix + time.Now().Format("20060102-150405")
+			if _, err := CreateSQLiteSnapshot(context.Background(), name); err != nil {
+				fmt.Printf("Automatic SQLite snapshot failed: %v\n", err)
+				continue
+			}
+			pruneAutoSnapshots()
+		}
+	}()
+}
+
+// pruneAutoSnapshots deletes automatic snapshots beyond maxAutoSnapshots
+func pruneAutoSnapshots() {
+	snapshots, err := ListSQLiteSnapshots()
+	if err != nil {
+		return
+	}
+
+	kept := 0
+	for _, snapshot := range snapshots {
+		if !strings.HasPrefix(snapshot.Name, autoSnapshotPrefix) {
+			continue
+		}
+		kept++
+		if kept > maxAutoSnapshots {
+			os.Remove(snapshotPath(snapshot.Name))
+		}
+	}
+}
+
+// snapshotPath returns the file path of a named snapshot
+func snapshotPath(name string) string {
+	return filepath.Join(snapshotDir, name+".sqlite")
+}
+
+// statSnapshot builds a SnapshotInfo from the snapshot file on disk
+func statSnapshot(name string) (*SnapshotInfo, error) {
+	stat, err := os.Stat(snapshotPath(name))
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotInfo{
+		Name:      name,
+		Size:      stat.Size(),
+		CreatedAt: stat.ModTime(),
+	}, nil
+}
+
+// backupSQLite copies the main database of src into dst using the SQLite backup API
+func backupSQLite(ctx context.Context, dst *sql.DB, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(dstDriverConn interface{}) error {
+		return srcConn.Raw(func(srcDriverConn interface{}) error {
+			dstSQLite, ok := dstDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("destination is not a SQLite connection")
+			}
+			srcSQLite, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return errors.New("source is not a SQLite connection")
+			}
+
+			backup, err := dstSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+
+			// Copy all pages in a single step
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// useSQLite makes a fresh SQLite database the live sqlite backend, with
+// snapshots stored in a directory of the test's own
+func useSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "playground.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousDir := snapshotDir
+	snapshotDir = filepath.Join(dir, "snapshots")
+	databasesMu.Lock()
+	previous, connected := databases["sqlite"]
+	databases["sqlite"] = db
+	databasesMu.Unlock()
+
+	t.Cleanup(func() {
+		databasesMu.Lock()
+		if connected {
+			databases["sqlite"] = previous
+		} else {
+			delete(databases, "sqlite")
+		}
+		databasesMu.Unlock()
+		snapshotDir = previousDir
+		db.Close()
+	})
+	return db
+}
+
+// countRows returns the number of rows of a table
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func TestSnapshotRestoresData(t *testing.T) {
+	db := useSQLite(t)
+	ctx := context.Background()
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT); INSERT INTO notes VALUES ('kept')"); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := CreateSQLiteSnapshot(ctx, "before")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Name != "before" || snapshot.Size == 0 {
+		t.Errorf("expected the snapshot to be described, got %+v", snapshot)
+	}
+
+	if _, err := db.Exec("INSERT INTO notes VALUES ('lost'); CREATE TABLE scratch (x INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreSQLiteSnapshot(ctx, "before"); err != nil {
+		t.Fatal(err)
+	}
+	if count := countRows(t, db, "notes"); count != 1 {
+		t.Errorf("expected the snapshot's single row after restoring, got %d", count)
+	}
+	if _, err := db.Exec("SELECT * FROM scratch"); err == nil {
+		t.Error("expected a table created after the snapshot to be gone")
+	}
+
+	snapshots, err := ListSQLiteSnapshots()
+	if err != nil || len(snapshots) != 1 || snapshots[0].Name != "before" {
+		t.Errorf("expected the snapshot to be listed, got %+v %v", snapshots, err)
+	}
+}
+
+func TestCreateSnapshotKeepsExistingSnapshots(t *testing.T) {
+	db := useSQLite(t)
+	ctx := context.Background()
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT); INSERT INTO notes VALUES ('first')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateSQLiteSnapshot(ctx, "daily"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.Exec("INSERT INTO notes VALUES ('second')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateSQLiteSnapshot(ctx, "daily"); err != ErrSnapshotExists {
+		t.Fatalf("expected ErrSnapshotExists, got %v", err)
+	}
+
+	if err := RestoreSQLiteSnapshot(ctx, "daily"); err != nil {
+		t.Fatal(err)
+	}
+	if count := countRows(t, db, "notes"); count != 1 {
+		t.Errorf("expected the first snapshot to be unchanged, got %d rows", count)
+	}
+}
+
+func TestSnapshotNamesAndMissingSnapshots(t *testing.T) {
+	useSQLite(t)
+	ctx := context.Background()
+	if _, err := CreateSQLiteSnapshot(ctx, "../escape"); err == nil {
+		t.Error("expected a name with a path to be rejected")
+	}
+	if err := RestoreSQLiteSnapshot(ctx, "missing"); err == nil {
+		t.Error("expected restoring a missing snapshot to fail")
+	}
+	if err := DeleteSQLiteSnapshot("missing"); err == nil {
+		t.Error("expected deleting a missing snapshot to fail")
+	}
+}
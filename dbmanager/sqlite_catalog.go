@@ -0,0 +1,147 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Alias the catalog database is attached under on the sandboxed SQLite
+// connections. Users pick neither the alias nor the file.
+const SQLiteCatalogAlias = "catalog"
+
+// Suppliers of the catalog database
+var catalogSuppliers = []struct {
+	name    string
+	country string
+}{
+	{"Northwind Traders", "USA"},
+	{"Maple Components", "Canada"},
+	{"Rhine Industrial", "Germany"},
+	{"Sakura Supply", "Japan"},
+}
+
+// Catalog entries of the items in test_data, by item ID: category,
+// supplier index and list price
+var catalogItems = []struct {
+	itemID    int
+	category  string
+	supplier  int
+	listPrice float64
+}{
+	{1, "Hardware", 0, 110.00},
+	{2, "Hardware", 0, 215.50},
+	{3, "Software", 1, 299.00},
+	{4, "Software", 1, 420.00},
+	{5, "Services", 2, 510.00},
+	{6, "Services", 2, 575.00},
+	{7, "Hardware", 3, 720.00},
+	{8, "Software", 3, 790.00},
+	// Items 9 and 10 are missing so outer joins have something to show
+}
+
+// Path of the catalog database, set by seedSQLiteCatalog
+var sqliteCatalogPath string
+
+// seedSQLiteCatalog (re)creates the catalog database next to the
+// playground at SQLITE_CATALOG_PATH (default ./catalog.sqlite). It holds
+// suppliers and item_catalog, keyed by the IDs of test_data, for practicing
+// joins across databases.
+func seedSQLiteCatalog() error {
+	path := envOr("SQLITE_CATALOG_PATH", "./catalog.sqlite")
+
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	statements := []string{
+		`DROP TABLE IF EXISTS item_catalog`,
+		`DROP TABLE IF EXISTS suppliers`,
+		`CREATE TABLE suppliers (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			country TEXT NOT NULL
+		)`,
+		`CREATE TABLE item_catalog (
+			item_id INTEGER PRIMARY KEY,
+			category TEXT NOT NULL,
+			supplier_id INTEGER NOT NULL REFERENCES suppliers (id),
+			list_price REAL NOT NULL
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	for i, supplier := range catalogSuppliers {
+		if _, err := db.Exec(`INSERT INTO suppliers (id, name, country) VALUES (?, ?, ?)`,
+			i+1, supplier.name, supplier.country); err != nil {
+			return err
+		}
+	}
+	for _, item := range catalogItems {
+		if _, err := db.Exec(`INSERT INTO item_catalog (item_id, category, supplier_id, list_price) VALUES (?, ?, ?, ?)`,
+			item.itemID, item.category, item.supplier+1, item.listPrice); err != nil {
+			return err
+		}
+	}
+
+	sqliteCatalogPath = path
+	return nil
+}
+
+// attachSQLiteCatalog attaches the catalog database read-only to a new
+// sandboxed connection, before its authorizer is registered. Attaching
+// stays possible only because the server runs it; the limit set afterwards
+// leaves no room for another database.
+func attachSQLiteCatalog(conn *sqlite3.SQLiteConn) error {
+	if sqliteCatalogPath == "" {
+		conn.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+		return nil
+	}
+
+	uri := "file:" + strings.ReplaceAll(sqliteCatalogPath, "?", "%3f") + "?mode=ro"
+	if _, err := conn.Exec(fmt.Sprintf("ATTACH DATABASE '%s' AS %s",
+		strings.ReplaceAll(uri, "'", "''"), SQLiteCatalogAlias), nil); err != nil {
+		// The playground stays usable without the catalog
+		fmt.Printf("Warning: Failed to attach the SQLite catalog: %v\n", err)
+		conn.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+		return nil
+	}
+	conn.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 1)
+	return nil
+}
+
+// SQLiteCatalogSchema returns the tables and columns of the attached
+// catalog database, or false when it is not available
+func SQLiteCatalogSchema() (map[string][]string, bool) {
+	db, ok := sandboxedDatabase()
+	if !ok || sqliteCatalogPath == "" {
+		return nil, false
+	}
+
+	rows, err := db.Query(`SELECT m.name, p.name
+		FROM ` + SQLiteCatalogAlias + `.sqlite_master m JOIN pragma_table_info(m.name, '` + SQLiteCatalogAlias + `') p
+		WHERE m.type = 'table'
+		ORDER BY m.name, p.cid`)
+	if err != nil {
+		return nil, false
+	}
+	defer rows.Close()
+
+	schema := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, false
+		}
+		schema[table] = append(schema[table], column)
+	}
+	return schema, rows.Err() == nil
+}
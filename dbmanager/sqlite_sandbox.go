@@ -0,0 +1,173 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Driver whose connections run user statements under the sandbox
+// authorizer
+const sqliteSandboxDriver = "sqlite3_sandbox"
+
+// Pragmas that only report on the database, whatever their argument
+var readOnlyPragmas = map[string]bool{
+	"table_info":        true,
+	"table_xinfo":       true,
+	"table_list":        true,
+	"index_list":        true,
+	"index_info":        true,
+	"index_xinfo":       true,
+	"foreign_key_list":  true,
+	"foreign_key_check": true,
+	"integrity_check":   true,
+	"quick_check":       true,
+	"collation_list":    true,
+	"function_list":     true,
+	"pragma_list":       true,
+	"database_list":     true,
+	"compile_options":   true,
+}
+
+// SQL functions that reach outside the database file
+var deniedSQLiteFunctions = map[string]bool{
+	"load_extension": true,
+	"readfile":       true,
+	"writefile":      true,
+	"edit":           true,
+	"fts3_tokenizer": true,
+}
+
+var (
+	// Pool of the SQLite playground whose connections carry the sandbox
+	// authorizer; the pool in databases stays trusted for the server's own
+	// statements
+	sandboxedSQLite *sql.DB
+
+	// Table name patterns user statements may touch, from
+	// SQLITE_TABLE_ALLOWLIST; empty allows every table
+	sqliteTableAllowlist []string
+
+	// Guards sandboxedSQLite
+	sandboxMu sync.RWMutex
+)
+
+func init() {
+	sql.Register(sqliteSandboxDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			if err := attachSQLiteCatalog(conn); err != nil {
+				return err
+			}
+			conn.RegisterAuthorizer(sqliteAuthorizer)
+			return nil
+		},
+	})
+}
+
+// openSQLiteSandbox opens the sandboxed pool of the SQLite playground and
+// reads SQLITE_TABLE_ALLOWLIST, a comma-separated list of table names or
+// patterns such as "lesson_*"
+func openSQLiteSandbox(dsn string) error {
+	sqliteTableAllowlist = nil
+	for _, pattern := range strings.Split(os.Getenv("SQLITE_TABLE_ALLOWLIST"), ",") {
+		if pattern = strings.ToLower(strings.TrimSpace(pattern)); pattern != "" {
+			sqliteTableAllowlist = append(sqliteTableAllowlist, pattern)
+		}
+	}
+
+	db, err := sql.Open(sqliteSandboxDriver, dsn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return err
+	}
+
+	sandboxMu.Lock()
+	if sandboxedSQLite != nil {
+		sandboxedSQLite.Close()
+	}
+	sandboxedSQLite = db
+	sandboxMu.Unlock()
+	return nil
+}
+
+// sandboxedDatabase returns the sandboxed SQLite pool, if open
+func sandboxedDatabase() (*sql.DB, bool) {
+	sandboxMu.RLock()
+	defer sandboxMu.RUnlock()
+	return sandboxedSQLite, sandboxedSQLite != nil
+}
+
+// sqliteAuthorizer is consulted by SQLite for every action a statement
+// takes while it is compiled. It denies attaching databases, changing
+// settings through PRAGMA, functions reaching the file system, and tables
+// outside the main and temp databases or the allowlist. Tables of the
+// server-attached catalog can be read but not changed.
+func sqliteAuthorizer(action int, arg1 string, arg2 string, database string) int {
+	switch action {
+	case sqlite3.SQLITE_ATTACH, sqlite3.SQLITE_DETACH:
+		return sqlite3.SQLITE_DENY
+
+	case sqlite3.SQLITE_PRAGMA:
+		// A pragma without an argument only reads its setting
+		if arg2 == "" || readOnlyPragmas[strings.ToLower(arg1)] {
+			return sqlite3.SQLITE_OK
+		}
+		return sqlite3.SQLITE_DENY
+
+	case sqlite3.SQLITE_FUNCTION:
+		if deniedSQLiteFunctions[strings.ToLower(arg2)] {
+			return sqlite3.SQLITE_DENY
+		}
+		return sqlite3.SQLITE_OK
+
+	case sqlite3.SQLITE_READ:
+		if database == SQLiteCatalogAlias || sqliteTableAllowed(arg1, database) {
+			return sqlite3.SQLITE_OK
+		}
+		return sqlite3.SQLITE_DENY
+
+	case sqlite3.SQLITE_INSERT, sqlite3.SQLITE_UPDATE, sqlite3.SQLITE_DELETE,
+		sqlite3.SQLITE_CREATE_TABLE, sqlite3.SQLITE_CREATE_TEMP_TABLE,
+		sqlite3.SQLITE_DROP_TABLE, sqlite3.SQLITE_DROP_TEMP_TABLE:
+		if !sqliteTableAllowed(arg1, database) {
+			return sqlite3.SQLITE_DENY
+		}
+		return sqlite3.SQLITE_OK
+
+	case sqlite3.SQLITE_ALTER_TABLE:
+		// The database comes first and the table second for ALTER TABLE
+		if !sqliteTableAllowed(arg2, arg1) {
+			return sqlite3.SQLITE_DENY
+		}
+		return sqlite3.SQLITE_OK
+	}
+	return sqlite3.SQLITE_OK
+}
+
+// sqliteTableAllowed reports whether user statements may use a table. The
+// sqlite_ schema tables stay reachable so DDL and schema queries work.
+func sqliteTableAllowed(table string, database string) bool {
+	if database != "" && database != "main" && database != "temp" {
+		return false
+	}
+	table = strings.ToLower(table)
+	if len(sqliteTableAllowlist) == 0 || strings.HasPrefix(table, "sqlite_") {
+		return true
+	}
+	for _, pattern := range sqliteTableAllowlist {
+		if matched, _ := path.Match(pattern, table); matched {
+			return true
+		}
+	}
+	return false
+}
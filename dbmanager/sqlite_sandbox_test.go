@@ -0,0 +1,106 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openSandbox opens a fresh SQLite database through the sandbox driver,
+// as user statements see the playground
+func openSandbox(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	dir := t.TempDir()
+	db, err := sql.Open(sqliteSandboxDriver, filepath.Join(dir, "playground.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("CREATE TABLE notes (body TEXT); INSERT INTO notes VALUES ('kept')"); err != nil {
+		t.Fatal(err)
+	}
+	return db, dir
+}
+
+// expectDenied fails the test unless the authorizer refuses a statement.
+// Denied reads are reported as prohibited, anything else as not authorized.
+func expectDenied(t *testing.T, db *sql.DB, statement string) {
+	t.Helper()
+	_, err := db.Exec(statement)
+	if err == nil || !strings.Contains(err.Error(), "not authorized") && !strings.Contains(err.Error(), "prohibited") {
+		t.Errorf("expected %q to be denied, got %v", statement, err)
+	}
+}
+
+func TestSandboxDeniesAttach(t *testing.T) {
+	db, dir := openSandbox(t)
+	expectDenied(t, db, "ATTACH DATABASE '"+filepath.Join(dir, "other.sqlite")+"' AS other")
+	expectDenied(t, db, "DETACH DATABASE other")
+}
+
+func TestSandboxDeniesWritingPragmas(t *testing.T) {
+	db, _ := openSandbox(t)
+	for _, statement := range []string{
+		"PRAGMA journal_mode = OFF",
+		"PRAGMA writable_schema = ON",
+		"PRAGMA foreign_keys = OFF",
+		"PRAGMA main.synchronous = 0",
+	} {
+		expectDenied(t, db, statement)
+	}
+
+	// Reading a setting or the schema is allowed
+	for _, statement := range []string{"PRAGMA journal_mode", "PRAGMA table_info(notes)"} {
+		rows, err := db.Query(statement)
+		if err != nil {
+			t.Errorf("expected %q to be allowed, got %v", statement, err)
+			continue
+		}
+		rows.Close()
+	}
+}
+
+func TestSandboxDeniesVacuumInto(t *testing.T) {
+	db, dir := openSandbox(t)
+	target := filepath.Join(dir, "copy.sqlite")
+	// VACUUM INTO attaches its target, which the authorizer denies
+	if _, err := db.Exec("VACUUM INTO '" + target + "'"); err == nil || !strings.Contains(err.Error(), "authorization denied") {
+		t.Errorf("expected VACUUM INTO to be denied, got %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("expected no copy of the database to be written, got %v", err)
+	}
+}
+
+func TestSandboxDeniesFunctions(t *testing.T) {
+	db, _ := openSandbox(t)
+	for _, statement := range []string{
+		"SELECT load_extension('/tmp/evil.so')",
+		"SELECT fts3_tokenizer('simple')",
+	} {
+		expectDenied(t, db, statement)
+	}
+
+	var body string
+	if err := db.QueryRow("SELECT upper(body) FROM notes").Scan(&body); err != nil || body != "KEPT" {
+		t.Errorf("expected other functions to be allowed, got %q %v", body, err)
+	}
+}
+
+func TestSandboxTableAllowlist(t *testing.T) {
+	db, _ := openSandbox(t)
+	if _, err := db.Exec("CREATE TABLE secrets (value TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	previous := sqliteTableAllowlist
+	sqliteTableAllowlist = []string{"note*"}
+	t.Cleanup(func() { sqliteTableAllowlist = previous })
+
+	expectDenied(t, db, "SELECT * FROM secrets")
+	expectDenied(t, db, "DELETE FROM secrets")
+	if _, err := db.Exec("INSERT INTO notes VALUES ('allowed')"); err != nil {
+		t.Errorf("expected an allowlisted table to be writable, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package dbmanager
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"example/user/playground/secrets"
+)
+
+// StatusChange is emitted when a backend connects or disconnects
+type StatusChange struct {
+	Dialect   string    `json:"dialect"`
+	Connected bool      `json:"connected"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+var (
+	// Functions notified of connection status changes
+	statusListeners []func(StatusChange)
+
+	// Guards statusListeners
+	statusListenersMu sync.Mutex
+
+	// Channels that wake a sleeping supervisor, keyed by dialect
+	supervisorWake = map[string]chan struct{}{
+		"mysql":      make(chan struct{}, 1),
+		"postgresql": make(chan struct{}, 1),
+	}
+)
+
+// OnStatusChange registers a function to be called whenever a backend
+// connects or disconnects. Listeners run on the supervisor goroutine and
+// must not block.
+func OnStatusChange(listener func(StatusChange)) {
+	statusListenersMu.Lock()
+	statusListeners = append(statusListeners, listener)
+	statusListenersMu.Unlock()
+}
+
+// setConnected records the connection status of a backend and notifies the
+// listeners if it changed
+func setConnected(dialect string, connected bool, err error) {
+	databasesMu.Lock()
+	changed := connectionStatuses[dialect] != connected
+	connectionStatuses[dialect] = connected
+	databasesMu.Unlock()
+	if !changed {
+		return
+	}
+
+	change := StatusChange{Dialect: dialect, Connected: connected, Time: time.Now()}
+	if err != nil {
+		change.Error = secrets.MaskError(err)
+	}
+
+	statusListenersMu.Lock()
+	listeners := append([]func(StatusChange){}, statusListeners...)
+	statusListenersMu.Unlock()
+	for _, listener := range listeners {
+		listener(change)
+	}
+}
+
+// Recheck makes the supervisor of a backend check it immediately, as after
+// its container was started again
+func Recheck(dialect string) {
+	wakeSupervisor(dialect)
+}
+
+// wakeSupervisor makes the supervisor of a backend check it immediately
+// instead of waiting for its next health check or retry
+func wakeSupervisor(dialect string) {
+	select {
+	case supervisorWake[dialect] <- struct{}{}:
+	default:
+	}
+}
+
+// supervise keeps a backend connected for the lifetime of the process. It
+// connects with exponential backoff, never giving up, and once connected
+// pings the backend on its health check interval, caching the outcome and
+// reconnecting when it goes away. The first
+// maxRetries attempts are reported as startup progress; after that the
+// backend is reported as failed while the retries continue.
+func supervise(dialect string, driver string, maxRetries int) {
+	attempt := 0
+	for {
+		if db, ok := database(dialect); ok {
+			sleep(dialect, healthCheckInterval(dialect))
+			if err := pingBackend(context.Background(), dialect, db); err != nil {
+				fmt.Printf("Lost connection to %s: %v\n", dialect, secrets.MaskError(err))
+				dropDatabase(dialect, db)
+				setConnected(dialect, false, err)
+				attempt = 0
+			}
+			continue
+		}
+
+		attempt++
+		fmt.Printf("Attempting to connect to %s (attempt %d)\n", dialect, attempt)
+		setProgress(dialect, StateConnecting, attempt, maxRetries, nil, 0)
+
+		start := time.Now()
+		err := tryConnect(dialect, driver)
+		if err == nil {
+			if db, ok := database(dialect); ok {
+				pingBackend(context.Background(), dialect, db)
+			}
+			setProgress(dialect, StateConnected, attempt, maxRetries, nil, 0)
+			setConnected(dialect, true, nil)
+			attempt = 0
+			continue
+		}
+
+		recordHealth(dialect, start, time.Since(start), err)
+		delay := retryDelay(attempt)
+		if attempt >= maxRetries {
+			setProgress(dialect, StateFailed, attempt, maxRetries, err, delay)
+		} else {
+			setProgress(dialect, StateWaiting, attempt, maxRetries, err, delay)
+		}
+		sleep(dialect, delay)
+	}
+}
+
+// sleep waits for the given duration or until the supervisor is woken
+func sleep(dialect string, duration time.Duration) {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-supervisorWake[dialect]:
+	}
+}
+
+// dropDatabase removes a dead connection pool so that requests fail fast
+// until the supervisor has reconnected
+func dropDatabase(dialect string, db *sql.DB) {
+	databasesMu.Lock()
+	if databases[dialect] == db {
+		delete(databases, dialect)
+	}
+	databasesMu.Unlock()
+	forgetServerInfo(dialect)
+	db.Close()
+}
@@ -0,0 +1,146 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// The sensor readings dataset: every sensor reports every interval from
+// start for the given number of days, apart from occasional outages
+const (
+	readingInterval = 15 * time.Minute
+	readingDays     = 30
+	readingBatch    = 500
+)
+
+// First reading of the dataset; fixed so exercises have stable answers
+var readingStart = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Sensors, where they are placed and the temperature they hover around
+var sensors = []struct {
+	id       int
+	location string
+	baseTemp float64
+}{
+	{1, "Server room", 19.5},
+	{2, "Office", 21.0},
+	{3, "Warehouse", 14.0},
+	{4, "Greenhouse", 24.0},
+	{5, "Cold storage", 4.0},
+	{6, "Lobby", 20.0},
+	{7, "Rooftop", 8.0},
+	{8, "Basement", 12.5},
+}
+
+// Table definitions of sensor_readings per dialect
+var sensorReadingsTables = map[string]string{
+	"sqlite": `CREATE TABLE IF NOT EXISTS sensor_readings (
+		id INTEGER PRIMARY KEY,
+		sensor_id INTEGER NOT NULL,
+		location TEXT NOT NULL,
+		recorded_at TEXT NOT NULL,
+		temperature REAL NOT NULL,
+		humidity REAL NOT NULL
+	)`,
+	"mysql": `CREATE TABLE IF NOT EXISTS sensor_readings (
+		id INT AUTO_INCREMENT PRIMARY KEY,
+		sensor_id INT NOT NULL,
+		location VARCHAR(50) NOT NULL,
+		recorded_at DATETIME NOT NULL,
+		temperature DECIMAL(5,2) NOT NULL,
+		humidity DECIMAL(5,2) NOT NULL,
+		INDEX idx_sensor_readings_sensor_time (sensor_id, recorded_at)
+	)`,
+	"postgresql": `CREATE TABLE IF NOT EXISTS sensor_readings (
+		id SERIAL PRIMARY KEY,
+		sensor_id INTEGER NOT NULL,
+		location VARCHAR(50) NOT NULL,
+		recorded_at TIMESTAMP NOT NULL,
+		temperature NUMERIC(5,2) NOT NULL,
+		humidity NUMERIC(5,2) NOT NULL
+	)`,
+}
+
+// Indexes created separately where CREATE TABLE cannot declare them
+var sensorReadingsIndexes = map[string]string{
+	"sqlite":     `CREATE INDEX IF NOT EXISTS idx_sensor_readings_sensor_time ON sensor_readings (sensor_id, recorded_at)`,
+	"postgresql": `CREATE INDEX IF NOT EXISTS idx_sensor_readings_sensor_time ON sensor_readings (sensor_id, recorded_at)`,
+}
+
+// seedSensorReadings creates the sensor_readings time series and fills it
+// with a month of readings when it is empty
+func seedSensorReadings(db *sql.DB, dialect string) error {
+	if _, err := db.Exec(sensorReadingsTables[dialect]); err != nil {
+		return err
+	}
+	if index, ok := sensorReadingsIndexes[dialect]; ok {
+		if _, err := db.Exec(index); err != nil {
+			return err
+		}
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sensor_readings").Scan(&count); err != nil || count > 0 {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows := sensorReadingRows()
+	for start := 0; start < len(rows); start += readingBatch {
+		end := start + readingBatch
+		if end > len(rows) {
+			end = len(rows)
+		}
+		stmt := "INSERT INTO sensor_readings (sensor_id, location, recorded_at, temperature, humidity) VALUES " +
+			strings.Join(rows[start:end], ", ")
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// sensorReadingRows generates the VALUES tuples of the dataset. Temperatures
+// follow a daily cycle with a slow drift and noise, humidity moves against
+// temperature, and each sensor has a few outages with no readings so gap
+// detection exercises have something to find.
+func sensorReadingRows() []string {
+	random := rand.New(rand.NewSource(42))
+	steps := int(readingDays * 24 * time.Hour / readingInterval)
+	rows := make([]string, 0, steps*len(sensors))
+
+	for _, sensor := range sensors {
+		outageStart := -1
+		outageEnd := -1
+		for step := 0; step < steps; step++ {
+			// Roughly two outages a month of one to six hours each
+			if step > outageEnd && random.Intn(steps/2) == 0 {
+				outageStart = step
+				outageEnd = step + 4 + random.Intn(20)
+			}
+			if step >= outageStart && step <= outageEnd {
+				continue
+			}
+
+			at := readingStart.Add(time.Duration(step) * readingInterval)
+			hour := float64(at.Hour()) + float64(at.Minute())/60
+			daily := 3 * math.Sin((hour-9)/24*2*math.Pi)
+			drift := 1.5 * math.Sin(float64(step)/float64(steps)*2*math.Pi)
+			temperature := sensor.baseTemp + daily + drift + random.NormFloat64()*0.4
+			humidity := math.Max(15, math.Min(95, 55-1.5*(daily+drift)+random.NormFloat64()*3))
+
+			rows = append(rows, fmt.Sprintf("(%d, '%s', '%s', %.2f, %.2f)",
+				sensor.id, sensor.location, at.Format("2006-01-02 15:04:05"), temperature, humidity))
+		}
+	}
+	return rows
+}
@@ -0,0 +1,130 @@
+package dbmanager
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Separates the dialect from the version in the key of a pinned backend,
+// such as "postgresql@16"
+const versionSeparator = "@"
+
+// ErrUnknownVersion is returned for a version no backend is registered for
+var ErrUnknownVersion = errors.New("unknown dialect version")
+
+// Prefixes of the environment variables holding the DSN of an additional
+// backend version per dialect, such as POSTGRES_DSN_16 or MYSQL_DSN_8_4
+var versionDSNPrefixes = map[string]string{
+	"mysql":      "MYSQL_DSN_",
+	"postgresql": "POSTGRES_DSN_",
+}
+
+// ConnectionKey returns the key a backend is registered under: the dialect
+// itself, or the dialect and version of a pinned backend
+func ConnectionKey(dialect string, version string) string {
+	if version == "" {
+		return dialect
+	}
+	return dialect + versionSeparator + version
+}
+
+// SplitConnectionKey returns the dialect and version of a connection key.
+// The version is empty for the default backend of a dialect.
+func SplitConnectionKey(key string) (string, string) {
+	dialect, version, _ := strings.Cut(key, versionSeparator)
+	return dialect, version
+}
+
+// BaseDialect returns the SQL dialect of a connection key
+func BaseDialect(key string) string {
+	dialect, _ := SplitConnectionKey(key)
+	return dialect
+}
+
+// discoverVersionedConnectionStrings finds the DSNs of additional backend
+// versions in the environment. Underscores in the variable's suffix stand
+// for dots, so MYSQL_DSN_8_4 registers mysql@8.4. The DSNs get the same
+// search_path and recursion settings as the default backends.
+func discoverVersionedConnectionStrings() map[string]string {
+	namespace := playgroundNamespace()
+	dsns := make(map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		for dialect, prefix := range versionDSNPrefixes {
+			if !strings.HasPrefix(name, prefix) || value == "" {
+				continue
+			}
+			version := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, prefix), "_", "."))
+			if !validVersion(version) {
+				fmt.Printf("Ignoring %s: versions may only contain letters, digits and dots\n", name)
+				continue
+			}
+			switch dialect {
+			case "mysql":
+				value = withRecursionDepth(value)
+			case "postgresql":
+				value = withSearchPath(value, namespace)
+			}
+			dsns[ConnectionKey(dialect, version)] = value
+		}
+	}
+	return dsns
+}
+
+// validVersion reports whether a version label is safe to use in keys,
+// messages and URLs
+func validVersion(version string) bool {
+	if version == "" || len(version) > 32 {
+		return false
+	}
+	for _, r := range version {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// Versions returns the pinned versions registered for a dialect, sorted
+func Versions(dialect string) []string {
+	versions := []string{}
+	for key := range connectionStrings {
+		if keyDialect, version := SplitConnectionKey(key); keyDialect == dialect && version != "" {
+			versions = append(versions, version)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// GetVersionedConnection returns the connection to a pinned version of a
+// dialect, or to its default backend when version is empty
+func GetVersionedConnection(dialect string, version string) (*sql.DB, error) {
+	if version == "" {
+		return GetDatabaseConnection(dialect)
+	}
+	key := ConnectionKey(dialect, version)
+	if _, ok := connectionStrings[key]; !ok {
+		available := Versions(dialect)
+		if len(available) == 0 {
+			return nil, fmt.Errorf("%w: no pinned versions of %s are registered", ErrUnknownVersion, dialect)
+		}
+		return nil, fmt.Errorf("%w: %s %s is not registered, available versions are %s",
+			ErrUnknownVersion, dialect, version, strings.Join(available, ", "))
+	}
+	return GetDatabaseConnection(key)
+}
+
+// displayName returns the name of a backend in progress messages, such as
+// "PostgreSQL 16"
+func displayName(key string) string {
+	dialect, version := SplitConnectionKey(key)
+	if version == "" {
+		return dialectNames[dialect]
+	}
+	return dialectNames[dialect] + " " + version
+}
@@ -0,0 +1,62 @@
+package dbmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// View is a view or materialized view with the query that defines it
+type View struct {
+	Name         string `json:"name"`
+	Materialized bool   `json:"materialized"`
+	Definition   string `json:"definition"`
+}
+
+// Queries listing views, whether they are materialized and their defining SQL
+var viewQueries = map[string]string{
+	"sqlite": `SELECT name, 0, sql FROM sqlite_master
+		WHERE type = 'view'
+		ORDER BY name`,
+	"mysql": `SELECT table_name, 0, view_definition FROM information_schema.views
+		WHERE table_schema = DATABASE()
+		ORDER BY table_name`,
+	"postgresql": `SELECT viewname, false, definition FROM pg_views
+		WHERE schemaname = current_schema()
+		UNION ALL
+		SELECT matviewname, true, definition FROM pg_matviews
+		WHERE schemaname = current_schema()
+		ORDER BY 1`,
+}
+
+// ListViews returns the views of a dialect's database with their
+// definitions
+func ListViews(ctx context.Context, dialect string) ([]View, error) {
+	db, ok := database(dialect)
+	if !ok {
+		return nil, fmt.Errorf("no database connection available for %s", dialect)
+	}
+	query, ok := viewQueries[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL dialect: %s", dialect)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	views := []View{}
+	for rows.Next() {
+		var view View
+		if err := rows.Scan(&view.Name, &view.Materialized, &view.Definition); err != nil {
+			return nil, err
+		}
+		views = append(views, view)
+	}
+	return views, rows.Err()
+}
@@ -0,0 +1,152 @@
+package dbusers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Names of users and databases the statements accept. They are pasted
+// into GRANT and CREATE USER, which take no placeholders.
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,62}$`)
+
+// Most connections the playground user may hold at once, enough for the
+// connection pool and the reaper
+const MaxUserConnections = 10
+
+// Account is the restricted user the playground connects as
+type Account struct {
+	User     string
+	Password string
+	Database string
+	// PostgreSQL schema holding the playground's objects
+	Schema string
+	// Whether to install PostGIS into the PostgreSQL schema, which only a
+	// superuser can do
+	PostGIS bool
+}
+
+// ValidName reports whether a user, database or schema name can be used in
+// statements without quoting concerns
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// Validate checks that the user, database and schema names can be used
+// unquoted
+func (a Account) Validate() error {
+	if !ValidName(a.User) {
+		return fmt.Errorf("invalid database user name %q", a.User)
+	}
+	if !ValidName(a.Database) {
+		return fmt.Errorf("invalid database name %q", a.Database)
+	}
+	if a.Schema != "" && !ValidName(a.Schema) {
+		return fmt.Errorf("invalid schema name %q", a.Schema)
+	}
+	if a.Password == "" {
+		return fmt.Errorf("the password of %s must not be empty", a.User)
+	}
+	return nil
+}
+
+// MySQLStatements returns the statements, to run as an administrator, that
+// apply the server-wide safety settings, create the playground's database
+// and create the playground user with privileges on that database only.
+// The performance_schema lock tables let the lock report show which session
+// blocks which. The user gets no PROCESS privilege: the reaper reads the
+// transactions of its sessions as the administrator.
+func MySQLStatements(account Account) ([]string, error) {
+	if err := account.Validate(); err != nil {
+		return nil, err
+	}
+	user := fmt.Sprintf("'%s'@'%%'", account.User)
+	password := mysqlString(account.Password)
+	return []string{
+		// No file access through LOAD DATA LOCAL
+		"SET GLOBAL local_infile = 0",
+		// Let a user without SUPER create functions and triggers when
+		// binary logging is on
+		"SET GLOBAL log_bin_trust_function_creators = 1",
+		fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", account.Database),
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY %s", user, password),
+		fmt.Sprintf("ALTER USER %s IDENTIFIED BY %s WITH MAX_USER_CONNECTIONS %d", user, password, MaxUserConnections),
+		fmt.Sprintf("GRANT SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, ALTER, INDEX, REFERENCES, "+
+			"CREATE TEMPORARY TABLES, LOCK TABLES, CREATE VIEW, SHOW VIEW, CREATE ROUTINE, ALTER ROUTINE, "+
+			"EXECUTE, TRIGGER ON `%s`.* TO %s", account.Database, user),
+		// Earlier versions granted PROCESS for the reaper
+		fmt.Sprintf("REVOKE PROCESS ON *.* FROM %s", user),
+		fmt.Sprintf("GRANT SELECT ON performance_schema.data_locks TO %s", user),
+		fmt.Sprintf("GRANT SELECT ON performance_schema.data_lock_waits TO %s", user),
+		fmt.Sprintf("GRANT SELECT ON performance_schema.threads TO %s", user),
+	}, nil
+}
+
+// PostgresStatements returns the statements, to run as a superuser
+// connected to the playground database, that apply the database's safety
+// settings and create the playground role and its schema. The role owns
+// the schema and the objects in it, so it can reset the sample tables, and
+// nothing else. PostGIS, when requested, is installed into the same schema
+// and stays owned by the superuser.
+func PostgresStatements(account Account) ([]string, error) {
+	if account.Schema == "" {
+		account.Schema = "public"
+	}
+	if err := account.Validate(); err != nil {
+		return nil, err
+	}
+	role := `"` + account.User + `"`
+	database := `"` + account.Database + `"`
+	schema := `"` + account.Schema + `"`
+	statements := []string{
+		// Large objects follow their owner's privileges
+		fmt.Sprintf("ALTER DATABASE %s SET lo_compat_privileges = off", database),
+		fmt.Sprintf(`DO $$ BEGIN
+			IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '%s') THEN
+				CREATE ROLE %s LOGIN;
+			END IF;
+		END $$`, account.User, role),
+		fmt.Sprintf("ALTER ROLE %s WITH LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOREPLICATION NOBYPASSRLS CONNECTION LIMIT %d PASSWORD %s",
+			role, MaxUserConnections, postgresString(account.Password)),
+		fmt.Sprintf("REVOKE ALL ON DATABASE %s FROM PUBLIC", database),
+		fmt.Sprintf("GRANT CONNECT, TEMPORARY ON DATABASE %s TO %s", database, role),
+		"REVOKE CREATE ON SCHEMA public FROM PUBLIC",
+		fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s AUTHORIZATION %s", schema, role),
+		fmt.Sprintf("ALTER SCHEMA %s OWNER TO %s", schema, role),
+		fmt.Sprintf("GRANT USAGE, CREATE ON SCHEMA %s TO %s", schema, role),
+		// Hand over sample tables created by an earlier superuser setup
+		fmt.Sprintf(`DO $$ DECLARE object record; BEGIN
+			FOR object IN
+				SELECT c.relname, c.relkind FROM pg_class c
+				JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname = '%s' AND c.relkind IN ('r', 'p', 'v', 'm')
+				AND pg_get_userbyid(c.relowner) <> '%s'
+				AND NOT EXISTS (SELECT FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+			LOOP
+				IF object.relkind = 'v' THEN
+					EXECUTE format('ALTER VIEW %s.%%I OWNER TO %s', object.relname);
+				ELSIF object.relkind = 'm' THEN
+					EXECUTE format('ALTER MATERIALIZED VIEW %s.%%I OWNER TO %s', object.relname);
+				ELSE
+					EXECUTE format('ALTER TABLE %s.%%I OWNER TO %s', object.relname);
+				END IF;
+			END LOOP;
+		END $$`, account.Schema, account.User, schema, role, schema, role, schema, role),
+	}
+	if account.PostGIS {
+		statements = append(statements, fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS postgis SCHEMA %s", schema))
+	}
+	return statements, nil
+}
+
+// mysqlString quotes a MySQL string literal, escaping backslashes as the
+// default SQL mode requires
+func mysqlString(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// postgresString quotes a PostgreSQL string literal
+func postgresString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,80 @@
+package dbusers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRejectsUnsafeNames(t *testing.T) {
+	for _, account := range []Account{
+		{User: "play`ground", Password: "secret", Database: "testdb"},
+		{User: "playground", Password: "secret", Database: "test db"},
+		{User: "playground", Password: "", Database: "testdb"},
+	} {
+		if err := account.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", account)
+		}
+	}
+}
+
+func TestMySQLStatementsQuotePassword(t *testing.T) {
+	statements, err := MySQLStatements(Account{User: "playground", Password: `it's\`, Database: "testdb"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	script := strings.Join(statements, ";\n")
+	if !strings.Contains(script, `IDENTIFIED BY 'it''s\\'`) {
+		t.Errorf("expected the password to be escaped:\n%s", script)
+	}
+	if !strings.Contains(script, "CREATE DATABASE IF NOT EXISTS `testdb`") {
+		t.Errorf("expected the playground database to be created:\n%s", script)
+	}
+	if !strings.Contains(script, "ON `testdb`.* TO 'playground'@'%'") {
+		t.Errorf("expected grants on the playground database only:\n%s", script)
+	}
+	if strings.Contains(script, "ALL PRIVILEGES") {
+		t.Errorf("expected no blanket grant:\n%s", script)
+	}
+	if strings.Contains(script, "GRANT PROCESS") {
+		t.Errorf("expected no PROCESS privilege:\n%s", script)
+	}
+}
+
+func TestPostgresStatementsCreateRestrictedRole(t *testing.T) {
+	statements, err := PostgresStatements(Account{User: "playground", Password: "o'clock", Database: "testdb", Schema: "sandbox"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	script := strings.Join(statements, ";\n")
+	for _, want := range []string{
+		"NOSUPERUSER NOCREATEDB NOCREATEROLE",
+		"PASSWORD 'o''clock'",
+		`GRANT CONNECT, TEMPORARY ON DATABASE "testdb" TO "playground"`,
+		`CREATE SCHEMA IF NOT EXISTS "sandbox" AUTHORIZATION "playground"`,
+		`ALTER TABLE "sandbox".%I OWNER TO "playground"`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("expected %q in:\n%s", want, script)
+		}
+	}
+}
+
+func TestPostgresStatementsInstallPostGIS(t *testing.T) {
+	account := Account{User: "playground", Password: "secret", Database: "testdb", Schema: "sandbox"}
+	statements, err := PostgresStatements(account)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if script := strings.Join(statements, ";\n"); strings.Contains(script, "postgis") {
+		t.Errorf("expected no extension unless requested:\n%s", script)
+	}
+
+	account.PostGIS = true
+	statements, err = PostgresStatements(account)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `CREATE EXTENSION IF NOT EXISTS postgis SCHEMA "sandbox"`; statements[len(statements)-1] != want {
+		t.Errorf("expected %q last, got %q", want, statements[len(statements)-1])
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/routines"
+	"example/user/playground/sqlvalidator"
+)
+
+// getDependencies lists the views of a dialect's database and the
+// session's routines, views and saved results with the objects each uses
+// and those using it, and the order the session's objects can be dropped
+// in. With the object query parameter, it also reports the impact of
+// dropping that table, view or routine: every object that uses it,
+// directly or not.
+func getDependencies(c *gin.Context) {
+	dialect := c.Param("dialect")
+	metadata, err := dbmanager.Metadata(c.Request.Context(), dialect)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list views: " + err.Error(),
+		})
+		return
+	}
+	views := metadata.Views
+
+	// The session's objects keep the dependencies recorded when they were
+	// created; other views are read from their definitions
+	owned := []routines.Routine{}
+	for _, routine := range routines.List(sessionOwner(c)) {
+		if routine.Dialect == dialect {
+			owned = append(owned, routine)
+		}
+	}
+	objects := append([]routines.Routine{}, owned...)
+	for _, view := range views {
+		if findRoutine(owned, view.Name) != nil {
+			continue
+		}
+		kind := sqlvalidator.RoutineView
+		if view.Materialized {
+			kind = sqlvalidator.RoutineMaterializedView
+		}
+		references := sqlvalidator.ReferencedObjects(view.Definition, dialect)
+		objects = append(objects, routines.Routine{
+			Dialect:   dialect,
+			Kind:      kind,
+			Name:      view.Name,
+			DependsOn: references.Tables,
+		})
+	}
+
+	list := make([]gin.H, 0, len(objects))
+	for _, object := range objects {
+		dependsOn := object.DependsOn
+		if dependsOn == nil {
+			dependsOn = []string{}
+		}
+		list = append(list, gin.H{
+			"name":       object.Name,
+			"kind":       object.Kind,
+			"owned":      findRoutine(owned, object.Name) != nil,
+			"dependsOn":  dependsOn,
+			"dependents": routineNames(routines.Dependents(objects, dialect, object.Name)),
+		})
+	}
+
+	response := gin.H{
+		"dialect":   dialect,
+		"objects":   list,
+		"dropOrder": routineNames(routines.DropOrder(owned)),
+	}
+	if name := c.Query("object"); name != "" {
+		// Short names of the session's objects are accepted, as in queries
+		if findRoutine(objects, name) == nil && findRoutine(owned, routines.Prefix(sessionOwner(c))+name) != nil {
+			name = routines.Prefix(sessionOwner(c)) + name
+		}
+		response["impact"] = gin.H{
+			"object":     name,
+			"dependents": routineNames(routines.Dependents(objects, dialect, name)),
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// findRoutine returns the routine of a list with a name
+func findRoutine(list []routines.Routine, name string) *routines.Routine {
+	for i := range list {
+		if strings.EqualFold(list[i].Name, name) {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+// routineNames returns the names of routines
+func routineNames(list []routines.Routine) []string {
+	names := make([]string, len(list))
+	for i, routine := range list {
+		names[i] = routine.Name
+	}
+	return names
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// Per-dialect limitations of dry runs, returned with every dry-run result
+var dryRunLimitations = map[string]string{
+	"sqlite":     "DDL is transactional in SQLite and is rolled back with the rest of the statement.",
+	"postgresql": "DDL is transactional in PostgreSQL and is rolled back; sequence values consumed by the statement are not.",
+	"mysql":      "MySQL commits DDL implicitly, so DDL statements are rejected in dry-run mode; AUTO_INCREMENT values consumed by the statement are not rolled back.",
+}
+
+// Statements that trigger an implicit commit in MySQL
+var mysqlImplicitCommitRegex = regexp.MustCompile(`^\s*(create|alter|drop|truncate|rename|lock|unlock)\b`)
+
+// Statements that produce a result set rather than an affected-row count
+var rowReturningRegex = regexp.MustCompile(`^\s*(select|with|show|explain|pragma|values|describe|desc)\b`)
+
+// isReadQuery reports whether sql only reads: it returns rows and has no
+// WITH clause leading an INSERT, UPDATE or DELETE or holding a
+// data-modifying CTE
+func isReadQuery(sql string, dialect string) bool {
+	return rowReturningRegex.MatchString(strings.ToLower(sqlvalidator.MaskSQL(sql, dialect))) && !sqlvalidator.WithClauseWrites(sql, dialect)
+}
+
+// dryRunSQL executes a statement inside a transaction that is always rolled
+// back, at the request's isolation level, and reports the result or
+// affected-row count. The statement is rewritten and limited as it would be
+// when executed; scopeRewrites are those already applied to statementSQL.
+func dryRunSQL(ctx context.Context, db *sql.DB, req SQLValidationRequest, statementSQL string, scopeRewrites []sqlvalidator.Rewrite) gin.H {
+	sqlLower := strings.ToLower(sqlvalidator.MaskSQL(statementSQL, req.Dialect))
+	note := dryRunLimitations[req.Dialect]
+
+	if req.Dialect == "mysql" && mysqlImplicitCommitRegex.MatchString(sqlLower) {
+		return gin.H{
+			"valid":  true,
+			"dryRun": true,
+			"error":  "Dry-run is not supported for this statement: " + note,
+			"note":   note,
+		}
+	}
+
+	executedSQL, rewrites := rewriteForExecution(statementSQL, req.Dialect)
+	rewrites = append(scopeRewrites, rewrites...)
+
+	ctx, cancel := queryContext(ctx, req.Dialect)
+	defer cancel()
+	tx, query, err := beginLimited(ctx, db, executedSQL, req.Dialect, txOptions(req))
+	if err != nil {
+		return gin.H{
+			"valid":  true,
+			"dryRun": true,
+			"error":  "Failed to start transaction: " + err.Error(),
+		}
+	}
+	// Never persist anything from a dry run
+	defer tx.Rollback()
+
+	start := time.Now()
+	var result *QueryResult
+	var rowsAffected int64
+	// A WITH clause may lead an UPDATE or DELETE, which reports a count
+	returnsRows := rowReturningRegex.MatchString(sqlLower)
+	if clause := sqlvalidator.ParseWithClause(statementSQL, req.Dialect); clause != nil && clause.MainKind != "select" {
+		returnsRows = false
+	}
+	if returnsRows {
+		result, err = executeQuery(contextQueryer{ctx, tx}, query, req.Dialect)
+	} else {
+		var res sql.Result
+		if res, err = tx.ExecContext(ctx, query); err == nil {
+			rowsAffected, _ = res.RowsAffected()
+		}
+	}
+	duration := time.Since(start)
+
+	if err != nil {
+		response := withRewrites(rewrittenErrorResponse("Query execution error: ", err, req.SQL, executedSQL), executedSQL, rewrites)
+		response["dryRun"] = true
+		response["note"] = note
+		return response
+	}
+
+	return withRewrites(gin.H{
+		"valid":        true,
+		"dryRun":       true,
+		"result":       result,
+		"rowsAffected": rowsAffected,
+		"durationMs":   float64(duration.Microseconds()) / 1000,
+		"note":         note,
+	}, executedSQL, rewrites)
+}
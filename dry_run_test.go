@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// openMemoryDB opens an in-memory SQLite database for a test
+func openMemoryDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// rewriteNames lists the names of the rewrites a response reports
+func rewriteNames(response map[string]interface{}) map[string]bool {
+	names := map[string]bool{}
+	rewrites, _ := response["rewrites"].([]sqlvalidator.Rewrite)
+	for _, rewrite := range rewrites {
+		names[rewrite.Name] = true
+	}
+	return names
+}
+
+func TestDryRunStopsUnboundedRecursion(t *testing.T) {
+	db := openMemoryDB(t)
+	query := "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n) SELECT count(*) FROM n"
+	req := SQLValidationRequest{SQL: query, Dialect: "sqlite", DryRun: true}
+
+	response := dryRunSQL(context.Background(), db, req, query, nil)
+	if response["error"] != nil {
+		t.Fatalf("expected the dry run to finish, got %v", response["error"])
+	}
+	result := response["result"].(*QueryResult)
+	if count := result.Rows[0][0]; count != int64(sqlvalidator.MaxRecursiveRows) {
+		t.Errorf("expected the recursion to stop at %d rows, got %v", sqlvalidator.MaxRecursiveRows, count)
+	}
+	if !rewriteNames(response)["recursion_cap"] {
+		t.Errorf("expected the recursion cap to be reported, got %v", response["rewrites"])
+	}
+}
+
+func TestDryRunLimitsRows(t *testing.T) {
+	db := openMemoryDB(t)
+	query := "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 1000) SELECT x FROM n"
+	req := SQLValidationRequest{SQL: query, Dialect: "sqlite", DryRun: true}
+
+	response := dryRunSQL(context.Background(), db, req, query, nil)
+	if response["error"] != nil {
+		t.Fatalf("expected the dry run to finish, got %v", response["error"])
+	}
+	executed, _ := response["executedSql"].(string)
+	if !rewriteNames(response)["row_limit"] || !strings.HasSuffix(executed, fmt.Sprintf("LIMIT %d", sqlvalidator.DefaultRowLimit)) {
+		t.Errorf("expected the row limit to be applied and reported, got %v", response)
+	}
+}
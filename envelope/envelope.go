@@ -0,0 +1,122 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"example/user/playground/dberrors"
+)
+
+// Response is the body of every response of an enveloped API version:
+// data on success, error on failure, and meta either way
+type Response struct {
+	Data  interface{}            `json:"data,omitempty"`
+	Error *Error                 `json:"error,omitempty"`
+	Meta  map[string]interface{} `json:"meta"`
+}
+
+// Error describes why a request failed
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// The other fields of the failed response, such as the error position,
+	// hints or a confirmation token
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Statuses of error codes that do not depend on whether the statement ran
+var codeStatuses = map[string]int{
+	dberrors.CodeValidationError:   http.StatusBadRequest,
+	"too_many_params":              http.StatusBadRequest,
+	"invalid_encoding":             http.StatusBadRequest,
+	"control_character":            http.StatusBadRequest,
+	"payload_too_large":            http.StatusRequestEntityTooLarge,
+	dberrors.CodeBlockedStatement:  http.StatusForbidden,
+	dberrors.CodePermissionDenied:  http.StatusForbidden,
+	dberrors.CodeNeedsConfirmation: http.StatusPreconditionRequired,
+	dberrors.CodeTimeout:           http.StatusGatewayTimeout,
+	dberrors.CodeConnectionError:   http.StatusServiceUnavailable,
+	"queue_full":                   http.StatusServiceUnavailable,
+	"rate_limited":                 http.StatusTooManyRequests,
+	"feature_disabled":             http.StatusNotFound,
+}
+
+// Wrap puts a JSON response body into the envelope and returns the status
+// it should be sent with. A body with an "error" message is a failure when
+// the status says so or, for the v1 bodies answered with 200, when it also
+// has "valid" or "errorCode". Failures keep an error status they already
+// have; otherwise the status follows from the error code: 400 for requests
+// rejected before a statement ran, 403 for safety blocks, 504 for timeouts
+// and 422 for statements the database failed. Fields other than the error
+// move into its details. Bodies that are not JSON are not wrapped.
+func Wrap(status int, body []byte, meta map[string]interface{}) (int, []byte, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return status, body, false
+	}
+
+	response := Response{Meta: meta}
+	fields, _ := data.(map[string]interface{})
+	message, failed := fields["error"].(string)
+	_, hasValid := fields["valid"]
+	_, hasCode := fields["errorCode"]
+	failed = failed && message != "" && (status >= http.StatusBadRequest || hasValid || hasCode)
+	if !failed {
+		response.Data = data
+	} else {
+		code, _ := fields["errorCode"].(string)
+		ran, _ := fields["valid"].(bool)
+		if status < http.StatusBadRequest {
+			status = errorStatus(code, ran)
+		}
+		if code == "" {
+			code = statusCode(status)
+		}
+		response.Error = &Error{Code: code, Message: message}
+		for name, value := range fields {
+			if name == "error" || name == "errorCode" || name == "valid" {
+				continue
+			}
+			if response.Error.Details == nil {
+				response.Error.Details = make(map[string]interface{})
+			}
+			response.Error.Details[name] = value
+		}
+	}
+
+	wrapped, err := json.Marshal(response)
+	if err != nil {
+		return status, body, false
+	}
+	return status, wrapped, true
+}
+
+// errorStatus returns the status of a failure answered with 200 in v1.
+// ran tells whether the statement reached the database, which v1 reports
+// as "valid": true.
+func errorStatus(code string, ran bool) int {
+	if status, ok := codeStatuses[code]; ok {
+		return status
+	}
+	switch {
+	case code == "":
+		return http.StatusInternalServerError
+	case ran:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusBadRequest
+}
+
+// statusCode names the error of a status without an error code, such as
+// not_found for 404
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "unknown_error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
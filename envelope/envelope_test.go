@@ -0,0 +1,102 @@
+package envelope
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func wrap(t *testing.T, status int, body string) (int, map[string]interface{}) {
+	t.Helper()
+	status, wrapped, ok := Wrap(status, []byte(body), map[string]interface{}{"api_version": "v2"})
+	if !ok {
+		t.Fatalf("expected %s to be wrapped", body)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(wrapped, &response); err != nil {
+		t.Fatal(err)
+	}
+	if response["meta"].(map[string]interface{})["api_version"] != "v2" {
+		t.Errorf("expected the meta of %s, got %v", body, response["meta"])
+	}
+	return status, response
+}
+
+func TestWrapSuccess(t *testing.T) {
+	status, response := wrap(t, http.StatusOK, `{"valid": true, "result": {"columns": ["n"], "rows": [[12345678901234567890]]}}`)
+	if status != http.StatusOK || response["error"] != nil {
+		t.Fatalf("expected a success, got %d %v", status, response)
+	}
+	data := response["data"].(map[string]interface{})
+	if data["valid"] != true {
+		t.Errorf("expected the body as data, got %v", data)
+	}
+	// Numbers are passed through without losing precision
+	if _, wrapped, _ := Wrap(http.StatusOK, []byte(`{"rows": [[12345678901234567890]]}`), nil); !strings.Contains(string(wrapped), "12345678901234567890") {
+		t.Errorf("expected the number unchanged, got %s", wrapped)
+	}
+
+	if _, response := wrap(t, http.StatusOK, `[1, 2]`); len(response["data"].([]interface{})) != 2 {
+		t.Errorf("expected an array as data, got %v", response)
+	}
+	// An error field is data unless the response says it failed
+	if status, response := wrap(t, http.StatusOK, `{"step": 2, "error": "deadlock detected"}`); status != http.StatusOK || response["data"] == nil {
+		t.Errorf("expected a lab step with an error to be data, got %d %v", status, response)
+	}
+}
+
+func TestWrapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   int
+		code   string
+	}{
+		{"rejected syntax", 200, `{"valid": false, "error": "near FORM", "errorCode": "syntax_error"}`, 400, "syntax_error"},
+		{"validation", 200, `{"valid": false, "error": "no such table", "errorCode": "validation_error"}`, 400, "validation_error"},
+		{"safety block", 200, `{"valid": false, "error": "DROP DATABASE is not allowed", "errorCode": "blocked_statement", "rule": "drop_database"}`, 403, "blocked_statement"},
+		{"confirmation", 200, `{"valid": false, "error": "Deletes every row", "errorCode": "confirmation_required", "confirmationToken": "abc"}`, 428, "confirmation_required"},
+		{"database error", 200, `{"valid": true, "error": "Query execution error: no such column", "errorCode": "missing_column"}`, 422, "missing_column"},
+		{"database syntax error", 200, `{"valid": true, "error": "syntax error", "errorCode": "syntax_error"}`, 422, "syntax_error"},
+		{"timeout", 200, `{"valid": true, "error": "canceling statement", "errorCode": "timeout"}`, 504, "timeout"},
+		{"connection", 200, `{"valid": true, "error": "Database connection error", "errorCode": "connection_error"}`, 503, "connection_error"},
+		{"unclassified", 200, `{"valid": true, "error": "Database connection error"}`, 500, "internal_server_error"},
+		{"status kept", 404, `{"error": "The nl2sql feature is disabled", "errorCode": "feature_disabled"}`, 404, "feature_disabled"},
+		{"code from status", 403, `{"error": "Admin access required"}`, 403, "forbidden"},
+		{"bad request", 400, `{"error": "Invalid request: dialect is required"}`, 400, "bad_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, response := wrap(t, tt.status, tt.body)
+			if status != tt.want {
+				t.Errorf("expected status %d, got %d", tt.want, status)
+			}
+			if response["data"] != nil {
+				t.Errorf("expected no data on failure, got %v", response["data"])
+			}
+			failure := response["error"].(map[string]interface{})
+			if failure["code"] != tt.code || failure["message"] == "" {
+				t.Errorf("expected code %s with a message, got %v", tt.code, failure)
+			}
+		})
+	}
+
+	_, response := wrap(t, 200, `{"valid": false, "error": "Deletes every row", "errorCode": "confirmation_required", "confirmationToken": "abc", "rule": "delete_all"}`)
+	details := response["error"].(map[string]interface{})["details"].(map[string]interface{})
+	if len(details) != 2 || details["confirmationToken"] != "abc" || details["rule"] != "delete_all" {
+		t.Errorf("expected the other fields as details, got %v", details)
+	}
+	_, response = wrap(t, 403, `{"error": "Admin access required"}`)
+	if _, ok := response["error"].(map[string]interface{})["details"]; ok {
+		t.Errorf("expected no details without other fields, got %v", response["error"])
+	}
+}
+
+func TestWrapNotJSON(t *testing.T) {
+	if status, body, ok := Wrap(200, []byte("id,name\n1,a\n"), nil); ok || status != 200 || string(body) != "id,name\n1,a\n" {
+		t.Errorf("expected a CSV body left alone, got %d %q %v", status, body, ok)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/activequeries"
+	"example/user/playground/dbmanager"
+	"example/user/playground/events"
+	"example/user/playground/slowlog"
+	"example/user/playground/sqlvalidator"
+)
+
+// Interval of keep-alive comments on idle event streams, so proxies do not
+// close them
+const eventKeepAliveInterval = 20 * time.Second
+
+// publishConnectionEvents forwards backend connection changes to the event
+// stream
+func publishConnectionEvents() {
+	dbmanager.OnStatusChange(func(change dbmanager.StatusChange) {
+		events.Publish(events.TypeConnection, change)
+	})
+}
+
+// publishSeedProgress forwards the progress of large dataset seeding to the
+// event stream, and the new table once a job finishes
+func publishSeedProgress() {
+	dbmanager.OnSeedProgress(func(job dbmanager.SeedJob) {
+		events.Publish(events.TypeSeeding, job)
+		if job.State == dbmanager.SeedRunning {
+			return
+		}
+		if schema, ok := dbmanager.CachedSchema(job.Dialect); ok {
+			publishSchemaChange(job.Dialect, schema)
+		}
+	})
+}
+
+// publishSchemaChange tells clients that a dialect's tables have changed
+func publishSchemaChange(dialect string, schema map[string][]string) {
+	tables := make([]string, 0, len(schema))
+	for table := range schema {
+		tables = append(tables, table)
+	}
+	events.Publish(events.TypeSchema, gin.H{
+		"dialect": dialect,
+		"tables":  tables,
+	})
+}
+
+// watchLongRunning lists a query among the active queries while it runs,
+// and notifies the session running it once it has run longer than the slow
+// query threshold, and again when it finishes. The returned function must
+// be called when the query completes.
+func watchLongRunning(owner string, dialect string, query string) func() {
+	// Whoever reads the list sees neither the session cookie nor the
+	// statement's literals
+	done := activequeries.Start(slowlog.SessionID(owner), dialect, sqlvalidator.MaskSQL(query, dialect))
+	threshold := slowlog.Threshold()
+	if threshold <= 0 {
+		return done
+	}
+
+	start := time.Now()
+	fired := make(chan struct{})
+	timer := time.AfterFunc(threshold, func() {
+		close(fired)
+		events.PublishTo(owner, events.TypeQueryRunning, gin.H{
+			"dialect": dialect,
+			"sql":     query,
+			"started": start,
+		})
+	})
+
+	return func() {
+		done()
+		if timer.Stop() {
+			return
+		}
+		<-fired
+		events.PublishTo(owner, events.TypeQueryDone, gin.H{
+			"dialect":     dialect,
+			"sql":         query,
+			"duration_ms": time.Since(start).Milliseconds(),
+		})
+	}
+}
+
+// streamEvents sends connection, query, schema and seeding events to the client as
+// Server-Sent Events until it disconnects
+func streamEvents(c *gin.Context) {
+	ch, unsubscribe := events.Subscribe(sessionOwner(c))
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	// Start with the current status so clients need not poll first
+	for dialect, connected := range dbmanager.GetConnectionStatuses() {
+		c.SSEvent(events.TypeConnection, events.Event{
+			Type: events.TypeConnection,
+			Time: time.Now(),
+			Data: dbmanager.StatusChange{Dialect: dialect, Connected: connected, Time: time.Now()},
+		})
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
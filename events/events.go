@@ -0,0 +1,87 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	TypeConnection   = "connection"
+	TypeQueryRunning = "query_running"
+	TypeQueryDone    = "query_done"
+	TypeSchema       = "schema"
+	TypeSeeding      = "seeding"
+)
+
+// Events buffered per subscriber before new ones are dropped
+const bufferSize = 32
+
+// Event is a notification pushed to subscribers
+type Event struct {
+	Type  string      `json:"type"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+	Owner string      `json:"-"`
+}
+
+// subscriber receives the events visible to its owner
+type subscriber struct {
+	owner  string
+	events chan Event
+}
+
+var (
+	// Active subscribers
+	subscribers = make(map[*subscriber]bool)
+
+	// Guards subscribers
+	mu sync.Mutex
+)
+
+// Subscribe registers a subscriber for the session owner and returns its
+// event channel and a function that unsubscribes it. The subscriber
+// receives broadcast events and events addressed to its owner.
+func Subscribe(owner string) (<-chan Event, func()) {
+	sub := &subscriber{owner: owner, events: make(chan Event, bufferSize)}
+
+	mu.Lock()
+	subscribers[sub] = true
+	mu.Unlock()
+
+	var once sync.Once
+	return sub.events, func() {
+		once.Do(func() {
+			mu.Lock()
+			delete(subscribers, sub)
+			mu.Unlock()
+			close(sub.events)
+		})
+	}
+}
+
+// Publish sends an event to every subscriber
+func Publish(eventType string, data interface{}) {
+	publish(Event{Type: eventType, Time: time.Now(), Data: data})
+}
+
+// PublishTo sends an event only to the subscribers of a session owner
+func PublishTo(owner string, eventType string, data interface{}) {
+	publish(Event{Type: eventType, Time: time.Now(), Data: data, Owner: owner})
+}
+
+// publish delivers an event without blocking; subscribers that have fallen
+// behind miss it
+func publish(event Event) {
+	mu.Lock()
+	defer mu.Unlock()
+	for sub := range subscribers {
+		if event.Owner != "" && event.Owner != sub.owner {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package events
+
+import "testing"
+
+func TestPublishReachesEverySubscriber(t *testing.T) {
+	first, unsubscribeFirst := Subscribe("session:a")
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := Subscribe("session:b")
+	defer unsubscribeSecond()
+
+	Publish(TypeConnection, "mysql")
+
+	for _, ch := range []<-chan Event{first, second} {
+		select {
+		case event := <-ch:
+			if event.Type != TypeConnection || event.Data != "mysql" {
+				t.Errorf("unexpected event %+v", event)
+			}
+		default:
+			t.Errorf("expected an event")
+		}
+	}
+}
+
+func TestPublishToOnlyReachesOwner(t *testing.T) {
+	owner, unsubscribeOwner := Subscribe("session:a")
+	defer unsubscribeOwner()
+	other, unsubscribeOther := Subscribe("session:b")
+	defer unsubscribeOther()
+
+	PublishTo("session:a", TypeQueryRunning, nil)
+
+	if len(owner) != 1 {
+		t.Errorf("expected the owner to receive the event")
+	}
+	if len(other) != 0 {
+		t.Errorf("expected other sessions not to receive the event")
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	ch, unsubscribe := Subscribe("session:a")
+	unsubscribe()
+	unsubscribe()
+
+	if _, ok := <-ch; ok {
+		t.Errorf("expected a closed channel")
+	}
+	Publish(TypeSchema, nil)
+}
+
+func TestPublishDropsEventsForSlowSubscribers(t *testing.T) {
+	ch, unsubscribe := Subscribe("session:a")
+	defer unsubscribe()
+
+	for i := 0; i < bufferSize+5; i++ {
+		Publish(TypeSchema, i)
+	}
+	if len(ch) != bufferSize {
+		t.Errorf("expected %d buffered events, got %d", bufferSize, len(ch))
+	}
+}
@@ -0,0 +1,310 @@
+package execpool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Priority orders the queries waiting in a queue. Workers take the highest
+// priority first and, within a priority, the query that has waited longest.
+type Priority int
+
+const (
+	// Queries nobody is waiting on, such as background jobs
+	Background Priority = iota
+	// Queries run in bulk, such as one statement against several backends
+	Batch
+	// Queries a user is waiting on in the editor
+	Interactive
+
+	priorities = 3
+)
+
+// String returns the name of a priority
+func (p Priority) String() string {
+	switch p {
+	case Background:
+		return "background"
+	case Batch:
+		return "batch"
+	case Interactive:
+		return "interactive"
+	}
+	return "unknown"
+}
+
+// ErrQueueFull is returned when every worker of a queue is busy and no more
+// queries can wait for one
+var ErrQueueFull = errors.New("too many queries are waiting to run, try again later")
+
+// Limits bound a queue: the workers running its queries at once and the
+// queries that may wait for one
+type Limits struct {
+	Workers  int
+	Capacity int
+}
+
+// Stats describe how a query went through its queue
+type Stats struct {
+	Queue    string
+	Priority Priority
+	// Time spent waiting for a worker
+	Wait time.Duration
+	// Time the worker spent running the query
+	Run time.Duration
+}
+
+// QueueStatus reports the use of a queue
+type QueueStatus struct {
+	Queue    string `json:"queue"`
+	Workers  int    `json:"workers"`
+	Capacity int    `json:"capacity"`
+	Running  int    `json:"running"`
+	// Queries waiting for a worker, by priority
+	Waiting map[string]int `json:"waiting"`
+	// Queries turned away because the queue was full
+	Rejected int64 `json:"rejected"`
+	// Queries that stopped waiting because their context ended
+	Cancelled int64 `json:"cancelled"`
+}
+
+// Pool runs queries on bounded workers, with a queue of its own for each
+// backend so a burst against one cannot hold up the others
+type Pool struct {
+	limits func(queue string) Limits
+
+	mu     sync.Mutex
+	queues map[string]*queue
+}
+
+// queue holds the queries waiting for one backend's workers
+type queue struct {
+	name   string
+	limits Limits
+
+	mu        sync.Mutex
+	ready     *sync.Cond
+	pending   [priorities][]*task
+	waiting   int
+	running   int
+	rejected  int64
+	cancelled int64
+}
+
+// task is a query waiting for or running on a worker
+type task struct {
+	ctx      context.Context
+	run      func(context.Context)
+	priority Priority
+	queuedAt time.Time
+
+	// Closed when a worker takes the task, and when it is done with it
+	started chan struct{}
+	done    chan struct{}
+
+	startedAt time.Time
+	ranFor    time.Duration
+	// Value the query panicked with, raised again in the caller
+	panicked interface{}
+}
+
+// New returns a pool whose queues are bounded by limits, looked up once
+// when a queue is first used. Queues with fewer than one worker get one.
+func New(limits func(queue string) Limits) *Pool {
+	return &Pool{
+		limits: limits,
+		queues: make(map[string]*queue),
+	}
+}
+
+// Do runs fn on a worker of a queue and waits for it to return. fn gets
+// ctx, which it should stop at. When ctx ends before a worker takes the
+// query, fn never runs and the context's error is returned. A panic in fn
+// is raised again in the caller.
+func (p *Pool) Do(ctx context.Context, name string, priority Priority, fn func(context.Context)) (Stats, error) {
+	if priority < 0 || priority >= priorities {
+		priority = Background
+	}
+	q := p.queue(name)
+	t := &task{
+		ctx:      ctx,
+		run:      fn,
+		priority: priority,
+		queuedAt: time.Now(),
+		started:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	stats := Stats{Queue: name, Priority: priority}
+	if err := q.push(t); err != nil {
+		return stats, err
+	}
+
+	select {
+	case <-t.started:
+	case <-ctx.Done():
+		if q.remove(t) {
+			stats.Wait = time.Since(t.queuedAt)
+			return stats, ctx.Err()
+		}
+		// A worker took the query meanwhile; fn sees ctx has ended
+		<-t.started
+	}
+	<-t.done
+
+	if t.panicked != nil {
+		panic(t.panicked)
+	}
+	stats.Wait = t.startedAt.Sub(t.queuedAt)
+	stats.Run = t.ranFor
+	return stats, nil
+}
+
+// Status reports the use of every queue, by name
+func (p *Pool) Status() []QueueStatus {
+	p.mu.Lock()
+	queues := make([]*queue, 0, len(p.queues))
+	for _, q := range p.queues {
+		queues = append(queues, q)
+	}
+	p.mu.Unlock()
+
+	statuses := make([]QueueStatus, 0, len(queues))
+	for _, q := range queues {
+		statuses = append(statuses, q.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Queue < statuses[j].Queue
+	})
+	return statuses
+}
+
+// queue returns the queue of a name, starting its workers when it is
+// first used
+func (p *Pool) queue(name string) *queue {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if q, ok := p.queues[name]; ok {
+		return q
+	}
+	limits := p.limits(name)
+	if limits.Workers < 1 {
+		limits.Workers = 1
+	}
+	if limits.Capacity < 0 {
+		limits.Capacity = 0
+	}
+	q := &queue{name: name, limits: limits}
+	q.ready = sync.NewCond(&q.mu)
+	for i := 0; i < limits.Workers; i++ {
+		go q.work()
+	}
+	p.queues[name] = q
+	return q
+}
+
+// push adds a task behind the others of its priority. A task finding an
+// idle worker never counts against the capacity.
+func (q *queue) push(t *task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.waiting >= q.limits.Capacity && q.running+q.waiting >= q.limits.Workers {
+		q.rejected++
+		return ErrQueueFull
+	}
+	q.pending[t.priority] = append(q.pending[t.priority], t)
+	q.waiting++
+	q.ready.Signal()
+	return nil
+}
+
+// remove takes a task that is still waiting out of the queue, reporting
+// whether it was
+func (q *queue) remove(t *task) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	pending := q.pending[t.priority]
+	for i, waiting := range pending {
+		if waiting == t {
+			q.pending[t.priority] = append(pending[:i:i], pending[i+1:]...)
+			q.waiting--
+			q.cancelled++
+			return true
+		}
+	}
+	return false
+}
+
+// next waits for a task and takes the one of the highest priority that
+// has waited longest
+func (q *queue) next() *task {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.waiting == 0 {
+		q.ready.Wait()
+	}
+	for priority := priorities - 1; priority >= 0; priority-- {
+		if pending := q.pending[priority]; len(pending) > 0 {
+			t := pending[0]
+			pending[0] = nil
+			q.pending[priority] = pending[1:]
+			q.waiting--
+			q.running++
+			return t
+		}
+	}
+	return nil
+}
+
+// work runs the queue's tasks until the process exits
+func (q *queue) work() {
+	for {
+		t := q.next()
+		t.startedAt = time.Now()
+		close(t.started)
+		q.execute(t)
+		t.ranFor = time.Since(t.startedAt)
+
+		q.mu.Lock()
+		q.running--
+		q.mu.Unlock()
+		close(t.done)
+	}
+}
+
+// execute runs a task, keeping a panic for its caller so a failing query
+// does not take the worker down
+func (q *queue) execute(t *task) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.panicked = r
+		}
+	}()
+	t.run(t.ctx)
+}
+
+// status reports the use of the queue
+func (q *queue) status() QueueStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	waiting := make(map[string]int, priorities)
+	for priority := Priority(0); priority < priorities; priority++ {
+		waiting[priority.String()] = len(q.pending[priority])
+	}
+	return QueueStatus{
+		Queue:     q.name,
+		Workers:   q.limits.Workers,
+		Capacity:  q.limits.Capacity,
+		Running:   q.running,
+		Waiting:   waiting,
+		Rejected:  q.rejected,
+		Cancelled: q.cancelled,
+	}
+}
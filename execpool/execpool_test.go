@@ -0,0 +1,176 @@
+package execpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixed returns the same limits for every queue
+func fixed(workers int, capacity int) func(string) Limits {
+	return func(string) Limits {
+		return Limits{Workers: workers, Capacity: capacity}
+	}
+}
+
+// block occupies the only worker of a queue until the returned function is
+// called
+func block(t *testing.T, p *Pool, name string) func() {
+	t.Helper()
+	release := make(chan struct{})
+	running := make(chan struct{})
+	go p.Do(context.Background(), name, Interactive, func(context.Context) {
+		close(running)
+		<-release
+	})
+	<-running
+	return func() { close(release) }
+}
+
+// waitForWaiting polls a queue until the given number of queries wait in it
+func waitForWaiting(t *testing.T, p *Pool, name string, want int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, status := range p.Status() {
+			total := 0
+			for _, n := range status.Waiting {
+				total += n
+			}
+			if status.Queue == name && total == want {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queries waiting in %s, got %+v", want, name, p.Status())
+}
+
+func TestDoRunsAndReportsStats(t *testing.T) {
+	p := New(fixed(1, 1))
+	ran := false
+	stats, err := p.Do(context.Background(), "sqlite", Interactive, func(context.Context) {
+		ran = true
+		time.Sleep(5 * time.Millisecond)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ran {
+		t.Fatal("expected the query to run")
+	}
+	if stats.Queue != "sqlite" || stats.Priority != Interactive || stats.Run < 5*time.Millisecond {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestHigherPriorityRunsFirst(t *testing.T) {
+	p := New(fixed(1, 10))
+	release := block(t, p, "mysql")
+
+	var mu sync.Mutex
+	order := []Priority{}
+	var wg sync.WaitGroup
+	for i, priority := range []Priority{Background, Batch, Interactive} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Do(context.Background(), "mysql", priority, func(context.Context) {
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+			})
+		}()
+		waitForWaiting(t, p, "mysql", i+1)
+	}
+	release()
+	wg.Wait()
+
+	if len(order) != 3 || order[0] != Interactive || order[1] != Batch || order[2] != Background {
+		t.Errorf("expected interactive, batch then background, got %v", order)
+	}
+}
+
+func TestWaitIsReported(t *testing.T) {
+	p := New(fixed(1, 1))
+	release := block(t, p, "postgresql")
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+
+	stats, err := p.Do(context.Background(), "postgresql", Batch, func(context.Context) {})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stats.Wait < 20*time.Millisecond {
+		t.Errorf("expected a wait of at least 20ms, got %s", stats.Wait)
+	}
+}
+
+func TestFullQueueRejects(t *testing.T) {
+	p := New(fixed(1, 1))
+	release := block(t, p, "mysql")
+	defer release()
+
+	go p.Do(context.Background(), "mysql", Interactive, func(context.Context) {})
+	waitForWaiting(t, p, "mysql", 1)
+
+	if _, err := p.Do(context.Background(), "mysql", Interactive, func(context.Context) {}); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+	if status := p.Status()[0]; status.Rejected != 1 {
+		t.Errorf("expected one rejected query, got %+v", status)
+	}
+}
+
+func TestQueuesAreSeparate(t *testing.T) {
+	p := New(fixed(1, 0))
+	release := block(t, p, "mysql")
+	defer release()
+
+	if _, err := p.Do(context.Background(), "postgresql", Interactive, func(context.Context) {}); err != nil {
+		t.Errorf("expected another queue to have a free worker, got %v", err)
+	}
+}
+
+func TestCancelWhileWaiting(t *testing.T) {
+	p := New(fixed(1, 1))
+	release := block(t, p, "sqlite")
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() {
+		_, err := p.Do(ctx, "sqlite", Interactive, func(context.Context) {
+			t.Error("expected the cancelled query not to run")
+		})
+		result <- err
+	}()
+	waitForWaiting(t, p, "sqlite", 1)
+	cancel()
+	if err := <-result; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if status := p.Status()[0]; status.Cancelled != 1 || status.Waiting["interactive"] != 0 {
+		t.Errorf("expected the cancelled query to leave the queue, got %+v", status)
+	}
+}
+
+func TestPanicReachesCaller(t *testing.T) {
+	p := New(fixed(1, 1))
+	func() {
+		defer func() {
+			if r := recover(); r != "boom" {
+				t.Errorf("expected the panic to reach the caller, got %v", r)
+			}
+		}()
+		p.Do(context.Background(), "sqlite", Interactive, func(context.Context) { panic("boom") })
+	}()
+
+	// The worker survives the panic
+	if _, err := p.Do(context.Background(), "sqlite", Interactive, func(context.Context) {}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
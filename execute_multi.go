@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/execpool"
+)
+
+// Dialects a query can be run against side by side
+var multiDialects = map[string]bool{
+	"sqlite":     true,
+	"mysql":      true,
+	"postgresql": true,
+	"mock":       true,
+}
+
+// MultiExecutionRequest runs one query against several dialects. A dialect
+// may pin a registered version, as in "postgresql@16", so versions of the
+// same engine can be compared.
+type MultiExecutionRequest struct {
+	SQL          string   `json:"sql" binding:"required"`
+	Dialects     []string `json:"dialects" binding:"required"`
+	DryRun       bool     `json:"dryRun"`
+	ValidateOnly bool     `json:"validateOnly"`
+	// Tokens from requiresConfirmation responses, keyed by dialect
+	ConfirmationTokens map[string]string `json:"confirmationTokens"`
+}
+
+// executeMulti runs a query against each requested dialect concurrently,
+// going through the same checks as a single execution, and returns every
+// dialect's response with its timing in the order the dialects were given
+func executeMulti(c *gin.Context) {
+	var req MultiExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, dialect := range req.Dialects {
+		if !multiDialects[dbmanager.BaseDialect(dialect)] || seen[dialect] {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "dialects must be distinct and one of sqlite, mysql, postgresql or mock, optionally pinned as in postgresql@16",
+			})
+			return
+		}
+		seen[dialect] = true
+	}
+	if len(req.Dialects) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "at least one dialect is required",
+		})
+		return
+	}
+
+	// Resolve the owner once so an anonymous client gets a single session
+	owner := sessionOwner(c)
+	results := make([]gin.H, len(req.Dialects))
+	var wg sync.WaitGroup
+	for i, dialect := range req.Dialects {
+		wg.Add(1)
+		go func(i int, dialect string) {
+			defer wg.Done()
+			start := time.Now()
+			base, version := dbmanager.SplitConnectionKey(dialect)
+			status, response := executeSQLRequest(c.Request.Context(), owner, SQLValidationRequest{
+				SQL:               req.SQL,
+				Dialect:           base,
+				Version:           version,
+				DryRun:            req.DryRun,
+				ValidateOnly:      req.ValidateOnly,
+				ConfirmationToken: req.ConfirmationTokens[dialect],
+			}, execpool.Batch)
+			response["dialect"] = dialect
+			response["status"] = status
+			response["durationMs"] = float64(time.Since(start).Microseconds()) / 1000
+			results[i] = response
+		}(i, dialect)
+	}
+	wg.Wait()
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+	})
+}
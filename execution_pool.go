@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/execpool"
+)
+
+// Queries of a backend that may wait for one of its workers by default
+const defaultExecutionQueueSize = 64
+
+// Queue shared by the queries of every registered connection
+const connectionsQueue = "connections"
+
+// Workers and queues running user queries, set by configureExecutionPool
+var executionPool *execpool.Pool
+
+// configureExecutionPool reads how many queries of each backend run at
+// once from EXECUTION_WORKERS, overridden per dialect by
+// EXECUTION_WORKERS_SQLITE, EXECUTION_WORKERS_MYSQL and
+// EXECUTION_WORKERS_POSTGRESQL, and how many may wait from
+// EXECUTION_QUEUE_SIZE. Workers default to the connections a backend's
+// pool opens, so queries wait in their queue rather than for a connection.
+// Pinned versions follow their dialect.
+func configureExecutionPool() {
+	workers := map[string]int{}
+	for _, name := range []string{"", "sqlite", "mysql", "postgresql"} {
+		variable := "EXECUTION_WORKERS"
+		if name != "" {
+			variable += "_" + strings.ToUpper(name)
+		}
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			workers[name] = n
+		} else {
+			fmt.Printf("Ignoring %s: %q is not a positive number of workers\n", variable, value)
+		}
+	}
+	capacity := defaultExecutionQueueSize
+	if value := os.Getenv("EXECUTION_QUEUE_SIZE"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+			capacity = n
+		} else {
+			fmt.Printf("Ignoring EXECUTION_QUEUE_SIZE: %q is not a number of queries\n", value)
+		}
+	}
+
+	executionPool = execpool.New(func(queue string) execpool.Limits {
+		limits := execpool.Limits{Workers: dbmanager.DefaultMaxOpenConns, Capacity: capacity}
+		if n, ok := workers[""]; ok {
+			limits.Workers = n
+		}
+		if n, ok := workers[dbmanager.BaseDialect(queue)]; ok {
+			limits.Workers = n
+		}
+		return limits
+	})
+}
+
+// runOnPool runs execute on a worker of a backend's queue at a priority
+// and reports how long the query waited for it in the response's
+// executionStats. A full queue answers 503 with errorCode queue_full.
+func runOnPool(ctx context.Context, queue string, priority execpool.Priority, execute func(context.Context) gin.H) (int, gin.H) {
+	if executionPool == nil {
+		return http.StatusOK, execute(ctx)
+	}
+
+	var response gin.H
+	stats, err := executionPool.Do(ctx, queue, priority, func(ctx context.Context) {
+		response = execute(ctx)
+	})
+	if err == execpool.ErrQueueFull {
+		return http.StatusServiceUnavailable, gin.H{
+			"valid":     false,
+			"error":     err.Error(),
+			"errorCode": codeQueueFull,
+		}
+	}
+	if err != nil {
+		return http.StatusGatewayTimeout, gin.H{
+			"valid":     false,
+			"error":     "The query was cancelled while waiting to run: " + err.Error(),
+			"errorCode": dberrors.CodeTimeout,
+		}
+	}
+	response["executionStats"] = gin.H{
+		"queue":       stats.Queue,
+		"priority":    stats.Priority.String(),
+		"queueWaitMs": float64(stats.Wait.Microseconds()) / 1000,
+		"executionMs": float64(stats.Run.Microseconds()) / 1000,
+	}
+	return http.StatusOK, response
+}
+
+// getExecutionQueues reports the workers, running and waiting queries of
+// every backend's execution queue
+func getExecutionQueues(c *gin.Context) {
+	queues := []execpool.QueueStatus{}
+	if executionPool != nil {
+		queues = executionPool.Status()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"queues": queues,
+	})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/features"
+	"example/user/playground/nl2sql"
+	"example/user/playground/sqlvalidator"
+)
+
+// Ways of explaining a query
+const (
+	explainModeRules = "rules"
+	explainModeLLM   = "llm"
+)
+
+type ExplainTextRequest struct {
+	SQL     string `json:"sql" binding:"required"`
+	Dialect string `json:"dialect" binding:"required"`
+	// rules (default) or llm
+	Mode string `json:"mode"`
+}
+
+// explainText describes in plain English what a query does, for learners
+// reading unfamiliar SQL. The rule-based explanation works from the
+// query's clauses; the LLM one asks the provider set up for nl2sql and is
+// gated and rate limited like it.
+func explainText(c *gin.Context) {
+	var req ExplainTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	switch req.Mode {
+	case "", explainModeRules:
+		explanation := sqlvalidator.Explain(req.SQL, req.Dialect)
+		c.JSON(http.StatusOK, gin.H{
+			"mode":    explainModeRules,
+			"summary": explanation.Summary,
+			"steps":   explanation.Steps,
+		})
+	case explainModeLLM:
+		if !features.Enabled(c.Request.Context(), features.NLToSQL) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":     fmt.Sprintf("The %s feature is disabled", features.NLToSQL),
+				"errorCode": codeFeatureDisabled,
+			})
+			return
+		}
+		if !allowRate(c, "rate:nl2sql", nl2sqlPerMinute, "questions") {
+			return
+		}
+
+		explanation, err := nl2sql.Explain(c.Request.Context(), req.SQL, req.Dialect)
+		if errors.Is(err, nl2sql.ErrNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error": "No LLM provider is configured; set NL2SQL_API_KEY or NL2SQL_API_URL",
+			})
+			return
+		}
+		if err != nil {
+			fmt.Printf("Failed to explain a %s query: %v\n", req.Dialect, err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": "Failed to explain the query: " + err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mode":        explainModeLLM,
+			"explanation": explanation,
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("Unknown mode %q; use %s or %s", req.Mode, explainModeRules, explainModeLLM),
+			"errorCode": dberrors.CodeValidationError,
+		})
+	}
+}
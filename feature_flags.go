@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/features"
+	"example/user/playground/sqlsplit"
+	"example/user/playground/sqlvalidator"
+)
+
+// Error code of requests to a capability that is switched off
+const codeFeatureDisabled = "feature_disabled"
+
+type FeatureFlagRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// configureFeatureFlags reads the deployment's flags from FEATURE_FLAGS,
+// such as "security_lab=true,user_connections=false". SECURITY_LAB=true
+// still enables the security lab when FEATURE_FLAGS leaves it out.
+func configureFeatureFlags() {
+	spec := os.Getenv("FEATURE_FLAGS")
+	if os.Getenv("SECURITY_LAB") == "true" {
+		spec = features.SecurityLab + "=true," + spec
+	}
+	if err := features.Configure(spec); err != nil {
+		log.Fatalf("Invalid FEATURE_FLAGS: %v\n", err)
+	}
+}
+
+// requireFeature rejects requests to a capability whose flag is off, as if
+// the route did not exist
+func requireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !features.Enabled(c.Request.Context(), name) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error":     fmt.Sprintf("The %s feature is disabled", name),
+				"errorCode": codeFeatureDisabled,
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Queries IsRetryableQuery checks for SELECT INTO and state-changing calls
+var selectRegex = regexp.MustCompile(`^\s*(select|with)\b`)
+
+// checkReadOnly rejects a request that may write while the read_only flag
+// is on. Every statement must return rows without changing anything, so
+// SELECT INTO, data-modifying CTEs and calls of functions such as nextval
+// are rejected too.
+func checkReadOnly(req SQLValidationRequest) gin.H {
+	if !features.Enabled(context.Background(), features.ReadOnly) {
+		return nil
+	}
+	for _, statement := range sqlsplit.Texts(req.SQL, req.Dialect) {
+		reads := isReadQuery(statement, req.Dialect)
+		if reads && selectRegex.MatchString(strings.ToLower(sqlvalidator.MaskSQL(statement, req.Dialect))) {
+			reads = sqlvalidator.IsRetryableQuery(statement, req.Dialect)
+		}
+		if !reads {
+			return gin.H{
+				"valid":     false,
+				"error":     "The playground is read-only; only queries that read can run",
+				"errorCode": dberrors.CodeBlockedStatement,
+			}
+		}
+	}
+	return nil
+}
+
+// listFeatureFlags returns every feature flag with its state and where the
+// state comes from
+func listFeatureFlags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"flags": features.List(c.Request.Context()),
+	})
+}
+
+// setFeatureFlag switches a flag on or off on every instance until it is
+// reset
+func setFeatureFlag(c *gin.Context) {
+	var req FeatureFlagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	flag, err := features.Set(c.Request.Context(), c.Param("name"), *req.Enabled)
+	if errors.Is(err, features.ErrUnknownFlag) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to set feature flag: " + err.Error(),
+		})
+		return
+	}
+	recordAudit(c, "", "feature.set", flag.Name, strconv.FormatBool(flag.Enabled))
+	c.JSON(http.StatusOK, flag)
+}
+
+// resetFeatureFlag removes the override of a flag so the deployment's
+// configuration applies again
+func resetFeatureFlag(c *gin.Context) {
+	flag, err := features.Reset(c.Request.Context(), c.Param("name"))
+	if errors.Is(err, features.ErrUnknownFlag) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to reset feature flag: " + err.Error(),
+		})
+		return
+	}
+	recordAudit(c, "", "feature.reset", flag.Name, strconv.FormatBool(flag.Enabled))
+	c.JSON(http.StatusOK, flag)
+}
@@ -0,0 +1,190 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"example/user/playground/sharedstate"
+)
+
+// Flags gating capabilities that a deployment may not want
+const (
+	// Seeding large datasets in background jobs
+	LargeDataset = "large_dataset"
+	// Databases registered by users
+	UserConnections = "user_connections"
+	// The deliberately vulnerable SQL injection lab
+	SecurityLab = "security_lab"
+	// Generating SQL from questions through an LLM provider
+	NLToSQL = "nl2sql"
+	// Running queries estimated to return many rows in the background
+	AsyncQueries = "async_queries"
+	// Two-session labs demonstrating isolation levels and deadlocks
+	ConcurrencyLab = "concurrency_lab"
+	// Keeping the results of recent queries to page, export and compare
+	ResultRetention = "result_retention"
+	// Refusing every statement that writes, on every database
+	ReadOnly = "read_only"
+	// Starting and stopping the backends' Docker containers
+	ContainerControl = "container_control"
+)
+
+// Where the state of a flag comes from
+const (
+	SourceDefault  = "default"
+	SourceConfig   = "config"
+	SourceOverride = "override"
+)
+
+// Prefix of the shared state keys holding runtime overrides
+const overridePrefix = "feature:"
+
+// ErrUnknownFlag is returned for a name that is not a known flag
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// Flag is the state of a feature flag
+type Flag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	// Whether the flag is on in a deployment without configuration
+	Default bool `json:"default"`
+	// Whether the state comes from the default, FEATURE_FLAGS or an admin
+	Source string `json:"source"`
+}
+
+// Known flags in display order. New subsystems ship disabled by default.
+var known = []Flag{
+	{Name: LargeDataset, Description: "Seed large datasets in background jobs", Default: true},
+	{Name: UserConnections, Description: "Let logged-in users register their own databases", Default: true},
+	{Name: SecurityLab, Description: "Serve the deliberately vulnerable SQL injection lab", Default: false},
+	{Name: NLToSQL, Description: "Generate SQL from natural language questions through an LLM provider", Default: false},
+	{Name: AsyncQueries, Description: "Run MySQL and PostgreSQL SELECTs estimated to return many rows as background jobs", Default: false},
+	{Name: ConcurrencyLab, Description: "Let instructors run statements in two concurrent MySQL or PostgreSQL sessions", Default: false},
+	{Name: ResultRetention, Description: "Keep the results of recent queries to page, export and compare without running them again", Default: false},
+	{Name: ReadOnly, Description: "Accept only queries that read, on every database", Default: false},
+	{Name: ContainerControl, Description: "Let admins start, stop and restart the MySQL and PostgreSQL containers through the Docker socket", Default: false},
+}
+
+var (
+	// Deployment configuration by flag name, from Configure
+	configured = map[string]bool{}
+
+	// Guards configured
+	mu sync.RWMutex
+)
+
+// Configure sets the deployment's flags from a comma-separated list such as
+// "security_lab=true,user_connections=false". A bare name enables the flag.
+// Flags left out keep their defaults.
+func Configure(spec string) error {
+	values := make(map[string]bool)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, value, hasValue := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := lookup(name); !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+		}
+		enabled := true
+		if hasValue {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return fmt.Errorf("invalid value for feature flag %s: %q", name, value)
+			}
+			enabled = parsed
+		}
+		values[name] = enabled
+	}
+
+	mu.Lock()
+	configured = values
+	mu.Unlock()
+	return nil
+}
+
+// Enabled reports whether a flag is on. An admin override, kept in shared
+// state so every instance agrees, wins over the configuration. Unknown
+// flags are off.
+func Enabled(ctx context.Context, name string) bool {
+	flag, ok := lookup(name)
+	if !ok {
+		return false
+	}
+	return resolve(ctx, flag).Enabled
+}
+
+// List returns the state of every known flag
+func List(ctx context.Context) []Flag {
+	flags := make([]Flag, 0, len(known))
+	for _, flag := range known {
+		flags = append(flags, resolve(ctx, flag))
+	}
+	return flags
+}
+
+// Set overrides a flag at runtime until it is reset
+func Set(ctx context.Context, name string, enabled bool) (Flag, error) {
+	flag, ok := lookup(name)
+	if !ok {
+		return Flag{}, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	value := []byte(strconv.FormatBool(enabled))
+	if err := sharedstate.Current().Set(ctx, overridePrefix+name, value, 0); err != nil {
+		return Flag{}, err
+	}
+	return resolve(ctx, flag), nil
+}
+
+// Reset removes the runtime override of a flag so the configuration
+// applies again
+func Reset(ctx context.Context, name string) (Flag, error) {
+	flag, ok := lookup(name)
+	if !ok {
+		return Flag{}, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	if err := sharedstate.Current().Delete(ctx, overridePrefix+name); err != nil {
+		return Flag{}, err
+	}
+	return resolve(ctx, flag), nil
+}
+
+// lookup returns the definition of a known flag
+func lookup(name string) (Flag, bool) {
+	for _, flag := range known {
+		if flag.Name == name {
+			return flag, true
+		}
+	}
+	return Flag{}, false
+}
+
+// resolve fills in the state of a flag from its override, configuration
+// or default. Overrides that cannot be read are ignored, so an outage of
+// shared state falls back to the configuration.
+func resolve(ctx context.Context, flag Flag) Flag {
+	flag.Enabled = flag.Default
+	flag.Source = SourceDefault
+
+	mu.RLock()
+	if enabled, ok := configured[flag.Name]; ok {
+		flag.Enabled = enabled
+		flag.Source = SourceConfig
+	}
+	mu.RUnlock()
+
+	if value, err := sharedstate.Current().Get(ctx, overridePrefix+flag.Name); err == nil {
+		if enabled, err := strconv.ParseBool(string(value)); err == nil {
+			flag.Enabled = enabled
+			flag.Source = SourceOverride
+		}
+	}
+	return flag
+}
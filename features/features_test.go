@@ -0,0 +1,75 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example/user/playground/sharedstate"
+)
+
+func TestDefaultsAndConfiguration(t *testing.T) {
+	sharedstate.Use(sharedstate.NewMemory())
+	ctx := context.Background()
+
+	if err := Configure(""); err != nil {
+		t.Fatal(err)
+	}
+	if !Enabled(ctx, UserConnections) || Enabled(ctx, SecurityLab) {
+		t.Error("expected the built-in defaults without configuration")
+	}
+
+	if err := Configure("security_lab, user_connections=false"); err != nil {
+		t.Fatal(err)
+	}
+	if !Enabled(ctx, SecurityLab) {
+		t.Error("expected a bare name to enable its flag")
+	}
+	if Enabled(ctx, UserConnections) {
+		t.Error("expected the configuration to disable user_connections")
+	}
+	if !Enabled(ctx, LargeDataset) {
+		t.Error("expected flags left out to keep their defaults")
+	}
+
+	if err := Configure("time_travel=true"); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("expected ErrUnknownFlag, got %v", err)
+	}
+	if err := Configure("security_lab=maybe"); err == nil {
+		t.Error("expected an error for a value that is not a boolean")
+	}
+	if Enabled(ctx, "time_travel") {
+		t.Error("expected unknown flags to be off")
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	sharedstate.Use(sharedstate.NewMemory())
+	ctx := context.Background()
+	if err := Configure("security_lab=false"); err != nil {
+		t.Fatal(err)
+	}
+
+	flag, err := Set(ctx, SecurityLab, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !flag.Enabled || flag.Source != SourceOverride || !Enabled(ctx, SecurityLab) {
+		t.Errorf("expected the override to enable the flag, got %+v", flag)
+	}
+
+	flag, err = Reset(ctx, SecurityLab)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flag.Enabled || flag.Source != SourceConfig {
+		t.Errorf("expected the configuration to apply after a reset, got %+v", flag)
+	}
+
+	if _, err := Set(ctx, "time_travel", true); !errors.Is(err, ErrUnknownFlag) {
+		t.Errorf("expected ErrUnknownFlag, got %v", err)
+	}
+	if flags := List(ctx); len(flags) != len(known) {
+		t.Errorf("expected every known flag to be listed, got %d", len(flags))
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+)
+
+// Caches an admin can flush
+const (
+	// Tables, columns, views and search indexes introspected per backend
+	cacheSchema = "schema"
+	// Retained results of recent queries
+	cacheResults = "results"
+	// Table and column completions of open editors
+	cacheAutocomplete = "autocomplete"
+)
+
+// Every cache, in the order they are flushed
+var flushableCaches = []string{cacheSchema, cacheResults, cacheAutocomplete}
+
+// FlushCacheRequest selects the caches to flush and the backends to flush
+// them for
+type FlushCacheRequest struct {
+	// schema, results or autocomplete; every cache when empty
+	Caches []string `json:"caches"`
+	// Backends such as mysql or postgresql@16; every backend when empty
+	Dialects []string `json:"dialects"`
+}
+
+// FlushedCaches reports the caches flushed for a backend
+type FlushedCaches struct {
+	Backend string   `json:"backend"`
+	Flushed []string `json:"flushed"`
+	// Caches that could not be flushed, with the reason
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// flushCache drops cached state so changes made to a database outside the
+// playground show up without a restart. The schema cache is introspected
+// again at once, and editors are told to reload their completions.
+func flushCache(c *gin.Context) {
+	var req FlushCacheRequest
+	// An empty body flushes everything
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	caches := flushableCaches
+	if len(req.Caches) > 0 {
+		selected := map[string]bool{}
+		for _, name := range req.Caches {
+			if !containsString(flushableCaches, name) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("Invalid request: unknown cache %q, expected one of %s", name, strings.Join(flushableCaches, ", ")),
+				})
+				return
+			}
+			selected[name] = true
+		}
+		caches = []string{}
+		for _, name := range flushableCaches {
+			if selected[name] {
+				caches = append(caches, name)
+			}
+		}
+	}
+
+	statuses := dbmanager.GetConnectionStatuses()
+	backends := req.Dialects
+	if len(backends) == 0 {
+		for backend := range statuses {
+			backends = append(backends, backend)
+		}
+	}
+	for _, backend := range backends {
+		if _, ok := statuses[backend]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request: unknown backend %q", backend),
+			})
+			return
+		}
+	}
+	sort.Strings(backends)
+
+	// Results are kept by dialect, so pinned versions share a flush
+	flushedResults := map[string]error{}
+	report := make([]FlushedCaches, 0, len(backends))
+	for _, backend := range backends {
+		flushed := FlushedCaches{Backend: backend, Flushed: []string{}}
+		fail := func(name string, err error) {
+			if flushed.Errors == nil {
+				flushed.Errors = map[string]string{}
+			}
+			flushed.Errors[name] = err.Error()
+		}
+		for _, name := range caches {
+			var err error
+			switch name {
+			case cacheSchema:
+				// The cache is dropped even when the schema fails to load
+				err = reloadSchema(backend)
+			case cacheResults:
+				dialect := dbmanager.BaseDialect(backend)
+				if _, done := flushedResults[dialect]; !done && retainedResults != nil {
+					flushedResults[dialect] = retainedResults.Flush(dialect)
+				}
+				err = flushedResults[dialect]
+			case cacheAutocomplete:
+				// Reloading the schema has told editors already
+				if containsString(caches, cacheSchema) {
+					break
+				}
+				if schema, ok := dbmanager.CachedSchema(backend); ok {
+					publishSchemaChange(backend, schema)
+				}
+			}
+			if err != nil {
+				fail(name, err)
+				continue
+			}
+			flushed.Flushed = append(flushed.Flushed, name)
+		}
+		report = append(report, flushed)
+	}
+
+	recordAudit(c, "", "cache.flush", strings.Join(backends, ","), strings.Join(caches, ","))
+	c.JSON(http.StatusOK, gin.H{
+		"backends": report,
+	})
+}
+
+// containsString reports whether a list holds a value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/sqlsplit"
+	"example/user/playground/sqlvalidator"
+)
+
+type FormatRequest struct {
+	SQL     string `json:"sql" binding:"required"`
+	Dialect string `json:"dialect" binding:"required"`
+}
+
+// formatSQL quotes identifiers that collide with reserved words and
+// normalizes identifier quoting for the dialect. Each statement of a script
+// is formatted on its own, keeping the delimiters, comments and MySQL
+// DELIMITER commands between them as written.
+func formatSQL(c *gin.Context) {
+	var req FormatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	var formatted strings.Builder
+	end := 0
+	for _, stmt := range sqlsplit.Split(req.SQL, req.Dialect) {
+		formatted.WriteString(req.SQL[end:stmt.Offset])
+		formatted.WriteString(sqlvalidator.SanitizeIdentifiers(stmt.Text, req.Dialect))
+		end = stmt.Offset + len(stmt.Text)
+	}
+	formatted.WriteString(req.SQL[end:])
+
+	c.JSON(http.StatusOK, gin.H{
+		"sql": formatted.String(),
+	})
+}
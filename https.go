@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Directory holding the generated self-signed certificate
+const selfSignedDir = "./tls"
+
+// How long a generated self-signed certificate is valid
+const selfSignedValidity = 365 * 24 * time.Hour
+
+// A self-signed certificate this close to expiry is generated again
+const selfSignedRenewBefore = 7 * 24 * time.Hour
+
+// tlsSettings describes how the server is exposed
+type tlsSettings struct {
+	// Whether HTTPS is served
+	enabled bool
+
+	// Certificate and key served over HTTPS
+	certFile string
+	keyFile  string
+
+	// Listen addresses for plain HTTP and HTTPS
+	httpAddr  string
+	httpsAddr string
+
+	// Whether the plain HTTP listener redirects to HTTPS; without it no
+	// plain HTTP listener runs while HTTPS is enabled
+	redirect bool
+
+	// CAs admin client certificates must chain to, when mutual TLS
+	// protects the admin API
+	adminClientCAs *x509.CertPool
+}
+
+// The server's TLS settings, loaded once by configureTLS
+var serverTLS = tlsSettings{httpAddr: ":8080"}
+
+// configureTLS reads the HTTPS settings. TLS_CERT_FILE and TLS_KEY_FILE
+// serve a provided certificate; TLS_SELF_SIGNED=true generates one for
+// local use. HTTP_PORT (default 8080) and HTTPS_PORT (default 8443) set the
+// ports, and HTTP_REDIRECT=false turns off the plain HTTP listener that
+// otherwise redirects to HTTPS. TLS_ADMIN_CLIENT_CA names a PEM bundle that
+// admin API clients must present a certificate from.
+func configureTLS() error {
+	settings := tlsSettings{
+		httpAddr:  ":" + envOr("HTTP_PORT", "8080"),
+		httpsAddr: ":" + envOr("HTTPS_PORT", "8443"),
+		redirect:  os.Getenv("HTTP_REDIRECT") != "false",
+		certFile:  os.Getenv("TLS_CERT_FILE"),
+		keyFile:   os.Getenv("TLS_KEY_FILE"),
+	}
+
+	switch {
+	case settings.certFile != "" || settings.keyFile != "":
+		if settings.certFile == "" || settings.keyFile == "" {
+			return errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+		}
+		if _, err := tls.LoadX509KeyPair(settings.certFile, settings.keyFile); err != nil {
+			return fmt.Errorf("failed to load TLS certificate: %v", err)
+		}
+		settings.enabled = true
+	case os.Getenv("TLS_SELF_SIGNED") == "true":
+		certFile, keyFile, err := ensureSelfSignedCertificate(selfSignedDir, selfSignedHosts())
+		if err != nil {
+			return fmt.Errorf("failed to generate self-signed certificate: %v", err)
+		}
+		settings.certFile, settings.keyFile = certFile, keyFile
+		settings.enabled = true
+	}
+
+	if path := os.Getenv("TLS_ADMIN_CLIENT_CA"); path != "" {
+		if !settings.enabled {
+			return errors.New("TLS_ADMIN_CLIENT_CA requires HTTPS to be enabled")
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificates found in %s", path)
+		}
+		settings.adminClientCAs = pool
+	}
+
+	serverTLS = settings
+	return nil
+}
+
+// envOr returns an environment variable or a default when it is unset
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// selfSignedHosts returns the names a self-signed certificate covers:
+// localhost and the comma-separated TLS_HOSTS
+func selfSignedHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	for _, host := range strings.Split(os.Getenv("TLS_HOSTS"), ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// ensureSelfSignedCertificate returns a self-signed certificate for hosts
+// in dir, generating a new one when none exists, it is about to expire or
+// it does not cover every host
+func ensureSelfSignedCertificate(dir string, hosts []string) (string, string, error) {
+	certFile := filepath.Join(dir, "self-signed.crt")
+	keyFile := filepath.Join(dir, "self-signed.key")
+	if pair, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil {
+		if cert, err := x509.ParseCertificate(pair.Certificate[0]); err == nil && certificateUsable(cert, hosts) {
+			return certFile, keyFile, nil
+		}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"SQL Playground"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return "", "", err
+	}
+	fmt.Printf("Generated self-signed certificate for %s in %s\n", strings.Join(hosts, ", "), certFile)
+	return certFile, keyFile, nil
+}
+
+// certificateUsable reports whether a certificate covers every host and
+// stays valid for a while yet
+func certificateUsable(cert *x509.Certificate, hosts []string) bool {
+	if time.Until(cert.NotAfter) < selfSignedRenewBefore {
+		return false
+	}
+	for _, host := range hosts {
+		if cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// startServers starts the HTTP and HTTPS listeners for handler and returns
+// them for shutdown
+func startServers(handler http.Handler) []*http.Server {
+	var servers []*http.Server
+	if !serverTLS.enabled {
+		servers = append(servers, &http.Server{Addr: serverTLS.httpAddr, Handler: handler})
+	} else {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+		if serverTLS.adminClientCAs != nil {
+			// Certificates are only required by the admin API, so other
+			// clients can still connect without one
+			tlsConfig.ClientCAs = serverTLS.adminClientCAs
+			tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+		}
+		servers = append(servers, &http.Server{Addr: serverTLS.httpsAddr, Handler: handler, TLSConfig: tlsConfig})
+		if serverTLS.redirect {
+			servers = append(servers, &http.Server{Addr: serverTLS.httpAddr, Handler: http.HandlerFunc(redirectToHTTPS)})
+		}
+	}
+
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			var err error
+			if srv.TLSConfig != nil {
+				fmt.Printf("Server starting on %s (HTTPS)\n", srv.Addr)
+				err = srv.ListenAndServeTLS(serverTLS.certFile, serverTLS.keyFile)
+			} else {
+				fmt.Printf("Server starting on %s\n", srv.Addr)
+				err = srv.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				log.Fatalf("listen: %s\n", err)
+			}
+		}(srv)
+	}
+	return servers
+}
+
+// redirectToHTTPS sends plain HTTP requests to the HTTPS listener. The
+// health check stays reachable over plain HTTP for probes.
+func redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/ping" {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"message":"pong","status":"ok"}`)
+		return
+	}
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if _, port, _ := net.SplitHostPort(serverTLS.httpsAddr); port != "443" {
+		host = net.JoinHostPort(host, port)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+}
+
+// secureCookies reports whether cookies should only be sent over HTTPS
+func secureCookies() bool {
+	return serverTLS.enabled
+}
+
+// hasAdminClientCertificate reports whether a request satisfies mutual TLS
+// for the admin API; it always does when mutual TLS is not configured
+func hasAdminClientCertificate(r *http.Request) bool {
+	if serverTLS.adminClientCAs == nil {
+		return true
+	}
+	return r.TLS != nil && len(r.TLS.VerifiedChains) > 0
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"database/sql"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/sqlvalidator"
+)
+
+// checkIsolationLevel checks the isolation level of a request against what
+// its dialect supports and rewrites it to its canonical name, such as
+// "REPEATABLE READ". It returns the response rejecting an unsupported one.
+func checkIsolationLevel(req *SQLValidationRequest) gin.H {
+	if req.IsolationLevel == "" {
+		return nil
+	}
+	level, _, err := sqlvalidator.IsolationLevel(req.IsolationLevel, req.Dialect)
+	if err != nil {
+		return gin.H{
+			"valid":     false,
+			"error":     "Invalid isolation level: " + err.Error(),
+			"errorCode": dberrors.CodeValidationError,
+		}
+	}
+	req.IsolationLevel = level
+	return nil
+}
+
+// txOptions returns the transaction options of a request checked by
+// checkIsolationLevel, or nil when it runs at the database's default level
+func txOptions(req SQLValidationRequest) *sql.TxOptions {
+	if req.IsolationLevel == "" {
+		return nil
+	}
+	_, level, err := sqlvalidator.IsolationLevel(req.IsolationLevel, req.Dialect)
+	if err != nil {
+		return nil
+	}
+	return &sql.TxOptions{Isolation: level}
+}
+
+// withIsolationLevel adds the isolation level a statement ran at to its
+// response
+func withIsolationLevel(response gin.H, level string) gin.H {
+	if level != "" {
+		response["isolationLevel"] = level
+	}
+	return response
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"encoding/json"
+)
+
+// jsonColumnsAsText turns the JSON documents of results back into their
+// text, for clients that asked for raw JSON
+func jsonColumnsAsText(results []*QueryResult) {
+	for _, result := range results {
+		for _, row := range result.Rows {
+			for _, i := range result.JSONColumns {
+				if document, ok := row[i].(json.RawMessage); ok {
+					row[i] = string(document)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+)
+
+type LargeDatasetRequest struct {
+	Dialect string `json:"dialect" binding:"required"`
+	// Number of rows to generate; defaults to LARGE_DATASET_ROWS
+	Rows int `json:"rows"`
+}
+
+// getLargeDatasetJobs returns the latest large dataset seeding job of every
+// backend and the configured limits
+func getLargeDatasetJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"default_rows": dbmanager.LargeDatasetRows(),
+		"min_rows":     dbmanager.MinLargeDatasetRows,
+		"max_rows":     dbmanager.MaxLargeDatasetRows,
+		"jobs":         dbmanager.LargeDatasetJobs(),
+	})
+}
+
+// startLargeDataset starts seeding the large_orders table of a backend.
+// Progress is reported by getLargeDatasetJobs and on the event stream.
+func startLargeDataset(c *gin.Context) {
+	var req LargeDatasetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.Rows == 0 {
+		req.Rows = dbmanager.LargeDatasetRows()
+	}
+
+	job, err := dbmanager.StartLargeDataset(req.Dialect, req.Rows)
+	if err == dbmanager.ErrSeedRunning {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	recordAudit(c, "", "large_dataset.start", req.Dialect, fmt.Sprintf("%d rows", req.Rows))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// cancelLargeDataset aborts the running seeding job of a backend
+func cancelLargeDataset(c *gin.Context) {
+	if err := dbmanager.CancelLargeDataset(c.Param("dialect")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	recordAudit(c, "", "large_dataset.cancel", c.Param("dialect"), "")
+	c.JSON(http.StatusAccepted, gin.H{
+		"status": "cancelling",
+	})
+}
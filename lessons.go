@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/lessons"
+	"example/user/playground/sqlvalidator"
+)
+
+// LessonAnswerRequest is a query submitted as the answer to an exercise
+type LessonAnswerRequest struct {
+	SQL     string `json:"sql" binding:"required"`
+	Dialect string `json:"dialect" binding:"required"`
+}
+
+// loadCustomLessons registers the lessons found in LESSONS_DIR
+func loadCustomLessons() {
+	dir := os.Getenv("LESSONS_DIR")
+	if dir == "" {
+		return
+	}
+	if err := lessons.LoadDir(dir); err != nil {
+		fmt.Printf("Failed to load lessons from %s: %v\n", dir, err)
+	}
+}
+
+// listLessons returns the lessons available for a dialect in course order
+func listLessons(c *gin.Context) {
+	summaries := []gin.H{}
+	for _, lesson := range lessons.List(c.Query("dialect")) {
+		summaries = append(summaries, gin.H{
+			"id":             lesson.ID,
+			"title":          lesson.Title,
+			"description":    lesson.Description,
+			"order":          lesson.Order,
+			"exercise_count": len(lesson.Exercises),
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"lessons": summaries,
+	})
+}
+
+// getLesson returns a lesson and its exercises without their answers
+func getLesson(c *gin.Context) {
+	lesson, err := lessons.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	exercises := []gin.H{}
+	for _, exercise := range lesson.Exercises {
+		exercises = append(exercises, gin.H{
+			"id":     exercise.ID,
+			"prompt": exercise.Prompt,
+			"hint":   exercise.Hint,
+		})
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":          lesson.ID,
+		"title":       lesson.Title,
+		"description": lesson.Description,
+		"order":       lesson.Order,
+		"setup":       lesson.Setup,
+		"exercises":   exercises,
+	})
+}
+
+// submitLessonAnswer grades an answer query against an exercise
+func submitLessonAnswer(c *gin.Context) {
+	var req LessonAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	lesson, err := lessons.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	exercise, err := lesson.Exercise(c.Param("exercise"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "exercise not found",
+		})
+		return
+	}
+	if !lesson.Supports(req.Dialect) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "This lesson is not available for " + req.Dialect,
+		})
+		return
+	}
+
+	// Lesson tables only exist during grading, so only syntax and safety
+	// are checked up front
+	// Answers run in a rolled-back transaction, so confirmation is not needed
+	safetyCheck := sqlvalidator.IsSafeDDLOperation(req.SQL, req.Dialect)
+	if !safetyCheck.Safe && !safetyCheck.RequiresConfirmation {
+		c.JSON(http.StatusOK, gin.H{
+			"passed":    false,
+			"error":     safetyCheck.Error,
+			"errorCode": dberrors.CodeBlockedStatement,
+		})
+		return
+	}
+	if valid, err := sqlvalidator.Validate(req.SQL, req.Dialect); !valid {
+		response := validationErrorResponse(err, req.SQL, req.Dialect)
+		response["passed"] = false
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	db, err := dbmanager.GetDatabaseConnection(req.Dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection error: " + err.Error(),
+		})
+		return
+	}
+
+	feedback, err := lessons.Check(db, req.Dialect, lesson, exercise, req.SQL)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to grade answer: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, feedback)
+}
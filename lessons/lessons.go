@@ -0,0 +1,186 @@
+package lessons
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"example/user/playground/resultcompare"
+)
+
+// ErrNotFound is returned when a lesson or exercise does not exist
+var ErrNotFound = errors.New("lesson not found")
+
+// Lesson is an ordered group of exercises sharing a dataset
+type Lesson struct {
+	ID          string `json:"id" yaml:"id"`
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description" yaml:"description"`
+	// Position of the lesson in the course
+	Order int `json:"order" yaml:"order"`
+	// Scripts creating the lesson's tables, keyed by dialect
+	Setup     map[string]string `json:"setup" yaml:"setup"`
+	Exercises []Exercise        `json:"exercises" yaml:"exercises"`
+}
+
+// Exercise asks for a query and describes how to grade it. Exactly one of
+// Expected, Solution or Checker is used, in that order of preference.
+type Exercise struct {
+	ID     string `json:"id" yaml:"id"`
+	Prompt string `json:"prompt" yaml:"prompt"`
+	Hint   string `json:"hint,omitempty" yaml:"hint"`
+	// Extra setup run after the lesson setup, keyed by dialect
+	Setup map[string]string `json:"setup,omitempty" yaml:"setup"`
+	// Result set the answer must produce
+	Expected *resultcompare.ResultSet `json:"expected,omitempty" yaml:"expected"`
+	// Reference query whose result the answer must match
+	Solution string `json:"solution,omitempty" yaml:"solution"`
+	// Query run after the answer that must return a single true value,
+	// used for exercises that modify data
+	Checker string `json:"checker,omitempty" yaml:"checker"`
+	// Whether rows must appear in the expected order
+	OrderMatters bool `json:"order_matters,omitempty" yaml:"order_matters"`
+	// Largest difference at which two numbers count as equal
+	FloatTolerance float64 `json:"float_tolerance,omitempty" yaml:"float_tolerance"`
+}
+
+//go:embed data/*
+var builtinFiles embed.FS
+
+var (
+	// Loaded lessons keyed by ID
+	lessons = make(map[string]*Lesson)
+
+	// Guards lessons
+	lessonsMu sync.RWMutex
+)
+
+func init() {
+	if err := loadFS(builtinFiles, "data"); err != nil {
+		panic(fmt.Sprintf("invalid built-in lesson: %v", err))
+	}
+}
+
+// LoadDir adds the lessons stored as .json, .yaml or .yml files in dir,
+// replacing lessons with the same ID
+func LoadDir(dir string) error {
+	return loadFS(os.DirFS(dir), ".")
+}
+
+// loadFS parses and registers every lesson file in dir of fsys
+func loadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, entry.Name())))
+		if err != nil {
+			return err
+		}
+
+		var lesson Lesson
+		if ext == ".json" {
+			err = json.Unmarshal(data, &lesson)
+		} else {
+			err = yaml.Unmarshal(data, &lesson)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+		if err := lesson.validate(); err != nil {
+			return fmt.Errorf("%s: %v", entry.Name(), err)
+		}
+
+		lessonsMu.Lock()
+		lessons[lesson.ID] = &lesson
+		lessonsMu.Unlock()
+	}
+	return nil
+}
+
+// validate checks that a lesson can be served and graded
+func (l *Lesson) validate() error {
+	if l.ID == "" || l.Title == "" {
+		return errors.New("lesson needs an id and a title")
+	}
+	seen := make(map[string]bool)
+	for _, exercise := range l.Exercises {
+		if exercise.ID == "" || seen[exercise.ID] {
+			return fmt.Errorf("lesson %s has a missing or duplicate exercise id", l.ID)
+		}
+		seen[exercise.ID] = true
+		if exercise.Expected == nil && exercise.Solution == "" && exercise.Checker == "" {
+			return fmt.Errorf("exercise %s has no expected result, solution or checker", exercise.ID)
+		}
+	}
+	return nil
+}
+
+// List returns the lessons available for a dialect in course order. An
+// empty dialect returns every lesson.
+func List(dialect string) []*Lesson {
+	lessonsMu.RLock()
+	defer lessonsMu.RUnlock()
+
+	result := []*Lesson{}
+	for _, lesson := range lessons {
+		if dialect == "" || lesson.Supports(dialect) {
+			result = append(result, lesson)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Order != result[j].Order {
+			return result[i].Order < result[j].Order
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Get returns a lesson by ID
+func Get(id string) (*Lesson, error) {
+	lessonsMu.RLock()
+	defer lessonsMu.RUnlock()
+
+	lesson, ok := lessons[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return lesson, nil
+}
+
+// Supports reports whether the lesson has a setup script for dialect, or
+// needs none
+func (l *Lesson) Supports(dialect string) bool {
+	if len(l.Setup) == 0 {
+		return true
+	}
+	_, ok := l.Setup[dialect]
+	return ok
+}
+
+// Exercise returns an exercise of the lesson by ID
+func (l *Lesson) Exercise(id string) (*Exercise, error) {
+	for i := range l.Exercises {
+		if l.Exercises[i].ID == id {
+			return &l.Exercises[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
@@ -0,0 +1,53 @@
+package lessons
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuiltinLessonsLoad(t *testing.T) {
+	list := List("sqlite")
+	if len(list) == 0 {
+		t.Fatal("expected built-in lessons")
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Order > list[i].Order {
+			t.Errorf("lessons out of order: %s before %s", list[i-1].ID, list[i].ID)
+		}
+	}
+}
+
+func TestLoadDirJSON(t *testing.T) {
+	dir := t.TempDir()
+	content := `{"id": "custom", "title": "Custom", "order": 99, "setup": {"sqlite": "CREATE TEMP TABLE t (x INTEGER);"},
+		"exercises": [{"id": "one", "prompt": "Select one", "expected": {"columns": ["x"], "rows": [[1]]}}]}`
+	if err := os.WriteFile(filepath.Join(dir, "custom.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	lesson, err := Get("custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lesson.Supports("mysql") || !lesson.Supports("sqlite") {
+		t.Errorf("unexpected dialect support for %+v", lesson.Setup)
+	}
+	if _, err := lesson.Exercise("one"); err != nil {
+		t.Errorf("expected exercise one: %v", err)
+	}
+}
+
+func TestLoadDirRejectsUngradableExercise(t *testing.T) {
+	dir := t.TempDir()
+	content := "id: broken\ntitle: Broken\nexercises:\n  - id: one\n    prompt: No answer given\n"
+	if err := os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadDir(dir); err == nil {
+		t.Error("expected an error for an exercise without expected result, solution or checker")
+	}
+}
@@ -0,0 +1,116 @@
+package lessons
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"example/user/playground/resultcompare"
+	"example/user/playground/sqlsplit"
+)
+
+// Most rows read from an answer or reference query
+const maxRows = 1000
+
+// Time allowed for setup, answer and grading together
+const checkTimeout = 10 * time.Second
+
+// Feedback is the outcome of grading an answer
+type Feedback struct {
+	Passed  bool                     `json:"passed"`
+	Message string                   `json:"message"`
+	Report  *resultcompare.Report    `json:"report,omitempty"`
+	Result  *resultcompare.ResultSet `json:"result,omitempty"`
+}
+
+// Check runs the lesson and exercise setup, the answer and the grading
+// query on a private connection inside a transaction that is rolled back,
+// so submissions never change the playground database. The connection is
+// discarded afterwards, taking any temporary tables with it.
+func Check(db *sql.DB, dialect string, lesson *Lesson, exercise *Exercise, answer string) (*Feedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkTimeout)
+	defer cancel()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer discard(conn)
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, script := range []string{lesson.Setup[dialect], exercise.Setup[dialect]} {
+		for _, stmt := range sqlsplit.Texts(script, dialect) {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return nil, fmt.Errorf("lesson setup failed: %v", err)
+			}
+		}
+	}
+
+	actual, err := resultcompare.Query(ctx, tx, answer, maxRows)
+	if err != nil {
+		return &Feedback{Passed: false, Message: "Your query failed: " + err.Error()}, nil
+	}
+
+	var feedback *Feedback
+	switch {
+	case exercise.Expected != nil:
+		feedback = grade(exercise.Expected, actual, exercise)
+	case exercise.Solution != "":
+		expected, err := resultcompare.Query(ctx, tx, exercise.Solution, maxRows)
+		if err != nil {
+			return nil, fmt.Errorf("reference solution failed: %v", err)
+		}
+		feedback = grade(expected, actual, exercise)
+	default:
+		feedback, err = runChecker(ctx, tx, exercise.Checker)
+		if err != nil {
+			return nil, err
+		}
+	}
+	feedback.Result = actual
+	return feedback, nil
+}
+
+// grade compares an answer's result with the expected one using the
+// exercise's comparison rules
+func grade(expected *resultcompare.ResultSet, actual *resultcompare.ResultSet, exercise *Exercise) *Feedback {
+	report := resultcompare.Compare(expected, actual, resultcompare.Options{
+		IgnoreRowOrder: !exercise.OrderMatters,
+		FloatTolerance: exercise.FloatTolerance,
+	})
+	if report.Match {
+		return &Feedback{Passed: true, Message: "Correct!", Report: report}
+	}
+	return &Feedback{Passed: false, Message: report.Message, Report: report}
+}
+
+// discard closes conn and keeps the pool from reusing its session
+func discard(conn *sql.Conn) {
+	conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
+
+// runChecker passes when the checker query returns a single true value
+func runChecker(ctx context.Context, tx *sql.Tx, checker string) (*Feedback, error) {
+	result, err := resultcompare.Query(ctx, tx, checker, maxRows)
+	if err != nil {
+		return nil, fmt.Errorf("checker query failed: %v", err)
+	}
+	if len(result.Rows) == 1 && len(result.Rows[0]) == 1 {
+		switch strings.ToLower(fmt.Sprint(result.Rows[0][0])) {
+		case "1", "true", "t":
+			return &Feedback{Passed: true, Message: "Correct!"}, nil
+		}
+	}
+	return &Feedback{Passed: false, Message: "The data does not look right yet"}, nil
+}
@@ -0,0 +1,71 @@
+package lessons
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", "file:"+t.Name()+"?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestCheckBuiltinExercises(t *testing.T) {
+	db := openTestDB(t)
+	lesson, err := Get("selecting-rows")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		exercise string
+		answer   string
+		passed   bool
+	}{
+		{"all-names", "SELECT name FROM pets ORDER BY name", true},
+		{"all-names", "SELECT name FROM pets WHERE age > 2", false},
+		{"cats", "SELECT name, age FROM pets WHERE species = 'cat'", true},
+		{"cats", "SELECT name FROM pets WHERE species = 'cat'", false},
+		{"oldest-first", "SELECT name FROM pets ORDER BY age DESC", true},
+		{"oldest-first", "SELECT name FROM pets ORDER BY age", false},
+	}
+	for _, test := range tests {
+		exercise, err := lesson.Exercise(test.exercise)
+		if err != nil {
+			t.Fatal(err)
+		}
+		feedback, err := Check(db, "sqlite", lesson, exercise, test.answer)
+		if err != nil {
+			t.Fatalf("%s: %v", test.exercise, err)
+		}
+		if feedback.Passed != test.passed {
+			t.Errorf("%s with %q: expected passed=%v, got %+v", test.exercise, test.answer, test.passed, feedback)
+		}
+	}
+}
+
+func TestCheckWithChecker(t *testing.T) {
+	db := openTestDB(t)
+	lesson, err := Get("changing-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exercise, _ := lesson.Exercise("insert-item")
+
+	feedback, err := Check(db, "sqlite", lesson, exercise, "INSERT INTO stock (item, quantity) VALUES ('bananas', 25)")
+	if err != nil || !feedback.Passed {
+		t.Fatalf("expected insert to pass, got %+v, %v", feedback, err)
+	}
+
+	// Every submission starts from freshly created lesson tables
+	feedback, err = Check(db, "sqlite", lesson, exercise, "SELECT 1")
+	if err != nil || feedback.Passed {
+		t.Fatalf("expected stale data to be gone, got %+v, %v", feedback, err)
+	}
+}
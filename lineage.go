@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/sqlvalidator"
+)
+
+type LineageRequest struct {
+	SQL     string `json:"sql" binding:"required"`
+	Dialect string `json:"dialect" binding:"required"`
+}
+
+// traceLineage reports which table columns each output column of a query
+// derives from, through aliases, expressions, derived tables and CTEs. The
+// bundled database's cached schema expands stars and places unqualified
+// columns; the query is not run.
+func traceLineage(c *gin.Context) {
+	var req LineageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	schema, _ := dbmanager.CachedSchema(req.Dialect)
+	columns, err := sqlvalidator.Lineage(req.SQL, req.Dialect, schema)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     err.Error(),
+			"errorCode": dberrors.CodeValidationError,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"columns": columns})
+}
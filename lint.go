@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/sqlvalidator"
+)
+
+// Analyses the lint endpoint runs
+const (
+	lintModeWarnings    = "warnings"
+	lintModePortability = "portability"
+)
+
+type LintRequest struct {
+	SQL     string `json:"sql" binding:"required"`
+	Dialect string `json:"dialect" binding:"required"`
+	// warnings (default) or portability
+	Mode string `json:"mode"`
+	// Dialects portability is checked against; every other one by default
+	Targets []string `json:"targets"`
+}
+
+// lintSQL reviews a query without running it. The default mode returns
+// the warnings validation would give, such as reserved names and
+// collation mismatches; the portability mode reports the constructs other
+// dialects reject, with their equivalents there.
+func lintSQL(c *gin.Context) {
+	var req LintRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	switch req.Mode {
+	case "", lintModeWarnings:
+		warnings := sqlvalidator.IsSafeDDLOperation(req.SQL, req.Dialect).Warnings
+		warnings = append(warnings, sqlvalidator.ReservedNameWarnings(req.SQL, req.Dialect)...)
+		if _, ok := dbmanager.CachedSchema(req.Dialect); ok {
+			warnings = append(warnings, collationWarnings(req.SQL, req.Dialect)...)
+		}
+		if warnings == nil {
+			warnings = []string{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mode":     lintModeWarnings,
+			"warnings": warnings,
+		})
+	case lintModePortability:
+		for _, target := range req.Targets {
+			if _, ok := sqlvalidator.Capabilities(target); !ok {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":     "Unsupported target dialect: " + target,
+					"errorCode": dberrors.CodeValidationError,
+				})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"mode":   lintModePortability,
+			"issues": sqlvalidator.CheckPortability(req.SQL, req.Dialect, req.Targets),
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     fmt.Sprintf("Unknown mode %q; use %s or %s", req.Mode, lintModeWarnings, lintModePortability),
+			"errorCode": dberrors.CodeValidationError,
+		})
+	}
+}
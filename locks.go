@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+)
+
+// getLocks reports the sessions of a dialect's database that wait for a
+// lock and the sessions blocking them, or on SQLite whether a writer holds
+// the database
+func getLocks(c *gin.Context) {
+	dialect := c.Param("dialect")
+	report, err := dbmanager.Locks(c.Request.Context(), dialect)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list locks: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -17,32 +19,188 @@ import (
 	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 
+	"example/user/playground/auth"
+	"example/user/playground/dberrors"
 	"example/user/playground/dbmanager"
+	"example/user/playground/execpool"
+	"example/user/playground/features"
+	"example/user/playground/mockdb"
+	"example/user/playground/resultstats"
+	"example/user/playground/resultvalues"
+	"example/user/playground/routines"
 	"example/user/playground/sqlvalidator"
 )
 
 type SQLValidationRequest struct {
-	SQL     string `json:"sql" binding:"required"`
-	Dialect string `json:"dialect" binding:"required"`
+	SQL          string `json:"sql" binding:"required"`
+	Dialect      string `json:"dialect" binding:"required"`
+	DryRun       bool   `json:"dryRun"`
+	Preview      bool   `json:"preview"`
+	ValidateOnly bool   `json:"validateOnly"`
+	// Token from a requiresConfirmation response that confirms this statement
+	ConfirmationToken string `json:"confirmationToken"`
+	// Registered connection of the user to run the query on instead of the
+	// bundled database of the dialect
+	ConnectionID string `json:"connectionId"`
+	// Pinned server version of the dialect, such as "16" for a PostgreSQL
+	// 16 backend registered alongside the default one
+	Version string `json:"version"`
+	// Name of the query, naming the sheet and file of XLSX downloads
+	Name string `json:"name"`
+	// Render the result as a "markdown" or "ascii" table, or as "insert"
+	// statements, instead of the format of the Accept header
+	Format string `json:"format"`
+	// Table, dialect and batch size of the insert format
+	Insert *InsertOptions `json:"insert"`
+	// Run the statement in a transaction at this isolation level, such as
+	// "REPEATABLE READ", instead of the database's default
+	IsolationLevel string `json:"isolationLevel"`
+	// Return JSON columns as their text instead of parsed values
+	RawJSON bool `json:"rawJson"`
+	// Summarize each column of the returned rows
+	ComputeStats bool `json:"computeStats"`
+	// Byte offset of the editor's cursor in SQL, which is then the whole
+	// editor buffer; only the statement at the cursor runs
+	Cursor *int `json:"cursor"`
+	// Selected range of the editor buffer in SQL; only the selection runs.
+	// It takes precedence over the cursor unless it is blank.
+	Selection *EditorSelection `json:"selection"`
+	// Download the result is written to as it is read, for formats other
+	// than JSON
+	export *resultExport
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
 }
 
 type QueryResult struct {
 	Columns []string        `json:"columns"`
 	Rows    [][]interface{} `json:"rows"`
+	// Indexes of the columns holding JSON documents
+	JSONColumns []int `json:"jsonColumns,omitempty"`
+	// Per-column statistics of the rows, when requested
+	Stats []resultstats.Column `json:"stats,omitempty"`
+	// Every row read for retention when there are more than the response
+	// shows
+	retainedRows [][]interface{}
 }
 
+// Rows of a result set a response shows
+const shownRows = 10
+
 func main() {
 	fmt.Println("Starting SQL Playground server...")
 
+	// Load the HTTP and HTTPS listener settings
+	if err := configureTLS(); err != nil {
+		log.Fatalf("TLS configuration error: %v\n", err)
+	}
+
+	// Keep tokens, shares, locks and rate limits in memory or Redis
+	configureSharedState()
+
+	// Switch experimental capabilities on or off from FEATURE_FLAGS
+	configureFeatureFlags()
+
+	// Push connection changes to clients of the event stream
+	publishConnectionEvents()
+
+	// Push large dataset seeding progress to clients of the event stream
+	publishSeedProgress()
+
 	// Initialize database connections
 	err := dbmanager.InitDatabases()
 	if err != nil {
 		fmt.Printf("Error initializing database connections: %v\n", err)
 	}
 
+	// Open the store of history, snippets, sessions and audit records
+	configureMetadataStore()
+
+	// Initialize the user credential store
+	if err := auth.Init(credentialStorePath, metadata); err != nil {
+		fmt.Printf("Error initializing credential store: %v\n", err)
+	}
+
+	// Load the keys encrypting stored credentials
+	configureSecrets()
+
+	// Open the store of user-registered database connections
+	configureUserConnections()
+
+	// Configure external identity providers
+	if err := auth.LoadProviders(publicBaseURL() + oauthPath); err != nil {
+		fmt.Printf("Error configuring identity providers: %v\n", err)
+	}
+
+	// Configure slow query logging
+	configureSlowQueryLog()
+
+	// Limit the size and content of API request bodies
+	configurePayloadLimits()
+
+	// Bound the time and memory of each statement per dialect
+	configureQueryLimits()
+
+	// Retry read-only queries that fail with transient errors
+	configureQueryRetries()
+
+	// Load the safety policy from SAFETY_POLICY
+	configureSafetyPolicy()
+
+	// Prepare the SQL injection lab when its feature flag is on
+	configureSecurityLab()
+
+	// Set up the LLM provider of natural language questions
+	configureNL2SQL()
+
+	// Run user queries on bounded workers with a queue per backend
+	configureExecutionPool()
+
+	// Start the workers of queries routed to the background
+	configureAsyncQueries()
+
+	// Keep the results of recent queries for RESULT_RETENTION
+	configureResultRetention()
+
+	// Cap downloads at EXPORT_MAX_ROWS rows
+	configureExports()
+
+	// Keep BLOB values of results for download
+	configureBinaryDownloads()
+
+	// Truncate result cells over MAX_CELL_BYTES
+	configureCellLimits()
+
+	// Close concurrency labs left unused for CONCURRENCY_LAB_IDLE_TIMEOUT
+	configureConcurrencyLab()
+
+	// Drop stored routines of sessions idle for ROUTINE_IDLE_TIMEOUT
+	startRoutineCleanup(routineIdleTimeout())
+
+	// Add teaching templates from TEMPLATES_DIR to the built-in library
+	loadCustomTemplates()
+
+	// Add lessons from LESSONS_DIR to the built-in course
+	loadCustomLessons()
+
+	// Terminate backend sessions idle in a transaction or running too long
+	configureReaper()
+
+	// Control the backends' containers when the Docker socket is mounted
+	configureContainerControl()
+
+	// Periodically snapshot the SQLite playground so experiments can be undone
+	dbmanager.StartPeriodicSnapshots(snapshotInterval())
+
 	// Initialize gin router
 	r := gin.Default()
 
+	// Compress responses with zstd or gzip when the client accepts it
+	r.Use(compressResponses)
+
 	// Configure CORS
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
@@ -53,6 +211,9 @@ func main() {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	// Resolve the logged-in user for every request
+	r.Use(loadCurrentUser)
+
 	// Serve static files
 	r.Static("/static", "./static")
 	r.StaticFile("/favicon.ico", "./static/favicon.ico")
@@ -62,6 +223,9 @@ func main() {
 		c.File("./static/index.html")
 	})
 
+	// Admin dashboard, for admins only like the API it calls
+	r.GET("/admin", requireAdmin, getAdminDashboard)
+
 	// Health check endpoint
 	r.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -71,26 +235,12 @@ func main() {
 		})
 	})
 
-	// Group API routes
-	api := r.Group("/api")
-	{
-		api.POST("/validate-sql", validateAndExecuteSQL)
-		api.GET("/db-status", getDatabaseStatus)
-	}
-
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:    ":8080",
-		Handler: r,
-	}
+	// API routes, versioned under /api/v1. The unversioned /api paths stay
+	// available as deprecated aliases of v1.
+	registerAPIVersions(r)
 
-	// Start the server in a goroutine
-	go func() {
-		fmt.Println("Server starting on :8080")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("listen: %s\n", err)
-		}
-	}()
+	// Serve HTTP, or HTTPS with a redirect from HTTP when TLS is configured
+	servers := startServers(r)
 
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
@@ -102,8 +252,10 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Fatal("Server forced to shutdown:", err)
+		}
 	}
 
 	fmt.Println("Server exited properly")
@@ -119,66 +271,338 @@ func validateAndExecuteSQL(c *gin.Context) {
 		return
 	}
 
+	if response := checkResultFormat(req); response != nil {
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+	statement, response := resolveStatement(&req)
+	if response != nil {
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Downloads are written as their rows are read
+	if format := resultFormat(c, req); format != "" && format != formatJSON {
+		req.export = &resultExport{c: c, format: format}
+	}
+
+	status, response := executeSQLRequest(c.Request.Context(), sessionOwner(c), req, execpool.Interactive)
+	if statement != nil {
+		response["statement"] = statement
+	}
+	writeExecuteResponse(c, status, response, req)
+}
+
+// executeSQLRequest validates and executes a query for a session owner and
+// returns the status and response to send. Statements run on the
+// backend's execution queue at priority and are cancelled when ctx ends, as
+// when the client disconnects; queries queued to run in the background are
+// not.
+func executeSQLRequest(ctx context.Context, owner string, req SQLValidationRequest, priority execpool.Priority) (int, gin.H) {
+	// Validate without executing when requested
+	if req.ValidateOnly {
+		return http.StatusOK, validateOffline(req)
+	}
+
+	// Nothing that writes runs while the playground is read-only
+	if response := checkReadOnly(req); response != nil {
+		return http.StatusOK, response
+	}
+
 	// First run safety checks
 	safetyCheck := sqlvalidator.IsSafeDDLOperation(req.SQL, req.Dialect)
-	if !safetyCheck.Safe {
-		c.JSON(http.StatusOK, gin.H{
-			"valid": false,
-			"error": safetyCheck.Error,
-		})
-		return
+	if safetyCheck.RequiresConfirmation {
+		if status, response := confirmStatement(owner, req, safetyCheck); response != nil {
+			return status, response
+		}
+	} else if !safetyCheck.Safe {
+		return http.StatusOK, gin.H{
+			"valid":     false,
+			"error":     safetyCheck.Error,
+			"errorCode": dberrors.CodeBlockedStatement,
+			"rule":      safetyCheck.Rule,
+		}
 	}
 
 	// Then validate the SQL
 	valid, err := sqlvalidator.Validate(req.SQL, req.Dialect)
 	if !valid {
-		c.JSON(http.StatusOK, gin.H{
-			"valid": false,
-			"error": err.Error(),
+		return http.StatusOK, validationErrorResponse(err, req.SQL, req.Dialect)
+	}
+	safetyCheck.Warnings = append(safetyCheck.Warnings, sqlvalidator.ReservedNameWarnings(req.SQL, req.Dialect)...)
+	if response := checkIsolationLevel(&req); response != nil {
+		return http.StatusOK, response
+	}
+
+	// Registered connections run on the user's own database, outside the
+	// session namespaces and cached schemas of the bundled ones
+	if req.ConnectionID != "" {
+		if !features.Enabled(ctx, features.UserConnections) {
+			return http.StatusNotFound, gin.H{
+				"valid":     false,
+				"error":     fmt.Sprintf("The %s feature is disabled", features.UserConnections),
+				"errorCode": codeFeatureDisabled,
+			}
+		}
+		status, response := runOnPool(ctx, connectionsQueue, priority, func(ctx context.Context) gin.H {
+			return executeOnConnection(ctx, owner, req)
 		})
-		return
+		return status, withWarnings(response, safetyCheck.Warnings)
+	}
+
+	// The mock dialect synthesizes results without a database
+	if req.Dialect == mockdb.Dialect {
+		return http.StatusOK, executeMock(req)
+	}
+
+	// If validation succeeds, execute the query on the requested version
+	db, err := dbmanager.GetVersionedConnection(req.Dialect, req.Version)
+	if errors.Is(err, dbmanager.ErrUnknownVersion) {
+		return http.StatusBadRequest, gin.H{
+			"valid":     false,
+			"error":     err.Error(),
+			"errorCode": dberrors.CodeValidationError,
+		}
+	}
+	if err != nil {
+		return http.StatusOK, gin.H{
+			"valid":     true,
+			"error":     "Database connection error: " + err.Error(),
+			"errorCode": dberrors.CodeConnectionError,
+			"result":    nil,
+		}
 	}
 
-	// If validation succeeds, execute the query
-	db, err := dbmanager.GetDatabaseConnection(req.Dialect)
+	// Keep procedures, functions, triggers and views inside the session's
+	// namespace, and let the session's views be queried by their short names
+	routines.Touch(owner)
+	statementSQL, routine, err := routines.Scope(req.SQL, req.Dialect, owner)
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"valid":  true,
-			"error":  "Database connection error: " + err.Error(),
-			"result": nil,
+		return http.StatusOK, gin.H{
+			"valid":     false,
+			"error":     err.Error(),
+			"errorCode": dberrors.CodeBlockedStatement,
+		}
+	}
+	scopeRewrites := []sqlvalidator.Rewrite{}
+	if statementSQL != req.SQL {
+		scopeRewrites = append(scopeRewrites, namespaceRewrite(routine))
+	}
+	statementSQL, resolved := routines.Resolve(statementSQL, req.Dialect, owner)
+	if len(resolved) > 0 {
+		scopeRewrites = append(scopeRewrites, viewResolutionRewrite(resolved))
+	}
+
+	// Catch unknown tables and columns before the database sees the query;
+	// routine bodies refer to parameters and variables the schema lacks
+	backend := dbmanager.ConnectionKey(req.Dialect, req.Version)
+	if schema, ok := dbmanager.CachedSchema(backend); ok && routine == nil {
+		if err := sqlvalidator.CheckReferences(statementSQL, req.Dialect, schema); err != nil {
+			return http.StatusOK, referenceErrorResponse(err)
+		}
+		safetyCheck.Warnings = append(safetyCheck.Warnings, collationWarnings(statementSQL, backend)...)
+	}
+
+	// Dry runs execute inside a transaction that is always rolled back
+	if req.DryRun {
+		return runOnPool(ctx, backend, priority, func(ctx context.Context) gin.H {
+			return withIsolationLevel(dryRunSQL(ctx, db, req, statementSQL, scopeRewrites), req.IsolationLevel)
 		})
-		return
 	}
 
+	// Show the rows an UPDATE or DELETE touches alongside the affected count
+	if req.Preview {
+		if previewSQL, ok := sqlvalidator.DerivePreviewSelect(statementSQL, req.Dialect); ok {
+			return runOnPool(ctx, backend, priority, func(ctx context.Context) gin.H {
+				return withIsolationLevel(executeWithPreview(ctx, owner, db, req, statementSQL, scopeRewrites, previewSQL), req.IsolationLevel)
+			})
+		}
+	}
+
+	// SELECTs estimated to return many rows run in the background and the
+	// client polls their job for the response
+	execute := func(ctx context.Context) gin.H {
+		if req.export != nil && routine == nil && isReadQuery(statementSQL, req.Dialect) {
+			return exportScoped(ctx, owner, db, req, statementSQL, scopeRewrites)
+		}
+		return executeScoped(ctx, owner, db, req, statementSQL, routine, scopeRewrites, safetyCheck.Warnings)
+	}
+	// A download is answered on its own request
+	if req.export == nil {
+		if status, response := queueLargeQuery(owner, req, statementSQL, execute); response != nil {
+			return status, response
+		}
+	}
+	return runOnPool(ctx, backend, priority, execute)
+}
+
+// executeScoped executes a validated statement that has been scoped to the
+// session's namespace and builds its response
+func executeScoped(ctx context.Context, owner string, db *sql.DB, req SQLValidationRequest, statementSQL string, routine *sqlvalidator.RoutineStatement, scopeRewrites []sqlvalidator.Rewrite, warnings []string) gin.H {
+	// Apply server-side rewrites such as row limits; the response reports
+	// them so users can tell why the executed SQL differs from theirs
+	executedSQL, rewrites := rewriteForExecution(statementSQL, req.Dialect)
+	rewrites = append(scopeRewrites, rewrites...)
+
 	// Execute the SQL query and get results
-	result, err := executeQuery(db, req.SQL, req.Dialect)
+	start := time.Now()
+	finished := watchLongRunning(owner, req.Dialect, executedSQL)
+	results, outParams, retries, err := executeWithRetries(ctx, db, executedSQL, req.Dialect, txOptions(req))
+	finished()
+	recordQueryTiming(owner, db, req.Dialect, executedSQL, time.Since(start), err)
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"valid":  true,
-			"error":  "Query execution error: " + err.Error(),
-			"result": nil,
-		})
-		return
+		response := withRewrites(rewrittenErrorResponse("Query execution error: ", err, req.SQL, executedSQL), executedSQL, rewrites)
+		return withIsolationLevel(withRetries(response, retries), req.IsolationLevel)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	if routine != nil {
+		routines.Record(owner, req.Dialect, routine)
+	}
+
+	// Keep the cached schema in sync with DDL statements so the next query
+	// is checked against the new tables and columns, even when the client
+	// has stopped waiting
+	if isSchemaChange(req.SQL, req.Dialect) {
+		reloadSchema(dbmanager.ConnectionKey(req.Dialect, req.Version))
+	}
+
+	if req.ComputeStats {
+		addColumnStats(results)
+	}
+	if req.RawJSON {
+		jsonColumnsAsText(results)
+	}
+	keepBinaryValues(owner, results)
+	truncateLargeCells(owner, results)
+	// Mistakes such as = NULL do not fail, they just match nothing
+	response := withHints(resultResponse(results, outParams), dberrors.Hints(nil, req.SQL, req.Dialect))
+	response = withRetries(withRewrites(withWarnings(response, warnings), executedSQL, rewrites), retries)
+	return withIsolationLevel(retainResult(owner, req, response), req.IsolationLevel)
+}
+
+// resultResponse builds the response of a successful execution. Statements
+// such as stored procedure calls can return several result sets; the first
+// is always the result.
+func resultResponse(results []*QueryResult, outParams map[string]interface{}) gin.H {
+	response := gin.H{
 		"valid":  true,
-		"result": result,
-	})
+		"result": results[0],
+	}
+	if len(results) > 1 {
+		response["resultSets"] = results
+	}
+	if len(outParams) > 0 {
+		response["outParams"] = outParams
+	}
+	return response
+}
+
+// executeQuery executes the SQL query and returns its first result set
+func executeQuery(db queryer, query string, dialect string) (*QueryResult, error) {
+	results, err := executeResultSets(db, query, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
 }
 
-// executeQuery executes the SQL query and returns results
-func executeQuery(db *sql.DB, query string, dialect string) (*QueryResult, error) {
+// executeResultSets executes the SQL query and returns every result set it
+// produces, such as those of a stored procedure. Sets without columns, like
+// the status MySQL sends after a CALL, are left out unless there is nothing
+// else to return.
+func executeResultSets(db queryer, query string, dialect string) ([]*QueryResult, error) {
 	rows, err := db.Query(query)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var first *QueryResult
+	results := []*QueryResult{}
+	for {
+		result, err := readResultSet(rows, dialect)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = result
+		}
+		if len(result.Columns) > 0 {
+			results = append(results, result)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		results = append(results, first)
+	}
+	return results, nil
+}
+
+// readResultSet reads the current result set of rows, up to the 10 rows a
+// response shows. While results are retained it reads on up to
+// retainedRowLimit rows, which the response leaves out.
+func readResultSet(rows *sql.Rows, dialect string) (*QueryResult, error) {
+	limit := shownRows
+	if retaining() && retainedRowLimit > limit {
+		limit = retainedRowLimit
+	}
+	result, err := readRows(rows, dialect, limit)
+	if err != nil || len(result.Rows) <= shownRows {
+		return result, err
+	}
+	// The shown rows are copies, so trimming their cells for the response
+	// leaves the retained rows whole
+	result.retainedRows = result.Rows
+	result.Rows = make([][]interface{}, shownRows)
+	for i := range result.Rows {
+		result.Rows[i] = append([]interface{}{}, result.retainedRows[i]...)
+	}
+	return result, nil
+}
+
+// readRows reads up to maxRows rows of the current result set, decoding
+// structured values by column type
+func readRows(rows *sql.Rows, dialect string, maxRows int) (*QueryResult, error) {
+	scanner, result, err := newRowScanner(rows, dialect)
+	if err != nil {
+		return nil, err
+	}
+	for len(result.Rows) < maxRows {
+		row, err := scanner.next()
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// rowScanner reads the rows of a result set one at a time, decoding
+// structured values by column type
+type rowScanner struct {
+	rows     *sql.Rows
+	dialect  string
+	decoders []resultvalues.Decoder
+	values   []interface{}
+	pointers []interface{}
+}
+
+// newRowScanner starts reading the current result set and returns it
+// without rows, with its columns
+func newRowScanner(rows *sql.Rows, dialect string) (*rowScanner, *QueryResult, error) {
 	// Get column names
 	columns, err := rows.Columns()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Prepare result container
@@ -187,54 +611,136 @@ func executeQuery(db *sql.DB, query string, dialect string) (*QueryResult, error
 		Rows:    [][]interface{}{},
 	}
 
-	// Prepare value holders
-	count := 0
-	values := make([]interface{}, len(columns))
-	valuePtrs := make([]interface{}, len(columns))
-
-	for i := range columns {
-		valuePtrs[i] = &values[i]
+	// Structured types, such as JSON documents and PostgreSQL arrays and
+	// ranges, are decoded from their text according to the dialect
+	scanner := &rowScanner{
+		rows:     rows,
+		dialect:  dialect,
+		decoders: make([]resultvalues.Decoder, len(columns)),
+		values:   make([]interface{}, len(columns)),
+		pointers: make([]interface{}, len(columns)),
 	}
-
-	// Iterate through rows
-	for rows.Next() {
-		if count >= 10 { // Limit to 10 rows
-			break
+	if types, err := rows.ColumnTypes(); err == nil {
+		for i, columnType := range types {
+			scanner.decoders[i] = resultvalues.For(dialect, columnType.DatabaseTypeName())
+			if resultvalues.IsJSON(columnType.DatabaseTypeName()) {
+				result.JSONColumns = append(result.JSONColumns, i)
+			}
 		}
+	}
+	for i := range columns {
+		scanner.pointers[i] = &scanner.values[i]
+	}
+	return scanner, result, nil
+}
 
-		err = rows.Scan(valuePtrs...)
-		if err != nil {
-			return nil, err
-		}
+// next reads the next row, or returns nil after the last one
+func (s *rowScanner) next() ([]interface{}, error) {
+	if !s.rows.Next() {
+		return nil, s.rows.Err()
+	}
+	if err := s.rows.Scan(s.pointers...); err != nil {
+		return nil, err
+	}
 
-		// Convert values to strings or appropriate type for JSON
-		row := make([]interface{}, len(columns))
-		for i, val := range values {
-			if val == nil {
-				row[i] = nil
-			} else {
-				switch v := val.(type) {
-				case []byte:
+	// Convert values to strings or appropriate type for JSON
+	row := make([]interface{}, len(s.values))
+	for i, val := range s.values {
+		if val == nil {
+			row[i] = nil
+		} else if s.decoders[i] != nil {
+			row[i] = resultvalues.Decode(s.decoders[i], val)
+		} else {
+			switch v := val.(type) {
+			case []byte:
+				if dbmanager.BaseDialect(s.dialect) == "sqlite" {
+					row[i] = resultvalues.NewBinary(v)
+				} else {
 					row[i] = string(v)
-				default:
-					row[i] = v
 				}
+			default:
+				row[i] = v
 			}
 		}
+	}
+	return row, nil
+}
 
-		result.Rows = append(result.Rows, row)
-		count++
+// getDatabaseStatus returns the status of all database connections, with
+// the last connection error and next retry time of those that are down, the
+// server and capabilities of those that are up and the latest cached health
+// check of each. With check=true the connected backends are pinged first,
+// within the request's deadline.
+func getDatabaseStatus(c *gin.Context) {
+	progress := make(map[string]dbmanager.InitProgress)
+	for _, entry := range dbmanager.GetInitProgress() {
+		progress[entry.Dialect] = entry
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if c.Query("check") == "true" {
+		checkHealthNow(c.Request.Context())
+	}
+	health := dbmanager.GetHealthChecks()
+	servers := dbmanager.GetServerInfos()
+	statuses := make(map[string]gin.H)
+	for dialect, connected := range dbmanager.GetConnectionStatuses() {
+		status := gin.H{"connected": connected}
+		if check, ok := health[dialect]; ok {
+			status["health"] = check
+		}
+		if server, ok := servers[dialect]; ok && connected {
+			addServerInfo(status, server)
+		}
+		if entry, ok := progress[dialect]; ok {
+			status["state"] = entry.State
+			status["message"] = entry.Message
+			if !connected {
+				status["last_error"] = entry.LastError
+				status["next_retry_at"] = entry.NextRetryAt
+			}
+		}
+		statuses[dialect] = status
+	}
+	// The mock dialect needs no database and is always available
+	statuses[mockdb.Dialect] = gin.H{"connected": true}
+	c.JSON(http.StatusOK, statuses)
+}
+
+// checkHealthNow pings every connected backend at once, caching the
+// outcomes as health checks
+func checkHealthNow(ctx context.Context) {
+	var wg sync.WaitGroup
+	for key, connected := range dbmanager.GetConnectionStatuses() {
+		if !connected {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dbmanager.CheckHealth(ctx, key)
+		}()
 	}
+	wg.Wait()
+}
 
-	return result, nil
+// addServerInfo adds the version, capabilities, character set and uptime
+// of a connected backend's server to its status
+func addServerInfo(status gin.H, server dbmanager.ServerInfo) {
+	status["server"] = server.Server
+	status["version"] = server.Version
+	status["capabilities"] = server.Capabilities
+	if server.CharacterSet != "" {
+		status["character_set"] = server.CharacterSet
+	}
+	if server.StartedAt != nil {
+		status["started_at"] = server.StartedAt
+		status["uptime_seconds"] = int64(time.Since(*server.StartedAt).Seconds())
+	}
 }
 
-// getDatabaseStatus returns the status of all database connections
-func getDatabaseStatus(c *gin.Context) {
-	statuses := dbmanager.GetConnectionStatuses()
-	c.JSON(http.StatusOK, statuses)
+// getInitProgress returns how far each database has come while starting up
+func getInitProgress(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"progress": dbmanager.GetInitProgress(),
+	})
 }
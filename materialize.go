@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/routines"
+	"example/user/playground/sqlsplit"
+	"example/user/playground/sqlvalidator"
+)
+
+// Queries whose rows can be saved as a table
+var materializableRegex = regexp.MustCompile(`^\s*(select|with|values)\b`)
+
+// Names accepted for materialized tables, before the session prefix
+var materializeNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,39}$`)
+
+// MaterializeRequest saves the rows of a query as a table of the session
+type MaterializeRequest struct {
+	SQL     string `json:"sql" binding:"required"`
+	Dialect string `json:"dialect" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+	// Token from a requiresConfirmation response that confirms this query
+	ConfirmationToken string `json:"confirmationToken"`
+}
+
+// materializeResult creates a session-scoped table from the rows of a
+// SELECT. The table is named into the session's namespace, can be queried
+// by its short name afterwards and is dropped with the session's routines
+// once the session goes idle.
+func materializeResult(c *gin.Context) {
+	var req MaterializeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if !materializeNameRegex.MatchString(req.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Table names must start with a letter or underscore and contain at most 40 letters, digits and underscores",
+		})
+		return
+	}
+	if !materializableRegex.MatchString(strings.ToLower(sqlvalidator.MaskSQL(req.SQL, req.Dialect))) || !isReadQuery(req.SQL, req.Dialect) || len(sqlsplit.Split(req.SQL, req.Dialect)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Only a single query that returns rows can be saved as a table",
+		})
+		return
+	}
+
+	owner := sessionOwner(c)
+	routines.Touch(owner)
+	query, response, status := prepareReadQuery(req.SQL, req.Dialect, owner, req.ConfirmationToken)
+	if response != nil {
+		c.JSON(status, response)
+		return
+	}
+
+	statement, table := routines.MaterializeStatement(query, req.Dialect, owner, req.Name)
+	if routines.Owns(owner, req.Dialect, sqlvalidator.RoutineTable, table) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "This session already has a table called " + req.Name,
+		})
+		return
+	}
+	if len(routines.List(owner)) >= routines.MaxPerOwner {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("A session can keep at most %d routines and tables; drop one first", routines.MaxPerOwner),
+		})
+		return
+	}
+
+	db, err := dbmanager.GetDatabaseConnection(req.Dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection error: " + err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := queryContext(c.Request.Context(), req.Dialect)
+	defer cancel()
+	if _, err := executeLimited(ctx, db, statement, req.Dialect, nil); err != nil {
+		c.JSON(http.StatusOK, rewrittenErrorResponse("Materialization error: ", err, req.SQL, statement))
+		return
+	}
+	routines.Record(owner, req.Dialect, &sqlvalidator.RoutineStatement{
+		Create:     true,
+		Kind:       sqlvalidator.RoutineTable,
+		Name:       table,
+		References: sqlvalidator.ReferencedObjects(query, req.Dialect),
+	})
+
+	var rows int64
+	quoted := sqlvalidator.QuoteIdentifier(table, req.Dialect)
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoted).Scan(&rows); err != nil {
+		fmt.Printf("Failed to count rows of %s: %v\n", table, err)
+	}
+	reloadSchema(req.Dialect)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"valid":     true,
+		"name":      req.Name,
+		"table":     table,
+		"rows":      rows,
+		"statement": statement,
+	})
+}
+
+// dropMaterializedResult drops a table the session materialized before it
+// would expire
+func dropMaterializedResult(c *gin.Context) {
+	dialect := c.Param("dialect")
+	owner := sessionOwner(c)
+	table := routines.Prefix(owner) + c.Param("name")
+	if !routines.Owns(owner, dialect, sqlvalidator.RoutineTable, table) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "No materialized table called " + c.Param("name") + " in this session",
+		})
+		return
+	}
+	// Views built on the table would break; they have to go first
+	if dependents := routines.Dependents(routines.List(owner), dialect, table); len(dependents) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Drop the views and routines using " + c.Param("name") + " first",
+			"dependents": routineNames(dependents),
+		})
+		return
+	}
+
+	db, err := dbmanager.GetDatabaseConnection(dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection error: " + err.Error(),
+		})
+		return
+	}
+	routine := routines.Routine{Dialect: dialect, Kind: sqlvalidator.RoutineTable, Name: table}
+	if _, err := db.ExecContext(c.Request.Context(), routines.DropStatement(routine)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to drop " + table + ": " + err.Error(),
+		})
+		return
+	}
+	routines.Record(owner, dialect, &sqlvalidator.RoutineStatement{Kind: sqlvalidator.RoutineTable, Name: table})
+	reloadSchema(dialect)
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": c.Param("name"),
+	})
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/secrets"
+	"example/user/playground/store"
+	"example/user/playground/workspace"
+)
+
+// Path of the SQLite metadata store used when METADATA_STORE is unset
+const metadataStorePath = "./metadata.sqlite"
+
+// How often expired login sessions are removed from the metadata store
+const sessionCleanupInterval = time.Hour
+
+// How long a metadata write may take before it is given up
+const metadataTimeout = 5 * time.Second
+
+// Longest snippet name
+const maxSnippetNameLength = 100
+
+// Store of query history, snippets, workspaces, login sessions and audit
+// records
+var metadata store.Store
+
+type SnippetRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Dialect string `json:"dialect" binding:"required"`
+	SQL     string `json:"sql" binding:"required"`
+}
+
+// configureMetadataStore opens the metadata store, which also keeps the
+// workspaces. METADATA_STORE selects sqlite (the default, in
+// METADATA_SQLITE_PATH or metadata.sqlite) or postgres (in METADATA_DSN),
+// which lets several instances share state.
+func configureMetadataStore() {
+	backend := envOr("METADATA_STORE", store.BackendSQLite)
+	dsn := envOr("METADATA_SQLITE_PATH", metadataStorePath)
+	if backend == store.BackendPostgres || backend == "postgresql" {
+		dsn = os.Getenv("METADATA_DSN")
+	}
+
+	s, err := store.Open(backend, dsn)
+	if err != nil {
+		log.Fatalf("Error opening %s metadata store: %v\n", backend, secrets.MaskError(err))
+	}
+	metadata = s
+	workspace.Init(s)
+	fmt.Printf("Using %s metadata store\n", s.Backend())
+
+	go func() {
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+			if _, err := metadata.DeleteExpiredSessions(ctx, time.Now()); err != nil {
+				fmt.Printf("Failed to remove expired sessions: %v\n", err)
+			}
+			cancel()
+			time.Sleep(sessionCleanupInterval)
+		}
+	}()
+}
+
+// recordHistory adds an executed query to its owner's history in the
+// background so the response is not held up
+func recordHistory(owner string, dialect string, query string, duration time.Duration, err error) {
+	if metadata == nil {
+		return
+	}
+	entry := &store.HistoryEntry{
+		Owner:      owner,
+		Dialect:    dialect,
+		SQL:        query,
+		DurationMs: float64(duration.Microseconds()) / 1000,
+		ExecutedAt: time.Now(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+		defer cancel()
+		if err := metadata.AddHistory(ctx, entry); err != nil {
+			fmt.Printf("Failed to record query history: %v\n", err)
+		}
+	}()
+}
+
+// recordAudit keeps a trail of an action by the current user, or by the
+// actor given when nobody is logged in yet
+func recordAudit(c *gin.Context, actor string, action string, target string, detail string) {
+	if metadata == nil {
+		return
+	}
+	if user := currentUser(c); user != nil {
+		actor = user.Username
+	}
+	record := &store.AuditRecord{
+		Actor:      actor,
+		Action:     action,
+		Target:     target,
+		Detail:     secrets.Mask(detail),
+		RemoteAddr: c.ClientIP(),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+	if err := metadata.AddAudit(ctx, record); err != nil {
+		fmt.Printf("Failed to record audit entry %s by %s: %v\n", action, actor, err)
+	}
+}
+
+// getHistory returns the session's most recent queries
+func getHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	history, err := metadata.ListHistory(c.Request.Context(), sessionOwner(c), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load history: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"history": history,
+	})
+}
+
+// clearHistory removes the session's query history
+func clearHistory(c *gin.Context) {
+	if err := metadata.ClearHistory(c.Request.Context(), sessionOwner(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to clear history: " + err.Error(),
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listSnippets returns the session's saved snippets
+func listSnippets(c *gin.Context) {
+	snippets, err := metadata.ListSnippets(c.Request.Context(), sessionOwner(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load snippets: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"snippets": snippets,
+	})
+}
+
+// saveSnippet creates a snippet, or updates the one named by the id
+// parameter
+func saveSnippet(c *gin.Context) {
+	var req SnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxSnippetNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("snippet names must be 1 to %d characters", maxSnippetNameLength),
+		})
+		return
+	}
+
+	snippet := &store.Snippet{
+		ID:      c.Param("id"),
+		Owner:   sessionOwner(c),
+		Name:    name,
+		Dialect: req.Dialect,
+		SQL:     req.SQL,
+	}
+	err := metadata.SaveSnippet(c.Request.Context(), snippet)
+	switch {
+	case err == store.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case err == store.ErrLimitReached:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save snippet: " + err.Error(),
+		})
+	case c.Param("id") == "":
+		c.JSON(http.StatusCreated, snippet)
+	default:
+		c.JSON(http.StatusOK, snippet)
+	}
+}
+
+// deleteSnippet removes one of the session's snippets
+func deleteSnippet(c *gin.Context) {
+	if err := metadata.DeleteSnippet(c.Request.Context(), sessionOwner(c), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// getAuditLog returns audit records, filtered by the actor, action and
+// since (RFC 3339) query parameters
+func getAuditLog(c *gin.Context) {
+	filter := store.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "since must be an RFC 3339 time",
+			})
+			return
+		}
+		filter.Since = t
+	}
+
+	records, err := metadata.ListAudit(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load audit log: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"records": records,
+	})
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/mockdb"
+)
+
+// executeMock answers a query with synthesized data instead of a database
+func executeMock(req SQLValidationRequest) gin.H {
+	result, err := mockdb.Execute(req.SQL)
+	if err != nil {
+		return gin.H{
+			"valid":  true,
+			"error":  "Query execution error: " + err.Error(),
+			"result": nil,
+		}
+	}
+
+	return gin.H{
+		"valid":        true,
+		"mock":         true,
+		"result":       &QueryResult{Columns: result.Columns, Rows: result.Rows},
+		"rowsAffected": result.RowsAffected,
+	}
+}
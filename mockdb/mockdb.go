@@ -0,0 +1,246 @@
+package mockdb
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// Dialect is the name of the database-free mock dialect
+const Dialect = "mock"
+
+// Most rows synthesized for a query, matching the playground's result cap
+const maxRows = 10
+
+// Columns used for SELECT *
+var starColumns = []string{"id", "name", "email", "price", "created_at"}
+
+// Result is a synthesized query result
+type Result struct {
+	Columns      []string        `json:"columns"`
+	Rows         [][]interface{} `json:"rows"`
+	RowsAffected int64           `json:"-"`
+}
+
+// Functions that collapse all rows into one when there is no GROUP BY
+var aggregateFunctions = map[string]bool{
+	"count": true, "sum": true, "avg": true, "min": true, "max": true,
+	"group_concat": true, "string_agg": true, "array_agg": true,
+}
+
+// Execute synthesizes a plausible result for sql without a database.
+// SELECT statements get columns named after their select list and random
+// rows; other statements report a random affected-row count. The same
+// query always produces the same result.
+func Execute(sql string) (*Result, error) {
+	tokens := withoutComments(sqlvalidator.Tokenize(sql, Dialect))
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(sql))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	selectAt := topLevelIndex(tokens, 0, "select")
+	if selectAt < 0 {
+		return &Result{Columns: []string{}, Rows: [][]interface{}{}, RowsAffected: int64(rng.Intn(5) + 1)}, nil
+	}
+
+	items, aggregate := selectList(tokens, selectAt)
+	columns := []string{}
+	for _, item := range items {
+		if (len(item) == 1 && item[0].Text == "*") || (len(item) == 3 && item[2].Text == "*") {
+			columns = append(columns, starColumns...)
+			continue
+		}
+		columns = append(columns, columnName(item))
+	}
+
+	rowCount := rng.Intn(maxRows-2) + 3
+	if aggregate && topLevelIndex(tokens, selectAt, "group") < 0 {
+		rowCount = 1
+	}
+	if limitAt := topLevelIndex(tokens, selectAt, "limit"); limitAt >= 0 && limitAt+1 < len(tokens) {
+		if limit, err := strconv.Atoi(tokens[limitAt+1].Text); err == nil && limit < rowCount {
+			rowCount = limit
+		}
+	}
+
+	rows := make([][]interface{}, rowCount)
+	for i := range rows {
+		row := make([]interface{}, len(columns))
+		for j, column := range columns {
+			row[j] = fakeValue(column, i, rng)
+		}
+		rows[i] = row
+	}
+	return &Result{Columns: columns, Rows: rows}, nil
+}
+
+// withoutComments drops comment tokens
+func withoutComments(tokens []sqlvalidator.Token) []sqlvalidator.Token {
+	result := make([]sqlvalidator.Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Kind != sqlvalidator.TokenComment {
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// topLevelIndex returns the index of the first keyword outside parentheses
+// at or after start, or -1
+func topLevelIndex(tokens []sqlvalidator.Token, start int, keyword string) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && tokens[i].Is(keyword) {
+			return i
+		}
+	}
+	return -1
+}
+
+// selectList splits the select list after tokens[selectAt] into its items
+// and reports whether any item calls an aggregate function
+func selectList(tokens []sqlvalidator.Token, selectAt int) ([][]sqlvalidator.Token, bool) {
+	items := [][]sqlvalidator.Token{}
+	current := []sqlvalidator.Token{}
+	aggregate := false
+	depth := 0
+
+	i := selectAt + 1
+	if i < len(tokens) && (tokens[i].Is("distinct") || tokens[i].Is("all")) {
+		i++
+	}
+	for ; i < len(tokens); i++ {
+		token := tokens[i]
+		if depth == 0 && (token.Is("from") || token.Is("where") || token.Is("group") || token.Is("order") ||
+			token.Is("limit") || token.Is("union") || token.Text == ";") {
+			break
+		}
+		switch token.Text {
+		case "(":
+			depth++
+			if i > 0 && aggregateFunctions[strings.ToLower(tokens[i-1].Text)] {
+				aggregate = true
+			}
+		case ")":
+			depth--
+		}
+		if depth == 0 && token.Text == "," {
+			items = append(items, current)
+			current = []sqlvalidator.Token{}
+			continue
+		}
+		current = append(current, token)
+	}
+	if len(current) > 0 {
+		items = append(items, current)
+	}
+	return items, aggregate
+}
+
+// columnName derives the output name of a select list item: its alias, the
+// column of a column reference, the called function or the literal text
+func columnName(item []sqlvalidator.Token) string {
+	last := item[len(item)-1]
+	if len(item) >= 2 && item[len(item)-2].Is("as") {
+		return last.Value()
+	}
+	// expr alias
+	if len(item) >= 2 && (last.Kind == sqlvalidator.TokenWord || last.Kind == sqlvalidator.TokenQuotedIdentifier) &&
+		!operatorKeywords[strings.ToLower(last.Text)] {
+		prev := item[len(item)-2]
+		switch {
+		case prev.Text == ")", prev.Kind == sqlvalidator.TokenNumber, prev.Kind == sqlvalidator.TokenString,
+			prev.Kind == sqlvalidator.TokenQuotedIdentifier,
+			prev.Kind == sqlvalidator.TokenWord && !operatorKeywords[strings.ToLower(prev.Text)]:
+			return last.Value()
+		}
+	}
+	// table.column
+	if len(item) == 3 && item[1].Text == "." {
+		return item[2].Value()
+	}
+	if len(item) == 1 {
+		return item[0].Value()
+	}
+	// function(...)
+	if len(item) >= 2 && item[1].Text == "(" {
+		return strings.ToLower(item[0].Text)
+	}
+
+	parts := make([]string, len(item))
+	for i, token := range item {
+		parts[i] = token.Text
+	}
+	return strings.Join(parts, " ")
+}
+
+// Keywords that end or join expressions and so never start an alias
+var operatorKeywords = map[string]bool{
+	"is": true, "not": true, "and": true, "or": true, "case": true, "when": true, "then": true,
+	"else": true, "end": true, "null": true, "true": true, "false": true, "like": true,
+	"in": true, "between": true, "distinct": true, "interval": true,
+}
+
+// Sample values for common column names
+var (
+	firstNames = []string{"Ada", "Grace", "Linus", "Margaret", "Alan", "Barbara", "Ken", "Edsger", "Frances", "Dennis"}
+	lastNames  = []string{"Lovelace", "Hopper", "Torvalds", "Hamilton", "Turing", "Liskov", "Thompson", "Dijkstra", "Allen", "Ritchie"}
+	countries  = []string{"USA", "Canada", "UK", "Germany", "France", "Japan", "Brazil", "India"}
+	cities     = []string{"New York", "Toronto", "London", "Berlin", "Paris", "Tokyo", "Sao Paulo", "Mumbai"}
+	categories = []string{"Electronics", "Books", "Clothing", "Home", "Toys", "Sports"}
+)
+
+// fakeValue returns a plausible value for a column based on its name
+func fakeValue(column string, row int, rng *rand.Rand) interface{} {
+	name := strings.ToLower(column)
+	pick := func(values []string) string {
+		return values[rng.Intn(len(values))]
+	}
+
+	switch {
+	case name == "id" || strings.HasSuffix(name, "_id"):
+		return row + 1
+	case strings.Contains(name, "country"):
+		return pick(countries)
+	case strings.Contains(name, "count") || strings.Contains(name, "quantity") || name == "stock":
+		return rng.Intn(100)
+	case strings.Contains(name, "email"):
+		return fmt.Sprintf("%s@example.com", strings.ToLower(pick(firstNames)))
+	case name == "first_name":
+		return pick(firstNames)
+	case name == "last_name":
+		return pick(lastNames)
+	case strings.Contains(name, "name"):
+		return pick(firstNames) + " " + pick(lastNames)
+	case strings.Contains(name, "city"):
+		return pick(cities)
+	case strings.Contains(name, "category"):
+		return pick(categories)
+	case strings.Contains(name, "price") || strings.Contains(name, "amount") || strings.Contains(name, "total") ||
+		name == "sum" || name == "avg" || strings.Contains(name, "value"):
+		return float64(rng.Intn(100000)) / 100
+	case strings.HasSuffix(name, "_at") || strings.Contains(name, "date") || strings.Contains(name, "time"):
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		return base.Add(time.Duration(rng.Intn(365*24)) * time.Hour).Format("2006-01-02 15:04:05")
+	case strings.HasPrefix(name, "is_") || strings.HasPrefix(name, "has_"):
+		return rng.Intn(2) == 1
+	case name == "min" || name == "max":
+		return rng.Intn(1000)
+	}
+	return fmt.Sprintf("%s %d", column, row+1)
+}
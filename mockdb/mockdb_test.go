@@ -0,0 +1,58 @@
+package mockdb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecuteInfersColumns(t *testing.T) {
+	tests := []struct {
+		sql     string
+		columns []string
+	}{
+		{"SELECT id, u.email, COUNT(*) AS n, price * 2 doubled FROM users u", []string{"id", "email", "n", "doubled"}},
+		{"SELECT UPPER(name), 42 FROM t", []string{"upper", "42"}},
+		{"SELECT * FROM anything", starColumns},
+		{"WITH x AS (SELECT a FROM b) SELECT total FROM x", []string{"total"}},
+		{"SELECT CASE WHEN a THEN 1 ELSE 0 END FROM t", []string{"CASE WHEN a THEN 1 ELSE 0 END"}},
+	}
+	for _, test := range tests {
+		result, err := Execute(test.sql)
+		if err != nil {
+			t.Fatalf("%q: %v", test.sql, err)
+		}
+		if !reflect.DeepEqual(result.Columns, test.columns) {
+			t.Errorf("%q: expected columns %v, got %v", test.sql, test.columns, result.Columns)
+		}
+	}
+}
+
+func TestExecuteRowCounts(t *testing.T) {
+	result, _ := Execute("SELECT name FROM users LIMIT 2")
+	if len(result.Rows) != 2 {
+		t.Errorf("expected LIMIT to cap rows, got %d", len(result.Rows))
+	}
+
+	result, _ = Execute("SELECT COUNT(*) FROM users")
+	if len(result.Rows) != 1 {
+		t.Errorf("expected one row for an aggregate, got %d", len(result.Rows))
+	}
+
+	result, _ = Execute("SELECT country, COUNT(*) FROM users GROUP BY country")
+	if len(result.Rows) < 3 || len(result.Rows) > maxRows {
+		t.Errorf("expected several grouped rows, got %d", len(result.Rows))
+	}
+
+	result, _ = Execute("UPDATE users SET name = 'x'")
+	if len(result.Columns) != 0 || result.RowsAffected < 1 {
+		t.Errorf("expected an affected-row count, got %+v", result)
+	}
+}
+
+func TestExecuteIsDeterministic(t *testing.T) {
+	first, _ := Execute("SELECT id, email, created_at FROM users")
+	second, _ := Execute("SELECT id, email, created_at FROM users")
+	if !reflect.DeepEqual(first, second) {
+		t.Error("expected the same query to produce the same rows")
+	}
+}
@@ -0,0 +1,80 @@
+package negotiate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Choose picks the offer a client prefers according to an Accept or
+// Accept-Encoding header. Each offer takes the quality of the most specific
+// range matching it; offers with quality 0 are refused. Ties go to the
+// offer listed first, and an empty header accepts the first offer. It
+// returns "" when the header refuses every offer.
+func Choose(header string, offers ...string) string {
+	if strings.TrimSpace(header) == "" {
+		if len(offers) == 0 {
+			return ""
+		}
+		return offers[0]
+	}
+
+	ranges := parse(header)
+	best, bestQuality := "", 0.0
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, r := range ranges {
+			if s := matches(r.value, offer); s > specificity {
+				quality, specificity = r.quality, s
+			}
+		}
+		if quality > bestQuality {
+			best, bestQuality = offer, quality
+		}
+	}
+	return best
+}
+
+// acceptRange is one comma-separated entry of a header
+type acceptRange struct {
+	value   string
+	quality float64
+}
+
+// parse splits a header into its ranges and their q values
+func parse(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(params[0]))
+		if value == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			name, q, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(name), "q") {
+				if parsed, err := strconv.ParseFloat(strings.TrimSpace(q), 64); err == nil {
+					quality = parsed
+				}
+			}
+		}
+		ranges = append(ranges, acceptRange{value: value, quality: quality})
+	}
+	return ranges
+}
+
+// matches returns how specifically a range matches an offer: 2 for the
+// offer itself, 1 for a type wildcard such as text/*, 0 for * or */*, and
+// -1 when it does not match
+func matches(r string, offer string) int {
+	offer = strings.ToLower(offer)
+	switch {
+	case r == offer:
+		return 2
+	case r == "*" || r == "*/*":
+		return 0
+	case strings.HasSuffix(r, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(r, "*")):
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,32 @@
+package negotiate
+
+import "testing"
+
+func TestChoose(t *testing.T) {
+	formats := []string{"application/json", "text/csv", "application/x-ndjson"}
+	encodings := []string{"zstd", "gzip"}
+	tests := []struct {
+		header string
+		offers []string
+		want   string
+	}{
+		{"", formats, "application/json"},
+		{"*/*", formats, "application/json"},
+		{"text/csv", formats, "text/csv"},
+		{"application/x-ndjson, application/json;q=0.5", formats, "application/x-ndjson"},
+		{"text/*", formats, "text/csv"},
+		{"text/csv;q=0, */*", formats, "application/json"},
+		{"text/html", formats, ""},
+		{"gzip, deflate, br", encodings, "gzip"},
+		{"gzip;q=0.8, zstd", encodings, "zstd"},
+		{"zstd;q=0.5, gzip;q=0.5", encodings, "zstd"},
+		{"*", encodings, "zstd"},
+		{"*;q=0, gzip", encodings, "gzip"},
+		{"identity", encodings, ""},
+	}
+	for _, test := range tests {
+		if got := Choose(test.header, test.offers...); got != test.want {
+			t.Errorf("Choose(%q, %v) = %q, want %q", test.header, test.offers, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/nl2sql"
+)
+
+// Defaults of the natural language to SQL provider
+const (
+	defaultNL2SQLURL     = "https://api.openai.com/v1"
+	defaultNL2SQLModel   = "gpt-4o-mini"
+	defaultNL2SQLTimeout = 30 * time.Second
+)
+
+// Questions allowed per client and minute, on top of the query rate limit
+var nl2sqlPerMinute = 10
+
+type NLToSQLRequest struct {
+	Question string `json:"question" binding:"required,max=2000"`
+	Dialect  string `json:"dialect" binding:"required"`
+	// Number of SQL candidates to ask for, up to 3
+	Candidates int `json:"candidates"`
+}
+
+// configureNL2SQL sets up the LLM provider questions are sent to. Setting
+// NL2SQL_API_KEY uses the OpenAI API; NL2SQL_API_URL points to any other
+// OpenAI-compatible endpoint, such as a local model server. Without either,
+// the endpoint answers 503 even when its feature flag is on.
+func configureNL2SQL() {
+	if n, err := strconv.Atoi(os.Getenv("NL2SQL_REQUESTS_PER_MINUTE")); err == nil && n >= 0 {
+		nl2sqlPerMinute = n
+	}
+
+	apiKey := os.Getenv("NL2SQL_API_KEY")
+	if apiKey == "" && os.Getenv("NL2SQL_API_URL") == "" {
+		return
+	}
+	timeout := defaultNL2SQLTimeout
+	if value, err := time.ParseDuration(os.Getenv("NL2SQL_TIMEOUT")); err == nil && value > 0 {
+		timeout = value
+	}
+	nl2sql.Configure(nl2sql.Provider{
+		BaseURL: envOr("NL2SQL_API_URL", defaultNL2SQLURL),
+		APIKey:  apiKey,
+		Model:   envOr("NL2SQL_MODEL", defaultNL2SQLModel),
+		Timeout: timeout,
+	})
+}
+
+// limitNL2SQLRate rejects questions beyond NL2SQL_REQUESTS_PER_MINUTE per
+// client, since each one costs a provider call
+func limitNL2SQLRate(c *gin.Context) {
+	limitRate(c, "rate:nl2sql", nl2sqlPerMinute, "questions")
+}
+
+// generateSQL asks the LLM provider for SQL answering a question about the
+// dialect's schema and validates each candidate without running it
+func generateSQL(c *gin.Context) {
+	var req NLToSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	metadata, err := dbmanager.Metadata(c.Request.Context(), req.Dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Failed to load schema: " + err.Error(),
+		})
+		return
+	}
+	schema := metadata.Tables
+
+	candidates, err := nl2sql.Generate(c.Request.Context(), nl2sql.Request{
+		Question:   req.Question,
+		Dialect:    req.Dialect,
+		Schema:     schema,
+		Candidates: req.Candidates,
+	})
+	if errors.Is(err, nl2sql.ErrNotConfigured) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "No LLM provider is configured; set NL2SQL_API_KEY or NL2SQL_API_URL",
+		})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Failed to generate SQL for %s: %v\n", req.Dialect, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error": "Failed to generate SQL: " + err.Error(),
+		})
+		return
+	}
+
+	// Generated SQL is only a suggestion until the validator has seen it
+	results := make([]gin.H, 0, len(candidates))
+	for _, sql := range candidates {
+		results = append(results, gin.H{
+			"sql":        sql,
+			"validation": validateOffline(SQLValidationRequest{SQL: sql, Dialect: req.Dialect}),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question":   req.Question,
+		"dialect":    req.Dialect,
+		"candidates": results,
+	})
+}
@@ -0,0 +1,269 @@
+package nl2sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Most candidates a single request may ask for
+const MaxCandidates = 3
+
+// Most tables described to the model, to keep prompts bounded
+const maxPromptTables = 100
+
+// Longest response body read from the provider
+const maxResponseBytes = 1 << 20
+
+// ErrNotConfigured is returned when no provider has been configured
+var ErrNotConfigured = errors.New("no natural language to SQL provider is configured")
+
+// Provider is an OpenAI-compatible chat completions endpoint
+type Provider struct {
+	// Base URL of the API, such as "https://api.openai.com/v1"
+	BaseURL string
+	// Sent as a bearer token when set; local servers often need none
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+}
+
+// Request is a question about the tables of a database
+type Request struct {
+	Question string
+	Dialect  string
+	// Column names by table
+	Schema map[string][]string
+	// Number of SQL candidates to ask for, at least one
+	Candidates int
+}
+
+var (
+	// Provider set by Configure, nil when unset
+	provider *Provider
+
+	// Guards provider
+	providerMu sync.RWMutex
+
+	// Fenced code blocks in a model's reply
+	codeFenceRegex = regexp.MustCompile("(?s)```[A-Za-z]*\\s*\\n?(.*?)```")
+)
+
+// Configure sets the provider questions are sent to
+func Configure(p Provider) {
+	providerMu.Lock()
+	provider = &p
+	providerMu.Unlock()
+}
+
+// Configured reports whether a provider has been configured
+func Configured() bool {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	return provider != nil
+}
+
+// Generate asks the configured provider for SQL answering a question and
+// returns the distinct candidates it proposes
+func Generate(ctx context.Context, req Request) ([]string, error) {
+	providerMu.RLock()
+	p := provider
+	providerMu.RUnlock()
+	if p == nil {
+		return nil, ErrNotConfigured
+	}
+	return p.Generate(ctx, req)
+}
+
+// chatMessage is a message of a chat completion
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is the body of a chat completion request
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	N           int           `json:"n,omitempty"`
+}
+
+// chatResponse is the part of a chat completion response that is used
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Generate asks the provider for SQL answering a question and returns the
+// distinct candidates it proposes
+func (p *Provider) Generate(ctx context.Context, req Request) ([]string, error) {
+	if req.Candidates < 1 {
+		req.Candidates = 1
+	}
+	if req.Candidates > MaxCandidates {
+		req.Candidates = MaxCandidates
+	}
+
+	// Candidates beyond the first only differ with some randomness
+	temperature := 0.0
+	if req.Candidates > 1 {
+		temperature = 0.7
+	}
+	replies, err := p.complete(ctx, chatRequest{
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt(req.Dialect, req.Schema)},
+			{Role: "user", Content: req.Question},
+		},
+		Temperature: temperature,
+		N:           req.Candidates,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []string{}
+	seen := map[string]bool{}
+	for _, reply := range replies {
+		sql := ExtractSQL(reply)
+		if sql == "" || seen[sql] {
+			continue
+		}
+		seen[sql] = true
+		candidates = append(candidates, sql)
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("provider returned no SQL")
+	}
+	return candidates, nil
+}
+
+// Explain asks the configured provider to explain a query step by step
+func Explain(ctx context.Context, sql string, dialect string) (string, error) {
+	providerMu.RLock()
+	p := provider
+	providerMu.RUnlock()
+	if p == nil {
+		return "", ErrNotConfigured
+	}
+	return p.Explain(ctx, sql, dialect)
+}
+
+// Explain asks the provider to explain a query step by step in plain
+// English, for someone learning SQL
+func (p *Provider) Explain(ctx context.Context, sql string, dialect string) (string, error) {
+	replies, err := p.complete(ctx, chatRequest{
+		Messages: []chatMessage{
+			{Role: "system", Content: fmt.Sprintf("You explain %s SQL to people learning it. "+
+				"Describe step by step, in plain English and in the order the database works through it, "+
+				"what the query does. Do not suggest changes.", dialect)},
+			{Role: "user", Content: sql},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	explanation := strings.TrimSpace(replies[0])
+	if explanation == "" {
+		return "", errors.New("provider returned no explanation")
+	}
+	return explanation, nil
+}
+
+// complete sends a chat completion request with the provider's model and
+// returns the content of each choice
+func (p *Provider) complete(ctx context.Context, chat chatRequest) ([]string, error) {
+	if p.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Timeout)
+		defer cancel()
+	}
+
+	chat.Model = p.Model
+	body, err := json.Marshal(chat)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.BaseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read provider response: %w", err)
+	}
+	var completion chatResponse
+	if err := json.Unmarshal(data, &completion); err != nil {
+		return nil, fmt.Errorf("provider answered with status %d and an unreadable body", resp.StatusCode)
+	}
+	if completion.Error != nil {
+		return nil, fmt.Errorf("provider error: %s", completion.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provider answered with status %d", resp.StatusCode)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, errors.New("provider returned no answer")
+	}
+
+	replies := make([]string, 0, len(completion.Choices))
+	for _, choice := range completion.Choices {
+		replies = append(replies, choice.Message.Content)
+	}
+	return replies, nil
+}
+
+// systemPrompt tells the model the dialect and the tables it may use
+func systemPrompt(dialect string, schema map[string][]string) string {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "You translate questions into a single %s SQL query. ", dialect)
+	prompt.WriteString("Reply with the SQL only, without explanation. ")
+	prompt.WriteString("Use only these tables and columns:\n")
+
+	tables := make([]string, 0, len(schema))
+	for table := range schema {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	if len(tables) > maxPromptTables {
+		tables = tables[:maxPromptTables]
+	}
+	for _, table := range tables {
+		fmt.Fprintf(&prompt, "%s(%s)\n", table, strings.Join(schema[table], ", "))
+	}
+	return prompt.String()
+}
+
+// ExtractSQL returns the SQL of a model's reply, taken from its first
+// fenced code block when it has one
+func ExtractSQL(reply string) string {
+	if match := codeFenceRegex.FindStringSubmatch(reply); match != nil {
+		reply = match[1]
+	}
+	return strings.TrimSpace(reply)
+}
@@ -0,0 +1,117 @@
+package nl2sql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExtractSQL(t *testing.T) {
+	tests := map[string]string{
+		"SELECT 1":                                    "SELECT 1",
+		"```sql\nSELECT * FROM users\n```":            "SELECT * FROM users",
+		"Here you go:\n```\nSELECT 2;\n```\nEnjoy":    "SELECT 2;",
+		"  SELECT name FROM users WHERE id = 1  \n\n": "SELECT name FROM users WHERE id = 1",
+	}
+	for reply, want := range tests {
+		if got := ExtractSQL(reply); got != want {
+			t.Errorf("ExtractSQL(%q) = %q, want %q", reply, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	var received chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("expected the API key as a bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(w, `{"choices": [
+			{"message": {"role": "assistant", "content": "`+"```sql\\nSELECT count(*) FROM users\\n```"+`"}},
+			{"message": {"role": "assistant", "content": "SELECT count(*) FROM users"}},
+			{"message": {"role": "assistant", "content": "SELECT count(id) FROM users"}}
+		]}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{BaseURL: server.URL + "/v1/", APIKey: "secret", Model: "test-model"}
+	candidates, err := p.Generate(context.Background(), Request{
+		Question:   "How many users are there?",
+		Dialect:    "sqlite",
+		Schema:     map[string][]string{"users": {"id", "name"}, "orders": {"id", "user_id"}},
+		Candidates: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 2 || candidates[0] != "SELECT count(*) FROM users" {
+		t.Errorf("expected two distinct candidates, got %q", candidates)
+	}
+
+	if received.Model != "test-model" || received.N != MaxCandidates {
+		t.Errorf("unexpected request %+v", received)
+	}
+	system := received.Messages[0].Content
+	if !strings.Contains(system, "sqlite") || !strings.Contains(system, "orders(id, user_id)\nusers(id, name)") {
+		t.Errorf("expected the dialect and sorted schema in the prompt, got %q", system)
+	}
+	if received.Messages[1].Content != "How many users are there?" {
+		t.Errorf("expected the question as the user message, got %q", received.Messages[1].Content)
+	}
+}
+
+func TestGenerateReportsProviderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error": {"message": "Incorrect API key provided"}}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{BaseURL: server.URL}
+	if _, err := p.Generate(context.Background(), Request{Question: "?", Dialect: "sqlite"}); err == nil || !strings.Contains(err.Error(), "Incorrect API key") {
+		t.Errorf("expected the provider's error message, got %v", err)
+	}
+}
+
+func TestGenerateWithoutProvider(t *testing.T) {
+	providerMu.Lock()
+	provider = nil
+	providerMu.Unlock()
+	if _, err := Generate(context.Background(), Request{Question: "?"}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("expected ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestExplain(t *testing.T) {
+	var received chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "  1. Reads users.\n2. Returns their names.\n"}}]}`)
+	}))
+	defer server.Close()
+
+	p := &Provider{BaseURL: server.URL, Model: "test-model"}
+	explanation, err := p.Explain(context.Background(), "SELECT name FROM users", "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explanation != "1. Reads users.\n2. Returns their names." {
+		t.Errorf("unexpected explanation %q", explanation)
+	}
+	if received.Model != "test-model" || !strings.Contains(received.Messages[0].Content, "mysql") || received.Messages[1].Content != "SELECT name FROM users" {
+		t.Errorf("unexpected request %+v", received)
+	}
+}
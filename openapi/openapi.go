@@ -0,0 +1,254 @@
+package openapi
+
+import (
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version of the OpenAPI specification the documents follow
+const Version = "3.0.3"
+
+// Operation describes one endpoint of the API
+type Operation struct {
+	Method  string
+	Path    string
+	Summary string
+	// Group of related operations, such as "Queries"
+	Tag string
+	// Zero value of the JSON request body type, nil without a body
+	Request interface{}
+	// Query string parameters
+	Query []string
+	// Whether an admin login is required
+	Admin bool
+}
+
+// Spec collects the operations of an API as routes are registered
+type Spec struct {
+	operations []Operation
+	mu         sync.Mutex
+}
+
+// New returns an empty spec
+func New() *Spec {
+	return &Spec{}
+}
+
+// Add records an operation
+func (s *Spec) Add(op Operation) {
+	s.mu.Lock()
+	s.operations = append(s.operations, op)
+	s.mu.Unlock()
+}
+
+// Operations returns the recorded operations in registration order
+func (s *Spec) Operations() []Operation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Operation(nil), s.operations...)
+}
+
+// Matches gin path parameters such as :id
+var pathParamRegex = regexp.MustCompile(`:([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Document builds the OpenAPI document of the recorded operations, served
+// from serverURL. Request bodies are described from their Go types, by
+// their json tags, with binding:"required" fields marked required.
+func (s *Spec) Document(title string, version string, serverURL string) map[string]interface{} {
+	components := map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"error":     map[string]interface{}{"type": "string"},
+				"errorCode": map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"error"},
+		},
+	}
+
+	paths := map[string]interface{}{}
+	for _, op := range s.Operations() {
+		path := pathParamRegex.ReplaceAllString(op.Path, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+		item[strings.ToLower(op.Method)] = operationObject(op, components)
+	}
+
+	return map[string]interface{}{
+		"openapi": Version,
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": version,
+		},
+		"servers": []map[string]interface{}{{"url": serverURL}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": components,
+		},
+	}
+}
+
+// operationObject describes one operation, adding the schemas of its
+// request body to components
+func operationObject(op Operation, components map[string]interface{}) map[string]interface{} {
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+			},
+		},
+	}
+	object := map[string]interface{}{
+		"summary":     op.Summary,
+		"operationId": operationID(op),
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{
+				"description": "Success",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": map[string]interface{}{"type": "object"},
+					},
+				},
+			},
+			"default": errorResponse,
+		},
+	}
+	if op.Tag != "" {
+		object["tags"] = []string{op.Tag}
+	}
+	if op.Admin {
+		object["description"] = "Requires an admin login."
+	}
+
+	parameters := []map[string]interface{}{}
+	for _, match := range pathParamRegex.FindAllStringSubmatch(op.Path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, name := range op.Query {
+		parameters = append(parameters, map[string]interface{}{
+			"name":   name,
+			"in":     "query",
+			"schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		object["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		object["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": Schema(reflect.TypeOf(op.Request), components),
+				},
+			},
+		}
+	}
+	return object
+}
+
+// operationID derives an identifier such as "post_validate_sql" from the
+// method and path of an operation
+func operationID(op Operation) string {
+	id := strings.ToLower(op.Method)
+	for _, part := range strings.Split(op.Path, "/") {
+		part = strings.TrimPrefix(part, ":")
+		if part == "" {
+			continue
+		}
+		id += "_" + strings.NewReplacer("-", "_", ".", "_").Replace(part)
+	}
+	return id
+}
+
+// Schema returns the JSON schema of a Go type. Named structs are added to
+// components once and referenced by name.
+func Schema(t reflect.Type, components map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, ok := components[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			components[t.Name()] = map[string]interface{}{}
+			components[t.Name()] = structSchema(t, components)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": Schema(t.Elem(), components)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": Schema(t.Elem(), components)}
+	case reflect.Struct:
+		return structSchema(t, components)
+	}
+	// interface{} and other types accept any value
+	return map[string]interface{}{}
+}
+
+// structSchema describes the exported fields of a struct by their json
+// names
+func structSchema(t reflect.Type, components map[string]interface{}) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if tagName := strings.Split(tag, ",")[0]; tagName != "" {
+				name = tagName
+			}
+		}
+		properties[name] = Schema(field.Type, components)
+		for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+			if rule == "required" {
+				required = append(required, name)
+			}
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
@@ -0,0 +1,97 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type tab struct {
+	Name string `json:"name"`
+}
+
+type workspaceRequest struct {
+	Name      string    `json:"name" binding:"required"`
+	Tabs      []tab     `json:"tabs"`
+	Active    *int      `json:"active_tab"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Owner     string    `json:"-"`
+	internal  string
+}
+
+func TestSchema(t *testing.T) {
+	components := map[string]interface{}{}
+	ref := Schema(reflect.TypeOf(workspaceRequest{}), components)
+	if ref["$ref"] != "#/components/schemas/workspaceRequest" {
+		t.Fatalf("expected a reference to the named struct, got %v", ref)
+	}
+
+	schema := components["workspaceRequest"].(map[string]interface{})
+	properties := schema["properties"].(map[string]interface{})
+	for _, name := range []string{"name", "tabs", "active_tab", "updated_at"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("expected property %s", name)
+		}
+	}
+	for _, name := range []string{"Owner", "-", "internal"} {
+		if _, ok := properties[name]; ok {
+			t.Errorf("expected %s to be left out", name)
+		}
+	}
+	if required := schema["required"].([]string); len(required) != 1 || required[0] != "name" {
+		t.Errorf("expected only name to be required, got %v", required)
+	}
+	if active := properties["active_tab"].(map[string]interface{}); active["type"] != "integer" {
+		t.Errorf("expected pointers to be described by their element, got %v", active)
+	}
+	if updated := properties["updated_at"].(map[string]interface{}); updated["format"] != "date-time" {
+		t.Errorf("expected times to be date-time strings, got %v", updated)
+	}
+	tabs := properties["tabs"].(map[string]interface{})
+	if items := tabs["items"].(map[string]interface{}); items["$ref"] != "#/components/schemas/tab" {
+		t.Errorf("expected array items to reference their struct, got %v", items)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	spec := New()
+	spec.Add(Operation{Method: "GET", Path: "/workspaces/:id", Summary: "Get a workspace", Tag: "Workspaces"})
+	spec.Add(Operation{Method: "PUT", Path: "/workspaces/:id", Summary: "Update a workspace", Request: workspaceRequest{}, Admin: true})
+	spec.Add(Operation{Method: "GET", Path: "/schema", Summary: "Get the schema", Query: []string{"dialect"}})
+
+	doc := spec.Document("Playground", "v1", "/api/v1")
+	if doc["openapi"] != Version {
+		t.Errorf("expected OpenAPI %s, got %v", Version, doc["openapi"])
+	}
+
+	paths := doc["paths"].(map[string]interface{})
+	item, ok := paths["/workspaces/{id}"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected gin parameters to become OpenAPI templates, got %v", paths)
+	}
+	if len(item) != 2 {
+		t.Errorf("expected both methods under one path, got %v", item)
+	}
+
+	get := item["get"].(map[string]interface{})
+	if get["operationId"] != "get_workspaces_id" {
+		t.Errorf("unexpected operationId %v", get["operationId"])
+	}
+	params := get["parameters"].([]map[string]interface{})
+	if len(params) != 1 || params[0]["name"] != "id" || params[0]["in"] != "path" {
+		t.Errorf("expected the id path parameter, got %v", params)
+	}
+
+	put := item["put"].(map[string]interface{})
+	if _, ok := put["requestBody"]; !ok {
+		t.Error("expected a request body")
+	}
+	if put["description"] == nil {
+		t.Error("expected admin operations to say so")
+	}
+
+	schemaParams := paths["/schema"].(map[string]interface{})["get"].(map[string]interface{})["parameters"].([]map[string]interface{})
+	if len(schemaParams) != 1 || schemaParams[0]["in"] != "query" {
+		t.Errorf("expected the dialect query parameter, got %v", schemaParams)
+	}
+}
@@ -0,0 +1,184 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default limits
+const (
+	DefaultMaxSQLLength = 100000
+	DefaultMaxParams    = 100
+	DefaultMaxBodyBytes = 1 << 20
+)
+
+// Error codes of rejected payloads
+const (
+	CodeTooLarge         = "payload_too_large"
+	CodeTooManyParams    = "too_many_params"
+	CodeInvalidEncoding  = "invalid_encoding"
+	CodeControlCharacter = "control_character"
+)
+
+// Limits bounds what a request may carry
+type Limits struct {
+	// Longest SQL text in characters, for every field whose name ends in "sql"
+	MaxSQLLength int
+	// Most query string parameters plus top-level JSON fields and the
+	// entries of top-level arrays and objects
+	MaxParams int
+	// Largest request body in bytes
+	MaxBodyBytes int64
+}
+
+// Violation describes why a payload was rejected
+type Violation struct {
+	// HTTP status to answer with, 413 or 422
+	Status  int
+	Code    string
+	Field   string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return v.Message
+}
+
+// DefaultLimits returns the limits used when none are configured
+func DefaultLimits() Limits {
+	return Limits{
+		MaxSQLLength: DefaultMaxSQLLength,
+		MaxParams:    DefaultMaxParams,
+		MaxBodyBytes: DefaultMaxBodyBytes,
+	}
+}
+
+// Check validates a request body and its query string parameter count
+// against the limits. The body must be UTF-8 without raw control
+// characters; when it is a JSON object its SQL fields are checked for
+// length and its fields, down to the entries of top-level arrays and
+// objects, for escaped control characters. Bodies that are not JSON
+// objects are otherwise left for the handler to reject.
+func Check(body []byte, queryParams int, limits Limits) *Violation {
+	if limits.MaxBodyBytes > 0 && int64(len(body)) > limits.MaxBodyBytes {
+		return &Violation{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    CodeTooLarge,
+			Message: fmt.Sprintf("request body exceeds %d bytes", limits.MaxBodyBytes),
+		}
+	}
+	if !utf8.Valid(body) {
+		return &Violation{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    CodeInvalidEncoding,
+			Message: "request body is not valid UTF-8",
+		}
+	}
+	if v := checkString("request body", string(body), Limits{}); v != nil {
+		return v
+	}
+
+	var fields map[string]interface{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&fields); err != nil {
+			fields = nil
+		}
+	}
+
+	params := queryParams + len(fields)
+	for _, value := range fields {
+		switch v := value.(type) {
+		case []interface{}:
+			params += len(v)
+		case map[string]interface{}:
+			params += len(v)
+		}
+	}
+	if limits.MaxParams > 0 && params > limits.MaxParams {
+		return &Violation{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    CodeTooManyParams,
+			Message: fmt.Sprintf("request has %d parameters, at most %d are allowed", params, limits.MaxParams),
+		}
+	}
+
+	for name, value := range fields {
+		if v := checkField(name, value, limits); v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+// checkField checks a top-level field and the entries directly inside it
+func checkField(name string, value interface{}, limits Limits) *Violation {
+	if v := checkString(name, name, limits); v != nil {
+		return v
+	}
+	switch value := value.(type) {
+	case string:
+		return checkString(name, value, limits)
+	case []interface{}:
+		for i, entry := range value {
+			if s, ok := entry.(string); ok {
+				if v := checkString(fmt.Sprintf("%s[%d]", name, i), s, limits); v != nil {
+					return v
+				}
+			}
+		}
+	case map[string]interface{}:
+		for key, entry := range value {
+			field := name + "." + key
+			if v := checkString(field, key, limits); v != nil {
+				return v
+			}
+			if s, ok := entry.(string); ok {
+				if v := checkString(field, s, limits); v != nil {
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkString rejects control characters other than tab and line breaks,
+// and SQL longer than the limit in fields named like "sql"
+func checkString(field string, value string, limits Limits) *Violation {
+	isSQL := strings.HasSuffix(strings.ToLower(field), "sql")
+	if isSQL && limits.MaxSQLLength > 0 && utf8.RuneCountInString(value) > limits.MaxSQLLength {
+		return &Violation{
+			Status:  http.StatusRequestEntityTooLarge,
+			Code:    CodeTooLarge,
+			Field:   field,
+			Message: fmt.Sprintf("%s exceeds %d characters", field, limits.MaxSQLLength),
+		}
+	}
+	for i, r := range value {
+		if isControl(r) {
+			return &Violation{
+				Status:  http.StatusUnprocessableEntity,
+				Code:    CodeControlCharacter,
+				Field:   field,
+				Message: fmt.Sprintf("%s contains control character U+%04X at offset %d", field, r, i),
+			}
+		}
+	}
+	return nil
+}
+
+// isControl reports whether a character is a control character that has
+// no place in SQL text
+func isControl(r rune) bool {
+	switch r {
+	case '\t', '\n', '\r':
+		return false
+	}
+	return r < 0x20 || (r >= 0x7f && r < 0xa0)
+}
@@ -0,0 +1,60 @@
+package payload
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCheckAcceptsOrdinaryRequests(t *testing.T) {
+	bodies := []string{
+		`{"sql": "SELECT *\n\tFROM users\r\nWHERE name = 'Zoë'", "dialect": "sqlite"}`,
+		`{"sql": "SELECT 1", "dialects": ["sqlite", "mysql"], "confirmationTokens": {"mysql": "abc"}}`,
+		`{"sql": "SELECT 1", "result": {"rows": [["\u0001 stored data"]]}}`,
+		``,
+		`not json`,
+	}
+	for _, body := range bodies {
+		if v := Check([]byte(body), 0, DefaultLimits()); v != nil {
+			t.Errorf("Check(%q) = %v, want nil", body, v)
+		}
+	}
+}
+
+func TestCheckRejections(t *testing.T) {
+	limits := Limits{MaxSQLLength: 10, MaxParams: 5, MaxBodyBytes: 200}
+	tests := []struct {
+		name   string
+		body   string
+		query  int
+		status int
+		code   string
+	}{
+		{"long body", `{"sql": "` + strings.Repeat("x", 300) + `"}`, 0, http.StatusRequestEntityTooLarge, CodeTooLarge},
+		{"long sql", `{"sql": "SELECT 1 FROM t"}`, 0, http.StatusRequestEntityTooLarge, CodeTooLarge},
+		{"long compare sql", `{"sql": "SELECT 1", "compare_sql": "SELECT 2 FROM t"}`, 0, http.StatusRequestEntityTooLarge, CodeTooLarge},
+		{"invalid utf-8", "{\"sql\": \"SELECT \xff\"}", 0, http.StatusUnprocessableEntity, CodeInvalidEncoding},
+		{"escaped nul", `{"sql": "SELECT\u0000"}`, 0, http.StatusUnprocessableEntity, CodeControlCharacter},
+		{"raw escape", "{\"dialect\": \"sql\x1bite\"}", 0, http.StatusUnprocessableEntity, CodeControlCharacter},
+		{"array entry", `{"dialects": ["sqlite\u0007"]}`, 0, http.StatusUnprocessableEntity, CodeControlCharacter},
+		{"too many entries", `{"sql": "SELECT 1", "dialects": ["a", "b", "c", "d", "e"]}`, 0, http.StatusUnprocessableEntity, CodeTooManyParams},
+		{"too many query params", `{"sql": "SELECT 1"}`, 5, http.StatusUnprocessableEntity, CodeTooManyParams},
+	}
+	for _, test := range tests {
+		v := Check([]byte(test.body), test.query, limits)
+		if v == nil {
+			t.Errorf("%s: expected a violation", test.name)
+			continue
+		}
+		if v.Status != test.status || v.Code != test.code {
+			t.Errorf("%s: got %d %s, want %d %s", test.name, v.Status, v.Code, test.status, test.code)
+		}
+	}
+}
+
+func TestSQLLengthCountsCharacters(t *testing.T) {
+	limits := Limits{MaxSQLLength: 5}
+	if v := Check([]byte(`{"sql": "ééééé"}`), 0, limits); v != nil {
+		t.Errorf("expected five multi-byte characters to fit, got %v", v)
+	}
+}
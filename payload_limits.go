@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/payload"
+)
+
+// Limits enforced on API requests, set by configurePayloadLimits
+var payloadLimits = payload.DefaultLimits()
+
+// configurePayloadLimits reads MAX_SQL_LENGTH, MAX_REQUEST_PARAMS and
+// MAX_REQUEST_BYTES. The body limit is raised when needed so the longest
+// allowed SQL, at up to 4 bytes per character, still fits.
+func configurePayloadLimits() {
+	limits := payload.DefaultLimits()
+	if n, err := strconv.Atoi(os.Getenv("MAX_SQL_LENGTH")); err == nil && n > 0 {
+		limits.MaxSQLLength = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("MAX_REQUEST_PARAMS")); err == nil && n > 0 {
+		limits.MaxParams = n
+	}
+	if n, err := strconv.ParseInt(os.Getenv("MAX_REQUEST_BYTES"), 10, 64); err == nil && n > 0 {
+		limits.MaxBodyBytes = n
+	}
+	if minimum := int64(limits.MaxSQLLength)*4 + 64<<10; limits.MaxBodyBytes < minimum {
+		limits.MaxBodyBytes = minimum
+	}
+	payloadLimits = limits
+}
+
+// validatePayload rejects API requests whose body is too large, not UTF-8,
+// carries too many parameters or SQL that is too long, or contains control
+// characters, before they reach the validator or a database
+func validatePayload(c *gin.Context) {
+	var body []byte
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(io.LimitReader(c.Request.Body, payloadLimits.MaxBodyBytes+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Failed to read request body: " + err.Error(),
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	queryParams := 0
+	for _, values := range c.Request.URL.Query() {
+		queryParams += len(values)
+	}
+	if violation := payload.Check(body, queryParams, payloadLimits); violation != nil {
+		response := gin.H{
+			"valid":     false,
+			"error":     violation.Message,
+			"errorCode": violation.Code,
+		}
+		if violation.Field != "" {
+			response["field"] = violation.Field
+		}
+		c.AbortWithStatusJSON(violation.Status, response)
+		return
+	}
+	c.Next()
+}
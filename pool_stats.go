@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+)
+
+// PoolSettingsRequest changes the connection pool of a backend. Fields left
+// out keep their current value.
+type PoolSettingsRequest struct {
+	MaxOpenConns *int `json:"max_open_conns"`
+	MaxIdleConns *int `json:"max_idle_conns"`
+	// Zero keeps connections open for as long as they are healthy
+	ConnMaxLifetimeSeconds *int64 `json:"conn_max_lifetime_seconds"`
+}
+
+// getPoolStats returns the connection pool statistics of every backend
+func getPoolStats(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"pools": dbmanager.GetPoolStats(),
+	})
+}
+
+// setPoolSettings tunes the connection pool of a MySQL or PostgreSQL backend
+// without a restart
+func setPoolSettings(c *gin.Context) {
+	backend := c.Param("backend")
+	var req PoolSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	settings, err := dbmanager.GetPoolSettings(backend)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if req.MaxOpenConns != nil {
+		settings.MaxOpenConns = *req.MaxOpenConns
+	}
+	if req.MaxIdleConns != nil {
+		settings.MaxIdleConns = *req.MaxIdleConns
+	}
+	if req.ConnMaxLifetimeSeconds != nil {
+		settings.ConnMaxLifetime = time.Duration(*req.ConnMaxLifetimeSeconds) * time.Second
+	}
+	if err := dbmanager.SetPoolSettings(backend, settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	lifetime := int64(settings.ConnMaxLifetime / time.Second)
+	recordAudit(c, "", "pool.settings", backend, fmt.Sprintf("max_open_conns=%d max_idle_conns=%d conn_max_lifetime_seconds=%d",
+		settings.MaxOpenConns, settings.MaxIdleConns, lifetime))
+	c.JSON(http.StatusOK, gin.H{
+		"backend":                   backend,
+		"max_open_conns":            settings.MaxOpenConns,
+		"max_idle_conns":            settings.MaxIdleConns,
+		"conn_max_lifetime_seconds": lifetime,
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// executeWithPreview runs an UPDATE or DELETE in a transaction at the
+// request's isolation level, capturing the affected rows before the change
+// and, for UPDATE, the same rows afterwards. statementSQL is the statement
+// scoped to the session, with scopeRewrites applied, that previewSQL was
+// derived from; it is rewritten and limited as it would be without a
+// preview.
+func executeWithPreview(ctx context.Context, owner string, db *sql.DB, req SQLValidationRequest, statementSQL string, scopeRewrites []sqlvalidator.Rewrite, previewSQL string) gin.H {
+	executedSQL, rewrites := rewriteForExecution(statementSQL, req.Dialect)
+	rewrites = append(scopeRewrites, rewrites...)
+
+	ctx, cancel := queryContext(ctx, req.Dialect)
+	defer cancel()
+	tx, query, err := beginLimited(ctx, db, executedSQL, req.Dialect, txOptions(req))
+	if err != nil {
+		return gin.H{
+			"valid": true,
+			"error": "Failed to start transaction: " + err.Error(),
+		}
+	}
+	defer tx.Rollback()
+
+	// The preview SELECTs share the transaction's limits; on MySQL they
+	// carry the timeout hint themselves
+	limitedPreview, _ := limitStatement(previewSQL, req.Dialect)
+
+	start := time.Now()
+	before, err := previewRows(ctx, tx, limitedPreview, req.Dialect)
+	if err != nil {
+		return gin.H{
+			"valid": true,
+			"error": "Preview query error: " + err.Error(),
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, query)
+	if err != nil {
+		recordQueryTiming(owner, db, req.Dialect, executedSQL, time.Since(start), err)
+		return withRewrites(rewrittenErrorResponse("Query execution error: ", err, req.SQL, executedSQL), executedSQL, rewrites)
+	}
+	rowsAffected, _ := res.RowsAffected()
+
+	// Rows an UPDATE no longer matches after the change are not shown here
+	var after *QueryResult
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(sqlvalidator.MaskSQL(statementSQL, req.Dialect))), "update") {
+		if after, err = previewRows(ctx, tx, limitedPreview, req.Dialect); err != nil {
+			after = nil
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return gin.H{
+			"valid": true,
+			"error": "Failed to commit: " + err.Error(),
+		}
+	}
+	recordQueryTiming(owner, db, req.Dialect, executedSQL, time.Since(start), nil)
+
+	return withRewrites(gin.H{
+		"valid":        true,
+		"rowsAffected": rowsAffected,
+		"preview": gin.H{
+			"query":  previewSQL,
+			"before": before,
+			"after":  after,
+		},
+	}, executedSQL, rewrites)
+}
+
+// previewRows runs a preview SELECT in the statement's transaction and reads
+// up to sqlvalidator.PreviewRowLimit of its rows, more than a response to
+// the SELECT alone would show
+func previewRows(ctx context.Context, tx *sql.Tx, query string, dialect string) (*QueryResult, error) {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return readRows(rows, dialect, sqlvalidator.PreviewRowLimit)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/sqlvalidator"
+)
+
+func TestPreviewRunsTheScopedStatement(t *testing.T) {
+	db := openMemoryDB(t)
+	if _, err := db.Exec("CREATE TABLE scoped_notes (id INTEGER, body TEXT); INSERT INTO scoped_notes VALUES (1, 'old')"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session's statement names a table the scoping resolved
+	req := SQLValidationRequest{SQL: "UPDATE notes SET body = 'new' WHERE id = 1", Dialect: "sqlite", Preview: true}
+	statementSQL := "UPDATE scoped_notes SET body = 'new' WHERE id = 1"
+	previewSQL, _ := sqlvalidator.DerivePreviewSelect(statementSQL, req.Dialect)
+	response := executeWithPreview(context.Background(), "session:test", db, req, statementSQL, nil, previewSQL)
+	if response["error"] != nil {
+		t.Fatalf("expected the scoped statement to run, got %v", response["error"])
+	}
+
+	var body string
+	if err := db.QueryRow("SELECT body FROM scoped_notes WHERE id = 1").Scan(&body); err != nil || body != "new" {
+		t.Errorf("expected the scoped table to be updated, got %q, %v", body, err)
+	}
+	preview := response["preview"].(gin.H)
+	if after := preview["after"].(*QueryResult); len(after.Rows) != 1 || after.Rows[0][1] != "new" {
+		t.Errorf("expected the preview to show the updated row, got %+v", after)
+	}
+}
+
+func TestPreviewShowsUpToThePreviewRowLimit(t *testing.T) {
+	db := openMemoryDB(t)
+	if _, err := db.Exec("CREATE TABLE counters (n INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("WITH RECURSIVE s(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM s WHERE n < 150) INSERT INTO counters SELECT n FROM s"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := SQLValidationRequest{SQL: "DELETE FROM counters", Dialect: "sqlite", Preview: true}
+	previewSQL, _ := sqlvalidator.DerivePreviewSelect(req.SQL, req.Dialect)
+	response := executeWithPreview(context.Background(), "session:test", db, req, req.SQL, nil, previewSQL)
+	if response["error"] != nil {
+		t.Fatalf("expected the delete to run, got %v", response["error"])
+	}
+	before := response["preview"].(gin.H)["before"].(*QueryResult)
+	if len(before.Rows) != sqlvalidator.PreviewRowLimit {
+		t.Errorf("expected %d preview rows, got %d", sqlvalidator.PreviewRowLimit, len(before.Rows))
+	}
+}
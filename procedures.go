@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"example/user/playground/routines"
+	"example/user/playground/sqlvalidator"
+)
+
+// contextQueryer runs queries under a context on a database, a
+// transaction, or a single pooled connection so session state such as
+// MySQL user variables carries over between them
+type contextQueryer struct {
+	ctx context.Context
+	q   interface {
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	}
+}
+
+func (q contextQueryer) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return q.q.QueryContext(q.ctx, query, args...)
+}
+
+// executeStatement runs a statement and returns all of its result sets. For
+// a MySQL CALL that passes user variables such as @total, the values the
+// procedure assigned to them are returned as output parameters.
+// Statements run under the dialect's per-query limits; materialized view
+// refreshes are limited to routines.RefreshTimeout instead. With opts the
+// statement runs in a transaction at the requested isolation level. The
+// statement is cancelled when ctx ends.
+func executeStatement(ctx context.Context, db *sql.DB, query string, dialect string, opts *sql.TxOptions) ([]*QueryResult, map[string]interface{}, error) {
+	// Refreshing a materialized view reruns its whole query
+	if stmt, _ := sqlvalidator.ParseRoutineStatement(query, dialect); stmt != nil && stmt.Refresh {
+		ctx, cancel := context.WithTimeout(ctx, routines.RefreshTimeout)
+		defer cancel()
+		if _, err := db.ExecContext(ctx, query); err != nil {
+			return nil, nil, err
+		}
+		return []*QueryResult{{Columns: []string{}, Rows: [][]interface{}{}}}, nil, nil
+	}
+
+	ctx, cancel := queryContext(ctx, dialect)
+	defer cancel()
+
+	variables := sqlvalidator.ProcedureOutputVariables(query, dialect)
+	if len(variables) == 0 {
+		results, err := executeLimited(ctx, db, query, dialect, opts)
+		return results, nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	session := contextQueryer{ctx, conn}
+	var tx *sql.Tx
+	if opts != nil {
+		if tx, err = conn.BeginTx(ctx, opts); err != nil {
+			return nil, nil, err
+		}
+		defer tx.Rollback()
+		session = contextQueryer{ctx, tx}
+	}
+
+	results, err := executeResultSets(session, query, dialect)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var outParams map[string]interface{}
+	values, err := executeQuery(session, "SELECT "+strings.Join(variables, ", "), dialect)
+	// When reading back the output fails, the procedure still ran
+	if err == nil && len(values.Rows) > 0 {
+		outParams = make(map[string]interface{}, len(variables))
+		for i, name := range variables {
+			outParams[name] = values.Rows[0][i]
+		}
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return results, outParams, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/execpool"
+	"example/user/playground/resultstats"
+	"example/user/playground/routines"
+	"example/user/playground/sqlvalidator"
+)
+
+// Rows sampled by default and at most when profiling a table
+const (
+	defaultProfileSample = 1000
+	maxProfileSample     = 5000
+)
+
+// ProfileTableRequest asks for a profile of a table's columns
+type ProfileTableRequest struct {
+	Dialect string `json:"dialect" binding:"required"`
+	Table   string `json:"table" binding:"required"`
+	// Rows to sample, up to 5000
+	SampleSize int `json:"sampleSize"`
+}
+
+// profileTable samples rows of a table of the bundled database and
+// describes each column: its share of NULLs, estimated cardinality, range,
+// histogram and a few sample values. Only tables of the cached schema and
+// of the session can be profiled, and the sample never exceeds
+// maxProfileSample rows.
+func profileTable(c *gin.Context) {
+	var req ProfileTableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.SampleSize <= 0 {
+		req.SampleSize = defaultProfileSample
+	}
+	if req.SampleSize > maxProfileSample {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("sampleSize must be at most %d", maxProfileSample),
+		})
+		return
+	}
+
+	owner := sessionOwner(c)
+	table, ok := profiledTable(owner, req.Dialect, req.Table)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Unknown table " + req.Table,
+		})
+		return
+	}
+
+	db, err := dbmanager.GetDatabaseConnection(req.Dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Database connection error: " + err.Error(),
+		})
+		return
+	}
+
+	// Profiles queue behind interactive queries on the backend's workers
+	status, response := runOnPool(c.Request.Context(), req.Dialect, execpool.Background, func(ctx context.Context) gin.H {
+		ctx, cancel := queryContext(ctx, req.Dialect)
+		defer cancel()
+
+		quoted := sqlvalidator.QuoteIdentifier(table, req.Dialect)
+		var total int64
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+quoted).Scan(&total); err != nil {
+			return queryErrorResponse("Profiling error: ", err, "")
+		}
+
+		query, method := sampleQuery(req.Dialect, quoted, isView(ctx, req.Dialect, table), total, req.SampleSize)
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return queryErrorResponse("Profiling error: ", err, query)
+		}
+		defer rows.Close()
+		sample, err := readRows(rows, req.Dialect, req.SampleSize)
+		if err != nil {
+			return queryErrorResponse("Profiling error: ", err, query)
+		}
+
+		return gin.H{
+			"dialect":     req.Dialect,
+			"table":       req.Table,
+			"totalRows":   total,
+			"sampledRows": len(sample.Rows),
+			"method":      method,
+			"statement":   query,
+			"columns":     resultstats.Profile(sample.Columns, sample.Rows, total),
+		}
+	})
+	c.JSON(status, response)
+}
+
+// profiledTable returns the name of a table in the cached schema, matched
+// case-insensitively, or of a table the session materialized under that
+// short name
+func profiledTable(owner string, dialect string, name string) (string, bool) {
+	prefixed := routines.Prefix(owner) + name
+	if routines.Owns(owner, dialect, sqlvalidator.RoutineTable, prefixed) {
+		return prefixed, true
+	}
+	schema, ok := dbmanager.CachedSchema(dialect)
+	if !ok {
+		return "", false
+	}
+	if _, ok := schema[name]; ok {
+		return name, true
+	}
+	for table := range schema {
+		if strings.EqualFold(table, name) {
+			return table, true
+		}
+	}
+	return "", false
+}
+
+// isView reports whether a table of the schema is a view, which
+// TABLESAMPLE cannot read
+func isView(ctx context.Context, dialect string, table string) bool {
+	metadata, err := dbmanager.Metadata(ctx, dialect)
+	if err != nil {
+		return false
+	}
+	for _, view := range metadata.Views {
+		if view.Name == table {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleQuery returns the statement sampling up to size rows of a table
+// with total rows, and how it samples. Small tables are read whole.
+// PostgreSQL tables are sampled with TABLESAMPLE BERNOULLI at a rate a
+// little above the share wanted, so the sample rarely falls short; other
+// tables are shuffled with ORDER BY RANDOM().
+func sampleQuery(dialect string, quoted string, view bool, total int64, size int) (string, string) {
+	if total <= int64(size) {
+		return fmt.Sprintf("SELECT * FROM %s LIMIT %d", quoted, size), "full"
+	}
+	switch {
+	case dialect == "postgresql" && !view:
+		percent := float64(size) / float64(total) * 100 * 1.2
+		if percent > 100 {
+			percent = 100
+		}
+		return fmt.Sprintf("SELECT * FROM %s TABLESAMPLE BERNOULLI (%.4f) LIMIT %d", quoted, percent, size), "tablesample"
+	case dialect == "mysql":
+		return fmt.Sprintf("SELECT * FROM %s ORDER BY RAND() LIMIT %d", quoted, size), "random"
+	}
+	return fmt.Sprintf("SELECT * FROM %s ORDER BY RANDOM() LIMIT %d", quoted, size), "random"
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/querybuilder"
+)
+
+// buildQuery generates the SQL of a structured query specification, as
+// assembled by a visual query builder
+func buildQuery(c *gin.Context) {
+	var spec querybuilder.Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	sql, err := querybuilder.Build(spec)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     err.Error(),
+			"errorCode": dberrors.CodeValidationError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dialect": spec.Dialect,
+		"sql":     sql,
+	})
+}
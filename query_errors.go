@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/sqlvalidator"
+)
+
+// validationErrorResponse builds the response body for a query rejected by
+// the validator, including the location of syntax errors and hints for
+// common mistakes
+func validationErrorResponse(err error, query string, dialect string) gin.H {
+	response := gin.H{
+		"valid":     false,
+		"error":     err.Error(),
+		"errorCode": dberrors.CodeValidationError,
+	}
+
+	var syntaxErr *sqlvalidator.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		response["errorCode"] = dberrors.CodeSyntaxError
+		if syntaxErr.Line > 0 {
+			response["errorPosition"] = dberrors.Position{Line: syntaxErr.Line, Column: syntaxErr.Column}
+		}
+		response["errorToken"] = syntaxErr.Token
+	}
+	return withHints(response, dberrors.Hints(err, query, dialect))
+}
+
+// queryErrorResponse builds the response body for a failed query execution,
+// adding a normalized error code, the error position when the driver reports
+// one, a structured explanation for constraint violations and hints for
+// common mistakes
+func queryErrorResponse(prefix string, err error, query string) gin.H {
+	return rewrittenErrorResponse(prefix, err, query, query)
+}
+
+// rewrittenErrorResponse builds the response body for a query that failed
+// as executedSQL, the query after the server's rewrites. The error position
+// is mapped back to the query as written, and hints look at it.
+func rewrittenErrorResponse(prefix string, err error, query string, executedSQL string) gin.H {
+	code, position := dberrors.Classify(err, executedSQL)
+	response := gin.H{
+		"valid":     true,
+		"error":     prefix + err.Error(),
+		"errorCode": code,
+		"result":    nil,
+	}
+	if position = dberrors.MapPosition(position, executedSQL, query); position != nil {
+		response["errorPosition"] = position
+	}
+	if explanation := dberrors.ExplainConstraint(err); explanation != nil {
+		response["explanation"] = explanation
+	}
+	return withHints(response, dberrors.Hints(err, query, ""))
+}
+
+// withHints adds suggestions for common mistakes to a response
+func withHints(response gin.H, hints []dberrors.Hint) gin.H {
+	if len(hints) > 0 {
+		response["hints"] = hints
+	}
+	return response
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"example/user/playground/querylimits"
+)
+
+// How long past a server-side timeout the client waits before giving up on
+// a MySQL or PostgreSQL statement itself
+const queryTimeoutGrace = time.Second
+
+// Per-query limits of each dialect, set by configureQueryLimits
+var queryLimits = querylimits.Defaults()
+
+// configureQueryLimits reads QUERY_TIMEOUT for every dialect, overridden
+// per dialect by QUERY_TIMEOUT_SQLITE, QUERY_TIMEOUT_MYSQL and
+// QUERY_TIMEOUT_POSTGRESQL, and POSTGRES_WORK_MEM
+func configureQueryLimits() {
+	limits := querylimits.Defaults()
+	for dialect, dialectLimits := range limits {
+		for _, name := range []string{"QUERY_TIMEOUT", "QUERY_TIMEOUT_" + strings.ToUpper(dialect)} {
+			value := os.Getenv(name)
+			if value == "" {
+				continue
+			}
+			timeout, err := querylimits.ParseTimeout(value)
+			if err != nil {
+				fmt.Printf("Ignoring %s: %v\n", name, err)
+				continue
+			}
+			dialectLimits.Timeout = timeout
+		}
+		limits[dialect] = dialectLimits
+	}
+
+	if value := os.Getenv("POSTGRES_WORK_MEM"); value != "" {
+		postgres := limits["postgresql"]
+		if querylimits.ValidWorkMem(value) {
+			postgres.WorkMem = value
+		} else {
+			fmt.Printf("Ignoring POSTGRES_WORK_MEM: %q is not a size such as 4MB\n", value)
+		}
+		limits["postgresql"] = postgres
+	}
+	queryLimits = limits
+}
+
+// queryContext returns a context ending when the client stops waiting for
+// a statement: when parent ends, as when the client disconnects, or at the
+// dialect's deadline. SQLite is interrupted at the deadline; MySQL and
+// PostgreSQL enforce the timeout on the server and get a short grace period.
+func queryContext(parent context.Context, dialect string) (context.Context, context.CancelFunc) {
+	timeout := queryLimits[dialect].Timeout
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	if dialect != "sqlite" {
+		timeout += queryTimeoutGrace
+	}
+	return context.WithTimeout(parent, timeout)
+}
+
+// executeLimited runs a statement under its dialect's per-query limits:
+// SET LOCAL statement_timeout and work_mem in a transaction on PostgreSQL,
+// a MAX_EXECUTION_TIME hint on MySQL SELECTs, and on SQLite the driver's
+// interrupt once ctx expires. With opts the statement runs in a transaction
+// of its own at the requested isolation level.
+func executeLimited(ctx context.Context, db *sql.DB, query string, dialect string, opts *sql.TxOptions) ([]*QueryResult, error) {
+	if opts == nil {
+		if limited, settings := limitStatement(query, dialect); len(settings) == 0 {
+			return executeResultSets(contextQueryer{ctx, db}, limited, dialect)
+		}
+	}
+
+	tx, query, err := beginLimited(ctx, db, query, dialect, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	results, err := executeResultSets(contextQueryer{ctx, tx}, query, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return results, tx.Commit()
+}
+
+// beginLimited starts a transaction at opts for a statement and applies its
+// dialect's per-query limits to the transaction. It returns the statement
+// to run in it, which on MySQL carries the MAX_EXECUTION_TIME hint.
+func beginLimited(ctx context.Context, db *sql.DB, query string, dialect string, opts *sql.TxOptions) (*sql.Tx, string, error) {
+	query, settings := limitStatement(query, dialect)
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, setting := range settings {
+		if _, err := tx.ExecContext(ctx, setting); err != nil {
+			tx.Rollback()
+			return nil, "", err
+		}
+	}
+	return tx, query, nil
+}
+
+// limitStatement returns a statement with the MySQL timeout hint applied
+// and the PostgreSQL settings its transaction needs
+func limitStatement(query string, dialect string) (string, []string) {
+	limits := queryLimits[dialect]
+	switch dialect {
+	case "postgresql":
+		if querylimits.Transactional(query) {
+			return query, querylimits.PostgresSettings(limits)
+		}
+	case "mysql":
+		query, _ = querylimits.MySQLHint(query, limits.Timeout)
+	}
+	return query, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/secrets"
+	"example/user/playground/sqlvalidator"
+)
+
+// Attempts of a read-only query failing with transient errors by default
+const defaultQueryAttempts = 3
+
+// Backoff before the first retry, doubling up to maxRetryBackoff
+const (
+	initialRetryBackoff = 50 * time.Millisecond
+	maxRetryBackoff     = time.Second
+)
+
+// Attempts of a read-only query, set by configureQueryRetries
+var queryAttempts = defaultQueryAttempts
+
+// QueryRetries describes the attempts of a query that hit transient errors
+type QueryRetries struct {
+	// Attempts made, including the first
+	Attempts int `json:"attempts"`
+	// Transient errors of the failed attempts, in order
+	Errors []string `json:"errors"`
+	// Time spent waiting between attempts
+	BackoffMs float64 `json:"backoffMs"`
+}
+
+// configureQueryRetries reads QUERY_RETRY_ATTEMPTS, the number of times a
+// read-only query may run when it fails with transient errors. 1 turns
+// retries off.
+func configureQueryRetries() {
+	value := os.Getenv("QUERY_RETRY_ATTEMPTS")
+	if value == "" {
+		return
+	}
+	attempts, err := strconv.Atoi(value)
+	if err != nil || attempts < 1 {
+		fmt.Printf("Ignoring QUERY_RETRY_ATTEMPTS: %q is not a positive number of attempts\n", value)
+		return
+	}
+	queryAttempts = attempts
+}
+
+// executeWithRetries executes a statement, running it again with capped
+// exponential backoff while it fails with a transient error such as a
+// reset connection, a deadlock or a serialization failure. Only queries
+// that merely read are retried. The retries are nil when the first
+// attempt settled the outcome. Retrying stops once ctx ends.
+func executeWithRetries(ctx context.Context, db *sql.DB, query string, dialect string, opts *sql.TxOptions) ([]*QueryResult, map[string]interface{}, *QueryRetries, error) {
+	results, outParams, err := executeStatement(ctx, db, query, dialect, opts)
+	if !dberrors.IsTransient(err) || queryAttempts < 2 || !sqlvalidator.IsRetryableQuery(query, dialect) {
+		return results, outParams, nil, err
+	}
+
+	retries := &QueryRetries{Attempts: 1}
+	backoff := initialRetryBackoff
+	for retries.Attempts < queryAttempts && dberrors.IsTransient(err) {
+		retries.Errors = append(retries.Errors, secrets.MaskError(err))
+
+		// Jitter keeps clients that failed together from retrying together
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return results, outParams, retries, err
+		case <-time.After(wait):
+		}
+		retries.BackoffMs += float64(wait.Microseconds()) / 1000
+		if backoff *= 2; backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
+
+		retries.Attempts++
+		results, outParams, err = executeStatement(ctx, db, query, dialect, opts)
+	}
+	return results, outParams, retries, err
+}
+
+// withRetries adds the retries of a query to its response
+func withRetries(response gin.H, retries *QueryRetries) gin.H {
+	if retries != nil {
+		response["retries"] = retries
+	}
+	return response
+}
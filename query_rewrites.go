@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/querylimits"
+	"example/user/playground/sqlvalidator"
+)
+
+// rewriteForExecution applies the server's rewrites to a statement and, on
+// MySQL, the MAX_EXECUTION_TIME hint of the query timeout, returning the SQL
+// to execute along with the rewrites that changed it
+func rewriteForExecution(sql string, dialect string) (string, []sqlvalidator.Rewrite) {
+	rewritten, rewrites := sqlvalidator.RewriteForExecution(sql, dialect)
+	return withServerHints(rewritten, rewrites, dialect)
+}
+
+// rewriteForExport rewrites a statement whose rows are downloaded as
+// rewriteForExecution does, without limiting the rows it returns
+func rewriteForExport(sql string, dialect string) (string, []sqlvalidator.Rewrite) {
+	rewritten, rewrites := sqlvalidator.RewriteForExport(sql, dialect)
+	return withServerHints(rewritten, rewrites, dialect)
+}
+
+// withServerHints adds the hints that stop a statement on the server, such
+// as MySQL's execution time limit, to an already rewritten statement
+func withServerHints(sql string, rewrites []sqlvalidator.Rewrite, dialect string) (string, []sqlvalidator.Rewrite) {
+	if dialect != "mysql" {
+		return sql, rewrites
+	}
+	timeout := queryLimits[dialect].Timeout
+	if hinted, ok := querylimits.MySQLHint(sql, timeout); ok {
+		sql = hinted
+		rewrites = append(rewrites, sqlvalidator.Rewrite{
+			Name:        "max_execution_time",
+			Description: fmt.Sprintf("Added a MAX_EXECUTION_TIME hint so MySQL stops the SELECT after %s", timeout),
+		})
+	}
+	return sql, rewrites
+}
+
+// withRewrites adds the SQL that actually ran and the rewrites that produced
+// it to a response, when the server changed the user's statement
+func withRewrites(response gin.H, executedSQL string, rewrites []sqlvalidator.Rewrite) gin.H {
+	if len(rewrites) > 0 {
+		response["executedSql"] = executedSQL
+		response["rewrites"] = rewrites
+	}
+	return response
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRewriteForExecutionReportsMySQLHint(t *testing.T) {
+	executed, rewrites := rewriteForExecution("SELECT id FROM articles LIMIT 5", "mysql")
+	if !strings.HasPrefix(executed, "SELECT /*+ MAX_EXECUTION_TIME(") {
+		t.Errorf("expected the hint in the executed SQL, got %q", executed)
+	}
+	if len(rewrites) != 1 || rewrites[0].Name != "max_execution_time" {
+		t.Errorf("expected the hint to be reported, got %+v", rewrites)
+	}
+
+	if _, rewrites := rewriteForExecution("SELECT id FROM articles LIMIT 5", "postgresql"); len(rewrites) != 0 {
+		t.Errorf("expected no rewrites outside MySQL, got %+v", rewrites)
+	}
+}
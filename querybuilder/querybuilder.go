@@ -0,0 +1,399 @@
+package querybuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// TableRef names a table, optionally under an alias
+type TableRef struct {
+	Table string `json:"table" binding:"required"`
+	Alias string `json:"alias"`
+}
+
+// ColumnRef names a column, qualified by a table name or alias when Table
+// is set
+type ColumnRef struct {
+	Table  string `json:"table"`
+	Column string `json:"column" binding:"required"`
+}
+
+// Column is a selected column, optionally aggregated and renamed. A column
+// of "*" selects every column.
+type Column struct {
+	Table  string `json:"table"`
+	Column string `json:"column" binding:"required"`
+	// count, count_distinct, sum, avg, min or max
+	Aggregate string `json:"aggregate"`
+	Alias     string `json:"alias"`
+}
+
+// JoinCondition equates a column of the joined table with another column
+type JoinCondition struct {
+	Left  ColumnRef `json:"left"`
+	Right ColumnRef `json:"right"`
+}
+
+// Join adds a table to the query
+type Join struct {
+	// inner, left, right or full; inner when empty
+	Type  string          `json:"type"`
+	Table string          `json:"table" binding:"required"`
+	Alias string          `json:"alias"`
+	On    []JoinCondition `json:"on"`
+}
+
+// Filter is a condition on a column. Filters are combined with AND.
+type Filter struct {
+	Table  string `json:"table"`
+	Column string `json:"column" binding:"required"`
+	// =, !=, <, <=, >, >=, like, not like, ilike, in, not in, between,
+	// is null or is not null
+	Operator string      `json:"operator" binding:"required"`
+	Value    interface{} `json:"value"`
+	// Operands of in, not in and between
+	Values []interface{} `json:"values"`
+}
+
+// Order sorts the result by a column
+type Order struct {
+	Table  string `json:"table"`
+	Column string `json:"column" binding:"required"`
+	// asc or desc; asc when empty
+	Direction string `json:"direction"`
+}
+
+// Spec is a structured description of a SELECT query
+type Spec struct {
+	Dialect  string      `json:"dialect" binding:"required"`
+	From     TableRef    `json:"from"`
+	Joins    []Join      `json:"joins"`
+	Columns  []Column    `json:"columns"`
+	Distinct bool        `json:"distinct"`
+	Filters  []Filter    `json:"filters"`
+	GroupBy  []ColumnRef `json:"groupBy"`
+	OrderBy  []Order     `json:"orderBy"`
+	// Most rows to return, unlimited when nil
+	Limit  *int `json:"limit"`
+	Offset int  `json:"offset"`
+}
+
+// Aggregate functions by name
+var aggregates = map[string]string{
+	"count":          "COUNT",
+	"count_distinct": "COUNT",
+	"sum":            "SUM",
+	"avg":            "AVG",
+	"min":            "MIN",
+	"max":            "MAX",
+}
+
+// Join keywords by join type
+var joinKeywords = map[string]string{
+	"":      "JOIN",
+	"inner": "JOIN",
+	"left":  "LEFT JOIN",
+	"right": "RIGHT JOIN",
+	"full":  "FULL OUTER JOIN",
+}
+
+// Comparison operators by name
+var comparisons = map[string]string{
+	"=":        "=",
+	"!=":       "<>",
+	"<>":       "<>",
+	"<":        "<",
+	"<=":       "<=",
+	">":        ">",
+	">=":       ">=",
+	"like":     "LIKE",
+	"not like": "NOT LIKE",
+}
+
+// builder generates the SQL of one spec
+type builder struct {
+	dialect sqlvalidator.DialectCapabilities
+	// Names the tables of the query can be referred to by
+	tables map[string]bool
+}
+
+// Build generates the SQL of a spec for its dialect. Identifiers are quoted
+// where the dialect needs it and values are written as literals.
+func Build(spec Spec) (string, error) {
+	dialect, ok := sqlvalidator.Capabilities(spec.Dialect)
+	if !ok {
+		return "", fmt.Errorf("unsupported dialect %q", spec.Dialect)
+	}
+	if spec.From.Table == "" {
+		return "", fmt.Errorf("from.table is required")
+	}
+	b := &builder{dialect: dialect, tables: map[string]bool{}}
+	b.addTable(spec.From)
+	for _, join := range spec.Joins {
+		b.addTable(TableRef{Table: join.Table, Alias: join.Alias})
+	}
+
+	var clauses []string
+
+	columns := []string{}
+	for _, column := range spec.Columns {
+		expr, err := b.column(column)
+		if err != nil {
+			return "", err
+		}
+		columns = append(columns, expr)
+	}
+	if len(columns) == 0 {
+		columns = append(columns, "*")
+	}
+	selectClause := "SELECT "
+	if spec.Distinct {
+		selectClause += "DISTINCT "
+	}
+	clauses = append(clauses, selectClause+strings.Join(columns, ", "))
+	clauses = append(clauses, "FROM "+b.table(spec.From))
+
+	for _, join := range spec.Joins {
+		clause, err := b.join(join)
+		if err != nil {
+			return "", err
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if len(spec.Filters) > 0 {
+		conditions := make([]string, 0, len(spec.Filters))
+		for _, filter := range spec.Filters {
+			condition, err := b.filter(filter)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, condition)
+		}
+		clauses = append(clauses, "WHERE "+strings.Join(conditions, " AND "))
+	}
+
+	if len(spec.GroupBy) > 0 {
+		groups := make([]string, 0, len(spec.GroupBy))
+		for _, ref := range spec.GroupBy {
+			expr, err := b.ref(ref.Table, ref.Column)
+			if err != nil {
+				return "", err
+			}
+			groups = append(groups, expr)
+		}
+		clauses = append(clauses, "GROUP BY "+strings.Join(groups, ", "))
+	}
+
+	if len(spec.OrderBy) > 0 {
+		orders := make([]string, 0, len(spec.OrderBy))
+		for _, order := range spec.OrderBy {
+			expr, err := b.ref(order.Table, order.Column)
+			if err != nil {
+				return "", err
+			}
+			switch strings.ToLower(order.Direction) {
+			case "", "asc":
+			case "desc":
+				expr += " DESC"
+			default:
+				return "", fmt.Errorf("unknown sort direction %q", order.Direction)
+			}
+			orders = append(orders, expr)
+		}
+		clauses = append(clauses, "ORDER BY "+strings.Join(orders, ", "))
+	}
+
+	limit := -1
+	if spec.Limit != nil {
+		if *spec.Limit < 0 {
+			return "", fmt.Errorf("limit must not be negative")
+		}
+		limit = *spec.Limit
+	}
+	if spec.Offset < 0 {
+		return "", fmt.Errorf("offset must not be negative")
+	}
+	if clause := dialect.LimitClause(limit, spec.Offset); clause != "" {
+		clauses = append(clauses, clause)
+	}
+
+	return strings.Join(clauses, "\n"), nil
+}
+
+// addTable records the name a table is referred to by
+func (b *builder) addTable(ref TableRef) {
+	if ref.Alias != "" {
+		b.tables[ref.Alias] = true
+	} else {
+		b.tables[ref.Table] = true
+	}
+}
+
+// table writes a table with its alias
+func (b *builder) table(ref TableRef) string {
+	if ref.Alias == "" {
+		return b.dialect.Quote(ref.Table)
+	}
+	return b.dialect.Quote(ref.Table) + " " + b.dialect.Quote(ref.Alias)
+}
+
+// ref writes a column, qualified by its table when one is given
+func (b *builder) ref(table string, column string) (string, error) {
+	if column == "" {
+		return "", fmt.Errorf("column is required")
+	}
+	name := b.dialect.Quote(column)
+	if column == "*" {
+		name = "*"
+	}
+	if table == "" {
+		return name, nil
+	}
+	if !b.tables[table] {
+		return "", fmt.Errorf("unknown table %q; columns must refer to a table or alias of the query", table)
+	}
+	return b.dialect.Quote(table) + "." + name, nil
+}
+
+// column writes a selected column
+func (b *builder) column(column Column) (string, error) {
+	expr, err := b.ref(column.Table, column.Column)
+	if err != nil {
+		return "", err
+	}
+
+	if column.Aggregate != "" {
+		function, ok := aggregates[strings.ToLower(column.Aggregate)]
+		if !ok {
+			return "", fmt.Errorf("unknown aggregate %q", column.Aggregate)
+		}
+		if strings.ToLower(column.Aggregate) == "count_distinct" {
+			expr = "DISTINCT " + expr
+		}
+		if column.Column == "*" && function != "COUNT" {
+			return "", fmt.Errorf("%s needs a column", function)
+		}
+		expr = function + "(" + expr + ")"
+	} else if column.Alias != "" && column.Column == "*" {
+		return "", fmt.Errorf("* cannot have an alias")
+	}
+
+	if column.Alias != "" {
+		expr += " AS " + b.dialect.Quote(column.Alias)
+	}
+	return expr, nil
+}
+
+// join writes a join clause
+func (b *builder) join(join Join) (string, error) {
+	joinType := strings.ToLower(join.Type)
+	keyword, ok := joinKeywords[joinType]
+	if !ok {
+		return "", fmt.Errorf("unknown join type %q", join.Type)
+	}
+	if joinType == "right" && !b.dialect.RightJoin || joinType == "full" && !b.dialect.FullOuterJoin {
+		return "", fmt.Errorf("%s is not supported by %s", keyword, b.dialect.Name)
+	}
+	if len(join.On) == 0 {
+		return "", fmt.Errorf("join of %s needs at least one condition", join.Table)
+	}
+
+	conditions := make([]string, 0, len(join.On))
+	for _, condition := range join.On {
+		left, err := b.ref(condition.Left.Table, condition.Left.Column)
+		if err != nil {
+			return "", err
+		}
+		right, err := b.ref(condition.Right.Table, condition.Right.Column)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, left+" = "+right)
+	}
+	return keyword + " " + b.table(TableRef{Table: join.Table, Alias: join.Alias}) + " ON " + strings.Join(conditions, " AND "), nil
+}
+
+// filter writes the condition of a filter
+func (b *builder) filter(filter Filter) (string, error) {
+	column, err := b.ref(filter.Table, filter.Column)
+	if err != nil {
+		return "", err
+	}
+
+	operator := strings.ToLower(strings.Join(strings.Fields(filter.Operator), " "))
+	switch operator {
+	case "is null":
+		return column + " IS NULL", nil
+	case "is not null":
+		return column + " IS NOT NULL", nil
+	case "in", "not in":
+		if len(filter.Values) == 0 {
+			return "", fmt.Errorf("%s needs at least one value", operator)
+		}
+		values := make([]string, 0, len(filter.Values))
+		for _, value := range filter.Values {
+			literal, err := b.literal(value)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, literal)
+		}
+		return column + " " + strings.ToUpper(operator) + " (" + strings.Join(values, ", ") + ")", nil
+	case "between":
+		if len(filter.Values) != 2 {
+			return "", fmt.Errorf("between needs two values")
+		}
+		low, err := b.literal(filter.Values[0])
+		if err != nil {
+			return "", err
+		}
+		high, err := b.literal(filter.Values[1])
+		if err != nil {
+			return "", err
+		}
+		return column + " BETWEEN " + low + " AND " + high, nil
+	}
+
+	value, err := b.literal(filter.Value)
+	if err != nil {
+		return "", err
+	}
+	if operator == "ilike" {
+		if b.dialect.ILike {
+			return column + " ILIKE " + value, nil
+		}
+		return "LOWER(" + column + ") LIKE LOWER(" + value + ")", nil
+	}
+	comparison, ok := comparisons[operator]
+	if !ok {
+		return "", fmt.Errorf("unknown operator %q", filter.Operator)
+	}
+	if filter.Value == nil {
+		return "", fmt.Errorf("%s needs a value; use is null to match NULL", filter.Operator)
+	}
+	return column + " " + comparison + " " + value, nil
+}
+
+// literal writes a JSON value as a SQL literal
+func (b *builder) literal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case string:
+		return b.dialect.StringLiteral(v), nil
+	}
+	return "", fmt.Errorf("unsupported value %v; values must be strings, numbers, booleans or null", value)
+}
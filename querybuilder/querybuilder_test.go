@@ -0,0 +1,130 @@
+package querybuilder
+
+import (
+	"strings"
+	"testing"
+)
+
+func intPtr(n int) *int {
+	return &n
+}
+
+func ordersSpec(dialect string) Spec {
+	return Spec{
+		Dialect: dialect,
+		From:    TableRef{Table: "users", Alias: "u"},
+		Joins: []Join{{
+			Type:  "left",
+			Table: "orders",
+			Alias: "o",
+			On:    []JoinCondition{{Left: ColumnRef{Table: "o", Column: "user_id"}, Right: ColumnRef{Table: "u", Column: "id"}}},
+		}},
+		Columns: []Column{
+			{Table: "u", Column: "name"},
+			{Table: "o", Column: "id", Aggregate: "count", Alias: "order"},
+		},
+		Filters: []Filter{
+			{Table: "u", Column: "name", Operator: "ilike", Value: "a%"},
+			{Table: "o", Column: "status", Operator: "in", Values: []interface{}{"paid", "shipped"}},
+		},
+		GroupBy: []ColumnRef{{Table: "u", Column: "name"}},
+		OrderBy: []Order{{Column: "order", Direction: "desc"}},
+		Limit:   intPtr(10),
+		Offset:  20,
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tests := map[string]string{
+		"sqlite": `SELECT u.name, COUNT(o.id) AS "order"
+FROM users u
+LEFT JOIN orders o ON o.user_id = u.id
+WHERE LOWER(u.name) LIKE LOWER('a%') AND o.status IN ('paid', 'shipped')
+GROUP BY u.name
+ORDER BY "order" DESC
+LIMIT 10 OFFSET 20`,
+		"mysql": "SELECT u.name, COUNT(o.id) AS `order`\n" +
+			"FROM users u\n" +
+			"LEFT JOIN orders o ON o.user_id = u.id\n" +
+			"WHERE LOWER(u.name) LIKE LOWER('a%') AND o.status IN ('paid', 'shipped')\n" +
+			"GROUP BY u.name\n" +
+			"ORDER BY `order` DESC\n" +
+			"LIMIT 10 OFFSET 20",
+		"postgresql": `SELECT u.name, COUNT(o.id) AS "order"
+FROM users u
+LEFT JOIN orders o ON o.user_id = u.id
+WHERE u.name ILIKE 'a%' AND o.status IN ('paid', 'shipped')
+GROUP BY u.name
+ORDER BY "order" DESC
+LIMIT 10 OFFSET 20`,
+	}
+
+	for dialect, want := range tests {
+		got, err := Build(ordersSpec(dialect))
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		if got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", dialect, got, want)
+		}
+	}
+}
+
+func TestBuildSelectsEverythingByDefault(t *testing.T) {
+	got, err := Build(Spec{
+		Dialect: "postgresql",
+		From:    TableRef{Table: "Users"},
+		Filters: []Filter{{Column: "deleted_at", Operator: "is null"}},
+		Offset:  5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SELECT *\nFROM \"Users\"\nWHERE deleted_at IS NULL\nOFFSET 5"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBuildEscapesValues(t *testing.T) {
+	got, err := Build(Spec{
+		Dialect: "mysql",
+		From:    TableRef{Table: "notes"},
+		Filters: []Filter{{Column: "body", Operator: "=", Value: `it's \`}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(got, `WHERE body = 'it''s \\'`) {
+		t.Errorf("expected the value to be escaped, got %s", got)
+	}
+}
+
+func TestBuildRejectsInvalidSpecs(t *testing.T) {
+	fullJoin := ordersSpec("mysql")
+	fullJoin.Joins[0].Type = "full"
+
+	unknownTable := ordersSpec("sqlite")
+	unknownTable.Columns = append(unknownTable.Columns, Column{Table: "p", Column: "id"})
+
+	badOperator := ordersSpec("sqlite")
+	badOperator.Filters = []Filter{{Column: "id", Operator: "; DROP TABLE users"}}
+
+	badValue := ordersSpec("sqlite")
+	badValue.Filters = []Filter{{Column: "id", Operator: "=", Value: map[string]interface{}{}}}
+
+	specs := map[string]Spec{
+		"full join on MySQL":   fullJoin,
+		"unknown table":        unknownTable,
+		"unknown operator":     badOperator,
+		"unsupported value":    badValue,
+		"negative limit":       {Dialect: "sqlite", From: TableRef{Table: "users"}, Limit: intPtr(-1)},
+		"unsupported dialect":  {Dialect: "oracle", From: TableRef{Table: "users"}},
+		"missing from table":   {Dialect: "sqlite"},
+		"join without columns": {Dialect: "sqlite", From: TableRef{Table: "a"}, Joins: []Join{{Table: "b"}}},
+	}
+	for name, spec := range specs {
+		if _, err := Build(spec); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
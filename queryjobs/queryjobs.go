@@ -0,0 +1,222 @@
+package queryjobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"example/user/playground/sharedstate"
+)
+
+// States of a job
+const (
+	Queued    = "queued"
+	Running   = "running"
+	Completed = "completed"
+	Failed    = "failed"
+)
+
+// How long a job and its response are kept after its last change
+const DefaultRetention = time.Hour
+
+// Characters used for job IDs
+const idAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Length of generated job IDs
+const idLength = 12
+
+// ErrNotFound is returned when a job does not exist, has expired or
+// belongs to someone else
+var ErrNotFound = errors.New("query job not found or expired")
+
+// ErrQueueFull is returned when every worker is busy and no more jobs can
+// wait for one
+var ErrQueueFull = errors.New("too many queries are waiting to run, try again later")
+
+// Job is a query running in the background. Its state is kept in shared
+// state so any instance can report it, while it runs on the instance that
+// accepted it.
+type Job struct {
+	ID            string `json:"id"`
+	State         string `json:"state"`
+	Dialect       string `json:"dialect"`
+	SQL           string `json:"sql"`
+	EstimatedRows int64  `json:"estimatedRows"`
+	// HTTP status and body the query would have been answered with
+	Status     int             `json:"status,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	StartedAt  *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+}
+
+// Run executes the query of a job and returns the status and response
+type Run func() (int, interface{})
+
+// queued is a job waiting for a worker
+type queued struct {
+	owner string
+	job   *Job
+	run   Run
+}
+
+// Queue runs jobs on a fixed number of workers
+type Queue struct {
+	pending   chan queued
+	retention time.Duration
+}
+
+// New starts a queue with the given number of workers, holding at most
+// capacity jobs waiting for one
+func New(workers int, capacity int, retention time.Duration) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+	if capacity < 0 {
+		capacity = 0
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	q := &Queue{
+		pending:   make(chan queued, capacity),
+		retention: retention,
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// jobKey returns the shared state key of a job. The owner is part of the
+// key so one session cannot read another's results.
+func jobKey(owner string, id string) string {
+	return "queryjob:" + owner + ":" + id
+}
+
+// Submit queues a query of an owner and returns its job
+func (q *Queue) Submit(owner string, dialect string, sql string, estimatedRows int64, run Run) (*Job, error) {
+	job := &Job{
+		State:         Queued,
+		Dialect:       dialect,
+		SQL:           sql,
+		EstimatedRows: estimatedRows,
+		CreatedAt:     time.Now(),
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	// Regenerate on the unlikely event of a collision
+	for {
+		if job.ID, err = newID(); err != nil {
+			return nil, err
+		}
+		stored, err := sharedstate.Current().SetNX(context.Background(), jobKey(owner, job.ID), data, q.retention)
+		if err != nil {
+			return nil, err
+		}
+		if stored {
+			break
+		}
+	}
+
+	// The worker updates its own copy of the job
+	submitted := *job
+	select {
+	case q.pending <- queued{owner: owner, job: job, run: run}:
+		return &submitted, nil
+	default:
+		sharedstate.Current().Delete(context.Background(), jobKey(owner, job.ID))
+		return nil, ErrQueueFull
+	}
+}
+
+// Get returns a job of an owner
+func (q *Queue) Get(owner string, id string) (*Job, error) {
+	data, err := sharedstate.Current().Get(context.Background(), jobKey(owner, id))
+	if err == sharedstate.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	job.ID = id
+	return &job, nil
+}
+
+// work runs queued jobs until the process exits
+func (q *Queue) work() {
+	for item := range q.pending {
+		q.execute(item)
+	}
+}
+
+// execute runs a job and stores its response
+func (q *Queue) execute(item queued) {
+	job := item.job
+	started := time.Now()
+	job.State = Running
+	job.StartedAt = &started
+	q.save(item.owner, job)
+
+	// A failing query must not take the worker down with it
+	defer func() {
+		if r := recover(); r != nil {
+			finished := time.Now()
+			job.State = Failed
+			job.Error = fmt.Sprint(r)
+			job.FinishedAt = &finished
+			q.save(item.owner, job)
+		}
+	}()
+
+	status, response := item.run()
+	finished := time.Now()
+	job.FinishedAt = &finished
+	data, err := json.Marshal(response)
+	if err != nil {
+		job.State = Failed
+		job.Error = "Failed to encode the response: " + err.Error()
+	} else {
+		job.State = Completed
+		job.Status = status
+		job.Response = data
+	}
+	q.save(item.owner, job)
+}
+
+// save stores the current state of a job
+func (q *Queue) save(owner string, job *Job) {
+	data, err := json.Marshal(job)
+	if err == nil {
+		err = sharedstate.Current().Set(context.Background(), jobKey(owner, job.ID), data, q.retention)
+	}
+	if err != nil {
+		fmt.Printf("Failed to save query job %s: %v\n", job.ID, err)
+	}
+}
+
+// newID generates a random job ID
+func newID() (string, error) {
+	id := make([]byte, idLength)
+	max := big.NewInt(int64(len(idAlphabet)))
+	for i := range id {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		id[i] = idAlphabet[n.Int64()]
+	}
+	return string(id), nil
+}
@@ -0,0 +1,95 @@
+package queryjobs
+
+import (
+	"testing"
+	"time"
+)
+
+// waitFor polls a job until it leaves the queued and running states
+func waitFor(t *testing.T, q *Queue, owner string, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(owner, id)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if job.State == Completed || job.State == Failed {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestSubmitStoresResponse(t *testing.T) {
+	q := New(1, 1, time.Minute)
+	job, err := q.Submit("session:a", "postgresql", "SELECT * FROM large_orders", 1000000, func() (int, interface{}) {
+		return 200, map[string]interface{}{"valid": true}
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.State != Queued || job.EstimatedRows != 1000000 {
+		t.Errorf("unexpected submitted job: %+v", job)
+	}
+
+	done := waitFor(t, q, "session:a", job.ID)
+	if done.State != Completed || done.Status != 200 || string(done.Response) != `{"valid":true}` {
+		t.Errorf("unexpected finished job: %+v", done)
+	}
+	if done.StartedAt == nil || done.FinishedAt == nil {
+		t.Errorf("expected start and finish times, got %+v", done)
+	}
+}
+
+func TestGetChecksOwner(t *testing.T) {
+	q := New(1, 1, time.Minute)
+	job, err := q.Submit("session:a", "mysql", "SELECT 1", 1, func() (int, interface{}) {
+		return 200, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := q.Get("session:b", job.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for another owner, got %v", err)
+	}
+}
+
+func TestSubmitWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{}, 1)
+	blocking := func() (int, interface{}) {
+		started <- struct{}{}
+		<-release
+		return 200, nil
+	}
+
+	q := New(1, 1, time.Minute)
+	if _, err := q.Submit("session:a", "mysql", "SELECT 1", 1, blocking); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-started
+	if _, err := q.Submit("session:a", "mysql", "SELECT 2", 1, blocking); err != nil {
+		t.Fatalf("expected the second job to wait, got %v", err)
+	}
+	if _, err := q.Submit("session:a", "mysql", "SELECT 3", 1, blocking); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestPanicFailsJob(t *testing.T) {
+	q := New(1, 1, time.Minute)
+	job, err := q.Submit("session:a", "mysql", "SELECT 1", 1, func() (int, interface{}) {
+		panic("driver exploded")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := waitFor(t, q, "session:a", job.ID)
+	if done.State != Failed || done.Error != "driver exploded" {
+		t.Errorf("unexpected failed job: %+v", done)
+	}
+}
@@ -0,0 +1,159 @@
+package querylimits
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// Default time a statement may run on every dialect
+const DefaultTimeout = 5 * time.Second
+
+// Default memory a PostgreSQL sort or hash may use before spilling to disk
+const DefaultWorkMem = "4MB"
+
+// Longest timeout that may be configured
+const MaxTimeout = 10 * time.Minute
+
+// Deepest a MySQL recursive CTE may recurse, set as cte_max_recursion_depth
+// on every connection whatever the server's own setting
+const MySQLRecursionDepth = 1000
+
+// Limits bound the resources of a single statement on one dialect
+type Limits struct {
+	// Time the statement may run; zero leaves it unbounded
+	Timeout time.Duration
+	// PostgreSQL work_mem, such as "4MB"; empty keeps the server setting
+	WorkMem string
+}
+
+// PostgreSQL memory sizes: a number with an optional unit
+var workMemPattern = regexp.MustCompile(`^[0-9]+(kB|MB|GB)?$`)
+
+// Defaults returns the limits of every dialect before configuration
+func Defaults() map[string]Limits {
+	return map[string]Limits{
+		"sqlite":     {Timeout: DefaultTimeout},
+		"mysql":      {Timeout: DefaultTimeout},
+		"postgresql": {Timeout: DefaultTimeout, WorkMem: DefaultWorkMem},
+	}
+}
+
+// ParseTimeout reads a timeout such as "5s" or "1500ms". "0" disables the
+// timeout.
+func ParseTimeout(value string) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if timeout < time.Millisecond || timeout > MaxTimeout {
+		return 0, fmt.Errorf("timeouts must be between 1ms and %s", MaxTimeout)
+	}
+	return timeout, nil
+}
+
+// ValidWorkMem reports whether value is a PostgreSQL memory size. It is
+// pasted into SET LOCAL, which takes no placeholders.
+func ValidWorkMem(value string) bool {
+	return workMemPattern.MatchString(value)
+}
+
+// PostgresSettings returns the SET LOCAL statements applying limits inside
+// a transaction
+func PostgresSettings(limits Limits) []string {
+	settings := []string{}
+	if limits.Timeout > 0 {
+		settings = append(settings, fmt.Sprintf("SET LOCAL statement_timeout = %d", milliseconds(limits.Timeout)))
+	}
+	if limits.WorkMem != "" && ValidWorkMem(limits.WorkMem) {
+		settings = append(settings, fmt.Sprintf("SET LOCAL work_mem = '%s'", limits.WorkMem))
+	}
+	return settings
+}
+
+// Statements PostgreSQL refuses to run inside a transaction block, or
+// whose meaning changes inside one
+var outsideTransaction = map[string]bool{
+	"VACUUM":     true,
+	"BEGIN":      true,
+	"START":      true,
+	"COMMIT":     true,
+	"END":        true,
+	"ROLLBACK":   true,
+	"ABORT":      true,
+	"SAVEPOINT":  true,
+	"RELEASE":    true,
+	"PREPARE":    true,
+	"CHECKPOINT": true,
+}
+
+// Transactional reports whether a PostgreSQL statement can run inside the
+// transaction that carries SET LOCAL limits
+func Transactional(sql string) bool {
+	tokens := words(sql)
+	if len(tokens) == 0 {
+		return true
+	}
+	if outsideTransaction[tokens[0]] {
+		return false
+	}
+	switch tokens[0] {
+	case "CREATE", "DROP", "ALTER", "REINDEX":
+	default:
+		return true
+	}
+	// CREATE/DROP DATABASE and TABLESPACE, ALTER SYSTEM, and indexes built
+	// or dropped CONCURRENTLY
+	for i, token := range tokens {
+		if token == "CONCURRENTLY" || (i == 1 && (token == "DATABASE" || token == "TABLESPACE" || token == "SYSTEM")) {
+			return false
+		}
+	}
+	return true
+}
+
+// MySQLHint adds a MAX_EXECUTION_TIME optimizer hint to a SELECT so the
+// server aborts it after timeout. MySQL only honours the hint on SELECT,
+// so other statements, and SELECTs that already set it, are unchanged.
+func MySQLHint(sql string, timeout time.Duration) (string, bool) {
+	if timeout <= 0 || strings.Contains(strings.ToUpper(sql), "MAX_EXECUTION_TIME") {
+		return sql, false
+	}
+	for _, token := range sqlvalidator.Tokenize(sql, "mysql") {
+		if token.Kind == sqlvalidator.TokenComment {
+			continue
+		}
+		if !token.Is("SELECT") {
+			return sql, false
+		}
+		end := token.Offset + len(token.Text)
+		hint := fmt.Sprintf(" /*+ MAX_EXECUTION_TIME(%d) */", milliseconds(timeout))
+		return sql[:end] + hint + sql[end:], true
+	}
+	return sql, false
+}
+
+// words returns the upper-cased words of sql, without comments
+func words(sql string) []string {
+	result := []string{}
+	for _, token := range sqlvalidator.Tokenize(sql, "postgresql") {
+		if token.Kind == sqlvalidator.TokenWord {
+			result = append(result, strings.ToUpper(token.Text))
+		}
+	}
+	return result
+}
+
+// milliseconds returns a timeout in whole milliseconds, at least one
+func milliseconds(timeout time.Duration) int64 {
+	if ms := timeout.Milliseconds(); ms > 0 {
+		return ms
+	}
+	return 1
+}
@@ -0,0 +1,73 @@
+package querylimits
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMySQLHint(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"SELECT * FROM users", "SELECT /*+ MAX_EXECUTION_TIME(2500) */ * FROM users"},
+		{"-- latest\nselect id FROM users", "-- latest\nselect /*+ MAX_EXECUTION_TIME(2500) */ id FROM users"},
+		{"UPDATE users SET name = 'x'", "UPDATE users SET name = 'x'"},
+		{"SELECT /*+ MAX_EXECUTION_TIME(10) */ 1", "SELECT /*+ MAX_EXECUTION_TIME(10) */ 1"},
+		{"WITH t AS (SELECT 1) SELECT * FROM t", "WITH t AS (SELECT 1) SELECT * FROM t"},
+	}
+	for _, tt := range tests {
+		got, changed := MySQLHint(tt.input, 2500*time.Millisecond)
+		if got != tt.want || changed != (tt.input != tt.want) {
+			t.Errorf("MySQLHint(%q) = %q, %v; want %q", tt.input, got, changed, tt.want)
+		}
+	}
+
+	if _, changed := MySQLHint("SELECT 1", 0); changed {
+		t.Error("expected no hint without a timeout")
+	}
+}
+
+func TestPostgresSettings(t *testing.T) {
+	settings := PostgresSettings(Limits{Timeout: 3 * time.Second, WorkMem: "8MB"})
+	if len(settings) != 2 ||
+		settings[0] != "SET LOCAL statement_timeout = 3000" ||
+		settings[1] != "SET LOCAL work_mem = '8MB'" {
+		t.Errorf("unexpected settings %q", settings)
+	}
+
+	if settings := PostgresSettings(Limits{WorkMem: "8MB'; DROP TABLE users; --"}); len(settings) != 0 {
+		t.Errorf("expected an invalid work_mem to be left out, got %q", settings)
+	}
+}
+
+func TestTransactional(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT database FROM servers":                 true,
+		"CREATE TABLE t (id INT)":                      true,
+		"VACUUM ANALYZE users":                         false,
+		"CREATE INDEX CONCURRENTLY idx ON t (id)":      false,
+		"CREATE DATABASE scratch":                      false,
+		"/* tidy */ ALTER SYSTEM SET work_mem = '1MB'": false,
+		"COMMIT": false,
+	}
+	for sql, want := range tests {
+		if got := Transactional(sql); got != want {
+			t.Errorf("Transactional(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	if timeout, err := ParseTimeout("1500ms"); err != nil || timeout != 1500*time.Millisecond {
+		t.Errorf("unexpected result %v, %v", timeout, err)
+	}
+	if timeout, err := ParseTimeout("0"); err != nil || timeout != 0 {
+		t.Errorf("expected 0 to disable the timeout, got %v, %v", timeout, err)
+	}
+	for _, value := range []string{"soon", "-1s", "1h"} {
+		if _, err := ParseTimeout(value); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
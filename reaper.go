@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+)
+
+// Default reaper thresholds, overridden by REAPER_INTERVAL,
+// REAPER_IDLE_IN_TRANSACTION and REAPER_LONG_RUNNING
+const (
+	defaultReaperInterval          = 30 * time.Second
+	defaultReaperIdleInTransaction = 2 * time.Minute
+	defaultReaperLongRunning       = 10 * time.Minute
+)
+
+// reaperDuration reads a reaper threshold from the environment. Zero
+// disables the check.
+func reaperDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	if value == "0" {
+		return 0
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		return fallback
+	}
+	return duration
+}
+
+// configureReaper starts terminating MySQL and PostgreSQL sessions left
+// idle in a transaction or running for too long
+func configureReaper() {
+	dbmanager.StartReaper(dbmanager.ReaperSettings{
+		Interval:          reaperDuration("REAPER_INTERVAL", defaultReaperInterval),
+		IdleInTransaction: reaperDuration("REAPER_IDLE_IN_TRANSACTION", defaultReaperIdleInTransaction),
+		LongRunning:       reaperDuration("REAPER_LONG_RUNNING", defaultReaperLongRunning),
+	})
+}
+
+// getReaperReport returns the reaper thresholds and the sessions it
+// terminated, newest first
+func getReaperReport(c *gin.Context) {
+	settings, reaped := dbmanager.Reaper()
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                     settings.Interval > 0 && (settings.IdleInTransaction > 0 || settings.LongRunning > 0),
+		"interval_seconds":            settings.Interval.Seconds(),
+		"idle_in_transaction_seconds": settings.IdleInTransaction.Seconds(),
+		"long_running_seconds":        settings.LongRunning.Seconds(),
+		"terminated":                  reaped,
+	})
+}
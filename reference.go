@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/reference"
+)
+
+// listFunctions returns the built-in function catalog of a dialect,
+// optionally filtered by category or a search term
+func listFunctions(c *gin.Context) {
+	functions, err := reference.Functions(c.Param("dialect"), c.Query("category"), c.Query("q"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"dialect":   c.Param("dialect"),
+		"functions": functions,
+	})
+}
+
+// getFunction returns the documentation of a single function
+func getFunction(c *gin.Context) {
+	function, ok := reference.Lookup(c.Param("dialect"), c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "function not found",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, function)
+}
@@ -0,0 +1,77 @@
+package reference
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownDialect is returned for dialects without a reference catalog
+var ErrUnknownDialect = errors.New("no function reference for this dialect")
+
+// Function documents a built-in SQL function
+type Function struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Signature   string `json:"signature"`
+	Description string `json:"description"`
+	Example     string `json:"example"`
+}
+
+//go:embed data/*.json
+var dataFiles embed.FS
+
+// Function catalogs keyed by dialect
+var catalogs = make(map[string][]Function)
+
+func init() {
+	for _, dialect := range []string{"sqlite", "mysql", "postgresql"} {
+		data, err := dataFiles.ReadFile("data/" + dialect + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("missing function reference for %s: %v", dialect, err))
+		}
+		var functions []Function
+		if err := json.Unmarshal(data, &functions); err != nil {
+			panic(fmt.Sprintf("invalid function reference for %s: %v", dialect, err))
+		}
+		catalogs[dialect] = functions
+	}
+}
+
+// Functions returns the functions of a dialect, filtered by category and by
+// a case-insensitive search in their name, signature and description
+func Functions(dialect string, category string, query string) ([]Function, error) {
+	functions, ok := catalogs[dialect]
+	if !ok {
+		return nil, ErrUnknownDialect
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	result := []Function{}
+	for _, function := range functions {
+		if category != "" && !strings.EqualFold(function.Category, category) {
+			continue
+		}
+		if query != "" &&
+			!strings.Contains(strings.ToLower(function.Name), query) &&
+			!strings.Contains(strings.ToLower(function.Signature), query) &&
+			!strings.Contains(strings.ToLower(function.Description), query) {
+			continue
+		}
+		result = append(result, function)
+	}
+	return result, nil
+}
+
+// Lookup returns a function of a dialect by name, for hover documentation
+func Lookup(dialect string, name string) (*Function, bool) {
+	for _, function := range catalogs[dialect] {
+		if strings.EqualFold(function.Name, name) {
+			f := function
+			return &f, true
+		}
+	}
+	return nil, false
+}
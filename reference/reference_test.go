@@ -0,0 +1,47 @@
+package reference
+
+import (
+	"testing"
+)
+
+func TestEveryDialectHasACatalog(t *testing.T) {
+	for _, dialect := range []string{"sqlite", "mysql", "postgresql"} {
+		functions, err := Functions(dialect, "", "")
+		if err != nil || len(functions) == 0 {
+			t.Errorf("%s: expected functions, got %d, %v", dialect, len(functions), err)
+		}
+		for _, function := range functions {
+			if function.Name == "" || function.Signature == "" || function.Description == "" || function.Example == "" {
+				t.Errorf("%s: incomplete entry %+v", dialect, function)
+			}
+		}
+	}
+}
+
+func TestFunctionsFilters(t *testing.T) {
+	functions, _ := Functions("postgresql", "aggregate", "")
+	for _, function := range functions {
+		if function.Category != "aggregate" {
+			t.Errorf("unexpected category in %+v", function)
+		}
+	}
+
+	functions, _ = Functions("mysql", "", "separator")
+	if len(functions) == 0 {
+		t.Error("expected search to match signatures and descriptions")
+	}
+
+	if _, err := Functions("oracle", "", ""); err != ErrUnknownDialect {
+		t.Errorf("expected ErrUnknownDialect, got %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	function, ok := Lookup("sqlite", "group_concat")
+	if !ok || function.Name != "GROUP_CONCAT" {
+		t.Fatalf("expected GROUP_CONCAT, got %+v", function)
+	}
+	if _, ok := Lookup("sqlite", "string_agg"); ok {
+		t.Error("STRING_AGG is not a SQLite function")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/envelope"
+)
+
+// Version of the API contract answering in the response envelope
+const envelopeAPIVersion = "v2"
+
+// envelopeResponses wraps the JSON responses of the v1 handlers into the
+// envelope of v2, with data, error and meta, and answers failures with an
+// error status instead of 200. Other responses, such as event streams,
+// exports and downloads, are sent as they are.
+func envelopeResponses(c *gin.Context) {
+	start := time.Now()
+	writer := &envelopeWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+	if !writer.buffered {
+		return
+	}
+
+	status, body, _ := envelope.Wrap(writer.Status(), writer.body.Bytes(), map[string]interface{}{
+		"api_version": envelopeAPIVersion,
+		"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+	})
+	c.Writer.Header().Del("Content-Length")
+	c.Writer.WriteHeader(status)
+	c.Writer.Write(body)
+}
+
+// envelopeWriter holds back a JSON body so it can be wrapped once the
+// handler is done
+type envelopeWriter struct {
+	gin.ResponseWriter
+	// Whether the body is JSON and held back, decided when it starts
+	decided  bool
+	buffered bool
+	body     bytes.Buffer
+}
+
+// start decides whether to hold back the body from its content type
+func (w *envelopeWriter) start() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.buffered = !w.ResponseWriter.Written() && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	w.start()
+	if w.buffered {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers unless the body is held back
+func (w *envelopeWriter) WriteHeaderNow() {
+	if !w.buffered {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// Flush sends what has been written so far unless the body is held back
+func (w *envelopeWriter) Flush() {
+	if !w.buffered {
+		w.ResponseWriter.Flush()
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// Rows written to a download by default
+const defaultExportRowLimit = 100000
+
+// Most rows written to a download, from EXPORT_MAX_ROWS
+var exportRowLimit = defaultExportRowLimit
+
+// configureExports reads EXPORT_MAX_ROWS, the most rows a download of a
+// result in a format other than JSON contains
+func configureExports() {
+	exportRowLimit = defaultExportRowLimit
+	if value := os.Getenv("EXPORT_MAX_ROWS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			exportRowLimit = n
+		} else {
+			fmt.Printf("Ignoring EXPORT_MAX_ROWS: %q is not a positive number of rows\n", value)
+		}
+	}
+}
+
+// resultExport is the download of a query's result in a format other than
+// JSON, written to the client from the query's cursor instead of from the
+// rows a response shows
+type resultExport struct {
+	c      *gin.Context
+	format string
+	// Whether the download has started, after which a failure can no
+	// longer be answered as JSON
+	started bool
+}
+
+// exportScoped runs a query that returns rows for a download, rewritten and
+// limited as executeScoped runs it except for the row limit of displayed
+// results. CSV and NDJSON rows are written as the
+// cursor returns them; the other formats need every row before they are
+// written. Either way the download stops after exportRowLimit rows. A query
+// that fails before its first row is answered as JSON.
+func exportScoped(ctx context.Context, owner string, db *sql.DB, req SQLValidationRequest, statementSQL string, scopeRewrites []sqlvalidator.Rewrite) gin.H {
+	executedSQL, rewrites := rewriteForExport(statementSQL, req.Dialect)
+	rewrites = append(scopeRewrites, rewrites...)
+
+	ctx, cancel := queryContext(ctx, req.Dialect)
+	defer cancel()
+
+	start := time.Now()
+	finished := watchLongRunning(owner, req.Dialect, executedSQL)
+	err := exportQuery(ctx, db, executedSQL, req)
+	finished()
+	recordQueryTiming(owner, db, req.Dialect, executedSQL, time.Since(start), err)
+	if err != nil && !req.export.started {
+		response := withRewrites(rewrittenErrorResponse("Query execution error: ", err, req.SQL, executedSQL), executedSQL, rewrites)
+		return withIsolationLevel(response, req.IsolationLevel)
+	}
+	if err != nil {
+		fmt.Printf("Failed to write %s result: %v\n", req.export.format, err)
+	}
+	return gin.H{"valid": true, "exported": true}
+}
+
+// exportQuery runs a query in a transaction under its dialect's limits and
+// writes its rows to the download
+func exportQuery(ctx context.Context, db *sql.DB, query string, req SQLValidationRequest) error {
+	tx, query, err := beginLimited(ctx, db, query, req.Dialect, txOptions(req))
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	scanner, result, err := newRowScanner(rows, req.Dialect)
+	if err != nil {
+		return err
+	}
+
+	switch req.export.format {
+	case formatCSV, formatNDJSON:
+		err = req.export.stream(scanner, result, req)
+	default:
+		for len(result.Rows) < exportRowLimit {
+			row, err := scanner.next()
+			if err != nil {
+				return err
+			}
+			if row == nil {
+				break
+			}
+			result.Rows = append(result.Rows, row)
+		}
+		if req.RawJSON {
+			jsonColumnsAsText([]*QueryResult{result})
+		}
+		req.export.started = true
+		writeExecuteResponse(req.export.c, http.StatusOK, gin.H{"valid": true, "result": result}, req)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// stream writes the rows of a CSV or NDJSON download as they are read. The
+// first row is read before anything is written, so a query that fails at
+// once is still answered as JSON.
+func (e *resultExport) stream(scanner *rowScanner, result *QueryResult, req SQLValidationRequest) error {
+	row, err := scanner.next()
+	if err != nil {
+		return err
+	}
+
+	e.started = true
+	e.c.Writer.Header().Add("Vary", "Accept")
+	setFormatHeaders(e.c, e.format, req)
+	e.c.Status(http.StatusOK)
+	out := bufio.NewWriter(e.c.Writer)
+
+	var write func(row []interface{}) error
+	if e.format == formatCSV {
+		w := csv.NewWriter(out)
+		if err := w.Write(result.Columns); err != nil {
+			return err
+		}
+		record := make([]string, len(result.Columns))
+		write = func(row []interface{}) error {
+			if err := writeCSVRow(w, record, row); err != nil {
+				return err
+			}
+			w.Flush()
+			return w.Error()
+		}
+	} else {
+		names, err := ndjsonNames(result.Columns)
+		if err != nil {
+			return err
+		}
+		write = func(row []interface{}) error {
+			return writeNDJSONRow(out, names, row)
+		}
+	}
+
+	asText := []*QueryResult{{JSONColumns: result.JSONColumns}}
+	for count := 0; row != nil && count < exportRowLimit; count++ {
+		if req.RawJSON {
+			asText[0].Rows = [][]interface{}{row}
+			jsonColumnsAsText(asText)
+		}
+		if err := write(row); err != nil {
+			return err
+		}
+		if row, err = scanner.next(); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
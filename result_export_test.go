@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportRequest builds a download request in a format, recording what is
+// written to the client
+func exportRequest(query string, format string) (SQLValidationRequest, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/execute", nil)
+	req := SQLValidationRequest{SQL: query, Dialect: "sqlite"}
+	req.export = &resultExport{c: c, format: format}
+	return req, w
+}
+
+func TestCSVExportStreamsRowsUpToTheCap(t *testing.T) {
+	exportRowLimit = 150
+	t.Cleanup(func() { exportRowLimit = defaultExportRowLimit })
+	db := openMemoryDB(t)
+	query := "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 200) SELECT x FROM n"
+	req, w := exportRequest(query, formatCSV)
+
+	response := exportScoped(context.Background(), "session:export", db, req, query, nil)
+	if response["exported"] != true {
+		t.Fatalf("expected the rows to be written, got %v", response)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 151 || lines[0] != "x" || lines[150] != "150" {
+		t.Errorf("expected a header and 150 rows, got %d lines ending %q", len(lines), lines[len(lines)-1])
+	}
+	if got := w.Header().Get("Content-Type"); got != formatCSV+"; charset=utf-8" {
+		t.Errorf("unexpected content type %q", got)
+	}
+}
+
+func TestExportFailingBeforeItsRowsAnswersWithJSON(t *testing.T) {
+	db := openMemoryDB(t)
+	query := "SELECT * FROM missing"
+	req, w := exportRequest(query, formatNDJSON)
+
+	response := exportScoped(context.Background(), "session:export", db, req, query, nil)
+	if response["exported"] == true || response["error"] == nil {
+		t.Fatalf("expected an error response, got %v", response)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("expected nothing to be written, got %q", w.Body.String())
+	}
+}
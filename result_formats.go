@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/negotiate"
+	"example/user/playground/resultvalues"
+	"example/user/playground/sqlinsert"
+	"example/user/playground/sqlvalidator"
+	"example/user/playground/texttable"
+	"example/user/playground/xlsx"
+)
+
+// Formats the execute endpoint can answer in, JSON first as the default
+const (
+	formatJSON     = "application/json"
+	formatCSV      = "text/csv"
+	formatNDJSON   = "application/x-ndjson"
+	formatXLSX     = xlsx.ContentType
+	formatMarkdown = "text/markdown"
+	formatASCII    = "text/plain"
+	formatInsert   = "application/sql"
+)
+
+// Formats a request can ask for with its format field, by name
+var namedFormats = map[string]string{
+	"markdown": formatMarkdown,
+	"ascii":    formatASCII,
+	"insert":   formatInsert,
+}
+
+// InsertOptions shape the INSERT statements of the insert format
+type InsertOptions struct {
+	// Table to insert into; "results" by default
+	Table string `json:"table"`
+	// Dialect to write the statements for; the query's dialect by default
+	Dialect string `json:"dialect"`
+	// Rows per statement; 100 by default
+	BatchSize int `json:"batchSize"`
+}
+
+// checkResultFormat returns the response rejecting a request's format
+// field or its insert options, or nil when they can be used
+func checkResultFormat(req SQLValidationRequest) gin.H {
+	reject := func(message string) gin.H {
+		return gin.H{
+			"valid":     false,
+			"error":     message,
+			"errorCode": dberrors.CodeValidationError,
+		}
+	}
+	if _, ok := namedFormats[req.Format]; req.Format != "" && !ok {
+		return reject(fmt.Sprintf("Unsupported format %q: results can be formatted as markdown, ascii or insert", req.Format))
+	}
+	if req.Insert == nil {
+		return nil
+	}
+	if req.Format != "insert" {
+		return reject(`insert options need "format": "insert"`)
+	}
+	if _, ok := sqlvalidator.Capabilities(req.Insert.Dialect); req.Insert.Dialect != "" && !ok {
+		return reject("Unsupported SQL dialect for INSERT statements: " + req.Insert.Dialect)
+	}
+	if req.Insert.BatchSize < 0 || req.Insert.BatchSize > sqlinsert.MaxBatchSize {
+		return reject(fmt.Sprintf("insert.batchSize must be between 1 and %d", sqlinsert.MaxBatchSize))
+	}
+	return nil
+}
+
+// writeExecuteResponse sends an execute response in the format the request's
+// format field or else its Accept header asks for. Query results can be sent
+// as CSV, as one JSON object per row, as an XLSX workbook whose sheet and
+// file are named after the query, as a Markdown or ASCII table, or as
+// INSERT statements; errors and responses without a result stay JSON.
+func writeExecuteResponse(c *gin.Context, status int, response gin.H, req SQLValidationRequest) {
+	// A download has been written while its rows were read
+	if response["exported"] == true {
+		return
+	}
+	c.Writer.Header().Add("Vary", "Accept")
+	format := resultFormat(c, req)
+	if format == "" {
+		c.JSON(http.StatusNotAcceptable, gin.H{
+			"valid": false,
+			"error": "Results can be returned as application/json, text/csv, application/x-ndjson or " + formatXLSX,
+		})
+		return
+	}
+
+	result, ok := response["result"].(*QueryResult)
+	if format == formatJSON || status != http.StatusOK || !ok || result == nil {
+		c.JSON(status, response)
+		return
+	}
+
+	if format == formatInsert {
+		writeInsertStatements(c, status, result, req)
+		return
+	}
+
+	setFormatHeaders(c, format, req)
+	c.Status(status)
+	out := bufio.NewWriter(c.Writer)
+	var err error
+	switch format {
+	case formatCSV:
+		err = writeCSV(out, result)
+	case formatXLSX:
+		err = xlsx.Write(out, req.Name, result.Columns, result.Rows)
+	case formatMarkdown:
+		err = texttable.Markdown(out, textTable(result))
+	case formatASCII:
+		err = texttable.ASCII(out, textTable(result))
+	default:
+		err = writeNDJSON(out, result)
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		fmt.Printf("Failed to write %s result: %v\n", format, err)
+	}
+}
+
+// resultFormat returns the format the request's format field or else its
+// Accept header asks for, or "" when none can be sent
+func resultFormat(c *gin.Context, req SQLValidationRequest) string {
+	if format := namedFormats[req.Format]; format != "" {
+		return format
+	}
+	return negotiate.Choose(c.GetHeader("Accept"), formatJSON, formatCSV, formatNDJSON, formatXLSX)
+}
+
+// setFormatHeaders sets the content type of a result in a format, naming
+// the file of XLSX downloads after the query
+func setFormatHeaders(c *gin.Context, format string, req SQLValidationRequest) {
+	if format == formatXLSX {
+		sheet := xlsx.SheetName(req.Name)
+		c.Header("Content-Type", format)
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": sheet + ".xlsx"}))
+		return
+	}
+	c.Header("Content-Type", format+"; charset=utf-8")
+}
+
+// writeCSV writes a result as CSV with a header row
+func writeCSV(out *bufio.Writer, result *QueryResult) error {
+	w := csv.NewWriter(out)
+	if err := w.Write(result.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		if err := writeCSVRow(w, record, row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeCSVRow writes a row as a CSV record, reusing record for its fields
+func writeCSVRow(w *csv.Writer, record []string, row []interface{}) error {
+	for i := range record {
+		record[i] = ""
+		if i < len(row) {
+			record[i] = csvValue(row[i])
+		}
+	}
+	return w.Write(record)
+}
+
+// csvValue formats a value for CSV; NULL becomes an empty field
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case json.RawMessage:
+		return string(v)
+	case []interface{}, *resultvalues.Range, *resultvalues.Geometry:
+		// Arrays, ranges and geometries are written as JSON
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeInsertStatements sends a result as INSERT statements for the dialect
+// of the insert options, or for the query's own
+func writeInsertStatements(c *gin.Context, status int, result *QueryResult, req SQLValidationRequest) {
+	opts := sqlinsert.Options{Dialect: dbmanager.BaseDialect(req.Dialect), SourceDialect: dbmanager.BaseDialect(req.Dialect)}
+	if req.Insert != nil {
+		opts.Table = req.Insert.Table
+		opts.BatchSize = req.Insert.BatchSize
+		if req.Insert.Dialect != "" {
+			opts.Dialect = req.Insert.Dialect
+		}
+	}
+	statements, err := sqlinsert.Statements(result.Columns, result.Rows, opts)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"valid":     false,
+			"error":     "The result cannot be written as INSERT statements: " + err.Error(),
+			"errorCode": dberrors.CodeValidationError,
+		})
+		return
+	}
+	c.Data(status, formatInsert+"; charset=utf-8", []byte(statements))
+}
+
+// textTable formats the cells of a result for a text table, writing NULL
+// as NULL and aligning numeric columns right
+func textTable(result *QueryResult) texttable.Table {
+	table := texttable.Table{
+		Columns:    result.Columns,
+		Rows:       make([][]string, len(result.Rows)),
+		RightAlign: make([]bool, len(result.Columns)),
+	}
+	for i := range table.RightAlign {
+		table.RightAlign[i] = len(result.Rows) > 0
+	}
+	for r, row := range result.Rows {
+		cells := make([]string, len(result.Columns))
+		for i := range cells {
+			var value interface{}
+			if i < len(row) {
+				value = row[i]
+			}
+			switch value.(type) {
+			case nil:
+				cells[i] = "NULL"
+				continue
+			case int, int32, int64, uint64, float32, float64, json.Number:
+			default:
+				table.RightAlign[i] = false
+			}
+			cells[i] = csvValue(value)
+		}
+		table.Rows[r] = cells
+	}
+	return table
+}
+
+// writeNDJSON writes each row as a JSON object keyed by column, one per
+// line, keeping the columns in result order
+func writeNDJSON(out *bufio.Writer, result *QueryResult) error {
+	names, err := ndjsonNames(result.Columns)
+	if err != nil {
+		return err
+	}
+	for _, row := range result.Rows {
+		if err := writeNDJSONRow(out, names, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ndjsonNames encodes the column names of NDJSON objects
+func ndjsonNames(columns []string) ([][]byte, error) {
+	names := make([][]byte, len(columns))
+	for i, column := range columns {
+		name, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = name
+	}
+	return names, nil
+}
+
+// writeNDJSONRow writes a row as a JSON object keyed by the encoded names
+// on a line of its own
+func writeNDJSONRow(out *bufio.Writer, names [][]byte, row []interface{}) error {
+	out.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		out.Write(name)
+		out.WriteByte(':')
+		var value interface{}
+		if i < len(row) {
+			value = row[i]
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		out.Write(encoded)
+	}
+	_, err := out.WriteString("}\n")
+	return err
+}
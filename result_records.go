@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/features"
+	"example/user/playground/queryjobs"
+	"example/user/playground/resultstore"
+)
+
+// storedResult is the result of a stored query response, keeping each
+// value as it was encoded
+type storedResult struct {
+	Columns []string            `json:"columns"`
+	Rows    [][]json.RawMessage `json:"rows"`
+}
+
+// getResultRow returns one row of a retained query result, or of the
+// result of a finished background query, as an object keyed by column in
+// column order, for a record view that needs neither the whole result nor
+// a second run of the query. Rows are numbered from 0.
+func getResultRow(c *gin.Context) {
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil || n < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid row: rows are numbered from 0"})
+		return
+	}
+	result, ok := loadStoredResult(c, c.Param("queryId"))
+	if !ok {
+		return
+	}
+	if n >= len(result.Rows) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":    fmt.Sprintf("The result has %d rows", len(result.Rows)),
+			"rowCount": len(result.Rows),
+		})
+		return
+	}
+
+	record, err := orderedRecord(result.Columns, result.Rows[n])
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode the row: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"queryId":  c.Param("queryId"),
+		"row":      n,
+		"rowCount": len(result.Rows),
+		"columns":  result.Columns,
+		"record":   record,
+	})
+}
+
+// loadStoredResult returns the retained result with an ID, or else the
+// result of the background query with it, answering the request itself
+// when there is neither
+func loadStoredResult(c *gin.Context, id string) (*storedResult, bool) {
+	owner := sessionOwner(c)
+	if retainedResults != nil && features.Enabled(c.Request.Context(), features.ResultRetention) {
+		retained, err := retainedResults.Get(owner, id)
+		if err == nil {
+			result := &storedResult{Columns: retained.Columns, Rows: make([][]json.RawMessage, len(retained.Rows))}
+			for r, row := range retained.Rows {
+				result.Rows[r] = make([]json.RawMessage, len(row))
+				for i, value := range row {
+					if result.Rows[r][i], err = json.Marshal(value); err != nil {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode the row: " + err.Error()})
+						return nil, false
+					}
+				}
+			}
+			return result, true
+		}
+		if err != resultstore.ErrNotFound {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the query result: " + err.Error()})
+			return nil, false
+		}
+	}
+
+	if asyncQueries == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": queryjobs.ErrNotFound.Error()})
+		return nil, false
+	}
+	job, err := asyncQueries.Get(owner, id)
+	if err == queryjobs.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the query: " + err.Error()})
+		return nil, false
+	}
+	if job.State != queryjobs.Completed {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "The query has no result yet",
+			"state": job.State,
+		})
+		return nil, false
+	}
+
+	var response struct {
+		Result *storedResult `json:"result"`
+	}
+	if err := json.Unmarshal(job.Response, &response); err != nil || response.Result == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The query returned no result"})
+		return nil, false
+	}
+	return response.Result, true
+}
+
+// orderedRecord encodes a row as a JSON object keyed by column, keeping
+// the columns in result order. A column named twice keeps its first value,
+// as JSON readers would keep only one of them.
+func orderedRecord(columns []string, row []json.RawMessage) (json.RawMessage, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	seen := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		if seen[column] {
+			continue
+		}
+		seen[column] = true
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		name, err := json.Marshal(column)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		if i < len(row) && len(row[i]) > 0 {
+			b.Write(row[i])
+		} else {
+			b.WriteString("null")
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
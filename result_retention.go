@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/features"
+	"example/user/playground/resultcompare"
+	"example/user/playground/resultstore"
+)
+
+// Rows of a retained result returned per page by default
+const defaultResultPage = 100
+
+// Rows of a result read for retention by default
+const defaultRetainedRowLimit = 10000
+
+var (
+	// Results of recent queries, set by configureResultRetention
+	retainedResults *resultstore.Store
+
+	// Most rows of a result that are read for retention, from
+	// RESULT_RETENTION_MAX_ROWS
+	retainedRowLimit = defaultRetainedRowLimit
+)
+
+// configureResultRetention reads RESULT_RETENTION, how long the result of a
+// query is kept after it ran, RESULT_RETENTION_MAX_BYTES, the largest
+// encoded result that is kept at all, and RESULT_RETENTION_MAX_ROWS, the
+// most rows of a result that are kept
+func configureResultRetention() {
+	retention := resultstore.DefaultRetention
+	if value := os.Getenv("RESULT_RETENTION"); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			retention = d
+		} else {
+			fmt.Printf("Ignoring RESULT_RETENTION: %q is not a positive duration such as 1h\n", value)
+		}
+	}
+	maxBytes := resultstore.DefaultMaxBytes
+	if value := os.Getenv("RESULT_RETENTION_MAX_BYTES"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			maxBytes = n
+		} else {
+			fmt.Printf("Ignoring RESULT_RETENTION_MAX_BYTES: %q is not a positive number of bytes\n", value)
+		}
+	}
+	retainedRowLimit = defaultRetainedRowLimit
+	if value := os.Getenv("RESULT_RETENTION_MAX_ROWS"); value != "" {
+		if n, err := strconv.Atoi(value); err == nil && n > 0 {
+			retainedRowLimit = n
+		} else {
+			fmt.Printf("Ignoring RESULT_RETENTION_MAX_ROWS: %q is not a positive number of rows\n", value)
+		}
+	}
+	retainedResults = resultstore.New(retention, maxBytes)
+}
+
+// retaining reports whether the results of queries are kept
+func retaining() bool {
+	return retainedResults != nil && features.Enabled(context.Background(), features.ResultRetention)
+}
+
+// retainResult keeps the result of a successful execution and adds its
+// queryId to the response. Results too large to keep are answered without
+// one.
+func retainResult(owner string, req SQLValidationRequest, response gin.H) gin.H {
+	result, ok := response["result"].(*QueryResult)
+	if !ok || result == nil || !retaining() {
+		return response
+	}
+	rows := result.Rows
+	if result.retainedRows != nil {
+		rows = result.retainedRows
+	}
+	retained := &resultstore.Result{
+		Dialect: req.Dialect,
+		SQL:     req.SQL,
+		Columns: result.Columns,
+		Rows:    rows,
+	}
+	if err := retainedResults.Save(owner, retained); err != nil {
+		if err != resultstore.ErrTooLarge {
+			fmt.Printf("Failed to retain query result: %v\n", err)
+		}
+		return response
+	}
+	response["queryId"] = retained.ID
+	return response
+}
+
+// loadRetainedResult returns a retained result of the session, answering
+// the request itself when there is none
+func loadRetainedResult(c *gin.Context, id string) (*resultstore.Result, bool) {
+	result, err := retainedResults.Get(sessionOwner(c), id)
+	if err == resultstore.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the query result: " + err.Error()})
+		return nil, false
+	}
+	return result, true
+}
+
+// getRetainedResult returns a page of a retained result, selected by the
+// offset and limit query parameters, without running the query again
+func getRetainedResult(c *gin.Context) {
+	offset, limit := 0, defaultResultPage
+	if value := c.Query("offset"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset: it must be 0 or more"})
+			return
+		}
+		offset = n
+	}
+	if value := c.Query("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit: it must be 1 or more"})
+			return
+		}
+		limit = n
+	}
+
+	result, ok := loadRetainedResult(c, c.Param("queryId"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"queryId":   result.ID,
+		"dialect":   result.Dialect,
+		"sql":       result.SQL,
+		"columns":   result.Columns,
+		"rows":      result.Page(offset, limit),
+		"offset":    offset,
+		"rowCount":  len(result.Rows),
+		"createdAt": result.CreatedAt,
+		"expiresAt": result.ExpiresAt,
+	})
+}
+
+// exportRetainedResult sends a retained result in any format of the execute
+// endpoint: the one named by the format query parameter, or else the one
+// the Accept header asks for. The name, table, targetDialect and batchSize
+// parameters match the execute request's fields.
+func exportRetainedResult(c *gin.Context) {
+	result, ok := loadRetainedResult(c, c.Param("queryId"))
+	if !ok {
+		return
+	}
+	req := SQLValidationRequest{
+		SQL:     result.SQL,
+		Dialect: result.Dialect,
+		Name:    c.Query("name"),
+		Format:  c.Query("format"),
+	}
+	if req.Format == "insert" {
+		batchSize, _ := strconv.Atoi(c.Query("batchSize"))
+		req.Insert = &InsertOptions{
+			Table:     c.Query("table"),
+			Dialect:   c.Query("targetDialect"),
+			BatchSize: batchSize,
+		}
+	}
+	if response := checkResultFormat(req); response != nil {
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+	writeExecuteResponse(c, http.StatusOK, gin.H{
+		"valid":   true,
+		"queryId": result.ID,
+		"result":  &QueryResult{Columns: result.Columns, Rows: result.Rows},
+	}, req)
+}
+
+// diffRetainedResults compares a retained result with a later one, such as
+// a run of the same query after a change, reporting the rows each lacks.
+// Rows are compared in order unless ignoreRowOrder is set, and columns by
+// position unless ignoreColumnOrder is set.
+func diffRetainedResults(c *gin.Context) {
+	earlier, ok := loadRetainedResult(c, c.Param("queryId"))
+	if !ok {
+		return
+	}
+	later, ok := loadRetainedResult(c, c.Param("otherId"))
+	if !ok {
+		return
+	}
+	report := resultcompare.Compare(
+		&resultcompare.ResultSet{Columns: earlier.Columns, Rows: earlier.Rows},
+		&resultcompare.ResultSet{Columns: later.Columns, Rows: later.Rows},
+		resultcompare.Options{
+			IgnoreRowOrder:    c.Query("ignoreRowOrder") == "true",
+			IgnoreColumnOrder: c.Query("ignoreColumnOrder") == "true",
+		},
+	)
+	c.JSON(http.StatusOK, gin.H{
+		"queryId":      earlier.ID,
+		"otherQueryId": later.ID,
+		"diff":         report,
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/features"
+	"example/user/playground/resultstore"
+)
+
+// retainResults turns result retention on for a test
+func retainResults(t *testing.T) {
+	t.Helper()
+	if err := features.Configure(features.ResultRetention); err != nil {
+		t.Fatal(err)
+	}
+	retainedResults = resultstore.New(time.Hour, 0)
+	t.Cleanup(func() {
+		retainedResults = nil
+		features.Configure("")
+	})
+}
+
+func TestRetainedResultKeepsRowsBeyondTheResponse(t *testing.T) {
+	retainResults(t)
+	db := openMemoryDB(t)
+	query := "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 25) SELECT x FROM n"
+	req := SQLValidationRequest{SQL: query, Dialect: "sqlite"}
+
+	response := executeScoped(context.Background(), "session:retained", db, req, query, nil, nil, nil)
+	if result := response["result"].(*QueryResult); len(result.Rows) != shownRows {
+		t.Fatalf("expected the response to show %d rows, got %d", shownRows, len(result.Rows))
+	}
+	queryID, ok := response["queryId"].(string)
+	if !ok {
+		t.Fatalf("expected the result to be retained, got %v", response)
+	}
+
+	gin.SetMode(gin.TestMode)
+	seen := []float64{}
+	for offset := 0; offset < 30; offset += 10 {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/result/%s?limit=10&offset=%d", queryID, offset), nil)
+		c.Request.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "retained"})
+		c.Params = gin.Params{{Key: "queryId", Value: queryID}}
+		getRetainedResult(c)
+
+		var page struct {
+			Rows     [][]float64 `json:"rows"`
+			RowCount int         `json:"rowCount"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		if page.RowCount != 25 {
+			t.Fatalf("expected 25 retained rows, got %d", page.RowCount)
+		}
+		for _, row := range page.Rows {
+			seen = append(seen, row[0])
+		}
+	}
+	if len(seen) != 25 || seen[0] != 1 || seen[24] != 25 {
+		t.Errorf("expected paging to return rows 1 to 25, got %v", seen)
+	}
+}
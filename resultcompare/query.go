@@ -0,0 +1,45 @@
+package resultcompare
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Queryer is satisfied by *sql.DB, *sql.Conn and *sql.Tx
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Query runs a statement and reads up to maxRows normalized rows.
+// Statements that return no rows yield an empty result.
+func Query(ctx context.Context, q Queryer, query string, maxRows int) (*ResultSet, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ResultSet{Columns: columns, Rows: [][]interface{}{}}
+	for len(result.Rows) < maxRows && rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make([]interface{}, len(columns))
+		for i, value := range values {
+			row[i] = Normalize(value)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
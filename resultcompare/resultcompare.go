@@ -0,0 +1,272 @@
+package resultcompare
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Most differing rows listed in a report
+const maxReportedRows = 20
+
+// ResultSet is a query result. Values are normalized by Query; sets built
+// by hand may hold numbers, strings, booleans and nil.
+type ResultSet struct {
+	Columns []string        `json:"columns" yaml:"columns"`
+	Rows    [][]interface{} `json:"rows" yaml:"rows"`
+}
+
+// Options control how strictly two result sets are compared
+type Options struct {
+	// Match columns by name instead of by position
+	IgnoreColumnOrder bool `json:"ignore_column_order" yaml:"ignore_column_order"`
+	// Compare rows as a multiset instead of in order
+	IgnoreRowOrder bool `json:"ignore_row_order" yaml:"ignore_row_order"`
+	// Largest absolute difference at which two numbers are equal
+	FloatTolerance float64 `json:"float_tolerance" yaml:"float_tolerance"`
+}
+
+// Report describes how an actual result differs from the expected one
+type Report struct {
+	Match bool `json:"match"`
+	// Partial credit between 0 and 1
+	Score        float64 `json:"score"`
+	Message      string  `json:"message"`
+	ColumnsMatch bool    `json:"columns_match"`
+	// Expected column names that the actual result lacks, when matching by name
+	MissingColumns []string `json:"missing_columns,omitempty"`
+	// Actual column names that were not expected, when matching by name
+	ExtraColumns []string `json:"extra_columns,omitempty"`
+	ExpectedRows int      `json:"expected_rows"`
+	ActualRows   int      `json:"actual_rows"`
+	MatchedRows  int      `json:"matched_rows"`
+	// Expected rows without a counterpart, up to maxReportedRows
+	MissingRows [][]interface{} `json:"missing_rows,omitempty"`
+	// Actual rows that were not expected, up to maxReportedRows
+	UnexpectedRows [][]interface{} `json:"unexpected_rows,omitempty"`
+}
+
+// Compare grades actual against expected. Without IgnoreColumnOrder,
+// columns are compared by position and their names are ignored.
+func Compare(expected *ResultSet, actual *ResultSet, opts Options) *Report {
+	report := &Report{
+		ExpectedRows: len(expected.Rows),
+		ActualRows:   len(actual.Rows),
+	}
+
+	actualRows, ok := alignColumns(expected, actual, opts, report)
+	if !ok {
+		report.Message = columnMessage(expected, actual, report)
+		return report
+	}
+	report.ColumnsMatch = true
+
+	if opts.IgnoreRowOrder {
+		matchUnordered(expected.Rows, actualRows, opts, report)
+	} else {
+		matchOrdered(expected.Rows, actualRows, opts, report)
+	}
+
+	total := len(expected.Rows)
+	if len(actualRows) > total {
+		total = len(actualRows)
+	}
+	if total == 0 {
+		report.Score = 1
+	} else {
+		report.Score = float64(report.MatchedRows) / float64(total)
+	}
+	report.Match = report.MatchedRows == len(expected.Rows) && len(actualRows) == len(expected.Rows)
+
+	switch {
+	case report.Match:
+		report.Message = "Results match"
+	case len(actualRows) != len(expected.Rows):
+		report.Message = fmt.Sprintf("Expected %d rows, got %d (%d matching)", len(expected.Rows), len(actualRows), report.MatchedRows)
+	default:
+		report.Message = fmt.Sprintf("%d of %d rows match", report.MatchedRows, len(expected.Rows))
+	}
+	return report
+}
+
+// columnCount returns the number of columns of a result set, taken from
+// its first row when no column names are given
+func columnCount(set *ResultSet) int {
+	if len(set.Columns) == 0 && len(set.Rows) > 0 {
+		return len(set.Rows[0])
+	}
+	return len(set.Columns)
+}
+
+// alignColumns returns the actual rows with their values in the order of
+// the expected columns, or false when the columns cannot be aligned
+func alignColumns(expected *ResultSet, actual *ResultSet, opts Options, report *Report) ([][]interface{}, bool) {
+	if !opts.IgnoreColumnOrder || len(expected.Columns) == 0 {
+		return actual.Rows, columnCount(expected) == columnCount(actual)
+	}
+
+	positions := make(map[string]int)
+	for i, name := range actual.Columns {
+		positions[strings.ToLower(name)] = i
+	}
+
+	order := make([]int, len(expected.Columns))
+	used := make(map[int]bool)
+	for i, name := range expected.Columns {
+		position, found := positions[strings.ToLower(name)]
+		if !found {
+			report.MissingColumns = append(report.MissingColumns, name)
+			continue
+		}
+		order[i] = position
+		used[position] = true
+	}
+	for i, name := range actual.Columns {
+		if !used[i] {
+			report.ExtraColumns = append(report.ExtraColumns, name)
+		}
+	}
+	if len(report.MissingColumns) > 0 || len(report.ExtraColumns) > 0 {
+		return nil, false
+	}
+
+	rows := make([][]interface{}, len(actual.Rows))
+	for i, row := range actual.Rows {
+		aligned := make([]interface{}, len(order))
+		for j, position := range order {
+			if position < len(row) {
+				aligned[j] = row[position]
+			}
+		}
+		rows[i] = aligned
+	}
+	return rows, true
+}
+
+// columnMessage explains why the columns of two results differ
+func columnMessage(expected *ResultSet, actual *ResultSet, report *Report) string {
+	if len(report.MissingColumns) > 0 {
+		return "Missing columns: " + strings.Join(report.MissingColumns, ", ")
+	}
+	if len(report.ExtraColumns) > 0 {
+		return "Unexpected columns: " + strings.Join(report.ExtraColumns, ", ")
+	}
+	return fmt.Sprintf("Expected %d columns, got %d", columnCount(expected), columnCount(actual))
+}
+
+// matchOrdered pairs rows by position
+func matchOrdered(expected [][]interface{}, actual [][]interface{}, opts Options, report *Report) {
+	for i, row := range expected {
+		if i < len(actual) && rowsEqual(row, actual[i], opts.FloatTolerance) {
+			report.MatchedRows++
+			continue
+		}
+		report.addMissing(row)
+		if i < len(actual) {
+			report.addUnexpected(actual[i])
+		}
+	}
+	for i := len(expected); i < len(actual); i++ {
+		report.addUnexpected(actual[i])
+	}
+}
+
+// matchUnordered pairs each expected row with the first equal actual row
+// that is not yet taken
+func matchUnordered(expected [][]interface{}, actual [][]interface{}, opts Options, report *Report) {
+	taken := make([]bool, len(actual))
+	for _, row := range expected {
+		found := false
+		for i, candidate := range actual {
+			if !taken[i] && rowsEqual(row, candidate, opts.FloatTolerance) {
+				taken[i] = true
+				found = true
+				break
+			}
+		}
+		if found {
+			report.MatchedRows++
+		} else {
+			report.addMissing(row)
+		}
+	}
+	for i, row := range actual {
+		if !taken[i] {
+			report.addUnexpected(row)
+		}
+	}
+}
+
+func (r *Report) addMissing(row []interface{}) {
+	if len(r.MissingRows) < maxReportedRows {
+		r.MissingRows = append(r.MissingRows, row)
+	}
+}
+
+func (r *Report) addUnexpected(row []interface{}) {
+	if len(r.UnexpectedRows) < maxReportedRows {
+		r.UnexpectedRows = append(r.UnexpectedRows, row)
+	}
+}
+
+// rowsEqual compares two rows value by value
+func rowsEqual(a []interface{}, b []interface{}, tolerance float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !valuesEqual(a[i], b[i], tolerance) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares two values after normalization, numerically when
+// both are numbers
+func valuesEqual(a interface{}, b interface{}, tolerance float64) bool {
+	na, nb := Normalize(a), Normalize(b)
+	if na == nil || nb == nil {
+		return na == nil && nb == nil
+	}
+
+	sa, sb := na.(string), nb.(string)
+	if sa == sb {
+		return true
+	}
+	fa, errA := strconv.ParseFloat(sa, 64)
+	fb, errB := strconv.ParseFloat(sb, 64)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return math.Abs(fa-fb) <= tolerance
+}
+
+// Normalize renders a value as text so results from different drivers and
+// hand-written expectations compare equal: numbers lose trailing zeros,
+// booleans become 1 or 0 and NULL stays nil
+func Normalize(value interface{}) interface{} {
+	var text string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		text = string(v)
+	case time.Time:
+		return v.UTC().Format("2006-01-02 15:04:05")
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		text = fmt.Sprint(v)
+	}
+
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return text
+}
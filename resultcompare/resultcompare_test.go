@@ -0,0 +1,99 @@
+package resultcompare
+
+import (
+	"testing"
+)
+
+func TestCompareExactMatch(t *testing.T) {
+	expected := &ResultSet{Columns: []string{"name", "age"}, Rows: [][]interface{}{{"Rex", 3}, {"Tom", 5}}}
+	actual := &ResultSet{Columns: []string{"n", "a"}, Rows: [][]interface{}{{[]byte("Rex"), int64(3)}, {"Tom", "5.0"}}}
+
+	report := Compare(expected, actual, Options{})
+	if !report.Match || report.Score != 1 {
+		t.Fatalf("expected a match, got %+v", report)
+	}
+}
+
+func TestCompareRowOrder(t *testing.T) {
+	expected := &ResultSet{Columns: []string{"x"}, Rows: [][]interface{}{{1}, {2}}}
+	actual := &ResultSet{Columns: []string{"x"}, Rows: [][]interface{}{{2}, {1}}}
+
+	if report := Compare(expected, actual, Options{}); report.Match {
+		t.Error("expected ordered comparison to fail")
+	}
+	if report := Compare(expected, actual, Options{IgnoreRowOrder: true}); !report.Match {
+		t.Errorf("expected unordered comparison to match, got %+v", report)
+	}
+}
+
+func TestCompareColumnOrder(t *testing.T) {
+	expected := &ResultSet{Columns: []string{"name", "age"}, Rows: [][]interface{}{{"Rex", 3}}}
+	actual := &ResultSet{Columns: []string{"AGE", "name"}, Rows: [][]interface{}{{3, "Rex"}}}
+
+	if report := Compare(expected, actual, Options{}); report.Match {
+		t.Error("expected positional comparison to fail")
+	}
+	if report := Compare(expected, actual, Options{IgnoreColumnOrder: true}); !report.Match {
+		t.Errorf("expected name-based comparison to match, got %+v", report)
+	}
+
+	missing := &ResultSet{Columns: []string{"name", "species"}, Rows: [][]interface{}{{"Rex", "dog"}}}
+	report := Compare(expected, missing, Options{IgnoreColumnOrder: true})
+	if report.ColumnsMatch || len(report.MissingColumns) != 1 || report.MissingColumns[0] != "age" {
+		t.Errorf("expected missing column age, got %+v", report)
+	}
+}
+
+func TestCompareFloatTolerance(t *testing.T) {
+	expected := &ResultSet{Columns: []string{"avg"}, Rows: [][]interface{}{{3.3333}}}
+	actual := &ResultSet{Columns: []string{"avg"}, Rows: [][]interface{}{{10.0 / 3}}}
+
+	if report := Compare(expected, actual, Options{}); report.Match {
+		t.Error("expected exact comparison to fail")
+	}
+	if report := Compare(expected, actual, Options{FloatTolerance: 0.001}); !report.Match {
+		t.Errorf("expected comparison within tolerance to match, got %+v", report)
+	}
+}
+
+func TestComparePartialCredit(t *testing.T) {
+	expected := &ResultSet{Columns: []string{"x"}, Rows: [][]interface{}{{1}, {2}, {3}, {4}}}
+	actual := &ResultSet{Columns: []string{"x"}, Rows: [][]interface{}{{1}, {2}, {5}}}
+
+	report := Compare(expected, actual, Options{IgnoreRowOrder: true})
+	if report.Match || report.MatchedRows != 2 || report.Score != 0.5 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if len(report.MissingRows) != 2 || len(report.UnexpectedRows) != 1 {
+		t.Errorf("unexpected row differences: %+v", report)
+	}
+}
+
+func TestCompareNulls(t *testing.T) {
+	expected := &ResultSet{Columns: []string{"x"}, Rows: [][]interface{}{{nil}}}
+	if report := Compare(expected, &ResultSet{Columns: []string{"x"}, Rows: [][]interface{}{{nil}}}, Options{}); !report.Match {
+		t.Error("expected NULL to equal NULL")
+	}
+	if report := Compare(expected, &ResultSet{Columns: []string{"x"}, Rows: [][]interface{}{{"NULL"}}}, Options{}); report.Match {
+		t.Error("expected NULL to differ from the string NULL")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  interface{}
+	}{
+		{[]byte("120.50"), "120.5"},
+		{int64(3), "3"},
+		{3.0, "3"},
+		{"Rex", "Rex"},
+		{nil, nil},
+		{true, "1"},
+	}
+	for _, test := range tests {
+		if got := Normalize(test.value); got != test.want {
+			t.Errorf("Normalize(%v) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
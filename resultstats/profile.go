@@ -0,0 +1,173 @@
+package resultstats
+
+import (
+	"math"
+	"time"
+)
+
+// Buckets of a histogram and sample values listed per column
+const (
+	histogramBuckets = 10
+	maxSampleValues  = 5
+)
+
+// ColumnProfile describes a table column from a sample of its rows
+type ColumnProfile struct {
+	Name string `json:"name"`
+	// Share of sampled values that are NULL
+	NullFraction float64 `json:"nullFraction"`
+	// Distinct values in the sample
+	SampleDistinct int `json:"sampleDistinct"`
+	// Estimated distinct values in the whole table
+	Cardinality int64       `json:"cardinality"`
+	Numeric     bool        `json:"numeric"`
+	Min         interface{} `json:"min,omitempty"`
+	Max         interface{} `json:"max,omitempty"`
+	// Equal-width buckets over numeric and time columns
+	Histogram []Bucket `json:"histogram,omitempty"`
+	// The first distinct values of the sample
+	SampleValues []interface{} `json:"sampleValues"`
+}
+
+// Bucket is a histogram bucket holding the values from Lower up to Upper;
+// the last bucket includes Upper
+type Bucket struct {
+	Lower interface{} `json:"lower"`
+	Upper interface{} `json:"upper"`
+	Count int         `json:"count"`
+}
+
+// Profile describes each column from a sample of rows of a table holding
+// totalRows rows. The cardinality is extrapolated from the sample with the
+// Haas and Stokes estimator PostgreSQL's ANALYZE uses; pass a totalRows of
+// the sample size or less when the sample is the whole table.
+func Profile(columns []string, rows [][]interface{}, totalRows int64) []ColumnProfile {
+	profiles := make([]ColumnProfile, len(columns))
+	for i, name := range columns {
+		values := make([]interface{}, 0, len(rows))
+		for _, row := range rows {
+			if i < len(row) {
+				values = append(values, row[i])
+			}
+		}
+		profiles[i] = profileColumn(name, values, totalRows)
+	}
+	return profiles
+}
+
+// profileColumn profiles one column's sampled values
+func profileColumn(name string, values []interface{}, totalRows int64) ColumnProfile {
+	summary := summarize(name, values)
+	profile := ColumnProfile{
+		Name:           name,
+		SampleDistinct: summary.Distinct,
+		Numeric:        summary.Numeric,
+		Min:            summary.Min,
+		Max:            summary.Max,
+		SampleValues:   []interface{}{},
+	}
+	if len(values) == 0 {
+		return profile
+	}
+	profile.NullFraction = float64(summary.Nulls) / float64(len(values))
+
+	present := []interface{}{}
+	counts := make(map[string]int)
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		present = append(present, value)
+		key := valueKey(value)
+		if counts[key] == 0 && len(profile.SampleValues) < maxSampleValues {
+			profile.SampleValues = append(profile.SampleValues, value)
+		}
+		counts[key]++
+	}
+	profile.Cardinality = estimateCardinality(counts, len(present), float64(totalRows)*(1-profile.NullFraction))
+
+	if summary.Numeric {
+		numbers, _ := asNumbers(present)
+		profile.Histogram = numericHistogram(numbers)
+	} else if low, ok := summary.Min.(time.Time); ok {
+		profile.Histogram = timeHistogram(present, low, summary.Max.(time.Time))
+	}
+	return profile
+}
+
+// estimateCardinality extrapolates the distinct values of a column from a
+// sample of sampled non-NULL values, where counts holds how often each
+// distinct value occurred, to a column of total non-NULL values
+func estimateCardinality(counts map[string]int, sampled int, total float64) int64 {
+	distinct := float64(len(counts))
+	if sampled == 0 || total <= float64(sampled) {
+		return int64(distinct)
+	}
+	singletons := 0.0
+	for _, count := range counts {
+		if count == 1 {
+			singletons++
+		}
+	}
+	n := float64(sampled)
+	estimate := n * distinct / (n - singletons + singletons*n/total)
+	estimate = math.Max(distinct, math.Min(estimate, total))
+	return int64(math.Round(estimate))
+}
+
+// numericHistogram counts numbers into equal-width buckets between their
+// smallest and largest value
+func numericHistogram(numbers []float64) []Bucket {
+	low, high := numbers[0], numbers[0]
+	for _, number := range numbers {
+		low = math.Min(low, number)
+		high = math.Max(high, number)
+	}
+	counts, width := bucketCounts(numbers, low, high)
+
+	buckets := make([]Bucket, len(counts))
+	for i, count := range counts {
+		buckets[i] = Bucket{Lower: low + float64(i)*width, Upper: low + float64(i+1)*width, Count: count}
+	}
+	buckets[len(buckets)-1].Upper = high
+	return buckets
+}
+
+// timeHistogram counts times into equal-width buckets between low and high
+func timeHistogram(values []interface{}, low time.Time, high time.Time) []Bucket {
+	seconds := make([]float64, len(values))
+	for i, value := range values {
+		seconds[i] = float64(value.(time.Time).Sub(low)) / float64(time.Second)
+	}
+	span := float64(high.Sub(low)) / float64(time.Second)
+	counts, width := bucketCounts(seconds, 0, span)
+
+	buckets := make([]Bucket, len(counts))
+	for i, count := range counts {
+		buckets[i] = Bucket{
+			Lower: low.Add(time.Duration(float64(i) * width * float64(time.Second))),
+			Upper: low.Add(time.Duration(float64(i+1) * width * float64(time.Second))),
+			Count: count,
+		}
+	}
+	buckets[len(buckets)-1].Upper = high
+	return buckets
+}
+
+// bucketCounts counts values between low and high into equal-width
+// buckets, a single one when every value is the same
+func bucketCounts(values []float64, low float64, high float64) ([]int, float64) {
+	if high <= low {
+		return []int{len(values)}, 0
+	}
+	width := (high - low) / histogramBuckets
+	counts := make([]int, histogramBuckets)
+	for _, value := range values {
+		bucket := int((value - low) / width)
+		if bucket >= histogramBuckets {
+			bucket = histogramBuckets - 1
+		}
+		counts[bucket]++
+	}
+	return counts, width
+}
@@ -0,0 +1,80 @@
+package resultstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProfileNumericColumn(t *testing.T) {
+	rows := [][]interface{}{}
+	for i := 0; i < 20; i++ {
+		rows = append(rows, []interface{}{int64(i)})
+	}
+	rows = append(rows, []interface{}{nil}, []interface{}{nil}, []interface{}{nil}, []interface{}{nil}, []interface{}{nil})
+
+	profile := Profile([]string{"n"}, rows, 25)[0]
+	if profile.NullFraction != 0.2 || profile.SampleDistinct != 20 || profile.Cardinality != 20 {
+		t.Errorf("unexpected profile: %+v", profile)
+	}
+	if len(profile.Histogram) != 10 {
+		t.Fatalf("expected 10 buckets, got %v", profile.Histogram)
+	}
+	for _, bucket := range profile.Histogram {
+		if bucket.Count != 2 {
+			t.Errorf("expected 2 values per bucket, got %+v", bucket)
+		}
+	}
+	if first, last := profile.Histogram[0], profile.Histogram[9]; first.Lower != 0.0 || last.Upper != 19.0 {
+		t.Errorf("unexpected histogram bounds: %+v %+v", first, last)
+	}
+	if want := []interface{}{int64(0), int64(1), int64(2), int64(3), int64(4)}; !reflect.DeepEqual(profile.SampleValues, want) {
+		t.Errorf("got sample values %v, want %v", profile.SampleValues, want)
+	}
+}
+
+func TestProfileEstimatesCardinality(t *testing.T) {
+	// Every sampled value is unique: the column looks unique
+	unique := [][]interface{}{}
+	for i := 0; i < 100; i++ {
+		unique = append(unique, []interface{}{i})
+	}
+	if got := Profile([]string{"id"}, unique, 10000)[0].Cardinality; got != 10000 {
+		t.Errorf("expected a unique column to extrapolate to the table, got %d", got)
+	}
+
+	// Every value repeats: the sample has seen them all
+	repeated := [][]interface{}{}
+	for i := 0; i < 100; i++ {
+		repeated = append(repeated, []interface{}{[]string{"a", "b", "c", "d"}[i%4]})
+	}
+	if got := Profile([]string{"status"}, repeated, 10000)[0].Cardinality; got != 4 {
+		t.Errorf("expected 4 distinct values, got %d", got)
+	}
+}
+
+func TestProfileTimeAndTextColumns(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := [][]interface{}{
+		{start, "b"},
+		{start.Add(10 * time.Hour), "a"},
+		{start.Add(5 * time.Hour), "b"},
+	}
+	profiles := Profile([]string{"at", "label"}, rows, 3)
+
+	at := profiles[0]
+	if len(at.Histogram) != 10 || at.Histogram[0].Lower != start || at.Histogram[9].Upper != start.Add(10*time.Hour) {
+		t.Errorf("unexpected time histogram: %+v", at.Histogram)
+	}
+	if at.Histogram[0].Count != 1 || at.Histogram[5].Count != 1 || at.Histogram[9].Count != 1 {
+		t.Errorf("unexpected time bucket counts: %+v", at.Histogram)
+	}
+
+	label := profiles[1]
+	if label.Histogram != nil || label.Min != "a" || label.Max != "b" || label.Cardinality != 2 {
+		t.Errorf("unexpected text profile: %+v", label)
+	}
+	if want := []interface{}{"b", "a"}; !reflect.DeepEqual(label.SampleValues, want) {
+		t.Errorf("got sample values %v, want %v", label.SampleValues, want)
+	}
+}
@@ -0,0 +1,208 @@
+package resultstats
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Most frequent values listed per column
+const maxTopValues = 5
+
+// Column summarizes the values of one result column
+type Column struct {
+	Name string `json:"name"`
+	// Values, NULL included
+	Count    int `json:"count"`
+	Nulls    int `json:"nulls"`
+	Distinct int `json:"distinct"`
+	// Whether every value that is not NULL is a number
+	Numeric bool `json:"numeric"`
+	// Smallest and largest values of numeric, text and time columns
+	Min  interface{} `json:"min,omitempty"`
+	Max  interface{} `json:"max,omitempty"`
+	Mean *float64    `json:"mean,omitempty"`
+	// Values that occur more than once, most frequent first
+	TopValues []ValueCount `json:"topValues"`
+}
+
+// ValueCount is a value and how often it occurs in a column
+type ValueCount struct {
+	Value interface{} `json:"value"`
+	Count int         `json:"count"`
+}
+
+// Compute summarizes each column of rows. Numbers may arrive as text, as
+// MySQL returns DECIMAL columns; a column whose values all parse as
+// numbers is numeric.
+func Compute(columns []string, rows [][]interface{}) []Column {
+	stats := make([]Column, len(columns))
+	for i, name := range columns {
+		values := make([]interface{}, 0, len(rows))
+		for _, row := range rows {
+			if i < len(row) {
+				values = append(values, row[i])
+			}
+		}
+		stats[i] = summarize(name, values)
+	}
+	return stats
+}
+
+// summarize computes the statistics of one column's values
+func summarize(name string, values []interface{}) Column {
+	column := Column{Name: name, Count: len(values), TopValues: []ValueCount{}}
+
+	counts := make(map[string]*ValueCount)
+	order := []string{}
+	present := []interface{}{}
+	for _, value := range values {
+		if value == nil {
+			column.Nulls++
+			continue
+		}
+		present = append(present, value)
+		key := valueKey(value)
+		if counts[key] == nil {
+			counts[key] = &ValueCount{Value: value}
+			order = append(order, key)
+		}
+		counts[key].Count++
+	}
+	column.Distinct = len(counts)
+
+	// Ties keep the order in which values first appeared
+	sort.SliceStable(order, func(a, b int) bool {
+		return counts[order[a]].Count > counts[order[b]].Count
+	})
+	for _, key := range order {
+		if counts[key].Count < 2 || len(column.TopValues) == maxTopValues {
+			break
+		}
+		column.TopValues = append(column.TopValues, *counts[key])
+	}
+
+	if len(present) == 0 {
+		return column
+	}
+	if numbers, ok := asNumbers(present); ok {
+		column.Numeric = true
+		minIndex, maxIndex := 0, 0
+		sum := 0.0
+		for i, number := range numbers {
+			if number < numbers[minIndex] {
+				minIndex = i
+			}
+			if number > numbers[maxIndex] {
+				maxIndex = i
+			}
+			sum += number
+		}
+		mean := sum / float64(len(numbers))
+		column.Min, column.Max = present[minIndex], present[maxIndex]
+		if !math.IsInf(mean, 0) && !math.IsNaN(mean) {
+			column.Mean = &mean
+		}
+		return column
+	}
+	column.Min, column.Max = orderedRange(present)
+	return column
+}
+
+// asNumbers converts values that are all numbers, or text holding
+// numbers, to floats
+func asNumbers(values []interface{}) ([]float64, bool) {
+	numbers := make([]float64, len(values))
+	for i, value := range values {
+		number, ok := toFloat(value)
+		if !ok {
+			return nil, false
+		}
+		numbers[i] = number
+	}
+	return numbers, true
+}
+
+// toFloat converts a number, or text holding one, to a float
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		number, err := strconv.ParseFloat(v, 64)
+		return number, err == nil && !math.IsNaN(number) && !math.IsInf(number, 0)
+	}
+	return 0, false
+}
+
+// orderedRange returns the smallest and largest values of a column of text
+// or times, or nils when the values are of other or mixed types
+func orderedRange(values []interface{}) (interface{}, interface{}) {
+	switch values[0].(type) {
+	case string:
+		low, high := values[0].(string), values[0].(string)
+		for _, value := range values {
+			text, ok := value.(string)
+			if !ok {
+				return nil, nil
+			}
+			if text < low {
+				low = text
+			}
+			if text > high {
+				high = text
+			}
+		}
+		return low, high
+	case time.Time:
+		low, high := values[0].(time.Time), values[0].(time.Time)
+		for _, value := range values {
+			moment, ok := value.(time.Time)
+			if !ok {
+				return nil, nil
+			}
+			if moment.Before(low) {
+				low = moment
+			}
+			if moment.After(high) {
+				high = moment
+			}
+		}
+		return low, high
+	}
+	return nil, nil
+}
+
+// valueKey identifies equal values. Equal numbers of different Go types
+// count as one value; structured values are compared by their JSON.
+func valueKey(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return "s:" + v
+	case time.Time:
+		return "t:" + v.UTC().Format(time.RFC3339Nano)
+	case bool:
+		return fmt.Sprintf("b:%t", v)
+	}
+	if number, ok := toFloat(value); ok {
+		return "n:" + strconv.FormatFloat(number, 'g', -1, 64)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("v:%v", value)
+	}
+	return "j:" + string(encoded)
+}
@@ -0,0 +1,80 @@
+package resultstats
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestComputeNumericColumns(t *testing.T) {
+	stats := Compute([]string{"id", "price"}, [][]interface{}{
+		{int64(1), "10.50"},
+		{int64(2), "2.25"},
+		{int64(3), nil},
+		{int64(4), "10.50"},
+	})
+
+	id := stats[0]
+	if !id.Numeric || id.Min != int64(1) || id.Max != int64(4) || *id.Mean != 2.5 || id.Distinct != 4 {
+		t.Errorf("unexpected id stats: %+v", id)
+	}
+	if len(id.TopValues) != 0 {
+		t.Errorf("expected no repeated ids, got %v", id.TopValues)
+	}
+
+	price := stats[1]
+	if !price.Numeric || price.Min != "2.25" || price.Max != "10.50" || *price.Mean != 7.75 {
+		t.Errorf("unexpected price stats: %+v", price)
+	}
+	if price.Count != 4 || price.Nulls != 1 || price.Distinct != 2 {
+		t.Errorf("unexpected price counts: %+v", price)
+	}
+	if want := []ValueCount{{Value: "10.50", Count: 2}}; !reflect.DeepEqual(price.TopValues, want) {
+		t.Errorf("got %v, want %v", price.TopValues, want)
+	}
+}
+
+func TestComputeTextAndTimeColumns(t *testing.T) {
+	early := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	stats := Compute([]string{"category", "created_at", "tags"}, [][]interface{}{
+		{"Furniture", late, []interface{}{"a"}},
+		{"Audio", early, []interface{}{"a"}},
+		{"Electronics", early, []interface{}{"b"}},
+		{"Audio", late, nil},
+		{"Electronics", early, nil},
+		{"Audio", nil, nil},
+	})
+
+	category := stats[0]
+	if category.Numeric || category.Min != "Audio" || category.Max != "Furniture" || category.Mean != nil {
+		t.Errorf("unexpected category stats: %+v", category)
+	}
+	want := []ValueCount{{Value: "Audio", Count: 3}, {Value: "Electronics", Count: 2}}
+	if !reflect.DeepEqual(category.TopValues, want) {
+		t.Errorf("got %v, want %v", category.TopValues, want)
+	}
+
+	created := stats[1]
+	if created.Min != early || created.Max != late || created.Distinct != 2 || created.Nulls != 1 {
+		t.Errorf("unexpected created_at stats: %+v", created)
+	}
+
+	tags := stats[2]
+	if tags.Distinct != 2 || tags.Min != nil || tags.Max != nil || tags.Nulls != 3 {
+		t.Errorf("unexpected tags stats: %+v", tags)
+	}
+	if len(tags.TopValues) != 1 || tags.TopValues[0].Count != 2 {
+		t.Errorf("expected the repeated array as top value, got %v", tags.TopValues)
+	}
+}
+
+func TestComputeEmptyAndAllNullColumns(t *testing.T) {
+	stats := Compute([]string{"a"}, [][]interface{}{{nil}, {nil}})
+	if stats[0].Count != 2 || stats[0].Nulls != 2 || stats[0].Distinct != 0 || stats[0].Numeric {
+		t.Errorf("unexpected stats: %+v", stats[0])
+	}
+	if stats := Compute([]string{"a"}, nil); stats[0].Count != 0 || stats[0].TopValues == nil {
+		t.Errorf("unexpected stats: %+v", stats[0])
+	}
+}
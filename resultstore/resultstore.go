@@ -0,0 +1,185 @@
+package resultstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"time"
+
+	"example/user/playground/sharedstate"
+)
+
+// How long a result is kept after the query that produced it
+const DefaultRetention = time.Hour
+
+// Largest encoded result kept by default; larger ones are not retained
+const DefaultMaxBytes = 1 << 20
+
+// Characters used for query IDs
+const idAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Length of generated query IDs
+const idLength = 12
+
+// ErrNotFound is returned when a result does not exist, has expired, was
+// flushed or belongs to someone else
+var ErrNotFound = errors.New("query result not found or expired")
+
+// ErrTooLarge is returned when a result is larger than the store keeps
+var ErrTooLarge = errors.New("query result is too large to keep")
+
+// Result is the result of a query as it was returned, kept so it can be
+// paged, exported or compared without running the query again. Values read
+// back keep their JSON form, with numbers as json.Number.
+type Result struct {
+	ID        string          `json:"queryId"`
+	Dialect   string          `json:"dialect"`
+	SQL       string          `json:"sql"`
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	CreatedAt time.Time       `json:"createdAt"`
+	ExpiresAt time.Time       `json:"expiresAt"`
+}
+
+// Store keeps recent results in shared state, so any instance can serve
+// them, for a limited time and up to a size per result
+type Store struct {
+	retention time.Duration
+	maxBytes  int
+}
+
+// New returns a store keeping results for retention, skipping results
+// whose encoding exceeds maxBytes
+func New(retention time.Duration, maxBytes int) *Store {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Store{retention: retention, maxBytes: maxBytes}
+}
+
+// resultKey returns the shared state key of a result. The owner is part of
+// the key so one session cannot read another's results.
+func resultKey(owner string, id string) string {
+	return "queryresult:" + owner + ":" + id
+}
+
+// flushKey returns the shared state key holding when the results of a
+// dialect were last flushed
+func flushKey(dialect string) string {
+	return "queryresult:flushed:" + dialect
+}
+
+// Save keeps a result of an owner under a new query ID, setting its ID and
+// times
+func (s *Store) Save(owner string, result *Result) error {
+	result.CreatedAt = time.Now()
+	result.ExpiresAt = result.CreatedAt.Add(s.retention)
+
+	// Regenerate on the unlikely event of a collision
+	for {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		result.ID = id
+		data, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		if len(data) > s.maxBytes {
+			result.ID = ""
+			return ErrTooLarge
+		}
+		stored, err := sharedstate.Current().SetNX(context.Background(), resultKey(owner, id), data, s.retention)
+		if err != nil {
+			return err
+		}
+		if stored {
+			return nil
+		}
+	}
+}
+
+// Get returns a result of an owner
+func (s *Store) Get(owner string, id string) (*Result, error) {
+	data, err := sharedstate.Current().Get(context.Background(), resultKey(owner, id))
+	if err == sharedstate.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var result Result
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	flushed, err := s.flushedAt(result.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	if !result.CreatedAt.After(flushed) {
+		return nil, ErrNotFound
+	}
+	return &result, nil
+}
+
+// Flush drops every result of a dialect kept so far, on every instance.
+// Results are not listed anywhere, so the time of the flush is kept instead
+// and older results are treated as gone until they expire.
+func (s *Store) Flush(dialect string) error {
+	data, err := time.Now().MarshalText()
+	if err != nil {
+		return err
+	}
+	return sharedstate.Current().Set(context.Background(), flushKey(dialect), data, s.retention)
+}
+
+// flushedAt returns when the results of a dialect were last flushed, or
+// the zero time when they were not within the retention
+func (s *Store) flushedAt(dialect string) (time.Time, error) {
+	var flushed time.Time
+	data, err := sharedstate.Current().Get(context.Background(), flushKey(dialect))
+	if err == sharedstate.ErrNotFound {
+		return flushed, nil
+	}
+	if err != nil {
+		return flushed, err
+	}
+	err = flushed.UnmarshalText(data)
+	return flushed, err
+}
+
+// Page returns count rows of a result starting at offset, or every row
+// from offset on when count is negative
+func (r *Result) Page(offset int, count int) [][]interface{} {
+	if offset >= len(r.Rows) {
+		return [][]interface{}{}
+	}
+	end := len(r.Rows)
+	if count >= 0 && offset+count < end {
+		end = offset + count
+	}
+	return r.Rows[offset:end]
+}
+
+// newID generates a random query ID
+func newID() (string, error) {
+	id := make([]byte, idLength)
+	max := big.NewInt(int64(len(idAlphabet)))
+	for i := range id {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		id[i] = idAlphabet[n.Int64()]
+	}
+	return string(id), nil
+}
@@ -0,0 +1,104 @@
+package resultstore
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveAndGet(t *testing.T) {
+	s := New(time.Minute, 0)
+	result := &Result{
+		Dialect: "postgresql",
+		SQL:     "SELECT id, name FROM users",
+		Columns: []string{"id", "name"},
+		Rows:    [][]interface{}{{int64(1), "Ada"}, {int64(2), nil}},
+	}
+	if err := s.Save("session:a", result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(result.ID) != idLength || !result.ExpiresAt.After(result.CreatedAt) {
+		t.Fatalf("expected an ID and expiry, got %+v", result)
+	}
+
+	got, err := s.Get("session:a", result.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.SQL != result.SQL || len(got.Rows) != 2 {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+	if got.Rows[0][0] != json.Number("1") || got.Rows[1][1] != nil {
+		t.Errorf("expected values in their JSON form, got %#v", got.Rows)
+	}
+}
+
+func TestGetChecksOwner(t *testing.T) {
+	s := New(time.Minute, 0)
+	result := &Result{Dialect: "sqlite", SQL: "SELECT 1", Columns: []string{"1"}, Rows: [][]interface{}{{int64(1)}}}
+	if err := s.Save("session:a", result); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Get("session:b", result.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for another owner, got %v", err)
+	}
+}
+
+func TestSaveRejectsLargeResults(t *testing.T) {
+	s := New(time.Minute, 100)
+	result := &Result{Dialect: "sqlite", SQL: "SELECT", Columns: []string{"v"}, Rows: [][]interface{}{{strings.Repeat("x", 200)}}}
+	if err := s.Save("session:a", result); err != ErrTooLarge {
+		t.Errorf("expected ErrTooLarge, got %v", err)
+	}
+	if result.ID != "" {
+		t.Errorf("expected no ID for a result that was not kept, got %q", result.ID)
+	}
+}
+
+func TestPage(t *testing.T) {
+	result := &Result{Rows: [][]interface{}{{1}, {2}, {3}}}
+	tests := []struct {
+		offset, count int
+		want          int
+	}{
+		{0, 2, 2},
+		{2, 5, 1},
+		{1, -1, 2},
+		{3, 1, 0},
+	}
+	for _, tt := range tests {
+		if got := result.Page(tt.offset, tt.count); len(got) != tt.want {
+			t.Errorf("Page(%d, %d) returned %d rows, want %d", tt.offset, tt.count, len(got), tt.want)
+		}
+	}
+}
+
+func TestFlush(t *testing.T) {
+	s := New(time.Minute, 0)
+	flushed := &Result{Dialect: "mysql", SQL: "SELECT 1", Columns: []string{"1"}, Rows: [][]interface{}{{int64(1)}}}
+	kept := &Result{Dialect: "sqlite", SQL: "SELECT 1", Columns: []string{"1"}, Rows: [][]interface{}{{int64(1)}}}
+	for _, result := range []*Result{flushed, kept} {
+		if err := s.Save("session:a", result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := s.Flush("mysql"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := s.Get("session:a", flushed.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for a flushed result, got %v", err)
+	}
+	if _, err := s.Get("session:a", kept.ID); err != nil {
+		t.Errorf("expected a result of another dialect to be kept, got %v", err)
+	}
+
+	later := &Result{Dialect: "mysql", SQL: "SELECT 2", Columns: []string{"2"}, Rows: [][]interface{}{{int64(2)}}}
+	if err := s.Save("session:a", later); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := s.Get("session:a", later.ID); err != nil {
+		t.Errorf("expected a result saved after the flush to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package resultvalues
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// Bytes of a binary value shown in its preview
+const BinaryPreviewBytes = 32
+
+// Binary is a BLOB, BYTEA or BINARY value. Responses carry its size, a
+// preview of its first bytes and the content type sniffed from them rather
+// than the bytes, which do not survive conversion to text; Data keeps the
+// whole value for downloads.
+type Binary struct {
+	Size        int    `json:"size"`
+	ContentType string `json:"contentType"`
+	// The first BinaryPreviewBytes bytes in hex and base64
+	Hex       string `json:"hex"`
+	Base64    string `json:"base64"`
+	Truncated bool   `json:"truncated,omitempty"`
+	// Where the whole value can be downloaded, when it was kept
+	URL  string `json:"url,omitempty"`
+	Data []byte `json:"-"`
+}
+
+// NewBinary describes a binary value
+func NewBinary(data []byte) *Binary {
+	preview := data
+	if len(preview) > BinaryPreviewBytes {
+		preview = preview[:BinaryPreviewBytes]
+	}
+	return &Binary{
+		Size:        len(data),
+		ContentType: http.DetectContentType(data),
+		Hex:         hex.EncodeToString(preview),
+		Base64:      base64.StdEncoding.EncodeToString(preview),
+		Truncated:   len(preview) < len(data),
+		Data:        data,
+	}
+}
+
+// String writes the whole value in hex with a 0x prefix, as text formats
+// such as CSV show it
+func (b *Binary) String() string {
+	return "0x" + hex.EncodeToString(b.Data)
+}
+
+// IsBinary reports whether a database type holds binary values: BYTEA on
+// PostgreSQL and the BLOB and BINARY types of MySQL. SQLite columns have
+// no such type; its driver scans blobs, and only blobs, as bytes, whatever
+// the column's declared type.
+func IsBinary(dialect string, databaseType string) bool {
+	databaseType = strings.ToUpper(databaseType)
+	switch dialect {
+	case "postgresql":
+		return databaseType == "BYTEA"
+	case "mysql":
+		return strings.HasSuffix(databaseType, "BLOB") || strings.HasSuffix(databaseType, "BINARY")
+	}
+	return false
+}
+
+// decodeBinary describes a binary value scanned as text, which in Go holds
+// its bytes unchanged
+func decodeBinary(text string) interface{} {
+	return NewBinary([]byte(text))
+}
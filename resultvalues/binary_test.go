@@ -0,0 +1,52 @@
+package resultvalues
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBinaryColumns(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 40)...)
+	tests := []struct {
+		dialect      string
+		databaseType string
+	}{
+		{"postgresql", "BYTEA"},
+		{"mysql", "LONGBLOB"},
+		{"mysql", "VARBINARY"},
+	}
+	for _, tt := range tests {
+		value, ok := Decode(For(tt.dialect, tt.databaseType), png).(*Binary)
+		if !ok {
+			t.Fatalf("%s %s: expected a Binary", tt.dialect, tt.databaseType)
+		}
+		if !bytes.Equal(value.Data, png) || value.Size != len(png) || value.ContentType != "image/png" || !value.Truncated {
+			t.Errorf("%s %s: unexpected value %+v", tt.dialect, tt.databaseType, value)
+		}
+	}
+	if For("mysql", "TEXT") != nil || For("sqlite", "BLOB") != nil {
+		t.Error("expected text and SQLite columns to keep the driver's conversion")
+	}
+}
+
+func TestBinaryPreview(t *testing.T) {
+	value := NewBinary([]byte{0x00, 0x01, 0xfe, 0xff})
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"size":4,"contentType":"application/octet-stream","hex":"0001feff","base64":"AAH+/w=="}`
+	if string(encoded) != want {
+		t.Errorf("json.Marshal() = %s, want %s", encoded, want)
+	}
+	if value.String() != "0x0001feff" {
+		t.Errorf("String() = %q", value.String())
+	}
+
+	long := NewBinary(bytes.Repeat([]byte{1}, BinaryPreviewBytes+1))
+	if len(long.Hex) != 2*BinaryPreviewBytes || !long.Truncated || !strings.HasPrefix(long.String(), "0x0101") {
+		t.Errorf("unexpected preview %+v", long)
+	}
+}
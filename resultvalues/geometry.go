@@ -0,0 +1,275 @@
+package resultvalues
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math"
+)
+
+// Geometry is a spatial value as a GeoJSON geometry. SRID is not part of
+// GeoJSON, which assumes WGS 84, and is added as a foreign member when the
+// value carries one.
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates,omitempty"`
+	Geometries  []*Geometry `json:"geometries,omitempty"`
+	SRID        uint32      `json:"srid,omitempty"`
+}
+
+// GeoJSON type names of the WKB geometry codes
+var geometryTypes = map[uint32]string{
+	1: "Point",
+	2: "LineString",
+	3: "Polygon",
+	4: "MultiPoint",
+	5: "MultiLineString",
+	6: "MultiPolygon",
+	7: "GeometryCollection",
+}
+
+// Flags PostGIS sets in the type code of extended WKB
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+var errInvalidWKB = errors.New("invalid WKB")
+
+// ParseWKB parses a geometry in well-known binary, including PostGIS's
+// extended WKB with an SRID and the ISO codes of Z and M geometries. M
+// values have no place in GeoJSON and are dropped.
+func ParseWKB(data []byte) (*Geometry, error) {
+	reader := &wkbReader{data: data}
+	geometry, err := reader.geometry()
+	if err != nil {
+		return nil, err
+	}
+	if reader.pos != len(data) {
+		return nil, errInvalidWKB
+	}
+	return geometry, nil
+}
+
+// decodeMySQLGeometry decodes MySQL's internal geometry format: a
+// little-endian SRID followed by WKB. Geographic coordinates are stored
+// longitude first, the order GeoJSON expects.
+func decodeMySQLGeometry(text string) interface{} {
+	if len(text) < 4 {
+		return text
+	}
+	geometry, err := ParseWKB([]byte(text[4:]))
+	if err != nil {
+		return text
+	}
+	geometry.SRID = binary.LittleEndian.Uint32([]byte(text[:4]))
+	return geometry
+}
+
+// decodePostGIS decodes the hex-encoded extended WKB PostGIS prints for
+// geometry and geography values. PostGIS types have no type name the
+// driver knows, so other values of such types, which do not parse, stay
+// text.
+func decodePostGIS(text string) interface{} {
+	// The shortest geometry is an empty collection of 9 bytes
+	if len(text) < 18 || len(text)%2 != 0 {
+		return text
+	}
+	data, err := hex.DecodeString(text)
+	if err != nil {
+		return text
+	}
+	geometry, err := ParseWKB(data)
+	if err != nil {
+		return text
+	}
+	return geometry
+}
+
+// wkbReader reads a WKB value
+type wkbReader struct {
+	data  []byte
+	pos   int
+	order binary.ByteOrder
+}
+
+// geometry reads one geometry with its byte order and type
+func (r *wkbReader) geometry() (*Geometry, error) {
+	if r.pos >= len(r.data) {
+		return nil, errInvalidWKB
+	}
+	switch r.data[r.pos] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return nil, errInvalidWKB
+	}
+	r.pos++
+
+	code, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	hasZ := code&ewkbZ != 0
+	hasM := code&ewkbM != 0
+	var srid uint32
+	if code&ewkbSRID != 0 {
+		if srid, err = r.uint32(); err != nil {
+			return nil, err
+		}
+	}
+	code &^= ewkbZ | ewkbM | ewkbSRID
+	// ISO WKB adds 1000 for Z, 2000 for M and 3000 for both
+	switch code / 1000 {
+	case 1:
+		hasZ = true
+	case 2:
+		hasM = true
+	case 3:
+		hasZ, hasM = true, true
+	}
+	name, ok := geometryTypes[code%1000]
+	if !ok || code >= 4000 {
+		return nil, errInvalidWKB
+	}
+
+	dimensions := 2
+	if hasZ {
+		dimensions++
+	}
+	if hasM {
+		dimensions++
+	}
+	geometry := &Geometry{Type: name, SRID: srid}
+
+	switch name {
+	case "Point":
+		point, err := r.position(dimensions, hasZ)
+		if err != nil {
+			return nil, err
+		}
+		// An empty point is written with NaN coordinates
+		if math.IsNaN(point[0]) {
+			geometry.Coordinates = []float64{}
+		} else {
+			geometry.Coordinates = point
+		}
+	case "LineString":
+		geometry.Coordinates, err = r.positions(dimensions, hasZ)
+	case "Polygon":
+		geometry.Coordinates, err = r.rings(dimensions, hasZ)
+	default:
+		var count uint32
+		if count, err = r.count(); err != nil {
+			return nil, err
+		}
+		members := make([]*Geometry, 0, count)
+		for i := uint32(0); i < count; i++ {
+			member, err := r.geometry()
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, member)
+		}
+		if name == "GeometryCollection" {
+			geometry.Geometries = members
+		} else {
+			coordinates := make([]interface{}, len(members))
+			for i, member := range members {
+				coordinates[i] = member.Coordinates
+			}
+			geometry.Coordinates = coordinates
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return geometry, nil
+}
+
+// rings reads the rings of a polygon
+func (r *wkbReader) rings(dimensions int, hasZ bool) ([][][]float64, error) {
+	count, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	rings := make([][][]float64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		ring, err := r.positions(dimensions, hasZ)
+		if err != nil {
+			return nil, err
+		}
+		rings = append(rings, ring)
+	}
+	return rings, nil
+}
+
+// positions reads a counted list of positions
+func (r *wkbReader) positions(dimensions int, hasZ bool) ([][]float64, error) {
+	count, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	positions := make([][]float64, 0, count)
+	for i := uint32(0); i < count; i++ {
+		position, err := r.position(dimensions, hasZ)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+// position reads one position and keeps x, y and, when present, z
+func (r *wkbReader) position(dimensions int, hasZ bool) ([]float64, error) {
+	keep := 2
+	if hasZ {
+		keep = 3
+	}
+	position := make([]float64, 0, keep)
+	for i := 0; i < dimensions; i++ {
+		value, err := r.uint64()
+		if err != nil {
+			return nil, err
+		}
+		if i < keep {
+			position = append(position, math.Float64frombits(value))
+		}
+	}
+	return position, nil
+}
+
+// count reads the number of items that follow, refusing counts the
+// remaining bytes cannot hold so corrupt values cannot allocate much
+func (r *wkbReader) count() (uint32, error) {
+	count, err := r.uint32()
+	if err != nil {
+		return 0, err
+	}
+	if int(count) > len(r.data)-r.pos {
+		return 0, errInvalidWKB
+	}
+	return count, nil
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	if len(r.data)-r.pos < 4 {
+		return 0, errInvalidWKB
+	}
+	value := r.order.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return value, nil
+}
+
+func (r *wkbReader) uint64() (uint64, error) {
+	if len(r.data)-r.pos < 8 {
+		return 0, errInvalidWKB
+	}
+	value := r.order.Uint64(r.data[r.pos:])
+	r.pos += 8
+	return value, nil
+}
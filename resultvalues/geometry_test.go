@@ -0,0 +1,100 @@
+package resultvalues
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// wkb builds little-endian WKB from a type code and the values after it,
+// where uint32 values are counts and float64 values coordinates
+func wkb(code uint32, values ...interface{}) []byte {
+	data := []byte{1}
+	data = binary.LittleEndian.AppendUint32(data, code)
+	for _, value := range values {
+		switch v := value.(type) {
+		case uint32:
+			data = binary.LittleEndian.AppendUint32(data, v)
+		case float64:
+			data = binary.LittleEndian.AppendUint64(data, math.Float64bits(v))
+		case []byte:
+			data = append(data, v...)
+		}
+	}
+	return data
+}
+
+func geoJSON(t *testing.T, value interface{}) string {
+	t.Helper()
+	if _, ok := value.(*Geometry); !ok {
+		t.Fatalf("expected a geometry, got %#v", value)
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(encoded)
+}
+
+func TestPostGISGeometries(t *testing.T) {
+	decoder := For("postgresql", "")
+
+	// SELECT 'SRID=4326;POINT(1 2)'::geometry
+	got := geoJSON(t, Decode(decoder, []byte("0101000020E6100000000000000000F03F0000000000000040")))
+	if want := `{"type":"Point","coordinates":[1,2],"srid":4326}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	polygon := wkb(3, uint32(1), uint32(4), 0.0, 0.0, 1.0, 0.0, 1.0, 1.0, 0.0, 0.0)
+	got = geoJSON(t, Decode(decoder, hex.EncodeToString(polygon)))
+	if want := `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// ISO POINT ZM drops the M value
+	multi := wkb(4, uint32(2), wkb(3001, 1.0, 2.0, 3.0, 4.0), wkb(3001, 5.0, 6.0, 7.0, 8.0))
+	got = geoJSON(t, Decode(decoder, hex.EncodeToString(multi)))
+	if want := `{"type":"MultiPoint","coordinates":[[1,2,3],[5,6,7]]}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	collection := wkb(7, uint32(2), wkb(1, math.NaN(), math.NaN()), wkb(2, uint32(2), 0.0, 0.0, 3.0, 4.0))
+	got = geoJSON(t, Decode(decoder, hex.EncodeToString(collection)))
+	if want := `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[]},{"type":"LineString","coordinates":[[0,0],[3,4]]}]}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestPostGISKeepsOtherValuesAsText(t *testing.T) {
+	decoder := For("postgresql", "")
+	point := hex.EncodeToString(wkb(1, 1.0, 2.0))
+	for _, text := range []string{
+		"happy",
+		"00",
+		"BOX(0 0,1 1)",
+		// Truncated and with trailing bytes
+		point[:len(point)-2],
+		point + "00",
+		// A count larger than the value
+		hex.EncodeToString(wkb(2, uint32(1000000))),
+	} {
+		if got := Decode(decoder, text); got != text {
+			t.Errorf("expected %q to stay text, got %#v", text, got)
+		}
+	}
+}
+
+func TestMySQLGeometries(t *testing.T) {
+	value := binary.LittleEndian.AppendUint32(nil, 4326)
+	value = append(value, wkb(1, -0.1276, 51.5072)...)
+	got := geoJSON(t, Decode(For("mysql", "GEOMETRY"), value))
+	if want := `{"type":"Point","coordinates":[-0.1276,51.5072],"srid":4326}`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if got := Decode(For("mysql", "GEOMETRY"), []byte{1, 2}); got != "\x01\x02" {
+		t.Errorf("expected a short value to stay as it is, got %#v", got)
+	}
+}
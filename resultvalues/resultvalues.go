@@ -0,0 +1,345 @@
+package resultvalues
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Range is a PostgreSQL range value. A nil bound is unbounded on that side.
+type Range struct {
+	Lower interface{} `json:"lower"`
+	Upper interface{} `json:"upper"`
+	// The bound brackets, such as "[)"
+	Bounds string `json:"bounds,omitempty"`
+	Empty  bool   `json:"empty,omitempty"`
+}
+
+// Decoder turns the text of a column value into a structured value
+type Decoder func(text string) interface{}
+
+// For returns the decoder of a column of the given database type, as
+// reported by the driver, or nil when values keep the driver's conversion.
+// JSON columns of every dialect become json.RawMessage; PostgreSQL arrays,
+// ranges and anonymous records become slices and Ranges; MySQL SET columns
+// become slices of their members. Spatial values of MySQL and PostGIS
+// become Geometries, and binary values Binaries. PostgreSQL enums and
+// named composite types, like PostGIS types, have no type name the driver
+// knows; they are tried as PostGIS geometries and otherwise stay text.
+func For(dialect string, databaseType string) Decoder {
+	databaseType = strings.ToUpper(databaseType)
+	if IsJSON(databaseType) {
+		return decodeJSON
+	}
+	if IsBinary(dialect, databaseType) {
+		return decodeBinary
+	}
+
+	switch dialect {
+	case "postgresql":
+		if strings.HasPrefix(databaseType, "_") {
+			element := postgresElement(strings.TrimPrefix(databaseType, "_"))
+			return func(text string) interface{} {
+				if value, ok := parseArray(text, element); ok {
+					return value
+				}
+				return text
+			}
+		}
+		if strings.HasSuffix(databaseType, "RANGE") && databaseType != "ANYRANGE" {
+			return postgresElement(databaseType)
+		}
+		if databaseType == "" {
+			return decodePostGIS
+		}
+		if databaseType == "RECORD" {
+			return func(text string) interface{} {
+				if fields, ok := parseRecord(text); ok {
+					return fields
+				}
+				return text
+			}
+		}
+
+	case "mysql":
+		if databaseType == "GEOMETRY" {
+			return decodeMySQLGeometry
+		}
+		if databaseType == "SET" {
+			return func(text string) interface{} {
+				if text == "" {
+					return []interface{}{}
+				}
+				members := []interface{}{}
+				for _, member := range strings.Split(text, ",") {
+					members = append(members, member)
+				}
+				return members
+			}
+		}
+	}
+	return nil
+}
+
+// IsJSON reports whether a database type holds JSON documents: JSON on
+// MySQL and SQLite, where it is only a declared type, and JSON or JSONB on
+// PostgreSQL
+func IsJSON(databaseType string) bool {
+	switch strings.ToUpper(databaseType) {
+	case "JSON", "JSONB":
+		return true
+	}
+	return false
+}
+
+// Decode applies a decoder to a scanned value. Text arrives as []byte or
+// string; other values, such as numbers the driver already converted, are
+// returned as they are.
+func Decode(decoder Decoder, value interface{}) interface{} {
+	switch v := value.(type) {
+	case []byte:
+		return decoder(string(v))
+	case string:
+		return decoder(v)
+	}
+	return value
+}
+
+// decodeJSON embeds a JSON document in the response as is, keeping key
+// order and number precision. Text that does not parse, which SQLite
+// allows in a JSON column, stays a string.
+func decodeJSON(text string) interface{} {
+	if !json.Valid([]byte(text)) {
+		return text
+	}
+	return json.RawMessage(text)
+}
+
+// Bound types of the PostgreSQL ranges whose bounds are not kept as text;
+// numeric and time bounds stay text to keep their precision and zone
+var rangeBounds = map[string]string{
+	"INT4RANGE": "INT4",
+	"INT8RANGE": "INT8",
+}
+
+// postgresElement returns the decoder of the scalar type of array elements
+// and of range values
+func postgresElement(databaseType string) Decoder {
+	if strings.HasSuffix(databaseType, "RANGE") {
+		return rangeDecoder(postgresElement(rangeBounds[databaseType]))
+	}
+	switch databaseType {
+	case "INT2", "INT4", "INT8", "OID":
+		return func(text string) interface{} {
+			if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+				return n
+			}
+			return text
+		}
+	case "FLOAT4", "FLOAT8":
+		return func(text string) interface{} {
+			if f, err := strconv.ParseFloat(text, 64); err == nil {
+				return f
+			}
+			return text
+		}
+	case "BOOL":
+		return func(text string) interface{} {
+			return text == "t" || text == "true"
+		}
+	case "JSON", "JSONB":
+		return decodeJSON
+	}
+	return decodeText
+}
+
+// decodeText keeps a value as text
+func decodeText(text string) interface{} {
+	return text
+}
+
+// rangeDecoder returns a decoder of range literals whose bounds are decoded
+// with bound
+func rangeDecoder(bound Decoder) Decoder {
+	return func(text string) interface{} {
+		if value, ok := parseRange(text, bound); ok {
+			return value
+		}
+		return text
+	}
+}
+
+// parseArray parses a PostgreSQL array literal such as {1,2,NULL} or
+// {{a,"b c"},{d,e}} into nested slices
+func parseArray(text string, element Decoder) ([]interface{}, bool) {
+	// Arrays with non-default bounds are prefixed with [1:2]=
+	if strings.HasPrefix(text, "[") {
+		if i := strings.Index(text, "="); i >= 0 {
+			text = text[i+1:]
+		}
+	}
+	value, rest, ok := parseArrayLevel(text, element)
+	return value, ok && rest == ""
+}
+
+// parseArrayLevel parses one brace-delimited level of an array literal and
+// returns the text after it
+func parseArrayLevel(text string, element Decoder) ([]interface{}, string, bool) {
+	if !strings.HasPrefix(text, "{") {
+		return nil, text, false
+	}
+	text = text[1:]
+	items := []interface{}{}
+	if strings.HasPrefix(text, "}") {
+		return items, text[1:], true
+	}
+
+	for {
+		switch {
+		case strings.HasPrefix(text, "{"):
+			nested, rest, ok := parseArrayLevel(text, element)
+			if !ok {
+				return nil, text, false
+			}
+			items = append(items, nested)
+			text = rest
+		case strings.HasPrefix(text, `"`):
+			item, rest, ok := parseQuoted(text)
+			if !ok {
+				return nil, text, false
+			}
+			items = append(items, element(item))
+			text = rest
+		default:
+			end := strings.IndexAny(text, ",}")
+			if end < 0 {
+				return nil, text, false
+			}
+			item := strings.TrimSpace(text[:end])
+			if strings.EqualFold(item, "NULL") {
+				items = append(items, nil)
+			} else {
+				items = append(items, element(item))
+			}
+			text = text[end:]
+		}
+
+		if text == "" {
+			return nil, text, false
+		}
+		separator := text[0]
+		text = text[1:]
+		if separator == '}' {
+			return items, text, true
+		}
+		if separator != ',' {
+			return nil, text, false
+		}
+	}
+}
+
+// parseRange parses a PostgreSQL range literal such as [1,10) or
+// ["2024-01-01 00:00:00",) or empty
+func parseRange(text string, bound Decoder) (*Range, bool) {
+	if strings.EqualFold(text, "empty") {
+		return &Range{Empty: true}, true
+	}
+	if len(text) < 3 || !strings.ContainsRune("[(", rune(text[0])) || !strings.ContainsRune("])", rune(text[len(text)-1])) {
+		return nil, false
+	}
+	fields, ok := splitFields(text[1 : len(text)-1])
+	if !ok || len(fields) != 2 {
+		return nil, false
+	}
+
+	result := &Range{Bounds: string(text[0]) + string(text[len(text)-1])}
+	if fields[0] != nil {
+		result.Lower = bound(*fields[0])
+	}
+	if fields[1] != nil {
+		result.Upper = bound(*fields[1])
+	}
+	return result, true
+}
+
+// parseRecord parses an anonymous PostgreSQL record such as (1,"a b",)
+// into its fields as text; an empty field is NULL
+func parseRecord(text string) ([]interface{}, bool) {
+	if len(text) < 2 || text[0] != '(' || text[len(text)-1] != ')' {
+		return nil, false
+	}
+	fields, ok := splitFields(text[1 : len(text)-1])
+	if !ok {
+		return nil, false
+	}
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		if field != nil {
+			values[i] = *field
+		}
+	}
+	return values, true
+}
+
+// splitFields splits the comma-separated fields of a range or record
+// literal. Fields may be double-quoted; an unquoted empty field is nil.
+func splitFields(text string) ([]*string, bool) {
+	fields := []*string{}
+	for {
+		var field *string
+		if strings.HasPrefix(text, `"`) {
+			value, rest, ok := parseQuoted(text)
+			if !ok {
+				return nil, false
+			}
+			field = &value
+			text = rest
+		} else {
+			end := strings.IndexByte(text, ',')
+			if end < 0 {
+				end = len(text)
+			}
+			if end > 0 {
+				value := text[:end]
+				field = &value
+			}
+			text = text[end:]
+		}
+		fields = append(fields, field)
+
+		if text == "" {
+			return fields, true
+		}
+		if text[0] != ',' {
+			return nil, false
+		}
+		text = text[1:]
+	}
+}
+
+// parseQuoted reads a double-quoted element starting at text[0].
+// Backslashes escape the next character and, in records and ranges, a
+// doubled quote stands for one.
+func parseQuoted(text string) (string, string, bool) {
+	const quote = '"'
+	var value strings.Builder
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 < len(text) {
+				i++
+				value.WriteByte(text[i])
+			}
+		case quote:
+			if i+1 < len(text) && text[i+1] == quote {
+				i++
+				value.WriteByte(quote)
+				continue
+			}
+			return value.String(), text[i+1:], true
+		default:
+			value.WriteByte(text[i])
+		}
+	}
+	return "", text, false
+}
@@ -0,0 +1,84 @@
+package resultvalues
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPostgresArrays(t *testing.T) {
+	tests := []struct {
+		databaseType string
+		text         string
+		want         interface{}
+	}{
+		{"_INT4", "{1,2,NULL}", []interface{}{int64(1), int64(2), nil}},
+		{"_TEXT", `{plain,"with space","quote\"d",""}`, []interface{}{"plain", "with space", `quote"d`, ""}},
+		{"_INT8", "{{1,2},{3,4}}", []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{int64(3), int64(4)}}},
+		{"_BOOL", "[0:1]={t,f}", []interface{}{true, false}},
+		{"_FLOAT8", "{}", []interface{}{}},
+		{"_TEXT", "{unterminated", "{unterminated"},
+	}
+	for _, tt := range tests {
+		got := Decode(For("postgresql", tt.databaseType), []byte(tt.text))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %q: got %#v, want %#v", tt.databaseType, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPostgresRanges(t *testing.T) {
+	tests := []struct {
+		databaseType string
+		text         string
+		want         interface{}
+	}{
+		{"INT4RANGE", "[1,10)", &Range{Lower: int64(1), Upper: int64(10), Bounds: "[)"}},
+		{"NUMRANGE", "(,2.50]", &Range{Upper: "2.50", Bounds: "(]"}},
+		{"TSRANGE", `["2024-01-01 00:00:00","2024-02-01 00:00:00")`,
+			&Range{Lower: "2024-01-01 00:00:00", Upper: "2024-02-01 00:00:00", Bounds: "[)"}},
+		{"DATERANGE", "empty", &Range{Empty: true}},
+		{"_INT4RANGE", `{"[1,3)","[5,7)"}`, []interface{}{
+			&Range{Lower: int64(1), Upper: int64(3), Bounds: "[)"},
+			&Range{Lower: int64(5), Upper: int64(7), Bounds: "[)"},
+		}},
+	}
+	for _, tt := range tests {
+		got := Decode(For("postgresql", tt.databaseType), []byte(tt.text))
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %q: got %#v, want %#v", tt.databaseType, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRecordsAndSets(t *testing.T) {
+	got := Decode(For("postgresql", "RECORD"), []byte(`(1,"a ""quoted"" b",)`))
+	if want := []interface{}{"1", `a "quoted" b`, nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+
+	got = Decode(For("mysql", "SET"), []byte("red,blue"))
+	if want := []interface{}{"red", "blue"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestJSONAndPlainTypes(t *testing.T) {
+	got := Decode(For("sqlite", "json"), []byte(`{"b": 1.50}`))
+	if document, ok := got.(json.RawMessage); !ok || string(document) != `{"b": 1.50}` {
+		t.Errorf("expected the document to be kept as is, got %#v", got)
+	}
+	if got := Decode(For("sqlite", "JSON"), "not json"); got != "not json" {
+		t.Errorf("expected invalid JSON to stay text, got %#v", got)
+	}
+
+	for _, tt := range []struct{ dialect, databaseType string }{
+		{"postgresql", "TEXT"},
+		{"mysql", "VARCHAR"},
+		{"sqlite", "_INT4"},
+	} {
+		if For(tt.dialect, tt.databaseType) != nil {
+			t.Errorf("expected no decoder for %s %q", tt.dialect, tt.databaseType)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package resultvalues
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Largest text cell a response carries whole by default
+const DefaultMaxCellBytes = 64 << 10
+
+// Truncated stands in for a text or JSON cell larger than the cell size
+// cap, so one huge value cannot swell a response. Preview holds the first
+// bytes of the value, cut at a character boundary; Text keeps all of it
+// for the formats that write whole values and for fetching the cell.
+type Truncated struct {
+	Truncated bool   `json:"truncated"`
+	FullSize  int    `json:"fullSize"`
+	Preview   string `json:"preview"`
+	// Where the whole value can be fetched, when it was kept
+	URL  string `json:"url,omitempty"`
+	Text string `json:"-"`
+}
+
+// String returns the whole value
+func (t *Truncated) String() string {
+	return t.Text
+}
+
+// Truncate returns the marker of a text or JSON value longer than
+// maxBytes, or nil when the value fits or is of another type
+func Truncate(value interface{}, maxBytes int) *Truncated {
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case json.RawMessage:
+		text = string(v)
+	default:
+		return nil
+	}
+	if len(text) <= maxBytes {
+		return nil
+	}
+
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return &Truncated{Truncated: true, FullSize: len(text), Preview: text[:end], Text: text}
+}
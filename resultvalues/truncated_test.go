@@ -0,0 +1,30 @@
+package resultvalues
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	if Truncate("short", 5) != nil || Truncate(int64(123456), 1) != nil {
+		t.Error("expected values that fit and non-text values to be kept")
+	}
+
+	marker := Truncate("héllo wörld", 2)
+	if marker == nil || marker.FullSize != len("héllo wörld") || marker.Preview != "h" || marker.String() != "héllo wörld" {
+		t.Fatalf("unexpected marker %#v", marker)
+	}
+	encoded, err := json.Marshal(marker)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := `{"truncated":true,"fullSize":13,"preview":"h"}`; string(encoded) != want {
+		t.Errorf("json.Marshal() = %s, want %s", encoded, want)
+	}
+
+	document := json.RawMessage(`{"items": [` + strings.Repeat(`1,`, 50) + `1]}`)
+	if marker := Truncate(document, 20); marker == nil || marker.Preview != `{"items": [1,1,1,1,1` {
+		t.Errorf("unexpected marker of a JSON document %+v", marker)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/routines"
+	"example/user/playground/sqlvalidator"
+)
+
+// How often routines of idle sessions are looked for
+const routineCleanupInterval = time.Minute
+
+// routineIdleTimeout reads how long a session may stay idle before its
+// routines are dropped from ROUTINE_IDLE_TIMEOUT
+func routineIdleTimeout() time.Duration {
+	value := os.Getenv("ROUTINE_IDLE_TIMEOUT")
+	if value == "" {
+		return routines.DefaultIdleTimeout
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return routines.DefaultIdleTimeout
+	}
+	return timeout
+}
+
+// startRoutineCleanup periodically drops the procedures, functions and
+// triggers of sessions that have gone idle
+func startRoutineCleanup(idle time.Duration) {
+	go func() {
+		for range time.Tick(routineCleanupInterval) {
+			for _, routine := range routines.Expire(idle) {
+				dropRoutine(routine)
+			}
+		}
+	}()
+}
+
+// dropRoutine removes an expired routine from its database
+func dropRoutine(routine routines.Routine) {
+	db, err := dbmanager.GetDatabaseConnection(routine.Dialect)
+	if err != nil {
+		fmt.Printf("Failed to drop expired %s %s: %v\n", routine.Kind, routine.Name, err)
+		return
+	}
+	if _, err := db.Exec(routines.DropStatement(routine)); err != nil {
+		fmt.Printf("Failed to drop expired %s %s: %v\n", routine.Kind, routine.Name, err)
+		return
+	}
+	fmt.Printf("Dropped expired %s %s from %s\n", routine.Kind, routine.Name, routine.Dialect)
+}
+
+// namespaceRewrite describes the renaming of a routine into the session's
+// namespace
+func namespaceRewrite(stmt *sqlvalidator.RoutineStatement) sqlvalidator.Rewrite {
+	return sqlvalidator.Rewrite{
+		Name:        "routine_namespace",
+		Description: fmt.Sprintf("Renamed the %s to %s to keep it in this session's namespace", stmt.Kind, stmt.Name),
+	}
+}
+
+// viewResolutionRewrite describes the resolution of short view and table
+// names to the session's views and saved results
+func viewResolutionRewrite(names []string) sqlvalidator.Rewrite {
+	return sqlvalidator.Rewrite{
+		Name:        "session_views",
+		Description: "Resolved " + strings.Join(names, ", ") + " to views and tables created in this session",
+	}
+}
+
+// listRoutines returns the procedures, functions, triggers and views created in
+// the current session
+func listRoutines(c *gin.Context) {
+	owner := sessionOwner(c)
+	c.JSON(http.StatusOK, gin.H{
+		"prefix":   routines.Prefix(owner),
+		"routines": routines.List(owner),
+	})
+}
@@ -0,0 +1,128 @@
+package routines
+
+import (
+	"sort"
+	"strings"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// dependsOn returns the names of the tables, views and routines a new
+// routine uses. Names of the owner's own views, tables and routines are
+// given as created, with the session prefix, whether or not the statement
+// used the short name. Calls are kept only when they name one of the
+// owner's procedures or functions. The caller must hold mu.
+func dependsOn(owner string, dialect string, stmt *sqlvalidator.RoutineStatement) []string {
+	prefix := strings.ToLower(Prefix(owner))
+	owned := make(map[string]*Routine)
+	for _, routine := range routines {
+		if routine.Owner == owner && routine.Dialect == dialect {
+			owned[strings.ToLower(routine.Name)] = routine
+		}
+	}
+	find := func(name string) *Routine {
+		lower := strings.ToLower(name)
+		if routine, ok := owned[lower]; ok {
+			return routine
+		}
+		return owned[prefix+lower]
+	}
+
+	names := []string{}
+	seen := map[string]bool{strings.ToLower(stmt.Name): true}
+	add := func(name string) {
+		if !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			names = append(names, name)
+		}
+	}
+
+	tables := stmt.References.Tables
+	if stmt.Table != "" {
+		tables = append([]string{stmt.Table}, tables...)
+	}
+	for _, table := range tables {
+		if routine := find(table); routine != nil {
+			add(routine.Name)
+		} else {
+			add(table)
+		}
+	}
+	for _, call := range stmt.References.Calls {
+		if routine := find(call); routine != nil &&
+			(routine.Kind == sqlvalidator.RoutineProcedure || routine.Kind == sqlvalidator.RoutineFunction) {
+			add(routine.Name)
+		}
+	}
+	return names
+}
+
+// DropOrder sorts routines so each comes before the routines it depends
+// on, newest first otherwise, so dropping them in order never drops a
+// table or view something else still uses. Routines depending on each
+// other in a cycle are ordered newest first.
+func DropOrder(list []Routine) []Routine {
+	remaining := append([]Routine{}, list...)
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return remaining[i].CreatedAt.After(remaining[j].CreatedAt)
+	})
+
+	ordered := make([]Routine, 0, len(remaining))
+	for len(remaining) > 0 {
+		next := 0
+		for i, routine := range remaining {
+			if !usedByAny(routine, remaining) {
+				next = i
+				break
+			}
+		}
+		ordered = append(ordered, remaining[next])
+		remaining = append(remaining[:next], remaining[next+1:]...)
+	}
+	return ordered
+}
+
+// usedByAny reports whether another routine of the list depends on routine
+func usedByAny(routine Routine, list []Routine) bool {
+	for _, other := range list {
+		if other.Dialect == routine.Dialect && other.Name != routine.Name && dependsOnName(other, routine.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// dependsOnName reports whether a routine uses the object called name
+func dependsOnName(routine Routine, name string) bool {
+	for _, dependency := range routine.DependsOn {
+		if strings.EqualFold(dependency, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dependents returns the routines of the list that use the object called
+// name, directly or through other routines of the list, in drop order.
+// They are the objects dropping it would break.
+func Dependents(list []Routine, dialect string, name string) []Routine {
+	dependents := []Routine{}
+	found := map[string]bool{strings.ToLower(name): true}
+	for changed := true; changed; {
+		changed = false
+		for _, routine := range list {
+			if routine.Dialect != dialect || found[strings.ToLower(routine.Name)] {
+				continue
+			}
+			for _, dependency := range routine.DependsOn {
+				if found[strings.ToLower(dependency)] {
+					found[strings.ToLower(routine.Name)] = true
+					dependents = append(dependents, routine)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return DropOrder(dependents)
+}
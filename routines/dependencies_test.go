@@ -0,0 +1,74 @@
+package routines
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// names joins the names of routines for comparisons
+func names(list []Routine) string {
+	result := make([]string, len(list))
+	for i, routine := range list {
+		result[i] = routine.Name
+	}
+	return strings.Join(result, ",")
+}
+
+func TestRecordDependencies(t *testing.T) {
+	reset()
+	prefix := Prefix("session:a")
+
+	for _, sql := range []string{
+		"CREATE VIEW cheap AS SELECT * FROM products WHERE price < 10",
+		"CREATE FUNCTION discount(p numeric) RETURNS numeric LANGUAGE sql AS $$ SELECT p * 0.9 $$",
+		"CREATE VIEW cheaper AS SELECT discount(price) FROM cheap JOIN Products USING (id)",
+	} {
+		_, stmt, err := Scope(sql, "postgresql", "session:a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		Record("session:a", "postgresql", stmt)
+	}
+
+	var cheaper Routine
+	for _, routine := range List("session:a") {
+		if routine.Name == prefix+"cheaper" {
+			cheaper = routine
+		}
+	}
+	if got, want := strings.Join(cheaper.DependsOn, ","), prefix+"cheap,Products,"+prefix+"discount"; got != want {
+		t.Errorf("expected cheaper to depend on %s, got %s", want, got)
+	}
+}
+
+func TestDropOrder(t *testing.T) {
+	now := time.Now()
+	list := []Routine{
+		{Dialect: "sqlite", Name: "top", DependsOn: []string{"middle"}, CreatedAt: now.Add(-time.Hour)},
+		{Dialect: "sqlite", Name: "base", CreatedAt: now},
+		{Dialect: "sqlite", Name: "middle", DependsOn: []string{"BASE", "orders"}, CreatedAt: now.Add(-2 * time.Hour)},
+		{Dialect: "sqlite", Name: "other", CreatedAt: now.Add(-3 * time.Hour)},
+	}
+	if got := names(DropOrder(list)); got != "top,middle,base,other" {
+		t.Errorf("DropOrder() = %s", got)
+	}
+	if got := names(Dependents(list, "sqlite", "base")); got != "top,middle" {
+		t.Errorf("Dependents(base) = %s", got)
+	}
+	if got := names(Dependents(list, "sqlite", "orders")); got != "top,middle" {
+		t.Errorf("Dependents(orders) = %s", got)
+	}
+	if got := names(Dependents(list, "mysql", "base")); got != "" {
+		t.Errorf("expected no dependents in another dialect, got %s", got)
+	}
+
+	// A cycle falls back to the newest first
+	cycle := []Routine{
+		{Dialect: "sqlite", Name: "a", DependsOn: []string{"b"}, CreatedAt: now.Add(-time.Hour)},
+		{Dialect: "sqlite", Name: "b", DependsOn: []string{"a"}, CreatedAt: now},
+	}
+	if got := names(DropOrder(cycle)); got != "b,a" {
+		t.Errorf("DropOrder() of a cycle = %s", got)
+	}
+}
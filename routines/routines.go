@@ -0,0 +1,250 @@
+package routines
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"example/user/playground/sqlvalidator"
+)
+
+// Largest routine definition accepted, in bytes
+const MaxSize = 8 * 1024
+
+// Most statements and control-flow constructs in a routine body
+const MaxComplexity = 50
+
+// Most routines a session may keep at once
+const MaxPerOwner = 20
+
+// How long a session may stay idle before its routines are dropped
+const DefaultIdleTimeout = 2 * time.Hour
+
+// Shortest time between two refreshes of a materialized view
+const MinRefreshInterval = 30 * time.Second
+
+// Longest a materialized view refresh may run
+const RefreshTimeout = 30 * time.Second
+
+// ErrNotOwned is returned when dropping or refreshing a routine or view
+// another session created
+var ErrNotOwned = errors.New("only routines and views created in this session can be dropped or refreshed")
+
+// Routine is a stored procedure, function, trigger or view created by a
+// session. DependsOn lists the tables, views and routines it uses, as
+// named in the database.
+type Routine struct {
+	Owner       string     `json:"-"`
+	Dialect     string     `json:"dialect"`
+	Kind        string     `json:"kind"`
+	Name        string     `json:"name"`
+	Table       string     `json:"table,omitempty"`
+	DependsOn   []string   `json:"depends_on,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RefreshedAt *time.Time `json:"refreshed_at,omitempty"`
+}
+
+var (
+	// Routines keyed by owner, dialect, kind and lower-cased name
+	routines = make(map[string]*Routine)
+
+	// When each owner last ran a statement
+	lastActive = make(map[string]time.Time)
+
+	// Guards routines and lastActive
+	mu sync.Mutex
+)
+
+// key identifies a routine in the routines map
+func key(owner string, dialect string, kind string, name string) string {
+	return owner + "\x00" + dialect + "\x00" + kind + "\x00" + strings.ToLower(name)
+}
+
+// Prefix returns the name prefix that keeps an owner's routines apart from
+// those of other sessions
+func Prefix(owner string) string {
+	sum := sha256.Sum256([]byte(owner))
+	return "s" + hex.EncodeToString(sum[:4]) + "_"
+}
+
+// Scope checks a CREATE, DROP or REFRESH of a routine or view against the
+// limits and rewrites its name into the owner's namespace. Other
+// statements are returned unchanged with a nil statement.
+func Scope(sql string, dialect string, owner string) (string, *sqlvalidator.RoutineStatement, error) {
+	stmt, err := sqlvalidator.ParseRoutineStatement(sql, dialect)
+	if err != nil || stmt == nil {
+		return sql, nil, err
+	}
+
+	prefix := Prefix(owner)
+	if !strings.HasPrefix(strings.ToLower(stmt.Name), prefix) {
+		stmt.Name = prefix + stmt.Name
+		name := stmt.Name
+		if stmt.NameToken.Kind == sqlvalidator.TokenQuotedIdentifier {
+			name = sqlvalidator.QuoteIdentifier(name, dialect)
+		}
+		token := stmt.NameToken
+		sql = sql[:token.Offset] + name + sql[token.Offset+len(token.Text):]
+	}
+
+	if !stmt.Create {
+		routine, ok := find(owner, dialect, stmt.Kind, stmt.Name)
+		if !ok {
+			return sql, nil, ErrNotOwned
+		}
+		if stmt.Refresh && routine.RefreshedAt != nil && time.Since(*routine.RefreshedAt) < MinRefreshInterval {
+			return sql, nil, fmt.Errorf("%s was refreshed less than %s ago", stmt.Name, MinRefreshInterval)
+		}
+		return sql, stmt, nil
+	}
+
+	if len(sql) > MaxSize {
+		return sql, nil, fmt.Errorf("routine definitions are limited to %d bytes", MaxSize)
+	}
+	if stmt.Complexity > MaxComplexity {
+		return sql, nil, fmt.Errorf("routine bodies are limited to %d statements and control-flow constructs", MaxComplexity)
+	}
+	if !Owns(owner, dialect, stmt.Kind, stmt.Name) && len(List(owner)) >= MaxPerOwner {
+		return sql, nil, fmt.Errorf("a session can keep at most %d routines; drop one first", MaxPerOwner)
+	}
+	return sql, stmt, nil
+}
+
+// Record tracks a routine statement that ran successfully
+func Record(owner string, dialect string, stmt *sqlvalidator.RoutineStatement) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	k := key(owner, dialect, stmt.Kind, stmt.Name)
+	switch {
+	case stmt.Refresh:
+		if routine, ok := routines[k]; ok {
+			now := time.Now()
+			routine.RefreshedAt = &now
+		}
+		return
+	case !stmt.Create:
+		delete(routines, k)
+		return
+	}
+	routines[k] = &Routine{
+		Owner:     owner,
+		Dialect:   dialect,
+		Kind:      stmt.Kind,
+		Name:      stmt.Name,
+		Table:     stmt.Table,
+		DependsOn: dependsOn(owner, dialect, stmt),
+		CreatedAt: time.Now(),
+	}
+}
+
+// Owns reports whether owner created the routine
+func Owns(owner string, dialect string, kind string, name string) bool {
+	_, ok := find(owner, dialect, kind, name)
+	return ok
+}
+
+// find returns a copy of an owner's routine
+func find(owner string, dialect string, kind string, name string) (Routine, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	routine, ok := routines[key(owner, dialect, kind, name)]
+	if !ok {
+		return Routine{}, false
+	}
+	return *routine, true
+}
+
+// Resolve rewrites references to the owner's views and materialized result
+// tables by their unprefixed names, so a view created as sales_summary can
+// be queried under that name. It returns the new SQL and the names that
+// were resolved.
+func Resolve(sql string, dialect string, owner string) (string, []string) {
+	prefix := Prefix(owner)
+	names := make(map[string]string)
+	for _, routine := range List(owner) {
+		if routine.Dialect != dialect {
+			continue
+		}
+		if !strings.HasSuffix(routine.Kind, sqlvalidator.RoutineView) && routine.Kind != sqlvalidator.RoutineTable {
+			continue
+		}
+		names[strings.ToLower(strings.TrimPrefix(routine.Name, prefix))] = routine.Name
+	}
+	if len(names) == 0 {
+		return sql, nil
+	}
+	return sqlvalidator.RenameTables(sql, dialect, names)
+}
+
+// List returns the routines of an owner, oldest first
+func List(owner string) []Routine {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := []Routine{}
+	for _, routine := range routines {
+		if routine.Owner == owner {
+			result = append(result, *routine)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// Touch records that an owner is still active
+func Touch(owner string) {
+	mu.Lock()
+	lastActive[owner] = time.Now()
+	mu.Unlock()
+}
+
+// Expire forgets and returns the routines of owners idle for longer than
+// idle, so the caller can drop them. They come in drop order, so views are
+// dropped before the views and tables they are built on.
+func Expire(idle time.Duration) []Routine {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cutoff := time.Now().Add(-idle)
+	expired := []Routine{}
+	for k, routine := range routines {
+		if lastActive[routine.Owner].After(cutoff) {
+			continue
+		}
+		expired = append(expired, *routine)
+		delete(routines, k)
+	}
+	for owner, active := range lastActive {
+		if !active.After(cutoff) {
+			delete(lastActive, owner)
+		}
+	}
+	return DropOrder(expired)
+}
+
+// MaterializeStatement returns the statement that saves the rows of a
+// query as the owner's table called name. Every dialect supports CREATE
+// TABLE ... AS; it returns the table's prefixed name too.
+func MaterializeStatement(query string, dialect string, owner string, name string) (string, string) {
+	table := Prefix(owner) + name
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	return fmt.Sprintf("CREATE TABLE %s AS %s", sqlvalidator.QuoteIdentifier(table, dialect), query), table
+}
+
+// DropStatement returns the statement that removes a routine
+func DropStatement(routine Routine) string {
+	name := sqlvalidator.QuoteIdentifier(routine.Name, routine.Dialect)
+	stmt := fmt.Sprintf("DROP %s IF EXISTS %s", strings.ToUpper(routine.Kind), name)
+	if routine.Kind == sqlvalidator.RoutineTrigger && routine.Dialect == "postgresql" {
+		stmt += " ON " + sqlvalidator.QuoteIdentifier(routine.Table, routine.Dialect)
+	}
+	return stmt
+}
@@ -0,0 +1,179 @@
+package routines
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"example/user/playground/sqlvalidator"
+)
+
+func reset() {
+	mu.Lock()
+	routines = make(map[string]*Routine)
+	lastActive = make(map[string]time.Time)
+	mu.Unlock()
+}
+
+func TestScopePrefixesNames(t *testing.T) {
+	reset()
+	prefix := Prefix("session:a")
+
+	sql, stmt, err := Scope("CREATE PROCEDURE tally() BEGIN SELECT 1; END", "mysql", "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stmt.Name != prefix+"tally" || !strings.Contains(sql, "PROCEDURE "+prefix+"tally()") {
+		t.Errorf("expected the name to be prefixed, got %q", sql)
+	}
+
+	sql, _, err = Scope("CREATE TRIGGER \"Audit\" AFTER INSERT ON pets BEGIN SELECT 1; END", "sqlite", "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sql, `TRIGGER "`+prefix+`Audit"`) {
+		t.Errorf("expected the quoted name to be prefixed, got %q", sql)
+	}
+
+	if sql, stmt, err := Scope("SELECT 1", "mysql", "session:a"); sql != "SELECT 1" || stmt != nil || err != nil {
+		t.Errorf("expected other statements to be unchanged, got %q, %v, %v", sql, stmt, err)
+	}
+}
+
+func TestDropRequiresOwnership(t *testing.T) {
+	reset()
+
+	_, stmt, err := Scope("CREATE TRIGGER audit AFTER INSERT ON pets BEGIN SELECT 1; END", "sqlite", "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Record("session:a", "sqlite", stmt)
+
+	if _, _, err := Scope("DROP TRIGGER audit", "sqlite", "session:b"); err != ErrNotOwned {
+		t.Errorf("expected ErrNotOwned for another session, got %v", err)
+	}
+
+	_, drop, err := Scope("DROP TRIGGER audit", "sqlite", "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Record("session:a", "sqlite", drop)
+	if len(List("session:a")) != 0 {
+		t.Errorf("expected the dropped trigger to be forgotten")
+	}
+}
+
+func TestScopeEnforcesLimits(t *testing.T) {
+	reset()
+
+	large := "CREATE PROCEDURE p() BEGIN " + strings.Repeat("SELECT 1; ", MaxSize/10) + "END"
+	if _, _, err := Scope(large, "mysql", "session:a"); err == nil {
+		t.Errorf("expected oversized routines to be rejected")
+	}
+
+	busy := "CREATE PROCEDURE p() BEGIN " + strings.Repeat("SET @x = 1; ", MaxComplexity+1) + "END"
+	if _, _, err := Scope(busy, "mysql", "session:a"); err == nil {
+		t.Errorf("expected overly complex routines to be rejected")
+	}
+
+	for i := 0; i < MaxPerOwner; i++ {
+		Record("session:a", "mysql", &sqlvalidator.RoutineStatement{
+			Create: true,
+			Kind:   sqlvalidator.RoutineProcedure,
+			Name:   Prefix("session:a") + strings.Repeat("p", i+1),
+		})
+	}
+	if _, _, err := Scope("CREATE PROCEDURE one_more() SELECT 1", "mysql", "session:a"); err == nil {
+		t.Errorf("expected the per-session routine limit to apply")
+	}
+}
+
+func TestExpireIdleOwners(t *testing.T) {
+	reset()
+
+	for _, owner := range []string{"session:idle", "session:active"} {
+		Record(owner, "postgresql", &sqlvalidator.RoutineStatement{
+			Create: true,
+			Kind:   sqlvalidator.RoutineTrigger,
+			Name:   Prefix(owner) + "audit",
+			Table:  "pets",
+		})
+	}
+	mu.Lock()
+	lastActive["session:idle"] = time.Now().Add(-3 * time.Hour)
+	mu.Unlock()
+	Touch("session:active")
+
+	expired := Expire(DefaultIdleTimeout)
+	if len(expired) != 1 || expired[0].Owner != "session:idle" {
+		t.Fatalf("expected the idle session's trigger to expire, got %+v", expired)
+	}
+	if got := DropStatement(expired[0]); got != `DROP TRIGGER IF EXISTS "`+Prefix("session:idle")+`audit" ON "pets"` {
+		t.Errorf("unexpected drop statement %q", got)
+	}
+	if len(List("session:active")) != 1 {
+		t.Errorf("expected the active session to keep its trigger")
+	}
+}
+
+func TestResolveOwnViews(t *testing.T) {
+	reset()
+
+	_, stmt, err := Scope("CREATE VIEW cheap AS SELECT * FROM products WHERE price < 10", "mysql", "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Record("session:a", "mysql", stmt)
+
+	sql, resolved := Resolve("SELECT name FROM cheap", "mysql", "session:a")
+	if sql != "SELECT name FROM `"+Prefix("session:a")+"cheap`" || len(resolved) != 1 {
+		t.Errorf("expected the view to be resolved, got %q", sql)
+	}
+	if sql, _ := Resolve("SELECT name FROM cheap", "mysql", "session:b"); sql != "SELECT name FROM cheap" {
+		t.Errorf("expected other sessions not to see the view, got %q", sql)
+	}
+}
+
+func TestResolveMaterializedTables(t *testing.T) {
+	reset()
+
+	statement, table := MaterializeStatement("SELECT * FROM customers WHERE active;\n", "postgresql", "session:a", "active")
+	if statement != `CREATE TABLE "`+table+`" AS SELECT * FROM customers WHERE active` {
+		t.Errorf("unexpected statement %q", statement)
+	}
+	Record("session:a", "postgresql", &sqlvalidator.RoutineStatement{Create: true, Kind: sqlvalidator.RoutineTable, Name: table})
+
+	sql, resolved := Resolve("SELECT count(*) FROM active", "postgresql", "session:a")
+	if sql != `SELECT count(*) FROM "`+table+`"` || len(resolved) != 1 {
+		t.Errorf("expected the table to be resolved, got %q", sql)
+	}
+	if DropStatement(List("session:a")[0]) != `DROP TABLE IF EXISTS "`+table+`"` {
+		t.Errorf("unexpected drop statement %q", DropStatement(List("session:a")[0]))
+	}
+}
+
+func TestRefreshIsRateLimited(t *testing.T) {
+	reset()
+
+	if _, _, err := Scope("REFRESH MATERIALIZED VIEW totals", "postgresql", "session:a"); err != ErrNotOwned {
+		t.Errorf("expected ErrNotOwned before the view exists, got %v", err)
+	}
+
+	_, stmt, err := Scope("CREATE MATERIALIZED VIEW totals AS SELECT 1", "postgresql", "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Record("session:a", "postgresql", stmt)
+
+	_, refresh, err := Scope("REFRESH MATERIALIZED VIEW totals", "postgresql", "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Record("session:a", "postgresql", refresh)
+	if List("session:a")[0].RefreshedAt == nil {
+		t.Errorf("expected the refresh time to be recorded")
+	}
+	if _, _, err := Scope("REFRESH MATERIALIZED VIEW totals", "postgresql", "session:a"); err == nil {
+		t.Errorf("expected a second refresh right away to be refused")
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/sqlsplit"
+)
+
+// EditorSelection is a selected range of the editor buffer, as byte offsets
+type EditorSelection struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// resolveStatement narrows a request whose SQL is the whole editor buffer
+// to what should run: the selection when it holds more than whitespace, or
+// else the statement at the cursor, found by the statement splitter so
+// strings, comments and routine bodies never split a statement. It returns
+// the resolved SQL with its span in the buffer, nil when the request has
+// neither a cursor nor a selection, or the response rejecting the request.
+func resolveStatement(req *SQLValidationRequest) (gin.H, gin.H) {
+	if req.Cursor == nil && req.Selection == nil {
+		return nil, nil
+	}
+	reject := func(message string) gin.H {
+		return gin.H{
+			"valid":     false,
+			"error":     message,
+			"errorCode": dberrors.CodeValidationError,
+		}
+	}
+
+	buffer := req.SQL
+	var cursor int
+	if selection := req.Selection; selection != nil {
+		if selection.Start < 0 || selection.Start > selection.End || selection.End > len(buffer) {
+			return nil, reject(fmt.Sprintf("selection must satisfy 0 <= start <= end <= %d, the length of sql in bytes", len(buffer)))
+		}
+		selected := buffer[selection.Start:selection.End]
+		if text := strings.TrimSpace(selected); text != "" {
+			start := selection.Start + strings.Index(selected, text)
+			req.SQL = text
+			return gin.H{"sql": text, "start": start, "end": start + len(text)}, nil
+		}
+		cursor = selection.Start
+	} else {
+		cursor = *req.Cursor
+		if cursor < 0 || cursor > len(buffer) {
+			return nil, reject(fmt.Sprintf("cursor must be between 0 and %d, the length of sql in bytes", len(buffer)))
+		}
+	}
+
+	stmt, ok := sqlsplit.At(buffer, req.Dialect, cursor)
+	if !ok {
+		return nil, reject("There is no statement to run")
+	}
+	req.SQL = stmt.Text
+	return gin.H{"sql": stmt.Text, "start": stmt.Offset, "end": stmt.Offset + len(stmt.Text)}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/sqlvalidator"
+)
+
+// How often the safety policy file is checked for changes
+const policyReloadInterval = 5 * time.Second
+
+// Dialects whose effective policy is reported
+var policyDialects = []string{"sqlite", "mysql", "postgresql", "mock"}
+
+// configureSafetyPolicy loads the safety policy from the YAML file named by
+// SAFETY_POLICY and reloads it when the file changes. Without the variable
+// the built-in policy applies. The cartesian_product check sizes tables by
+// the cached row counts and flags joins producing more row combinations
+// than CARTESIAN_PRODUCT_MAX_ROWS.
+func configureSafetyPolicy() {
+	sqlvalidator.SetTableRowCounts(func(dialect string) map[string]int64 {
+		counts, _ := dbmanager.CachedRowCounts(dialect)
+		return counts
+	})
+	if n, err := strconv.ParseInt(os.Getenv("CARTESIAN_PRODUCT_MAX_ROWS"), 10, 64); err == nil && n > 0 {
+		sqlvalidator.SetCartesianRowLimit(n)
+	}
+
+	path := os.Getenv("SAFETY_POLICY")
+	if path == "" {
+		return
+	}
+	if err := sqlvalidator.LoadPolicyFile(path); err != nil {
+		fmt.Printf("Failed to load safety policy, using built-in rules: %v\n", err)
+	}
+	sqlvalidator.WatchPolicyFile(path, policyReloadInterval)
+}
+
+// getSafetyPolicy returns the effective safety rules per dialect, or for
+// the dialect given in the query string
+func getSafetyPolicy(c *gin.Context) {
+	dialects := policyDialects
+	if dialect := c.Query("dialect"); dialect != "" {
+		dialects = []string{dialect}
+	}
+
+	rules := make(map[string][]sqlvalidator.PolicyRule)
+	for _, dialect := range dialects {
+		rules[dialect] = sqlvalidator.EffectiveRules(dialect)
+	}
+
+	policy := sqlvalidator.CurrentPolicy()
+	c.JSON(http.StatusOK, gin.H{
+		"source":    policy.Source,
+		"loaded_at": policy.LoadedAt,
+		"rules":     rules,
+	})
+}
+
+// withWarnings adds the warnings of warn-only policy rules to a response
+func withWarnings(response gin.H, warnings []string) gin.H {
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	return response
+}
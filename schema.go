@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/routines"
+	"example/user/playground/sqlvalidator"
+)
+
+// getSchema returns the tables and columns of a dialect's database along
+// with its views and their defining SQL, its full-text search indexes and
+// the collations of its text columns. For SQLite, the tables of the
+// attached catalog database are listed by alias. Views created by the current session
+// are marked as owned. The schema is served from the metadata cached when
+// the backend connected or last ran a DDL statement, as of loadedAt.
+func getSchema(c *gin.Context) {
+	dialect := c.DefaultQuery("dialect", "sqlite")
+
+	metadata, err := dbmanager.Metadata(c.Request.Context(), dialect)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load schema: " + err.Error(),
+		})
+		return
+	}
+	collations, _ := dbmanager.CachedColumnCollations(dialect)
+
+	owner := sessionOwner(c)
+	viewList := make([]gin.H, 0, len(metadata.Views))
+	for _, view := range metadata.Views {
+		kind := sqlvalidator.RoutineView
+		if view.Materialized {
+			kind = sqlvalidator.RoutineMaterializedView
+		}
+		viewList = append(viewList, gin.H{
+			"name":         view.Name,
+			"materialized": view.Materialized,
+			"definition":   view.Definition,
+			"owned":        routines.Owns(owner, dialect, kind, view.Name),
+		})
+	}
+
+	response := gin.H{
+		"dialect": dialect,
+		"tables":  metadata.Tables,
+		"views":   viewList,
+		// Full-text search features with an example query each
+		"fullTextSearch": metadata.SearchIndexes,
+		// Character set and collation of text columns by table and column
+		"columnCollations": collations,
+		"loadedAt":         metadata.LoadedAt,
+	}
+
+	// Tables of the read-only catalog attached to the SQLite playground
+	if dialect == "sqlite" {
+		if catalog, ok := dbmanager.SQLiteCatalogSchema(); ok {
+			response["attachedDatabases"] = gin.H{
+				dbmanager.SQLiteCatalogAlias: catalog,
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// How long reloading a schema may take before it is left uncached
+const schemaReloadTimeout = 30 * time.Second
+
+// reloadSchema drops the cached schema of a dialect after the server or a
+// user's DDL statement changed it, introspects it again and tells
+// subscribers about it. A schema that fails to load stays uncached, so
+// the next request introspects it rather than seeing stale tables.
+func reloadSchema(dialect string) error {
+	dbmanager.InvalidateSchema(dialect)
+	ctx, cancel := context.WithTimeout(context.Background(), schemaReloadTimeout)
+	defer cancel()
+	schema, err := dbmanager.LoadSchema(ctx, dialect)
+	if err != nil {
+		fmt.Printf("Failed to reload %s schema: %v\n", dialect, err)
+		return err
+	}
+	publishSchemaChange(dialect, schema)
+	return nil
+}
@@ -0,0 +1,238 @@
+package seclab
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+
+	"example/user/playground/resultcompare"
+	"example/user/playground/sqlvalidator"
+)
+
+// Driver whose connections run lab statements under labAuthorizer
+const labDriver = "sqlite3_seclab"
+
+// Most rows returned by the vulnerable login query
+const maxRows = 100
+
+// Time allowed for a single lab query
+const queryTimeout = 5 * time.Second
+
+// The deliberately vulnerable query. User input is pasted between the
+// quotes instead of being passed as parameters.
+const loginTemplate = "SELECT id, username, role FROM users WHERE username = '%s' AND password = '%s'"
+
+// The same query written safely with placeholders
+const parameterizedLogin = "SELECT id, username, role FROM users WHERE username = ? AND password = ?"
+
+// Tables and rows of the lab database, recreated on every reset
+const seedScript = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, username TEXT NOT NULL UNIQUE, password TEXT NOT NULL, role TEXT NOT NULL);
+INSERT INTO users (username, password, role) VALUES
+	('admin', 'hunter2', 'admin'),
+	('alice', 'correct-horse', 'student'),
+	('bob', 'letmein', 'student');
+CREATE TABLE credit_cards (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users(id), number TEXT NOT NULL, expires TEXT NOT NULL);
+INSERT INTO credit_cards (user_id, number, expires) VALUES
+	(1, '4111 1111 1111 1111', '12/29'),
+	(2, '5500 0000 0000 0004', '03/28'),
+	(3, '3400 0000 0000 009', '07/27');
+`
+
+// ErrDisabled is returned when the lab has not been enabled
+var ErrDisabled = errors.New("the security lab is not enabled")
+
+var (
+	// The throwaway lab database, nil while the lab is disabled
+	db *sql.DB
+
+	// Directory holding the lab database file
+	dir string
+
+	// Guards db and dir
+	mu sync.Mutex
+)
+
+// Attempt is the outcome of a login against the vulnerable query
+type Attempt struct {
+	SQL            string                   `json:"sql"`
+	Parameterized  string                   `json:"parameterized"`
+	Injected       bool                     `json:"injected"`
+	Blocked        bool                     `json:"blocked"`
+	ValidatorError string                   `json:"validator_error,omitempty"`
+	Rule           string                   `json:"rule,omitempty"`
+	Executed       bool                     `json:"executed"`
+	Error          string                   `json:"error,omitempty"`
+	Result         *resultcompare.ResultSet `json:"result,omitempty"`
+}
+
+// SQL functions that reach outside the lab database
+var deniedFunctions = map[string]bool{
+	"load_extension": true,
+	"readfile":       true,
+	"writefile":      true,
+	"edit":           true,
+	"fts3_tokenizer": true,
+}
+
+func init() {
+	sql.Register(labDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+			conn.RegisterAuthorizer(labAuthorizer)
+			return nil
+		},
+	})
+}
+
+// labAuthorizer keeps injected statements inside the lab database. Stacked
+// statements still run, so the lab can show the damage they do, but they
+// cannot attach other files, which VACUUM INTO does too, change settings
+// through PRAGMA or load code.
+func labAuthorizer(action int, arg1 string, arg2 string, database string) int {
+	switch action {
+	case sqlite3.SQLITE_ATTACH, sqlite3.SQLITE_DETACH, sqlite3.SQLITE_PRAGMA:
+		return sqlite3.SQLITE_DENY
+	case sqlite3.SQLITE_FUNCTION:
+		if deniedFunctions[strings.ToLower(arg2)] {
+			return sqlite3.SQLITE_DENY
+		}
+	}
+	return sqlite3.SQLITE_OK
+}
+
+// Enable creates the lab database in a fresh temporary directory. The lab
+// only ever uses its own SQLite file and never touches the playground
+// backends.
+func Enable() error {
+	mu.Lock()
+	defer mu.Unlock()
+	return recreate()
+}
+
+// Enabled reports whether the lab is available
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return db != nil
+}
+
+// Reset discards the lab database, including any damage done by injected
+// statements, and seeds a new one
+func Reset() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if db == nil {
+		return ErrDisabled
+	}
+	return recreate()
+}
+
+// Disable closes the lab database and removes its file
+func Disable() {
+	mu.Lock()
+	defer mu.Unlock()
+	closeDatabase()
+}
+
+// recreate replaces the lab database with a freshly seeded one. mu must be
+// held.
+func recreate() error {
+	closeDatabase()
+
+	tempDir, err := os.MkdirTemp("", "seclab-")
+	if err != nil {
+		return err
+	}
+	labDB, err := sql.Open(labDriver, filepath.Join(tempDir, "lab.db"))
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return err
+	}
+	if _, err := labDB.Exec(seedScript); err != nil {
+		labDB.Close()
+		os.RemoveAll(tempDir)
+		return err
+	}
+	db, dir = labDB, tempDir
+	return nil
+}
+
+// closeDatabase closes and deletes the current lab database. mu must be
+// held.
+func closeDatabase() {
+	if db != nil {
+		db.Close()
+		os.RemoveAll(dir)
+	}
+	db, dir = nil, ""
+}
+
+// BuildLogin pastes the credentials into the vulnerable login query
+func BuildLogin(username string, password string) string {
+	return fmt.Sprintf(loginTemplate, username, password)
+}
+
+// IsInjected reports whether the credentials changed the structure of the
+// login query. Harmless input always becomes exactly one string literal per
+// field, so any other token count means the input escaped its quotes.
+func IsInjected(query string) bool {
+	expected := len(sqlvalidator.Tokenize(BuildLogin("", ""), "sqlite"))
+	tokens := sqlvalidator.Tokenize(query, "sqlite")
+	if len(tokens) != expected {
+		return true
+	}
+	for _, token := range tokens {
+		if token.Kind == sqlvalidator.TokenComment {
+			return true
+		}
+	}
+	return false
+}
+
+// Login runs the vulnerable login query and reports what the playground
+// validator makes of it. When guarded is set, statements the validator
+// rejects or whose structure was changed by the input are not executed.
+func Login(username string, password string, guarded bool) (*Attempt, error) {
+	query := BuildLogin(username, password)
+	attempt := &Attempt{
+		SQL:           query,
+		Parameterized: parameterizedLogin,
+		Injected:      IsInjected(query),
+	}
+
+	if _, err := sqlvalidator.Validate(query, "sqlite"); err != nil {
+		attempt.Blocked = true
+		attempt.ValidatorError = err.Error()
+		attempt.Rule = sqlvalidator.IsSafeDDLOperation(query, "sqlite").Rule
+	}
+	if guarded && (attempt.Blocked || attempt.Injected) {
+		return attempt, nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if db == nil {
+		return nil, ErrDisabled
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	attempt.Executed = true
+	result, err := resultcompare.Query(ctx, db, query, maxRows)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt, nil
+	}
+	attempt.Result = result
+	return attempt, nil
+}
@@ -0,0 +1,135 @@
+package seclab
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func enableLab(t *testing.T) {
+	if err := Enable(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(Disable)
+}
+
+func TestIsInjected(t *testing.T) {
+	tests := []struct {
+		username string
+		password string
+		injected bool
+	}{
+		{"alice", "correct-horse", false},
+		{"o''brien", "x", false},
+		{"admin'--", "", true},
+		{"' OR '1'='1", "' OR '1'='1", true},
+		{"' UNION SELECT id, number, expires FROM credit_cards--", "", true},
+	}
+	for _, test := range tests {
+		if got := IsInjected(BuildLogin(test.username, test.password)); got != test.injected {
+			t.Errorf("IsInjected(%q, %q) = %v, want %v", test.username, test.password, got, test.injected)
+		}
+	}
+}
+
+func TestLoginWithValidCredentials(t *testing.T) {
+	enableLab(t)
+
+	attempt, err := Login("alice", "correct-horse", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !attempt.Executed || attempt.Injected || len(attempt.Result.Rows) != 1 {
+		t.Errorf("expected one row for a valid login, got %+v", attempt)
+	}
+}
+
+func TestLoginBypassLeaksRows(t *testing.T) {
+	enableLab(t)
+
+	attempt, err := Login("' OR '1'='1", "' OR '1'='1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !attempt.Injected || len(attempt.Result.Rows) != 3 {
+		t.Errorf("expected the tautology to return every user, got %+v", attempt)
+	}
+}
+
+func TestGuardedLoginRefusesInjection(t *testing.T) {
+	enableLab(t)
+
+	attempt, err := Login("admin'--", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempt.Executed {
+		t.Errorf("expected the guarded login not to run an injected query")
+	}
+}
+
+func TestValidatorBlocksStackedStatements(t *testing.T) {
+	enableLab(t)
+
+	attempt, err := Login("'; DELETE FROM credit_cards; --", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !attempt.Blocked || attempt.Rule != "stacked_statements" || attempt.Executed {
+		t.Errorf("expected the validator to block stacked statements, got %+v", attempt)
+	}
+}
+
+func TestResetRestoresSeedData(t *testing.T) {
+	enableLab(t)
+
+	if _, err := Login("'; DELETE FROM users; --", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Reset(); err != nil {
+		t.Fatal(err)
+	}
+	attempt, err := Login("bob", "letmein", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attempt.Result.Rows) != 1 {
+		t.Errorf("expected the reset to restore the seeded users, got %+v", attempt)
+	}
+}
+
+func TestDisabledLab(t *testing.T) {
+	if _, err := Login("alice", "correct-horse", false); err != ErrDisabled {
+		t.Errorf("expected ErrDisabled, got %v", err)
+	}
+	if err := Reset(); err != ErrDisabled {
+		t.Errorf("expected ErrDisabled, got %v", err)
+	}
+}
+
+func TestInjectedAttachIsRefused(t *testing.T) {
+	enableLab(t)
+	target := filepath.Join(t.TempDir(), "stolen.db")
+
+	for _, username := range []string{
+		"'; ATTACH DATABASE '" + target + "' AS x; CREATE TABLE x.loot (v); --",
+		"'; PRAGMA writable_schema = ON; --",
+		"' UNION SELECT load_extension('/tmp/evil.so'), 1, 1 --",
+	} {
+		attempt, err := Login(username, "", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !attempt.Executed || !strings.Contains(attempt.Error, "not authorized") && !strings.Contains(attempt.Error, "authorization denied") {
+			t.Errorf("expected %q to be refused, got %+v", username, attempt)
+		}
+	}
+	// VACUUM INTO attaches its target when it runs
+	if _, err := db.Exec("VACUUM INTO '" + target + "'"); err == nil {
+		t.Error("expected VACUUM INTO to be refused")
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("expected no file to be written outside the lab, got %v", err)
+	}
+}
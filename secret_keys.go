@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/connections"
+	"example/user/playground/secrets"
+)
+
+// Path of the key file used when SECRETS_KEY and SECRETS_KEY_COMMAND are unset
+const secretKeyPath = "./secrets.key"
+
+// configureSecrets loads the keys encrypting stored credentials
+func configureSecrets() {
+	if err := secrets.Init(secretKeyPath); err != nil {
+		fmt.Printf("Error loading secret encryption keys: %v\n", err)
+	}
+}
+
+// rotateStoredSecrets seals stored credentials again with the primary key
+// once the connection store is open, so previous keys can be retired
+func rotateStoredSecrets() {
+	rotated, err := connections.Rotate()
+	if err != nil {
+		fmt.Printf("Failed to rotate stored credentials: %v\n", secrets.MaskError(err))
+	}
+	if rotated > 0 {
+		fmt.Printf("Re-encrypted %d stored credentials with key %s\n", rotated, secrets.Status().KeyID)
+	}
+}
+
+// getSecretsStatus reports the encryption keys by ID and how many stored
+// credentials still use a previous key
+func getSecretsStatus(c *gin.Context) {
+	stale, err := connections.StaleCount()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to inspect stored credentials: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"keys":  secrets.Status(),
+		"stale": stale,
+	})
+}
+
+// rotateSecrets re-encrypts stored credentials with the primary key. With
+// a key file, a new primary key is generated first unless the body sets
+// "generate_key" to false.
+func rotateSecrets(c *gin.Context) {
+	req := struct {
+		GenerateKey *bool `json:"generate_key"`
+	}{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	generate := secrets.Status().Source == secrets.SourceFile
+	if req.GenerateKey != nil {
+		generate = *req.GenerateKey
+	}
+	if generate {
+		if err := secrets.GenerateKey(); err != nil {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	rotated, err := connections.Rotate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to rotate stored credentials: " + secrets.MaskError(err),
+			"rotated": rotated,
+		})
+		return
+	}
+	recordAudit(c, "", "secrets.rotate", secrets.Status().KeyID, fmt.Sprintf("re-encrypted %d credentials", rotated))
+	c.JSON(http.StatusOK, gin.H{
+		"keys":    secrets.Status(),
+		"rotated": rotated,
+	})
+}
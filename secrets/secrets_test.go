@@ -0,0 +1,153 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestSealAndOpen(t *testing.T) {
+	if err := SetKeys(testKey(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	sealed, err := Seal([]byte("s3cret"), []byte("row:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("s3cret")) {
+		t.Error("expected the secret to be encrypted")
+	}
+	if plaintext, err := Open(sealed, []byte("row:1")); err != nil || string(plaintext) != "s3cret" {
+		t.Errorf("expected the secret to open, got %q, %v", plaintext, err)
+	}
+	if _, err := Open(sealed, []byte("row:2")); err != ErrCorrupt {
+		t.Errorf("expected a secret bound to another record to be rejected, got %v", err)
+	}
+	if Stale(sealed) {
+		t.Error("expected a secret sealed with the primary key not to be stale")
+	}
+}
+
+func TestRotation(t *testing.T) {
+	if err := SetKeys(testKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := Seal([]byte("s3cret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetKeys(testKey(2), testKey(1)); err != nil {
+		t.Fatal(err)
+	}
+	if !Stale(sealed) {
+		t.Error("expected a secret sealed with a previous key to be stale")
+	}
+	if plaintext, err := Open(sealed, nil); err != nil || string(plaintext) != "s3cret" {
+		t.Errorf("expected a previous key to open the secret, got %q, %v", plaintext, err)
+	}
+	if info := Status(); len(info.PreviousKeyIDs) != 1 || info.KeyID == info.PreviousKeyIDs[0] {
+		t.Errorf("expected one previous key, got %+v", info)
+	}
+
+	if err := SetKeys(testKey(2)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(sealed, nil); err != ErrUnknownKey {
+		t.Errorf("expected a retired key to be reported, got %v", err)
+	}
+}
+
+func TestKeyFile(t *testing.T) {
+	t.Setenv("SECRETS_KEY", "")
+	t.Setenv("SECRETS_KEY_COMMAND", "")
+	t.Setenv("SECRETS_PREVIOUS_KEYS", "")
+	path := filepath.Join(t.TempDir(), "secrets.key")
+
+	if err := Init(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected a private key file, got %v, %v", info, err)
+	}
+	sealed, err := Seal([]byte("s3cret"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := GenerateKey(); err != nil {
+		t.Fatal(err)
+	}
+	if !Stale(sealed) {
+		t.Error("expected a generated key to become the primary one")
+	}
+	if _, err := Open(sealed, nil); err != nil {
+		t.Errorf("expected the old key to stay readable, got %v", err)
+	}
+	if info := Status(); info.Source != SourceFile || len(info.PreviousKeyIDs) != 1 {
+		t.Errorf("expected a file key with one previous key, got %+v", info)
+	}
+}
+
+func TestKeyFromEnvironment(t *testing.T) {
+	t.Setenv("SECRETS_KEY", hex.EncodeToString(testKey(3)))
+	t.Setenv("SECRETS_PREVIOUS_KEYS", hex.EncodeToString(testKey(4)))
+	if err := Init(filepath.Join(t.TempDir(), "secrets.key")); err != nil {
+		t.Fatal(err)
+	}
+	if info := Status(); info.Source != SourceEnv || len(info.PreviousKeyIDs) != 1 {
+		t.Errorf("expected an environment key with one previous key, got %+v", info)
+	}
+	if err := GenerateKey(); err == nil {
+		t.Error("expected keys from the environment not to be generated")
+	}
+
+	t.Setenv("SECRETS_KEY", "short")
+	if err := Init(filepath.Join(t.TempDir(), "secrets.key")); err == nil {
+		t.Error("expected an invalid key to be rejected")
+	}
+}
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{
+			"dial postgres://app:s3cret@db:5432/sales failed",
+			"dial postgres://app:***@db:5432/sales failed",
+		},
+		{
+			"host=db user=app password='a b' dbname=sales",
+			"host=db user=app password=*** dbname=sales",
+		},
+		{
+			"postgres://db/sales?user=app&password=s3cret&sslmode=require",
+			"postgres://db/sales?user=app&password=***&sslmode=require",
+		},
+		{
+			"invalid DSN app:s3cret@tcp(db:3306)/sales",
+			"invalid DSN app:***@tcp(db:3306)/sales",
+		},
+		{
+			"pq: password authentication failed for user \"app\"",
+			"pq: password authentication failed for user \"app\"",
+		},
+	}
+	for _, test := range tests {
+		if got := Mask(test.text); got != test.want {
+			t.Errorf("Mask(%q) = %q, want %q", test.text, got, test.want)
+		}
+		if strings.Contains(Mask(test.text), "s3cret") {
+			t.Errorf("Mask(%q) kept the password", test.text)
+		}
+	}
+}
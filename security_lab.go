@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/features"
+	"example/user/playground/seclab"
+)
+
+type SecurityLabLoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Refuse to run queries the validator rejects or the input has altered
+	Guarded bool `json:"guarded"`
+}
+
+// configureSecurityLab prepares the SQL injection lab when its feature
+// flag is on at startup. The lab runs against its own throwaway SQLite
+// database, never the playground's MySQL, PostgreSQL or SQLite backends.
+func configureSecurityLab() {
+	if !features.Enabled(context.Background(), features.SecurityLab) {
+		return
+	}
+	if err := ensureSecurityLab(); err != nil {
+		fmt.Printf("Failed to enable the security lab: %v\n", err)
+		return
+	}
+	fmt.Println("Security lab enabled with a throwaway SQLite database")
+}
+
+// ensureSecurityLab creates the lab database the first time the lab is
+// used, for labs switched on at runtime
+func ensureSecurityLab() error {
+	if seclab.Enabled() {
+		return nil
+	}
+	return seclab.Enable()
+}
+
+// securityLabLogin runs the lab's deliberately vulnerable login query with
+// the submitted credentials
+func securityLabLogin(c *gin.Context) {
+	var req SecurityLabLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+	if err := ensureSecurityLab(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	attempt, err := seclab.Login(req.Username, req.Password, req.Guarded)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, attempt)
+}
+
+// resetSecurityLab restores the lab database to its seeded state
+func resetSecurityLab(c *gin.Context) {
+	if err := ensureSecurityLab(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := seclab.Reset(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reset": true})
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/secrets"
+	"example/user/playground/sqlvalidator"
+)
+
+// Outcomes of a self-test check
+const (
+	selfTestPass = "pass"
+	selfTestFail = "fail"
+	selfTestSkip = "skip"
+)
+
+// Longest query timeout the self-test waits out; longer ones are skipped
+const maxSelfTestTimeout = 30 * time.Second
+
+// Rows the row limit check generates, more than the limit adds
+const selfTestRows = sqlvalidator.DefaultRowLimit * 5
+
+// SelfTestCheck is the outcome of one check against a backend
+type SelfTestCheck struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	DurationMs float64 `json:"duration_ms"`
+	Detail     string  `json:"detail,omitempty"`
+}
+
+// selfTestStep is a check run against a connected backend
+type selfTestStep struct {
+	name string
+	run  func(ctx context.Context, key string, dialect string) (status string, detail string, err error)
+}
+
+// Checks run against every backend, in order
+var selfTestSteps = []selfTestStep{
+	{"select", selfTestSelect},
+	{"dml_rollback", selfTestRollback},
+	{"timeout", selfTestTimeout},
+	{"row_limit", selfTestRowLimit},
+}
+
+// runSelfTest runs canned queries against every backend through the same
+// paths user queries take, so operators can verify a deployment end to end
+// after an upgrade. Backends are tested concurrently; a backend that is
+// down fails its connect check and skips the rest. Checks still running
+// are cancelled when the client disconnects.
+func runSelfTest(c *gin.Context) {
+	statuses := dbmanager.GetConnectionStatuses()
+	results := make(map[string][]SelfTestCheck, len(statuses))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for key, connected := range statuses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checks := selfTestBackend(c.Request.Context(), key, connected)
+			mu.Lock()
+			results[key] = checks
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	passed := true
+	failed := []string{}
+	for _, key := range keys {
+		for _, check := range results[key] {
+			if check.Status == selfTestFail {
+				passed = false
+				failed = append(failed, key+"/"+check.Name)
+			}
+		}
+	}
+
+	detail := "passed"
+	if !passed {
+		detail = fmt.Sprintf("failed: %v", failed)
+	}
+	recordAudit(c, "", "selftest.run", "", detail)
+	c.JSON(http.StatusOK, gin.H{
+		"passed":   passed,
+		"backends": results,
+	})
+}
+
+// selfTestBackend runs every check against one backend
+func selfTestBackend(ctx context.Context, key string, connected bool) []SelfTestCheck {
+	dialect, _ := dbmanager.SplitConnectionKey(key)
+	start := time.Now()
+	connect := SelfTestCheck{Name: "connect", Status: selfTestPass}
+	if _, err := selfTestConnection(key); err != nil || !connected {
+		connect.Status = selfTestFail
+		connect.Detail = "The backend is not connected"
+		if err != nil {
+			connect.Detail = secrets.MaskError(err)
+		}
+	}
+	connect.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+
+	checks := []SelfTestCheck{connect}
+	for _, step := range selfTestSteps {
+		check := SelfTestCheck{Name: step.name, Status: selfTestSkip}
+		if connect.Status != selfTestPass {
+			check.Detail = "Skipped because the backend is not connected"
+			checks = append(checks, check)
+			continue
+		}
+		start := time.Now()
+		status, detail, err := step.run(ctx, key, dialect)
+		check.Status, check.Detail = status, detail
+		if err != nil {
+			check.Status, check.Detail = selfTestFail, secrets.MaskError(err)
+		}
+		check.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// selfTestConnection returns the pool user queries of a backend run on
+func selfTestConnection(key string) (*sql.DB, error) {
+	dialect, version := dbmanager.SplitConnectionKey(key)
+	return dbmanager.GetVersionedConnection(dialect, version)
+}
+
+// selfTestSelect runs a plain SELECT
+func selfTestSelect(ctx context.Context, key string, dialect string) (string, string, error) {
+	db, err := selfTestConnection(key)
+	if err != nil {
+		return "", "", err
+	}
+	results, _, err := executeStatement(ctx, db, "SELECT 1 AS one", dialect, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if rows := results[0].Rows; len(rows) != 1 || fmt.Sprint(rows[0][0]) != "1" {
+		return selfTestFail, fmt.Sprintf("Expected a single row holding 1, got %v", rows), nil
+	}
+	return selfTestPass, "", nil
+}
+
+// selfTestRollback changes every article inside a transaction and checks
+// the change is gone once it is rolled back
+func selfTestRollback(ctx context.Context, key string, dialect string) (string, string, error) {
+	db, err := selfTestConnection(key)
+	if err != nil {
+		return "", "", err
+	}
+	ctx, cancel := queryContext(ctx, dialect)
+	defer cancel()
+
+	const marker = "selftest"
+	countQuery := "SELECT COUNT(*) FROM articles WHERE topic = '" + marker + "'"
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+	result, err := tx.ExecContext(ctx, "UPDATE articles SET topic = '"+marker+"'")
+	if err != nil {
+		return "", "", err
+	}
+	updated, _ := result.RowsAffected()
+	var inside int64
+	if err := tx.QueryRowContext(ctx, countQuery).Scan(&inside); err != nil {
+		return "", "", err
+	}
+	if err := tx.Rollback(); err != nil {
+		return "", "", err
+	}
+
+	var after int64
+	if err := db.QueryRowContext(ctx, countQuery).Scan(&after); err != nil {
+		return "", "", err
+	}
+	if inside == 0 || after != 0 {
+		return selfTestFail, fmt.Sprintf("Expected the update to be visible only inside the transaction, saw %d rows inside and %d after rolling back", inside, after), nil
+	}
+	return selfTestPass, fmt.Sprintf("Updated and rolled back %d rows", updated), nil
+}
+
+// selfTestTimeout runs a statement outlasting the dialect's query timeout
+// and checks it is cut off in time
+func selfTestTimeout(ctx context.Context, key string, dialect string) (string, string, error) {
+	timeout := queryLimits[dialect].Timeout
+	if timeout <= 0 {
+		return selfTestSkip, "No query timeout is configured", nil
+	}
+	if timeout > maxSelfTestTimeout {
+		return selfTestSkip, fmt.Sprintf("The %s query timeout is too long to wait out", timeout), nil
+	}
+	db, err := selfTestConnection(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	sleep := int((timeout + queryTimeoutGrace).Seconds()) + 5
+	var query string
+	switch dialect {
+	case "mysql":
+		// MAX_EXECUTION_TIME cuts SLEEP short without an error
+		query = "SELECT SLEEP(" + strconv.Itoa(sleep) + ")"
+	case "postgresql":
+		query = "SELECT pg_sleep(" + strconv.Itoa(sleep) + ")"
+	default:
+		query = "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 1000000000) SELECT COUNT(*) FROM n"
+	}
+
+	start := time.Now()
+	_, _, err = executeStatement(ctx, db, query, dialect, nil)
+	elapsed := time.Since(start)
+	if elapsed > timeout+queryTimeoutGrace+time.Second {
+		return selfTestFail, fmt.Sprintf("A statement ran for %s despite a timeout of %s", elapsed.Round(time.Millisecond), timeout), nil
+	}
+	if err == nil && dialect != "mysql" {
+		return selfTestFail, "A statement outlasting the timeout finished without an error", nil
+	}
+	return selfTestPass, fmt.Sprintf("Stopped after %s with a timeout of %s", elapsed.Round(time.Millisecond), timeout), nil
+}
+
+// selfTestRowLimit checks that a SELECT without a LIMIT gets the default
+// row limit before it runs
+func selfTestRowLimit(ctx context.Context, key string, dialect string) (string, string, error) {
+	db, err := selfTestConnection(key)
+	if err != nil {
+		return "", "", err
+	}
+	query := fmt.Sprintf("WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < %d) SELECT x FROM n", selfTestRows)
+	executed, rewrites := sqlvalidator.RewriteForExecution(query, dialect)
+	limited := false
+	for _, rewrite := range rewrites {
+		limited = limited || rewrite.Name == "row_limit"
+	}
+	if !limited {
+		return selfTestFail, "No LIMIT was added to an unbounded SELECT", nil
+	}
+	results, _, err := executeStatement(ctx, db, executed, dialect, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if rows := len(results[0].Rows); rows != sqlvalidator.DefaultRowLimit {
+		return selfTestFail, fmt.Sprintf("Expected %d of %d rows, got %d", sqlvalidator.DefaultRowLimit, selfTestRows, rows), nil
+	}
+	return selfTestPass, fmt.Sprintf("Returned %d of %d rows", sqlvalidator.DefaultRowLimit, selfTestRows), nil
+}
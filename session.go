@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Name of the cookie identifying an anonymous editor session
+const sessionCookieName = "playground_session"
+
+// Lifetime of the session cookie in seconds (30 days)
+const sessionCookieMaxAge = 30 * 24 * 60 * 60
+
+// sessionOwner returns the owner key for the current request. Logged-in
+// users own their data; anonymous clients get a session cookie instead.
+func sessionOwner(c *gin.Context) string {
+	if user := currentUser(c); user != nil {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+
+	if id, err := c.Cookie(sessionCookieName); err == nil && id != "" {
+		return "session:" + id
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "session:anonymous"
+	}
+	id := hex.EncodeToString(b)
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName, id, sessionCookieMaxAge, "/", "", secureCookies(), true)
+	return "session:" + id
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/mockdb"
+	"example/user/playground/sharing"
+	"example/user/playground/sqlvalidator"
+)
+
+type ShareRequest struct {
+	SQL     string       `json:"sql" binding:"required"`
+	Dialect string       `json:"dialect" binding:"required"`
+	Result  *QueryResult `json:"result"`
+	// Lifetime of the share in hours, defaults to one week
+	ExpiresInHours int `json:"expires_in_hours"`
+}
+
+// createShare stores a query under a short ID for sharing, up to
+// sharing.MaxSharesPerOwner a day for each session
+func createShare(c *gin.Context) {
+	var req ShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if _, ok := sqlvalidator.Capabilities(req.Dialect); !ok && req.Dialect != mockdb.Dialect {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Unsupported dialect: " + req.Dialect,
+			"errorCode": dberrors.CodeValidationError,
+		})
+		return
+	}
+
+	var result interface{}
+	if req.Result != nil {
+		result = req.Result
+	}
+
+	ttl := time.Duration(req.ExpiresInHours) * time.Hour
+	share, err := sharing.Create(sessionOwner(c), req.SQL, req.Dialect, result, ttl)
+	if err == sharing.ErrLimitReached {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to create share: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         share.ID,
+		"url":        "/?share=" + share.ID,
+		"expires_at": share.ExpiresAt,
+	})
+}
+
+// getShare returns a shared query by ID
+func getShare(c *gin.Context) {
+	share, err := sharing.Get(c.Param("id"))
+	if err == sharing.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load share: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, share)
+}
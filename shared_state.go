@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/secrets"
+	"example/user/playground/sharedstate"
+)
+
+// Window over which query executions are counted for rate limiting
+const rateLimitWindow = time.Minute
+
+// Query executions allowed per client and window; zero disables the limit
+var queriesPerMinute int
+
+// configureSharedState selects where confirmation tokens, shares, locks,
+// seeding jobs and rate limit counters are kept. STATE_BACKEND=redis keeps
+// them in the Redis server at REDIS_URL, so several instances behind a load
+// balancer agree; the default keeps them in memory. It also reads
+// RATE_LIMIT_QUERIES_PER_MINUTE.
+func configureSharedState() {
+	backend := envOr("STATE_BACKEND", sharedstate.BackendMemory)
+	s, err := sharedstate.Open(backend, os.Getenv("REDIS_URL"))
+	if err != nil {
+		log.Fatalf("Error opening %s shared state: %v\n", backend, secrets.MaskError(err))
+	}
+	sharedstate.Use(s)
+	fmt.Printf("Using %s shared state\n", s.Backend())
+
+	if n, err := strconv.Atoi(os.Getenv("RATE_LIMIT_QUERIES_PER_MINUTE")); err == nil && n > 0 {
+		queriesPerMinute = n
+	}
+}
+
+// limitQueryRate rejects query executions beyond the configured number per
+// minute. Logged-in users are counted by account and anonymous clients by
+// address, across every instance sharing state.
+func limitQueryRate(c *gin.Context) {
+	limitRate(c, "rate", queriesPerMinute, "queries")
+}
+
+// limitRate counts a request against a per-minute limit of the client
+// under a key prefix and rejects it with 429 beyond the limit. A limit of
+// zero disables the check.
+func limitRate(c *gin.Context, prefix string, perMinute int, what string) {
+	if allowRate(c, prefix, perMinute, what) {
+		c.Next()
+	}
+}
+
+// allowRate counts a request like limitRate, for handlers that only limit
+// some of their requests. It reports whether the request may go on and
+// otherwise has already answered it.
+func allowRate(c *gin.Context, prefix string, perMinute int, what string) bool {
+	if perMinute == 0 {
+		return true
+	}
+
+	client := "ip:" + c.ClientIP()
+	if user := currentUser(c); user != nil {
+		client = "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	window := time.Now().Truncate(rateLimitWindow)
+	key := fmt.Sprintf("%s:%s:%d", prefix, client, window.Unix())
+
+	count, err := sharedstate.Current().Incr(c.Request.Context(), key, rateLimitWindow)
+	if err != nil {
+		// Let requests through rather than fail them while shared state is down
+		fmt.Printf("Failed to count %s of %s: %v\n", what, client, err)
+		return true
+	}
+	if count > int64(perMinute) {
+		retryAfter := int(time.Until(window.Add(rateLimitWindow)).Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":     fmt.Sprintf("Rate limit of %d %s per minute exceeded", perMinute, what),
+			"errorCode": "rate_limited",
+		})
+		return false
+	}
+	return true
+}
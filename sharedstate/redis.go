@@ -0,0 +1,80 @@
+package sharedstate
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Prefix of every key kept in Redis, so the playground can share a server
+const redisKeyPrefix = "playground:"
+
+// redisStore keeps state in Redis, shared by every instance using it
+type redisStore struct {
+	client *redis.Client
+}
+
+// OpenRedis connects to the Redis server at a redis:// or rediss:// URL
+func OpenRedis(url string) (Store, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &redisStore{client: client}, nil
+}
+
+func (r *redisStore) Backend() string {
+	return BackendRedis
+}
+
+func (r *redisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.Get(ctx, redisKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (r *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, redisKeyPrefix+key, value, ttl).Err()
+}
+
+func (r *redisStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, redisKeyPrefix+key, value, ttl).Result()
+}
+
+func (r *redisStore) GetDel(ctx context.Context, key string) ([]byte, error) {
+	value, err := r.client.GetDel(ctx, redisKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (r *redisStore) Delete(ctx context.Context, key string) error {
+	return r.client.Del(ctx, redisKeyPrefix+key).Err()
+}
+
+func (r *redisStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	key = redisKeyPrefix + key
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		// Create the counter with its expiry first, so it is set only once
+		pipe.SetNX(ctx, key, 0, ttl)
+		incr = pipe.Incr(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
@@ -0,0 +1,195 @@
+package sharedstate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Backends shared state can be kept in
+const (
+	BackendMemory = "memory"
+	BackendRedis  = "redis"
+)
+
+// ErrNotFound is returned for keys that do not exist or have expired
+var ErrNotFound = errors.New("key not found")
+
+// Store keeps short-lived state that every instance behind a load balancer
+// must agree on, such as confirmation tokens, shares, locks and rate limit
+// counters. Keys expire after their TTL; a TTL of zero keeps them forever.
+type Store interface {
+	// Get returns the value of a key
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores a value
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetNX stores a value only when the key does not exist, reporting
+	// whether it did
+	SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// GetDel returns the value of a key and removes it in one step
+	GetDel(ctx context.Context, key string) ([]byte, error)
+	// Delete removes a key
+	Delete(ctx context.Context, key string) error
+	// Incr adds one to a counter and returns the new count. A counter that
+	// does not exist starts at zero and expires after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Backend names where the state is kept
+	Backend() string
+}
+
+var (
+	// Store used by the application, in memory until configured
+	current Store = NewMemory()
+
+	// Guards current
+	mu sync.RWMutex
+)
+
+// Open opens shared state on a backend. Redis takes a redis:// URL.
+func Open(backend string, url string) (Store, error) {
+	switch backend {
+	case BackendMemory, "":
+		return NewMemory(), nil
+	case BackendRedis:
+		return OpenRedis(url)
+	default:
+		return nil, fmt.Errorf("unsupported shared state backend: %s", backend)
+	}
+}
+
+// Use makes s the store returned by Current
+func Use(s Store) {
+	mu.Lock()
+	current = s
+	mu.Unlock()
+}
+
+// Current returns the store in use
+func Current() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Number of writes between sweeps of expired keys in memory
+const sweepEvery = 256
+
+// memoryEntry is a value kept in memory with its expiry
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// memoryStore keeps state in the process, for a single instance
+type memoryStore struct {
+	entries map[string]memoryEntry
+	writes  int
+	mu      sync.Mutex
+}
+
+// NewMemory returns a store kept in the process. Instances using it do not
+// see each other's state.
+func NewMemory() Store {
+	return &memoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (m *memoryStore) Backend() string {
+	return BackendMemory
+}
+
+// get returns a live entry; the caller must hold mu
+func (m *memoryStore) get(key string) (memoryEntry, bool) {
+	entry, ok := m.entries[key]
+	if ok && !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		delete(m.entries, key)
+		return memoryEntry{}, false
+	}
+	return entry, ok
+}
+
+// set stores an entry, sweeping expired ones now and then; the caller
+// must hold mu
+func (m *memoryStore) set(key string, value []byte, ttl time.Duration) {
+	entry := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+	m.entries[key] = entry
+
+	m.writes++
+	if m.writes%sweepEvery == 0 {
+		now := time.Now()
+		for k, e := range m.entries {
+			if !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+	}
+}
+
+func (m *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), entry.value...), nil
+}
+
+func (m *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.set(key, value, ttl)
+	return nil
+}
+
+func (m *memoryStore) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.get(key); ok {
+		return false, nil
+	}
+	m.set(key, value, ttl)
+	return true, nil
+}
+
+func (m *memoryStore) GetDel(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.get(key)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(m.entries, key)
+	return entry.value, nil
+}
+
+func (m *memoryStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memoryStore) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.get(key)
+	var count int64
+	if ok {
+		fmt.Sscan(string(entry.value), &count)
+	}
+	count++
+	value := []byte(fmt.Sprint(count))
+	if ok {
+		// Keep the expiry of an existing counter
+		entry.value = value
+		m.entries[key] = entry
+	} else {
+		m.set(key, value, ttl)
+	}
+	return count, nil
+}
@@ -0,0 +1,62 @@
+package sharedstate
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemory()
+	ctx := context.Background()
+
+	if _, err := s.Get(ctx, "missing"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	s.Set(ctx, "key", []byte("value"), 0)
+	if value, err := s.Get(ctx, "key"); err != nil || string(value) != "value" {
+		t.Errorf("expected the stored value, got %q, %v", value, err)
+	}
+
+	if ok, _ := s.SetNX(ctx, "key", []byte("other"), 0); ok {
+		t.Error("expected SetNX not to replace an existing key")
+	}
+	if value, err := s.GetDel(ctx, "key"); err != nil || string(value) != "value" {
+		t.Errorf("expected GetDel to return the value, got %q, %v", value, err)
+	}
+	if _, err := s.GetDel(ctx, "key"); err != ErrNotFound {
+		t.Errorf("expected GetDel to remove the key, got %v", err)
+	}
+	if ok, _ := s.SetNX(ctx, "key", []byte("other"), 0); !ok {
+		t.Error("expected SetNX to store a missing key")
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	s := NewMemory()
+	ctx := context.Background()
+
+	s.Set(ctx, "short", []byte("value"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, err := s.Get(ctx, "short"); err != ErrNotFound {
+		t.Errorf("expected the key to expire, got %v", err)
+	}
+	if ok, _ := s.SetNX(ctx, "short", []byte("again"), 0); !ok {
+		t.Error("expected SetNX to replace an expired key")
+	}
+}
+
+func TestMemoryIncr(t *testing.T) {
+	s := NewMemory()
+	ctx := context.Background()
+
+	for want := int64(1); want <= 3; want++ {
+		if got, err := s.Incr(ctx, "counter", time.Millisecond*20); err != nil || got != want {
+			t.Errorf("expected count %d, got %d, %v", want, got, err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got, _ := s.Incr(ctx, "counter", time.Minute); got != 1 {
+		t.Errorf("expected the counter to restart after its window, got %d", got)
+	}
+}
@@ -0,0 +1,151 @@
+package sharing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"example/user/playground/sharedstate"
+)
+
+// Default lifetime of a shared query
+const DefaultExpiration = 7 * 24 * time.Hour
+
+// Longest lifetime a shared query may request
+const MaxExpiration = 30 * 24 * time.Hour
+
+// Most queries an owner may share a day
+const MaxSharesPerOwner = 100
+
+// Window the shares of an owner are counted in
+const shareQuotaWindow = 24 * time.Hour
+
+// Characters used for share IDs
+const idAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Length of generated share IDs
+const idLength = 8
+
+var (
+	// ErrNotFound is returned when a share does not exist or has expired
+	ErrNotFound = errors.New("shared query not found or expired")
+
+	// ErrLimitReached is returned when an owner has shared the most queries
+	// allowed a day
+	ErrLimitReached = fmt.Errorf("at most %d queries can be shared a day", MaxSharesPerOwner)
+)
+
+// SharedQuery is a query stored under a short ID
+type SharedQuery struct {
+	ID        string      `json:"id"`
+	SQL       string      `json:"sql"`
+	Dialect   string      `json:"dialect"`
+	Result    interface{} `json:"result,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	Views     int         `json:"views"`
+}
+
+// shareKey returns the shared state key of a share
+func shareKey(id string) string {
+	return "share:" + id
+}
+
+// Create stores a query for its owner and returns the new share. Shares are
+// kept in shared state so every instance can serve them, and each owner
+// may create MaxSharesPerOwner of them a day.
+func Create(owner string, sql string, dialect string, result interface{}, ttl time.Duration) (*SharedQuery, error) {
+	state := sharedstate.Current()
+	ctx := context.Background()
+
+	window := time.Now().Truncate(shareQuotaWindow)
+	count, err := state.Incr(ctx, fmt.Sprintf("shares:%s:%d", owner, window.Unix()), shareQuotaWindow)
+	if err != nil {
+		return nil, err
+	}
+	if count > MaxSharesPerOwner {
+		return nil, ErrLimitReached
+	}
+
+	if ttl <= 0 {
+		ttl = DefaultExpiration
+	}
+	if ttl > MaxExpiration {
+		ttl = MaxExpiration
+	}
+
+	now := time.Now()
+	share := &SharedQuery{
+		SQL:       sql,
+		Dialect:   dialect,
+		Result:    result,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	data, err := json.Marshal(share)
+	if err != nil {
+		return nil, err
+	}
+
+	// Regenerate on the unlikely event of a collision
+	for {
+		if share.ID, err = newID(); err != nil {
+			return nil, err
+		}
+		stored, err := state.SetNX(ctx, shareKey(share.ID), data, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if stored {
+			return share, nil
+		}
+	}
+}
+
+// Get returns a shared query and increments its view counter
+func Get(id string) (*SharedQuery, error) {
+	state := sharedstate.Current()
+	ctx := context.Background()
+
+	data, err := state.Get(ctx, shareKey(id))
+	if err == sharedstate.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var share SharedQuery
+	if err := json.Unmarshal(data, &share); err != nil {
+		return nil, err
+	}
+	share.ID = id
+	ttl := time.Until(share.ExpiresAt)
+	if ttl <= 0 {
+		return nil, ErrNotFound
+	}
+
+	views, err := state.Incr(ctx, shareKey(id)+":views", ttl)
+	if err != nil {
+		return nil, err
+	}
+	share.Views = int(views)
+	return &share, nil
+}
+
+// newID generates a random share ID
+func newID() (string, error) {
+	id := make([]byte, idLength)
+	max := big.NewInt(int64(len(idAlphabet)))
+	for i := range id {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		id[i] = idAlphabet[n.Int64()]
+	}
+	return string(id), nil
+}
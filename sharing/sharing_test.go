@@ -0,0 +1,49 @@
+package sharing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCreateAndGetCountsViews(t *testing.T) {
+	share, err := Create("session:test", "SELECT 1", "sqlite", nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 1; i <= 2; i++ {
+		got, err := Get(share.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Views != i {
+			t.Errorf("expected %d views, got %d", i, got.Views)
+		}
+	}
+}
+
+func TestGetExpiredShare(t *testing.T) {
+	share, err := Create("session:test", "SELECT 1", "sqlite", nil, time.Nanosecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, err := Get(share.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCreateLimitsSharesPerOwner(t *testing.T) {
+	for i := 0; i < MaxSharesPerOwner; i++ {
+		if _, err := Create("session:busy", "SELECT 1", "sqlite", nil, 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if _, err := Create("session:busy", "SELECT 1", "sqlite", nil, 0); err != ErrLimitReached {
+		t.Errorf("expected ErrLimitReached, got %v", err)
+	}
+	if _, err := Create("session:other", "SELECT 1", "sqlite", nil, 0); err != nil {
+		t.Errorf("expected other owners to share, got %v", err)
+	}
+}
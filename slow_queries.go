@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/execpool"
+	"example/user/playground/slowlog"
+	"example/user/playground/sqlvalidator"
+)
+
+type SlowQueryThresholdRequest struct {
+	ThresholdMs int `json:"threshold_ms"`
+}
+
+// configureSlowQueryLog reads the slow query threshold from SLOW_QUERY_THRESHOLD
+func configureSlowQueryLog() {
+	value := os.Getenv("SLOW_QUERY_THRESHOLD")
+	if value == "" {
+		return
+	}
+	if threshold, err := time.ParseDuration(value); err == nil {
+		slowlog.SetThreshold(threshold)
+	}
+}
+
+// recordQueryTiming adds an executed query to the history and the slow
+// query log and, for slow SELECT statements, attaches a plan summary in the
+// background. The log keeps the query with its string literals masked and
+// a hash of the session, since admins read it across sessions.
+func recordQueryTiming(owner string, db *sql.DB, dialect string, query string, duration time.Duration, err error) {
+	entry := &slowlog.Entry{
+		Dialect:    dialect,
+		SQL:        sqlvalidator.MaskSQL(query, dialect),
+		Duration:   duration,
+		Session:    slowlog.SessionID(owner),
+		ExecutedAt: time.Now(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	recordHistory(owner, dialect, query, duration, err)
+
+	if !slowlog.Record(entry) || err != nil {
+		return
+	}
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(query)), "select") {
+		return
+	}
+
+	go runOnPool(context.Background(), dialect, execpool.Background, func(ctx context.Context) gin.H {
+		if summary, err := dbmanager.ExplainQuery(ctx, db, dialect, query); err == nil {
+			slowlog.SetPlanSummary(entry, summary)
+		}
+		return gin.H{}
+	})
+}
+
+// getSlowQueries returns the slowest recent queries per dialect
+func getSlowQueries(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threshold_ms": slowlog.Threshold().Milliseconds(),
+		"queries":      slowlog.Slowest(c.Query("dialect"), limit),
+	})
+}
+
+// setSlowQueryThreshold changes the slow query logging threshold
+func setSlowQueryThreshold(c *gin.Context) {
+	var req SlowQueryThresholdRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ThresholdMs < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: threshold_ms must be a non-negative number",
+		})
+		return
+	}
+
+	slowlog.SetThreshold(time.Duration(req.ThresholdMs) * time.Millisecond)
+	recordAudit(c, "", "slow_queries.threshold", "", fmt.Sprintf("%d ms", req.ThresholdMs))
+	c.JSON(http.StatusOK, gin.H{
+		"threshold_ms": req.ThresholdMs,
+	})
+}
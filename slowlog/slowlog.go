@@ -0,0 +1,124 @@
+package slowlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of recent queries kept for the leaderboard
+const maxEntries = 1000
+
+// Default duration above which a query is logged as slow
+const DefaultThreshold = 500 * time.Millisecond
+
+// Entry is a single timed query execution
+type Entry struct {
+	Dialect     string        `json:"dialect"`
+	SQL         string        `json:"sql"`
+	Duration    time.Duration `json:"-"`
+	DurationMs  float64       `json:"duration_ms"`
+	PlanSummary string        `json:"plan_summary,omitempty"`
+	// Hash of the session that ran the query, from SessionID
+	Session    string    `json:"session"`
+	ExecutedAt time.Time `json:"executed_at"`
+	Error      string    `json:"error,omitempty"`
+}
+
+var (
+	// Ring buffer of recent entries
+	entries = make([]*Entry, 0, maxEntries)
+
+	// Position of the oldest entry once the buffer is full
+	next int
+
+	// Queries slower than this are printed to the server log
+	threshold = DefaultThreshold
+
+	// Guards entries, next and threshold
+	mu sync.Mutex
+)
+
+// SetThreshold changes the duration above which queries are logged as slow
+func SetThreshold(d time.Duration) {
+	mu.Lock()
+	threshold = d
+	mu.Unlock()
+}
+
+// Threshold returns the current slow query threshold
+func Threshold() time.Duration {
+	mu.Lock()
+	defer mu.Unlock()
+	return threshold
+}
+
+// Record stores a timed query and returns true if it exceeded the threshold
+func Record(entry *Entry) bool {
+	entry.DurationMs = float64(entry.Duration.Microseconds()) / 1000
+
+	mu.Lock()
+	if len(entries) < maxEntries {
+		entries = append(entries, entry)
+	} else {
+		entries[next] = entry
+		next = (next + 1) % maxEntries
+	}
+	slow := threshold > 0 && entry.Duration >= threshold
+	mu.Unlock()
+
+	if slow {
+		fmt.Printf("Slow %s query (%.1fms) from %s: %s\n",
+			entry.Dialect, entry.DurationMs, entry.Session, singleLine(entry.SQL))
+	}
+	return slow
+}
+
+// SetPlanSummary attaches a plan summary to a recorded entry
+func SetPlanSummary(entry *Entry, summary string) {
+	mu.Lock()
+	entry.PlanSummary = summary
+	mu.Unlock()
+}
+
+// Slowest returns the slowest recent queries per dialect, limited to limit
+// entries each. An empty dialect returns all dialects.
+func Slowest(dialect string, limit int) map[string][]Entry {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make(map[string][]Entry)
+	for _, entry := range entries {
+		if dialect != "" && entry.Dialect != dialect {
+			continue
+		}
+		result[entry.Dialect] = append(result[entry.Dialect], *entry)
+	}
+
+	for name, list := range result {
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Duration > list[j].Duration
+		})
+		if limit > 0 && len(list) > limit {
+			list = list[:limit]
+		}
+		result[name] = list
+	}
+	return result
+}
+
+// SessionID returns a short hash of an owner key, which tells the queries
+// of a session apart without revealing the cookie the key holds
+func SessionID(owner string) string {
+	sum := sha256.Sum256([]byte(owner))
+	return hex.EncodeToString(sum[:6])
+}
+
+// singleLine collapses whitespace so a query fits on one log line
+func singleLine(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
@@ -0,0 +1,92 @@
+package slowlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// reset empties the log and restores the default threshold
+func reset(t *testing.T) {
+	t.Helper()
+	mu.Lock()
+	entries = entries[:0]
+	next = 0
+	threshold = DefaultThreshold
+	mu.Unlock()
+	t.Cleanup(func() { SetThreshold(DefaultThreshold) })
+}
+
+func TestRecordKeepsRecentEntries(t *testing.T) {
+	reset(t)
+	for i := 0; i < maxEntries+5; i++ {
+		Record(&Entry{Dialect: "sqlite", SQL: fmt.Sprintf("SELECT %d", i), Duration: time.Duration(i) * time.Microsecond})
+	}
+
+	all := Slowest("sqlite", 0)["sqlite"]
+	if len(all) != maxEntries {
+		t.Fatalf("expected %d entries, got %d", maxEntries, len(all))
+	}
+	for _, entry := range all {
+		if entry.SQL == "SELECT 0" || entry.SQL == "SELECT 4" {
+			t.Errorf("expected the oldest entries to be replaced, found %q", entry.SQL)
+		}
+	}
+	if all[0].SQL != fmt.Sprintf("SELECT %d", maxEntries+4) {
+		t.Errorf("expected the newest entry to be kept, got %q first", all[0].SQL)
+	}
+}
+
+func TestSlowestSortsAndLimits(t *testing.T) {
+	reset(t)
+	Record(&Entry{Dialect: "mysql", SQL: "fast", Duration: time.Millisecond})
+	Record(&Entry{Dialect: "mysql", SQL: "slowest", Duration: 3 * time.Millisecond})
+	Record(&Entry{Dialect: "mysql", SQL: "slow", Duration: 2 * time.Millisecond})
+	Record(&Entry{Dialect: "postgresql", SQL: "other", Duration: time.Second})
+
+	mysql := Slowest("mysql", 2)
+	if len(mysql) != 1 || len(mysql["mysql"]) != 2 {
+		t.Fatalf("expected the two slowest MySQL queries only, got %+v", mysql)
+	}
+	if mysql["mysql"][0].SQL != "slowest" || mysql["mysql"][1].SQL != "slow" {
+		t.Errorf("expected the slowest first, got %+v", mysql["mysql"])
+	}
+	if mysql["mysql"][0].DurationMs != 3 {
+		t.Errorf("expected duration_ms to be set, got %v", mysql["mysql"][0].DurationMs)
+	}
+
+	if all := Slowest("", 0); len(all) != 2 || len(all["postgresql"]) != 1 {
+		t.Errorf("expected every dialect, got %+v", all)
+	}
+}
+
+func TestRecordReportsSlowQueries(t *testing.T) {
+	reset(t)
+	SetThreshold(10 * time.Millisecond)
+	if Threshold() != 10*time.Millisecond {
+		t.Fatalf("expected the new threshold, got %s", Threshold())
+	}
+	if Record(&Entry{Dialect: "sqlite", SQL: "SELECT 1", Duration: 5 * time.Millisecond}) {
+		t.Error("expected a query under the threshold not to be slow")
+	}
+	if !Record(&Entry{Dialect: "sqlite", SQL: "SELECT 2", Duration: 10 * time.Millisecond}) {
+		t.Error("expected a query at the threshold to be slow")
+	}
+
+	// A threshold of zero turns slow query logging off
+	SetThreshold(0)
+	if Record(&Entry{Dialect: "sqlite", SQL: "SELECT 3", Duration: time.Hour}) {
+		t.Error("expected no slow queries without a threshold")
+	}
+}
+
+func TestSessionIDHidesOwner(t *testing.T) {
+	owner := "session:3f9a1c0e7b2d"
+	id := SessionID(owner)
+	if id == owner || len(id) != 12 {
+		t.Errorf("expected a 12 character hash, got %q", id)
+	}
+	if SessionID(owner) != id || SessionID("session:other") == id {
+		t.Error("expected the same owner to hash alike and others not to")
+	}
+}
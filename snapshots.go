@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+)
+
+// Default interval between automatic SQLite snapshots
+const defaultSnapshotInterval = 15 * time.Minute
+
+type SnapshotRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// snapshotInterval reads the automatic snapshot interval from SQLITE_SNAPSHOT_INTERVAL
+func snapshotInterval() time.Duration {
+	value := os.Getenv("SQLITE_SNAPSHOT_INTERVAL")
+	if value == "" {
+		return defaultSnapshotInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultSnapshotInterval
+	}
+	return interval
+}
+
+// listSnapshots returns all stored SQLite snapshots
+func listSnapshots(c *gin.Context) {
+	snapshots, err := dbmanager.ListSQLiteSnapshots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list snapshots: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// createSnapshot snapshots the SQLite database under the requested name
+func createSnapshot(c *gin.Context) {
+	var req SnapshotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+
+	snapshot, err := dbmanager.CreateSQLiteSnapshot(c.Request.Context(), req.Name)
+	if err == dbmanager.ErrSnapshotExists {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+// restoreSnapshot restores the SQLite database from a named snapshot and
+// reloads its schema
+func restoreSnapshot(c *gin.Context) {
+	name := c.Param("name")
+	if err := dbmanager.RestoreSQLiteSnapshot(c.Request.Context(), name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	// The restored tables replace whatever the cached schema describes
+	reloadSchema("sqlite")
+	c.JSON(http.StatusOK, gin.H{
+		"restored": name,
+	})
+}
+
+// deleteSnapshot removes a named SQLite snapshot
+func deleteSnapshot(c *gin.Context) {
+	name := c.Param("name")
+	if err := dbmanager.DeleteSQLiteSnapshot(name); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": name,
+	})
+}
@@ -0,0 +1,304 @@
+package sqlinsert
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"example/user/playground/resultvalues"
+	"example/user/playground/sqlvalidator"
+)
+
+// Rows per INSERT statement by default and at most
+const (
+	DefaultBatchSize = 100
+	MaxBatchSize     = 1000
+)
+
+// DefaultTable names the table of statements generated without one
+const DefaultTable = "results"
+
+// Options of generated statements
+type Options struct {
+	// Table to insert into, quoted as the target dialect needs
+	Table string
+	// Dialect the statements are written for
+	Dialect string
+	// Dialect the rows were read from, which decides how structured values
+	// are understood; the target dialect when empty
+	SourceDialect string
+	// Rows per statement; DefaultBatchSize when 0
+	BatchSize int
+}
+
+// Statements writes rows as INSERT statements for the target dialect, one
+// per batch of rows, each listing the columns and ending with a semicolon.
+// It fails for a value the target dialect cannot hold, such as NaN outside
+// PostgreSQL.
+func Statements(columns []string, rows [][]interface{}, opts Options) (string, error) {
+	capabilities, ok := sqlvalidator.Capabilities(opts.Dialect)
+	if !ok {
+		return "", fmt.Errorf("unsupported SQL dialect: %s", opts.Dialect)
+	}
+	if opts.BatchSize < 0 || opts.BatchSize > MaxBatchSize {
+		return "", fmt.Errorf("batch size must be between 1 and %d", MaxBatchSize)
+	}
+	batchSize := opts.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultBatchSize
+	}
+	table := strings.TrimSpace(opts.Table)
+	if table == "" {
+		table = DefaultTable
+	}
+	if len(columns) == 0 {
+		return "", fmt.Errorf("the result has no columns to insert")
+	}
+	w := writer{capabilities: capabilities, sourceDialect: opts.SourceDialect}
+	if w.sourceDialect == "" {
+		w.sourceDialect = opts.Dialect
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = capabilities.Quote(column)
+	}
+	head := "INSERT INTO " + capabilities.Quote(table) + " (" + strings.Join(quoted, ", ") + ") VALUES\n"
+
+	var b strings.Builder
+	for start := 0; start < len(rows); start += batchSize {
+		end := start + batchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+		b.WriteString(head)
+		for r, row := range rows[start:end] {
+			b.WriteString("  (")
+			for i := range columns {
+				if i > 0 {
+					b.WriteString(", ")
+				}
+				var value interface{}
+				if i < len(row) {
+					value = row[i]
+				}
+				literal, err := w.literal(value)
+				if err != nil {
+					return "", fmt.Errorf("row %d, column %s: %v", start+r+1, columns[i], err)
+				}
+				b.WriteString(literal)
+			}
+			if start+r == end-1 {
+				b.WriteString(");\n")
+			} else {
+				b.WriteString("),\n")
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// writer writes result values as literals of a target dialect
+type writer struct {
+	capabilities  sqlvalidator.DialectCapabilities
+	sourceDialect string
+}
+
+// postgres reports whether the target dialect is PostgreSQL
+func (w writer) postgres() bool {
+	return w.capabilities.Name == "postgresql"
+}
+
+// literal writes a value as a SQL literal
+func (w writer) literal(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
+	case bool:
+		if w.postgres() {
+			return strings.ToUpper(strconv.FormatBool(v)), nil
+		}
+		// MySQL's TRUE is 1, and SQLite has no boolean type
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return w.float(float64(v))
+	case float64:
+		return w.float(v)
+	case json.Number:
+		// Numbers of results read back from JSON keep their digits
+		return string(v), nil
+	case string:
+		return w.capabilities.StringLiteral(v), nil
+	case *resultvalues.Binary:
+		return w.literal(v.Data)
+	case *resultvalues.Truncated:
+		return w.capabilities.StringLiteral(v.Text), nil
+	case []byte:
+		if w.postgres() {
+			return `'\x` + hex.EncodeToString(v) + "'", nil
+		}
+		return "X'" + hex.EncodeToString(v) + "'", nil
+	case time.Time:
+		return w.capabilities.StringLiteral(w.timeText(v)), nil
+	case json.RawMessage:
+		return w.capabilities.StringLiteral(string(v)), nil
+	case []interface{}:
+		switch {
+		case w.postgres():
+			text, err := w.arrayText(v)
+			if err != nil {
+				return "", err
+			}
+			return w.capabilities.StringLiteral(text), nil
+		case w.sourceDialect == "mysql" && w.capabilities.Name == "mysql":
+			// MySQL results hold slices only for SET columns
+			members := make([]string, len(v))
+			for i, member := range v {
+				members[i] = fmt.Sprint(member)
+			}
+			return w.capabilities.StringLiteral(strings.Join(members, ",")), nil
+		}
+	case *resultvalues.Range:
+		if w.postgres() {
+			text, err := w.rangeText(v)
+			if err != nil {
+				return "", err
+			}
+			return w.capabilities.StringLiteral(text), nil
+		}
+	}
+
+	// Other structured values, such as arrays outside PostgreSQL, JSON
+	// documents and geometries, are written as JSON text
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return w.capabilities.StringLiteral(string(encoded)), nil
+}
+
+// float writes a number; only PostgreSQL has NaN and infinities
+func (w writer) float(v float64) (string, error) {
+	switch {
+	case !math.IsNaN(v) && !math.IsInf(v, 0):
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case !w.postgres():
+		return "", fmt.Errorf("%s cannot store %v", w.capabilities.Name, v)
+	case math.IsNaN(v):
+		return "'NaN'", nil
+	case v > 0:
+		return "'Infinity'", nil
+	default:
+		return "'-Infinity'", nil
+	}
+}
+
+// timeText writes a time as the text of a date or timestamp literal: a
+// date when it falls on midnight without an offset, and otherwise a
+// timestamp carrying its offset on PostgreSQL and its wall time elsewhere
+func (w writer) timeText(v time.Time) string {
+	if _, offset := v.Zone(); offset == 0 && v.Hour() == 0 && v.Minute() == 0 && v.Second() == 0 && v.Nanosecond() == 0 {
+		return v.Format("2006-01-02")
+	}
+	if w.postgres() {
+		return v.Format("2006-01-02 15:04:05.999999-07:00")
+	}
+	return v.Format("2006-01-02 15:04:05.999999")
+}
+
+// arrayText writes a slice as a PostgreSQL array literal such as
+// {1,NULL,"a b"}, with nested slices as further dimensions
+func (w writer) arrayText(values []interface{}) (string, error) {
+	elements := make([]string, len(values))
+	for i, value := range values {
+		if nested, ok := value.([]interface{}); ok {
+			text, err := w.arrayText(nested)
+			if err != nil {
+				return "", err
+			}
+			elements[i] = text
+			continue
+		}
+		if value == nil {
+			elements[i] = "NULL"
+			continue
+		}
+		text, err := w.elementText(value)
+		if err != nil {
+			return "", err
+		}
+		elements[i] = text
+	}
+	return "{" + strings.Join(elements, ",") + "}", nil
+}
+
+// rangeText writes a range as a PostgreSQL range literal such as [1,5)
+func (w writer) rangeText(r *resultvalues.Range) (string, error) {
+	if r.Empty {
+		return "empty", nil
+	}
+	bounds := r.Bounds
+	if len(bounds) != 2 {
+		bounds = "[)"
+	}
+	var lower, upper string
+	var err error
+	if r.Lower != nil {
+		if lower, err = w.elementText(r.Lower); err != nil {
+			return "", err
+		}
+	}
+	if r.Upper != nil {
+		if upper, err = w.elementText(r.Upper); err != nil {
+			return "", err
+		}
+	}
+	return bounds[:1] + lower + "," + upper + bounds[1:], nil
+}
+
+// elementText writes a value inside an array or range literal, quoting
+// anything but numbers and booleans
+func (w writer) elementText(value interface{}) (string, error) {
+	var text string
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "t", nil
+		}
+		return "f", nil
+	case int, int32, int64, uint64, json.Number:
+		return fmt.Sprint(v), nil
+	case float32:
+		literal, err := w.float(float64(v))
+		return strings.Trim(literal, "'"), err
+	case float64:
+		literal, err := w.float(v)
+		return strings.Trim(literal, "'"), err
+	case string:
+		text = v
+	case time.Time:
+		text = w.timeText(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		text = string(encoded)
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`, nil
+}
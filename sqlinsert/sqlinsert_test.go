@@ -0,0 +1,117 @@
+package sqlinsert
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"example/user/playground/resultvalues"
+)
+
+func TestStatementsBatches(t *testing.T) {
+	rows := [][]interface{}{
+		{int64(1), "Ada"},
+		{int64(2), nil},
+		{int64(3), "O'Brien"},
+	}
+	got, err := Statements([]string{"id", "name"}, rows, Options{Table: "users", Dialect: "sqlite", BatchSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "INSERT INTO users (id, name) VALUES\n" +
+		"  (1, 'Ada'),\n" +
+		"  (2, NULL);\n" +
+		"INSERT INTO users (id, name) VALUES\n" +
+		"  (3, 'O''Brien');\n"
+	if got != want {
+		t.Errorf("Statements() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStatementsQuoting(t *testing.T) {
+	tests := []struct {
+		dialect string
+		want    string
+	}{
+		{"mysql", "INSERT INTO `Top customers` (`order`, Total) VALUES\n  ('a\\\\b', 1);\n"},
+		{"postgresql", "INSERT INTO \"Top customers\" (\"order\", \"Total\") VALUES\n  ('a\\b', TRUE);\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			got, err := Statements([]string{"order", "Total"}, [][]interface{}{{`a\b`, true}}, Options{Table: "Top customers", Dialect: tt.dialect})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Statements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLiterals(t *testing.T) {
+	noon := time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("", 3600))
+	tests := []struct {
+		name    string
+		dialect string
+		source  string
+		value   interface{}
+		want    string
+	}{
+		{"date", "mysql", "", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), "'2024-03-01'"},
+		{"timestamp", "mysql", "", noon, "'2024-03-01 12:30:00'"},
+		{"timestamptz", "postgresql", "", noon, "'2024-03-01 12:30:00+01:00'"},
+		{"bytes", "sqlite", "", []byte{0xde, 0xad}, "X'dead'"},
+		{"bytea", "postgresql", "", []byte{0xde, 0xad}, `'\xdead'`},
+		{"json", "sqlite", "", json.RawMessage(`{"a":1}`), `'{"a":1}'`},
+		{"array", "postgresql", "", []interface{}{int64(1), nil, "a \"b\""}, `'{1,NULL,"a \"b\""}'`},
+		{"nested array", "postgresql", "", []interface{}{[]interface{}{int64(1)}, []interface{}{int64(2)}}, `'{{1},{2}}'`},
+		{"array elsewhere", "sqlite", "postgresql", []interface{}{int64(1), "a"}, `'[1,"a"]'`},
+		{"set", "mysql", "mysql", []interface{}{"a", "b"}, "'a,b'"},
+		{"range", "postgresql", "", &resultvalues.Range{Lower: int64(1), Upper: int64(5), Bounds: "[)"}, "'[1,5)'"},
+		{"unbounded range", "postgresql", "", &resultvalues.Range{Lower: int64(1), Bounds: "[)"}, "'[1,)'"},
+		{"empty range", "postgresql", "", &resultvalues.Range{Empty: true}, "'empty'"},
+		{"nan", "postgresql", "", math.NaN(), "'NaN'"},
+		{"boolean", "sqlite", "", false, "0"},
+		{"stored number", "mysql", "", json.Number("12.50"), "12.50"},
+		{"binary value", "sqlite", "", resultvalues.NewBinary([]byte{0xde, 0xad}), "X'dead'"},
+		{"truncated text", "sqlite", "", resultvalues.Truncate("it's long", 2), "'it''s long'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Statements([]string{"v"}, [][]interface{}{{tt.value}}, Options{Table: "t", Dialect: tt.dialect, SourceDialect: tt.source})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := "INSERT INTO t (v) VALUES\n  (" + tt.want + ");\n"
+			if got != want {
+				t.Errorf("Statements() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestStatementsRejectsUnrepresentableValues(t *testing.T) {
+	_, err := Statements([]string{"v"}, [][]interface{}{{int64(1)}, {math.Inf(1)}}, Options{Dialect: "mysql"})
+	if err == nil || !strings.Contains(err.Error(), "row 2, column v") {
+		t.Errorf("expected an error naming row 2, got %v", err)
+	}
+}
+
+func TestStatementsDefaults(t *testing.T) {
+	got, err := Statements([]string{"id"}, [][]interface{}{{int64(1)}}, Options{Dialect: "sqlite"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(got, "INSERT INTO results (id)") {
+		t.Errorf("expected the default table, got %q", got)
+	}
+	if _, err := Statements([]string{"id"}, nil, Options{Dialect: "oracle"}); err == nil {
+		t.Error("expected an error for an unsupported dialect")
+	}
+	if _, err := Statements([]string{"id"}, nil, Options{Dialect: "sqlite", BatchSize: MaxBatchSize + 1}); err == nil {
+		t.Error("expected an error for an oversized batch")
+	}
+}
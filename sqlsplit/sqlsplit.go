@@ -0,0 +1,319 @@
+package sqlsplit
+
+import (
+	"strings"
+)
+
+// Statement is a single statement of a script
+type Statement struct {
+	// The statement without its delimiter and surrounding whitespace
+	Text string
+	// Byte offset of Text in the script
+	Offset int
+}
+
+// Words naming a routine or trigger in a CREATE statement, whose body may
+// hold statements ending in semicolons between BEGIN and END
+var routineKinds = map[string]bool{
+	"procedure": true,
+	"function":  true,
+	"trigger":   true,
+	"event":     true,
+}
+
+// Words after END that close a MySQL compound statement rather than a
+// BEGIN block or CASE
+var compoundEnds = map[string]bool{
+	"if":     true,
+	"loop":   true,
+	"while":  true,
+	"repeat": true,
+}
+
+// Split splits a script into its statements where the dialect ends them:
+// on semicolons outside strings, quoted identifiers and comments. Semicolons
+// also stay inside PostgreSQL dollar-quoted strings and the BEGIN ... END
+// bodies of routines and triggers, and a MySQL script may change the
+// delimiter with the client's DELIMITER command, which is not a statement
+// itself. Statements holding only whitespace and comments are dropped; an
+// unterminated string or comment runs to the end of the script.
+func Split(sql string, dialect string) []Statement {
+	s := &splitter{sql: sql, dialect: dialect, delimiter: ";", statements: []Statement{}}
+	s.split()
+	return s.statements
+}
+
+// Texts splits a script as Split does and returns the statements' text
+func Texts(sql string, dialect string) []string {
+	statements := Split(sql, dialect)
+	texts := make([]string, len(statements))
+	for i, stmt := range statements {
+		texts[i] = stmt.Text
+	}
+	return texts
+}
+
+// At returns the statement of a script an editor cursor at offset is in:
+// the statement around it, or else the last one before it, as when the
+// cursor follows a statement's semicolon, or else the first one after it.
+// It is false when the script has no statements.
+func At(sql string, dialect string, offset int) (Statement, bool) {
+	statements := Split(sql, dialect)
+	if len(statements) == 0 {
+		return Statement{}, false
+	}
+	at := statements[0]
+	for _, stmt := range statements {
+		if stmt.Offset > offset {
+			break
+		}
+		at = stmt
+	}
+	return at, true
+}
+
+// splitter scans a script, keeping the state of the current statement
+type splitter struct {
+	sql        string
+	dialect    string
+	delimiter  string
+	statements []Statement
+
+	// Where the current statement starts
+	start int
+	// Whether the current statement has anything but whitespace and comments
+	code bool
+	// Words seen in the current statement, and the last of them
+	words    int
+	previous string
+	// Whether the statement is a CREATE statement, and of a routine
+	create  bool
+	routine bool
+	// Nesting of BEGIN and CASE blocks in a routine body
+	depth int
+}
+
+func (s *splitter) split() {
+	sql := s.sql
+	for i := 0; i < len(sql); {
+		if !s.code && s.dialect == "mysql" {
+			if end, delimiter, ok := delimiterCommand(sql, i); ok {
+				s.delimiter = delimiter
+				s.start = end
+				i = end
+				continue
+			}
+		}
+		if strings.HasPrefix(sql[i:], s.delimiter) && (s.depth == 0 || s.delimiter != ";") {
+			s.flush(i)
+			i += len(s.delimiter)
+			s.start = i
+			continue
+		}
+
+		ch := sql[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n' || ch == '\f':
+			i++
+		case ch == '-' && strings.HasPrefix(sql[i:], "--") && (s.dialect != "mysql" || dashCommentFollows(sql, i+2)):
+			i = lineEnd(sql, i)
+		case ch == '#' && s.dialect == "mysql":
+			i = lineEnd(sql, i)
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			i = s.blockCommentEnd(i)
+		case ch == '\'' || ch == '"':
+			s.code = true
+			i = quotedEnd(sql, i, ch, s.backslashEscapes(i))
+		case ch == '`' && s.dialect != "postgresql":
+			s.code = true
+			i = quotedEnd(sql, i, ch, false)
+		case ch == '[' && s.dialect == "sqlite":
+			s.code = true
+			if end := strings.IndexByte(sql[i+1:], ']'); end >= 0 {
+				i += end + 2
+			} else {
+				i = len(sql)
+			}
+		case ch == '$' && s.dialect == "postgresql" && (i == 0 || !isWordByte(sql[i-1])):
+			s.code = true
+			i = dollarQuotedEnd(sql, i)
+		case isWordStart(ch):
+			s.code = true
+			end := i
+			for end < len(sql) && isWordByte(sql[end]) && !s.delimiterAt(end) {
+				end++
+			}
+			s.word(strings.ToLower(sql[i:end]), end)
+			i = end
+		default:
+			s.code = true
+			i++
+		}
+	}
+	s.flush(len(sql))
+}
+
+// delimiterAt reports whether a delimiter other than a semicolon starts at
+// i; MySQL scripts often end routines with END$$, which reads as one word
+func (s *splitter) delimiterAt(i int) bool {
+	return s.delimiter != ";" && strings.HasPrefix(s.sql[i:], s.delimiter)
+}
+
+// flush ends the current statement at end, keeping it when it holds code
+func (s *splitter) flush(end int) {
+	if s.code {
+		text := strings.TrimSpace(s.sql[s.start:end])
+		offset := s.start + strings.Index(s.sql[s.start:end], text)
+		s.statements = append(s.statements, Statement{Text: text, Offset: offset})
+	}
+	s.code = false
+	s.words = 0
+	s.previous = ""
+	s.create = false
+	s.routine = false
+	s.depth = 0
+}
+
+// word tracks the words of a statement that open and close routine bodies.
+// end is where the word ends in the script.
+func (s *splitter) word(word string, end int) {
+	previous := s.previous
+	s.words++
+	s.previous = word
+	switch {
+	case s.words == 1:
+		s.create = word == "create"
+	case s.create && !s.routine && routineKinds[word]:
+		s.routine = true
+	case s.routine && word == "begin":
+		s.depth++
+	case s.depth > 0 && word == "case" && previous != "end":
+		s.depth++
+	case s.depth > 0 && word == "end" && !compoundEnds[nextWord(s.sql, end)]:
+		s.depth--
+	}
+}
+
+// backslashEscapes reports whether a backslash escapes the next character
+// in the string starting at i: always in MySQL, and in PostgreSQL's
+// E'...' strings
+func (s *splitter) backslashEscapes(i int) bool {
+	switch s.dialect {
+	case "mysql":
+		return true
+	case "postgresql":
+		return s.sql[i] == '\'' && i > 0 && (s.sql[i-1] == 'e' || s.sql[i-1] == 'E') &&
+			(i == 1 || !isWordByte(s.sql[i-2]))
+	}
+	return false
+}
+
+// blockCommentEnd returns the end of the /* */ comment starting at i.
+// PostgreSQL nests block comments.
+func (s *splitter) blockCommentEnd(i int) int {
+	depth := 0
+	for j := i; j < len(s.sql)-1; j++ {
+		switch {
+		case s.sql[j] == '/' && s.sql[j+1] == '*' && (depth == 0 || s.dialect == "postgresql"):
+			depth++
+			j++
+		case s.sql[j] == '*' && s.sql[j+1] == '/':
+			if depth--; depth == 0 {
+				return j + 2
+			}
+			j++
+		}
+	}
+	return len(s.sql)
+}
+
+// delimiterCommand recognizes the MySQL client's DELIMITER command at i,
+// returning where its line ends and the new delimiter
+func delimiterCommand(sql string, i int) (int, string, bool) {
+	const command = "delimiter"
+	if len(sql) < i+len(command)+1 || !strings.EqualFold(sql[i:i+len(command)], command) {
+		return 0, "", false
+	}
+	if next := sql[i+len(command)]; next != ' ' && next != '\t' {
+		return 0, "", false
+	}
+	end := lineEnd(sql, i)
+	fields := strings.Fields(sql[i+len(command) : end])
+	if len(fields) == 0 {
+		return 0, "", false
+	}
+	return end, fields[0], true
+}
+
+// dashCommentFollows reports whether "--" before i starts a MySQL comment,
+// which takes whitespace or the end of the script after the dashes
+func dashCommentFollows(sql string, i int) bool {
+	return i >= len(sql) || sql[i] == ' ' || sql[i] == '\t' || sql[i] == '\r' || sql[i] == '\n'
+}
+
+// lineEnd returns the index of the newline ending the line at i, or the
+// end of sql
+func lineEnd(sql string, i int) int {
+	if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(sql)
+}
+
+// quotedEnd returns the end of the string or quoted identifier starting at
+// i. A doubled quote stands for the quote itself.
+func quotedEnd(sql string, i int, quote byte, backslash bool) int {
+	for j := i + 1; j < len(sql); j++ {
+		switch {
+		case backslash && sql[j] == '\\':
+			j++
+		case sql[j] == quote:
+			if j+1 < len(sql) && sql[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(sql)
+}
+
+// dollarQuotedEnd returns the end of the PostgreSQL dollar-quoted string
+// starting at i, as in $$...$$ or $body$...$body$. A $ that does not start
+// one, as in the parameter $1, is skipped.
+func dollarQuotedEnd(sql string, i int) int {
+	j := i + 1
+	if j < len(sql) && isWordStart(sql[j]) {
+		for j < len(sql) && isWordByte(sql[j]) && sql[j] != '$' {
+			j++
+		}
+	}
+	if j >= len(sql) || sql[j] != '$' {
+		return i + 1
+	}
+	tag := sql[i : j+1]
+	if end := strings.Index(sql[j+1:], tag); end >= 0 {
+		return j + 1 + end + len(tag)
+	}
+	return len(sql)
+}
+
+// nextWord returns the lowercased word after whitespace at i
+func nextWord(sql string, i int) string {
+	for i < len(sql) && strings.IndexByte(" \t\r\n", sql[i]) >= 0 {
+		i++
+	}
+	end := i
+	for end < len(sql) && isWordByte(sql[end]) {
+		end++
+	}
+	return strings.ToLower(sql[i:end])
+}
+
+func isWordStart(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch == '_' || ch >= 0x80
+}
+
+func isWordByte(ch byte) bool {
+	return isWordStart(ch) || ch >= '0' && ch <= '9' || ch == '$'
+}
@@ -0,0 +1,205 @@
+package sqlsplit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		sql     string
+		want    []string
+	}{
+		// Delimiters and empty statements
+		{"single statement", "sqlite", "SELECT 1", []string{"SELECT 1"}},
+		{"trailing semicolon", "sqlite", "SELECT 1;", []string{"SELECT 1"}},
+		{"two statements", "sqlite", "SELECT 1; SELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"empty statements", "sqlite", ";; SELECT 1 ;\n;\t; SELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"empty script", "sqlite", "", []string{}},
+		{"whitespace only", "postgresql", " \n\t ", []string{}},
+		{"multiline statement", "postgresql", "SELECT a,\n  b\nFROM t;\nSELECT 2", []string{"SELECT a,\n  b\nFROM t", "SELECT 2"}},
+
+		// Strings and quoted identifiers
+		{"semicolon in string", "sqlite", "SELECT 'a;b'; SELECT 2", []string{"SELECT 'a;b'", "SELECT 2"}},
+		{"doubled quote", "sqlite", "SELECT 'it''s;'; SELECT 2", []string{"SELECT 'it''s;'", "SELECT 2"}},
+		{"double quoted identifier", "postgresql", `SELECT "a;b" FROM t; SELECT 2`, []string{`SELECT "a;b" FROM t`, "SELECT 2"}},
+		{"doubled double quote", "postgresql", `SELECT "a"";" FROM t; SELECT 2`, []string{`SELECT "a"";" FROM t`, "SELECT 2"}},
+		{"backtick identifier", "mysql", "SELECT `a;b` FROM t; SELECT 2", []string{"SELECT `a;b` FROM t", "SELECT 2"}},
+		{"doubled backtick", "mysql", "SELECT `a``;` FROM t; SELECT 2", []string{"SELECT `a``;` FROM t", "SELECT 2"}},
+		{"sqlite brackets", "sqlite", "SELECT [a;b] FROM t; SELECT 2", []string{"SELECT [a;b] FROM t", "SELECT 2"}},
+		{"brackets elsewhere are operators", "postgresql", "SELECT a[1]; SELECT ']'", []string{"SELECT a[1]", "SELECT ']'"}},
+		{"mysql backslash escape", "mysql", `SELECT 'a\';b'; SELECT 2`, []string{`SELECT 'a\';b'`, "SELECT 2"}},
+		{"mysql escaped backslash", "mysql", `SELECT 'a\\'; SELECT 2`, []string{`SELECT 'a\\'`, "SELECT 2"}},
+		{"mysql double quoted string", "mysql", `SELECT "a\";b"; SELECT 2`, []string{`SELECT "a\";b"`, "SELECT 2"}},
+		{"standard strings keep backslashes", "postgresql", `SELECT 'a\'; SELECT 2`, []string{`SELECT 'a\'`, "SELECT 2"}},
+		{"postgresql escape string", "postgresql", `SELECT E'a\';b'; SELECT 2`, []string{`SELECT E'a\';b'`, "SELECT 2"}},
+		{"word ending in e is no escape string", "postgresql", `SELECT name'a\'; SELECT 2`, []string{`SELECT name'a\'`, "SELECT 2"}},
+		{"sqlite keeps backslashes", "sqlite", `SELECT 'a\'; SELECT 2`, []string{`SELECT 'a\'`, "SELECT 2"}},
+		{"unterminated string", "sqlite", "SELECT 1; SELECT 'a; SELECT 2", []string{"SELECT 1", "SELECT 'a; SELECT 2"}},
+
+		// Comments
+		{"line comment", "sqlite", "SELECT 1 -- a; b\n; SELECT 2", []string{"SELECT 1 -- a; b", "SELECT 2"}},
+		{"block comment", "postgresql", "SELECT /* a; b */ 1; SELECT 2", []string{"SELECT /* a; b */ 1", "SELECT 2"}},
+		{"multiline block comment", "sqlite", "SELECT 1 /* a;\n b; */; SELECT 2", []string{"SELECT 1 /* a;\n b; */", "SELECT 2"}},
+		{"quote in comment", "sqlite", "SELECT 1; -- it's\nSELECT 2", []string{"SELECT 1", "-- it's\nSELECT 2"}},
+		{"comment marker in string", "sqlite", "SELECT '--'; SELECT '/*'; SELECT 2", []string{"SELECT '--'", "SELECT '/*'", "SELECT 2"}},
+		{"comment only statements", "sqlite", "SELECT 1; -- done\n/* and */", []string{"SELECT 1"}},
+		{"nested block comments", "postgresql", "SELECT /* a /* b; */ c; */ 1; SELECT 2", []string{"SELECT /* a /* b; */ c; */ 1", "SELECT 2"}},
+		{"mysql block comments do not nest", "mysql", "SELECT /* a /* b */ 1; SELECT 2", []string{"SELECT /* a /* b */ 1", "SELECT 2"}},
+		{"mysql hash comment", "mysql", "SELECT 1 # a; b\n; SELECT 2", []string{"SELECT 1 # a; b", "SELECT 2"}},
+		{"hash outside mysql", "postgresql", "SELECT 1 # 2; SELECT 2", []string{"SELECT 1 # 2", "SELECT 2"}},
+		{"mysql dashes need a space", "mysql", "SELECT 1--1; SELECT 2", []string{"SELECT 1--1", "SELECT 2"}},
+		{"mysql dash comment", "mysql", "SELECT 1 -- a; b\n; SELECT 2", []string{"SELECT 1 -- a; b", "SELECT 2"}},
+		{"mysql executable comment", "mysql", "SELECT /*!40101 1; */ 2; SELECT 3", []string{"SELECT /*!40101 1; */ 2", "SELECT 3"}},
+		{"unterminated comment", "sqlite", "SELECT 1; SELECT 2 /* ; SELECT 3", []string{"SELECT 1", "SELECT 2 /* ; SELECT 3"}},
+
+		// PostgreSQL dollar quoting
+		{
+			"dollar-quoted body", "postgresql",
+			"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql; SELECT f()",
+			[]string{"CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql", "SELECT f()"},
+		},
+		{
+			"tagged dollar quote", "postgresql",
+			"DO $body$ BEGIN RAISE NOTICE '$$;'; END $body$; SELECT 2",
+			[]string{"DO $body$ BEGIN RAISE NOTICE '$$;'; END $body$", "SELECT 2"},
+		},
+		{
+			"nested dollar quotes", "postgresql",
+			"SELECT $a$ x $b$ ; $b$ y; $a$; SELECT 2",
+			[]string{"SELECT $a$ x $b$ ; $b$ y; $a$", "SELECT 2"},
+		},
+		{"positional parameters", "postgresql", "SELECT $1; SELECT $2", []string{"SELECT $1", "SELECT $2"}},
+		{"dollar in identifiers", "postgresql", "SELECT a$b$; SELECT 2", []string{"SELECT a$b$", "SELECT 2"}},
+		{"dollar quote at start", "postgresql", "$$;$$; SELECT 2", []string{"$$;$$", "SELECT 2"}},
+		{"unterminated dollar quote", "postgresql", "SELECT $$ a; SELECT 2", []string{"SELECT $$ a; SELECT 2"}},
+		{"dollars outside postgresql", "sqlite", "SELECT '$$'; SELECT $$; SELECT 2", []string{"SELECT '$$'", "SELECT $$", "SELECT 2"}},
+		{
+			"begin atomic body", "postgresql",
+			"CREATE FUNCTION f() RETURNS int LANGUAGE sql BEGIN ATOMIC SELECT 1; SELECT 2; END; SELECT f()",
+			[]string{"CREATE FUNCTION f() RETURNS int LANGUAGE sql BEGIN ATOMIC SELECT 1; SELECT 2; END", "SELECT f()"},
+		},
+
+		// Routine and trigger bodies
+		{
+			"sqlite trigger", "sqlite",
+			"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = n + 1; DELETE FROM c; END; SELECT 1",
+			[]string{"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = n + 1; DELETE FROM c; END", "SELECT 1"},
+		},
+		{
+			"sqlite temp trigger with case", "sqlite",
+			"CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = CASE WHEN n > 1 THEN 0 ELSE n END; END; SELECT 1",
+			[]string{"CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN UPDATE b SET n = CASE WHEN n > 1 THEN 0 ELSE n END; END", "SELECT 1"},
+		},
+		{
+			"mysql procedure without delimiter", "mysql",
+			"CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; WHILE 0 DO SELECT 2; END WHILE; SELECT 3; END; CALL p()",
+			[]string{"CREATE PROCEDURE p() BEGIN IF 1 THEN SELECT 1; END IF; WHILE 0 DO SELECT 2; END WHILE; SELECT 3; END", "CALL p()"},
+		},
+		{
+			"mysql nested blocks and labels", "mysql",
+			"CREATE PROCEDURE p() outer_block: BEGIN inner_block: BEGIN SELECT 1; END inner_block; CASE x WHEN 1 THEN SELECT 2; END CASE; LOOP LEAVE outer_block; END LOOP; END outer_block; SELECT 3",
+			[]string{"CREATE PROCEDURE p() outer_block: BEGIN inner_block: BEGIN SELECT 1; END inner_block; CASE x WHEN 1 THEN SELECT 2; END CASE; LOOP LEAVE outer_block; END LOOP; END outer_block", "SELECT 3"},
+		},
+		{
+			"mysql event", "mysql",
+			"CREATE EVENT e ON SCHEDULE EVERY 1 DAY DO BEGIN DELETE FROM a; DELETE FROM b; END; SELECT 1",
+			[]string{"CREATE EVENT e ON SCHEDULE EVERY 1 DAY DO BEGIN DELETE FROM a; DELETE FROM b; END", "SELECT 1"},
+		},
+		{
+			"routine without a body block", "mysql",
+			"CREATE FUNCTION f() RETURNS INT RETURN 1; SELECT f()",
+			[]string{"CREATE FUNCTION f() RETURNS INT RETURN 1", "SELECT f()"},
+		},
+		{"transaction begin", "sqlite", "BEGIN; INSERT INTO a VALUES (1); END;", []string{"BEGIN", "INSERT INTO a VALUES (1)", "END"}},
+		{"case outside routines", "sqlite", "SELECT CASE WHEN 1 THEN 2 END; SELECT 3", []string{"SELECT CASE WHEN 1 THEN 2 END", "SELECT 3"}},
+		{"begin in a table", "sqlite", "CREATE TABLE t (begin_at TEXT); SELECT 1", []string{"CREATE TABLE t (begin_at TEXT)", "SELECT 1"}},
+
+		// MySQL DELIMITER
+		{
+			"delimiter", "mysql",
+			"DELIMITER //\nCREATE PROCEDURE p() BEGIN SELECT 1; END//\nDELIMITER ;\nCALL p();",
+			[]string{"CREATE PROCEDURE p() BEGIN SELECT 1; END", "CALL p()"},
+		},
+		{
+			"delimiter after a word", "mysql",
+			"delimiter $$\nCREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END$$\ndelimiter ;\nSELECT 1",
+			[]string{"CREATE TRIGGER t BEFORE INSERT ON a FOR EACH ROW BEGIN SET NEW.x = 1; END", "SELECT 1"},
+		},
+		{
+			"statements under a delimiter", "mysql",
+			"DELIMITER ;;\nSELECT 1; SELECT 2;;\nSELECT 3;;",
+			[]string{"SELECT 1; SELECT 2", "SELECT 3"},
+		},
+		{
+			"delimiter in a string", "mysql",
+			"DELIMITER //\nSELECT '//'//\nSELECT 2",
+			[]string{"SELECT '//'", "SELECT 2"},
+		},
+		{
+			"delimiter after comments", "mysql",
+			"SELECT 1;\n-- routines\nDELIMITER //\nSELECT 2//",
+			[]string{"SELECT 1", "SELECT 2"},
+		},
+		{"delimiter mid-statement", "mysql", "SELECT delimiter FROM t; SELECT 2", []string{"SELECT delimiter FROM t", "SELECT 2"}},
+		{"delimiter column", "mysql", "delimiter;", []string{"delimiter"}},
+		{"delimiter outside mysql", "sqlite", "DELIMITER //\nSELECT 1//", []string{"DELIMITER //\nSELECT 1//"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Texts(tt.sql, tt.dialect); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Texts(%q, %s) =\n%q\nwant\n%q", tt.sql, tt.dialect, got, tt.want)
+			}
+			for _, stmt := range Split(tt.sql, tt.dialect) {
+				if tt.sql[stmt.Offset:stmt.Offset+len(stmt.Text)] != stmt.Text {
+					t.Errorf("statement %q is not at offset %d", stmt.Text, stmt.Offset)
+				}
+			}
+		})
+	}
+}
+
+func TestSplitOffsets(t *testing.T) {
+	sql := "  SELECT 1;\n\n  -- next\n  SELECT 2 ;"
+	want := []Statement{
+		{Text: "SELECT 1", Offset: 2},
+		{Text: "-- next\n  SELECT 2", Offset: 15},
+	}
+	if got := Split(sql, "sqlite"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAt(t *testing.T) {
+	sql := "-- setup\nSELECT 1;  SELECT 2;\n\n/* last */ SELECT 3"
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{0, "-- setup\nSELECT 1"},
+		{12, "-- setup\nSELECT 1"},
+		// Right after a semicolon, the statement it ends
+		{18, "-- setup\nSELECT 1"},
+		{20, "SELECT 2"},
+		{29, "SELECT 2"},
+		{30, "SELECT 2"},
+		{31, "/* last */ SELECT 3"},
+		{len(sql), "/* last */ SELECT 3"},
+	}
+	for _, tt := range tests {
+		got, ok := At(sql, "sqlite", tt.offset)
+		if !ok || got.Text != tt.want {
+			t.Errorf("At(%d) = %q, %v, want %q", tt.offset, got.Text, ok, tt.want)
+		}
+	}
+
+	if got, ok := At("\n\n  SELECT 1", "sqlite", 0); !ok || got.Text != "SELECT 1" {
+		t.Errorf("expected the first statement before any, got %q, %v", got.Text, ok)
+	}
+	if _, ok := At(" -- nothing", "sqlite", 3); ok {
+		t.Error("expected no statement in a script of comments")
+	}
+}
@@ -0,0 +1,225 @@
+package sqlvalidator
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// Row combinations a join without a join condition may produce by default
+// before the cartesian_product check flags it
+const DefaultCartesianRowLimit = 1000000
+
+var (
+	// Returns the approximate row counts of a dialect's tables, nil until set
+	tableRowCounts func(dialect string) map[string]int64
+
+	// Largest number of row combinations allowed without a join condition
+	cartesianRowLimit int64 = DefaultCartesianRowLimit
+
+	// Guards tableRowCounts and cartesianRowLimit
+	cartesianMu sync.RWMutex
+)
+
+// SetTableRowCounts sets where the cartesian_product check looks up the
+// sizes of tables. Tables it does not know count as a single row.
+func SetTableRowCounts(counts func(dialect string) map[string]int64) {
+	cartesianMu.Lock()
+	tableRowCounts = counts
+	cartesianMu.Unlock()
+}
+
+// SetCartesianRowLimit sets how many row combinations a join without a
+// join condition may produce before the cartesian_product check flags it
+func SetCartesianRowLimit(rows int64) {
+	cartesianMu.Lock()
+	cartesianRowLimit = rows
+	cartesianMu.Unlock()
+}
+
+// hasLargeCartesianProduct reports whether a query in sql pairs tables
+// without any condition relating them, and the sizes of the unrelated
+// parts multiply to more rows than the limit. The tables of each query are
+// the nodes of a join graph, and ON, USING and WHERE conditions that refer
+// to several of them are its edges; more than one connected part makes a
+// cartesian product.
+func hasLargeCartesianProduct(sql string, dialect string) bool {
+	cartesianMu.RLock()
+	counts, limit := tableRowCounts, cartesianRowLimit
+	cartesianMu.RUnlock()
+
+	sizes := map[string]int64{}
+	if counts != nil {
+		for table, rows := range counts(dialect) {
+			sizes[strings.ToLower(table)] = rows
+		}
+	}
+
+	for _, statement := range splitTokenStatements(withoutComments(Tokenize(sql, dialect))) {
+		for _, block := range queryBlocks(statement) {
+			if joinGraphRows(block, sizes) > float64(limit) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// queryBlocks returns the single SELECTs of a statement: the parts of set
+// operations, the bodies of CTEs and the subqueries nested anywhere
+func queryBlocks(tokens []Token) [][]Token {
+	if clause := parseWithClause(tokens); clause != nil {
+		blocks := [][]Token{}
+		for _, expression := range clause.Expressions {
+			blocks = append(blocks, queryBlocks(expression.body)...)
+		}
+		return append(blocks, queryBlocks(clause.main)...)
+	}
+
+	blocks := [][]Token{}
+	parts, _ := splitSetOperations(tokens)
+	for _, part := range parts {
+		for len(part) > 1 && part[0].Text == "(" && closingParen(part, 0) == len(part)-1 {
+			part = part[1 : len(part)-1]
+		}
+		blocks = append(blocks, part)
+		for i := 0; i < len(part); i++ {
+			if part[i].Text == "(" && i+1 < len(part) && (part[i+1].Is("select") || part[i+1].Is("with")) {
+				end := closingParen(part, i)
+				blocks = append(blocks, queryBlocks(part[i+1:end])...)
+				i = end
+			}
+		}
+	}
+	return blocks
+}
+
+// joinGraphRows returns the number of row combinations the unrelated parts
+// of a query's join graph produce, or 0 when every table is related to the
+// others. Each part counts as large as its largest table.
+func joinGraphRows(tokens []Token, sizes map[string]int64) float64 {
+	from := splitClauses(tokens, selectClauseKeywords)["from"]
+	segments := splitJoins(from)
+	if len(segments) < 2 {
+		return 0
+	}
+
+	// Union-find over the sources, which aliases and names refer to
+	parents := make([]int, len(segments))
+	rows := make([]int64, len(segments))
+	keys := map[string]int{}
+	root := func(i int) int {
+		for parents[i] != i {
+			i = parents[i]
+		}
+		return i
+	}
+	union := func(a, b int) {
+		parents[root(a)] = root(b)
+	}
+
+	// relate joins the sources a condition refers to; joined is the source
+	// an ON condition belongs to, or -1 for WHERE conditions
+	relate := func(condition []Token, joined int) {
+		qualified, unqualified := columnReferences(condition)
+		related := []int{}
+		for _, qualifier := range qualified {
+			if i, ok := keys[qualifier]; ok {
+				related = append(related, i)
+			}
+		}
+		switch {
+		case joined >= 0 && unqualified > 0:
+			// Unqualified columns in ON most likely come from both sides
+			related = append(related, joined, joined-1)
+		case unqualified > 0 && unqualified+len(related) > 1:
+			// Elsewhere they may come from any of the tables
+			related = related[:0]
+			for i := range segments {
+				related = append(related, i)
+			}
+		}
+		for _, i := range related {
+			union(i, related[0])
+		}
+	}
+
+	for i, segment := range segments {
+		parents[i] = i
+		kind, source, condition, using := parseJoinSegment(segment)
+		if ref, _, _ := parseTableRef(source, 0, true); ref.Name != "" {
+			rows[i] = sizes[strings.ToLower(ref.Name)]
+			keys[strings.ToLower(ref.Name)] = i
+			if ref.Alias != "" {
+				keys[strings.ToLower(ref.Alias)] = i
+			}
+		} else if len(source) > 0 && source[len(source)-1].Kind != TokenPunctuation {
+			// Subqueries are referred to by their alias
+			keys[strings.ToLower(source[len(source)-1].Value())] = i
+		}
+
+		switch {
+		case i == 0:
+		case kind == "natural" || using:
+			// Columns of the same name relate the source to the one before
+			union(i, i-1)
+		case len(condition) > 0:
+			relate(condition, i)
+		}
+	}
+
+	if where := splitClauses(tokens, selectClauseKeywords)["where"]; len(where) > 0 {
+		for _, condition := range splitTopLevel(where, "and") {
+			relate(condition, -1)
+		}
+	}
+
+	largest := map[int]int64{}
+	for i := range segments {
+		r := root(i)
+		if _, ok := largest[r]; !ok || rows[i] > largest[r] {
+			largest[r] = rows[i]
+		}
+	}
+	if len(largest) < 2 {
+		return 0
+	}
+	product := 1.0
+	for _, size := range largest {
+		product *= math.Max(float64(size), 1)
+	}
+	return product
+}
+
+// columnReferences returns the lower-cased qualifiers of the qualified
+// columns in a condition, and how many columns it names without one.
+// Unqualified names inside subqueries belong to their own tables and are
+// not counted; qualified ones may refer to the outer query.
+func columnReferences(tokens []Token) ([]string, int) {
+	qualifiers := []string{}
+	unqualified := 0
+	// Whether each open parenthesis is inside a subquery
+	subqueries := []bool{}
+	for i, token := range tokens {
+		previous, next := tokenAt(tokens, i-1), tokenAt(tokens, i+1)
+		inSubquery := len(subqueries) > 0 && subqueries[len(subqueries)-1]
+		switch {
+		case token.Text == "(":
+			subqueries = append(subqueries, inSubquery || next.Is("select") || next.Is("with"))
+		case token.Text == ")":
+			if len(subqueries) > 0 {
+				subqueries = subqueries[:len(subqueries)-1]
+			}
+		case token.Kind != TokenWord && token.Kind != TokenQuotedIdentifier:
+		case previous.Text == ".":
+			// The column of a qualified name
+		case next.Text == ".":
+			qualifiers = append(qualifiers, strings.ToLower(token.Value()))
+		case inSubquery || next.Text == "(" || token.Kind == TokenWord && len(ReservedIn(token.Text)) > 0:
+			// Names of subqueries, functions and keywords
+		default:
+			unqualified++
+		}
+	}
+	return qualifiers, unqualified
+}
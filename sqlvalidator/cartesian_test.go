@@ -0,0 +1,53 @@
+package sqlvalidator
+
+import "testing"
+
+func TestLargeCartesianProduct(t *testing.T) {
+	SetTableRowCounts(func(dialect string) map[string]int64 {
+		return map[string]int64{"large_orders": 1000000, "customers": 50000, "products": 200, "Tags": 10}
+	})
+	SetCartesianRowLimit(DefaultCartesianRowLimit)
+	defer SetTableRowCounts(nil)
+
+	tests := map[string]bool{
+		"SELECT * FROM large_orders, customers":                                                                           true,
+		"SELECT * FROM large_orders o CROSS JOIN customers c":                                                             true,
+		"SELECT * FROM large_orders o JOIN customers c ON 1 = 1":                                                          true,
+		"SELECT * FROM large_orders o, customers c WHERE o.status = 'paid' AND c.id > 10":                                 true,
+		"SELECT * FROM large_orders o, customers c WHERE o.customer_id IN (SELECT id FROM users)":                         true,
+		"WITH x AS (SELECT * FROM customers, products) SELECT * FROM x":                                                   true,
+		"SELECT * FROM products WHERE id IN (SELECT p.id FROM products p, customers c)":                                   true,
+		"SELECT * FROM products p, tags t":                                                                                false,
+		"SELECT * FROM large_orders o JOIN customers c ON o.customer_id = c.id":                                           false,
+		"SELECT * FROM large_orders o, customers c WHERE c.id = o.customer_id AND c.id > 10":                              false,
+		"SELECT * FROM large_orders JOIN customers USING (customer_id)":                                                   false,
+		"SELECT * FROM large_orders NATURAL JOIN customers":                                                               false,
+		"SELECT * FROM large_orders o JOIN customers ON customer_id = id":                                                 false,
+		"SELECT * FROM large_orders o, customers c WHERE EXISTS (SELECT 1 FROM products p WHERE p.a = o.a AND p.b = c.b)": false,
+		"SELECT * FROM large_orders o, (SELECT MAX(id) AS top FROM customers) m":                                          false,
+		"SELECT * FROM unknown_a, unknown_b":                                                                              false,
+	}
+	for sql, want := range tests {
+		if got := hasLargeCartesianProduct(sql, "postgresql"); got != want {
+			t.Errorf("hasLargeCartesianProduct(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
+
+func TestCartesianRowLimit(t *testing.T) {
+	SetTableRowCounts(func(dialect string) map[string]int64 {
+		return map[string]int64{"products": 200, "tags": 10}
+	})
+	defer SetTableRowCounts(nil)
+	defer SetCartesianRowLimit(DefaultCartesianRowLimit)
+
+	SetCartesianRowLimit(1000)
+	if !hasLargeCartesianProduct("SELECT * FROM products, tags", "mysql") {
+		t.Error("expected 2000 combinations to exceed a limit of 1000")
+	}
+
+	result := IsSafeDDLOperation("SELECT * FROM products, tags", "mysql")
+	if result.Safe || result.Rule != "cartesian_product" {
+		t.Errorf("expected the cartesian_product rule to block the query, got %+v", result)
+	}
+}
@@ -0,0 +1,256 @@
+package sqlvalidator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Collations maps table names to the collation of each of their text
+// columns
+type Collations map[string]map[string]string
+
+// Operators whose result depends on the collation of their operands
+var collatedOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	"like": true, "ilike": true,
+}
+
+// What each dialect does when the two sides of a comparison have
+// different collations
+var collationMismatchEffects = map[string]string{
+	"mysql":      `MySQL converts one side by coercibility rules, or fails with "Illegal mix of collations"`,
+	"postgresql": `PostgreSQL fails with "could not determine which collation to use"`,
+	"sqlite":     "SQLite uses the collation of the left column, so swapping the sides can change the result",
+}
+
+// Words that start a table constraint rather than a column definition
+var tableConstraintKeywords = words("constraint primary unique check foreign")
+
+// columnOperand is a column compared by an operator
+type columnOperand struct {
+	qualifier string
+	name      string
+}
+
+// CollationWarnings returns a warning for every comparison in sql between
+// two columns whose collations differ, which behaves differently on each
+// dialect and often surprisingly. Columns qualified by a table or alias are
+// resolved against the statement's tables, unqualified ones when exactly
+// one of them has the column. A COLLATE clause on either side settles the
+// comparison and is not reported.
+func CollationWarnings(sql string, dialect string, collations Collations) []string {
+	if len(collations) == 0 {
+		return nil
+	}
+	tokens := withoutComments(Tokenize(sql, dialect))
+
+	tables := make(map[string]string)
+	for _, ref := range tableRefs(tokens) {
+		name, ok := collations.tableName(ref.Name)
+		if !ok {
+			continue
+		}
+		tables[strings.ToLower(ref.Name)] = name
+		if ref.Alias != "" {
+			tables[strings.ToLower(ref.Alias)] = name
+		}
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	warnings := []string{}
+	seen := make(map[string]bool)
+	for i, token := range tokens {
+		if (token.Kind != TokenPunctuation && token.Kind != TokenWord) || !collatedOperators[strings.ToLower(token.Text)] {
+			continue
+		}
+		leftEnd := i
+		// a NOT LIKE b
+		if token.Kind == TokenWord && i > 0 && tokens[i-1].Is("not") {
+			leftEnd--
+		}
+		left, ok := operandBefore(tokens, leftEnd)
+		if !ok {
+			continue
+		}
+		right, ok := operandAfter(tokens, i+1)
+		if !ok {
+			continue
+		}
+
+		leftTable, leftCollation, ok := collations.resolve(left, tables)
+		if !ok {
+			continue
+		}
+		rightTable, rightCollation, ok := collations.resolve(right, tables)
+		if !ok || strings.EqualFold(leftCollation, rightCollation) {
+			continue
+		}
+
+		message := fmt.Sprintf("Comparing %s.%s (%s) with %s.%s (%s)",
+			leftTable, left.name, leftCollation, rightTable, right.name, rightCollation)
+		if seen[message] {
+			continue
+		}
+		seen[message] = true
+		if effect, ok := collationMismatchEffects[dialect]; ok {
+			message += ": " + effect
+		}
+		warnings = append(warnings, message+". Add COLLATE to one side to choose the collation.")
+	}
+	return warnings
+}
+
+// ColumnCollations returns the collations declared with COLLATE in the
+// column definitions of a CREATE TABLE statement, keyed by lower-cased
+// column name. SQLite keeps column collations only in the table's SQL.
+func ColumnCollations(createSQL string, dialect string) map[string]string {
+	tokens := withoutComments(Tokenize(createSQL, dialect))
+	collations := make(map[string]string)
+	open := -1
+	for i, token := range tokens {
+		if token.Text == "(" {
+			open = i
+			break
+		}
+	}
+	if open < 0 {
+		return collations
+	}
+
+	end := closingParen(tokens, open)
+	start := open + 1
+	depth := 0
+	for i := start; i <= end; i++ {
+		switch tokens[i].Text {
+		case "(":
+			depth++
+			continue
+		case ")":
+			if depth > 0 {
+				depth--
+				continue
+			}
+		case ",":
+			if depth > 0 {
+				continue
+			}
+		default:
+			continue
+		}
+		definition := tokens[start:i]
+		start = i + 1
+		if len(definition) == 0 || (definition[0].Kind == TokenWord && tableConstraintKeywords[strings.ToLower(definition[0].Text)]) {
+			continue
+		}
+		for j := 1; j+1 < len(definition); j++ {
+			if definition[j].Is("collate") {
+				collations[strings.ToLower(definition[0].Value())] = definition[j+1].Value()
+				break
+			}
+		}
+	}
+	return collations
+}
+
+// tableName returns the name a table has in the collations, matching it
+// case-insensitively
+func (c Collations) tableName(table string) (string, bool) {
+	if _, ok := c[table]; ok {
+		return table, true
+	}
+	for name := range c {
+		if strings.EqualFold(name, table) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// resolve returns the table and collation of a compared column, or false
+// when it is not a text column of exactly one of the statement's tables
+func (c Collations) resolve(operand columnOperand, tables map[string]string) (string, string, bool) {
+	if operand.qualifier != "" {
+		table, ok := tables[strings.ToLower(operand.qualifier)]
+		if !ok {
+			return "", "", false
+		}
+		collation, ok := columnCollation(c[table], operand.name)
+		return table, collation, ok
+	}
+
+	found := ""
+	collation := ""
+	for _, table := range tables {
+		if table == found {
+			continue
+		}
+		if value, ok := columnCollation(c[table], operand.name); ok {
+			if found != "" {
+				return "", "", false
+			}
+			found, collation = table, value
+		}
+	}
+	return found, collation, found != ""
+}
+
+// columnCollation looks up a column case-insensitively
+func columnCollation(columns map[string]string, name string) (string, bool) {
+	if collation, ok := columns[name]; ok {
+		return collation, true
+	}
+	for column, collation := range columns {
+		if strings.EqualFold(column, name) {
+			return collation, true
+		}
+	}
+	return "", false
+}
+
+// operandBefore reads the column, plain or qualified, that ends right
+// before tokens[end]
+func operandBefore(tokens []Token, end int) (columnOperand, bool) {
+	i := end - 1
+	if i < 0 || !isIdentifier(tokens[i]) {
+		return columnOperand{}, false
+	}
+	operand := columnOperand{name: tokens[i].Value()}
+	if i >= 2 && tokens[i-1].Text == "." && isIdentifier(tokens[i-2]) {
+		operand.qualifier = tokens[i-2].Value()
+		i -= 2
+	}
+	// The name after COLLATE is a collation, and a dot before the
+	// qualifier makes it a schema
+	if i > 0 && (tokens[i-1].Is("collate") || tokens[i-1].Text == ".") {
+		return columnOperand{}, false
+	}
+	return operand, true
+}
+
+// operandAfter reads the column, plain or qualified, that starts at
+// tokens[start] and is not followed by COLLATE or a call
+func operandAfter(tokens []Token, start int) (columnOperand, bool) {
+	i := start
+	if i >= len(tokens) || !isIdentifier(tokens[i]) {
+		return columnOperand{}, false
+	}
+	operand := columnOperand{name: tokens[i].Value()}
+	if i+2 < len(tokens) && tokens[i+1].Text == "." && isIdentifier(tokens[i+2]) {
+		operand = columnOperand{qualifier: tokens[i].Value(), name: tokens[i+2].Value()}
+		i += 2
+	}
+	if i+1 < len(tokens) && (tokens[i+1].Is("collate") || tokens[i+1].Text == "(" || tokens[i+1].Text == ".") {
+		return columnOperand{}, false
+	}
+	return operand, true
+}
+
+// isIdentifier reports whether a token can name a column
+func isIdentifier(token Token) bool {
+	if token.Kind == TokenQuotedIdentifier {
+		return true
+	}
+	return token.Kind == TokenWord && !sqlKeywords[strings.ToLower(token.Text)]
+}
@@ -0,0 +1,69 @@
+package sqlvalidator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testCollations = Collations{
+	"products":  {"name": "utf8mb4_0900_ai_ci", "category": "utf8mb4_0900_ai_ci"},
+	"suppliers": {"name": "utf8mb4_bin", "region": "latin1_swedish_ci"},
+}
+
+func TestCollationWarningsForMixedComparisons(t *testing.T) {
+	for _, query := range []string{
+		"SELECT * FROM products p JOIN suppliers s ON p.name = s.name",
+		"SELECT * FROM products, suppliers WHERE category <> region",
+		"SELECT * FROM products p JOIN suppliers s ON s.name NOT LIKE p.name",
+		"SELECT * FROM products p JOIN suppliers s ON p.name = s.name OR p.name = s.name",
+	} {
+		warnings := CollationWarnings(query, "mysql", testCollations)
+		if len(warnings) != 1 {
+			t.Errorf("expected one warning for %q, got %v", query, warnings)
+			continue
+		}
+		if !strings.Contains(warnings[0], "Illegal mix of collations") {
+			t.Errorf("expected the MySQL behavior in %q", warnings[0])
+		}
+	}
+
+	warnings := CollationWarnings("SELECT * FROM products p JOIN suppliers s ON p.name = s.name", "postgresql", testCollations)
+	want := `Comparing products.name (utf8mb4_0900_ai_ci) with suppliers.name (utf8mb4_bin): PostgreSQL fails with "could not determine which collation to use". Add COLLATE to one side to choose the collation.`
+	if !reflect.DeepEqual(warnings, []string{want}) {
+		t.Errorf("got %v, want %q", warnings, want)
+	}
+}
+
+func TestCollationWarningsIgnoreSettledComparisons(t *testing.T) {
+	for _, query := range []string{
+		// Same collation
+		"SELECT * FROM products WHERE name = category",
+		// Explicit COLLATE on either side
+		"SELECT * FROM products p JOIN suppliers s ON p.name = s.name COLLATE utf8mb4_bin",
+		"SELECT * FROM products p JOIN suppliers s ON p.name COLLATE utf8mb4_bin = s.name",
+		// Literals, functions and ambiguous or unknown columns
+		"SELECT * FROM products p JOIN suppliers s ON p.name = 'x'",
+		"SELECT * FROM products p JOIN suppliers s ON LOWER(p.name) = s.name",
+		"SELECT * FROM products, suppliers WHERE name = region",
+		"SELECT * FROM products p JOIN orders o ON p.name = o.name",
+	} {
+		if warnings := CollationWarnings(query, "mysql", testCollations); len(warnings) != 0 {
+			t.Errorf("expected no warning for %q, got %v", query, warnings)
+		}
+	}
+}
+
+func TestColumnCollations(t *testing.T) {
+	got := ColumnCollations(`CREATE TABLE people (
+		id INTEGER PRIMARY KEY,
+		"Name" TEXT COLLATE NOCASE NOT NULL,
+		code VARCHAR(10) CHECK (code <> '') COLLATE RTRIM,
+		email TEXT,
+		UNIQUE (email COLLATE NOCASE)
+	)`, "sqlite")
+	want := map[string]string{"name": "NOCASE", "code": "RTRIM"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
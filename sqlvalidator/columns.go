@@ -0,0 +1,249 @@
+package sqlvalidator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Words that can appear where a column name could and are never columns
+var sqlKeywords = map[string]bool{
+	"select": true, "from": true, "where": true, "and": true, "or": true, "not": true,
+	"null": true, "is": true, "in": true, "like": true, "ilike": true, "between": true,
+	"case": true, "when": true, "then": true, "else": true, "end": true, "as": true,
+	"distinct": true, "all": true, "any": true, "some": true, "exists": true,
+	"group": true, "by": true, "order": true, "asc": true, "desc": true, "nulls": true,
+	"first": true, "last": true, "limit": true, "offset": true, "having": true,
+	"join": true, "left": true, "right": true, "inner": true, "outer": true, "cross": true,
+	"full": true, "natural": true, "on": true, "using": true, "union": true,
+	"except": true, "intersect": true, "true": true, "false": true, "unknown": true,
+	"insert": true, "into": true, "values": true, "update": true, "set": true,
+	"delete": true, "default": true, "returning": true, "escape": true, "collate": true,
+	"similar": true, "to": true, "interval": true, "date": true, "time": true,
+	"timestamp": true, "year": true, "month": true, "day": true, "hour": true,
+	"minute": true, "second": true, "week": true, "quarter": true,
+	"current_date": true, "current_time": true, "current_timestamp": true,
+	"current_user": true, "localtime": true, "localtimestamp": true, "over": true,
+	"partition": true, "rows": true, "range": true, "preceding": true,
+	"following": true, "unbounded": true, "current": true, "row": true,
+	"ignore": true, "replace": true, "duplicate": true, "key": true, "conflict": true,
+	"do": true, "nothing": true, "excluded": true, "fetch": true, "next": true,
+	"only": true, "with": true, "recursive": true, "window": true, "filter": true,
+	"within": true, "for": true, "of": true, "share": true, "lock": true, "mode": true,
+	"glob": true, "regexp": true, "rlike": true, "div": true, "mod": true, "xor": true,
+	"binary": true, "straight_join": true, "lateral": true, "percent": true, "ties": true,
+	"array": true, "isnull": true, "notnull": true, "nocase": true,
+	// SQLite implicit row id columns
+	"rowid": true, "_rowid_": true, "oid": true,
+}
+
+// checkColumns verifies column references. Qualified references such as
+// p.price are checked in every statement; unqualified ones only in simple
+// single-table statements where their table is unambiguous.
+func checkColumns(sql string, tokens []Token, refs []TableRef, schema Schema, ctes map[string]bool) error {
+	tables := make(map[string]string)
+	for _, ref := range refs {
+		if _, ok := schema.lookup(ref.Name); !ok || ctes[strings.ToLower(ref.Name)] {
+			continue
+		}
+		tables[strings.ToLower(ref.Name)] = ref.Name
+		if ref.Alias != "" {
+			tables[strings.ToLower(ref.Alias)] = ref.Name
+		}
+	}
+
+	// Qualified references: alias.column
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i+1].Text != "." || (i > 0 && tokens[i-1].Text == ".") {
+			continue
+		}
+		table, ok := tables[strings.ToLower(tokens[i].Value())]
+		column := tokens[i+2]
+		if !ok || column.Text == "*" || (column.Kind != TokenWord && column.Kind != TokenQuotedIdentifier) {
+			continue
+		}
+		if i+3 < len(tokens) && tokens[i+3].Text == "." {
+			// schema.table.column
+			continue
+		}
+		if err := checkColumn(sql, column, table, schema); err != nil {
+			return err
+		}
+	}
+
+	if len(refs) != 1 || len(ctes) > 0 || countKeyword(tokens, "select") > 1 || hasOtherSources(tokens) {
+		return nil
+	}
+	table, ok := tables[strings.ToLower(refs[0].Name)]
+	if !ok {
+		return nil
+	}
+
+	aliases := selectAliases(tokens)
+	for i, token := range tokens {
+		if token.Kind != TokenWord && token.Kind != TokenQuotedIdentifier {
+			continue
+		}
+		if token.Kind == TokenWord && (sqlKeywords[strings.ToLower(token.Text)] ||
+			strings.ContainsAny(token.Text[:1], "@$:?")) {
+			continue
+		}
+		if aliases[strings.ToLower(token.Value())] || tables[strings.ToLower(token.Value())] != "" {
+			continue
+		}
+		// Named parameters, casts and output names
+		if i > 0 && (tokens[i-1].Text == "." || tokens[i-1].Is("as") || tokens[i-1].Text == "::" || tokens[i-1].Text == ":") {
+			continue
+		}
+		// Function calls, qualifiers and typed literals such as DATE '2024-01-01'
+		if i+1 < len(tokens) && (tokens[i+1].Text == "(" || tokens[i+1].Text == "." || tokens[i+1].Kind == TokenString) {
+			continue
+		}
+		if err := checkColumn(sql, token, table, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkColumn returns a *ReferenceError if column is not part of table
+func checkColumn(sql string, column Token, table string, schema Schema) error {
+	columns, _ := schema.lookup(table)
+	name := column.Value()
+	for _, existing := range columns {
+		if strings.EqualFold(existing, name) {
+			return nil
+		}
+	}
+
+	line, col := lineColumn(sql, column.Offset)
+	refErr := &ReferenceError{
+		Kind:    UnknownColumn,
+		Message: fmt.Sprintf("unknown column '%s' in table '%s'", name, table),
+		Name:    name,
+		Line:    line,
+		Column:  col,
+	}
+	if suggestion := closestMatch(name, columns); suggestion != "" {
+		refErr.Suggestion = suggestion
+		refErr.Message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+	}
+	return refErr
+}
+
+// selectAliases returns the lower-cased output names defined in the select
+// list, both "expr AS name" and the implicit "expr name" form
+func selectAliases(tokens []Token) map[string]bool {
+	aliases := make(map[string]bool)
+	for i := 1; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind != TokenWord && token.Kind != TokenQuotedIdentifier {
+			continue
+		}
+		if tokens[i-1].Is("as") {
+			aliases[strings.ToLower(token.Value())] = true
+			continue
+		}
+		// expr name, / expr name FROM
+		prev := tokens[i-1]
+		endsExpression := prev.Text == ")" || prev.Kind == TokenNumber || prev.Kind == TokenString ||
+			prev.Kind == TokenQuotedIdentifier ||
+			(prev.Kind == TokenWord && !sqlKeywords[strings.ToLower(prev.Text)])
+		followedByListEnd := i+1 >= len(tokens) || tokens[i+1].Text == "," || tokens[i+1].Is("from")
+		if endsExpression && followedByListEnd && token.Kind == TokenWord && !sqlKeywords[strings.ToLower(token.Text)] {
+			aliases[strings.ToLower(token.Value())] = true
+		}
+	}
+	return aliases
+}
+
+// hasOtherSources reports whether rows can come from anything besides the
+// single referenced table: joins, comma-separated FROM lists, table
+// functions or USING clauses
+func hasOtherSources(tokens []Token) bool {
+	for i, token := range tokens {
+		if token.Is("join") || token.Is("using") {
+			return true
+		}
+		if !token.Is("from") || insideFunctionCall(tokens, i) {
+			continue
+		}
+		for j := i + 1; j < len(tokens); j++ {
+			if tokens[j].Text == "," || tokens[j].Text == "(" {
+				return true
+			}
+			if tokens[j].Text == ";" || (tokens[j].Kind == TokenWord && clauseKeywords[strings.ToLower(tokens[j].Text)]) {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// countKeyword counts the tokens matching keyword
+func countKeyword(tokens []Token, keyword string) int {
+	count := 0
+	for _, token := range tokens {
+		if token.Is(keyword) {
+			count++
+		}
+	}
+	return count
+}
+
+// closestMatch returns the candidate most similar to name, or "" when no
+// candidate is close enough to be a plausible typo
+func closestMatch(name string, candidates []string) string {
+	best := ""
+	bestDistance := -1
+	lower := strings.ToLower(name)
+
+	for _, candidate := range candidates {
+		distance := levenshtein(lower, strings.ToLower(candidate))
+		if bestDistance < 0 || distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	maxDistance := len(name) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	if bestDistance < 0 || bestDistance > maxDistance {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// minInt returns the smallest of its arguments
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
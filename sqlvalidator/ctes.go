@@ -0,0 +1,306 @@
+package sqlvalidator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Most rows a recursive CTE may produce on SQLite, where the cap is added
+// to the CTE itself
+const MaxRecursiveRows = 10000
+
+// Statements that change data when they lead a CTE body or follow a WITH
+// clause
+var dataModifyingKeywords = words("insert update delete merge")
+
+// Keywords that start the statement following a WITH clause
+var mainStatementKeywords = words("select insert update delete merge values table")
+
+// CommonTableExpression is one named query of a WITH clause
+type CommonTableExpression struct {
+	Name string
+	// Whether the body refers to the expression itself
+	Recursive bool
+	// First keyword of the body, such as select or delete
+	Kind string
+
+	body []Token
+	// Names given to the body's columns in parentheses after the name
+	columns []string
+}
+
+// WithClause is the WITH clause leading a statement
+type WithClause struct {
+	Recursive   bool
+	Expressions []CommonTableExpression
+	// First keyword of the statement after the clause
+	MainKind string
+
+	main []Token
+}
+
+// ParseWithClause parses the WITH clause leading the first statement of
+// sql, or returns nil when it does not start with one
+func ParseWithClause(sql string, dialect string) *WithClause {
+	tokens := withoutComments(Tokenize(sql, dialect))
+	for i, token := range tokens {
+		if token.Text == ";" {
+			tokens = tokens[:i]
+			break
+		}
+	}
+	return parseWithClause(tokens)
+}
+
+// WithClauseWrites reports whether a statement in sql starts with a WITH
+// clause and changes data, either in the statement after the clause or in
+// a data-modifying CTE, which PostgreSQL supports
+func WithClauseWrites(sql string, dialect string) bool {
+	for _, statement := range splitTokenStatements(withoutComments(Tokenize(sql, dialect))) {
+		if clause := parseWithClause(statement); clause != nil && clause.Writes() {
+			return true
+		}
+	}
+	return false
+}
+
+// Writes reports whether the statement led by the clause changes data
+func (w *WithClause) Writes() bool {
+	if dataModifyingKeywords[w.MainKind] {
+		return true
+	}
+	for _, expression := range w.Expressions {
+		if dataModifyingKeywords[expression.Kind] {
+			return true
+		}
+	}
+	return false
+}
+
+// UnboundedRecursion returns the recursive expressions whose recursive
+// part has no WHERE condition while the statement has no LIMIT either.
+// Such a query only stops at the database's recursion limit, or keeps
+// going until the query timeout where there is none.
+func (w *WithClause) UnboundedRecursion() []string {
+	if hasTopLevel(w.main, "limit", "fetch") {
+		return nil
+	}
+	names := []string{}
+	for _, expression := range w.Expressions {
+		if !expression.Recursive {
+			continue
+		}
+		if !hasTopLevel(recursiveMember(expression.body), "where") {
+			names = append(names, expression.Name)
+		}
+	}
+	return names
+}
+
+// CapRecursion adds LIMIT MaxRecursiveRows to the body of every recursive
+// CTE without a limit of its own, and reports whether it changed anything.
+// Only SQLite is rewritten: a LIMIT at the end of a recursive CTE caps the
+// rows it ever produces there, while MySQL relies on
+// cte_max_recursion_depth and PostgreSQL rejects a LIMIT in the recursive
+// part.
+func CapRecursion(sql string, dialect string) (string, bool) {
+	if dialect != "sqlite" {
+		return sql, false
+	}
+	insertAt := []int{}
+	for _, statement := range splitTokenStatements(withoutComments(Tokenize(sql, dialect))) {
+		clause := parseWithClause(statement)
+		if clause == nil {
+			continue
+		}
+		for _, expression := range clause.Expressions {
+			if !expression.Recursive || len(expression.body) == 0 || hasTopLevel(expression.body, "limit") {
+				continue
+			}
+			last := expression.body[len(expression.body)-1]
+			insertAt = append(insertAt, last.Offset+len(last.Text))
+		}
+	}
+	if len(insertAt) == 0 {
+		return sql, false
+	}
+
+	var out strings.Builder
+	previous := 0
+	for _, offset := range insertAt {
+		out.WriteString(sql[previous:offset])
+		out.WriteString(" LIMIT " + strconv.Itoa(MaxRecursiveRows))
+		previous = offset
+	}
+	out.WriteString(sql[previous:])
+	return out.String(), true
+}
+
+// parseWithClause parses the WITH clause at the start of a statement's
+// comment-free tokens
+func parseWithClause(tokens []Token) *WithClause {
+	if len(tokens) == 0 || !tokens[0].Is("with") {
+		return nil
+	}
+	clause := &WithClause{}
+	i := 1
+	if i < len(tokens) && tokens[i].Is("recursive") {
+		clause.Recursive = true
+		i++
+	}
+
+	for i < len(tokens) {
+		if tokens[i].Kind != TokenWord && tokens[i].Kind != TokenQuotedIdentifier {
+			break
+		}
+		expression := CommonTableExpression{Name: tokens[i].Value()}
+		i++
+		// name (col1, col2) AS (...)
+		if i < len(tokens) && tokens[i].Text == "(" {
+			end := closingParen(tokens, i)
+			for _, column := range splitTopLevel(tokens[i+1:end], ",") {
+				if len(column) > 0 {
+					expression.columns = append(expression.columns, column[0].Value())
+				}
+			}
+			i = end + 1
+		}
+		if i >= len(tokens) || !tokens[i].Is("as") {
+			break
+		}
+		i++
+		// PostgreSQL's AS [NOT] MATERIALIZED (...)
+		if i < len(tokens) && tokens[i].Is("not") {
+			i++
+		}
+		if i < len(tokens) && tokens[i].Is("materialized") {
+			i++
+		}
+		if i >= len(tokens) || tokens[i].Text != "(" {
+			break
+		}
+		end := closingParen(tokens, i)
+		expression.body = tokens[i+1 : end]
+		expression.Kind = firstKeyword(expression.body)
+		for _, token := range expression.body {
+			if (token.Kind == TokenWord || token.Kind == TokenQuotedIdentifier) && strings.EqualFold(token.Value(), expression.Name) {
+				expression.Recursive = true
+				break
+			}
+		}
+		clause.Expressions = append(clause.Expressions, expression)
+		i = end + 1
+
+		// PostgreSQL's SEARCH and CYCLE clauses follow the body
+		for i < len(tokens) && tokens[i].Text != "," && !isMainKeyword(tokens[i]) && tokens[i].Text != "(" {
+			i++
+		}
+		if i < len(tokens) && tokens[i].Text == "," {
+			i++
+			continue
+		}
+		break
+	}
+
+	if i < len(tokens) {
+		clause.main = tokens[i:]
+		clause.MainKind = firstKeyword(clause.main)
+	}
+	return clause
+}
+
+// isMainKeyword reports whether a token starts the statement after a WITH
+// clause
+func isMainKeyword(token Token) bool {
+	return token.Kind == TokenWord && mainStatementKeywords[strings.ToLower(token.Text)]
+}
+
+// closingParen returns the index of the parenthesis closing the one at
+// open, or the last index when it is never closed
+func closingParen(tokens []Token, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// firstKeyword returns the lower-cased first word of tokens, looking past
+// opening parentheses
+func firstKeyword(tokens []Token) string {
+	for _, token := range tokens {
+		if token.Kind == TokenWord {
+			return strings.ToLower(token.Text)
+		}
+		if token.Text != "(" {
+			break
+		}
+	}
+	return ""
+}
+
+// hasTopLevel reports whether one of the keywords appears in tokens outside
+// parentheses
+func hasTopLevel(tokens []Token, keywords ...string) bool {
+	depth := 0
+	for _, token := range tokens {
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 0 {
+			continue
+		}
+		for _, keyword := range keywords {
+			if token.Is(keyword) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recursiveMember returns the tokens after the last top-level UNION of a
+// recursive CTE body
+func recursiveMember(body []Token) []Token {
+	member := body
+	depth := 0
+	for i, token := range body {
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && token.Is("union") {
+			member = body[i+1:]
+		}
+	}
+	return member
+}
+
+// splitTokenStatements splits tokens into statements at semicolons
+func splitTokenStatements(tokens []Token) [][]Token {
+	statements := [][]Token{}
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && tokens[i].Text != ";" {
+			continue
+		}
+		if i > start {
+			statements = append(statements, tokens[start:i])
+		}
+		start = i + 1
+	}
+	return statements
+}
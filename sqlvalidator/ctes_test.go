@@ -0,0 +1,106 @@
+package sqlvalidator
+
+import (
+	"testing"
+)
+
+func TestWithClauseWrites(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+		writes  bool
+	}{
+		{"postgresql", "WITH moved AS (DELETE FROM orders WHERE shipped RETURNING *) INSERT INTO archive SELECT * FROM moved", true},
+		{"postgresql", "WITH gone AS (DELETE FROM orders WHERE id = 1 RETURNING id) SELECT * FROM gone", true},
+		{"postgresql", "WITH recent AS MATERIALIZED (SELECT * FROM orders) SELECT * FROM recent", false},
+		{"sqlite", "WITH old AS (SELECT id FROM items) DELETE FROM items WHERE id IN (SELECT id FROM old)", true},
+		{"mysql", "SELECT 1; WITH t AS (SELECT 1) UPDATE items SET price = 0 WHERE id = 1", true},
+		{"mysql", "WITH t AS (SELECT 'delete' AS word) SELECT word FROM t", false},
+		{"sqlite", "DELETE FROM items WHERE id = 1", false},
+	}
+	for _, tt := range tests {
+		if got := WithClauseWrites(tt.sql, tt.dialect); got != tt.writes {
+			t.Errorf("WithClauseWrites(%q) = %v, want %v", tt.sql, got, tt.writes)
+		}
+	}
+}
+
+func TestParseWithClause(t *testing.T) {
+	clause := ParseWithClause(`WITH RECURSIVE tree(id, parent) AS (
+		SELECT id, parent FROM nodes WHERE parent IS NULL
+		UNION ALL
+		SELECT n.id, n.parent FROM nodes n JOIN tree t ON n.parent = t.id
+	) SEARCH DEPTH FIRST BY id SET ordercol,
+	totals AS (SELECT count(*) FROM nodes)
+	SELECT * FROM tree, totals`, "postgresql")
+	if clause == nil || !clause.Recursive || len(clause.Expressions) != 2 || clause.MainKind != "select" {
+		t.Fatalf("unexpected clause %+v", clause)
+	}
+	if !clause.Expressions[0].Recursive || clause.Expressions[1].Recursive {
+		t.Errorf("expected only tree to be recursive, got %+v", clause.Expressions)
+	}
+	if names := clause.UnboundedRecursion(); len(names) != 1 || names[0] != "tree" {
+		t.Errorf("expected tree to have no stopping condition, got %v", names)
+	}
+
+	if ParseWithClause("SELECT 1", "sqlite") != nil {
+		t.Error("expected no clause for a plain SELECT")
+	}
+}
+
+func TestUnboundedRecursionWarning(t *testing.T) {
+	unbounded := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n"
+	result := IsSafeDDLOperation(unbounded, "sqlite")
+	if !result.Safe || len(result.Warnings) != 1 {
+		t.Errorf("expected a warning for %q, got %+v", unbounded, result)
+	}
+
+	for _, query := range []string{
+		"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 10) SELECT i FROM n",
+		"WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n LIMIT 10",
+		"WITH n(i) AS (SELECT 1) SELECT i FROM n",
+	} {
+		if result := IsSafeDDLOperation(query, "sqlite"); !result.Safe || len(result.Warnings) != 0 {
+			t.Errorf("expected no warning for %q, got %+v", query, result)
+		}
+	}
+}
+
+func TestDataModifyingCTEWithoutWhereRequiresConfirmation(t *testing.T) {
+	query := "WITH gone AS (DELETE FROM orders RETURNING id) SELECT count(*) FROM gone"
+	result := IsSafeDDLOperation(query, "postgresql")
+	if !result.RequiresConfirmation || result.Rule != "unfiltered_write" {
+		t.Errorf("expected %q to require confirmation, got %+v", query, result)
+	}
+}
+
+func TestCapRecursion(t *testing.T) {
+	got, changed := CapRecursion("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n", "sqlite")
+	want := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n LIMIT 10000) SELECT count(*) FROM n"
+	if !changed || got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	for _, tt := range []struct {
+		dialect string
+		sql     string
+	}{
+		{"sqlite", "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n LIMIT 50) SELECT i FROM n"},
+		{"sqlite", "WITH totals AS (SELECT count(*) FROM items) SELECT * FROM totals"},
+		{"postgresql", "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n LIMIT 5"},
+	} {
+		if got, changed := CapRecursion(tt.sql, tt.dialect); changed || got != tt.sql {
+			t.Errorf("expected %q to stay unchanged, got %q", tt.sql, got)
+		}
+	}
+}
+
+func TestPostgresRecursionRequiresLimit(t *testing.T) {
+	query := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT i FROM n"
+	if result := IsSafeDDLOperation(query, "postgresql"); result.Safe || result.Rule != "recursion_without_limit" {
+		t.Errorf("expected %q to be blocked, got %+v", query, result)
+	}
+	if result := IsSafeDDLOperation(query+" LIMIT 10", "postgresql"); !result.Safe {
+		t.Errorf("expected a LIMIT to let the query run, got %+v", result)
+	}
+}
@@ -0,0 +1,64 @@
+package sqlvalidator
+
+import (
+	"strings"
+)
+
+// ObjectReferences are the objects a statement or routine body uses
+type ObjectReferences struct {
+	// Tables and views read or written, without CTEs
+	Tables []string
+	// Names called as procedures or functions, including built-in ones;
+	// callers keep the names of routines they know
+	Calls []string
+}
+
+// ReferencedObjects returns the tables, views and routines sql uses, such
+// as the tables a view or saved result reads
+func ReferencedObjects(sql string, dialect string) ObjectReferences {
+	return objectReferences(withoutComments(Tokenize(sql, dialect)))
+}
+
+// objectReferences collects the unqualified table references and calls of
+// tokens, each name once
+func objectReferences(tokens []Token) ObjectReferences {
+	refs := ObjectReferences{Tables: []string{}, Calls: []string{}}
+	ctes := cteNames(tokens)
+	seen := make(map[string]bool)
+	for _, ref := range tableRefs(tokens) {
+		lower := strings.ToLower(ref.Name)
+		if ctes[lower] || seen["table:"+lower] {
+			continue
+		}
+		seen["table:"+lower] = true
+		refs.Tables = append(refs.Tables, ref.Name)
+	}
+
+	for i, token := range tokens {
+		if token.Kind != TokenWord && token.Kind != TokenQuotedIdentifier ||
+			token.Kind == TokenWord && sqlKeywords[strings.ToLower(token.Text)] {
+			continue
+		}
+		// INSERT INTO name (columns)
+		if tokenAt(tokens, i-1).Is("into") {
+			continue
+		}
+		// CALL name and PostgreSQL's EXECUTE FUNCTION name in triggers may
+		// omit the parentheses; other calls are name(...)
+		called := tokenAt(tokens, i+1).Text == "("
+		if previous := tokenAt(tokens, i-1); previous.Is("call") ||
+			(previous.Is("function") || previous.Is("procedure")) && tokenAt(tokens, i-2).Is("execute") {
+			called = true
+		}
+		if !called || tokenAt(tokens, i-1).Text == "." || tokenAt(tokens, i+1).Text == "." {
+			continue
+		}
+		lower := strings.ToLower(token.Value())
+		if seen["call:"+lower] {
+			continue
+		}
+		seen["call:"+lower] = true
+		refs.Calls = append(refs.Calls, token.Value())
+	}
+	return refs
+}
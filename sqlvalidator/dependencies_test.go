@@ -0,0 +1,28 @@
+package sqlvalidator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReferencedObjects(t *testing.T) {
+	tests := []struct {
+		sql    string
+		tables string
+		calls  string
+	}{
+		{"SELECT o.id FROM orders o JOIN Customers c ON c.id = o.customer_id JOIN orders x ON true", "orders,Customers", ""},
+		{"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent, archive.orders", "orders", ""},
+		{"BEGIN INSERT INTO audit (id) VALUES (1); CALL tally; SELECT add_tax(total) FROM sales; END", "audit,sales", "tally,add_tax"},
+		{"AFTER INSERT ON pets FOR EACH ROW EXECUTE FUNCTION log_pet()", "", "log_pet"},
+	}
+	for _, tt := range tests {
+		refs := ReferencedObjects(tt.sql, "postgresql")
+		if got := strings.Join(refs.Tables, ","); got != tt.tables {
+			t.Errorf("ReferencedObjects(%q) tables = %q, want %q", tt.sql, got, tt.tables)
+		}
+		if got := strings.Join(refs.Calls, ","); got != tt.calls {
+			t.Errorf("ReferencedObjects(%q) calls = %q, want %q", tt.sql, got, tt.calls)
+		}
+	}
+}
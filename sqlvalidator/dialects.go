@@ -0,0 +1,104 @@
+package sqlvalidator
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DialectCapabilities describes how a dialect spells the constructs that
+// differ between the supported dialects
+type DialectCapabilities struct {
+	Name string `json:"name"`
+	// RIGHT OUTER JOIN is available
+	RightJoin bool `json:"rightJoin"`
+	// FULL OUTER JOIN is available
+	FullOuterJoin bool `json:"fullOuterJoin"`
+	// ILIKE matches case-insensitively; elsewhere both sides are lowered
+	ILike bool `json:"ilike"`
+	// Backslashes in string literals start escape sequences
+	BackslashEscapes bool `json:"backslashEscapes"`
+	// Unquoted identifiers are folded to lower case, so names with upper
+	// case letters must be quoted
+	FoldsToLower bool `json:"foldsToLower"`
+	// OFFSET may be given without LIMIT
+	OffsetWithoutLimit bool `json:"offsetWithoutLimit"`
+	// LIMIT standing for no limit, for dialects that need one before OFFSET
+	UnboundedLimit string `json:"-"`
+	// Isolation levels a transaction can request, from weakest to strictest
+	IsolationLevels []string `json:"isolationLevels"`
+}
+
+// Capabilities of the supported dialects
+var dialectCapabilities = map[string]DialectCapabilities{
+	"sqlite": {
+		Name:           "sqlite",
+		RightJoin:      true,
+		FullOuterJoin:  true,
+		UnboundedLimit: "-1",
+		// Transactions are always serializable
+		IsolationLevels: []string{Serializable},
+	},
+	"mysql": {
+		Name:             "mysql",
+		RightJoin:        true,
+		BackslashEscapes: true,
+		UnboundedLimit:   "18446744073709551615",
+		IsolationLevels:  []string{ReadUncommitted, ReadCommitted, RepeatableRead, Serializable},
+	},
+	"postgresql": {
+		Name:               "postgresql",
+		RightJoin:          true,
+		FullOuterJoin:      true,
+		ILike:              true,
+		FoldsToLower:       true,
+		OffsetWithoutLimit: true,
+		// READ UNCOMMITTED is accepted but behaves as READ COMMITTED, so it
+		// is left out rather than promising dirty reads
+		IsolationLevels: []string{ReadCommitted, RepeatableRead, Serializable},
+	},
+}
+
+// Identifiers that never need quoting, unless they are reserved words
+var plainIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Capabilities returns the capabilities of a dialect
+func Capabilities(dialect string) (DialectCapabilities, bool) {
+	capabilities, ok := dialectCapabilities[dialect]
+	return capabilities, ok
+}
+
+// Quote returns a name as the dialect needs it written, quoting it only
+// when it is not a plain identifier or collides with a reserved word
+func (d DialectCapabilities) Quote(name string) string {
+	lower := strings.ToLower(name)
+	if plainIdentifierRegex.MatchString(name) && !reservedWords[d.Name][lower] && (!d.FoldsToLower || name == lower) {
+		return name
+	}
+	return QuoteIdentifier(name, d.Name)
+}
+
+// StringLiteral returns a string literal holding value
+func (d DialectCapabilities) StringLiteral(value string) string {
+	if d.BackslashEscapes {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// LimitClause returns the clause limiting a query to limit rows after
+// skipping offset, with a negative limit for no limit. It is empty when
+// neither applies.
+func (d DialectCapabilities) LimitClause(limit int, offset int) string {
+	var clause string
+	switch {
+	case limit >= 0:
+		clause = "LIMIT " + strconv.Itoa(limit)
+	case offset > 0 && !d.OffsetWithoutLimit:
+		clause = "LIMIT " + d.UnboundedLimit
+	}
+	if offset > 0 {
+		clause = strings.TrimPrefix(clause+" OFFSET "+strconv.Itoa(offset), " ")
+	}
+	return clause
+}
@@ -0,0 +1,60 @@
+package sqlvalidator
+
+import "testing"
+
+func TestQuoteOnlyWhenNeeded(t *testing.T) {
+	tests := []struct {
+		dialect string
+		name    string
+		want    string
+	}{
+		{"sqlite", "users", "users"},
+		{"sqlite", "order", `"order"`},
+		{"mysql", "order", "`order`"},
+		{"mysql", "Users", "Users"},
+		{"postgresql", "Users", `"Users"`},
+		{"postgresql", "user", `"user"`},
+		{"sqlite", "first name", `"first name"`},
+	}
+
+	for _, tt := range tests {
+		capabilities, _ := Capabilities(tt.dialect)
+		if got := capabilities.Quote(tt.name); got != tt.want {
+			t.Errorf("Quote(%q) in %s = %s, want %s", tt.name, tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestLimitClause(t *testing.T) {
+	tests := []struct {
+		dialect string
+		limit   int
+		offset  int
+		want    string
+	}{
+		{"sqlite", 10, 0, "LIMIT 10"},
+		{"sqlite", 10, 20, "LIMIT 10 OFFSET 20"},
+		{"sqlite", -1, 20, "LIMIT -1 OFFSET 20"},
+		{"mysql", -1, 5, "LIMIT 18446744073709551615 OFFSET 5"},
+		{"postgresql", -1, 5, "OFFSET 5"},
+		{"postgresql", -1, 0, ""},
+	}
+
+	for _, tt := range tests {
+		capabilities, _ := Capabilities(tt.dialect)
+		if got := capabilities.LimitClause(tt.limit, tt.offset); got != tt.want {
+			t.Errorf("LimitClause(%d, %d) in %s = %q, want %q", tt.limit, tt.offset, tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestStringLiteral(t *testing.T) {
+	mysql, _ := Capabilities("mysql")
+	if got := mysql.StringLiteral(`it's C:\`); got != `'it''s C:\\'` {
+		t.Errorf("unexpected MySQL literal %s", got)
+	}
+	sqlite, _ := Capabilities("sqlite")
+	if got := sqlite.StringLiteral(`C:\`); got != `'C:\'` {
+		t.Errorf("unexpected SQLite literal %s", got)
+	}
+}
@@ -0,0 +1,117 @@
+package sqlvalidator
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Fractions of rows a WHERE condition on one table is assumed to keep:
+// equality and IN tests pick out few rows, other tests a third of them
+const (
+	equalitySelectivity = 0.1
+	rangeSelectivity    = 1.0 / 3
+)
+
+// EstimateRows estimates how many rows a SELECT returns from the
+// approximate row counts of its tables. Only simple queries are estimated:
+// a single SELECT without CTEs or set operations whose sources are all
+// tables with a known count. Related tables count as large as the largest
+// of them and unrelated ones multiply, as in the cartesian_product check;
+// each WHERE condition on a single table keeps a fixed fraction of the
+// rows, an aggregate without GROUP BY returns one row and LIMIT caps the
+// result. Grouping and DISTINCT are assumed to keep every row.
+func EstimateRows(sql string, dialect string, counts map[string]int64) (int64, bool) {
+	statements := splitTokenStatements(withoutComments(Tokenize(sql, dialect)))
+	if len(statements) != 1 || len(statements[0]) == 0 || !statements[0][0].Is("select") {
+		return 0, false
+	}
+	tokens := statements[0]
+	if parts, _ := splitSetOperations(tokens); len(parts) != 1 {
+		return 0, false
+	}
+
+	sizes := map[string]int64{}
+	for table, rows := range counts {
+		sizes[strings.ToLower(table)] = rows
+	}
+
+	clauses := splitClauses(tokens, selectClauseKeywords)
+	from := clauses["from"]
+	if len(from) == 0 {
+		return 1, true
+	}
+	largest := int64(0)
+	for _, segment := range splitJoins(from) {
+		_, source, _, _ := parseJoinSegment(segment)
+		ref, next, plain := parseTableRef(source, 0, true)
+		rows, known := sizes[strings.ToLower(ref.Name)]
+		if !plain || next != len(source) || !known {
+			return 0, false
+		}
+		if rows > largest {
+			largest = rows
+		}
+	}
+
+	estimate := joinGraphRows(tokens, sizes)
+	if estimate == 0 {
+		estimate = float64(largest)
+	}
+	for _, condition := range splitTopLevel(clauses["where"], "and") {
+		estimate *= conditionSelectivity(condition)
+	}
+
+	columns := clauses["select"]
+	if len(clauses["group"]) == 0 && len(aggregateCalls(explainer{sql: sql}, columns)) > 0 {
+		return 1, true
+	}
+	for _, clause := range []string{"limit", "fetch"} {
+		if limit, ok := rowLimit(clauses[clause]); ok && float64(limit) < estimate {
+			estimate = float64(limit)
+		}
+	}
+
+	if estimate >= math.MaxInt64 {
+		return math.MaxInt64, true
+	}
+	if estimate > 0 && estimate < 1 {
+		return 1, true
+	}
+	return int64(math.Round(estimate)), true
+}
+
+// conditionSelectivity returns the fraction of rows a WHERE conjunct is
+// assumed to keep. Join conditions between tables, and the upper bounds of
+// BETWEEN that the split at AND leaves on their own, keep every row.
+func conditionSelectivity(condition []Token) float64 {
+	qualifiers, unqualified := columnReferences(condition)
+	tables := map[string]bool{}
+	for _, qualifier := range qualifiers {
+		tables[qualifier] = true
+	}
+	if len(tables) > 1 || len(tables)+unqualified == 0 {
+		return 1
+	}
+	if indexOfTopLevel(condition, "or", "not") < 0 && indexOfTopLevel(condition, "=", "in") >= 0 {
+		return equalitySelectivity
+	}
+	return rangeSelectivity
+}
+
+// rowLimit reads the row count of a LIMIT or FETCH clause: the last number
+// of LIMIT n, LIMIT offset, n and FETCH FIRST n ROWS ONLY
+func rowLimit(clause []Token) (int64, bool) {
+	limit := int64(-1)
+	for _, token := range clause {
+		if token.Kind != TokenNumber {
+			continue
+		}
+		n, err := strconv.ParseInt(token.Text, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		limit = n
+	}
+	return limit, limit >= 0
+}
@@ -0,0 +1,43 @@
+package sqlvalidator
+
+import "testing"
+
+func TestEstimateRows(t *testing.T) {
+	counts := map[string]int64{"large_orders": 1000000, "customers": 50000, "Products": 200}
+
+	tests := []struct {
+		sql  string
+		want int64
+		ok   bool
+	}{
+		{"SELECT * FROM large_orders", 1000000, true},
+		{"SELECT * FROM products", 200, true},
+		{"SELECT 1", 1, true},
+		{"SELECT * FROM large_orders WHERE status = 'paid'", 100000, true},
+		{"SELECT * FROM large_orders WHERE status IN ('paid', 'shipped') AND amount > 10", 33333, true},
+		{"SELECT * FROM large_orders WHERE amount BETWEEN 10 AND 20", 333333, true},
+		{"SELECT * FROM large_orders o JOIN customers c ON o.customer_id = c.id", 1000000, true},
+		{"SELECT * FROM large_orders o, customers c WHERE o.customer_id = c.id AND c.country = 'NL'", 100000, true},
+		{"SELECT * FROM customers, products", 10000000, true},
+		{"SELECT * FROM large_orders LIMIT 50", 50, true},
+		{"SELECT * FROM large_orders ORDER BY id LIMIT 10, 500", 500, true},
+		{"SELECT * FROM large_orders FETCH FIRST 20 ROWS ONLY", 20, true},
+		{"SELECT COUNT(*) FROM large_orders", 1, true},
+		{"SELECT status, COUNT(*) FROM large_orders GROUP BY status", 1000000, true},
+		{"SELECT * FROM large_orders -- all of them\n;", 1000000, true},
+		{"SELECT * FROM unknown_table", 0, false},
+		{"SELECT * FROM shop.large_orders", 0, false},
+		{"SELECT * FROM (SELECT * FROM large_orders) o", 0, false},
+		{"SELECT * FROM generate_series(1, 10)", 0, false},
+		{"WITH o AS (SELECT * FROM large_orders) SELECT * FROM o", 0, false},
+		{"SELECT id FROM customers UNION SELECT id FROM products", 0, false},
+		{"SELECT 1; SELECT * FROM large_orders", 0, false},
+		{"DELETE FROM large_orders", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := EstimateRows(tt.sql, "postgresql", counts)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("EstimateRows(%q) = %d, %v, want %d, %v", tt.sql, got, ok, tt.want, tt.ok)
+		}
+	}
+}
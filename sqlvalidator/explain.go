@@ -0,0 +1,916 @@
+package sqlvalidator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Longest clause text quoted in an explanation step
+const maxQuotedClause = 160
+
+// Explanation describes in plain English what SQL does, step by step in
+// the order the database works through it
+type Explanation struct {
+	Summary string   `json:"summary"`
+	Steps   []string `json:"steps"`
+}
+
+// Functions that collapse the rows of a group into one value
+var aggregateFunctions = words("count sum avg min max total group_concat string_agg array_agg json_agg jsonb_agg " +
+	"json_group_array json_arrayagg bool_and bool_or every")
+
+// Keywords starting the top-level clauses of a SELECT
+var selectClauseKeywords = words("select from where group having window order limit offset fetch")
+
+// Keywords that may lead a join
+var joinModifiers = words("left right full inner outer cross natural straight_join")
+
+// explainer describes the statements of one SQL text
+type explainer struct {
+	sql string
+}
+
+// Explain describes each statement of sql. The description works from the
+// tokens of the statements, so it also covers SQL that does not run.
+func Explain(sql string, dialect string) Explanation {
+	e := explainer{sql: sql}
+	statements := splitTokenStatements(withoutComments(Tokenize(sql, dialect)))
+	switch len(statements) {
+	case 0:
+		return Explanation{Summary: "There is no statement to explain.", Steps: []string{}}
+	case 1:
+		summary, steps := e.statement(statements[0])
+		return Explanation{Summary: summary, Steps: steps}
+	}
+
+	explanation := Explanation{
+		Summary: fmt.Sprintf("Runs %d statements one after another.", len(statements)),
+		Steps:   []string{},
+	}
+	for i, statement := range statements {
+		summary, steps := e.statement(statement)
+		explanation.Steps = append(explanation.Steps, fmt.Sprintf("Statement %d: %s", i+1, summary))
+		for _, step := range steps {
+			explanation.Steps = append(explanation.Steps, fmt.Sprintf("Statement %d: %s", i+1, step))
+		}
+	}
+	return explanation
+}
+
+// statement describes one statement
+func (e explainer) statement(tokens []Token) (string, []string) {
+	switch firstKeyword(tokens) {
+	case "with":
+		return e.with(tokens)
+	case "select":
+		return e.query(tokens)
+	case "values":
+		return fmt.Sprintf("Returns a fixed list of %s.", plural(countGroups(tokens[1:]), "row")), []string{}
+	case "insert", "replace":
+		return e.insert(tokens)
+	case "update":
+		return e.update(tokens)
+	case "delete":
+		return e.delete(tokens)
+	case "create":
+		return e.create(tokens)
+	case "drop":
+		return e.drop(tokens)
+	case "alter":
+		return e.alter(tokens)
+	case "truncate":
+		name := "the table"
+		if ref, _, ok := parseTableRef(tokens, skipWords(tokens, 1, "table"), false); ok {
+			name = ref.Name
+		}
+		return fmt.Sprintf("Removes every row of %s.", name), []string{}
+	}
+	if len(tokens) == 0 {
+		return "An empty statement.", []string{}
+	}
+	return fmt.Sprintf("Runs a %s statement.", strings.ToUpper(tokens[0].Text)), []string{}
+}
+
+// with describes a statement led by a WITH clause
+func (e explainer) with(tokens []Token) (string, []string) {
+	clause := parseWithClause(tokens)
+	if clause == nil || len(clause.main) == 0 {
+		return "Defines temporary named results.", []string{}
+	}
+
+	steps := []string{}
+	for _, expression := range clause.Expressions {
+		if expression.Recursive {
+			steps = append(steps, fmt.Sprintf("Defines %s recursively: it starts from the rows of its first part and keeps adding rows built from the rows it produced last, until no new rows appear.", expression.Name))
+			continue
+		}
+		summary, _ := e.statement(expression.body)
+		steps = append(steps, fmt.Sprintf("Defines %s as a temporary result that %s", expression.Name, lowerFirst(summary)))
+	}
+	summary, mainSteps := e.statement(clause.main)
+	return summary, append(steps, mainSteps...)
+}
+
+// query describes a SELECT, combining the parts of UNION, INTERSECT and
+// EXCEPT
+func (e explainer) query(tokens []Token) (string, []string) {
+	parts, operators := splitSetOperations(tokens)
+	if len(parts) == 1 {
+		return e.selectQuery(parts[0])
+	}
+
+	// ORDER BY and LIMIT after the last query apply to the combined rows
+	last := parts[len(parts)-1]
+	var tail []Token
+	if cut := indexOfTopLevel(last, "order", "limit", "offset", "fetch"); cut > 0 {
+		parts[len(parts)-1], tail = last[:cut], last[cut:]
+	}
+
+	steps := []string{}
+	for i, part := range parts {
+		summary, partSteps := e.selectQuery(part)
+		steps = append(steps, fmt.Sprintf("Query %d: %s", i+1, summary))
+		for _, step := range partSteps {
+			steps = append(steps, fmt.Sprintf("Query %d: %s", i+1, step))
+		}
+	}
+	for _, operator := range operators {
+		switch operator {
+		case "union all":
+			steps = append(steps, "Appends the results of the queries to each other, keeping duplicates (UNION ALL).")
+		case "union":
+			steps = append(steps, "Appends the results of the queries to each other and removes duplicate rows (UNION).")
+		case "intersect", "intersect all":
+			steps = append(steps, "Keeps only the rows returned by both queries (INTERSECT).")
+		case "except", "except all", "minus":
+			steps = append(steps, "Keeps the rows of the first query that the second one does not return (EXCEPT).")
+		}
+	}
+	steps = append(steps, e.ordering(splitClauses(tail, selectClauseKeywords))...)
+	return fmt.Sprintf("Combines the results of %d queries.", len(parts)), dedupe(steps)
+}
+
+// selectQuery describes a single SELECT in the order the database
+// evaluates its clauses
+func (e explainer) selectQuery(tokens []Token) (string, []string) {
+	// (SELECT ...) as a part of a set operation
+	for len(tokens) > 1 && tokens[0].Text == "(" && closingParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	clauses := splitClauses(tokens, selectClauseKeywords)
+	steps := []string{}
+
+	sources := []string{}
+	if from := clauses["from"]; len(from) > 0 {
+		var fromSteps []string
+		sources, fromSteps = e.from(from)
+		steps = append(steps, fromSteps...)
+	}
+
+	if where := clauses["where"]; len(where) > 0 {
+		steps = append(steps, fmt.Sprintf("Keeps only the rows where %s%s.", e.text(where), subqueryNote(where)))
+	}
+
+	columns := clauses["select"]
+	distinct := len(columns) > 0 && columns[0].Is("distinct")
+	if distinct || len(columns) > 0 && columns[0].Is("all") {
+		columns = columns[1:]
+	}
+
+	aggregates := aggregateCalls(e, columns)
+	if group := clauses["group"]; len(group) > 0 {
+		steps = append(steps, fmt.Sprintf("Groups the rows that have the same %s.", e.text(group)))
+		if len(aggregates) > 0 {
+			steps = append(steps, fmt.Sprintf("Computes %s for each group.", joinList(aggregates)))
+		}
+	} else if len(aggregates) > 0 {
+		steps = append(steps, fmt.Sprintf("Computes %s over all the rows, giving a single row.", joinList(aggregates)))
+	}
+	if having := clauses["having"]; len(having) > 0 {
+		steps = append(steps, fmt.Sprintf("Keeps only the groups where %s%s.", e.text(having), subqueryNote(having)))
+	}
+	if windows := windowFunctions(columns); len(windows) > 0 {
+		steps = append(steps, fmt.Sprintf("Computes the window functions %s for each row from its related rows, without merging rows.", joinList(windows)))
+	}
+
+	outputs := outputNames(e, columns)
+	if len(outputs) == 1 && outputs[0] == "*" {
+		steps = append(steps, "Returns every column.")
+	} else if len(outputs) > 0 {
+		steps = append(steps, fmt.Sprintf("Returns %s: %s.", plural(len(outputs), "column"), joinList(outputs)))
+	}
+	if distinct {
+		steps = append(steps, "Removes duplicate rows from the result.")
+	}
+
+	steps = append(steps, e.ordering(clauses)...)
+
+	if len(sources) == 0 {
+		return "Computes a single row without reading any table.", steps
+	}
+	return fmt.Sprintf("Reads %s and returns the rows that result.", joinList(sources)), steps
+}
+
+// from describes the tables of a FROM clause and how they are joined,
+// returning their names and the steps
+func (e explainer) from(tokens []Token) ([]string, []string) {
+	sources := []string{}
+	steps := []string{}
+
+	for i, segment := range splitJoins(tokens) {
+		kind, sourceTokens, condition, using := parseJoinSegment(segment)
+		source := e.source(sourceTokens)
+		sources = append(sources, sourceName(source))
+
+		on := ""
+		if using {
+			on = fmt.Sprintf(" on equal values of %s", strings.Trim(e.text(condition), "()"))
+		} else if len(condition) > 0 {
+			on = " where " + e.text(condition)
+		}
+
+		switch {
+		case i == 0:
+			steps = append(steps, fmt.Sprintf("Reads the rows of %s.", source))
+		case kind == "cross":
+			steps = append(steps, fmt.Sprintf("Pairs every row so far with every row of %s (a cross join)%s.", source, on))
+		case kind == "natural":
+			steps = append(steps, fmt.Sprintf("Joins %s on every column with the same name on both sides (a natural join).", source))
+		case kind == "left":
+			steps = append(steps, fmt.Sprintf("Joins %s%s, keeping every row so far and filling the columns of %s with NULL where nothing matches (a left join).", source, on, sourceName(source)))
+		case kind == "right":
+			steps = append(steps, fmt.Sprintf("Joins %s%s, keeping every row of %s and filling the other columns with NULL where nothing matches (a right join).", source, on, sourceName(source)))
+		case kind == "full":
+			steps = append(steps, fmt.Sprintf("Joins %s%s, keeping the unmatched rows of both sides with NULL for the missing side (a full join).", source, on))
+		default:
+			steps = append(steps, fmt.Sprintf("Joins %s, keeping only the combinations%s (an inner join).", source, on))
+		}
+	}
+	return sources, steps
+}
+
+// source describes a table, subquery or table function of a FROM clause
+func (e explainer) source(tokens []Token) string {
+	if len(tokens) == 0 {
+		return "a table"
+	}
+	if ref, _, ok := parseTableRef(tokens, 0, true); ok {
+		if ref.Alias != "" && !strings.EqualFold(ref.Alias, ref.Name) {
+			return fmt.Sprintf("%s (as %s)", ref.Name, ref.Alias)
+		}
+		return ref.Name
+	}
+
+	start := 0
+	if tokens[0].Is("lateral") {
+		start = 1
+	}
+	if start < len(tokens) && tokens[start].Text == "(" {
+		end := closingParen(tokens, start)
+		alias := ""
+		rest := tokens[end+1:]
+		if len(rest) > 0 && rest[0].Is("as") {
+			rest = rest[1:]
+		}
+		if len(rest) > 0 {
+			alias = " (as " + rest[0].Value() + ")"
+		}
+		return "a subquery" + alias
+	}
+	return e.text(tokens)
+}
+
+// ordering describes the ORDER BY, LIMIT, OFFSET and FETCH clauses of a
+// query
+func (e explainer) ordering(clauses map[string][]Token) []string {
+	steps := []string{}
+	if order := clauses["order"]; len(order) > 0 {
+		items := []string{}
+		for _, item := range splitTopLevel(order, ",") {
+			items = append(items, e.orderItem(item))
+		}
+		steps = append(steps, fmt.Sprintf("Sorts the result by %s.", joinList(items)))
+	}
+	return append(steps, e.limit(clauses["limit"], clauses["offset"], clauses["fetch"])...)
+}
+
+// orderItem describes one ORDER BY item
+func (e explainer) orderItem(item []Token) string {
+	if len(item) > 1 {
+		switch last := item[len(item)-1]; {
+		case last.Is("desc"):
+			return e.text(item[:len(item)-1]) + " (highest first)"
+		case last.Is("asc"):
+			return e.text(item[:len(item)-1]) + " (lowest first)"
+		}
+	}
+	return e.text(item)
+}
+
+// limit describes LIMIT, OFFSET and FETCH clauses
+func (e explainer) limit(limit []Token, offset []Token, fetch []Token) []string {
+	steps := []string{}
+	count := ""
+	skip := ""
+	if len(limit) > 0 {
+		parts := splitTopLevel(limit, ",")
+		count = e.text(parts[len(parts)-1])
+		// MySQL's LIMIT offset, count
+		if len(parts) == 2 {
+			skip = e.text(parts[0])
+		}
+	}
+	if len(offset) > 0 {
+		skip = e.text(trimWords(offset, "row", "rows"))
+	}
+	if len(fetch) > 0 {
+		for _, token := range fetch {
+			if token.Kind == TokenNumber {
+				count = token.Text
+				break
+			}
+		}
+	}
+	if skip != "" {
+		steps = append(steps, fmt.Sprintf("Skips the first %s rows.", skip))
+	}
+	if count != "" && !strings.EqualFold(count, "all") {
+		steps = append(steps, fmt.Sprintf("Returns at most %s rows.", count))
+	}
+	return steps
+}
+
+// insert describes an INSERT or REPLACE
+func (e explainer) insert(tokens []Token) (string, []string) {
+	i := skipWords(tokens, 0, "insert", "replace", "ignore", "or", "into", "abort", "fail", "rollback")
+	ref, next, ok := parseTableRef(tokens, i, false)
+	if !ok {
+		return "Inserts rows into a table.", []string{}
+	}
+	steps := []string{}
+	rest := tokens[next:]
+
+	// Column list
+	if len(rest) > 0 && rest[0].Text == "(" {
+		end := closingParen(rest, 0)
+		steps = append(steps, fmt.Sprintf("Fills the columns %s; any other columns get their default values.", e.text(rest[1:end])))
+		rest = rest[end+1:]
+	}
+
+	summary := fmt.Sprintf("Inserts rows into %s.", ref.Name)
+	clauses := splitClauses(rest, words("values select with on returning default"))
+	switch {
+	case len(clauses["values"]) > 0:
+		summary = fmt.Sprintf("Inserts %s into %s.", plural(countGroups(clauses["values"]), "row"), ref.Name)
+	case len(clauses["select"]) > 0 || len(clauses["with"]) > 0:
+		summary = fmt.Sprintf("Inserts the rows returned by a query into %s.", ref.Name)
+		query := rest[indexOfTopLevel(rest, "select", "with"):]
+		if end := indexOfTopLevel(query, "on", "returning"); end > 0 {
+			query = query[:end]
+		}
+		_, querySteps := e.statement(query)
+		for _, step := range querySteps {
+			steps = append(steps, "The query: "+lowerFirst(step))
+		}
+	}
+	if len(clauses["on"]) > 0 || strings.HasPrefix(strings.ToLower(tokens[0].Text), "replace") {
+		steps = append(steps, "Where a row with the same key already exists, changes that row instead of adding another.")
+	}
+	if returning := clauses["returning"]; len(returning) > 0 {
+		steps = append(steps, fmt.Sprintf("Returns %s of the inserted rows.", e.text(returning)))
+	}
+	return summary, steps
+}
+
+// update describes an UPDATE
+func (e explainer) update(tokens []Token) (string, []string) {
+	ref, next, ok := parseTableRef(tokens, skipWords(tokens, 1, "or", "ignore", "low_priority", "only"), false)
+	if !ok {
+		return "Changes rows of a table.", []string{}
+	}
+	clauses := splitClauses(tokens[next:], words("set from where returning order limit"))
+
+	columns := []string{}
+	for _, assignment := range splitTopLevel(clauses["set"], ",") {
+		if len(assignment) > 0 {
+			columns = append(columns, assignment[0].Value())
+		}
+	}
+	changed := "columns"
+	if len(columns) > 0 {
+		changed = joinList(columns)
+	}
+
+	steps := []string{}
+	if set := clauses["set"]; len(set) > 0 {
+		steps = append(steps, fmt.Sprintf("Sets %s.", e.text(set)))
+	}
+	if from := clauses["from"]; len(from) > 0 {
+		steps = append(steps, fmt.Sprintf("Looks up the new values in %s.", e.text(from)))
+	}
+	if returning := clauses["returning"]; len(returning) > 0 {
+		steps = append(steps, fmt.Sprintf("Returns %s of the changed rows.", e.text(returning)))
+	}
+	if where := clauses["where"]; len(where) > 0 {
+		return fmt.Sprintf("Changes %s in the rows of %s where %s.", changed, ref.Name, e.text(where)), steps
+	}
+	return fmt.Sprintf("Changes %s in every row of %s.", changed, ref.Name), steps
+}
+
+// delete describes a DELETE
+func (e explainer) delete(tokens []Token) (string, []string) {
+	ref, next, ok := parseTableRef(tokens, skipWords(tokens, 1, "low_priority", "quick", "ignore", "from", "only"), false)
+	if !ok {
+		return "Deletes rows of a table.", []string{}
+	}
+	clauses := splitClauses(tokens[next:], words("using where returning order limit"))
+	steps := []string{}
+	if returning := clauses["returning"]; len(returning) > 0 {
+		steps = append(steps, fmt.Sprintf("Returns %s of the deleted rows.", e.text(returning)))
+	}
+	if where := clauses["where"]; len(where) > 0 {
+		return fmt.Sprintf("Deletes the rows of %s where %s%s.", ref.Name, e.text(where), subqueryNote(where)), steps
+	}
+	return fmt.Sprintf("Deletes every row of %s.", ref.Name), steps
+}
+
+// create describes a CREATE statement
+func (e explainer) create(tokens []Token) (string, []string) {
+	i := skipWords(tokens, 1, "or", "replace", "temporary", "temp", "unique", "materialized", "unlogged", "virtual")
+	if i >= len(tokens) {
+		return "Creates a database object.", []string{}
+	}
+	kind := strings.ToLower(tokens[i].Text)
+	i = skipWords(tokens, i+1, "if", "not", "exists", "concurrently")
+	if i >= len(tokens) {
+		return fmt.Sprintf("Creates a %s.", kind), []string{}
+	}
+	name := e.qualifiedName(tokens, i)
+	rest := tokens[i:]
+
+	switch kind {
+	case "table":
+		if as := indexOfTopLevel(rest, "as"); as >= 0 && as+1 < len(rest) {
+			_, steps := e.statement(rest[as+1:])
+			return fmt.Sprintf("Creates the table %s and fills it with the rows of a query.", name), prefixSteps("The query: ", steps)
+		}
+		if open := indexOfTopLevel(rest, "("); open >= 0 {
+			definitions := rest[open+1 : closingParen(rest, open)]
+			columns := []string{}
+			for _, definition := range splitTopLevel(definitions, ",") {
+				if len(definition) > 0 && !constraintKeywords[strings.ToLower(definition[0].Text)] {
+					columns = append(columns, definition[0].Value())
+				}
+			}
+			return fmt.Sprintf("Creates the table %s with %s: %s.", name, plural(len(columns), "column"), joinList(columns)), []string{}
+		}
+	case "view":
+		if as := indexOfTopLevel(rest, "as"); as >= 0 && as+1 < len(rest) {
+			_, steps := e.statement(rest[as+1:])
+			return fmt.Sprintf("Creates the view %s, a saved query that runs whenever the view is read.", name), prefixSteps("The view: ", steps)
+		}
+	case "index":
+		if on := indexOfTopLevel(rest, "on"); on >= 0 && on+1 < len(rest) {
+			return fmt.Sprintf("Creates the index %s on %s so lookups and sorts on those columns avoid reading the whole table.", name, e.text(rest[on+1:])), []string{}
+		}
+	}
+	return fmt.Sprintf("Creates the %s %s.", kind, name), []string{}
+}
+
+// drop describes a DROP statement
+func (e explainer) drop(tokens []Token) (string, []string) {
+	i := skipWords(tokens, 1, "temporary", "materialized")
+	if i >= len(tokens) {
+		return "Drops a database object.", []string{}
+	}
+	kind := strings.ToLower(tokens[i].Text)
+	next := i + 1
+	condition := ""
+	if next+1 < len(tokens) && tokens[next].Is("if") && tokens[next+1].Is("exists") {
+		condition = " if it exists"
+		next += 2
+	}
+	name := e.qualifiedName(tokens, next)
+	if kind == "table" {
+		return fmt.Sprintf("Drops the table %s%s, deleting it together with all of its rows.", name, condition), []string{}
+	}
+	return fmt.Sprintf("Drops the %s %s%s.", kind, name, condition), []string{}
+}
+
+// alter describes an ALTER statement
+func (e explainer) alter(tokens []Token) (string, []string) {
+	if len(tokens) < 3 {
+		return "Changes a database object.", []string{}
+	}
+	kind := strings.ToLower(tokens[1].Text)
+	i := skipWords(tokens, 2, "if", "exists", "only")
+	name := e.qualifiedName(tokens, i)
+	changes := []string{}
+	for _, change := range splitTopLevel(tokens[minInt(i+1, len(tokens)):], ",") {
+		changes = append(changes, e.text(change))
+	}
+	return fmt.Sprintf("Changes the %s %s.", kind, name), prefixSteps("Change: ", changes)
+}
+
+// qualifiedName returns the possibly schema-qualified name at tokens[i]
+func (e explainer) qualifiedName(tokens []Token, i int) string {
+	if i >= len(tokens) {
+		return ""
+	}
+	name := tokens[i].Value()
+	for j := i + 1; j+1 < len(tokens) && tokens[j].Text == "."; j += 2 {
+		name += "." + tokens[j+1].Value()
+	}
+	return name
+}
+
+// text returns the SQL text of tokens with whitespace collapsed, shortened
+// when it is long
+func (e explainer) text(tokens []Token) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	last := tokens[len(tokens)-1]
+	text := strings.Join(strings.Fields(e.sql[tokens[0].Offset:last.Offset+len(last.Text)]), " ")
+	if len(text) > maxQuotedClause {
+		cut := strings.LastIndexByte(text[:maxQuotedClause], ' ')
+		if cut <= 0 {
+			cut = maxQuotedClause
+		}
+		text = text[:cut] + " ..."
+	}
+	return text
+}
+
+// splitClauses splits tokens at the top-level keywords that start clauses,
+// keyed by keyword. "GROUP BY" and "ORDER BY" are keyed group and order.
+func splitClauses(tokens []Token, keywords map[string]bool) map[string][]Token {
+	clauses := map[string][]Token{}
+	current := ""
+	start := 0
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 0 || token.Kind != TokenWord {
+			continue
+		}
+		keyword := strings.ToLower(token.Text)
+		if !keywords[keyword] || (keyword == "group" || keyword == "order") && !(i+1 < len(tokens) && tokens[i+1].Is("by")) {
+			continue
+		}
+		// Keep the first occurrence, such as the outer SELECT's clause
+		if _, seen := clauses[keyword]; seen {
+			continue
+		}
+		if current != "" {
+			clauses[current] = tokens[start:i]
+		}
+		current = keyword
+		start = i + 1
+		if keyword == "group" || keyword == "order" {
+			start = i + 2
+			i++
+		}
+	}
+	if current != "" && start <= len(tokens) {
+		clauses[current] = tokens[start:]
+	}
+	return clauses
+}
+
+// splitSetOperations splits a query at top-level UNION, INTERSECT and
+// EXCEPT and returns the parts and the operators between them
+func splitSetOperations(tokens []Token) ([][]Token, []string) {
+	parts := [][]Token{}
+	operators := []string{}
+	start := 0
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 0 || !(tokens[i].Is("union") || tokens[i].Is("intersect") || tokens[i].Is("except") || tokens[i].Is("minus")) {
+			continue
+		}
+		operator := strings.ToLower(tokens[i].Text)
+		parts = append(parts, tokens[start:i])
+		if i+1 < len(tokens) && (tokens[i+1].Is("all") || tokens[i+1].Is("distinct")) {
+			if tokens[i+1].Is("all") {
+				operator += " all"
+			}
+			i++
+		}
+		operators = append(operators, operator)
+		start = i + 1
+	}
+	return append(parts, tokens[start:]), operators
+}
+
+// splitJoins splits a FROM clause into its first source and one segment
+// per joined source, each starting with its join keywords or comma
+func splitJoins(tokens []Token) [][]Token {
+	segments := [][]Token{}
+	start := 0
+	depth := 0
+	for i, token := range tokens {
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 0 || i == start {
+			continue
+		}
+		startsJoin := token.Text == "," ||
+			token.Kind == TokenWord && (token.Is("join") || joinModifiers[strings.ToLower(token.Text)]) &&
+				!(tokens[i-1].Kind == TokenWord && (joinModifiers[strings.ToLower(tokens[i-1].Text)]))
+		if startsJoin {
+			segments = append(segments, tokens[start:i])
+			start = i
+		}
+	}
+	return append(segments, tokens[start:])
+}
+
+// parseJoinSegment splits a segment of splitJoins into its join kind, its
+// source and its ON or USING condition. The kind is "" for the first
+// source, "cross" for a comma and the lower-cased leading join keyword
+// otherwise, with plain JOIN being "inner".
+func parseJoinSegment(segment []Token) (kind string, source []Token, condition []Token, using bool) {
+	body := segment
+	for len(body) > 0 && (body[0].Text == "," || body[0].Kind == TokenWord && (joinModifiers[strings.ToLower(body[0].Text)] || body[0].Is("join"))) {
+		if body[0].Text == "," {
+			kind = "cross"
+		} else if !body[0].Is("join") && !body[0].Is("outer") {
+			kind = strings.ToLower(body[0].Text)
+		} else if kind == "" {
+			kind = "inner"
+		}
+		body = body[1:]
+	}
+
+	// Source, then ON or USING
+	source = body
+	depth := 0
+	for j, token := range body {
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && (token.Is("on") || token.Is("using")) {
+			return kind, body[:j], body[j+1:], token.Is("using")
+		}
+	}
+	return kind, source, nil, false
+}
+
+// splitTopLevel splits tokens at a separator, punctuation or a keyword,
+// outside parentheses
+func splitTopLevel(tokens []Token, separator string) [][]Token {
+	parts := [][]Token{}
+	start := 0
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.Text == "(":
+			depth++
+		case token.Text == ")":
+			depth--
+		case token.Text == separator || token.Is(separator):
+			if depth == 0 {
+				parts = append(parts, tokens[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if start < len(tokens) {
+		parts = append(parts, tokens[start:])
+	}
+	return parts
+}
+
+// indexOfTopLevel returns the index of the first top-level token matching
+// one of the keywords or punctuation, or -1
+func indexOfTopLevel(tokens []Token, texts ...string) int {
+	depth := 0
+	for i, token := range tokens {
+		for _, text := range texts {
+			if depth == 0 && (token.Is(text) || token.Text == text) {
+				return i
+			}
+		}
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+	}
+	return -1
+}
+
+// countGroups counts the top-level parenthesized groups of a VALUES list
+func countGroups(tokens []Token) int {
+	count := 0
+	depth := 0
+	for _, token := range tokens {
+		switch token.Text {
+		case "(":
+			if depth == 0 {
+				count++
+			}
+			depth++
+		case ")":
+			depth--
+		}
+	}
+	return count
+}
+
+// aggregateCalls returns the aggregate function calls of a select list
+// that are not window functions
+func aggregateCalls(e explainer, columns []Token) []string {
+	calls := []string{}
+	seen := map[string]bool{}
+	for i := 0; i+1 < len(columns); i++ {
+		if columns[i].Kind != TokenWord || !aggregateFunctions[strings.ToLower(columns[i].Text)] || columns[i+1].Text != "(" {
+			continue
+		}
+		end := closingParen(columns, i+1)
+		if end+1 < len(columns) && columns[end+1].Is("over") {
+			continue
+		}
+		call := e.text(columns[i : end+1])
+		if !seen[call] {
+			seen[call] = true
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// windowFunctions returns the names of the functions applied OVER a window
+func windowFunctions(columns []Token) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for i, token := range columns {
+		if !token.Is("over") || i == 0 || columns[i-1].Text != ")" {
+			continue
+		}
+		depth := 0
+		for j := i - 1; j > 0; j-- {
+			switch columns[j].Text {
+			case ")":
+				depth++
+			case "(":
+				depth--
+			}
+			if depth == 0 {
+				name := strings.ToUpper(columns[j-1].Text)
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+				break
+			}
+		}
+	}
+	return names
+}
+
+// outputNames returns the alias or text of each item of a select list
+func outputNames(e explainer, columns []Token) []string {
+	names := []string{}
+	for _, item := range splitTopLevel(columns, ",") {
+		if len(item) == 0 {
+			continue
+		}
+		last := item[len(item)-1]
+		switch {
+		case len(item) > 2 && item[len(item)-2].Is("as"):
+			names = append(names, last.Value())
+		case len(item) > 1 && (last.Kind == TokenWord || last.Kind == TokenQuotedIdentifier) &&
+			item[len(item)-2].Text != "." && !reservedWords["postgresql"][strings.ToLower(last.Text)] && !clauseKeywords[strings.ToLower(last.Text)]:
+			// expression alias
+			names = append(names, last.Value())
+		default:
+			names = append(names, e.text(item))
+		}
+	}
+	return names
+}
+
+// subqueryNote points out a nested query in a condition
+func subqueryNote(tokens []Token) string {
+	for _, token := range tokens {
+		if token.Is("select") {
+			return " (checked with a subquery)"
+		}
+	}
+	return ""
+}
+
+// skipWords returns the index of the first token from i on that is not
+// one of the words
+func skipWords(tokens []Token, i int, skipped ...string) int {
+	for i < len(tokens) {
+		found := false
+		for _, word := range skipped {
+			if tokens[i].Is(word) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+// trimWords drops trailing tokens that are one of the words
+func trimWords(tokens []Token, trimmed ...string) []Token {
+	for len(tokens) > 1 {
+		found := false
+		for _, word := range trimmed {
+			if tokens[len(tokens)-1].Is(word) {
+				found = true
+			}
+		}
+		if !found {
+			break
+		}
+		tokens = tokens[:len(tokens)-1]
+	}
+	return tokens
+}
+
+// sourceName returns the table of a source description without its alias
+func sourceName(source string) string {
+	if name, _, found := strings.Cut(source, " (as "); found {
+		return name
+	}
+	return source
+}
+
+// joinList joins items as "a, b and c"
+func joinList(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// plural returns a count with a noun, adding an s unless the count is one
+func plural(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// lowerFirst lower-cases the first letter of a sentence
+func lowerFirst(sentence string) string {
+	if sentence == "" {
+		return sentence
+	}
+	return strings.ToLower(sentence[:1]) + sentence[1:]
+}
+
+// prefixSteps prefixes each step
+func prefixSteps(prefix string, steps []string) []string {
+	prefixed := make([]string, 0, len(steps))
+	for _, step := range steps {
+		prefixed = append(prefixed, prefix+lowerFirst(step))
+	}
+	return prefixed
+}
+
+// dedupe drops repeated steps, keeping the first
+func dedupe(steps []string) []string {
+	result := []string{}
+	seen := map[string]bool{}
+	for _, step := range steps {
+		if !seen[step] {
+			seen[step] = true
+			result = append(result, step)
+		}
+	}
+	return result
+}
@@ -0,0 +1,104 @@
+package sqlvalidator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplainSelect(t *testing.T) {
+	sql := `SELECT u.name, COUNT(o.id) AS orders
+		FROM users u
+		LEFT JOIN orders o ON o.user_id = u.id
+		WHERE u.active = 1
+		GROUP BY u.name
+		HAVING COUNT(o.id) > 2
+		ORDER BY orders DESC
+		LIMIT 10`
+
+	got := Explain(sql, "sqlite")
+	want := Explanation{
+		Summary: "Reads users and orders and returns the rows that result.",
+		Steps: []string{
+			"Reads the rows of users (as u).",
+			"Joins orders (as o) where o.user_id = u.id, keeping every row so far and filling the columns of orders with NULL where nothing matches (a left join).",
+			"Keeps only the rows where u.active = 1.",
+			"Groups the rows that have the same u.name.",
+			"Computes COUNT(o.id) for each group.",
+			"Keeps only the groups where COUNT(o.id) > 2.",
+			"Returns 2 columns: u.name and orders.",
+			"Sorts the result by orders (highest first).",
+			"Returns at most 10 rows.",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestExplainSelectWithoutTables(t *testing.T) {
+	got := Explain("SELECT 1", "postgresql")
+	if got.Summary != "Computes a single row without reading any table." {
+		t.Errorf("unexpected summary %q", got.Summary)
+	}
+}
+
+func TestExplainWindowsAndSetOperations(t *testing.T) {
+	got := Explain("SELECT DISTINCT name, ROW_NUMBER() OVER (ORDER BY price) AS rank FROM items UNION ALL SELECT name, 0 AS rank FROM archived LIMIT 5, 10", "mysql")
+	steps := strings.Join(got.Steps, "\n")
+	for _, want := range []string{
+		"Query 1: Computes the window functions ROW_NUMBER",
+		"Query 1: Removes duplicate rows",
+		"keeping duplicates (UNION ALL)",
+		"\nSkips the first 5 rows.\nReturns at most 10 rows.",
+	} {
+		if !strings.Contains(steps, want) {
+			t.Errorf("expected a step containing %q in\n%s", want, steps)
+		}
+	}
+}
+
+func TestExplainCTE(t *testing.T) {
+	got := Explain(`WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 5),
+		big AS (SELECT * FROM orders WHERE total > 100)
+		SELECT * FROM n, big`, "postgresql")
+	if !strings.HasPrefix(got.Steps[0], "Defines n recursively") {
+		t.Errorf("expected the recursive CTE first, got %q", got.Steps[0])
+	}
+	if got.Steps[1] != "Defines big as a temporary result that reads orders and returns the rows that result." {
+		t.Errorf("unexpected CTE step %q", got.Steps[1])
+	}
+	if !strings.Contains(strings.Join(got.Steps, "\n"), "Pairs every row so far with every row of big (a cross join).") {
+		t.Errorf("expected the comma join to be a cross join, got %v", got.Steps)
+	}
+}
+
+func TestExplainWrites(t *testing.T) {
+	tests := map[string]string{
+		"INSERT INTO users (name, email) VALUES ('a', 'a@x'), ('b', 'b@x')":       "Inserts 2 rows into users.",
+		"INSERT INTO archive SELECT * FROM orders WHERE total < 0":                "Inserts the rows returned by a query into archive.",
+		"UPDATE items SET price = price * 2, stock = 0 WHERE id = 3":              "Changes price and stock in the rows of items where id = 3.",
+		"UPDATE items SET price = 0":                                              "Changes price in every row of items.",
+		"DELETE FROM sessions WHERE expires_at < now()":                           "Deletes the rows of sessions where expires_at < now().",
+		"DELETE FROM sessions":                                                    "Deletes every row of sessions.",
+		"CREATE TABLE pets (id INTEGER PRIMARY KEY, name TEXT, PRIMARY KEY (id))": "Creates the table pets with 2 columns: id and name.",
+		"CREATE INDEX idx_name ON users (name)":                                   "Creates the index idx_name on users (name) so lookups and sorts on those columns avoid reading the whole table.",
+		"DROP TABLE IF EXISTS pets":                                               "Drops the table pets if it exists, deleting it together with all of its rows.",
+		"TRUNCATE TABLE logs":                                                     "Removes every row of logs.",
+	}
+	for sql, want := range tests {
+		if got := Explain(sql, "postgresql").Summary; got != want {
+			t.Errorf("Explain(%q) = %q, want %q", sql, got, want)
+		}
+	}
+}
+
+func TestExplainSeveralStatements(t *testing.T) {
+	got := Explain("DELETE FROM a; SELECT * FROM b -- done", "sqlite")
+	if got.Summary != "Runs 2 statements one after another." {
+		t.Errorf("unexpected summary %q", got.Summary)
+	}
+	if got.Steps[0] != "Statement 1: Deletes every row of a." || got.Steps[1] != "Statement 2: Reads b and returns the rows that result." {
+		t.Errorf("unexpected steps %v", got.Steps)
+	}
+}
@@ -0,0 +1,90 @@
+package sqlvalidator
+
+import (
+	"strings"
+)
+
+// Types of highlighted tokens
+const (
+	HighlightKeyword    = "keyword"
+	HighlightIdentifier = "identifier"
+	HighlightString     = "string"
+	HighlightNumber     = "number"
+	HighlightOperator   = "operator"
+	HighlightComment    = "comment"
+)
+
+// HighlightToken is a token of a query with the type an editor colours it
+// by. Start and End are byte offsets in the query; Line and Column, where
+// the token starts, are 1-based and count characters.
+type HighlightToken struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Highlight splits sql into typed tokens with the tokenizer every analysis
+// uses, so an editor highlights and selects what the server reads. A word
+// is a keyword when the dialect reserves it or it is a common keyword,
+// unless it follows a dot as part of a qualified name. Variables and
+// parameters such as @total and $1 are identifiers, and punctuation is an
+// operator.
+func Highlight(sql string, dialect string) []HighlightToken {
+	tokens := Tokenize(sql, dialect)
+	highlighted := make([]HighlightToken, 0, len(tokens))
+	line, column, position := 1, 1, 0
+	for i, token := range tokens {
+		// Advance the position incrementally rather than from the start for
+		// every token
+		for _, ch := range sql[position:token.Offset] {
+			if ch == '\n' {
+				line, column = line+1, 1
+			} else {
+				column++
+			}
+		}
+		position = token.Offset
+
+		highlighted = append(highlighted, HighlightToken{
+			Type:   highlightType(tokens, i, dialect),
+			Text:   token.Text,
+			Start:  token.Offset,
+			End:    token.Offset + len(token.Text),
+			Line:   line,
+			Column: column,
+		})
+	}
+	return highlighted
+}
+
+// highlightType returns the type of tokens[i]
+func highlightType(tokens []Token, i int, dialect string) string {
+	token := tokens[i]
+	switch token.Kind {
+	case TokenString:
+		return HighlightString
+	case TokenNumber:
+		return HighlightNumber
+	case TokenComment:
+		return HighlightComment
+	case TokenPunctuation:
+		return HighlightOperator
+	case TokenQuotedIdentifier:
+		return HighlightIdentifier
+	}
+
+	lower := strings.ToLower(token.Text)
+	if tokenAt(tokens, i-1).Text == "." {
+		return HighlightIdentifier
+	}
+	if sqlKeywords[lower] || reservedWords[dialect][lower] || dialectKeywords[dialect][lower] {
+		return HighlightKeyword
+	}
+	if _, known := dialectKeywords[dialect]; !known && len(ReservedIn(lower)) > 0 {
+		return HighlightKeyword
+	}
+	return HighlightIdentifier
+}
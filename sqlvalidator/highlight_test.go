@@ -0,0 +1,59 @@
+package sqlvalidator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describeHighlight renders tokens as "type:text" pairs
+func describeHighlight(tokens []HighlightToken) string {
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		parts[i] = token.Type + ":" + token.Text
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+		want    string
+	}{
+		{"sqlite", "SELECT id, 'a' FROM t WHERE n >= 1.5",
+			"keyword:SELECT identifier:id operator:, string:'a' keyword:FROM identifier:t keyword:WHERE identifier:n operator:>= number:1.5"},
+		{"postgresql", `SELECT "order".key FROM "order" -- all`,
+			`keyword:SELECT identifier:"order" operator:. identifier:key keyword:FROM identifier:"order" comment:-- all`},
+		{"mysql", "SELECT `select`, \"text\" # note",
+			"keyword:SELECT identifier:`select` operator:, string:\"text\" comment:# note"},
+		{"postgresql", "SELECT $1::int /* cast */",
+			"keyword:SELECT identifier:$1 operator::: identifier:int comment:/* cast */"},
+		{"mysql", "SET @total = 0", "keyword:SET identifier:@total operator:= number:0"},
+		{"mock", "SELECT x FROM t LIMIT 1", "keyword:SELECT identifier:x keyword:FROM identifier:t keyword:LIMIT number:1"},
+	}
+
+	for _, tt := range tests {
+		if got := describeHighlight(Highlight(tt.sql, tt.dialect)); got != tt.want {
+			t.Errorf("Highlight(%q, %s) =\n%s\nwant\n%s", tt.sql, tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightSpans(t *testing.T) {
+	sql := "SELECT 'é'\n  FROM t"
+	tokens := Highlight(sql, "sqlite")
+	got := make([]string, len(tokens))
+	for i, token := range tokens {
+		if sql[token.Start:token.End] != token.Text {
+			t.Errorf("token %q does not span %d-%d", token.Text, token.Start, token.End)
+		}
+		got[i] = fmt.Sprintf("%s@%d:%d", token.Text, token.Line, token.Column)
+	}
+	if want := "SELECT@1:1 'é'@1:8 FROM@2:3 t@2:8"; strings.Join(got, " ") != want {
+		t.Errorf("Highlight() positions = %s, want %s", strings.Join(got, " "), want)
+	}
+	if len(Highlight("", "sqlite")) != 0 {
+		t.Error("expected no tokens for empty SQL")
+	}
+}
@@ -0,0 +1,272 @@
+package sqlvalidator
+
+import (
+	"strings"
+)
+
+// words builds a set from a space-separated list
+func words(list string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(list) {
+		set[word] = true
+	}
+	return set
+}
+
+// Words reserved in every supported dialect
+const commonReservedWords = "all and as asc between by case check collate column constraint create cross " +
+	"default delete desc distinct drop else end except exists false foreign from full group having in " +
+	"inner insert intersect into is join left like natural not null on or order outer primary references " +
+	"right select set table then to true union unique update using values when where with"
+
+// Reserved words per dialect. Quoting a word that did not need it is
+// harmless, so the lists err on the side of including keywords.
+var reservedWords = map[string]map[string]bool{
+	"mysql": words(commonReservedWords + " add alter before both call change condition continue current_date " +
+		"current_time current_timestamp current_user database databases dense_rank div double dual fetch " +
+		"float for force generated grant groups if ignore index int integer interval key keys kill lag lead " +
+		"leading lines load lock long match mod option optimize out over partition percent_rank precision " +
+		"procedure purge range rank read real recursive regexp release rename repeat replace require " +
+		"restrict return revoke rlike row row_number rows schema schemas separator show signal spatial sql " +
+		"starting straight_join system trailing trigger undo unlock unsigned usage use varchar varying while " +
+		"window write xor zerofill"),
+	"postgresql": words(commonReservedWords + " analyse analyze array asymmetric authorization binary both cast " +
+		"collation concurrently current_catalog current_date current_role current_schema current_time " +
+		"current_timestamp current_user deferrable do fetch for freeze grant ilike initially isnull lateral " +
+		"leading limit localtime localtimestamp notnull offset only overlaps placing returning session_user " +
+		"similar some symmetric tablesample trailing user variadic verbose window"),
+	"sqlite": words(commonReservedWords + " abort add after alter autoincrement before commit conflict " +
+		"deferrable escape glob if index isnull limit notnull offset raise regexp rollback transaction " +
+		"trigger view"),
+}
+
+// Keywords that stand for values, rewritten only where a table name must be
+var valueKeywords = words("null true false default unknown current_date current_time current_timestamp " +
+	"current_user current_role current_schema current_catalog session_user user localtime localtimestamp")
+
+// Keywords that start an expression rather than name a column
+var expressionKeywords = words("case not exists any some all array interval cast select row with values")
+
+// Keywords that start a constraint rather than a column definition
+var constraintKeywords = words("constraint primary foreign unique check key index fulltext spatial exclude like")
+
+// Keywords that may follow a table keyword without being a table name
+var tableModifiers = words("if only lateral outfile dumpfile set")
+
+// Keywords after which a table name follows
+var tableKeywords = words("from join into update table exists")
+
+// Keywords that may follow AS without being an alias
+var asFollowers = words("select with values not materialized table")
+
+// Comparison operators, whose operands are expressions
+var comparisonOperators = words("= < > <= >= <> !=")
+
+// Keywords that start a condition
+var conditionKeywords = words("where and or on when")
+
+// Predicates that follow a column in a condition
+var predicateKeywords = words("is in like between")
+
+// QuoteIdentifier quotes a name for a dialect: backticks for MySQL and
+// double quotes for the others
+func QuoteIdentifier(name string, dialect string) string {
+	if dialect == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// SanitizeIdentifiers quotes unquoted identifiers that collide with reserved
+// words and rewrites quoted identifiers to the dialect's quoting style, so
+// that a column named order or a PostgreSQL table named user can be queried.
+// A reserved word is only treated as an identifier where its position shows
+// it names a table or column; keywords, strings and comments are unchanged.
+func SanitizeIdentifiers(sql string, dialect string) string {
+	reserved, ok := reservedWords[dialect]
+	if !ok {
+		reserved = words(commonReservedWords)
+	}
+
+	tokens := withoutComments(Tokenize(sql, dialect))
+	var out strings.Builder
+	last := 0
+	parens := []string{}
+	statement := ""
+	openCases := 0
+	rewritten := -1
+
+	for i, token := range tokens {
+		switch token.Text {
+		case "(":
+			parens = append(parens, parenKind(tokens, i))
+		case ")":
+			if len(parens) > 0 {
+				parens = parens[:len(parens)-1]
+			}
+		case ";":
+			statement = ""
+			parens = parens[:0]
+			openCases = 0
+		}
+		if statement == "" && token.Kind == TokenWord {
+			statement = strings.ToLower(token.Text)
+		}
+
+		replacement := token.Text
+		switch token.Kind {
+		case TokenQuotedIdentifier:
+			replacement = requote(token, dialect)
+		case TokenWord:
+			word := strings.ToLower(token.Text)
+			// END closes a CASE expression before it can name a column
+			if word == "case" {
+				openCases++
+			}
+			if word == "end" && openCases > 0 {
+				openCases--
+				break
+			}
+			paren := ""
+			if len(parens) > 0 {
+				paren = parens[len(parens)-1]
+			}
+			if reserved[word] && usedAsIdentifier(tokens, i, paren, statement, rewritten == i-1) {
+				name := token.Text
+				// PostgreSQL folds unquoted identifiers to lower case
+				if dialect == "postgresql" {
+					name = word
+				}
+				replacement = QuoteIdentifier(name, dialect)
+				rewritten = i
+			}
+		}
+
+		if replacement != token.Text {
+			out.WriteString(sql[last:token.Offset])
+			out.WriteString(replacement)
+			last = token.Offset + len(token.Text)
+		}
+	}
+	out.WriteString(sql[last:])
+	return out.String()
+}
+
+// requote rewrites a quoted identifier in the dialect's quoting style
+func requote(token Token, dialect string) string {
+	opening := token.Text[0]
+	if (dialect == "mysql" && opening == '`') || (dialect != "mysql" && opening == '"') {
+		return token.Text
+	}
+	closing := opening
+	if opening == '[' {
+		closing = ']'
+	}
+	name := strings.ReplaceAll(token.Value(), string([]byte{closing, closing}), string(closing))
+	return QuoteIdentifier(name, dialect)
+}
+
+// parenKind classifies the parenthesis at tokens[i]: "cast" for CAST and
+// CONVERT calls, "list" for lists of column names, "columns" for the column
+// definitions of CREATE TABLE and "" otherwise
+func parenKind(tokens []Token, i int) string {
+	prev, beforePrev := tokenAt(tokens, i-1), tokenAt(tokens, i-2)
+	if prev.Is("cast") || prev.Is("convert") {
+		return "cast"
+	}
+	if prev.Is("key") || prev.Is("unique") || prev.Is("using") {
+		return "list"
+	}
+	if prev.Kind != TokenWord && prev.Kind != TokenQuotedIdentifier {
+		return ""
+	}
+	switch {
+	case beforePrev.Is("table") || beforePrev.Is("exists"):
+		return "columns"
+	case beforePrev.Is("into") || beforePrev.Is("references") || beforePrev.Is("on") ||
+		beforePrev.Is("key") || beforePrev.Is("index") || beforePrev.Is("using"):
+		return "list"
+	}
+	return ""
+}
+
+// usedAsIdentifier reports whether the reserved word at tokens[i] is used
+// as a table or column name rather than as a keyword. prevIsName is set
+// when the preceding word has already been recognized as an identifier.
+func usedAsIdentifier(tokens []Token, i int, paren string, statement string, prevIsName bool) bool {
+	word := strings.ToLower(tokens[i].Text)
+	prev, next := tokenAt(tokens, i-1), tokenAt(tokens, i+1)
+	prevWord := ""
+	if prev.Kind == TokenWord && !prevIsName {
+		prevWord = strings.ToLower(prev.Text)
+	}
+
+	// Qualified names such as order.id or t.order
+	if prev.Text == "." || next.Text == "." {
+		return true
+	}
+
+	// Table names and aliases; USER names the current user elsewhere but
+	// cannot here
+	if tableKeywords[prevWord] {
+		return !tableModifiers[word] && !expressionKeywords[word] && (!valueKeywords[word] || word == "user")
+	}
+	if prevWord == "as" && paren != "cast" {
+		return !asFollowers[word] && next.Text != "("
+	}
+
+	if valueKeywords[word] {
+		return false
+	}
+
+	switch paren {
+	case "cast":
+		return prev.Text == "(" && next.Is("as")
+	case "list":
+		if (prev.Text == "(" || prev.Text == ",") &&
+			(next.Text == "," || next.Text == ")" || next.Is("asc") || next.Is("desc")) {
+			return true
+		}
+	case "columns":
+		if (prev.Text == "(" || prev.Text == ",") && !constraintKeywords[word] &&
+			(next.Kind == TokenWord || next.Kind == TokenQuotedIdentifier) {
+			return true
+		}
+	}
+
+	switch {
+	case prevWord == "column":
+		return word != "if"
+	case prevWord == "add":
+		return !constraintKeywords[word] && word != "column" && next.Kind == TokenWord
+	case prevWord == "by":
+		return !expressionKeywords[word] && next.Text != "("
+	}
+
+	if next.Text == "(" || expressionKeywords[word] {
+		return false
+	}
+
+	// Select list items such as SELECT order, name FROM t
+	if (prevWord == "select" || prevWord == "distinct" || prev.Text == ",") && statement != "grant" && statement != "revoke" &&
+		(next.Text == "," || next.Text == ";" || next.Text == "" || next.Is("from") || next.Is("as")) {
+		return true
+	}
+
+	// Operands of comparisons and predicates such as order = 1 or order IS NULL
+	if comparisonOperators[next.Text] || comparisonOperators[prev.Text] {
+		return true
+	}
+	if (conditionKeywords[prevWord] || prev.Text == "(") && predicateKeywords[strings.ToLower(next.Text)] {
+		return true
+	}
+	return false
+}
+
+// tokenAt returns tokens[i], or an empty token when i is out of range
+func tokenAt(tokens []Token, i int) Token {
+	if i < 0 || i >= len(tokens) {
+		return Token{}
+	}
+	return tokens[i]
+}
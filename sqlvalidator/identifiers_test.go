@@ -0,0 +1,69 @@
+package sqlvalidator
+
+import "testing"
+
+func TestSanitizeIdentifiersQuotesReservedColumns(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+		want    string
+	}{
+		{"sqlite", "SELECT order, name FROM items", `SELECT "order", name FROM items`},
+		{"mysql", "SELECT order, name FROM items", "SELECT `order`, name FROM items"},
+		{"mysql", "SELECT i.order FROM items i WHERE order > 5 ORDER BY order DESC",
+			"SELECT i.`order` FROM items i WHERE `order` > 5 ORDER BY `order` DESC"},
+		{"postgresql", "SELECT * FROM user WHERE Group = 'admin'", `SELECT * FROM "user" WHERE "group" = 'admin'`},
+		{"sqlite", "INSERT INTO items (order, name) VALUES (1, 'a')", `INSERT INTO items ("order", name) VALUES (1, 'a')`},
+		{"sqlite", "UPDATE items SET order = 2 WHERE id = 1", `UPDATE items SET "order" = 2 WHERE id = 1`},
+		{"postgresql", "CREATE TABLE IF NOT EXISTS orders (id INT, desc TEXT, PRIMARY KEY (id))",
+			`CREATE TABLE IF NOT EXISTS orders (id INT, "desc" TEXT, PRIMARY KEY (id))`},
+		{"sqlite", "SELECT name AS from FROM items", `SELECT name AS "from" FROM items`},
+		{"sqlite", "ALTER TABLE items ADD COLUMN limit INTEGER", `ALTER TABLE items ADD COLUMN "limit" INTEGER`},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeIdentifiers(tt.sql, tt.dialect); got != tt.want {
+			t.Errorf("SanitizeIdentifiers(%q, %s) = %q, want %q", tt.sql, tt.dialect, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeIdentifiersLeavesKeywordsAlone(t *testing.T) {
+	queries := []string{
+		"SELECT id, name FROM items WHERE price > 10 ORDER BY name DESC LIMIT 5",
+		"SELECT CASE WHEN a > 1 THEN 'x' ELSE 'y' END AS label FROM t WHERE CASE WHEN b THEN 1 ELSE 0 END = 1",
+		"SELECT CAST(price AS INTEGER), NULL, CURRENT_TIMESTAMP FROM items",
+		"DROP TABLE IF EXISTS items",
+		"SELECT 1 UNION ALL SELECT 2",
+		"SELECT * FROM a LEFT JOIN b ON a.id = b.a_id WHERE b.id IS NULL AND NOT EXISTS (SELECT 1 FROM c)",
+		"SELECT 'order' FROM items -- order by",
+		"WITH recent AS (SELECT * FROM items) SELECT * FROM recent",
+	}
+
+	for _, query := range queries {
+		if got := SanitizeIdentifiers(query, "sqlite"); got != query {
+			t.Errorf("expected %q unchanged, got %q", query, got)
+		}
+	}
+}
+
+func TestSanitizeIdentifiersRequotesForDialect(t *testing.T) {
+	if got := SanitizeIdentifiers("SELECT `first name` FROM [my table]", "sqlite"); got != `SELECT "first name" FROM "my table"` {
+		t.Errorf("unexpected sqlite result %q", got)
+	}
+	if got := SanitizeIdentifiers("SELECT `a``b` FROM t", "postgresql"); got != `SELECT "a`+"`"+`b" FROM t` {
+		t.Errorf("unexpected postgresql result %q", got)
+	}
+	if got := SanitizeIdentifiers(`SELECT "text" FROM t`, "mysql"); got != `SELECT "text" FROM t` {
+		t.Errorf("expected MySQL strings unchanged, got %q", got)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got := QuoteIdentifier("a`b", "mysql"); got != "`a``b`" {
+		t.Errorf("unexpected MySQL quoting %q", got)
+	}
+	if got := QuoteIdentifier(`say "hi"`, "postgresql"); got != `"say ""hi"""` {
+		t.Errorf("unexpected PostgreSQL quoting %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package sqlvalidator
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Isolation levels a transaction can request
+const (
+	ReadUncommitted = "READ UNCOMMITTED"
+	ReadCommitted   = "READ COMMITTED"
+	RepeatableRead  = "REPEATABLE READ"
+	Serializable    = "SERIALIZABLE"
+)
+
+// Levels of database/sql for each isolation level
+var isolationLevels = map[string]sql.IsolationLevel{
+	ReadUncommitted: sql.LevelReadUncommitted,
+	ReadCommitted:   sql.LevelReadCommitted,
+	RepeatableRead:  sql.LevelRepeatableRead,
+	Serializable:    sql.LevelSerializable,
+}
+
+// IsolationLevel checks that a dialect supports an isolation level given
+// as "REPEATABLE READ", "repeatable_read" or similar. It returns the
+// level's canonical name and its value for sql.TxOptions.
+func IsolationLevel(name string, dialect string) (string, sql.IsolationLevel, error) {
+	canonical := strings.Join(strings.Fields(strings.NewReplacer("_", " ", "-", " ").Replace(strings.ToUpper(name))), " ")
+	level, ok := isolationLevels[canonical]
+	if !ok {
+		return "", sql.LevelDefault, fmt.Errorf("unknown isolation level %q; use READ UNCOMMITTED, READ COMMITTED, REPEATABLE READ or SERIALIZABLE", name)
+	}
+
+	capabilities, ok := Capabilities(dialect)
+	if !ok {
+		return "", sql.LevelDefault, fmt.Errorf("isolation levels are not supported for %s", dialect)
+	}
+	for _, supported := range capabilities.IsolationLevels {
+		if supported == canonical {
+			return canonical, level, nil
+		}
+	}
+	return "", sql.LevelDefault, fmt.Errorf("%s does not support %s; use %s", dialect, canonical, strings.Join(capabilities.IsolationLevels, ", "))
+}
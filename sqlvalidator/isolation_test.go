@@ -0,0 +1,43 @@
+package sqlvalidator
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestIsolationLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		want    string
+		level   sql.IsolationLevel
+	}{
+		{"READ COMMITTED", "postgresql", ReadCommitted, sql.LevelReadCommitted},
+		{"repeatable_read", "mysql", RepeatableRead, sql.LevelRepeatableRead},
+		{" Read  Uncommitted ", "mysql", ReadUncommitted, sql.LevelReadUncommitted},
+		{"serializable", "sqlite", Serializable, sql.LevelSerializable},
+	}
+	for _, tt := range tests {
+		name, level, err := IsolationLevel(tt.name, tt.dialect)
+		if err != nil || name != tt.want || level != tt.level {
+			t.Errorf("IsolationLevel(%q, %s) = %q, %v, %v; want %q, %v", tt.name, tt.dialect, name, level, err, tt.want, tt.level)
+		}
+	}
+}
+
+func TestIsolationLevelUnsupported(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+	}{
+		{"READ UNCOMMITTED", "postgresql"},
+		{"READ COMMITTED", "sqlite"},
+		{"SNAPSHOT", "mysql"},
+		{"SERIALIZABLE", "mock"},
+	}
+	for _, tt := range tests {
+		if _, _, err := IsolationLevel(tt.name, tt.dialect); err == nil {
+			t.Errorf("expected IsolationLevel(%q, %s) to fail", tt.name, tt.dialect)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package sqlvalidator
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+)
+
+// Reserved words of each dialect, one per line, with # starting a comment
+//
+//go:embed keywords/*.txt
+var keywordFiles embed.FS
+
+// Dialects with keyword lists, in the order warnings name them
+var keywordDialects = []string{"mysql", "postgresql", "sqlite"}
+
+// Names of the dialects as their documentation spells them
+var dialectTitles = map[string]string{
+	"mysql":      "MySQL",
+	"postgresql": "PostgreSQL",
+	"sqlite":     "SQLite",
+}
+
+// Reserved words keyed by dialect, loaded from keywordFiles
+var dialectKeywords = make(map[string]map[string]bool)
+
+func init() {
+	for _, dialect := range keywordDialects {
+		data, err := keywordFiles.ReadFile("keywords/" + dialect + ".txt")
+		if err != nil {
+			panic(fmt.Sprintf("missing keyword list for %s: %v", dialect, err))
+		}
+		keywords := make(map[string]bool)
+		for _, line := range strings.Split(string(data), "\n") {
+			if word := strings.TrimSpace(line); word != "" && !strings.HasPrefix(word, "#") {
+				keywords[strings.ToLower(word)] = true
+			}
+		}
+		dialectKeywords[dialect] = keywords
+	}
+}
+
+// ReservedIn returns the dialects that reserve a word, so that it can only
+// name a table or column there when quoted
+func ReservedIn(word string) []string {
+	dialects := []string{}
+	for _, dialect := range keywordDialects {
+		if dialectKeywords[dialect][strings.ToLower(word)] {
+			dialects = append(dialects, dialect)
+		}
+	}
+	return dialects
+}
+
+// createdName is a table, view or column name a statement introduces
+type createdName struct {
+	kind  string
+	name  string
+	table string
+}
+
+// ReservedNameWarnings returns a warning for every table, view or column
+// that sql creates, adds or renames to a word reserved in any supported
+// dialect. Quoting makes such a name work, but every query on another
+// dialect has to quote it too, which is easy to miss when porting.
+func ReservedNameWarnings(sql string, dialect string) []string {
+	warnings := []string{}
+	seen := make(map[string]bool)
+	for _, statement := range splitTokenStatements(withoutComments(Tokenize(sql, dialect))) {
+		for _, created := range createdNames(statement) {
+			dialects := ReservedIn(created.name)
+			if len(dialects) == 0 {
+				continue
+			}
+			titles := make([]string, len(dialects))
+			for i, reservedIn := range dialects {
+				titles[i] = dialectTitles[reservedIn]
+			}
+
+			subject := fmt.Sprintf("%s %s", created.kind, created.name)
+			if created.table != "" {
+				subject += " of " + created.table
+			}
+			message := fmt.Sprintf("%s is a reserved word in %s, where every query must quote it.",
+				strings.ToUpper(subject[:1])+subject[1:], joinList(titles))
+			if !seen[message] {
+				seen[message] = true
+				warnings = append(warnings, message)
+			}
+		}
+	}
+	return warnings
+}
+
+// createdNames returns the names a CREATE TABLE, CREATE VIEW or ALTER TABLE
+// statement introduces
+func createdNames(tokens []Token) []createdName {
+	switch firstKeyword(tokens) {
+	case "create":
+		i := skipWords(tokens, 1, "or", "replace", "temporary", "temp", "global", "local", "unlogged", "materialized", "recursive")
+		if i >= len(tokens) || !(tokens[i].Is("table") || tokens[i].Is("view")) {
+			return nil
+		}
+		kind := strings.ToLower(tokens[i].Text)
+		i, name := unqualifiedName(tokens, skipWords(tokens, i+1, "if", "not", "exists"))
+		if name == "" {
+			return nil
+		}
+		names := []createdName{{kind: kind, name: name}}
+		if kind == "table" && i < len(tokens) && tokens[i].Text == "(" {
+			definitions := tokens[i+1 : closingParen(tokens, i)]
+			for _, definition := range splitTopLevel(definitions, ",") {
+				if len(definition) > 0 && !constraintKeywords[strings.ToLower(definition[0].Text)] {
+					names = append(names, createdName{kind: "column", name: definition[0].Value(), table: name})
+				}
+			}
+		}
+		return names
+
+	case "alter":
+		if len(tokens) < 2 || !tokens[1].Is("table") {
+			return nil
+		}
+		i, table := unqualifiedName(tokens, skipWords(tokens, 2, "if", "exists", "only"))
+		if table == "" {
+			return nil
+		}
+		names := []createdName{}
+		for _, change := range splitTopLevel(tokens[i:], ",") {
+			if created, ok := alteredName(change, table); ok {
+				names = append(names, created)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// alteredName returns the name one change of an ALTER TABLE statement
+// introduces: an added column, a renamed column or a renamed table
+func alteredName(change []Token, table string) (createdName, bool) {
+	if len(change) < 2 {
+		return createdName{}, false
+	}
+	switch {
+	case change[0].Is("add"):
+		i := skipWords(change, 1, "column", "if", "not", "exists")
+		if i < len(change) && !constraintKeywords[strings.ToLower(change[i].Text)] {
+			return createdName{kind: "column", name: change[i].Value(), table: table}, true
+		}
+	case change[0].Is("rename"):
+		// RENAME COLUMN a TO b, or RENAME TO t
+		if to := indexOfTopLevel(change, "to", "as"); to >= 0 && to+1 < len(change) {
+			if to == 1 {
+				_, name := unqualifiedName(change, to+1)
+				return createdName{kind: "table", name: name}, name != ""
+			}
+			if !change[1].Is("index") && !change[1].Is("key") && !change[1].Is("constraint") {
+				return createdName{kind: "column", name: change[to+1].Value(), table: table}, true
+			}
+		}
+	case change[0].Is("change"):
+		// MySQL's CHANGE [COLUMN] old new type
+		i := skipWords(change, 1, "column")
+		if i+1 < len(change) {
+			return createdName{kind: "column", name: change[i+1].Value(), table: table}, true
+		}
+	}
+	return createdName{}, false
+}
+
+// unqualifiedName returns the name at tokens[i] without its schema, and
+// the index of the token after it
+func unqualifiedName(tokens []Token, i int) (int, string) {
+	if i >= len(tokens) || (tokens[i].Kind != TokenWord && tokens[i].Kind != TokenQuotedIdentifier) {
+		return i, ""
+	}
+	name := tokens[i].Value()
+	i++
+	for i+1 < len(tokens) && tokens[i].Text == "." {
+		name = tokens[i+1].Value()
+		i += 2
+	}
+	return i, name
+}
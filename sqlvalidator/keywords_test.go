@@ -0,0 +1,70 @@
+package sqlvalidator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReservedIn(t *testing.T) {
+	tests := map[string][]string{
+		"order":  {"mysql", "postgresql", "sqlite"},
+		"USER":   {"postgresql"},
+		"rank":   {"mysql"},
+		"index":  {"mysql", "sqlite"},
+		"name":   {},
+		"status": {},
+	}
+	for word, want := range tests {
+		if got := ReservedIn(word); !reflect.DeepEqual(got, want) {
+			t.Errorf("ReservedIn(%q) = %v, want %v", word, got, want)
+		}
+	}
+}
+
+func TestReservedNameWarnings(t *testing.T) {
+	tests := []struct {
+		sql     string
+		dialect string
+		want    []string
+	}{
+		{
+			sql:     `CREATE TABLE IF NOT EXISTS shop.purchases (id INTEGER PRIMARY KEY, "order" TEXT, rank INT, CONSTRAINT pk UNIQUE (rank))`,
+			dialect: "postgresql",
+			want: []string{
+				"Column order of purchases is a reserved word in MySQL, PostgreSQL and SQLite, where every query must quote it.",
+				"Column rank of purchases is a reserved word in MySQL, where every query must quote it.",
+			},
+		},
+		{
+			sql:     "CREATE TABLE `user` (id INT); CREATE VIEW `window` AS SELECT 1",
+			dialect: "mysql",
+			want: []string{
+				"Table user is a reserved word in PostgreSQL, where every query must quote it.",
+				"View window is a reserved word in MySQL and PostgreSQL, where every query must quote it.",
+			},
+		},
+		{
+			sql:     "ALTER TABLE items ADD COLUMN `group` INT, RENAME COLUMN price TO `limit`, ADD CONSTRAINT c CHECK (id > 0)",
+			dialect: "mysql",
+			want: []string{
+				"Column group of items is a reserved word in MySQL, PostgreSQL and SQLite, where every query must quote it.",
+				"Column limit of items is a reserved word in MySQL, PostgreSQL and SQLite, where every query must quote it.",
+			},
+		},
+		{
+			sql:     `ALTER TABLE items RENAME TO "select"`,
+			dialect: "sqlite",
+			want:    []string{"Table select is a reserved word in MySQL, PostgreSQL and SQLite, where every query must quote it."},
+		},
+		{
+			sql:     "SELECT `order` FROM items; CREATE TABLE notes (id INT, body TEXT) -- CREATE TABLE `order`",
+			dialect: "mysql",
+			want:    []string{},
+		},
+	}
+	for _, tt := range tests {
+		if got := ReservedNameWarnings(tt.sql, tt.dialect); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ReservedNameWarnings(%q) =\n%q\nwant\n%q", tt.sql, got, tt.want)
+		}
+	}
+}
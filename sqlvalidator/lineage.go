@@ -0,0 +1,634 @@
+package sqlvalidator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of output columns in a lineage
+const (
+	// The column passes a source column through, possibly renamed
+	LineageColumn = "column"
+	// The column is computed from its sources row by row
+	LineageExpression = "expression"
+	// The column aggregates its sources over groups of rows
+	LineageAggregate = "aggregate"
+	// The column reads no source column
+	LineageConstant = "constant"
+)
+
+// Words inside expressions that never name a column
+var lineageKeywords = words("case when then else end and or not null is in like ilike between distinct " +
+	"as true false interval cast over partition by order asc desc nulls within group rows range groups " +
+	"preceding following unbounded current row exists any all some collate escape similar at zone")
+
+// Keywords that may end an expression, so a name after them is an alias
+var lineageEndKeywords = words("end null true false")
+
+// ColumnSource is a column of a table, view or table function that an
+// output column reads. Table functions are named with () after them.
+type ColumnSource struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// ColumnLineage describes where an output column of a query comes from
+type ColumnLineage struct {
+	Name string `json:"name"`
+	// SQL of the select list item, or * for columns a star expands to
+	Expression string `json:"expression"`
+	// LineageColumn, LineageExpression, LineageAggregate or LineageConstant
+	Kind    string         `json:"kind"`
+	Sources []ColumnSource `json:"sources"`
+	// CTEs and derived tables the column passes through, outermost first
+	Via []string `json:"via,omitempty"`
+	// Names matching no column of the query's sources, or several of them
+	Unresolved []string `json:"unresolved,omitempty"`
+}
+
+// relation is a table, CTE, derived table or table function in scope of a
+// SELECT
+type relation struct {
+	name  string
+	alias string
+	// Columns of a table when the schema knows it, nil otherwise
+	columns []string
+	// Output columns of a CTE or derived table
+	outputs []ColumnLineage
+	derived bool
+	// Table functions such as generate_series(...)
+	function bool
+}
+
+// columnRef is a column named in an expression, with the table or alias
+// qualifying it
+type columnRef struct {
+	qualifier string
+	column    string
+}
+
+// lineageAnalyzer traces the output columns of the queries of one SQL text
+type lineageAnalyzer struct {
+	sql     string
+	dialect string
+	schema  Schema
+}
+
+// Lineage reports, for each output column of a single SELECT, WITH or
+// VALUES query, the table columns it derives from, following aliases,
+// expressions, derived tables, CTEs and set operations. The schema expands
+// stars and settles which table an unqualified column belongs to; without
+// it, such columns are attributed to the only table they can come from.
+func Lineage(sql string, dialect string, schema Schema) ([]ColumnLineage, error) {
+	statements := splitTokenStatements(withoutComments(Tokenize(sql, dialect)))
+	if len(statements) == 0 {
+		return nil, errors.New("there is no query to trace")
+	}
+	if len(statements) > 1 {
+		return nil, errors.New("lineage is traced for a single query")
+	}
+	tokens := statements[0]
+	kind := firstKeyword(tokens)
+	if kind == "with" {
+		if clause := parseWithClause(tokens); clause != nil {
+			kind = clause.MainKind
+		}
+	}
+	if kind != "select" && kind != "values" {
+		return nil, fmt.Errorf("lineage is traced for SELECT queries, not %s statements", strings.ToUpper(kind))
+	}
+
+	a := lineageAnalyzer{sql: sql, dialect: dialect, schema: schema}
+	return a.query(tokens, map[string]*relation{}, nil), nil
+}
+
+// query traces a query with its WITH clause and set operations, given the
+// CTEs in scope and the relations of enclosing queries
+func (a lineageAnalyzer) query(tokens []Token, ctes map[string]*relation, outer []*relation) []ColumnLineage {
+	for len(tokens) > 1 && tokens[0].Text == "(" && closingParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	if len(tokens) > 0 && tokens[0].Is("with") {
+		clause := parseWithClause(tokens)
+		if clause == nil {
+			return []ColumnLineage{}
+		}
+		scoped := make(map[string]*relation, len(ctes)+len(clause.Expressions))
+		for name, cte := range ctes {
+			scoped[name] = cte
+		}
+		for _, expression := range clause.Expressions {
+			cte := &relation{name: expression.Name, derived: true}
+			if expression.Recursive {
+				// The recursive part reads the rows of the anchor part
+				parts, _ := splitSetOperations(expression.body)
+				cte.outputs = a.named(a.query(parts[0], scoped, outer), expression.columns)
+				scoped[strings.ToLower(expression.Name)] = cte
+			}
+			cte.outputs = a.named(a.query(expression.body, scoped, outer), expression.columns)
+			scoped[strings.ToLower(expression.Name)] = cte
+		}
+		return a.query(clause.main, scoped, outer)
+	}
+
+	parts, _ := splitSetOperations(tokens)
+	last := parts[len(parts)-1]
+	if cut := indexOfTopLevel(last, "order", "limit", "offset", "fetch"); cut > 0 && len(parts) > 1 {
+		parts[len(parts)-1] = last[:cut]
+	}
+	columns := a.selectQuery(parts[0], ctes, outer)
+	for _, part := range parts[1:] {
+		// Set operations take their names from the first query and their
+		// values from every one
+		for i, column := range a.selectQuery(part, ctes, outer) {
+			if i >= len(columns) {
+				break
+			}
+			merged := &columns[i]
+			if merged.Kind != column.Kind {
+				merged.Kind = LineageExpression
+			}
+			merged.Sources = appendSources(merged.Sources, column.Sources...)
+			merged.Via = appendNames(merged.Via, column.Via...)
+			merged.Unresolved = appendNames(merged.Unresolved, column.Unresolved...)
+		}
+	}
+	return columns
+}
+
+// named renames the output columns of a CTE to the names of its column list
+func (a lineageAnalyzer) named(columns []ColumnLineage, names []string) []ColumnLineage {
+	for i := range columns {
+		if i < len(names) {
+			columns[i].Name = names[i]
+		}
+	}
+	return columns
+}
+
+// selectQuery traces the select list of a single SELECT or VALUES query
+func (a lineageAnalyzer) selectQuery(tokens []Token, ctes map[string]*relation, outer []*relation) []ColumnLineage {
+	for len(tokens) > 1 && tokens[0].Text == "(" && closingParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+	columns := []ColumnLineage{}
+	if len(tokens) > 0 && tokens[0].Is("values") {
+		rows := splitTopLevel(tokens[1:], ",")
+		if len(rows) == 0 || len(rows[0]) < 2 {
+			return columns
+		}
+		for i := range splitTopLevel(rows[0][1:len(rows[0])-1], ",") {
+			columns = append(columns, ColumnLineage{
+				Name:       fmt.Sprintf("column%d", i+1),
+				Expression: "VALUES",
+				Kind:       LineageConstant,
+				Sources:    []ColumnSource{},
+			})
+		}
+		return columns
+	}
+
+	clauses := splitClauses(tokens, selectClauseKeywords)
+	scope := a.scope(clauses["from"], ctes, outer)
+	list := clauses["select"]
+	if len(list) > 0 && (list[0].Is("distinct") || list[0].Is("all")) {
+		list = list[1:]
+		// PostgreSQL's DISTINCT ON (...)
+		if len(list) > 1 && list[0].Is("on") && list[1].Text == "(" {
+			list = list[closingParen(list, 1)+1:]
+		}
+	}
+
+	for _, item := range splitTopLevel(list, ",") {
+		switch {
+		case len(item) == 0:
+		case len(item) == 1 && item[0].Text == "*":
+			for _, rel := range scope {
+				columns = append(columns, a.expand(rel)...)
+			}
+		case len(item) >= 3 && item[len(item)-1].Text == "*" && item[len(item)-2].Text == ".":
+			if rel := findRelation(scope, item[len(item)-3].Value()); rel != nil {
+				columns = append(columns, a.expand(rel)...)
+			}
+		default:
+			columns = append(columns, a.item(item, scope, ctes, outer))
+		}
+	}
+	return columns
+}
+
+// scope returns the relations of a FROM clause, in order
+func (a lineageAnalyzer) scope(from []Token, ctes map[string]*relation, outer []*relation) []*relation {
+	relations := []*relation{}
+	if len(from) == 0 {
+		return relations
+	}
+	for _, segment := range splitJoins(from) {
+		_, source, _, _ := parseJoinSegment(segment)
+		// LATERAL derived tables see the relations before them
+		visible := append(append([]*relation{}, relations...), outer...)
+		if rel := a.relation(source, ctes, visible); rel != nil {
+			relations = append(relations, rel)
+		}
+	}
+	return relations
+}
+
+// relation reads the table, CTE, derived table or table function of a
+// FROM clause source
+func (a lineageAnalyzer) relation(tokens []Token, ctes map[string]*relation, outer []*relation) *relation {
+	for len(tokens) > 0 && (tokens[0].Is("lateral") || tokens[0].Is("only")) {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	// alias reads "[AS] alias" at i
+	alias := func(i int) string {
+		if i < len(tokens) && tokens[i].Is("as") {
+			i++
+		}
+		if i < len(tokens) && (tokens[i].Kind == TokenQuotedIdentifier ||
+			tokens[i].Kind == TokenWord && !clauseKeywords[strings.ToLower(tokens[i].Text)]) {
+			return tokens[i].Value()
+		}
+		return ""
+	}
+
+	if tokens[0].Text == "(" {
+		end := closingParen(tokens, 0)
+		inner := tokens[1:end]
+		if kind := firstKeyword(inner); kind != "select" && kind != "with" && kind != "values" {
+			return nil
+		}
+		name := alias(end + 1)
+		return &relation{name: name, alias: name, outputs: a.query(inner, ctes, outer), derived: true}
+	}
+	if tokens[0].Kind != TokenWord && tokens[0].Kind != TokenQuotedIdentifier {
+		return nil
+	}
+
+	name := tokens[0].Value()
+	qualified := false
+	next := 1
+	for next+1 < len(tokens) && tokens[next].Text == "." {
+		name = tokens[next+1].Value()
+		qualified = true
+		next += 2
+	}
+	if next < len(tokens) && tokens[next].Text == "(" {
+		end := closingParen(tokens, next)
+		return &relation{name: name + "()", alias: alias(end + 1), function: true}
+	}
+
+	rel := &relation{name: name, alias: alias(next)}
+	if cte, ok := ctes[strings.ToLower(name)]; ok && !qualified {
+		rel.outputs = cte.outputs
+		rel.derived = true
+		return rel
+	}
+	if columns, ok := a.schema.lookup(name); ok {
+		rel.columns = columns
+	}
+	return rel
+}
+
+// expand returns the columns a star expands to for a relation
+func (a lineageAnalyzer) expand(rel *relation) []ColumnLineage {
+	columns := []ColumnLineage{}
+	switch {
+	case rel.derived:
+		for _, output := range rel.outputs {
+			output.Via = append([]string{rel.label()}, output.Via...)
+			output.Expression = "*"
+			columns = append(columns, output)
+		}
+	case rel.columns != nil:
+		for _, column := range rel.columns {
+			columns = append(columns, ColumnLineage{
+				Name:       column,
+				Expression: "*",
+				Kind:       LineageColumn,
+				Sources:    []ColumnSource{{Table: rel.name, Column: column}},
+			})
+		}
+	default:
+		// Without the schema, the star stands for every column
+		columns = append(columns, ColumnLineage{
+			Name:       "*",
+			Expression: "*",
+			Kind:       LineageColumn,
+			Sources:    []ColumnSource{{Table: rel.name, Column: "*"}},
+		})
+	}
+	return columns
+}
+
+// item traces one expression of a select list
+func (a lineageAnalyzer) item(item []Token, scope []*relation, ctes map[string]*relation, outer []*relation) ColumnLineage {
+	expression, name := splitItemAlias(item)
+	column := ColumnLineage{
+		Name:       name,
+		Expression: a.text(expression),
+		Kind:       LineageExpression,
+		Sources:    []ColumnSource{},
+	}
+
+	refs, subqueries := a.references(expression, scope, ctes, outer)
+	refKind := LineageColumn
+	for _, ref := range refs {
+		sources, via, kind, ok := resolveColumn(ref, scope, outer)
+		if !ok {
+			unresolved := ref.column
+			if ref.qualifier != "" {
+				unresolved = ref.qualifier + "." + ref.column
+			}
+			column.Unresolved = appendNames(column.Unresolved, unresolved)
+			continue
+		}
+		column.Sources = appendSources(column.Sources, sources...)
+		column.Via = appendNames(column.Via, via...)
+		refKind = kind
+	}
+	for _, subquery := range subqueries {
+		column.Sources = appendSources(column.Sources, subquery.Sources...)
+		column.Via = appendNames(column.Via, subquery.Via...)
+		column.Unresolved = appendNames(column.Unresolved, subquery.Unresolved...)
+	}
+
+	bare := len(expression) == 1 || len(expression) == 3 && expression[1].Text == "."
+	switch {
+	case bare && len(refs) == 1:
+		// A column of a derived table keeps the kind it has there
+		column.Kind = refKind
+		if column.Name == "" {
+			column.Name = refs[0].column
+		}
+	case len(aggregateCalls(explainer{sql: a.sql}, expression)) > 0:
+		column.Kind = LineageAggregate
+	case len(refs) == 0 && len(subqueries) == 0:
+		column.Kind = LineageConstant
+	}
+	if column.Name == "" {
+		column.Name = column.Expression
+	}
+	return column
+}
+
+// references returns the columns an expression names and the lineage of
+// the first column of each subquery in it
+func (a lineageAnalyzer) references(tokens []Token, scope []*relation, ctes map[string]*relation, outer []*relation) ([]columnRef, []ColumnLineage) {
+	refs := []columnRef{}
+	subqueries := []ColumnLineage{}
+	enclosing := append(append([]*relation{}, scope...), outer...)
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Text == "(" && i+1 < len(tokens) && (tokens[i+1].Is("select") || tokens[i+1].Is("with")) {
+			end := closingParen(tokens, i)
+			if columns := a.query(tokens[i+1:end], ctes, enclosing); len(columns) > 0 {
+				subqueries = append(subqueries, columns[0])
+			}
+			i = end
+			continue
+		}
+		if token.Kind != TokenWord && token.Kind != TokenQuotedIdentifier {
+			continue
+		}
+		if i > 0 && (tokens[i-1].Is("as") || tokens[i-1].Text == "::") {
+			// Types of CAST(... AS type) and PostgreSQL's ::type
+			continue
+		}
+		if token.Kind == TokenWord {
+			lower := strings.ToLower(token.Text)
+			if lineageKeywords[lower] || reservedWords[a.dialect][lower] || strings.HasPrefix(token.Text, "@") {
+				continue
+			}
+			// The field of EXTRACT(field FROM ...), typed literals such as
+			// DATE '2024-01-01', ARRAY[...], NULLS FIRST and AT TIME ZONE
+			if i > 1 && tokens[i-1].Text == "(" && tokens[i-2].Is("extract") ||
+				i+1 < len(tokens) && (tokens[i+1].Kind == TokenString || tokens[i+1].Text == "[" || tokens[i+1].Is("zone")) ||
+				i > 0 && tokens[i-1].Is("nulls") {
+				continue
+			}
+		}
+		if i+1 < len(tokens) && tokens[i+1].Text == "(" {
+			// Function calls
+			continue
+		}
+
+		// table.column, or schema.table.column
+		parts := []string{token.Value()}
+		for i+2 < len(tokens) && tokens[i+1].Text == "." &&
+			(tokens[i+2].Kind == TokenWord || tokens[i+2].Kind == TokenQuotedIdentifier) {
+			parts = append(parts, tokens[i+2].Value())
+			i += 2
+		}
+		ref := columnRef{column: parts[len(parts)-1]}
+		if len(parts) > 1 {
+			ref.qualifier = parts[len(parts)-2]
+		}
+		refs = append(refs, ref)
+	}
+	return refs, subqueries
+}
+
+// resolveColumn finds the source columns of a column reference, looking in
+// the query's own relations before those of enclosing queries. It returns
+// the sources, the derived tables passed through, the kind of the column
+// read and whether the reference was resolved.
+func resolveColumn(ref columnRef, scope []*relation, outer []*relation) ([]ColumnSource, []string, string, bool) {
+	if ref.qualifier != "" {
+		rel := findRelation(scope, ref.qualifier)
+		if rel == nil {
+			rel = findRelation(outer, ref.qualifier)
+		}
+		if rel == nil {
+			return nil, nil, "", false
+		}
+		return rel.column(ref.column)
+	}
+
+	for _, relations := range [][]*relation{scope, outer} {
+		var matches []*relation
+		var unknown []*relation
+		for _, rel := range relations {
+			switch {
+			case rel.has(ref.column):
+				matches = append(matches, rel)
+			case rel.open():
+				unknown = append(unknown, rel)
+			}
+		}
+		switch {
+		case len(matches) == 1:
+			return matches[0].column(ref.column)
+		case len(matches) > 1:
+			return nil, nil, "", false
+		case len(unknown) == 1:
+			// The only relation whose columns are unknown
+			return unknown[0].column(ref.column)
+		case len(unknown) > 1:
+			return nil, nil, "", false
+		}
+	}
+	return nil, nil, "", false
+}
+
+// findRelation returns the relation an alias or table name refers to
+func findRelation(relations []*relation, name string) *relation {
+	for _, rel := range relations {
+		if rel.alias != "" && strings.EqualFold(rel.alias, name) {
+			return rel
+		}
+	}
+	for _, rel := range relations {
+		if rel.alias == "" && strings.EqualFold(strings.TrimSuffix(rel.name, "()"), name) {
+			return rel
+		}
+	}
+	return nil
+}
+
+// label names a relation in the via list of the columns read through it
+func (r *relation) label() string {
+	if r.name != "" {
+		return r.name
+	}
+	return "subquery"
+}
+
+// open reports whether a relation may have columns that are not known: a
+// table missing from the schema, or a derived table selecting * from one
+func (r *relation) open() bool {
+	if !r.derived {
+		return r.columns == nil
+	}
+	for _, output := range r.outputs {
+		if output.Name == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// has reports whether a relation is known to have a column
+func (r *relation) has(name string) bool {
+	if r.derived {
+		for _, output := range r.outputs {
+			if strings.EqualFold(output.Name, name) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, column := range r.columns {
+		if strings.EqualFold(column, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// column returns the sources, the derived tables passed through and the
+// kind of a column of the relation
+func (r *relation) column(name string) ([]ColumnSource, []string, string, bool) {
+	if !r.derived {
+		if r.columns != nil && !r.has(name) {
+			return nil, nil, "", false
+		}
+		for _, column := range r.columns {
+			if strings.EqualFold(column, name) {
+				name = column
+			}
+		}
+		return []ColumnSource{{Table: r.name, Column: name}}, nil, LineageColumn, true
+	}
+	for _, output := range r.outputs {
+		if strings.EqualFold(output.Name, name) {
+			return output.Sources, append([]string{r.label()}, output.Via...), output.Kind, true
+		}
+	}
+	for _, output := range r.outputs {
+		if output.Name == "*" {
+			// A column of the tables the unexpanded star stands for
+			sources := make([]ColumnSource, len(output.Sources))
+			for i, source := range output.Sources {
+				sources[i] = ColumnSource{Table: source.Table, Column: name}
+			}
+			return sources, append([]string{r.label()}, output.Via...), LineageColumn, true
+		}
+	}
+	return nil, nil, "", false
+}
+
+// splitItemAlias splits a select list item into its expression and its
+// alias, given with AS or directly after the expression
+func splitItemAlias(item []Token) ([]Token, string) {
+	n := len(item)
+	if n > 2 && item[n-2].Is("as") {
+		return item[:n-2], item[n-1].Value()
+	}
+	if n > 1 {
+		last, previous := item[n-1], item[n-2]
+		endsExpression := previous.Kind == TokenQuotedIdentifier || previous.Kind == TokenNumber ||
+			previous.Kind == TokenString || previous.Text == ")" ||
+			previous.Kind == TokenWord && (!lineageKeywords[strings.ToLower(previous.Text)] || lineageEndKeywords[strings.ToLower(previous.Text)])
+		isName := last.Kind == TokenQuotedIdentifier ||
+			last.Kind == TokenWord && !lineageKeywords[strings.ToLower(last.Text)] && !clauseKeywords[strings.ToLower(last.Text)]
+		if endsExpression && isName {
+			return item[:n-1], last.Value()
+		}
+	}
+	return item, ""
+}
+
+// text returns the SQL text of tokens with whitespace collapsed
+func (a lineageAnalyzer) text(tokens []Token) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	last := tokens[len(tokens)-1]
+	return strings.Join(strings.Fields(a.sql[tokens[0].Offset:last.Offset+len(last.Text)]), " ")
+}
+
+// appendSources adds sources that are not listed yet, copying the list so
+// lists shared between columns stay untouched
+func appendSources(sources []ColumnSource, added ...ColumnSource) []ColumnSource {
+	sources = append([]ColumnSource{}, sources...)
+	for _, source := range added {
+		found := false
+		for _, existing := range sources {
+			if strings.EqualFold(existing.Table, source.Table) && strings.EqualFold(existing.Column, source.Column) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// appendNames adds names that are not listed yet, copying the list
+func appendNames(names []string, added ...string) []string {
+	if len(added) == 0 {
+		return names
+	}
+	names = append([]string{}, names...)
+	for _, name := range added {
+		found := false
+		for _, existing := range names {
+			if existing == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			names = append(names, name)
+		}
+	}
+	return names
+}
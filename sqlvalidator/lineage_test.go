@@ -0,0 +1,139 @@
+package sqlvalidator
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describeLineage renders a lineage as "name kind table.column,..." lines
+func describeLineage(columns []ColumnLineage) string {
+	lines := make([]string, len(columns))
+	for i, column := range columns {
+		sources := make([]string, len(column.Sources))
+		for j, source := range column.Sources {
+			sources[j] = source.Table + "." + source.Column
+		}
+		lines[i] = fmt.Sprintf("%s %s %s", column.Name, column.Kind, strings.Join(sources, ","))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLineage(t *testing.T) {
+	schema := Schema{
+		"users":  {"id", "name", "email"},
+		"orders": {"id", "user_id", "total", "created_at"},
+	}
+	tests := []struct {
+		name string
+		sql  string
+		want []string
+	}{
+		{
+			"aliases and expressions",
+			"SELECT u.name AS customer, o.total * 1.2 gross, upper(email), 'x' AS tag FROM users u JOIN orders o ON o.user_id = u.id",
+			[]string{"customer column users.name", "gross expression orders.total", "upper(email) expression users.email", "tag constant "},
+		},
+		{
+			"aggregates",
+			"SELECT user_id, sum(total) AS spent, count(*) AS n FROM orders GROUP BY user_id",
+			[]string{"user_id column orders.user_id", "spent aggregate orders.total", "n aggregate "},
+		},
+		{
+			"CTE with a column list",
+			"WITH spend(customer, amount) AS (SELECT user_id, sum(total) FROM orders GROUP BY user_id) SELECT s.customer, amount AS spent FROM spend s",
+			[]string{"customer column orders.user_id", "spent aggregate orders.total"},
+		},
+		{
+			"derived table",
+			"SELECT big.id FROM (SELECT id FROM orders WHERE total > 100) big",
+			[]string{"id column orders.id"},
+		},
+		{
+			"union",
+			"SELECT name FROM users UNION SELECT email FROM users ORDER BY 1",
+			[]string{"name column users.name,users.email"},
+		},
+		{
+			"stars",
+			"SELECT u.*, o.total FROM users u, orders o",
+			[]string{"id column users.id", "name column users.name", "email column users.email", "total column orders.total"},
+		},
+		{
+			"scalar subquery",
+			"SELECT name, (SELECT max(created_at) FROM orders o WHERE o.user_id = u.id) AS last_order FROM users u",
+			[]string{"name column users.name", "last_order aggregate orders.created_at"},
+		},
+		{
+			"typed literals and casts",
+			"SELECT CAST(created_at AS date) AS day, created_at > DATE '2024-01-01' AS recent FROM orders",
+			[]string{"day expression orders.created_at", "recent expression orders.created_at"},
+		},
+		{
+			"values",
+			"VALUES (1, 'a'), (2, 'b')",
+			[]string{"column1 constant ", "column2 constant "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := Lineage(tt.sql, "postgresql", schema)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := describeLineage(columns), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("Lineage() =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestLineageVia(t *testing.T) {
+	columns, err := Lineage("WITH recent AS (SELECT * FROM orders) SELECT t.total FROM (SELECT total FROM recent) t", "sqlite", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(columns) != 1 || strings.Join(columns[0].Via, ",") != "t,recent" {
+		t.Fatalf("expected the column to pass through t and recent, got %+v", columns)
+	}
+	if got := describeLineage(columns); got != "total column orders.total" {
+		t.Errorf("Lineage() = %s", got)
+	}
+}
+
+func TestLineageUnresolved(t *testing.T) {
+	columns, err := Lineage("SELECT id, nickname FROM users, orders", "mysql", Schema{
+		"users":  {"id", "name"},
+		"orders": {"id", "total"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(columns) != 2 {
+		t.Fatalf("expected 2 columns, got %+v", columns)
+	}
+	for _, column := range columns {
+		if len(column.Sources) != 0 || len(column.Unresolved) != 1 {
+			t.Errorf("expected %s to be unresolved, got %+v", column.Name, column)
+		}
+	}
+
+	// Without a schema, a column of the only table is that table's
+	columns, err = Lineage("SELECT nickname FROM users", "mysql", nil)
+	if err != nil || describeLineage(columns) != "nickname column users.nickname" {
+		t.Errorf("unexpected lineage %+v (%v)", columns, err)
+	}
+}
+
+func TestLineageRejectsOtherStatements(t *testing.T) {
+	for _, sql := range []string{
+		"",
+		"UPDATE users SET name = 'x'",
+		"WITH old AS (SELECT id FROM users) DELETE FROM users WHERE id IN (SELECT id FROM old)",
+		"SELECT 1; SELECT 2",
+	} {
+		if _, err := Lineage(sql, "postgresql", nil); err == nil {
+			t.Errorf("expected an error for %q", sql)
+		}
+	}
+}
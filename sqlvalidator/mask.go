@@ -0,0 +1,169 @@
+package sqlvalidator
+
+import (
+	"strings"
+)
+
+// Words after which the strings of a statement run as SQL: dynamic SQL
+// in routine bodies and prepared statements, and functions that run the
+// query they are given
+var dynamicSQLWords = words("execute prepare immediate query_to_xml query_to_xmlschema " +
+	"query_to_xml_and_xmlschema dblink dblink_exec dblink_open dblink_send_query")
+
+// MaskSQL blanks out what a database never runs as SQL, so pattern checks
+// see only the statements themselves: comments become spaces, and so do
+// the contents of string literals, between their quotes. Quotes, escapes
+// and comments follow the dialect's rules, and offsets and line breaks are
+// kept. Quoted identifiers are left as written, and so are the bodies of
+// PostgreSQL dollar-quoted strings and MySQL's /*! */ comments, which
+// the databases run, and strings of a statement that runs them as SQL,
+// such as one with EXECUTE or PREPARE.
+func MaskSQL(sql string, dialect string) string {
+	masked := []byte(sql)
+	maskRange(masked, sql, 0, dialect, false)
+	return string(masked)
+}
+
+// stripComments blanks out the comments of sql as MaskSQL does, keeping
+// string literals whole
+func stripComments(sql string, dialect string) string {
+	masked := []byte(sql)
+	maskRange(masked, sql, 0, dialect, true)
+	return string(masked)
+}
+
+// maskRange masks sql from start to its end into masked, leaving string
+// literals alone when keepStrings is set
+func maskRange(masked []byte, sql string, start int, dialect string, keepStrings bool) {
+	dynamic := false
+	for i := start; i < len(sql); {
+		ch := sql[i]
+		switch {
+		case ch == '-' && strings.HasPrefix(sql[i:], "--") && (dialect != "mysql" || i+2 == len(sql) || strings.IndexByte(" \t\r\n", sql[i+2]) >= 0),
+			ch == '#' && dialect == "mysql":
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			blank(masked, i, i+end)
+			i += end
+
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*!") && dialect == "mysql":
+			// MySQL runs the contents of /*! */ comments, after an optional
+			// minimum server version
+			end := strings.Index(sql[i:], "*/")
+			if end < 0 {
+				end = len(sql) - i
+			}
+			body := i + 3
+			for body < i+end && isDigit(sql[body]) {
+				body++
+			}
+			blank(masked, i, body)
+			maskRange(masked, sql[:i+end], body, dialect, keepStrings)
+			blank(masked, i+end, min(i+end+2, len(sql)))
+			i = min(i+end+2, len(sql))
+
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := blockCommentEnd(sql, i, dialect == "postgresql")
+			blank(masked, i, end)
+			i = end
+
+		case ch == '\'' || ch == '"' && dialect == "mysql":
+			backslash := dialect == "mysql" ||
+				dialect == "postgresql" && i > 0 && (sql[i-1] == 'e' || sql[i-1] == 'E') && (i == 1 || !isWordChar(sql[i-2]))
+			end := scanQuoted(sql, i, ch, backslash)
+			if !dynamic && !keepStrings {
+				closing := end
+				if end-1 > i && sql[end-1] == ch {
+					closing = end - 1
+				}
+				blank(masked, i+1, closing)
+			}
+			i = end
+
+		case ch == '"' || ch == '`' || (ch == '[' && dialect == "sqlite"):
+			closing := ch
+			if ch == '[' {
+				closing = ']'
+			}
+			i = scanQuoted(sql, i, closing, false)
+
+		case ch == '$' && dialect == "postgresql" && (i == 0 || !isWordChar(sql[i-1])):
+			tag := dollarTag(sql, i)
+			if tag == "" {
+				i++
+				continue
+			}
+			body := i + len(tag)
+			end := strings.Index(sql[body:], tag)
+			if end < 0 {
+				end = len(sql) - body
+			}
+			maskRange(masked, sql[:body+end], body, dialect, keepStrings)
+			i = min(body+end+len(tag), len(sql))
+
+		case isWordChar(ch) || ch >= 0x80:
+			end := i + 1
+			for end < len(sql) && (isWordChar(sql[end]) || sql[end] >= 0x80 || sql[end] == '$') {
+				end++
+			}
+			if dynamicSQLWords[strings.ToLower(sql[i:end])] {
+				dynamic = true
+			}
+			i = end
+
+		case ch == ';':
+			dynamic = false
+			i++
+
+		default:
+			i++
+		}
+	}
+}
+
+// blank replaces masked[from:to] with spaces, keeping line breaks
+func blank(masked []byte, from int, to int) {
+	for i := from; i < to; i++ {
+		if masked[i] != '\n' {
+			masked[i] = ' '
+		}
+	}
+}
+
+// blockCommentEnd returns the offset just past the /* */ comment starting
+// at start, or the end of sql if it is not closed. PostgreSQL nests block
+// comments.
+func blockCommentEnd(sql string, start int, nested bool) int {
+	depth := 0
+	for i := start; i+1 < len(sql); i++ {
+		switch {
+		case sql[i] == '/' && sql[i+1] == '*' && (depth == 0 || nested):
+			depth++
+			i++
+		case sql[i] == '*' && sql[i+1] == '/':
+			if depth--; depth == 0 {
+				return i + 2
+			}
+			i++
+		}
+	}
+	return len(sql)
+}
+
+// dollarTag returns the tag of the PostgreSQL dollar-quoted string starting
+// at start, such as $$ or $body$, or "" when the $ starts none, as in the
+// parameter $1
+func dollarTag(sql string, start int) string {
+	i := start + 1
+	if i < len(sql) && !isDigit(sql[i]) {
+		for i < len(sql) && (isWordChar(sql[i]) || sql[i] >= 0x80) {
+			i++
+		}
+	}
+	if i >= len(sql) || sql[i] != '$' {
+		return ""
+	}
+	return sql[start : i+1]
+}
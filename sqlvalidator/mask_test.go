@@ -0,0 +1,120 @@
+package sqlvalidator
+
+import (
+	"strings"
+	"testing"
+)
+
+// squeezeSpaces collapses runs of spaces, so expectations need not count
+// the spaces masking leaves
+func squeezeSpaces(s string) string {
+	for strings.Contains(s, "  ") {
+		s = strings.ReplaceAll(s, "  ", " ")
+	}
+	return s
+}
+
+func TestMaskSQL(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		sql     string
+		want    string
+	}{
+		{"line comment", "sqlite", "SELECT 1 -- drop table x\nFROM t", "SELECT 1 \nFROM t"},
+		{"block comment", "postgresql", "DROP/**/TABLE t", "DROP TABLE t"},
+		{"multiline comment", "sqlite", "SELECT /* a\nb */ 1", "SELECT \n 1"},
+		{"string", "sqlite", "SELECT 'drop table x'", "SELECT ' '"},
+		{"doubled quote", "sqlite", "SELECT 'it''s -- not a comment'; DROP TABLE t", "SELECT ' '; DROP TABLE t"},
+		{"quoted identifiers kept", "postgresql", `SELECT "drop table" FROM t`, `SELECT "drop table" FROM t`},
+		{"backticks kept", "mysql", "SELECT `drop table` FROM t", "SELECT `drop table` FROM t"},
+		{"sqlite brackets kept", "sqlite", "SELECT [a -- b] FROM t", "SELECT [a -- b] FROM t"},
+		{"mysql double quoted string", "mysql", `SELECT "drop table"`, `SELECT " "`},
+		{"mysql backslash escape", "mysql", `SELECT 'a\' ; DROP TABLE t -- '`, `SELECT ' '`},
+		{"standard strings end at the quote", "postgresql", `SELECT 'a\'; DROP TABLE t; -- '`, `SELECT ' '; DROP TABLE t; `},
+		{"sqlite keeps backslashes", "sqlite", `SELECT 'a\'; DROP TABLE t`, `SELECT ' '; DROP TABLE t`},
+		{"postgresql escape string", "postgresql", `SELECT E'a\'; DROP TABLE t'`, `SELECT E' '`},
+		{"mysql hash comment", "mysql", "SELECT 1 # drop table\n", "SELECT 1 \n"},
+		{"hash outside mysql", "postgresql", "SELECT 1 # 2", "SELECT 1 # 2"},
+		{"mysql dashes need a space", "mysql", "SELECT 1--1; DROP TABLE t", "SELECT 1--1; DROP TABLE t"},
+		{"mysql executable comment", "mysql", "SELECT 1 /*!50000 ; DROP TABLE t */", "SELECT 1 ; DROP TABLE t "},
+		{"mysql comments do not nest", "mysql", "/* /* */ DROP TABLE t", " DROP TABLE t"},
+		{"postgresql comments nest", "postgresql", "/* /* */ DROP TABLE t */ SELECT 1", " SELECT 1"},
+		{"dollar-quoted body", "postgresql", "DO $$ BEGIN DROP TABLE t; END $$", "DO $$ BEGIN DROP TABLE t; END $$"},
+		{"strings in a dollar-quoted body", "postgresql", "DO $f$ BEGIN RAISE NOTICE 'x -- y'; END $f$", "DO $f$ BEGIN RAISE NOTICE ' '; END $f$"},
+		{"quote in a dollar-quoted string", "postgresql", "SELECT $$it's$$; DROP TABLE t; -- '", "SELECT $$it' $$; DROP TABLE t; "},
+		{"positional parameter", "postgresql", "SELECT $1, 'x'", "SELECT $1, ' '"},
+		{"execute", "postgresql", "DO $$ BEGIN EXECUTE 'DROP TABLE t'; END $$", "DO $$ BEGIN EXECUTE 'DROP TABLE t'; END $$"},
+		{"prepare", "mysql", "PREPARE s FROM 'DROP TABLE t'; SELECT 'x'", "PREPARE s FROM 'DROP TABLE t'; SELECT ' '"},
+		{"query_to_xml", "postgresql", "SELECT query_to_xml('DROP TABLE t', true, true, '')", "SELECT query_to_xml('DROP TABLE t', true, true, '')"},
+		{"unterminated string", "sqlite", "SELECT 'abc", "SELECT ' "},
+		{"unterminated comment", "sqlite", "SELECT 1 /* abc", "SELECT 1 "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MaskSQL(tt.sql, tt.dialect)
+			if squeezed := squeezeSpaces(got); squeezed != tt.want {
+				t.Errorf("MaskSQL(%q, %s) =\n%q\nwant\n%q", tt.sql, tt.dialect, squeezed, tt.want)
+			}
+			if len(got) != len(tt.sql) {
+				t.Errorf("MaskSQL changed the length from %d to %d", len(tt.sql), len(got))
+			}
+		})
+	}
+}
+
+func TestSafetyChecksIgnoreStringsAndComments(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+	}{
+		{"sqlite", "SELECT 'drop table users' AS advice"},
+		{"sqlite", "-- delete old rows first\nSELECT * FROM items"},
+		{"sqlite", "SELECT * FROM items WHERE note = '; delete from items'"},
+		{"mysql", "SELECT 'shutdown' AS word"},
+		{"mysql", "SELECT * FROM items # set global x = 1"},
+		{"mysql", "SELECT 'update mysql.user' AS note"},
+		{"postgresql", "SELECT 'copy' AS word"},
+		{"postgresql", "SELECT * FROM items /* pg_sleep(10) */"},
+		{"postgresql", "INSERT INTO notes (body) VALUES ('alter pg_catalog')"},
+	}
+
+	for _, tt := range tests {
+		if result := IsSafeDDLOperation(tt.sql, tt.dialect); !result.Safe {
+			t.Errorf("expected %q to be safe on %s, got %+v", tt.sql, tt.dialect, result)
+		}
+	}
+}
+
+func TestSafetyChecksSeeThroughComments(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+		rule    string
+	}{
+		{"sqlite", "DROP/**/TABLE items", "drop_table"},
+		{"sqlite", "DROP/* x */TABLE items", "drop_table"},
+		{"postgresql", "DROP--\nTABLE items", "drop_table"},
+		{"mysql", "DROP#\nTABLE items", "drop_table"},
+		{"mysql", "DROP/*!*/TABLE items", "drop_table"},
+		{"mysql", "SELECT 1 /*!50000 ; DROP TABLE items */", "drop_table"},
+		{"mysql", "SELECT 1--1; DELETE FROM items", "stacked_statements"},
+		{"sqlite", "SELECT 'a'/**/;/**/DELETE FROM items", "stacked_statements"},
+		{"postgresql", "SELECT $$it's$$; DROP TABLE items; -- '", "drop_table"},
+		{"postgresql", "DO $$ BEGIN EXECUTE 'DROP TABLE items'; END $$", "drop_table"},
+		{"mysql", "PREPARE s FROM 'DROP TABLE items'", "drop_table"},
+	}
+
+	for _, tt := range tests {
+		result := IsSafeDDLOperation(tt.sql, tt.dialect)
+		if result.Safe || result.Rule != tt.rule {
+			t.Errorf("expected %q to be blocked by %s on %s, got %+v", tt.sql, tt.rule, tt.dialect, result)
+		}
+	}
+
+	if result := IsSafeDDLOperation("SET/**/GLOBAL max_connections = 1", "mysql"); result.Safe ||
+		!strings.Contains(result.Error, "global variables") {
+		t.Errorf("expected SET GLOBAL behind a comment to be blocked, got %+v", result)
+	}
+}
@@ -0,0 +1,330 @@
+package sqlvalidator
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severities of a policy rule
+const (
+	SeverityBlock   = "block"
+	SeverityConfirm = "confirm"
+	SeverityWarn    = "warn"
+	SeverityOff     = "off"
+)
+
+// PolicyRule is a named pattern that blocks, asks for confirmation of or
+// warns about matching statements. Patterns are matched against the
+// lower-cased SQL with comments and string contents masked by MaskSQL;
+// Check names a built-in check from policyChecks. A rule with both matches
+// only when both do.
+type PolicyRule struct {
+	Name     string   `yaml:"name" json:"name"`
+	Pattern  string   `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	Check    string   `yaml:"check,omitempty" json:"check,omitempty"`
+	Message  string   `yaml:"message" json:"message"`
+	Severity string   `yaml:"severity" json:"severity"`
+	Dialects []string `yaml:"dialects,omitempty" json:"dialects,omitempty"`
+
+	regex *regexp.Regexp
+}
+
+// Policy is the set of safety rules applied before queries run
+type Policy struct {
+	Rules []PolicyRule `yaml:"rules" json:"rules"`
+	// Severity overrides keyed by dialect, then by rule name
+	Overrides map[string]map[string]string `yaml:"overrides,omitempty" json:"overrides,omitempty"`
+	// File the policy was loaded from, empty for the built-in policy
+	Source   string    `yaml:"-" json:"source"`
+	LoadedAt time.Time `yaml:"-" json:"loaded_at"`
+}
+
+// Rules applied when no policy file is configured
+var defaultPolicyRules = []PolicyRule{
+	{Name: "drop_database", Pattern: `drop\s+(database|schema|user)`, Message: "DROP DATABASE/SCHEMA/USER operations are not allowed"},
+	{Name: "truncate_database", Pattern: `truncate\s+database`, Message: "TRUNCATE DATABASE operations are not allowed"},
+	{Name: "delete_sensitive_tables", Pattern: `delete\s+from\s+(user|users|permission|permissions|role|roles|account|accounts)`, Message: "DELETE operations on sensitive tables are not allowed"},
+	{Name: "alter_user", Pattern: `alter\s+user`, Message: "ALTER USER operations are not allowed"},
+	{Name: "grant_all", Pattern: `grant\s+all`, Message: "GRANT ALL operations are not allowed"},
+	{Name: "revoke_all", Pattern: `revoke\s+all`, Message: "REVOKE ALL operations are not allowed"},
+	{Name: "shutdown", Pattern: `shutdown`, Message: "SHUTDOWN operations are not allowed"},
+	{Name: "create_database", Pattern: `create\s+(database|schema)`, Message: "CREATE DATABASE/SCHEMA operations are not allowed"},
+	{Name: "drop_table", Pattern: `drop\s+table`, Message: "DROP TABLE operations are not allowed in this playground"},
+	{Name: "drop_column", Pattern: `alter\s+table\s+\w+\s+drop\s+column`, Message: "ALTER TABLE DROP COLUMN operations are not allowed"},
+	{Name: "delete_all_rows", Pattern: `delete\s+from\s+\w+\s+where\s+1\s*=\s*1`, Message: "DELETE all records operations are not allowed"},
+	{Name: "update_all_rows", Pattern: `update\s+\w+\s+set\s+.+where\s+1\s*=\s*1`, Message: "UPDATE all records operations are not allowed"},
+	{Name: "stacked_statements", Pattern: `;\s*(drop|delete|update|insert|alter|create)`, Message: "SQL injection attempts are not allowed"},
+	{Name: "unfiltered_write", Check: "unfiltered_write", Severity: SeverityConfirm,
+		Message: "This UPDATE or DELETE has no WHERE clause and changes every row of the table"},
+	{Name: "unbounded_recursion", Check: "unbounded_recursion", Severity: SeverityWarn, Dialects: []string{"sqlite", "mysql"},
+		Message: "A recursive CTE has no WHERE condition in its recursive part and the query has no LIMIT; it runs until the recursion cap or the query timeout stops it"},
+	{Name: "recursion_without_limit", Check: "unbounded_recursion", Dialects: []string{"postgresql"},
+		Message: "A recursive CTE without a WHERE condition in its recursive part needs a LIMIT on the outer query"},
+	{Name: "cartesian_product", Check: "cartesian_product",
+		Message: "This query joins large tables without a condition relating them, pairing every row of one with every row of the other; add a join condition with ON or WHERE"},
+}
+
+// Built-in checks that rules can refer to by name
+var policyChecks = map[string]func(sql string, dialect string) bool{
+	"unfiltered_write":    isUnfilteredWrite,
+	"unbounded_recursion": hasUnboundedRecursion,
+	"cartesian_product":   hasLargeCartesianProduct,
+}
+
+var (
+	// Policy in effect
+	currentPolicy = DefaultPolicy()
+
+	// Guards currentPolicy
+	policyMu sync.RWMutex
+)
+
+// DefaultPolicy returns the built-in policy
+func DefaultPolicy() *Policy {
+	policy := &Policy{Rules: append([]PolicyRule{}, defaultPolicyRules...), LoadedAt: time.Now()}
+	if err := policy.compile(); err != nil {
+		panic(fmt.Sprintf("invalid built-in policy: %v", err))
+	}
+	return policy
+}
+
+// ParsePolicy parses and validates a YAML policy
+func ParsePolicy(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	if err := policy.compile(); err != nil {
+		return nil, err
+	}
+	policy.LoadedAt = time.Now()
+	return &policy, nil
+}
+
+// compile validates the rules and overrides and compiles the patterns
+func (p *Policy) compile() error {
+	names := make(map[string]bool)
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.Name == "" {
+			return fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("duplicate rule %s", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if rule.Severity == "" {
+			rule.Severity = SeverityBlock
+		}
+		if !validSeverity(rule.Severity) {
+			return fmt.Errorf("rule %s has unknown severity %q", rule.Name, rule.Severity)
+		}
+		if rule.Message == "" {
+			rule.Message = "Statement matches safety rule " + rule.Name
+		}
+		if rule.Pattern == "" && rule.Check == "" {
+			return fmt.Errorf("rule %s needs a pattern or a check", rule.Name)
+		}
+		if _, ok := policyChecks[rule.Check]; rule.Check != "" && !ok {
+			return fmt.Errorf("rule %s refers to unknown check %s", rule.Name, rule.Check)
+		}
+		if rule.Pattern != "" {
+			regex, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return fmt.Errorf("rule %s has an invalid pattern: %v", rule.Name, err)
+			}
+			rule.regex = regex
+		}
+	}
+
+	for dialect, overrides := range p.Overrides {
+		for name, severity := range overrides {
+			if !names[name] {
+				return fmt.Errorf("override for %s refers to unknown rule %s", dialect, name)
+			}
+			if !validSeverity(severity) {
+				return fmt.Errorf("override of %s for %s has unknown severity %q", name, dialect, severity)
+			}
+		}
+	}
+	return nil
+}
+
+// validSeverity reports whether severity is a known severity
+func validSeverity(severity string) bool {
+	return severity == SeverityBlock || severity == SeverityConfirm || severity == SeverityWarn || severity == SeverityOff
+}
+
+// matches reports whether a statement triggers the rule
+func (r PolicyRule) matches(sql string, sqlLower string, dialect string) bool {
+	if r.regex != nil && !r.regex.MatchString(sqlLower) {
+		return false
+	}
+	if r.Check != "" && !policyChecks[r.Check](sql, dialect) {
+		return false
+	}
+	return true
+}
+
+// matchesAny reports whether any of the statements triggers the rule
+func (r PolicyRule) matchesAny(statements []string, dialect string) bool {
+	for _, sql := range statements {
+		if r.matches(sql, strings.ToLower(MaskSQL(sql, dialect)), dialect) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnfilteredWrite reports whether any statement in sql, or a
+// data-modifying CTE in one, is an UPDATE or DELETE without a WHERE clause
+func isUnfilteredWrite(sql string, dialect string) bool {
+	for _, statement := range splitTokenStatements(withoutComments(Tokenize(sql, dialect))) {
+		if writesEveryRow(statement) {
+			return true
+		}
+		if clause := parseWithClause(statement); clause != nil {
+			for _, expression := range clause.Expressions {
+				if writesEveryRow(expression.body) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// hasUnboundedRecursion reports whether a statement in sql has a recursive
+// CTE without a stopping condition
+func hasUnboundedRecursion(sql string, dialect string) bool {
+	for _, statement := range splitTokenStatements(withoutComments(Tokenize(sql, dialect))) {
+		if clause := parseWithClause(statement); clause != nil && len(clause.UnboundedRecursion()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writesEveryRow reports whether a statement's outermost UPDATE or DELETE
+// has no WHERE clause
+func writesEveryRow(tokens []Token) bool {
+	if len(tokens) == 0 || !(tokens[0].Is("update") || tokens[0].Is("delete") || tokens[0].Is("with")) {
+		return false
+	}
+
+	depth := 0
+	write := false
+	for _, token := range tokens {
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 0 {
+			continue
+		}
+		switch {
+		case !write && (token.Is("update") || token.Is("delete")):
+			write = true
+		case !write && (token.Is("select") || token.Is("insert")):
+			return false
+		case write && token.Is("where"):
+			return false
+		}
+	}
+	return write
+}
+
+// SetPolicy replaces the policy in effect
+func SetPolicy(policy *Policy) {
+	policyMu.Lock()
+	currentPolicy = policy
+	policyMu.Unlock()
+}
+
+// CurrentPolicy returns the policy in effect
+func CurrentPolicy() *Policy {
+	policyMu.RLock()
+	defer policyMu.RUnlock()
+	return currentPolicy
+}
+
+// LoadPolicyFile reads a policy from a YAML file and puts it into effect.
+// The current policy is kept if the file is invalid.
+func LoadPolicyFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	policy.Source = path
+	SetPolicy(policy)
+	return nil
+}
+
+// WatchPolicyFile reloads the policy file whenever its modification time
+// changes, checking at the given interval
+func WatchPolicyFile(path string, interval time.Duration) {
+	var lastModified time.Time
+	if info, err := os.Stat(path); err == nil {
+		lastModified = info.ModTime()
+	}
+
+	go func() {
+		for range time.Tick(interval) {
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(lastModified) {
+				continue
+			}
+			lastModified = info.ModTime()
+			if err := LoadPolicyFile(path); err != nil {
+				fmt.Printf("Failed to reload safety policy: %v\n", err)
+				continue
+			}
+			fmt.Printf("Reloaded safety policy from %s\n", path)
+		}
+	}()
+}
+
+// EffectiveRules returns the rules that apply to a dialect, with its
+// overrides applied and disabled rules left out
+func EffectiveRules(dialect string) []PolicyRule {
+	policy := CurrentPolicy()
+	overrides := policy.Overrides[dialect]
+
+	rules := []PolicyRule{}
+	for _, rule := range policy.Rules {
+		if len(rule.Dialects) > 0 && !containsString(rule.Dialects, dialect) {
+			continue
+		}
+		if severity, ok := overrides[rule.Name]; ok {
+			rule.Severity = severity
+		}
+		if rule.Severity == SeverityOff {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package sqlvalidator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultPolicyBlocksDropTable(t *testing.T) {
+	result := IsSafeDDLOperation("DROP TABLE items", "sqlite")
+	if result.Safe || result.Rule != "drop_table" {
+		t.Errorf("expected drop_table to block, got %+v", result)
+	}
+}
+
+func TestParsePolicyWarnAndOverrides(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`
+rules:
+  - name: drop_table
+    pattern: 'drop\s+table'
+    message: Dropping tables is risky
+    severity: warn
+  - name: file_access
+    pattern: 'load_file'
+    dialects: [mysql]
+overrides:
+  postgresql:
+    drop_table: block
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetPolicy(policy)
+	defer SetPolicy(DefaultPolicy())
+
+	result := IsSafeDDLOperation("DROP TABLE items", "sqlite")
+	if !result.Safe || len(result.Warnings) != 1 || result.Warnings[0] != "Dropping tables is risky" {
+		t.Errorf("expected a warning for sqlite, got %+v", result)
+	}
+	if result := IsSafeDDLOperation("DROP TABLE items", "postgresql"); result.Safe {
+		t.Errorf("expected the postgresql override to block")
+	}
+
+	if result := IsSafeDDLOperation("SELECT load_file('/etc/passwd')", "sqlite"); !result.Safe {
+		t.Errorf("expected the mysql-only rule not to apply to sqlite")
+	}
+	result = IsSafeDDLOperation("SELECT load_file('/etc/passwd')", "mysql")
+	if result.Safe || !strings.Contains(result.Error, "file_access") {
+		t.Errorf("expected the default message for file_access, got %+v", result)
+	}
+}
+
+func TestParsePolicyRejectsInvalidPolicies(t *testing.T) {
+	policies := map[string]string{
+		"unknown severity": "rules:\n  - name: a\n    pattern: x\n    severity: maybe\n",
+		"invalid pattern":  "rules:\n  - name: a\n    pattern: '('\n",
+		"duplicate rule":   "rules:\n  - name: a\n    pattern: x\n  - name: a\n    pattern: y\n",
+		"unknown override": "rules:\n  - name: a\n    pattern: x\noverrides:\n  sqlite:\n    b: off\n",
+	}
+
+	for name, policy := range policies {
+		if _, err := ParsePolicy([]byte(policy)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestEffectiveRulesLeavesOutDisabledRules(t *testing.T) {
+	policy, err := ParsePolicy([]byte("rules:\n  - name: a\n    pattern: x\noverrides:\n  sqlite:\n    a: off\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	SetPolicy(policy)
+	defer SetPolicy(DefaultPolicy())
+
+	if rules := EffectiveRules("sqlite"); len(rules) != 0 {
+		t.Errorf("expected no rules for sqlite, got %d", len(rules))
+	}
+	if rules := EffectiveRules("mysql"); len(rules) != 1 || rules[0].Severity != SeverityBlock {
+		t.Errorf("expected one blocking rule for mysql, got %+v", rules)
+	}
+}
+
+func TestUnfilteredWriteRequiresConfirmation(t *testing.T) {
+	for _, query := range []string{
+		"DELETE FROM items",
+		"UPDATE items SET price = 0;",
+		"WITH old AS (SELECT id FROM items WHERE price < 1) DELETE FROM items",
+	} {
+		result := IsSafeDDLOperation(query, "sqlite")
+		if result.Safe || !result.RequiresConfirmation || result.Rule != "unfiltered_write" {
+			t.Errorf("expected %q to require confirmation, got %+v", query, result)
+		}
+		if valid, err := Validate(query, "sqlite"); !valid {
+			t.Errorf("expected %q to pass validation, got %v", query, err)
+		}
+	}
+
+	for _, query := range []string{
+		"DELETE FROM items WHERE id = 1",
+		"UPDATE items SET price = 0 WHERE id IN (SELECT id FROM old)",
+		"SELECT * FROM items FOR UPDATE",
+		"INSERT INTO items (id) VALUES (1) ON CONFLICT (id) DO UPDATE SET price = 0",
+	} {
+		if result := IsSafeDDLOperation(query, "sqlite"); !result.Safe {
+			t.Errorf("expected %q to be safe, got %+v", query, result)
+		}
+	}
+}
+
+func TestBlockingRulesTakePrecedenceOverConfirmation(t *testing.T) {
+	result := IsSafeDDLOperation("DELETE FROM users", "sqlite")
+	if result.Safe || result.RequiresConfirmation || result.Rule != "delete_sensitive_tables" {
+		t.Errorf("expected delete_sensitive_tables to block, got %+v", result)
+	}
+}
+
+func TestParsePolicyRequiresPatternOrKnownCheck(t *testing.T) {
+	for _, policy := range []string{
+		"rules:\n  - name: a\n",
+		"rules:\n  - name: a\n    check: nonsense\n",
+	} {
+		if _, err := ParsePolicy([]byte(policy)); err == nil {
+			t.Errorf("expected an error for %q", policy)
+		}
+	}
+}
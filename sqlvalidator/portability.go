@@ -0,0 +1,272 @@
+package sqlvalidator
+
+import (
+	"sort"
+	"strings"
+)
+
+// Dialects a query's portability is checked against
+var portableDialects = []string{"mysql", "postgresql", "sqlite"}
+
+// PortabilityIssue is a construct of a query that another supported
+// dialect rejects or reads differently. Line and Column are 1-based.
+type PortabilityIssue struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// The construct as written
+	Text   string `json:"text"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Target dialects the construct does not work on
+	Dialects []string `json:"dialects"`
+	// How to write it for each of those dialects
+	Suggestions map[string]string `json:"suggestions"`
+}
+
+// portabilityRule matches a construct at tokens[i] and describes it,
+// returning the number of tokens it spans, or 0 when it does not match
+type portabilityRule struct {
+	name    string
+	message string
+	// Dialects the construct does not work on
+	unsupported []string
+	match       func(tokens []Token, i int) int
+	// suggest returns the equivalent of the construct in a target dialect
+	suggest func(construct []Token, target string) string
+}
+
+var portabilityRules = []portabilityRule{
+	{
+		name:        "backtick_quoting",
+		message:     "Backticks quote identifiers only in MySQL and SQLite",
+		unsupported: []string{"postgresql"},
+		match: func(tokens []Token, i int) int {
+			if tokens[i].Kind == TokenQuotedIdentifier && strings.HasPrefix(tokens[i].Text, "`") {
+				return 1
+			}
+			return 0
+		},
+		suggest: func(construct []Token, target string) string {
+			return QuoteIdentifier(construct[0].Value(), target)
+		},
+	},
+	{
+		name:        "double_quoted_string",
+		message:     "Double quotes delimit strings only in MySQL; elsewhere they quote identifiers",
+		unsupported: []string{"postgresql", "sqlite"},
+		match: func(tokens []Token, i int) int {
+			if tokens[i].Kind == TokenString && strings.HasPrefix(tokens[i].Text, `"`) {
+				return 1
+			}
+			return 0
+		},
+		suggest: func(construct []Token, target string) string {
+			text := construct[0].Text
+			return "'" + strings.ReplaceAll(text[1:len(text)-1], "'", "''") + "'"
+		},
+	},
+	{
+		name:        "auto_increment",
+		message:     "AUTO_INCREMENT is MySQL's way of generating keys",
+		unsupported: []string{"postgresql", "sqlite"},
+		match:       matchWord("auto_increment"),
+		suggest: suggestByDialect(map[string]string{
+			"postgresql": "GENERATED BY DEFAULT AS IDENTITY",
+			"sqlite":     "INTEGER PRIMARY KEY AUTOINCREMENT",
+		}),
+	},
+	{
+		name:        "autoincrement",
+		message:     "AUTOINCREMENT is SQLite's way of generating keys",
+		unsupported: []string{"mysql", "postgresql"},
+		match:       matchWord("autoincrement"),
+		suggest: suggestByDialect(map[string]string{
+			"mysql":      "AUTO_INCREMENT",
+			"postgresql": "GENERATED BY DEFAULT AS IDENTITY",
+		}),
+	},
+	{
+		name:        "serial",
+		message:     "SERIAL generates keys only in PostgreSQL and MySQL",
+		unsupported: []string{"sqlite"},
+		match:       matchColumnType("serial"),
+		suggest: suggestByDialect(map[string]string{
+			"sqlite": "INTEGER PRIMARY KEY",
+		}),
+	},
+	{
+		name:        "bigserial",
+		message:     "SMALLSERIAL and BIGSERIAL are PostgreSQL types",
+		unsupported: []string{"mysql", "sqlite"},
+		match:       matchColumnType("smallserial", "bigserial"),
+		suggest: suggestByDialect(map[string]string{
+			"mysql":  "BIGINT UNSIGNED NOT NULL AUTO_INCREMENT",
+			"sqlite": "INTEGER PRIMARY KEY",
+		}),
+	},
+	{
+		name:        "ilike",
+		message:     "ILIKE is PostgreSQL's case-insensitive LIKE",
+		unsupported: []string{"mysql", "sqlite"},
+		match:       matchWord("ilike"),
+		suggest: suggestByDialect(map[string]string{
+			"mysql":  "LIKE, which ignores case under the default collations",
+			"sqlite": "LIKE, which ignores case for ASCII letters",
+		}),
+	},
+	{
+		name:        "on_duplicate_key_update",
+		message:     "ON DUPLICATE KEY UPDATE is MySQL's upsert",
+		unsupported: []string{"postgresql", "sqlite"},
+		match:       matchWords("on", "duplicate", "key", "update"),
+		suggest: func(construct []Token, target string) string {
+			return "ON CONFLICT (key columns) DO UPDATE SET column = excluded.column"
+		},
+	},
+	{
+		name:        "on_conflict",
+		message:     "ON CONFLICT is the upsert of PostgreSQL and SQLite",
+		unsupported: []string{"mysql"},
+		match:       matchWords("on", "conflict"),
+		suggest: suggestByDialect(map[string]string{
+			"mysql": "ON DUPLICATE KEY UPDATE column = VALUES(column), or INSERT IGNORE for DO NOTHING",
+		}),
+	},
+	{
+		name:        "returning",
+		message:     "RETURNING is not supported by MySQL",
+		unsupported: []string{"mysql"},
+		match:       matchWord("returning"),
+		suggest: suggestByDialect(map[string]string{
+			"mysql": "a SELECT after the statement, with LAST_INSERT_ID() for a generated key",
+		}),
+	},
+	{
+		name:        "double_colon_cast",
+		message:     "The :: cast is PostgreSQL syntax",
+		unsupported: []string{"mysql", "sqlite"},
+		match: func(tokens []Token, i int) int {
+			if tokens[i].Text == "::" {
+				return 1
+			}
+			return 0
+		},
+		suggest: func(construct []Token, target string) string {
+			return "CAST(value AS type)"
+		},
+	},
+	{
+		name:        "limit_offset_comma",
+		message:     "LIMIT offset, count is not supported by PostgreSQL",
+		unsupported: []string{"postgresql"},
+		match: func(tokens []Token, i int) int {
+			if tokens[i].Is("limit") && tokenAt(tokens, i+2).Text == "," && tokenAt(tokens, i+3).Text != "" {
+				return 4
+			}
+			return 0
+		},
+		suggest: func(construct []Token, target string) string {
+			return "LIMIT " + construct[3].Text + " OFFSET " + construct[1].Text
+		},
+	},
+}
+
+// matchWord matches a single keyword
+func matchWord(word string) func(tokens []Token, i int) int {
+	return matchWords(word)
+}
+
+// matchWords matches a sequence of keywords
+func matchWords(words ...string) func(tokens []Token, i int) int {
+	return func(tokens []Token, i int) int {
+		for j, word := range words {
+			if !tokenAt(tokens, i+j).Is(word) {
+				return 0
+			}
+		}
+		return len(words)
+	}
+}
+
+// matchColumnType matches a type name after a column name in a CREATE or
+// ALTER statement, so columns that share the name are not mistaken for it
+func matchColumnType(types ...string) func(tokens []Token, i int) int {
+	return func(tokens []Token, i int) int {
+		if !containsString(types, strings.ToLower(tokens[i].Text)) || tokens[i].Kind != TokenWord {
+			return 0
+		}
+		previous := tokenAt(tokens, i-1)
+		if previous.Kind != TokenQuotedIdentifier &&
+			(previous.Kind != TokenWord || sqlKeywords[strings.ToLower(previous.Text)]) {
+			return 0
+		}
+		for j := i - 1; j >= 0 && tokens[j].Text != ";"; j-- {
+			if tokens[j].Is("create") || tokens[j].Is("alter") {
+				return 1
+			}
+		}
+		return 0
+	}
+}
+
+// suggestByDialect suggests a fixed equivalent per target dialect
+func suggestByDialect(suggestions map[string]string) func(construct []Token, target string) string {
+	return func(construct []Token, target string) string {
+		return suggestions[target]
+	}
+}
+
+// CheckPortability reports the constructs of sql, written for dialect,
+// that the target dialects reject, with an equivalent for each target.
+// Without targets, every other supported dialect is checked.
+func CheckPortability(sql string, dialect string, targets []string) []PortabilityIssue {
+	if len(targets) == 0 {
+		for _, target := range portableDialects {
+			if target != dialect {
+				targets = append(targets, target)
+			}
+		}
+	}
+
+	issues := []PortabilityIssue{}
+	tokens := withoutComments(Tokenize(sql, dialect))
+	for i := 0; i < len(tokens); i++ {
+		for _, rule := range portabilityRules {
+			n := rule.match(tokens, i)
+			if n == 0 {
+				continue
+			}
+			construct := tokens[i : i+n]
+			issue := PortabilityIssue{
+				Rule:        rule.name,
+				Message:     rule.message,
+				Text:        constructText(sql, construct),
+				Dialects:    []string{},
+				Suggestions: map[string]string{},
+			}
+			issue.Line, issue.Column = lineColumn(sql, construct[0].Offset)
+			for _, target := range targets {
+				if !containsString(rule.unsupported, target) {
+					continue
+				}
+				issue.Dialects = append(issue.Dialects, target)
+				if suggestion := rule.suggest(construct, target); suggestion != "" {
+					issue.Suggestions[target] = suggestion
+				}
+			}
+			if len(issue.Dialects) > 0 {
+				sort.Strings(issue.Dialects)
+				issues = append(issues, issue)
+			}
+			i += n - 1
+			break
+		}
+	}
+	return issues
+}
+
+// constructText returns the SQL text a construct's tokens span
+func constructText(sql string, construct []Token) string {
+	last := construct[len(construct)-1]
+	return sql[construct[0].Offset : last.Offset+len(last.Text)]
+}
@@ -0,0 +1,56 @@
+package sqlvalidator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckPortability(t *testing.T) {
+	tests := []struct {
+		name    string
+		sql     string
+		dialect string
+		targets []string
+		rules   []string
+	}{
+		{"mysql table", "CREATE TABLE `users` (id INT AUTO_INCREMENT PRIMARY KEY, name TEXT DEFAULT \"x\")", "mysql", nil,
+			[]string{"backtick_quoting", "auto_increment", "double_quoted_string"}},
+		{"mysql upsert", "INSERT INTO t (id) VALUES (1) ON DUPLICATE KEY UPDATE id = id + 1 LIMIT 5, 10", "mysql", nil,
+			[]string{"on_duplicate_key_update", "limit_offset_comma"}},
+		{"postgresql query", "SELECT id::text FROM users WHERE name ILIKE 'a%' RETURNING id", "postgresql", nil,
+			[]string{"double_colon_cast", "ilike", "returning"}},
+		{"serial columns", "CREATE TABLE t (id SERIAL, big BIGSERIAL); SELECT serial FROM t", "postgresql", nil,
+			[]string{"serial", "bigserial"}},
+		{"only the targets", "INSERT INTO t VALUES (1) ON CONFLICT DO NOTHING RETURNING id", "postgresql", []string{"sqlite"}, nil},
+		{"comments", "SELECT 1 -- ILIKE", "postgresql", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := []string{}
+			for _, issue := range CheckPortability(tt.sql, tt.dialect, tt.targets) {
+				rules = append(rules, issue.Rule)
+			}
+			if len(tt.rules) == 0 {
+				tt.rules = []string{}
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("CheckPortability() rules = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestPortabilitySuggestions(t *testing.T) {
+	issues := CheckPortability("SELECT `order` FROM t\nLIMIT 20, 10", "mysql", nil)
+	if len(issues) != 2 {
+		t.Fatalf("expected 2 issues, got %+v", issues)
+	}
+	if issues[0].Suggestions["postgresql"] != `"order"` || strings.Join(issues[0].Dialects, ",") != "postgresql" {
+		t.Errorf("unexpected quoting issue %+v", issues[0])
+	}
+	limit := issues[1]
+	if limit.Text != "LIMIT 20, 10" || limit.Line != 2 || limit.Column != 1 || limit.Suggestions["postgresql"] != "LIMIT 10 OFFSET 20" {
+		t.Errorf("unexpected limit issue %+v", limit)
+	}
+}
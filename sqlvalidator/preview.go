@@ -0,0 +1,129 @@
+package sqlvalidator
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Maximum number of rows returned by a derived preview SELECT
+const PreviewRowLimit = 100
+
+var (
+	// UPDATE <table> [[AS] alias] SET ...
+	updateTargetRegex = regexp.MustCompile(`(?is)^\s*update\s+([\w.` + "`" + `"\[\]]+)(\s+(?:as\s+)?(\w+))?\s+set\s`)
+
+	// DELETE FROM <table> [[AS] alias] ...
+	deleteTargetRegex = regexp.MustCompile(`(?is)^\s*delete\s+from\s+([\w.` + "`" + `"\[\]]+)(\s+(?:as\s+)?(\w+))?(\s|;|$)`)
+
+	// Words that may follow the table name but are not aliases
+	nonAliasWords = map[string]bool{
+		"set": true, "where": true, "order": true, "limit": true, "returning": true, "using": true,
+	}
+)
+
+// DerivePreviewSelect builds a SELECT that returns the rows an UPDATE or
+// DELETE statement will touch. It returns false for other statements and for
+// forms it cannot translate, such as multi-table updates. Comments are left
+// out of the preview, so a commented-out filter never narrows it.
+func DerivePreviewSelect(sql string, dialect string) (string, bool) {
+	trimmed := strings.TrimSuffix(strings.TrimSpace(stripComments(sql, dialect)), ";")
+	trimmed = strings.TrimSpace(trimmed)
+
+	var match []string
+	if m := updateTargetRegex.FindStringSubmatch(trimmed); m != nil {
+		match = m
+	} else if m := deleteTargetRegex.FindStringSubmatch(trimmed); m != nil {
+		match = m
+	} else {
+		return "", false
+	}
+
+	table := match[1]
+	alias := match[3]
+	if nonAliasWords[strings.ToLower(alias)] {
+		alias = ""
+	}
+
+	// Keywords are searched for in the masked statement, where string
+	// contents cannot be mistaken for them
+	lower := strings.ToLower(MaskSQL(trimmed, dialect))
+
+	// Statements joining other tables cannot be previewed reliably
+	if findTopLevelKeyword(lower, "join") >= 0 || findTopLevelKeyword(lower, "using") >= 0 {
+		return "", false
+	}
+
+	from := table
+	if alias != "" {
+		from += " " + alias
+	}
+
+	preview := "SELECT * FROM " + from
+
+	if where := findTopLevelKeyword(lower, "where"); where >= 0 {
+		end := len(trimmed)
+		if returning := findTopLevelKeyword(lower, "returning"); returning > where {
+			end = returning
+		}
+		clause := strings.TrimSpace(trimmed[where:end])
+		// The statement's own LIMIT bounds the rows it touches
+		if findTopLevelKeyword(lower[where:end], "limit") >= 0 {
+			return preview + " " + clause, true
+		}
+		preview += " " + clause
+	}
+
+	return preview + " LIMIT " + strconv.Itoa(PreviewRowLimit), true
+}
+
+// findTopLevelKeyword returns the offset of the first occurrence of keyword
+// outside string literals, quoted identifiers and parentheses, or -1.
+// sqlLower must already be lower-cased.
+func findTopLevelKeyword(sqlLower string, keyword string) int {
+	depth := 0
+	var quote byte
+
+	for i := 0; i < len(sqlLower); i++ {
+		ch := sqlLower[i]
+
+		if quote != 0 {
+			if ch == quote {
+				// Doubled quotes are escaped quotes
+				if i+1 < len(sqlLower) && sqlLower[i+1] == quote {
+					i++
+					continue
+				}
+				quote = 0
+			}
+			continue
+		}
+
+		switch ch {
+		case '\'', '"', '`':
+			quote = ch
+			continue
+		case '(':
+			depth++
+			continue
+		case ')':
+			depth--
+			continue
+		}
+
+		if depth != 0 || !strings.HasPrefix(sqlLower[i:], keyword) {
+			continue
+		}
+		before := i == 0 || !isWordChar(sqlLower[i-1])
+		after := i+len(keyword) >= len(sqlLower) || !isWordChar(sqlLower[i+len(keyword)])
+		if before && after {
+			return i
+		}
+	}
+	return -1
+}
+
+// isWordChar reports whether ch can be part of an identifier
+func isWordChar(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,45 @@
+package sqlvalidator
+
+import "testing"
+
+func TestDerivePreviewSelectUpdate(t *testing.T) {
+	got, ok := DerivePreviewSelect("UPDATE products SET price = 10 WHERE category = 'Audio';", "sqlite")
+	want := "SELECT * FROM products WHERE category = 'Audio' LIMIT 100"
+	if !ok || got != want {
+		t.Errorf("expected %q, got %q (ok=%v)", want, got, ok)
+	}
+}
+
+func TestDerivePreviewSelectDeleteWithReturning(t *testing.T) {
+	got, ok := DerivePreviewSelect("DELETE FROM customers c WHERE c.country = 'USA' RETURNING id", "sqlite")
+	want := "SELECT * FROM customers c WHERE c.country = 'USA' LIMIT 100"
+	if !ok || got != want {
+		t.Errorf("expected %q, got %q (ok=%v)", want, got, ok)
+	}
+}
+
+func TestDerivePreviewSelectIgnoresWhereInString(t *testing.T) {
+	got, ok := DerivePreviewSelect("UPDATE test_data SET name = 'where'", "sqlite")
+	want := "SELECT * FROM test_data LIMIT 100"
+	if !ok || got != want {
+		t.Errorf("expected %q, got %q (ok=%v)", want, got, ok)
+	}
+}
+
+func TestDerivePreviewSelectSkipsSelect(t *testing.T) {
+	if _, ok := DerivePreviewSelect("SELECT * FROM test_data", "sqlite"); ok {
+		t.Error("expected no preview for SELECT")
+	}
+}
+
+func TestDerivePreviewSelectLeavesOutCommentedFilters(t *testing.T) {
+	got, ok := DerivePreviewSelect("DELETE FROM t -- WHERE id = 1", "sqlite")
+	if !ok || got != "SELECT * FROM t LIMIT 100" {
+		t.Errorf("expected the commented-out filter to be left out, got %q, %v", got, ok)
+	}
+
+	got, ok = DerivePreviewSelect("/* tidy up */ UPDATE t SET a = '--' WHERE b = 2 /* LIMIT 1 */", "postgresql")
+	if !ok || got != "SELECT * FROM t WHERE b = 2 LIMIT 100" {
+		t.Errorf("expected comments to be left out and strings kept, got %q, %v", got, ok)
+	}
+}
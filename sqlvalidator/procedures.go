@@ -0,0 +1,37 @@
+package sqlvalidator
+
+import (
+	"strings"
+)
+
+// ProcedureOutputVariables returns the MySQL user variables, such as @total,
+// passed as arguments to a CALL statement. They receive the values of OUT
+// and INOUT parameters and can be read back on the same connection.
+// Other dialects return OUT parameters as a result row and yield nil.
+func ProcedureOutputVariables(sql string, dialect string) []string {
+	if dialect != "mysql" {
+		return nil
+	}
+	tokens := withoutComments(Tokenize(sql, dialect))
+	if len(tokens) == 0 || !tokens[0].Is("call") {
+		return nil
+	}
+
+	var variables []string
+	seen := make(map[string]bool)
+	for i, token := range tokens {
+		if token.Text == ";" {
+			break
+		}
+		// @@name is a system variable, never an output parameter
+		if token.Kind != TokenWord || len(token.Text) < 2 || token.Text[0] != '@' || tokenAt(tokens, i-1).Text == "@" {
+			continue
+		}
+		name := strings.ToLower(token.Text)
+		if !seen[name] {
+			seen[name] = true
+			variables = append(variables, token.Text)
+		}
+	}
+	return variables
+}
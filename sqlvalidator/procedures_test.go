@@ -0,0 +1,26 @@
+package sqlvalidator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProcedureOutputVariables(t *testing.T) {
+	tests := []struct {
+		sql     string
+		dialect string
+		want    []string
+	}{
+		{"CALL order_totals(42, @total, @count)", "mysql", []string{"@total", "@count"}},
+		{"call refresh(@Total, @total);", "mysql", []string{"@Total"}},
+		{"CALL report(@@session.sql_mode, '@not_a_variable')", "mysql", nil},
+		{"CALL report(1) -- @ignored", "mysql", nil},
+		{"SELECT @total", "mysql", nil},
+		{"CALL order_totals(42, NULL)", "postgresql", nil},
+	}
+	for _, test := range tests {
+		if got := ProcedureOutputVariables(test.sql, test.dialect); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ProcedureOutputVariables(%q) = %v, want %v", test.sql, got, test.want)
+		}
+	}
+}
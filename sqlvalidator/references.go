@@ -0,0 +1,397 @@
+package sqlvalidator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema maps table names to their column names
+type Schema map[string][]string
+
+// ReferenceError reports a table or column that does not exist in the schema
+type ReferenceError struct {
+	Kind       string `json:"kind"`
+	Message    string `json:"message"`
+	Name       string `json:"name"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+func (e *ReferenceError) Error() string {
+	return e.Message
+}
+
+// Reference kinds reported by CheckReferences
+const (
+	UnknownTable  = "missing_table"
+	UnknownColumn = "missing_column"
+)
+
+// TableRef is a table referenced by a statement
+type TableRef struct {
+	Name   string
+	Alias  string
+	Offset int
+}
+
+// Words that end a table reference instead of naming its alias
+var clauseKeywords = map[string]bool{
+	"where": true, "join": true, "left": true, "right": true, "inner": true, "outer": true,
+	"cross": true, "full": true, "natural": true, "on": true, "using": true, "group": true,
+	"order": true, "limit": true, "having": true, "set": true, "values": true, "union": true,
+	"except": true, "intersect": true, "window": true, "offset": true, "fetch": true,
+	"for": true, "returning": true, "select": true, "lateral": true, "straight_join": true,
+	"as": true, "default": true, "partition": true, "with": true, "into": true,
+}
+
+// lookup returns the columns of a table, matching the name case-insensitively
+func (s Schema) lookup(table string) ([]string, bool) {
+	if columns, ok := s[table]; ok {
+		return columns, true
+	}
+	for name, columns := range s {
+		if strings.EqualFold(name, table) {
+			return columns, true
+		}
+	}
+	return nil, false
+}
+
+// CheckReferences resolves the tables and columns referenced by sql against
+// schema and returns a *ReferenceError for the first unknown name, with a
+// "did you mean" suggestion when a similar name exists
+func CheckReferences(sql string, dialect string, schema Schema) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	tokens := withoutComments(Tokenize(sql, dialect))
+	ctes := cteNames(tokens)
+	created := createdTables(tokens)
+	refs := tableRefs(tokens)
+
+	for _, ref := range refs {
+		name := strings.ToLower(ref.Name)
+		if ctes[name] || created[name] || isSystemTable(ref.Name, dialect) {
+			continue
+		}
+		if _, ok := schema.lookup(ref.Name); ok {
+			continue
+		}
+
+		line, column := lineColumn(sql, ref.Offset)
+		refErr := &ReferenceError{
+			Kind:    UnknownTable,
+			Message: fmt.Sprintf("unknown table '%s'", ref.Name),
+			Name:    ref.Name,
+			Line:    line,
+			Column:  column,
+		}
+		if suggestion := closestMatch(ref.Name, schema.tableNames()); suggestion != "" {
+			refErr.Suggestion = suggestion
+			refErr.Message += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		return refErr
+	}
+
+	// Columns may be added by DDL earlier in the same script
+	if changesSchema(tokens) {
+		return nil
+	}
+	return checkColumns(sql, tokens, refs, schema, ctes)
+}
+
+// RenameTables replaces unqualified table references whose lower-cased name
+// is a key of names with the mapped name, quoted for the dialect. CTEs of
+// the same name are left alone. It returns the new SQL and the names that
+// were replaced.
+func RenameTables(sql string, dialect string, names map[string]string) (string, []string) {
+	tokens := withoutComments(Tokenize(sql, dialect))
+	ctes := cteNames(tokens)
+	byOffset := make(map[int]Token, len(tokens))
+	for _, token := range tokens {
+		byOffset[token.Offset] = token
+	}
+
+	refs := tableRefs(tokens)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Offset < refs[j].Offset })
+
+	var out strings.Builder
+	last := 0
+	renamed := []string{}
+	for _, ref := range refs {
+		lower := strings.ToLower(ref.Name)
+		target, ok := names[lower]
+		if !ok || ctes[lower] || ref.Offset < last {
+			continue
+		}
+		token := byOffset[ref.Offset]
+		out.WriteString(sql[last:token.Offset])
+		out.WriteString(QuoteIdentifier(target, dialect))
+		last = token.Offset + len(token.Text)
+		renamed = append(renamed, ref.Name)
+	}
+	out.WriteString(sql[last:])
+	return out.String(), renamed
+}
+
+// tableNames returns the names of all tables in the schema
+func (s Schema) tableNames() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	return names
+}
+
+// createdTables returns the lower-cased names of tables and views created
+// by the statements themselves
+func createdTables(tokens []Token) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < len(tokens); i++ {
+		if !tokens[i].Is("create") {
+			continue
+		}
+		j := i + 1
+		for j < len(tokens) && tokens[j].Kind == TokenWord && !tokens[j].Is("table") && !tokens[j].Is("view") {
+			j++
+		}
+		if j >= len(tokens) {
+			break
+		}
+		j++
+		// IF NOT EXISTS
+		for j < len(tokens) && (tokens[j].Is("if") || tokens[j].Is("not") || tokens[j].Is("exists")) {
+			j++
+		}
+		if j < len(tokens) {
+			for j+2 < len(tokens) && tokens[j+1].Text == "." {
+				j += 2
+			}
+			names[strings.ToLower(tokens[j].Value())] = true
+		}
+	}
+	return names
+}
+
+// changesSchema reports whether the tokens contain CREATE, ALTER or RENAME statements
+func changesSchema(tokens []Token) bool {
+	for _, token := range tokens {
+		if token.Is("create") || token.Is("alter") || token.Is("rename") {
+			return true
+		}
+	}
+	return false
+}
+
+// tableRefs returns the tables named after FROM, JOIN, UPDATE and INSERT INTO.
+// Subqueries, table functions and schema-qualified names are skipped.
+func tableRefs(tokens []Token) []TableRef {
+	refs := []TableRef{}
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind != TokenWord {
+			continue
+		}
+
+		switch {
+		case token.Is("from") && !insideFunctionCall(tokens, i):
+			// FROM a, b JOIN c
+			for j := i + 1; j < len(tokens); {
+				ref, next, ok := parseTableRef(tokens, j, true)
+				if ok {
+					refs = append(refs, ref)
+				}
+				if next < len(tokens) && tokens[next].Text == "," {
+					j = next + 1
+					continue
+				}
+				break
+			}
+		case token.Is("join"):
+			if ref, _, ok := parseTableRef(tokens, i+1, true); ok {
+				refs = append(refs, ref)
+			}
+		case token.Is("update") && !(i > 0 && (tokens[i-1].Is("key") || tokens[i-1].Is("do"))):
+			// ON DUPLICATE KEY UPDATE and ON CONFLICT DO UPDATE reuse the target table
+			if ref, _, ok := parseTableRef(tokens, i+1, false); ok {
+				refs = append(refs, ref)
+			}
+		case token.Is("into") && i > 0 && (tokens[i-1].Is("insert") || tokens[i-1].Is("replace") || tokens[i-1].Is("ignore")):
+			// The parenthesized group after the table is its column list
+			if ref, _, ok := parseTableRef(tokens, i+1, false); ok {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// parseTableRef reads "name [[AS] alias]" at tokens[i] and returns the
+// reference, the index after it and whether a plain table was found. With
+// allowFunctions, a name followed by "(" is a table function, not a table.
+func parseTableRef(tokens []Token, i int, allowFunctions bool) (TableRef, int, bool) {
+	if i >= len(tokens) {
+		return TableRef{}, i, false
+	}
+
+	// ONLY / LATERAL modifiers
+	if tokens[i].Is("only") || tokens[i].Is("lateral") {
+		i++
+	}
+	if i >= len(tokens) || (tokens[i].Kind != TokenWord && tokens[i].Kind != TokenQuotedIdentifier) {
+		// Subqueries and other expressions
+		return TableRef{}, skipToListEnd(tokens, i), false
+	}
+	if tokens[i].Kind == TokenWord && clauseKeywords[strings.ToLower(tokens[i].Text)] {
+		return TableRef{}, i, false
+	}
+
+	name := tokens[i]
+	next := i + 1
+	qualified := false
+	for next+1 < len(tokens) && tokens[next].Text == "." {
+		qualified = true
+		name = tokens[next+1]
+		next += 2
+	}
+
+	// Table functions such as generate_series(...)
+	if allowFunctions && next < len(tokens) && tokens[next].Text == "(" {
+		return TableRef{}, skipToListEnd(tokens, next), false
+	}
+
+	ref := TableRef{Name: name.Value(), Offset: tokens[i].Offset}
+
+	if next < len(tokens) && tokens[next].Is("as") {
+		next++
+	}
+	if next < len(tokens) && (tokens[next].Kind == TokenQuotedIdentifier ||
+		(tokens[next].Kind == TokenWord && !clauseKeywords[strings.ToLower(tokens[next].Text)])) {
+		ref.Alias = tokens[next].Value()
+		next++
+	}
+
+	// Objects in other schemas are not part of the introspected schema
+	return ref, next, !qualified
+}
+
+// skipToListEnd returns the index after a parenthesized group starting at i,
+// or i when there is no group
+func skipToListEnd(tokens []Token, i int) int {
+	if i >= len(tokens) || tokens[i].Text != "(" {
+		return i
+	}
+	depth := 0
+	for j := i; j < len(tokens); j++ {
+		switch tokens[j].Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				next := j + 1
+				// Derived tables carry an alias
+				if next < len(tokens) && tokens[next].Is("as") {
+					next++
+				}
+				if next < len(tokens) && tokens[next].Kind == TokenWord && !clauseKeywords[strings.ToLower(tokens[next].Text)] {
+					next++
+				}
+				return next
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// insideFunctionCall reports whether tokens[i] sits directly inside a
+// function call such as EXTRACT(year FROM col) rather than a subquery
+func insideFunctionCall(tokens []Token, i int) bool {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch tokens[j].Text {
+		case ")":
+			depth++
+		case "(":
+			if depth > 0 {
+				depth--
+				continue
+			}
+			// The group is a subquery when it starts with SELECT
+			if j+1 < len(tokens) && (tokens[j+1].Is("select") || tokens[j+1].Is("with")) {
+				return false
+			}
+			return j > 0 && tokens[j-1].Kind == TokenWord
+		}
+	}
+	return false
+}
+
+// cteNames returns the lower-cased names defined by WITH clauses
+func cteNames(tokens []Token) map[string]bool {
+	names := make(map[string]bool)
+	hasWith := false
+	for _, token := range tokens {
+		if token.Is("with") {
+			hasWith = true
+			break
+		}
+	}
+	if !hasWith {
+		return names
+	}
+
+	for i := 0; i+1 < len(tokens); i++ {
+		if tokens[i].Kind != TokenWord && tokens[i].Kind != TokenQuotedIdentifier {
+			continue
+		}
+		next := i + 1
+		// name (col1, col2) AS (...)
+		if tokens[next].Text == "(" {
+			depth := 0
+			for ; next < len(tokens); next++ {
+				if tokens[next].Text == "(" {
+					depth++
+				} else if tokens[next].Text == ")" {
+					depth--
+					if depth == 0 {
+						break
+					}
+				}
+			}
+			next++
+		}
+		if next < len(tokens) && tokens[next].Is("as") {
+			next++
+			// PostgreSQL's AS [NOT] MATERIALIZED (...)
+			if next < len(tokens) && tokens[next].Is("not") {
+				next++
+			}
+			if next < len(tokens) && tokens[next].Is("materialized") {
+				next++
+			}
+			if next < len(tokens) && tokens[next].Text == "(" {
+				names[strings.ToLower(tokens[i].Value())] = true
+			}
+		}
+	}
+	return names
+}
+
+// isSystemTable reports whether name refers to a built-in catalog table
+func isSystemTable(name string, dialect string) bool {
+	lower := strings.ToLower(name)
+	switch dialect {
+	case "sqlite":
+		return strings.HasPrefix(lower, "sqlite_") || strings.HasPrefix(lower, "pragma_")
+	case "postgresql":
+		return strings.HasPrefix(lower, "pg_")
+	case "mysql":
+		return lower == "dual"
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package sqlvalidator
+
+import (
+	"errors"
+	"testing"
+)
+
+var testSchema = Schema{
+	"products":  {"id", "name", "price", "category", "created_at"},
+	"customers": {"id", "first_name", "country"},
+}
+
+func TestCheckReferencesUnknownTable(t *testing.T) {
+	err := CheckReferences("SELECT * FROM prodcts WHERE price > 1", "mysql", testSchema)
+
+	var refErr *ReferenceError
+	if !errors.As(err, &refErr) {
+		t.Fatalf("expected *ReferenceError, got %v", err)
+	}
+	if refErr.Kind != UnknownTable || refErr.Name != "prodcts" || refErr.Column != 15 {
+		t.Errorf("unexpected error: %+v", refErr)
+	}
+}
+
+func TestCheckReferencesKnownTables(t *testing.T) {
+	queries := []string{
+		"SELECT p.name FROM products p JOIN customers c ON c.id = p.id",
+		"WITH expensive AS (SELECT * FROM products) SELECT * FROM expensive",
+		"SELECT EXTRACT(year FROM created_at) FROM products",
+		"SELECT * FROM (SELECT id FROM customers) sub, products",
+		"INSERT INTO products (name) VALUES ('x')",
+		"SELECT * FROM information_schema.tables",
+	}
+	for _, query := range queries {
+		if err := CheckReferences(query, "postgresql", testSchema); err != nil {
+			t.Errorf("expected %q to pass, got %v", query, err)
+		}
+	}
+}
+
+func TestCheckReferencesSuggestsTable(t *testing.T) {
+	err := CheckReferences("SELECT * FROM prodcts", "sqlite", testSchema)
+
+	var refErr *ReferenceError
+	if !errors.As(err, &refErr) || refErr.Suggestion != "products" {
+		t.Fatalf("expected suggestion 'products', got %v", err)
+	}
+}
+
+func TestCheckReferencesUnknownColumn(t *testing.T) {
+	tests := []struct {
+		query      string
+		name       string
+		suggestion string
+	}{
+		{"SELECT pricee FROM products", "pricee", "price"},
+		{"SELECT p.nme FROM products p JOIN customers c ON c.id = p.id", "nme", "name"},
+		{"UPDATE products SET category = 'x' WHERE prize > 10", "prize", "price"},
+		{"SELECT id FROM customers WHERE shoe_size > 40", "shoe_size", ""},
+	}
+	for _, test := range tests {
+		err := CheckReferences(test.query, "postgresql", testSchema)
+
+		var refErr *ReferenceError
+		if !errors.As(err, &refErr) {
+			t.Errorf("%q: expected *ReferenceError, got %v", test.query, err)
+			continue
+		}
+		if refErr.Kind != UnknownColumn || refErr.Name != test.name || refErr.Suggestion != test.suggestion {
+			t.Errorf("%q: unexpected error: %+v", test.query, refErr)
+		}
+	}
+}
+
+func TestCheckReferencesKnownColumns(t *testing.T) {
+	queries := []string{
+		"SELECT name, price * 2 AS doubled FROM products ORDER BY doubled DESC",
+		"SELECT category, COUNT(*) total FROM products GROUP BY category",
+		"SELECT * FROM products WHERE created_at > DATE '2024-01-01' AND name LIKE 'a%'",
+		"SELECT c.first_name, p.name FROM customers c, products p",
+		"SELECT x FROM products, generate_series(1, 3) x",
+		"CREATE TABLE t (a INT); SELECT a FROM t",
+		"ALTER TABLE products ADD COLUMN stock INT; SELECT stock FROM products",
+		"SELECT CAST(price AS numeric) FROM products WHERE id = $1",
+		"INSERT INTO products (name, price) VALUES ('x', 1) ON CONFLICT (name) DO UPDATE SET price = excluded.price",
+	}
+	for _, query := range queries {
+		if err := CheckReferences(query, "postgresql", testSchema); err != nil {
+			t.Errorf("expected %q to pass, got %v", query, err)
+		}
+	}
+}
+
+func TestClosestMatch(t *testing.T) {
+	candidates := []string{"price", "name", "category"}
+	if got := closestMatch("Pric", candidates); got != "price" {
+		t.Errorf("expected 'price', got %q", got)
+	}
+	if got := closestMatch("weight", candidates); got != "" {
+		t.Errorf("expected no match, got %q", got)
+	}
+}
+
+func TestRenameTables(t *testing.T) {
+	names := map[string]string{"totals": "s1_totals"}
+
+	sql, renamed := RenameTables("SELECT t.* FROM Totals t JOIN other.totals o ON o.id = t.id", "postgresql", names)
+	if sql != `SELECT t.* FROM "s1_totals" t JOIN other.totals o ON o.id = t.id` || len(renamed) != 1 {
+		t.Errorf("unexpected rename %q, %v", sql, renamed)
+	}
+
+	input := "WITH totals AS (SELECT 1 AS n) SELECT n FROM totals"
+	if sql, renamed := RenameTables(input, "postgresql", names); sql != input || len(renamed) != 0 {
+		t.Errorf("expected CTEs to shadow views, got %q", sql)
+	}
+}
@@ -0,0 +1,67 @@
+package sqlvalidator
+
+import (
+	"fmt"
+)
+
+// Rewrite describes a change the server made to a statement before running
+// it, so users can see why the executed SQL differs from what they typed
+type Rewrite struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// rewriter changes a statement and reports whether it did
+type rewriter struct {
+	name        string
+	description string
+	apply       func(sql string, dialect string) (string, bool)
+	// Whether the rewrite only bounds the rows a response shows, which a
+	// download bounds by reading its cursor instead
+	displayOnly bool
+}
+
+// Rewrites applied, in order, to statements before they are executed
+var executionRewriters = []rewriter{
+	{
+		name:        "row_limit",
+		description: fmt.Sprintf("Added LIMIT %d to a SELECT without a row limit", DefaultRowLimit),
+		apply:       HasLimitForSelect,
+		displayOnly: true,
+	},
+	{
+		name:        "recursion_cap",
+		description: fmt.Sprintf("Capped recursive CTEs at %d rows", MaxRecursiveRows),
+		apply:       CapRecursion,
+	},
+}
+
+// RewriteForExecution applies the server's rewrites to a statement and
+// returns the SQL to execute along with the rewrites that changed it
+func RewriteForExecution(sql string, dialect string) (string, []Rewrite) {
+	return rewrite(sql, dialect, false)
+}
+
+// RewriteForExport applies the server's rewrites to a statement whose rows
+// are downloaded, leaving out the row limit of displayed results
+func RewriteForExport(sql string, dialect string) (string, []Rewrite) {
+	return rewrite(sql, dialect, true)
+}
+
+// rewrite applies the execution rewriters, skipping the display-only ones
+// for downloads
+func rewrite(sql string, dialect string, export bool) (string, []Rewrite) {
+	applied := []Rewrite{}
+	for _, r := range executionRewriters {
+		if export && r.displayOnly {
+			continue
+		}
+		rewritten, changed := r.apply(sql, dialect)
+		if !changed {
+			continue
+		}
+		sql = rewritten
+		applied = append(applied, Rewrite{Name: r.name, Description: r.description})
+	}
+	return sql, applied
+}
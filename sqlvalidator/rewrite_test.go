@@ -0,0 +1,33 @@
+package sqlvalidator
+
+import "testing"
+
+func TestRewriteForExecutionAddsRowLimit(t *testing.T) {
+	sql, rewrites := RewriteForExecution("SELECT * FROM users;", "sqlite")
+	if sql != "SELECT * FROM users LIMIT 100;" {
+		t.Errorf("unexpected SQL %q", sql)
+	}
+	if len(rewrites) != 1 || rewrites[0].Name != "row_limit" {
+		t.Errorf("expected the row_limit rewrite, got %+v", rewrites)
+	}
+}
+
+func TestRewriteForExecutionLeavesOtherStatements(t *testing.T) {
+	for _, input := range []string{
+		"SELECT * FROM users LIMIT 5",
+		"UPDATE users SET name = 'x' WHERE id = 1",
+		"INSERT INTO users (name) SELECT name FROM staff",
+	} {
+		sql, rewrites := RewriteForExecution(input, "postgresql")
+		if sql != input || len(rewrites) != 0 {
+			t.Errorf("expected %q to be unchanged, got %q with %+v", input, sql, rewrites)
+		}
+	}
+}
+
+func TestRewriteForExportLeavesRowsUnlimited(t *testing.T) {
+	sql, rewrites := RewriteForExport("SELECT * FROM users;", "sqlite")
+	if sql != "SELECT * FROM users;" || len(rewrites) != 0 {
+		t.Errorf("expected the download to be unlimited, got %q with %+v", sql, rewrites)
+	}
+}
@@ -0,0 +1,245 @@
+package sqlvalidator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of stored routines and views
+const (
+	RoutineProcedure        = "procedure"
+	RoutineFunction         = "function"
+	RoutineTrigger          = "trigger"
+	RoutineView             = "view"
+	RoutineMaterializedView = "materialized view"
+	// Tables saved from query results; never parsed from user statements
+	RoutineTable = "table"
+)
+
+// Routine kinds each dialect can create
+var routineKinds = map[string][]string{
+	"mysql":      {RoutineProcedure, RoutineFunction, RoutineTrigger, RoutineView},
+	"postgresql": {RoutineProcedure, RoutineFunction, RoutineTrigger, RoutineView, RoutineMaterializedView},
+	"sqlite":     {RoutineTrigger, RoutineView},
+}
+
+// Words that may appear between CREATE and the routine kind
+var routineModifiers = words("or replace temp temporary constraint recursive")
+
+// Languages PostgreSQL routines may be written in; the others can reach
+// the server's file system or run native code
+var trustedLanguages = words("sql plpgsql")
+
+// Keywords that start a control-flow construct in a routine body
+var controlFlowKeywords = words("if case loop while repeat for")
+
+// RoutineStatement is a CREATE or DROP of a stored procedure, function,
+// trigger or view, or a REFRESH of a materialized view
+type RoutineStatement struct {
+	Create  bool
+	Refresh bool
+	Kind    string
+	Name    string
+	// Table a trigger is attached to, when the statement names it
+	Table string
+	// The name as written in the statement
+	NameToken Token
+	// Statements and control-flow constructs in the routine body
+	Complexity int
+	// Objects the body of a created routine or view uses
+	References ObjectReferences
+}
+
+// ParseRoutineStatement recognizes statements that create or drop a stored
+// routine or view and refreshes of materialized views. It returns nil for
+// other statements and an error for routine statements the playground does
+// not allow, such as schema-qualified names, DEFINER clauses or untrusted
+// languages.
+func ParseRoutineStatement(sql string, dialect string) (*RoutineStatement, error) {
+	tokens := withoutComments(Tokenize(sql, dialect))
+	if len(tokens) < 2 {
+		return nil, nil
+	}
+
+	var stmt RoutineStatement
+	i := 1
+	switch {
+	case tokens[0].Is("create"):
+		stmt.Create = true
+		i = skipCreateModifiers(tokens, i)
+		if tokenAt(tokens, i).Is("definer") {
+			return nil, errors.New("DEFINER clauses are not allowed")
+		}
+	case tokens[0].Is("drop"):
+	case tokens[0].Is("refresh"):
+		stmt.Refresh = true
+	default:
+		return nil, nil
+	}
+
+	kind := strings.ToLower(tokenAt(tokens, i).Text)
+	if kind == "materialized" && tokenAt(tokens, i+1).Is("view") {
+		kind = RoutineMaterializedView
+		i++
+	}
+	switch {
+	case tokenAt(tokens, i).Kind != TokenWord:
+		return nil, nil
+	case stmt.Refresh && kind != RoutineMaterializedView:
+		return nil, nil
+	case kind != RoutineProcedure && kind != RoutineFunction && kind != RoutineTrigger &&
+		kind != RoutineView && kind != RoutineMaterializedView:
+		return nil, nil
+	}
+	stmt.Kind = kind
+	if !containsString(routineKinds[dialect], kind) {
+		return nil, fmt.Errorf("%s does not support creating a %s", dialect, kind)
+	}
+	i++
+
+	// REFRESH MATERIALIZED VIEW CONCURRENTLY
+	if stmt.Refresh && tokenAt(tokens, i).Is("concurrently") {
+		i++
+	}
+
+	// IF [NOT] EXISTS
+	if tokenAt(tokens, i).Is("if") {
+		i++
+		if tokenAt(tokens, i).Is("not") {
+			i++
+		}
+		if !tokenAt(tokens, i).Is("exists") {
+			return nil, errors.New("expected EXISTS after IF")
+		}
+		i++
+	}
+
+	name := tokenAt(tokens, i)
+	if name.Kind != TokenWord && name.Kind != TokenQuotedIdentifier {
+		return nil, fmt.Errorf("expected a %s name", kind)
+	}
+	if tokenAt(tokens, i+1).Text == "." {
+		return nil, errors.New("routine and view names cannot be qualified with a schema or database")
+	}
+	if !stmt.Create && tokenAt(tokens, i+1).Text == "," {
+		return nil, fmt.Errorf("drop one %s at a time", kind)
+	}
+	stmt.NameToken = name
+	stmt.Name = name.Value()
+	if name.Kind == TokenWord && dialect == "postgresql" {
+		stmt.Name = strings.ToLower(stmt.Name)
+	}
+
+	body := tokens[i+1:]
+	if kind == RoutineTrigger {
+		stmt.Table = triggerTable(body)
+	}
+	if !stmt.Create {
+		return &stmt, nil
+	}
+	stmt.References = objectReferences(body)
+	if strings.HasSuffix(kind, RoutineView) {
+		// Views have no body beyond their query
+		return &stmt, nil
+	}
+
+	if err := checkRoutineBody(body, dialect); err != nil {
+		return nil, err
+	}
+	stmt.Complexity = routineComplexity(body)
+	return &stmt, nil
+}
+
+// skipCreateModifiers returns the index of the first token after CREATE
+// that is not a modifier such as OR REPLACE or MySQL's ALGORITHM = MERGE
+// and SQL SECURITY INVOKER
+func skipCreateModifiers(tokens []Token, i int) int {
+	for i < len(tokens) {
+		switch {
+		case tokens[i].Kind == TokenWord && routineModifiers[strings.ToLower(tokens[i].Text)]:
+			i++
+		case tokens[i].Is("algorithm") && tokenAt(tokens, i+1).Text == "=":
+			i += 3
+		case tokens[i].Is("sql") && tokenAt(tokens, i+1).Is("security"):
+			i += 3
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// triggerTable returns the table named after the first ON of a trigger
+// statement
+func triggerTable(tokens []Token) string {
+	for i, token := range tokens {
+		if !token.Is("on") {
+			continue
+		}
+		table := tokenAt(tokens, i+1)
+		if tokenAt(tokens, i+2).Text == "." {
+			table = tokenAt(tokens, i+3)
+		}
+		return table.Value()
+	}
+	return ""
+}
+
+// checkRoutineBody rejects routines that load native code or use untrusted
+// languages
+func checkRoutineBody(tokens []Token, dialect string) error {
+	for i, token := range tokens {
+		switch {
+		case token.Is("soname"):
+			return errors.New("loadable functions are not allowed")
+		case token.Is("language") && dialect == "postgresql":
+			language := strings.ToLower(tokenAt(tokens, i+1).Value())
+			if tokenAt(tokens, i+1).Kind == TokenString {
+				language = strings.ToLower(strings.Trim(language, "'"))
+			}
+			if !trustedLanguages[language] {
+				return fmt.Errorf("routines in language %s are not allowed", language)
+			}
+		}
+	}
+	return nil
+}
+
+// routineComplexity counts the statements and control-flow constructs in
+// a routine body. END IF and similar closing words are not counted.
+func routineComplexity(tokens []Token) int {
+	complexity := 0
+	for i, token := range tokens {
+		switch {
+		case token.Text == ";":
+			complexity++
+		case token.Kind == TokenWord && controlFlowKeywords[strings.ToLower(token.Text)] && !tokenAt(tokens, i-1).Is("end"):
+			complexity++
+		}
+	}
+	return complexity
+}
+
+// splitPolicyTargets returns the parts of sql the safety policy checks one
+// by one: each statement of a routine body, so the semicolons between them
+// are not mistaken for stacked statements, and otherwise sql itself
+func splitPolicyTargets(sql string, dialect string) []string {
+	if stmt, err := ParseRoutineStatement(sql, dialect); stmt == nil || err != nil || !stmt.Create {
+		return []string{sql}
+	}
+
+	targets := []string{}
+	start := 0
+	for _, token := range Tokenize(sql, dialect) {
+		if token.Text != ";" {
+			continue
+		}
+		targets = append(targets, sql[start:token.Offset])
+		start = token.Offset + 1
+	}
+	if strings.TrimSpace(sql[start:]) != "" {
+		targets = append(targets, sql[start:])
+	}
+	return targets
+}
@@ -0,0 +1,123 @@
+package sqlvalidator
+
+import "testing"
+
+func TestParseRoutineStatement(t *testing.T) {
+	tests := []struct {
+		sql     string
+		dialect string
+		create  bool
+		kind    string
+		name    string
+		table   string
+	}{
+		{"CREATE PROCEDURE add_order(IN total INT) BEGIN INSERT INTO orders (total) VALUES (total); END", "mysql", true, RoutineProcedure, "add_order", ""},
+		{"CREATE OR REPLACE FUNCTION Double(x int) RETURNS int AS $$ SELECT x * 2 $$ LANGUAGE sql", "postgresql", true, RoutineFunction, "double", ""},
+		{"CREATE TRIGGER IF NOT EXISTS audit AFTER INSERT ON main.pets BEGIN SELECT 1; END", "sqlite", true, RoutineTrigger, "audit", "pets"},
+		{"DROP TRIGGER IF EXISTS `audit`", "mysql", false, RoutineTrigger, "audit", ""},
+		{"DROP FUNCTION double(int)", "postgresql", false, RoutineFunction, "double", ""},
+	}
+	for _, test := range tests {
+		stmt, err := ParseRoutineStatement(test.sql, test.dialect)
+		if err != nil || stmt == nil {
+			t.Errorf("ParseRoutineStatement(%q) = %v, %v", test.sql, stmt, err)
+			continue
+		}
+		if stmt.Create != test.create || stmt.Kind != test.kind || stmt.Name != test.name || stmt.Table != test.table {
+			t.Errorf("ParseRoutineStatement(%q) = %+v", test.sql, stmt)
+		}
+	}
+}
+
+func TestParseRoutineStatementIgnoresOtherStatements(t *testing.T) {
+	for _, sql := range []string{
+		"CREATE TABLE trigger_log (id INT)",
+		"DROP TABLE procedures",
+		"SELECT * FROM functions",
+	} {
+		if stmt, err := ParseRoutineStatement(sql, "mysql"); stmt != nil || err != nil {
+			t.Errorf("expected %q not to be a routine statement, got %+v, %v", sql, stmt, err)
+		}
+	}
+}
+
+func TestParseRoutineStatementRejectsUnsafeRoutines(t *testing.T) {
+	tests := []struct {
+		sql     string
+		dialect string
+	}{
+		{"CREATE DEFINER = root PROCEDURE p() SELECT 1", "mysql"},
+		{"CREATE PROCEDURE other_db.p() SELECT 1", "mysql"},
+		{"CREATE FUNCTION f RETURNS STRING SONAME 'udf.so'", "mysql"},
+		{"CREATE FUNCTION f() RETURNS int AS 'lib', 'f' LANGUAGE c", "postgresql"},
+		{"CREATE FUNCTION f() RETURNS int AS $$ return 1 $$ LANGUAGE plpython3u", "postgresql"},
+		{"CREATE PROCEDURE p() BEGIN SELECT 1; END", "sqlite"},
+	}
+	for _, test := range tests {
+		if _, err := ParseRoutineStatement(test.sql, test.dialect); err == nil {
+			t.Errorf("expected %q to be rejected", test.sql)
+		}
+	}
+}
+
+func TestRoutineComplexity(t *testing.T) {
+	stmt, err := ParseRoutineStatement(`CREATE PROCEDURE p(n INT)
+BEGIN
+	IF n > 0 THEN
+		UPDATE counters SET value = value + n;
+	END IF;
+	WHILE n > 0 DO
+		SET n = n - 1;
+	END WHILE;
+END`, "mysql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two control-flow constructs and four statements
+	if stmt.Complexity != 6 {
+		t.Errorf("expected complexity 6, got %d", stmt.Complexity)
+	}
+}
+
+func TestRoutineBodiesAreNotStackedStatements(t *testing.T) {
+	sql := "CREATE TRIGGER audit AFTER UPDATE ON pets BEGIN INSERT INTO log (pet) VALUES (NEW.id); UPDATE stats SET updates = updates + 1 WHERE id = 1; END"
+	if result := IsSafeDDLOperation(sql, "sqlite"); !result.Safe {
+		t.Errorf("expected the trigger to be allowed, got %+v", result)
+	}
+
+	sql = "CREATE TRIGGER wipe AFTER INSERT ON pets BEGIN DELETE FROM pets WHERE 1=1; END"
+	if result := IsSafeDDLOperation(sql, "sqlite"); result.Safe || result.Rule != "delete_all_rows" {
+		t.Errorf("expected rules to apply inside the body, got %+v", result)
+	}
+}
+
+func TestParseViewStatements(t *testing.T) {
+	tests := []struct {
+		sql     string
+		dialect string
+		kind    string
+		name    string
+		refresh bool
+	}{
+		{"CREATE OR REPLACE ALGORITHM = MERGE SQL SECURITY INVOKER VIEW cheap AS SELECT * FROM products WHERE price < 10", "mysql", RoutineView, "cheap", false},
+		{"CREATE MATERIALIZED VIEW IF NOT EXISTS Totals AS SELECT country, COUNT(*) FROM customers GROUP BY country", "postgresql", RoutineMaterializedView, "totals", false},
+		{"REFRESH MATERIALIZED VIEW CONCURRENTLY totals", "postgresql", RoutineMaterializedView, "totals", true},
+		{"DROP VIEW IF EXISTS cheap", "sqlite", RoutineView, "cheap", false},
+	}
+	for _, test := range tests {
+		stmt, err := ParseRoutineStatement(test.sql, test.dialect)
+		if err != nil || stmt == nil {
+			t.Errorf("ParseRoutineStatement(%q) = %v, %v", test.sql, stmt, err)
+			continue
+		}
+		if stmt.Kind != test.kind || stmt.Name != test.name || stmt.Refresh != test.refresh {
+			t.Errorf("ParseRoutineStatement(%q) = %+v", test.sql, stmt)
+		}
+	}
+
+	for _, sql := range []string{"CREATE MATERIALIZED VIEW totals AS SELECT 1", "DROP VIEW a, b"} {
+		if _, err := ParseRoutineStatement(sql, "sqlite"); err == nil {
+			t.Errorf("expected %q to be rejected", sql)
+		}
+	}
+}
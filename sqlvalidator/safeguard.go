@@ -2,94 +2,102 @@ package sqlvalidator
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+// SET GLOBAL and SET @@global. on MySQL, with any spacing
+var setGlobalRegex = regexp.MustCompile(`\bset\s+(global\b|@@global\.)`)
+
 // SafetyCheckResult represents the result of a safety check
 type SafetyCheckResult struct {
 	Safe  bool
 	Error string
+	// Name of the policy rule that blocked the statement
+	Rule string
+	// Set when the statement may run once the user confirms it
+	RequiresConfirmation bool
+	// Messages of matching warn-only policy rules
+	Warnings []string
 }
 
-// IsSafeDDLOperation checks if a Data Definition Language (DDL) operation is safe
+// IsSafeDDLOperation checks if a Data Definition Language (DDL) operation is
+// safe. Patterns are matched with comments and string contents masked, so
+// neither hides a statement nor triggers a rule.
 func IsSafeDDLOperation(sql string, dialect string) SafetyCheckResult {
-	sqlLower := strings.ToLower(sql)
-
-	// Explicitly block dangerous operations
-	blockedPatterns := []struct {
-		pattern string
-		message string
-	}{
-		{`drop\s+(database|schema|user)`, "DROP DATABASE/SCHEMA/USER operations are not allowed"},
-		{`truncate\s+database`, "TRUNCATE DATABASE operations are not allowed"},
-		{`delete\s+from\s+(user|users|permission|permissions|role|roles|account|accounts)`, "DELETE operations on sensitive tables are not allowed"},
-		{`alter\s+user`, "ALTER USER operations are not allowed"},
-		{`grant\s+all`, "GRANT ALL operations are not allowed"},
-		{`revoke\s+all`, "REVOKE ALL operations are not allowed"},
-		{`shutdown`, "SHUTDOWN operations are not allowed"},
-		{`create\s+(database|schema)`, "CREATE DATABASE/SCHEMA operations are not allowed"},
-		{`drop\s+table`, "DROP TABLE operations are not allowed in this playground"},
-		{`alter\s+table\s+\w+\s+drop\s+column`, "ALTER TABLE DROP COLUMN operations are not allowed"},
-		{`delete\s+from\s+\w+\s+where\s+1\s*=\s*1`, "DELETE all records operations are not allowed"},
-		{`update\s+\w+\s+set\s+.+where\s+1\s*=\s*1`, "UPDATE all records operations are not allowed"},
-		{`(;|--)\s*(drop|delete|update|insert|alter|create)`, "SQL injection attempts are not allowed"},
-	}
-
-	for _, blockedPattern := range blockedPatterns {
-		matched, err := regexp.MatchString(blockedPattern.pattern, sqlLower)
-		if err != nil {
-			continue // Skip this pattern if there's a regex error
-		}
-		if matched {
+	sqlLower := strings.ToLower(MaskSQL(sql, dialect))
+
+	// Apply the configured safety policy; blocking rules take precedence
+	// over rules that ask for confirmation
+	var warnings []string
+	var confirm *PolicyRule
+	targets := splitPolicyTargets(sql, dialect)
+	for _, rule := range EffectiveRules(dialect) {
+		if !rule.matchesAny(targets, dialect) {
+			continue
+		}
+		switch rule.Severity {
+		case SeverityWarn:
+			warnings = append(warnings, rule.Message)
+		case SeverityConfirm:
+			if confirm == nil {
+				matched := rule
+				confirm = &matched
+			}
+		default:
 			return SafetyCheckResult{
 				Safe:  false,
-				Error: blockedPattern.message,
+				Error: rule.Message,
+				Rule:  rule.Name,
 			}
 		}
 	}
 
 	// Restrict operations based on dialect
+	var result SafetyCheckResult
 	switch dialect {
 	case "sqlite":
-		return verifySQLiteSafety(sqlLower)
+		result = verifySQLiteSafety(sqlLower)
 	case "mysql":
-		return verifyMySQLSafety(sqlLower)
+		result = verifyMySQLSafety(sql, sqlLower)
 	case "postgresql":
-		return verifyPostgreSQLSafety(sqlLower)
+		result = verifyPostgreSQLSafety(sql, sqlLower)
+	case "mock":
+		// Mock queries never reach a database
+		result = SafetyCheckResult{Safe: true}
 	default:
 		return SafetyCheckResult{
 			Safe:  false,
 			Error: "Unsupported SQL dialect",
 		}
 	}
-}
-
-// verifySQLiteSafety checks if an operation is safe for SQLite
-func verifySQLiteSafety(sqlLower string) SafetyCheckResult {
-	// Protect against PRAGMA changes that could modify database behavior
-	if strings.Contains(sqlLower, "pragma") &&
-		(strings.Contains(sqlLower, "journal_mode") ||
-			strings.Contains(sqlLower, "synchronous") ||
-			strings.Contains(sqlLower, "secure_delete")) {
-		return SafetyCheckResult{
-			Safe:  false,
-			Error: "PRAGMA statements that modify database settings are not allowed",
-		}
+	if !result.Safe {
+		return result
 	}
-
-	// Restrict attaching other databases
-	if strings.Contains(sqlLower, "attach database") {
+	if confirm != nil {
 		return SafetyCheckResult{
-			Safe:  false,
-			Error: "ATTACH DATABASE operations are not allowed",
+			Safe:                 false,
+			Error:                confirm.Message,
+			Rule:                 confirm.Name,
+			RequiresConfirmation: true,
+			Warnings:             warnings,
 		}
 	}
+	result.Warnings = warnings
+	return result
+}
 
+// verifySQLiteSafety checks if an operation is safe for SQLite. ATTACH,
+// PRAGMA writes and tables outside the allowlist are denied by the
+// authorizer of the sandboxed connections, where comments, quoting or
+// spacing cannot hide them.
+func verifySQLiteSafety(sqlLower string) SafetyCheckResult {
 	return SafetyCheckResult{Safe: true}
 }
 
-// verifyMySQLSafety checks if an operation is safe for MySQL
-func verifyMySQLSafety(sqlLower string) SafetyCheckResult {
+// verifyMySQLSafety checks if an operation is safe for MySQL. sqlLower is
+// the lower-cased statement with comments and strings masked.
+func verifyMySQLSafety(sql string, sqlLower string) SafetyCheckResult {
 	// Block system table modifications
 	if (strings.Contains(sqlLower, "mysql.") ||
 		strings.Contains(sqlLower, "information_schema.") ||
@@ -105,19 +113,26 @@ func verifyMySQLSafety(sqlLower string) SafetyCheckResult {
 	}
 
 	// Block system variable changes
-	if strings.Contains(sqlLower, "set global") ||
-		strings.Contains(sqlLower, "set @@global") {
+	if setGlobalRegex.MatchString(sqlLower) {
 		return SafetyCheckResult{
 			Safe:  false,
 			Error: "Setting global variables is not allowed",
 		}
 	}
 
+	if leavesNamespace(sql, "mysql") {
+		return SafetyCheckResult{
+			Safe:  false,
+			Error: "Switching to another database is not allowed",
+		}
+	}
+
 	return SafetyCheckResult{Safe: true}
 }
 
-// verifyPostgreSQLSafety checks if an operation is safe for PostgreSQL
-func verifyPostgreSQLSafety(sqlLower string) SafetyCheckResult {
+// verifyPostgreSQLSafety checks if an operation is safe for PostgreSQL.
+// sqlLower is the lower-cased statement with comments and strings masked.
+func verifyPostgreSQLSafety(sql string, sqlLower string) SafetyCheckResult {
 	// Block system catalog modifications
 	if strings.Contains(sqlLower, "pg_") &&
 		(strings.Contains(sqlLower, "insert") ||
@@ -146,46 +161,180 @@ func verifyPostgreSQLSafety(sqlLower string) SafetyCheckResult {
 		}
 	}
 
+	if leavesNamespace(sql, "postgresql") {
+		return SafetyCheckResult{
+			Safe:  false,
+			Error: "Changing the schema search path is not allowed",
+		}
+	}
+
 	return SafetyCheckResult{Safe: true}
 }
 
-// HasLimitForSelect checks if SELECT statements have a LIMIT clause
-// and adds a default limit if necessary
-func HasLimitForSelect(sql string) (string, bool) {
-	trimmed := strings.TrimSpace(sql)
-	sqlLower := strings.ToLower(trimmed)
+// leavesNamespace reports whether sql moves the session out of the
+// playground's namespace: USE on MySQL, and SET search_path, SET SCHEMA or
+// set_config('search_path', ...) on PostgreSQL. Unqualified names resolve
+// in the namespace only while the connection's default stays in place.
+func leavesNamespace(sql string, dialect string) bool {
+	tokens := withoutComments(Tokenize(sql, dialect))
+	statementStart := true
+	for i, token := range tokens {
+		if token.Text == ";" {
+			statementStart = true
+			continue
+		}
+		first := statementStart
+		statementStart = false
+
+		switch dialect {
+		case "mysql":
+			if first && token.Is("use") {
+				return true
+			}
+		case "postgresql":
+			if first && token.Is("set") {
+				next := i + 1
+				if next < len(tokens) && (tokens[next].Is("session") || tokens[next].Is("local")) {
+					next++
+				}
+				if next < len(tokens) && (tokens[next].Is("search_path") || tokens[next].Is("schema")) {
+					return true
+				}
+			}
+			if token.Is("set_config") && i+2 < len(tokens) && tokens[i+1].Text == "(" &&
+				strings.EqualFold(strings.Trim(tokens[i+2].Text, "'"), "search_path") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Row limit added to SELECT statements that have none
+const DefaultRowLimit = 100
+
+// HasLimitForSelect adds a LIMIT of DefaultRowLimit rows to every query in
+// sql whose outermost SELECT is unbounded, and reports whether it changed
+// anything. Limits inside subqueries and CTEs do not count, an existing
+// LIMIT or FETCH FIRST is kept, and the clause is placed before OFFSET and
+// locking clauses and ahead of trailing comments and semicolons.
+func HasLimitForSelect(sql string, dialect string) (string, bool) {
+	tokens := Tokenize(sql, dialect)
+	insertAt := []int{}
 
-	// If it's not a SELECT statement, no change needed
-	if !strings.HasPrefix(sqlLower, "select") {
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && tokens[i].Text != ";" {
+			continue
+		}
+		if offset, ok := limitInsertOffset(withoutComments(tokens[start:i])); ok {
+			insertAt = append(insertAt, offset)
+		}
+		start = i + 1
+	}
+	if len(insertAt) == 0 {
 		return sql, false
 	}
 
-	// Check if LIMIT is already present (case insensitive)
-	limitRegex := regexp.MustCompile(`\blimit\b`)
-	if limitRegex.MatchString(sqlLower) {
-		return trimmed, false
+	var out strings.Builder
+	last := 0
+	for _, offset := range insertAt {
+		out.WriteString(sql[last:offset])
+		out.WriteString(" LIMIT " + strconv.Itoa(DefaultRowLimit))
+		last = offset
 	}
+	out.WriteString(sql[last:])
+	return out.String(), true
+}
 
-	// Preserve trailing semicolon if present
-	hasSemicolon := strings.HasSuffix(trimmed, ";")
-	if hasSemicolon {
-		trimmed = strings.TrimSuffix(trimmed, ";")
+// limitInsertOffset returns where a LIMIT clause belongs in a statement,
+// or false if the statement is not an unbounded query
+func limitInsertOffset(tokens []Token) (int, bool) {
+	if !isQuery(tokens) {
+		return 0, false
+	}
+
+	end := len(tokens)
+	depth := 0
+	for i, token := range tokens {
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth != 0 {
+			continue
+		}
+		switch {
+		case token.Is("limit"), token.Is("fetch"):
+			return 0, false
+		case (token.Is("offset") || token.Is("for")) && end == len(tokens):
+			end = i
+		}
+	}
+	if end == 0 {
+		return 0, false
 	}
 
-	// Add a default LIMIT of 100 rows
-	modifiedSQL := trimmed + " LIMIT 100"
-	if hasSemicolon {
-		modifiedSQL += ";"
+	last := tokens[end-1]
+	return last.Offset + len(last.Text), true
+}
+
+// isQuery reports whether a statement is a SELECT, possibly behind WITH
+// clauses or wrapped in parentheses
+func isQuery(tokens []Token) bool {
+	if len(tokens) == 0 {
+		return false
 	}
 
-	return modifiedSQL, true
+	switch {
+	case tokens[0].Is("select"):
+		return true
+	case tokens[0].Text == "(":
+		for _, token := range tokens {
+			if token.Kind == TokenWord {
+				return token.Is("select")
+			}
+		}
+	case tokens[0].Is("with"):
+		// The LIMIT belongs to the statement after the CTEs, never inside
+		// their bodies
+		return parseWithClause(tokens).MainKind == "select"
+	}
+	return false
 }
 
-// SanitizeIdentifiers ensures that table and column identifiers are properly quoted
-func SanitizeIdentifiers(sql string, dialect string) string {
-	// This is a simplified version. In reality, this would require a proper SQL parser
-	// to correctly identify and quote all identifiers.
+// Functions whose calls change state, so a query calling them must not be
+// run twice
+var sideEffectFunctions = words("nextval setval set_config pg_advisory_lock pg_advisory_xact_lock " +
+	"pg_try_advisory_lock pg_advisory_unlock pg_terminate_backend pg_cancel_backend dblink_exec " +
+	"get_lock release_lock release_all_locks")
 
-	// For the playground purposes, just ensure basic safety
-	return sql
+// IsRetryableQuery reports whether every statement in sql is a SELECT that
+// only reads, so running it again after a transient error cannot change
+// anything: no data-modifying CTEs, no SELECT INTO and no calls of
+// functions such as nextval that change state
+func IsRetryableQuery(sql string, dialect string) bool {
+	statements := splitTokenStatements(withoutComments(Tokenize(sql, dialect)))
+	if len(statements) == 0 {
+		return false
+	}
+	for _, statement := range statements {
+		if !isQuery(statement) {
+			return false
+		}
+		if clause := parseWithClause(statement); clause != nil && clause.Writes() {
+			return false
+		}
+		for i, token := range statement {
+			if token.Kind != TokenWord {
+				continue
+			}
+			if token.Is("into") || sideEffectFunctions[strings.ToLower(token.Text)] && tokenAt(statement, i+1).Text == "(" {
+				return false
+			}
+		}
+	}
+	return true
 }
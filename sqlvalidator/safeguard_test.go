@@ -3,7 +3,7 @@ package sqlvalidator
 import "testing"
 
 func TestHasLimitForSelectAddsLimit(t *testing.T) {
-	got, added := HasLimitForSelect("SELECT * FROM test")
+	got, added := HasLimitForSelect("SELECT * FROM test", "sqlite")
 	want := "SELECT * FROM test LIMIT 100"
 	if !added || got != want {
 		t.Errorf("expected %q with added=true, got %q and added=%v", want, got, added)
@@ -11,7 +11,7 @@ func TestHasLimitForSelectAddsLimit(t *testing.T) {
 }
 
 func TestHasLimitForSelectWithSemicolon(t *testing.T) {
-	got, added := HasLimitForSelect("SELECT * FROM test;")
+	got, added := HasLimitForSelect("SELECT * FROM test;", "sqlite")
 	want := "SELECT * FROM test LIMIT 100;"
 	if !added || got != want {
 		t.Errorf("expected %q with added=true, got %q and added=%v", want, got, added)
@@ -20,7 +20,7 @@ func TestHasLimitForSelectWithSemicolon(t *testing.T) {
 
 func TestHasLimitForSelectAlreadyHasLimit(t *testing.T) {
 	query := "SELECT * FROM test LIMIT 10;"
-	got, added := HasLimitForSelect(query)
+	got, added := HasLimitForSelect(query, "sqlite")
 	if added || got != query {
 		t.Errorf("expected original query unchanged, got %q and added=%v", got, added)
 	}
@@ -28,8 +28,102 @@ func TestHasLimitForSelectAlreadyHasLimit(t *testing.T) {
 
 func TestHasLimitForSelectParameterLimit(t *testing.T) {
 	query := "SELECT * FROM test LIMIT ?;"
-	got, added := HasLimitForSelect(query)
+	got, added := HasLimitForSelect(query, "sqlite")
 	if added || got != query {
 		t.Errorf("expected original query unchanged, got %q and added=%v", got, added)
 	}
 }
+
+func TestHasLimitForSelectDialectAware(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		sql     string
+		want    string
+	}{
+		{"trailing comment", "sqlite", "SELECT * FROM test -- all rows", "SELECT * FROM test LIMIT 100 -- all rows"},
+		{"semicolon in string", "mysql", "SELECT * FROM test WHERE name = 'a;b';", "SELECT * FROM test WHERE name = 'a;b' LIMIT 100;"},
+		{"limit only in subquery", "postgresql", "SELECT * FROM (SELECT * FROM test LIMIT 5) t",
+			"SELECT * FROM (SELECT * FROM test LIMIT 5) t LIMIT 100"},
+		{"cte", "postgresql", "WITH recent AS (SELECT * FROM test LIMIT 5) SELECT * FROM recent",
+			"WITH recent AS (SELECT * FROM test LIMIT 5) SELECT * FROM recent LIMIT 100"},
+		{"cte without limit", "postgresql", "WITH recent AS (SELECT * FROM test) SELECT * FROM recent",
+			"WITH recent AS (SELECT * FROM test) SELECT * FROM recent LIMIT 100"},
+		{"data-modifying cte", "postgresql", "WITH gone AS (DELETE FROM test WHERE id < 5 RETURNING *) SELECT * FROM gone",
+			"WITH gone AS (DELETE FROM test WHERE id < 5 RETURNING *) SELECT * FROM gone LIMIT 100"},
+		{"offset", "mysql", "SELECT * FROM test ORDER BY id OFFSET 10", "SELECT * FROM test ORDER BY id LIMIT 100 OFFSET 10"},
+		{"locking clause", "postgresql", "SELECT * FROM test FOR UPDATE", "SELECT * FROM test LIMIT 100 FOR UPDATE"},
+		{"union", "sqlite", "SELECT id FROM a UNION SELECT id FROM b", "SELECT id FROM a UNION SELECT id FROM b LIMIT 100"},
+		{"several statements", "sqlite", "SELECT 1; DELETE FROM test; SELECT 2 LIMIT 1",
+			"SELECT 1 LIMIT 100; DELETE FROM test; SELECT 2 LIMIT 1"},
+	}
+
+	for _, tt := range tests {
+		got, added := HasLimitForSelect(tt.sql, tt.dialect)
+		if !added || got != tt.want {
+			t.Errorf("%s: expected %q with added=true, got %q and added=%v", tt.name, tt.want, got, added)
+		}
+	}
+}
+
+func TestHasLimitForSelectLeavesOtherStatements(t *testing.T) {
+	queries := []string{
+		"SELECT * FROM test FETCH FIRST 5 ROWS ONLY",
+		"WITH old AS (SELECT id FROM test) DELETE FROM test WHERE id IN (SELECT id FROM old)",
+		"UPDATE test SET value = 1",
+		"-- SELECT * FROM test",
+	}
+
+	for _, query := range queries {
+		if got, added := HasLimitForSelect(query, "postgresql"); added || got != query {
+			t.Errorf("expected %q unchanged, got %q and added=%v", query, got, added)
+		}
+	}
+}
+
+func TestSafetyBlocksLeavingNamespace(t *testing.T) {
+	tests := []struct {
+		dialect string
+		sql     string
+		blocked bool
+	}{
+		{"mysql", "USE mysql", true},
+		{"mysql", "SELECT 1; /* next */ use `information_schema`", true},
+		{"mysql", "SELECT * FROM users WHERE name = 'use'", false},
+		{"postgresql", "SET search_path TO public", true},
+		{"postgresql", "set session search_path = other, public", true},
+		{"postgresql", "SET SCHEMA 'public'", true},
+		{"postgresql", "SELECT set_config('search_path', 'public', false)", true},
+		{"postgresql", "SET LOCAL statement_timeout = 1000", false},
+		{"postgresql", "UPDATE users SET search_path = 'x' WHERE id = 1", false},
+	}
+	for _, tt := range tests {
+		result := IsSafeDDLOperation(tt.sql, tt.dialect)
+		if result.Safe == tt.blocked {
+			t.Errorf("%s %q: expected blocked=%v, got %+v", tt.dialect, tt.sql, tt.blocked, result)
+		}
+	}
+}
+
+func TestIsRetryableQuery(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT * FROM products": true,
+		"SELECT 1; (SELECT 2)":   true,
+		"WITH recent AS (SELECT * FROM orders) SELECT * FROM recent":        true,
+		"SELECT * FROM accounts FOR UPDATE":                                 true,
+		"SELECT 'nextval(x)' AS label":                                      true,
+		"UPDATE products SET price = 1":                                     false,
+		"SELECT 1; DELETE FROM products":                                    false,
+		"WITH gone AS (DELETE FROM orders RETURNING id) SELECT * FROM gone": false,
+		"SELECT * INTO archive FROM orders":                                 false,
+		"SELECT id FROM orders INTO OUTFILE '/tmp/orders.csv'":              false,
+		"SELECT nextval('orders_id_seq')":                                   false,
+		"SELECT GET_LOCK('job', 10)":                                        false,
+		"":                                                                  false,
+	}
+	for sql, want := range tests {
+		if got := IsRetryableQuery(sql, "postgresql"); got != want {
+			t.Errorf("IsRetryableQuery(%q) = %v, want %v", sql, got, want)
+		}
+	}
+}
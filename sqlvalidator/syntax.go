@@ -0,0 +1,281 @@
+package sqlvalidator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	_ "github.com/mattn/go-sqlite3"
+	pg_query "github.com/pganalyze/pg_query_go/v6"
+	pgparser "github.com/pganalyze/pg_query_go/v6/parser"
+
+	"example/user/playground/sqlsplit"
+)
+
+// SyntaxError is a parse error with the location of the offending token.
+// Line and Column are 1-based, and zero when the location is unknown.
+type SyntaxError struct {
+	Message string `json:"message"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Token   string `json:"token"`
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Line == 0 {
+		if e.Token == "" {
+			return e.Message
+		}
+		return fmt.Sprintf("%s near %q", e.Message, e.Token)
+	}
+	if e.Token == "" {
+		return fmt.Sprintf("%s (line %d, column %d)", e.Message, e.Line, e.Column)
+	}
+	return fmt.Sprintf("%s near %q (line %d, column %d)", e.Message, e.Token, e.Line, e.Column)
+}
+
+var (
+	// at or near "token"
+	postgresNearRegex = regexp.MustCompile(`at or near "((?s).*)"$`)
+
+	// near "token": syntax error
+	sqliteSyntaxRegex = regexp.MustCompile(`near "([^"]*)": syntax error`)
+
+	// Most prefixes of a statement prepared to find the token a SQLite
+	// syntax error is near
+	maxSQLiteErrorProbes = 100
+
+	// Empty in-memory SQLite database used only to parse statements
+	sqliteParser     *sql.DB
+	sqliteParserErr  error
+	sqliteParserOnce sync.Once
+)
+
+// checkSyntax parses sql with the parser of the given dialect and returns a
+// *SyntaxError describing the first syntax error, or nil
+func checkSyntax(sql string, dialect string) error {
+	switch dialect {
+	case "postgresql":
+		return checkPostgreSQLSyntax(sql)
+	case "sqlite":
+		return checkSQLiteSyntax(sql)
+	case "mysql", "mock":
+		return checkLexicalSyntax(sql)
+	}
+	return nil
+}
+
+// checkPostgreSQLSyntax parses sql with libpg_query, the PostgreSQL server parser
+func checkPostgreSQLSyntax(sql string) error {
+	_, err := pg_query.Parse(sql)
+	if err == nil {
+		return nil
+	}
+
+	var parseErr *pgparser.Error
+	if !errors.As(err, &parseErr) {
+		return &SyntaxError{Message: err.Error(), Line: 1, Column: 1}
+	}
+
+	syntaxErr := &SyntaxError{Message: parseErr.Message}
+	if m := postgresNearRegex.FindStringSubmatch(parseErr.Message); m != nil {
+		syntaxErr.Token = m[1]
+		syntaxErr.Message = strings.TrimSpace(strings.TrimSuffix(parseErr.Message, m[0]))
+	}
+	syntaxErr.Line, syntaxErr.Column = lineColumnFromCharPos(sql, parseErr.Cursorpos)
+	return syntaxErr
+}
+
+// checkSQLiteSyntax prepares each statement against an empty in-memory
+// database. SQLite parses a statement completely before resolving names, so
+// only syntax errors are reported; unknown tables and columns are ignored.
+func checkSQLiteSyntax(query string) error {
+	parser, err := sqliteParserDB()
+	if err != nil {
+		return nil
+	}
+
+	for _, stmt := range sqlsplit.Split(query, "sqlite") {
+		prepared, err := parser.Prepare(stmt.Text)
+		if err == nil {
+			prepared.Close()
+			continue
+		}
+
+		message := err.Error()
+		if !strings.Contains(message, "syntax error") && !strings.Contains(message, "incomplete input") {
+			continue
+		}
+
+		syntaxErr := &SyntaxError{Message: "syntax error"}
+		m := sqliteSyntaxRegex.FindStringSubmatch(message)
+		if m == nil {
+			syntaxErr.Message = "incomplete input"
+			syntaxErr.Line, syntaxErr.Column = lineColumn(query, stmt.Offset+len(stmt.Text))
+			return syntaxErr
+		}
+		syntaxErr.Token = m[1]
+		if offset, ok := locateSQLiteError(parser, stmt.Text, m[1]); ok {
+			syntaxErr.Line, syntaxErr.Column = lineColumn(query, stmt.Offset+offset)
+		}
+		return syntaxErr
+	}
+	return nil
+}
+
+// SQLiteErrorOffset returns the byte offset in query of the token a SQLite
+// syntax error is reported near. The driver does not expose the offset
+// SQLite keeps, so it is found by parsing; ok is false when the token
+// cannot be told apart from others with the same text.
+func SQLiteErrorOffset(query string, token string) (offset int, ok bool) {
+	parser, err := sqliteParserDB()
+	if err != nil {
+		return 0, false
+	}
+	for _, stmt := range sqlsplit.Split(query, "sqlite") {
+		prepared, err := parser.Prepare(stmt.Text)
+		if err == nil {
+			prepared.Close()
+			continue
+		}
+		if m := sqliteSyntaxRegex.FindStringSubmatch(err.Error()); m != nil && m[1] == token {
+			if offset, ok := locateSQLiteError(parser, stmt.Text, token); ok {
+				return stmt.Offset + offset, true
+			}
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// locateSQLiteError returns the offset of the occurrence of token that a
+// statement's syntax error is near. SQLite stops at the first token it
+// cannot parse, so that occurrence is the first one where the statement cut
+// just after it fails near the same token; earlier occurrences leave a
+// valid or merely incomplete statement.
+func locateSQLiteError(parser *sql.DB, stmt string, token string) (int, bool) {
+	if token == "" {
+		return 0, false
+	}
+	from := 0
+	for probe := 0; probe < maxSQLiteErrorProbes; probe++ {
+		index := strings.Index(stmt[from:], token)
+		if index < 0 {
+			return 0, false
+		}
+		index += from
+		prepared, err := parser.Prepare(stmt[:index+len(token)])
+		if err == nil {
+			prepared.Close()
+		} else if m := sqliteSyntaxRegex.FindStringSubmatch(err.Error()); m != nil && m[1] == token {
+			return index, true
+		}
+		from = index + 1
+	}
+	return 0, false
+}
+
+// sqliteParserDB returns the empty in-memory database statements are
+// parsed with
+func sqliteParserDB() (*sql.DB, error) {
+	sqliteParserOnce.Do(func() {
+		sqliteParser, sqliteParserErr = sql.Open("sqlite3", "file::memory:?mode=memory")
+	})
+	return sqliteParser, sqliteParserErr
+}
+
+// checkLexicalSyntax reports unterminated strings, quoted identifiers and
+// comments and unbalanced parentheses. It is used for MySQL, for which no
+// embeddable parser is available; full syntax errors surface on execution.
+// The mock dialect accepts any SQL flavour and uses it as well.
+func checkLexicalSyntax(sql string) error {
+	var opened []int
+	var quote byte
+	quoteStart := 0
+
+	for i := 0; i < len(sql); i++ {
+		ch := sql[i]
+
+		if quote != 0 {
+			if ch == '\\' && quote != '`' {
+				i++
+				continue
+			}
+			if ch == quote {
+				if i+1 < len(sql) && sql[i+1] == quote {
+					i++
+					continue
+				}
+				quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case ch == '\'' || ch == '"' || ch == '`':
+			quote = ch
+			quoteStart = i
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"), ch == '#':
+			if end := strings.IndexByte(sql[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(sql)
+			}
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				line, column := lineColumn(sql, i)
+				return &SyntaxError{Message: "unterminated comment", Line: line, Column: column, Token: "/*"}
+			}
+			i += end + 3
+		case ch == '(':
+			opened = append(opened, i)
+		case ch == ')':
+			if len(opened) == 0 {
+				line, column := lineColumn(sql, i)
+				return &SyntaxError{Message: "unmatched closing parenthesis", Line: line, Column: column, Token: ")"}
+			}
+			opened = opened[:len(opened)-1]
+		}
+	}
+
+	if quote != 0 {
+		line, column := lineColumn(sql, quoteStart)
+		return &SyntaxError{Message: "unterminated quoted string", Line: line, Column: column, Token: string(quote)}
+	}
+	if len(opened) > 0 {
+		line, column := lineColumn(sql, opened[len(opened)-1])
+		return &SyntaxError{Message: "unclosed parenthesis", Line: line, Column: column, Token: "("}
+	}
+	return nil
+}
+
+// lineColumn converts a byte offset in sql into a 1-based line and column
+func lineColumn(sql string, offset int) (int, int) {
+	if offset > len(sql) {
+		offset = len(sql)
+	}
+	before := sql[:offset]
+	line := strings.Count(before, "\n") + 1
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// lineColumnFromCharPos converts a 1-based character position into a line and column
+func lineColumnFromCharPos(sql string, pos int) (int, int) {
+	if pos < 1 {
+		return 1, 1
+	}
+	chars := 0
+	for offset := range sql {
+		chars++
+		if chars == pos {
+			return lineColumn(sql, offset)
+		}
+	}
+	return lineColumn(sql, len(sql))
+}
@@ -0,0 +1,60 @@
+package sqlvalidator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateReportsPostgreSQLSyntaxLocation(t *testing.T) {
+	_, err := Validate("SELECT *\nFORM customers", "postgresql")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if syntaxErr.Line != 2 || syntaxErr.Column != 1 || syntaxErr.Token != "FORM" {
+		t.Errorf("expected FORM at line 2 column 1, got %+v", syntaxErr)
+	}
+}
+
+func TestValidateReportsSQLiteSyntaxLocation(t *testing.T) {
+	_, err := Validate("SELECT 1;\nSELECT * FROM test_data WHER id = 1", "sqlite")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if syntaxErr.Line != 2 || syntaxErr.Token != "id" {
+		t.Errorf("expected token id on line 2, got %+v", syntaxErr)
+	}
+}
+
+func TestValidateReportsTheSQLiteTokenThatFailed(t *testing.T) {
+	_, err := Validate("SELECT a FROM t WHERE a = 1 1", "sqlite")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if syntaxErr.Line != 1 || syntaxErr.Column != 29 || syntaxErr.Token != "1" {
+		t.Errorf("expected the second 1 at column 29, got %+v", syntaxErr)
+	}
+}
+
+func TestValidateIgnoresUnknownSQLiteTables(t *testing.T) {
+	if valid, err := Validate("SELECT * FROM test_data", "sqlite"); !valid {
+		t.Errorf("expected valid query, got %v", err)
+	}
+}
+
+func TestValidateReportsUnclosedParenthesisForMySQL(t *testing.T) {
+	_, err := Validate("SELECT COUNT(* FROM products", "mysql")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected *SyntaxError, got %v", err)
+	}
+	if syntaxErr.Column != 13 {
+		t.Errorf("expected column 13, got %+v", syntaxErr)
+	}
+}
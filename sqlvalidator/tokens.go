@@ -0,0 +1,152 @@
+package sqlvalidator
+
+import (
+	"strings"
+)
+
+// TokenKind classifies a lexical token
+type TokenKind string
+
+const (
+	TokenWord             TokenKind = "word"
+	TokenQuotedIdentifier TokenKind = "quoted_identifier"
+	TokenString           TokenKind = "string"
+	TokenNumber           TokenKind = "number"
+	TokenPunctuation      TokenKind = "punctuation"
+	TokenComment          TokenKind = "comment"
+)
+
+// Token is a lexical token and its byte offset in the SQL text
+type Token struct {
+	Kind   TokenKind `json:"kind"`
+	Text   string    `json:"text"`
+	Offset int       `json:"offset"`
+}
+
+// Value returns the token text with identifier quotes removed
+func (t Token) Value() string {
+	if t.Kind == TokenQuotedIdentifier && len(t.Text) >= 2 {
+		return t.Text[1 : len(t.Text)-1]
+	}
+	return t.Text
+}
+
+// Is reports whether the token is the given keyword, ignoring case
+func (t Token) Is(keyword string) bool {
+	return t.Kind == TokenWord && strings.EqualFold(t.Text, keyword)
+}
+
+// Tokenize splits sql into tokens. Backticks quote identifiers for MySQL,
+// double quotes quote identifiers everywhere except MySQL. Comments are
+// returned as TokenComment so callers can skip or keep them.
+func Tokenize(sql string, dialect string) []Token {
+	tokens := []Token{}
+
+	for i := 0; i < len(sql); {
+		ch := sql[i]
+		start := i
+
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+			continue
+
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"), ch == '#' && dialect == "mysql":
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+			tokens = append(tokens, Token{Kind: TokenComment, Text: sql[start:i], Offset: start})
+
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 4
+			}
+			tokens = append(tokens, Token{Kind: TokenComment, Text: sql[start:i], Offset: start})
+
+		case ch == '\'':
+			i = scanQuoted(sql, i, '\'', dialect == "mysql")
+			tokens = append(tokens, Token{Kind: TokenString, Text: sql[start:i], Offset: start})
+
+		case ch == '"' && dialect == "mysql":
+			i = scanQuoted(sql, i, '"', true)
+			tokens = append(tokens, Token{Kind: TokenString, Text: sql[start:i], Offset: start})
+
+		case ch == '"' || ch == '`' || (ch == '[' && dialect == "sqlite"):
+			closing := ch
+			if ch == '[' {
+				closing = ']'
+			}
+			i = scanQuoted(sql, i, closing, false)
+			tokens = append(tokens, Token{Kind: TokenQuotedIdentifier, Text: sql[start:i], Offset: start})
+
+		case isDigit(ch) || (ch == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			i++
+			for i < len(sql) && (isDigit(sql[i]) || sql[i] == '.' || sql[i] == 'e' || sql[i] == 'E') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: TokenNumber, Text: sql[start:i], Offset: start})
+
+		case isWordChar(ch) || ch >= 0x80 || ch == '@' || ch == '$':
+			i++
+			for i < len(sql) && (isWordChar(sql[i]) || sql[i] >= 0x80 || sql[i] == '$') {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: TokenWord, Text: sql[start:i], Offset: start})
+
+		default:
+			i++
+			// Keep common two-character operators together
+			if i < len(sql) {
+				switch sql[start : i+1] {
+				case "<=", ">=", "<>", "!=", "||", "::", "->", "=>":
+					i++
+				}
+			}
+			tokens = append(tokens, Token{Kind: TokenPunctuation, Text: sql[start:i], Offset: start})
+		}
+	}
+
+	return tokens
+}
+
+// withoutComments returns the tokens that are not comments
+func withoutComments(tokens []Token) []Token {
+	result := make([]Token, 0, len(tokens))
+	for _, token := range tokens {
+		if token.Kind != TokenComment {
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// scanQuoted returns the offset just past the quoted section starting at
+// start. Doubled closing characters are escapes; backslash escapes are
+// honoured when backslashEscapes is set.
+func scanQuoted(sql string, start int, closing byte, backslashEscapes bool) int {
+	for i := start + 1; i < len(sql); i++ {
+		if backslashEscapes && sql[i] == '\\' {
+			i++
+			continue
+		}
+		if sql[i] == closing {
+			if i+1 < len(sql) && sql[i+1] == closing {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(sql)
+}
+
+// isDigit reports whether ch is an ASCII digit
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,119 @@
+package sqlvalidator
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kinds of transaction control statements
+const (
+	TxBegin      = "begin"
+	TxCommit     = "commit"
+	TxRollback   = "rollback"
+	TxSavepoint  = "savepoint"
+	TxRollbackTo = "rollback_to"
+	TxRelease    = "release"
+)
+
+// Words that may follow BEGIN, COMMIT or ROLLBACK without changing what
+// the statement does to the transaction
+var transactionNoise = words("work transaction deferred immediate exclusive")
+
+// TransactionControl is a statement that starts or ends a transaction or
+// manages one of its savepoints
+type TransactionControl struct {
+	Kind string
+	// Savepoint set, rolled back to or released; unquoted names are
+	// lowered since every dialect matches them case-insensitively
+	Savepoint string
+}
+
+// ParseTransactionControl recognizes BEGIN, START TRANSACTION, COMMIT, END,
+// ROLLBACK, ABORT, SAVEPOINT, ROLLBACK TO [SAVEPOINT] and RELEASE
+// [SAVEPOINT]. Other statements return nil.
+func ParseTransactionControl(sql string, dialect string) *TransactionControl {
+	tokens := withoutComments(Tokenize(sql, dialect))
+	for len(tokens) > 0 && tokens[len(tokens)-1].Text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 || tokens[0].Kind != TokenWord {
+		return nil
+	}
+
+	keyword := func(i int) string {
+		if i < len(tokens) && tokens[i].Kind == TokenWord {
+			return strings.ToLower(tokens[i].Text)
+		}
+		return ""
+	}
+	// savepointAt reads the savepoint name that ends the statement at i
+	savepointAt := func(i int, kind string) *TransactionControl {
+		if keyword(i) == "savepoint" {
+			i++
+		}
+		if i != len(tokens)-1 {
+			return nil
+		}
+		name := tokens[i].Value()
+		switch tokens[i].Kind {
+		case TokenWord:
+			name = strings.ToLower(name)
+		case TokenQuotedIdentifier:
+		default:
+			return nil
+		}
+		return &TransactionControl{Kind: kind, Savepoint: name}
+	}
+
+	switch keyword(0) {
+	case "begin":
+		return &TransactionControl{Kind: TxBegin}
+	case "start":
+		if keyword(1) == "transaction" {
+			return &TransactionControl{Kind: TxBegin}
+		}
+	case "commit", "end":
+		return &TransactionControl{Kind: TxCommit}
+	case "abort":
+		return &TransactionControl{Kind: TxRollback}
+	case "rollback":
+		i := 1
+		for transactionNoise[keyword(i)] {
+			i++
+		}
+		if keyword(i) == "to" {
+			return savepointAt(i+1, TxRollbackTo)
+		}
+		return &TransactionControl{Kind: TxRollback}
+	case "savepoint":
+		return savepointAt(1, TxSavepoint)
+	case "release":
+		return savepointAt(1, TxRelease)
+	}
+	return nil
+}
+
+// SavepointStatement returns the statement setting, rolling back to or
+// releasing a savepoint, with its name quoted as the dialect needs it.
+// Every supported dialect accepts SAVEPOINT, ROLLBACK TO SAVEPOINT and
+// RELEASE SAVEPOINT.
+func SavepointStatement(kind string, name string, dialect string) (string, error) {
+	capabilities, ok := Capabilities(dialect)
+	if !ok {
+		return "", errors.New("unsupported SQL dialect")
+	}
+	if strings.TrimSpace(name) == "" || len(name) > 64 {
+		return "", errors.New("savepoint names must have 1 to 64 characters")
+	}
+	name = capabilities.Quote(name)
+
+	switch kind {
+	case TxSavepoint:
+		return "SAVEPOINT " + name, nil
+	case TxRollbackTo:
+		return "ROLLBACK TO SAVEPOINT " + name, nil
+	case TxRelease:
+		return "RELEASE SAVEPOINT " + name, nil
+	}
+	return "", errors.New("savepoint operations are savepoint, rollback_to and release")
+}
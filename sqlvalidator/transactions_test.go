@@ -0,0 +1,63 @@
+package sqlvalidator
+
+import "testing"
+
+func TestParseTransactionControl(t *testing.T) {
+	tests := []struct {
+		sql       string
+		dialect   string
+		kind      string
+		savepoint string
+	}{
+		{"BEGIN", "postgresql", TxBegin, ""},
+		{"begin immediate transaction;", "sqlite", TxBegin, ""},
+		{"START TRANSACTION ISOLATION LEVEL SERIALIZABLE", "postgresql", TxBegin, ""},
+		{"COMMIT WORK", "mysql", TxCommit, ""},
+		{"END", "postgresql", TxCommit, ""},
+		{"-- undo\nROLLBACK", "mysql", TxRollback, ""},
+		{"SAVEPOINT Before_Update", "postgresql", TxSavepoint, "before_update"},
+		{`SAVEPOINT "Mixed"`, "postgresql", TxSavepoint, "Mixed"},
+		{"ROLLBACK TO s1", "postgresql", TxRollbackTo, "s1"},
+		{"ROLLBACK WORK TO SAVEPOINT `s1`", "mysql", TxRollbackTo, "s1"},
+		{"RELEASE SAVEPOINT s1", "sqlite", TxRelease, "s1"},
+		{"RELEASE s1", "postgresql", TxRelease, "s1"},
+	}
+	for _, tt := range tests {
+		got := ParseTransactionControl(tt.sql, tt.dialect)
+		if got == nil || got.Kind != tt.kind || got.Savepoint != tt.savepoint {
+			t.Errorf("ParseTransactionControl(%q) = %+v, want %s %q", tt.sql, got, tt.kind, tt.savepoint)
+		}
+	}
+
+	for _, sql := range []string{"SELECT 1", "SET TRANSACTION ISOLATION LEVEL READ COMMITTED", "SAVEPOINT", "RELEASE SAVEPOINT a b"} {
+		if got := ParseTransactionControl(sql, "postgresql"); got != nil {
+			t.Errorf("expected %q not to be transaction control, got %+v", sql, got)
+		}
+	}
+}
+
+func TestSavepointStatement(t *testing.T) {
+	tests := []struct {
+		kind    string
+		name    string
+		dialect string
+		want    string
+	}{
+		{TxSavepoint, "s1", "postgresql", "SAVEPOINT s1"},
+		{TxRollbackTo, "order", "mysql", "ROLLBACK TO SAVEPOINT `order`"},
+		{TxRelease, "Before Update", "sqlite", `RELEASE SAVEPOINT "Before Update"`},
+	}
+	for _, tt := range tests {
+		got, err := SavepointStatement(tt.kind, tt.name, tt.dialect)
+		if err != nil || got != tt.want {
+			t.Errorf("SavepointStatement(%s, %q, %s) = %q, %v; want %q", tt.kind, tt.name, tt.dialect, got, err, tt.want)
+		}
+	}
+
+	if _, err := SavepointStatement(TxCommit, "s1", "mysql"); err == nil {
+		t.Error("expected an error for an operation other than a savepoint one")
+	}
+	if _, err := SavepointStatement(TxSavepoint, " ", "mysql"); err == nil {
+		t.Error("expected an error for an empty savepoint name")
+	}
+}
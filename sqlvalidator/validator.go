@@ -5,7 +5,8 @@ import (
 	"strings"
 )
 
-// Validate checks if the SQL query is valid for the given dialect
+// Validate checks if the SQL query is valid for the given dialect.
+// Syntax errors are returned as *SyntaxError with the error location.
 func Validate(sql string, dialect string) (bool, error) {
 	// Trim whitespace
 	sql = strings.TrimSpace(sql)
@@ -15,12 +16,18 @@ func Validate(sql string, dialect string) (bool, error) {
 		return false, errors.New("SQL query cannot be empty")
 	}
 
-	// Run safety checks
+	// Run safety checks; statements that only need confirmation are left to
+	// the caller
 	safetyCheck := IsSafeDDLOperation(sql, dialect)
-	if !safetyCheck.Safe {
+	if !safetyCheck.Safe && !safetyCheck.RequiresConfirmation {
 		return false, errors.New(safetyCheck.Error)
 	}
 
+	// Parse the statement to report syntax errors with their location
+	if err := checkSyntax(sql, strings.ToLower(dialect)); err != nil {
+		return false, err
+	}
+
 	// Dialect-specific validation
 	switch strings.ToLower(dialect) {
 	case "mysql":
@@ -29,6 +36,9 @@ func Validate(sql string, dialect string) (bool, error) {
 		return validatePostgreSQL(sql)
 	case "sqlite":
 		return validateSQLite(sql)
+	case "mock":
+		// Any statement gets a synthesized result
+		return true, nil
 	default:
 		return false, errors.New("unsupported SQL dialect")
 	}
@@ -0,0 +1,228 @@
+document.addEventListener('DOMContentLoaded', function() {
+    // How often the dashboard refreshes, in milliseconds
+    const refreshInterval = 5000;
+
+    const api = '/api/v1';
+
+    // Escape text for insertion into HTML
+    function escapeHtml(text) {
+        const div = document.createElement('div');
+        div.textContent = text == null ? '' : String(text);
+        return div.innerHTML;
+    }
+
+    // Fetch JSON from the admin API, failing with the server's error message
+    function fetchJSON(path, options) {
+        return fetch(api + path, options).then(response =>
+            response.json().then(data => {
+                if (!response.ok) {
+                    throw new Error(data.error || response.statusText);
+                }
+                return data;
+            })
+        );
+    }
+
+    function showError(error) {
+        const banner = document.getElementById('dashboard-error');
+        if (error) {
+            banner.textContent = error.message;
+            banner.classList.remove('hidden');
+        } else {
+            banner.classList.add('hidden');
+        }
+    }
+
+    function fillTable(id, rows, empty, columns) {
+        const body = document.getElementById(id);
+        if (rows.length === 0) {
+            body.innerHTML = `<tr><td colspan="${columns}" class="text-gray-500 py-1">${empty}</td></tr>`;
+            return;
+        }
+        body.innerHTML = rows.join('');
+    }
+
+    function row(cells) {
+        return '<tr class="border-t">' + cells.map(cell => `<td class="py-1 pr-3 align-top">${cell}</td>`).join('') + '</tr>';
+    }
+
+    function sqlCell(sql) {
+        return `<code class="font-mono text-xs break-all">${escapeHtml(sql)}</code>`;
+    }
+
+    function formatMs(ms) {
+        return ms >= 1000 ? (ms / 1000).toFixed(1) + ' s' : Math.round(ms) + ' ms';
+    }
+
+    function loadConnections() {
+        return Promise.all([fetchJSON('/db-status'), fetchJSON('/pool-stats')]).then(([statuses, stats]) => {
+            const pools = {};
+            (stats.pools || []).forEach(pool => { pools[pool.backend] = pool; });
+
+            const rows = Object.keys(statuses).sort().map(backend => {
+                const status = statuses[backend];
+                const pool = pools[backend] || {};
+                const healthy = status.connected && (!status.health || status.health.healthy);
+                const latency = status.health && status.health.latency_ms != null ? formatMs(status.health.latency_ms) : '';
+                return row([
+                    escapeHtml(backend),
+                    `<span class="${healthy ? 'text-green-700' : 'text-red-700'}">${escapeHtml(status.state || (healthy ? 'connected' : 'disconnected'))}</span>`,
+                    latency,
+                    escapeHtml(pool.open_connections),
+                    escapeHtml(pool.in_use),
+                    escapeHtml(pool.idle),
+                    escapeHtml(pool.wait_count),
+                    escapeHtml(status.last_error || status.message || ''),
+                ]);
+            });
+            fillTable('connections', rows, 'No backends', 8);
+        });
+    }
+
+    // Containers are listed only while the container_control flag is on and
+    // the server can reach the Docker socket
+    function loadContainers() {
+        const section = document.getElementById('containers-section');
+        return fetch(api + '/admin/containers').then(response => {
+            if (response.status === 404 || response.status === 503) {
+                section.classList.add('hidden');
+                return;
+            }
+            return response.json().then(data => {
+                if (!response.ok) {
+                    throw new Error(data.error || response.statusText);
+                }
+                section.classList.remove('hidden');
+                const rows = data.containers.map(container => row([
+                    escapeHtml(container.backend),
+                    escapeHtml(container.container),
+                    escapeHtml(container.error || container.status),
+                    escapeHtml(container.health || ''),
+                    container.started_at ? escapeHtml(new Date(container.started_at).toLocaleString()) : '',
+                    ['start', 'stop', 'restart'].map(action =>
+                        `<button data-backend="${escapeHtml(container.backend)}" data-action="${action}" class="text-indigo-600 hover:underline mr-2">${action}</button>`
+                    ).join(''),
+                ]));
+                fillTable('containers', rows, 'No containers', 6);
+            });
+        });
+    }
+
+    function loadExecutionQueues() {
+        return fetchJSON('/admin/execution-queues').then(data => {
+            const rows = data.queues.map(queue => row([
+                escapeHtml(queue.queue),
+                escapeHtml(queue.running),
+                escapeHtml(queue.workers),
+                Object.keys(queue.waiting).filter(priority => queue.waiting[priority] > 0)
+                    .map(priority => `${escapeHtml(queue.waiting[priority])} ${escapeHtml(priority)}`).join(', ') || '0',
+                escapeHtml(queue.capacity),
+                escapeHtml(queue.rejected),
+            ]));
+            fillTable('execution-queues', rows, 'No queries have run yet', 6);
+        });
+    }
+
+    function loadActiveQueries() {
+        return fetchJSON('/admin/queries').then(data => {
+            const rows = data.queries.map(query => row([
+                formatMs(query.elapsed_ms),
+                escapeHtml(query.dialect),
+                escapeHtml(query.session),
+                sqlCell(query.sql),
+            ]));
+            fillTable('active-queries', rows, 'No queries running', 4);
+        });
+    }
+
+    function loadSlowQueries() {
+        return fetchJSON('/slow-queries?limit=20').then(data => {
+            document.getElementById('slow-threshold').textContent = `(over ${formatMs(data.threshold_ms)})`;
+            const rows = data.queries.map(query => row([
+                escapeHtml(new Date(query.executed_at).toLocaleString()),
+                formatMs(query.duration_ms),
+                escapeHtml(query.dialect),
+                escapeHtml(query.session),
+                sqlCell(query.sql) + (query.error ? `<div class="text-red-700 text-xs">${escapeHtml(query.error)}</div>` : ''),
+            ]));
+            fillTable('slow-queries', rows, 'No slow queries', 5);
+        });
+    }
+
+    function loadFeatureFlags() {
+        return fetchJSON('/admin/features').then(data => {
+            const rows = data.flags.map(flag => row([
+                `<input type="checkbox" data-flag="${escapeHtml(flag.name)}" ${flag.enabled ? 'checked' : ''}>`,
+                `<div class="font-medium">${escapeHtml(flag.name)}</div><div class="text-gray-500">${escapeHtml(flag.description)}</div>`,
+                escapeHtml(flag.source),
+                flag.source === 'override' ? `<button data-reset="${escapeHtml(flag.name)}" class="text-indigo-600 hover:underline">Reset</button>` : '',
+            ]));
+            fillTable('feature-flags', rows, 'No feature flags', 4);
+
+            const readOnly = data.flags.find(flag => flag.name === 'read_only');
+            document.getElementById('read-only-toggle').checked = !!(readOnly && readOnly.enabled);
+        });
+    }
+
+    function loadAuditLog() {
+        return fetchJSON('/admin/audit?limit=50').then(data => {
+            const rows = data.records.map(record => row([
+                escapeHtml(new Date(record.time).toLocaleString()),
+                escapeHtml(record.actor),
+                escapeHtml(record.action),
+                escapeHtml(record.target),
+                escapeHtml(record.detail),
+            ]));
+            fillTable('audit-log', rows, 'No audit records', 5);
+        });
+    }
+
+    function refresh() {
+        Promise.all([loadConnections(), loadContainers(), loadExecutionQueues(), loadActiveQueries(), loadSlowQueries(), loadFeatureFlags(), loadAuditLog()])
+            .then(() => {
+                showError(null);
+                document.getElementById('last-refresh').textContent = 'Updated ' + new Date().toLocaleTimeString();
+            })
+            .catch(showError);
+    }
+
+    // Override a flag, or remove its override, then show the new state
+    function setFlag(name, enabled) {
+        fetchJSON(`/admin/features/${encodeURIComponent(name)}`, {
+            method: 'PUT',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ enabled: enabled }),
+        }).then(refresh).catch(showError);
+    }
+
+    function resetFlag(name) {
+        fetchJSON(`/admin/features/${encodeURIComponent(name)}`, { method: 'DELETE' })
+            .then(refresh).catch(showError);
+    }
+
+    document.getElementById('feature-flags').addEventListener('change', event => {
+        if (event.target.dataset.flag) {
+            setFlag(event.target.dataset.flag, event.target.checked);
+        }
+    });
+    document.getElementById('feature-flags').addEventListener('click', event => {
+        if (event.target.dataset.reset) {
+            resetFlag(event.target.dataset.reset);
+        }
+    });
+    document.getElementById('containers').addEventListener('click', event => {
+        const { backend, action } = event.target.dataset;
+        if (!backend || !confirm(`${action} the ${backend} container?`)) {
+            return;
+        }
+        event.target.disabled = true;
+        fetchJSON(`/admin/containers/${encodeURIComponent(backend)}/${action}`, { method: 'POST' })
+            .then(refresh).catch(showError);
+    });
+    document.getElementById('read-only-toggle').addEventListener('change', event => {
+        setFlag('read_only', event.target.checked);
+    });
+
+    refresh();
+    setInterval(refresh, refreshInterval);
+});
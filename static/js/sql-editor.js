@@ -3,14 +3,22 @@ document.addEventListener('DOMContentLoaded', function() {
     const state = {
         editor: null,
         selectedDialect: 'sqlite',
+        // Registered connection the editor runs queries on, if any
+        selectedConnection: null,
+        // Connections the logged-in user has registered; null when anonymous
+        userConnections: null,
         darkMode: localStorage.getItem('darkMode') === 'true',
         executeInProgress: false,
         lastResults: null,
+        errorMark: null,
         dbStatuses: {
-            sqlite: false,
-            mysql: false,
-            postgresql: false
+            sqlite: { connected: false },
+            mysql: { connected: false },
+            postgresql: { connected: false },
+            mock: { connected: true }
         },
+        initProgress: {},
+        eventsConnected: false,
         sortState: {
             column: null,
             direction: 'asc'
@@ -34,6 +42,11 @@ document.addEventListener('DOMContentLoaded', function() {
                 name: 'Sum of values',
                 description: 'Calculate the total sum of all values',
                 query: 'SELECT SUM(value) AS total_value FROM test_data;'
+            },
+            {
+                name: 'Hourly averages',
+                description: 'Bucket sensor readings by hour',
+                query: 'SELECT strftime(\'%Y-%m-%d %H:00\', recorded_at) AS hour,\n       location, ROUND(AVG(temperature), 2) AS avg_temperature\nFROM sensor_readings\nWHERE sensor_id = 1\nGROUP BY hour, location\nORDER BY hour\nLIMIT 24;'
             }
         ],
         mysql: [
@@ -51,6 +64,11 @@ document.addEventListener('DOMContentLoaded', function() {
                 name: 'Low stock items',
                 description: 'Find products with less than 20 items in stock',
                 query: 'SELECT * FROM products WHERE stock < 20\nORDER BY stock ASC;'
+            },
+            {
+                name: 'Daily temperatures',
+                description: 'Daily minimum, average and maximum per location',
+                query: 'SELECT DATE(recorded_at) AS day, location,\n       MIN(temperature) AS min_temp, ROUND(AVG(temperature), 2) AS avg_temp, MAX(temperature) AS max_temp\nFROM sensor_readings\nGROUP BY day, location\nORDER BY day, location\nLIMIT 50;'
             }
         ],
         postgresql: [
@@ -68,28 +86,50 @@ document.addEventListener('DOMContentLoaded', function() {
                 name: 'Search by name',
                 description: 'Find customers with "son" in their last name',
                 query: 'SELECT * FROM customers\nWHERE last_name LIKE \'%son%\';'
+            },
+            {
+                name: 'Moving average',
+                description: 'Hourly temperatures with a rolling 24 hour average',
+                query: 'SELECT date_trunc(\'hour\', recorded_at) AS hour,\n       ROUND(AVG(temperature), 2) AS avg_temp,\n       ROUND(AVG(AVG(temperature)) OVER (ORDER BY date_trunc(\'hour\', recorded_at)\n             ROWS BETWEEN 23 PRECEDING AND CURRENT ROW), 2) AS moving_avg\nFROM sensor_readings\nWHERE sensor_id = 3\nGROUP BY hour\nORDER BY hour\nLIMIT 72;'
+            }
+        ],
+        mock: [
+            {
+                name: 'Any table',
+                description: 'Generate fake rows without a database',
+                query: 'SELECT id, first_name, email, country\nFROM users\nLIMIT 5;'
+            },
+            {
+                name: 'Aggregates',
+                description: 'Aggregate queries return a single fake row',
+                query: 'SELECT COUNT(*) AS orders, SUM(total) AS revenue\nFROM orders;'
             }
         ]
     };
 
-    // Database info for SQL hints
+    // Database info for SQL hints until the live schema has loaded
     const databaseSchemas = {
         sqlite: {
-            test_data: ["id", "name", "value"]
+            test_data: ["id", "name", "value"],
+            sensor_readings: ["id", "sensor_id", "location", "recorded_at", "temperature", "humidity"]
         },
         mysql: {
-            products: ["id", "name", "description", "price", "category", "stock", "created_at"]
+            products: ["id", "name", "description", "price", "category", "stock", "created_at"],
+            sensor_readings: ["id", "sensor_id", "location", "recorded_at", "temperature", "humidity"]
         },
         postgresql: {
-            customers: ["id", "first_name", "last_name", "email", "phone", "country", "city", "address", "postal_code", "created_at"]
-        }
+            customers: ["id", "first_name", "last_name", "email", "phone", "country", "city", "address", "postal_code", "created_at"],
+            sensor_readings: ["id", "sensor_id", "location", "recorded_at", "temperature", "humidity"]
+        },
+        mock: {}
     };
 
     // Icons for each dialect
     const dialectIcons = {
         sqlite: '<i class="fas fa-file-alt mr-2"></i>',
         mysql: '<i class="fas fa-database mr-2"></i>',
-        postgresql: '<i class="fas fa-server mr-2"></i>'
+        postgresql: '<i class="fas fa-server mr-2"></i>',
+        mock: '<i class="fas fa-magic mr-2"></i>'
     };
 
     // DOM Elements
@@ -105,6 +145,7 @@ document.addEventListener('DOMContentLoaded', function() {
         csvExportBtn: document.getElementById('csvExportBtn'),
         dbConnections: document.getElementById('dbConnections'),
         sampleQueries: document.getElementById('sampleQueries'),
+        queryTemplates: document.getElementById('queryTemplates'),
         dialectBadge: document.getElementById('dialectBadge'),
         cursorPosition: document.getElementById('cursorPosition'),
         editorStats: document.getElementById('editorStats'),
@@ -113,6 +154,8 @@ document.addEventListener('DOMContentLoaded', function() {
         errorMessage: document.getElementById('errorMessage'),
         resultsContainer: document.getElementById('resultsContainer'),
         resultCount: document.getElementById('resultCount'),
+        resultSetTabs: document.getElementById('resultSetTabs'),
+        outParams: document.getElementById('outParams'),
         resultsTableHead: document.getElementById('resultsTableHead'),
         resultsTableBody: document.getElementById('resultsTableBody'),
         rowLimitWarning: document.getElementById('rowLimitWarning'),
@@ -174,7 +217,7 @@ document.addEventListener('DOMContentLoaded', function() {
     }
 
     // Execute the SQL query
-    function executeQuery() {
+    function executeQuery(confirmationToken) {
         if (state.executeInProgress) return;
         
         state.executeInProgress = true;
@@ -190,14 +233,17 @@ document.addEventListener('DOMContentLoaded', function() {
         const sql = state.editor.getValue();
         
         // Validate and execute the query
-        fetch('/api/validate-sql', {
+        fetch('/api/v1/validate-sql', {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json',
             },
             body: JSON.stringify({
                 sql: sql,
-                dialect: state.selectedDialect
+                dialect: state.selectedDialect,
+                connectionId: state.selectedConnection ? state.selectedConnection.id : undefined,
+                // Event listeners pass an event rather than a token
+                confirmationToken: typeof confirmationToken === 'string' ? confirmationToken : undefined
             }),
         })
         .then(response => {
@@ -207,16 +253,48 @@ document.addEventListener('DOMContentLoaded', function() {
             return response.json();
         })
         .then(data => {
+            // Risky statements run only after the user confirms them
+            if (data.requiresConfirmation) {
+                if (window.confirm(`${data.error}\n\nRun it anyway?`)) {
+                    setTimeout(() => executeQuery(data.confirmationToken), 0);
+                } else {
+                    showError(data.error);
+                }
+                return;
+            }
+
             if (!data.valid) {
                 showError(data.error);
+                highlightErrorPosition(data.errorPosition);
                 return;
             }
 
+            // The server changed the statement before running it
+            if (data.rewrites && data.rewrites.length > 0) {
+                const applied = data.rewrites.map(rewrite => escapeHtml(rewrite.description)).join('<br>');
+                showToast('Query rewritten', `${applied}<br><code>${escapeHtml(data.executedSql)}</code>`, 'info');
+            }
+
             if (data.error) {
                 showError(data.error);
+                highlightErrorPosition(data.errorPosition);
                 return;
             }
+
+            // Warn-only safety rules matched but the query still ran
+            (data.warnings || []).forEach(warning => {
+                showToast('Warning', warning, 'warning');
+            });
+
+            // The backend was busy with other queries for a noticeable time
+            if (data.executionStats && data.executionStats.queueWaitMs >= 1000) {
+                const wait = (data.executionStats.queueWaitMs / 1000).toFixed(1);
+                showToast('Query queued', `Waited ${wait} s for a free ${escapeHtml(data.executionStats.queue)} worker`, 'info');
+            }
             
+            renderResultSetTabs(data.resultSets || []);
+            renderOutParams(data.outParams);
+
             // Handle successful query
             if (data.result) {
                 state.lastResults = data.result;
@@ -240,6 +318,42 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
+    // Show one button per result set when a statement returned several
+    function renderResultSetTabs(resultSets) {
+        elements.resultSetTabs.innerHTML = '';
+        elements.resultSetTabs.classList.toggle('hidden', resultSets.length < 2);
+        if (resultSets.length < 2) {
+            return;
+        }
+
+        resultSets.forEach((resultSet, index) => {
+            const button = document.createElement('button');
+            button.className = 'text-xs px-2 py-1 rounded bg-gray-100 hover:bg-gray-200 dark:bg-gray-700 dark:text-gray-300 dark:hover:bg-gray-600';
+            button.textContent = `Set ${index + 1}`;
+            button.onclick = function() {
+                elements.resultSetTabs.querySelectorAll('button').forEach(other => {
+                    other.classList.remove('ring-2', 'ring-blue-400');
+                });
+                button.classList.add('ring-2', 'ring-blue-400');
+                state.lastResults = resultSet;
+                displayResults(resultSet);
+            };
+            if (index === 0) {
+                button.classList.add('ring-2', 'ring-blue-400');
+            }
+            elements.resultSetTabs.appendChild(button);
+        });
+    }
+
+    // Show the output parameters of a stored procedure call
+    function renderOutParams(outParams) {
+        const names = Object.keys(outParams || {});
+        elements.outParams.classList.toggle('hidden', names.length === 0);
+        elements.outParams.textContent = names
+            .map(name => `${name} = ${outParams[name] === null ? 'NULL' : outParams[name]}`)
+            .join('   ');
+    }
+
     // Display query results in the table
     function displayResults(result) {
         // Enable export buttons
@@ -413,6 +527,22 @@ document.addEventListener('DOMContentLoaded', function() {
             
             // Set the formatted query in the editor
             state.editor.setValue(formattedQuery);
+
+            // Let the server quote identifiers that collide with reserved words
+            fetch('/api/v1/format', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ sql: formattedQuery, dialect: state.selectedDialect })
+            })
+                .then(response => response.json())
+                .then(data => {
+                    if (data.sql && state.editor.getValue() === formattedQuery) {
+                        state.editor.setValue(data.sql);
+                    }
+                })
+                .catch(error => {
+                    console.error('Failed to quote identifiers:', error);
+                });
             
             showToast('Success', 'SQL query formatted', 'success');
         } catch (error) {
@@ -446,9 +576,10 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Change SQL dialect
     function changeDialect(dialect) {
-        if (state.selectedDialect === dialect) return;
+        if (state.selectedDialect === dialect && !state.selectedConnection) return;
         
         state.selectedDialect = dialect;
+        state.selectedConnection = null;
         elements.dialectBadge.textContent = dialect;
         
         // Update editor hints for the selected dialect
@@ -456,8 +587,11 @@ document.addEventListener('DOMContentLoaded', function() {
             tables: databaseSchemas[dialect]
         });
         
+        loadSchemaHints();
+
         // Update sample queries display
         renderSampleQueries();
+        loadQueryTemplates();
         
         // Load a default query for the selected dialect
         state.editor.setValue(sampleQueries[dialect][0].query);
@@ -466,6 +600,32 @@ document.addEventListener('DOMContentLoaded', function() {
         updateDatabaseConnectionsList();
     }
 
+    // Load a shared query when the page is opened with ?share=<id>
+    function loadSharedQuery() {
+        const shareId = new URLSearchParams(window.location.search).get('share');
+        if (!shareId) return;
+
+        fetch(`/api/v1/share/${encodeURIComponent(shareId)}`)
+            .then(response => {
+                if (!response.ok) {
+                    throw new Error('Shared query not found or expired');
+                }
+                return response.json();
+            })
+            .then(share => {
+                changeDialect(share.dialect);
+                state.editor.setValue(share.sql);
+                if (share.result && share.result.columns && share.result.columns.length > 0) {
+                    state.lastResults = share.result;
+                    displayResults(share.result);
+                }
+                showToast('Shared query', 'Loaded shared query into the editor', 'info');
+            })
+            .catch(error => {
+                showToast('Error', error.message, 'error');
+            });
+    }
+
     // Show sample query in the editor
     function loadSampleQuery(query) {
         state.editor.setValue(query);
@@ -538,8 +698,28 @@ document.addEventListener('DOMContentLoaded', function() {
         elements.errorContainer.classList.remove('hidden');
     }
 
+    // Underline the token at the position reported by the server
+    function highlightErrorPosition(position) {
+        if (!position || !position.line) return;
+
+        const line = position.line - 1;
+        const ch = Math.max((position.column || 1) - 1, 0);
+        const lineText = state.editor.getLine(line) || '';
+        const tokenEnd = lineText.slice(ch).search(/\s|$/);
+
+        state.errorMark = state.editor.markText(
+            { line: line, ch: ch },
+            { line: line, ch: ch + Math.max(tokenEnd, 1) },
+            { className: 'sql-error-token' }
+        );
+    }
+
     // Hide error message
     function hideError() {
+        if (state.errorMark) {
+            state.errorMark.clear();
+            state.errorMark = null;
+        }
         elements.errorContainer.classList.add('hidden');
         elements.errorMessage.textContent = '';
     }
@@ -579,6 +759,10 @@ document.addEventListener('DOMContentLoaded', function() {
             bgClass = 'bg-red-50 border-red-200 dark:bg-red-900/20 dark:border-red-800';
             textClass = 'text-red-800 dark:text-red-300';
             iconClass = 'text-red-500 dark:text-red-400';
+        } else if (type === 'warning') {
+            bgClass = 'bg-yellow-50 border-yellow-200 dark:bg-yellow-900/20 dark:border-yellow-800';
+            textClass = 'text-yellow-800 dark:text-yellow-300';
+            iconClass = 'text-yellow-500 dark:text-yellow-400';
         } else {
             bgClass = 'bg-blue-50 border-blue-200 dark:bg-blue-900/20 dark:border-blue-800';
             textClass = 'text-blue-800 dark:text-blue-300';
@@ -587,7 +771,7 @@ document.addEventListener('DOMContentLoaded', function() {
         
         toast.className = `toast ${bgClass} border rounded-lg shadow-lg`;
         
-        const icon = type === 'success' ? 'fa-check-circle' : type === 'error' ? 'fa-exclamation-circle' : 'fa-info-circle';
+        const icon = type === 'success' ? 'fa-check-circle' : type === 'error' ? 'fa-exclamation-circle' : type === 'warning' ? 'fa-exclamation-triangle' : 'fa-info-circle';
         
         toast.innerHTML = `
             <div class="flex p-4">
@@ -637,7 +821,7 @@ document.addEventListener('DOMContentLoaded', function() {
 
     // Check database connection status
     function checkDatabaseConnections() {
-        fetch('/api/db-status')
+        fetch('/api/v1/db-status')
             .then(response => response.json())
             .then(data => {
                 state.dbStatuses = data;
@@ -646,15 +830,122 @@ document.addEventListener('DOMContentLoaded', function() {
             .catch(error => {
                 console.error('Failed to check database status:', error);
             });
+        checkInitProgress();
+    }
+
+    // Poll startup progress until every backend has connected or given up
+    function checkInitProgress() {
+        fetch('/api/v1/init-progress')
+            .then(response => response.json())
+            .then(data => {
+                const progress = {};
+                let starting = false;
+                (data.progress || []).forEach(entry => {
+                    progress[entry.dialect] = entry;
+                    if (['pending', 'connecting', 'waiting'].includes(entry.state)) {
+                        starting = true;
+                    }
+                });
+                state.initProgress = progress;
+                updateDatabaseConnectionsList();
+                if (starting) {
+                    setTimeout(() => {
+                        fetch('/api/v1/db-status')
+                            .then(response => response.json())
+                            .then(statuses => { state.dbStatuses = statuses; })
+                            .finally(checkInitProgress);
+                    }, 2000);
+                }
+            })
+            .catch(error => {
+                console.error('Failed to check initialization progress:', error);
+            });
+    }
+
+    // Listen for server events instead of polling for status changes
+    function subscribeToEvents() {
+        if (!window.EventSource) return;
+
+        const source = new EventSource('/api/v1/events');
+        source.onopen = () => {
+            state.eventsConnected = true;
+        };
+        source.onerror = () => {
+            // The browser reconnects on its own; poll until it has
+            state.eventsConnected = false;
+        };
+
+        source.addEventListener('connection', e => {
+            const change = JSON.parse(e.data).data;
+            const previous = state.dbStatuses[change.dialect] || {};
+            if (previous.connected === change.connected) return;
+            // Fetch the full status for the new error and retry details
+            checkDatabaseConnections();
+            if (change.dialect === state.selectedDialect) {
+                showToast(change.connected ? 'Connected' : 'Connection lost',
+                    `${change.dialect} is ${change.connected ? 'back online' : 'offline'}`,
+                    change.connected ? 'success' : 'error');
+            }
+        });
+
+        source.addEventListener('query_running', e => {
+            const event = JSON.parse(e.data);
+            showToast('Query running', `Your ${event.data.dialect} query is taking a while…`, 'info');
+        });
+
+        source.addEventListener('query_done', e => {
+            const event = JSON.parse(e.data);
+            showToast('Query finished', `Finished after ${(event.data.duration_ms / 1000).toFixed(1)}s`, 'info');
+        });
+
+        source.addEventListener('schema', e => {
+            const event = JSON.parse(e.data);
+            if (event.data.dialect === state.selectedDialect) {
+                loadSchemaHints();
+                loadQueryTemplates();
+            }
+        });
+
+        source.addEventListener('seeding', e => {
+            const job = JSON.parse(e.data).data;
+            if (job.state === 'running') return;
+            showToast('Large dataset ' + job.state,
+                `${job.dialect} ${job.table}: ${job.inserted.toLocaleString()} of ${job.rows.toLocaleString()} rows` +
+                (job.error ? ` (${escapeHtml(job.error)})` : ''),
+                job.state === 'completed' ? 'success' : job.state === 'failed' ? 'error' : 'info');
+        });
+    }
+
+    // Escape text for interpolation into HTML
+    function escapeHtml(text) {
+        const div = document.createElement('div');
+        div.textContent = text;
+        return div.innerHTML.replace(/"/g, '&quot;');
     }
 
     // Update database connections list
     function updateDatabaseConnectionsList() {
         let html = '';
         
-        ['sqlite', 'mysql', 'postgresql'].forEach(dialect => {
+        ['sqlite', 'mysql', 'postgresql', 'mock'].forEach(dialect => {
             const isActive = state.selectedDialect === dialect;
-            const isConnected = state.dbStatuses[dialect];
+            const status = state.dbStatuses[dialect] || {};
+            const isConnected = status.connected;
+            const progress = state.initProgress[dialect] || status;
+            let statusText = isConnected ? 'Connected' : 'Offline';
+            if (!isConnected && progress.message && progress.state !== 'connected') {
+                statusText = progress.message;
+            }
+            // Connected backends report their server version and the
+            // features it has
+            let statusTitle = !isConnected && progress.last_error ? progress.last_error : '';
+            if (isConnected && status.version) {
+                statusText = `${status.server} ${status.version.split(/[\s-]/)[0]}`;
+                statusTitle = 'Supports: ' + Object.keys(status.capabilities || {})
+                    .filter(feature => status.capabilities[feature])
+                    .map(feature => feature.replace(/_/g, ' '))
+                    .join(', ');
+            }
             
             html += `
                 <div 
@@ -668,14 +959,42 @@ document.addEventListener('DOMContentLoaded', function() {
                         <span 
                             class="status-dot ${isConnected ? 'connected' : 'disconnected'}">
                         </span>
-                        <span class="text-xs text-gray-500 dark:text-gray-400">
-                            ${isConnected ? 'Connected' : 'Offline'}
+                        <span class="text-xs text-gray-500 dark:text-gray-400" title="${escapeHtml(statusTitle)}">
+                            ${escapeHtml(statusText)}
                         </span>
                     </div>
                 </div>
             `;
         });
         
+        // Databases the logged-in user registered
+        if (state.userConnections) {
+            state.userConnections.forEach(connection => {
+                const isActive = state.selectedConnection && state.selectedConnection.id === connection.id;
+                html += `
+                    <div 
+                        class="flex items-center justify-between p-2 rounded-md cursor-pointer hover:bg-gray-100 dark:hover:bg-gray-700 ${isActive ? 'bg-primary-50 dark:bg-primary-900/20' : ''}" 
+                        data-connection="${escapeHtml(connection.id)}" title="${escapeHtml(connection.target)}">
+                        <div class="flex items-center min-w-0">
+                            ${dialectIcons[connection.dialect]}
+                            <span class="truncate dark:text-gray-300">${escapeHtml(connection.name)}</span>
+                        </div>
+                        <div class="flex items-center text-xs text-gray-500 dark:text-gray-400">
+                            ${connection.read_only ? '<i class="fas fa-lock mr-2" title="Read-only"></i>' : ''}
+                            <button class="hover:text-red-500" data-remove-connection="${escapeHtml(connection.id)}" title="Remove connection">
+                                <i class="fas fa-times"></i>
+                            </button>
+                        </div>
+                    </div>
+                `;
+            });
+            html += `
+                <button id="addConnectionBtn" class="w-full p-2 text-sm text-left rounded-md text-gray-500 hover:bg-gray-100 dark:text-gray-400 dark:hover:bg-gray-700">
+                    <i class="fas fa-plus mr-2"></i>Add your database
+                </button>
+            `;
+        }
+        
         elements.dbConnections.innerHTML = html;
         
         // Add click event listeners
@@ -684,6 +1003,83 @@ document.addEventListener('DOMContentLoaded', function() {
                 changeDialect(el.dataset.dialect);
             });
         });
+        document.querySelectorAll('#dbConnections [data-connection]').forEach(el => {
+            el.addEventListener('click', () => {
+                const connection = state.userConnections.find(c => c.id === el.dataset.connection);
+                if (connection) selectConnection(connection);
+            });
+        });
+        document.querySelectorAll('#dbConnections [data-remove-connection]').forEach(el => {
+            el.addEventListener('click', event => {
+                event.stopPropagation();
+                removeUserConnection(el.dataset.removeConnection);
+            });
+        });
+        const addConnectionBtn = document.getElementById('addConnectionBtn');
+        if (addConnectionBtn) {
+            addConnectionBtn.addEventListener('click', addUserConnection);
+        }
+    }
+
+    // Fetch the databases the logged-in user registered; anonymous users
+    // get none and the section stays hidden
+    function loadUserConnections() {
+        fetch('/api/v1/connections')
+            .then(response => response.ok ? response.json() : null)
+            .then(data => {
+                state.userConnections = data ? data.connections : null;
+                if (state.selectedConnection && !(state.userConnections || []).some(c => c.id === state.selectedConnection.id)) {
+                    changeDialect(state.selectedDialect);
+                }
+                updateDatabaseConnectionsList();
+            })
+            .catch(() => {});
+    }
+
+    // Run queries on a registered database
+    function selectConnection(connection) {
+        changeDialect(connection.dialect);
+        state.selectedConnection = connection;
+        elements.dialectBadge.textContent = connection.name;
+        // The bundled sample schema does not describe the user's database
+        state.editor.setOption('hintOptions', { tables: {} });
+        state.editor.setValue('');
+        updateDatabaseConnectionsList();
+    }
+
+    // Register a database after asking for its details
+    function addUserConnection() {
+        const name = window.prompt('Name for the connection');
+        if (!name) return;
+        const dialect = window.prompt('Dialect (mysql or postgresql)', 'postgresql');
+        if (!dialect) return;
+        const dsn = window.prompt('Connection string (DSN)');
+        if (!dsn) return;
+        const readOnly = window.confirm('Use this connection read-only?');
+
+        fetch('/api/v1/connections', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ name: name, dialect: dialect.trim().toLowerCase(), dsn: dsn, read_only: readOnly })
+        })
+            .then(response => response.json())
+            .then(data => {
+                if (data.error) {
+                    showToast('Connection not added', escapeHtml(data.error), 'error');
+                    return;
+                }
+                showToast('Connection added', `${escapeHtml(data.connection.name)} runs ${escapeHtml(data.server.server)} ${escapeHtml(data.server.version)}`, 'success');
+                loadUserConnections();
+            })
+            .catch(error => showToast('Connection not added', escapeHtml(error.message), 'error'));
+    }
+
+    // Forget a registered database
+    function removeUserConnection(id) {
+        if (!window.confirm('Remove this connection?')) return;
+        fetch(`/api/v1/connections/${encodeURIComponent(id)}`, { method: 'DELETE' })
+            .then(() => loadUserConnections())
+            .catch(error => showToast('Connection not removed', escapeHtml(error.message), 'error'));
     }
 
     // Render sample queries based on selected dialect
@@ -713,6 +1109,63 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     }
 
+    // Complete the tables and columns of the selected dialect's live schema,
+    // which the server caches until a DDL statement changes it
+    function loadSchemaHints() {
+        const dialect = state.selectedDialect;
+
+        fetch(`/api/v1/schema?dialect=${encodeURIComponent(dialect)}`)
+            .then(response => response.ok ? response.json() : null)
+            .then(data => {
+                // A registered connection has a schema of its own
+                if (!data || !data.tables || dialect !== state.selectedDialect || state.selectedConnection) return;
+                state.editor.setOption('hintOptions', { tables: data.tables });
+            })
+            .catch(() => {});
+    }
+
+    // Fetch the teaching templates of the selected dialect, resolved against its schema
+    function loadQueryTemplates() {
+        const dialect = state.selectedDialect;
+
+        fetch(`/api/v1/templates?dialect=${encodeURIComponent(dialect)}`)
+            .then(response => response.json())
+            .then(data => {
+                if (dialect !== state.selectedDialect) return;
+                renderQueryTemplates(data.templates || []);
+            })
+            .catch(() => renderQueryTemplates([]));
+    }
+
+    function renderQueryTemplates(templates) {
+        if (templates.length === 0) {
+            elements.queryTemplates.innerHTML = '<div class="text-xs text-gray-500 dark:text-gray-400">No templates available for this database.</div>';
+            return;
+        }
+
+        let html = '';
+        templates.forEach(template => {
+            html += `
+                <div 
+                    class="p-2 rounded-md cursor-pointer hover:bg-gray-100 dark:hover:bg-gray-700 text-sm query-template" 
+                    data-query="${encodeURIComponent(template.sql)}">
+                    <div class="font-medium mb-1 dark:text-gray-300">${template.title}
+                        <span class="ml-1 text-xs text-primary-600 dark:text-primary-400">${template.category}</span>
+                    </div>
+                    <div class="text-xs text-gray-500 dark:text-gray-400 line-clamp-2">${template.description}</div>
+                </div>
+            `;
+        });
+
+        elements.queryTemplates.innerHTML = html;
+
+        document.querySelectorAll('.query-template').forEach(el => {
+            el.addEventListener('click', () => {
+                loadSampleQuery(decodeURIComponent(el.dataset.query));
+            });
+        });
+    }
+
     // Helper function to format date for filenames
     function formatDate(date) {
         return date.toISOString().replace(/[:.]/g, '-').split('T')[0];
@@ -748,9 +1201,16 @@ document.addEventListener('DOMContentLoaded', function() {
         // Render initial UI
         updateDatabaseConnectionsList();
         renderSampleQueries();
+        loadSchemaHints();
+        loadQueryTemplates();
         
-        // Check database connections
+        // Check database connections and follow changes live
         checkDatabaseConnections();
+        subscribeToEvents();
+        loadUserConnections();
+
+        // Pre-fill the editor from a share link
+        loadSharedQuery();
         
         // Set up event listeners
         elements.executeQueryBtn.addEventListener('click', executeQuery);
@@ -789,8 +1249,12 @@ document.addEventListener('DOMContentLoaded', function() {
             }
         });
         
-        // Periodically check database connections
-        setInterval(checkDatabaseConnections, 30000); // every 30 seconds
+        // Fall back to polling database connections while the event stream is down
+        setInterval(() => {
+            if (!state.eventsConnected) {
+                checkDatabaseConnections();
+            }
+        }, 30000); // every 30 seconds
     }
 
     // Start the application
@@ -0,0 +1,523 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Default and largest number of history or audit records listed at once
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// sqlStore keeps metadata in a SQL database. SQLite and Postgres share the
+// queries, which are written with ? placeholders and rebound for Postgres.
+type sqlStore struct {
+	db      *sql.DB
+	backend string
+}
+
+// SQLite schema of the metadata tables
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS query_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		owner TEXT NOT NULL,
+		dialect TEXT NOT NULL,
+		sql_text TEXT NOT NULL,
+		duration_ms REAL NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		executed_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS query_history_owner ON query_history (owner, id)`,
+	`CREATE TABLE IF NOT EXISTS snippets (
+		id TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL,
+		dialect TEXT NOT NULL,
+		sql_text TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS snippets_owner ON snippets (owner)`,
+	`CREATE TABLE IF NOT EXISTS workspaces (
+		id TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL,
+		tabs TEXT NOT NULL,
+		active_tab INTEGER NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		updated_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS workspaces_owner ON workspaces (owner)`,
+	`CREATE TABLE IF NOT EXISTS sessions (
+		token_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL,
+		expires_at TIMESTAMP NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		time TIMESTAMP NOT NULL,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target TEXT NOT NULL DEFAULT '',
+		detail TEXT NOT NULL DEFAULT '',
+		remote_addr TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_time ON audit_log (time)`,
+}
+
+// Postgres schema of the metadata tables
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS query_history (
+		id BIGSERIAL PRIMARY KEY,
+		owner TEXT NOT NULL,
+		dialect TEXT NOT NULL,
+		sql_text TEXT NOT NULL,
+		duration_ms DOUBLE PRECISION NOT NULL,
+		error TEXT NOT NULL DEFAULT '',
+		executed_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS query_history_owner ON query_history (owner, id)`,
+	`CREATE TABLE IF NOT EXISTS snippets (
+		id TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL,
+		dialect TEXT NOT NULL,
+		sql_text TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS snippets_owner ON snippets (owner)`,
+	`CREATE TABLE IF NOT EXISTS workspaces (
+		id TEXT PRIMARY KEY,
+		owner TEXT NOT NULL,
+		name TEXT NOT NULL,
+		tabs TEXT NOT NULL,
+		active_tab INTEGER NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS workspaces_owner ON workspaces (owner)`,
+	`CREATE TABLE IF NOT EXISTS sessions (
+		token_hash TEXT PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		time TIMESTAMPTZ NOT NULL,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target TEXT NOT NULL DEFAULT '',
+		detail TEXT NOT NULL DEFAULT '',
+		remote_addr TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS audit_log_time ON audit_log (time)`,
+}
+
+// OpenSQLite opens a store in a SQLite file, for single-instance
+// deployments
+func OpenSQLite(path string) (Store, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows one writer; serialize access instead of failing
+	db.SetMaxOpenConns(1)
+	return open(db, BackendSQLite, sqliteSchema)
+}
+
+// OpenPostgres opens a store in a Postgres database that several
+// instances can share
+func OpenPostgres(dsn string) (Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(10)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	return open(db, BackendPostgres, postgresSchema)
+}
+
+// open creates the metadata tables of a freshly opened database
+func open(db *sql.DB, backend string, schema []string) (Store, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	for _, statement := range schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &sqlStore{db: db, backend: backend}, nil
+}
+
+// rebind rewrites ? placeholders as $1, $2, ... for Postgres
+func (s *sqlStore) rebind(query string) string {
+	if s.backend != BackendPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *sqlStore) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.rebind(query), args...)
+}
+
+func (s *sqlStore) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.rebind(query), args...)
+}
+
+// insertID runs an INSERT and returns the generated id column
+func (s *sqlStore) insertID(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	if s.backend == BackendPostgres {
+		var id int64
+		err := s.db.QueryRowContext(ctx, s.rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, err
+	}
+	res, err := s.exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *sqlStore) Backend() string {
+	return s.backend
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqlStore) AddHistory(ctx context.Context, entry *HistoryEntry) error {
+	if entry.ExecutedAt.IsZero() {
+		entry.ExecutedAt = time.Now()
+	}
+	id, err := s.insertID(ctx,
+		`INSERT INTO query_history (owner, dialect, sql_text, duration_ms, error, executed_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.Owner, entry.Dialect, entry.SQL, entry.DurationMs, entry.Error, entry.ExecutedAt.UTC())
+	if err != nil {
+		return err
+	}
+	entry.ID = id
+
+	_, err = s.exec(ctx,
+		`DELETE FROM query_history WHERE owner = ? AND id < (
+			SELECT MIN(id) FROM (
+				SELECT id FROM query_history WHERE owner = ? ORDER BY id DESC LIMIT ?
+			) AS kept
+		)`,
+		entry.Owner, entry.Owner, MaxHistoryPerOwner)
+	return err
+}
+
+func (s *sqlStore) ListHistory(ctx context.Context, owner string, limit int) ([]HistoryEntry, error) {
+	rows, err := s.query(ctx,
+		`SELECT id, owner, dialect, sql_text, duration_ms, error, executed_at
+		FROM query_history WHERE owner = ? ORDER BY id DESC LIMIT ?`,
+		owner, listLimit(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []HistoryEntry{}
+	for rows.Next() {
+		var entry HistoryEntry
+		if err := rows.Scan(&entry.ID, &entry.Owner, &entry.Dialect, &entry.SQL, &entry.DurationMs, &entry.Error, &entry.ExecutedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, entry)
+	}
+	return result, rows.Err()
+}
+
+func (s *sqlStore) ClearHistory(ctx context.Context, owner string) error {
+	_, err := s.exec(ctx, `DELETE FROM query_history WHERE owner = ?`, owner)
+	return err
+}
+
+func (s *sqlStore) SaveSnippet(ctx context.Context, snippet *Snippet) error {
+	now := time.Now().UTC()
+	if snippet.ID != "" {
+		res, err := s.exec(ctx,
+			`UPDATE snippets SET name = ?, dialect = ?, sql_text = ?, updated_at = ?
+			WHERE id = ? AND owner = ?`,
+			snippet.Name, snippet.Dialect, snippet.SQL, now, snippet.ID, snippet.Owner)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		snippet.UpdatedAt = now
+		return s.db.QueryRowContext(ctx, s.rebind(`SELECT created_at FROM snippets WHERE id = ?`), snippet.ID).Scan(&snippet.CreatedAt)
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM snippets WHERE owner = ?`), snippet.Owner).Scan(&count); err != nil {
+		return err
+	}
+	if count >= MaxSnippetsPerOwner {
+		return ErrLimitReached
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx,
+		`INSERT INTO snippets (id, owner, name, dialect, sql_text, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, snippet.Owner, snippet.Name, snippet.Dialect, snippet.SQL, now, now)
+	if err != nil {
+		return err
+	}
+	snippet.ID, snippet.CreatedAt, snippet.UpdatedAt = id, now, now
+	return nil
+}
+
+func (s *sqlStore) ListSnippets(ctx context.Context, owner string) ([]Snippet, error) {
+	rows, err := s.query(ctx,
+		`SELECT id, owner, name, dialect, sql_text, created_at, updated_at
+		FROM snippets WHERE owner = ? ORDER BY name, created_at`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Snippet{}
+	for rows.Next() {
+		var snippet Snippet
+		if err := rows.Scan(&snippet.ID, &snippet.Owner, &snippet.Name, &snippet.Dialect, &snippet.SQL, &snippet.CreatedAt, &snippet.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, snippet)
+	}
+	return result, rows.Err()
+}
+
+func (s *sqlStore) DeleteSnippet(ctx context.Context, owner string, id string) error {
+	res, err := s.exec(ctx, `DELETE FROM snippets WHERE id = ? AND owner = ?`, id, owner)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) SaveWorkspace(ctx context.Context, workspace *Workspace) error {
+	now := time.Now().UTC()
+	if workspace.ID != "" {
+		res, err := s.exec(ctx,
+			`UPDATE workspaces SET name = ?, tabs = ?, active_tab = ?, updated_at = ?
+			WHERE id = ? AND owner = ?`,
+			workspace.Name, workspace.Tabs, workspace.ActiveTab, now, workspace.ID, workspace.Owner)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrNotFound
+		}
+		workspace.UpdatedAt = now
+		return s.db.QueryRowContext(ctx, s.rebind(`SELECT created_at FROM workspaces WHERE id = ?`), workspace.ID).Scan(&workspace.CreatedAt)
+	}
+
+	var count int
+	if err := s.db.QueryRowContext(ctx, s.rebind(`SELECT COUNT(*) FROM workspaces WHERE owner = ?`), workspace.Owner).Scan(&count); err != nil {
+		return err
+	}
+	if count >= MaxWorkspacesPerOwner {
+		return ErrWorkspaceLimitReached
+	}
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	_, err = s.exec(ctx,
+		`INSERT INTO workspaces (id, owner, name, tabs, active_tab, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, workspace.Owner, workspace.Name, workspace.Tabs, workspace.ActiveTab, now, now)
+	if err != nil {
+		return err
+	}
+	workspace.ID, workspace.CreatedAt, workspace.UpdatedAt = id, now, now
+	return nil
+}
+
+func (s *sqlStore) GetWorkspace(ctx context.Context, owner string, id string) (*Workspace, error) {
+	workspace := Workspace{ID: id, Owner: owner}
+	err := s.db.QueryRowContext(ctx,
+		s.rebind(`SELECT name, tabs, active_tab, created_at, updated_at FROM workspaces WHERE id = ? AND owner = ?`), id, owner,
+	).Scan(&workspace.Name, &workspace.Tabs, &workspace.ActiveTab, &workspace.CreatedAt, &workspace.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (s *sqlStore) ListWorkspaces(ctx context.Context, owner string) ([]Workspace, error) {
+	rows, err := s.query(ctx,
+		`SELECT id, owner, name, tabs, active_tab, created_at, updated_at
+		FROM workspaces WHERE owner = ? ORDER BY updated_at DESC, id`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Workspace{}
+	for rows.Next() {
+		var workspace Workspace
+		if err := rows.Scan(&workspace.ID, &workspace.Owner, &workspace.Name, &workspace.Tabs, &workspace.ActiveTab, &workspace.CreatedAt, &workspace.UpdatedAt); err != nil {
+			return nil, err
+		}
+		result = append(result, workspace)
+	}
+	return result, rows.Err()
+}
+
+func (s *sqlStore) DeleteWorkspace(ctx context.Context, owner string, id string) error {
+	res, err := s.exec(ctx, `DELETE FROM workspaces WHERE id = ? AND owner = ?`, id, owner)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) PutSession(ctx context.Context, session *Session) error {
+	_, err := s.exec(ctx,
+		`INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)`,
+		session.TokenHash, session.UserID, session.ExpiresAt.UTC())
+	return err
+}
+
+func (s *sqlStore) GetSession(ctx context.Context, tokenHash string) (*Session, error) {
+	session := Session{TokenHash: tokenHash}
+	err := s.db.QueryRowContext(ctx,
+		s.rebind(`SELECT user_id, expires_at FROM sessions WHERE token_hash = ?`), tokenHash,
+	).Scan(&session.UserID, &session.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (s *sqlStore) DeleteSession(ctx context.Context, tokenHash string) error {
+	_, err := s.exec(ctx, `DELETE FROM sessions WHERE token_hash = ?`, tokenHash)
+	return err
+}
+
+func (s *sqlStore) DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.exec(ctx, `DELETE FROM sessions WHERE expires_at < ?`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *sqlStore) AddAudit(ctx context.Context, record *AuditRecord) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if record.Actor == "" || record.Action == "" {
+		return errors.New("audit records need an actor and an action")
+	}
+	id, err := s.insertID(ctx,
+		`INSERT INTO audit_log (time, actor, action, target, detail, remote_addr)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		record.Time.UTC(), record.Actor, record.Action, record.Target, record.Detail, record.RemoteAddr)
+	if err != nil {
+		return err
+	}
+	record.ID = id
+	return nil
+}
+
+func (s *sqlStore) ListAudit(ctx context.Context, filter AuditFilter) ([]AuditRecord, error) {
+	query := `SELECT id, time, actor, action, target, detail, remote_addr FROM audit_log WHERE 1 = 1`
+	var args []interface{}
+	if filter.Actor != "" {
+		query += ` AND actor = ?`
+		args = append(args, filter.Actor)
+	}
+	if filter.Action != "" {
+		query += ` AND action = ?`
+		args = append(args, filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND time >= ?`
+		args = append(args, filter.Since.UTC())
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, listLimit(filter.Limit))
+
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []AuditRecord{}
+	for rows.Next() {
+		var record AuditRecord
+		if err := rows.Scan(&record.ID, &record.Time, &record.Actor, &record.Action, &record.Target, &record.Detail, &record.RemoteAddr); err != nil {
+			return nil, err
+		}
+		result = append(result, record)
+	}
+	return result, rows.Err()
+}
+
+// listLimit bounds the number of records listed at once
+func listLimit(limit int) int {
+	if limit <= 0 {
+		return defaultListLimit
+	}
+	if limit > maxListLimit {
+		return maxListLimit
+	}
+	return limit
+}
+
+// newID returns a random record identifier
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Most history entries kept per owner; older ones are pruned
+const MaxHistoryPerOwner = 500
+
+// Most snippets an owner may save
+const MaxSnippetsPerOwner = 200
+
+// Most workspaces an owner may keep
+const MaxWorkspacesPerOwner = 50
+
+// Backends a store can be opened on
+const (
+	BackendSQLite   = "sqlite"
+	BackendPostgres = "postgres"
+)
+
+var (
+	// ErrNotFound is returned when a record does not exist for the owner
+	ErrNotFound = errors.New("record not found")
+
+	// ErrLimitReached is returned when an owner has saved the most
+	// snippets allowed
+	ErrLimitReached = fmt.Errorf("at most %d snippets can be saved", MaxSnippetsPerOwner)
+
+	// ErrWorkspaceLimitReached is returned when an owner keeps the most
+	// workspaces allowed
+	ErrWorkspaceLimitReached = fmt.Errorf("at most %d workspaces can be kept", MaxWorkspacesPerOwner)
+)
+
+// Store persists application metadata: query history, saved snippets,
+// workspaces, login sessions and audit records. Instances sharing a Postgres store
+// share this state.
+type Store interface {
+	// AddHistory records an executed query, pruning the owner's oldest
+	// entries beyond MaxHistoryPerOwner
+	AddHistory(ctx context.Context, entry *HistoryEntry) error
+	// ListHistory returns an owner's most recent queries, newest first
+	ListHistory(ctx context.Context, owner string, limit int) ([]HistoryEntry, error)
+	// ClearHistory removes an owner's history
+	ClearHistory(ctx context.Context, owner string) error
+
+	// SaveSnippet creates a snippet when its ID is empty and updates the
+	// owner's snippet otherwise
+	SaveSnippet(ctx context.Context, snippet *Snippet) error
+	// ListSnippets returns an owner's snippets by name
+	ListSnippets(ctx context.Context, owner string) ([]Snippet, error)
+	// DeleteSnippet removes an owner's snippet
+	DeleteSnippet(ctx context.Context, owner string, id string) error
+
+	// SaveWorkspace creates a workspace when its ID is empty and updates
+	// the owner's workspace otherwise
+	SaveWorkspace(ctx context.Context, workspace *Workspace) error
+	// GetWorkspace returns an owner's workspace
+	GetWorkspace(ctx context.Context, owner string, id string) (*Workspace, error)
+	// ListWorkspaces returns an owner's workspaces, most recently updated
+	// first
+	ListWorkspaces(ctx context.Context, owner string) ([]Workspace, error)
+	// DeleteWorkspace removes an owner's workspace
+	DeleteWorkspace(ctx context.Context, owner string, id string) error
+
+	// PutSession stores a login session
+	PutSession(ctx context.Context, session *Session) error
+	// GetSession returns the session with a token hash
+	GetSession(ctx context.Context, tokenHash string) (*Session, error)
+	// DeleteSession ends a session
+	DeleteSession(ctx context.Context, tokenHash string) error
+	// DeleteExpiredSessions removes sessions that expired before now
+	DeleteExpiredSessions(ctx context.Context, now time.Time) (int64, error)
+
+	// AddAudit records an audited action
+	AddAudit(ctx context.Context, record *AuditRecord) error
+	// ListAudit returns audit records matching a filter, newest first
+	ListAudit(ctx context.Context, filter AuditFilter) ([]AuditRecord, error)
+
+	// Backend names the database the store is kept in
+	Backend() string
+	// Close releases the store
+	Close() error
+}
+
+// HistoryEntry is a query an owner executed
+type HistoryEntry struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"-"`
+	Dialect    string    `json:"dialect"`
+	SQL        string    `json:"sql"`
+	DurationMs float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+// Snippet is a named query an owner saved for reuse
+type Snippet struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"-"`
+	Name      string    `json:"name"`
+	Dialect   string    `json:"dialect"`
+	SQL       string    `json:"sql"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Workspace is a named set of editor tabs an owner keeps. The tabs are
+// stored as the JSON document the workspace package encodes.
+type Workspace struct {
+	ID        string
+	Owner     string
+	Name      string
+	Tabs      string
+	ActiveTab int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Session is a login session, identified by the hash of its token
+type Session struct {
+	TokenHash string
+	UserID    int64
+	ExpiresAt time.Time
+}
+
+// AuditRecord is an action worth keeping a trail of, such as a login or
+// an admin change
+type AuditRecord struct {
+	ID         int64     `json:"id"`
+	Time       time.Time `json:"time"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Target     string    `json:"target,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+	RemoteAddr string    `json:"remote_addr,omitempty"`
+}
+
+// AuditFilter selects audit records; empty fields match everything
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Since  time.Time
+	Limit  int
+}
+
+// Open opens a store on a backend: a SQLite file path or a Postgres DSN
+func Open(backend string, dsn string) (Store, error) {
+	switch backend {
+	case BackendSQLite, "":
+		return OpenSQLite(dsn)
+	case BackendPostgres, "postgresql":
+		return OpenPostgres(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported metadata store backend: %s", backend)
+	}
+}
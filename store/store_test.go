@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) Store {
+	s, err := Open(BackendSQLite, filepath.Join(t.TempDir(), "metadata.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestHistory(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	for _, sql := range []string{"SELECT 1", "SELECT 2"} {
+		if err := s.AddHistory(ctx, &HistoryEntry{Owner: "user:1", Dialect: "sqlite", SQL: sql, DurationMs: 1.5}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.AddHistory(ctx, &HistoryEntry{Owner: "user:2", Dialect: "sqlite", SQL: "SELECT 3"})
+
+	history, err := s.ListHistory(ctx, "user:1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].SQL != "SELECT 2" {
+		t.Errorf("expected the owner's queries newest first, got %+v", history)
+	}
+
+	if err := s.ClearHistory(ctx, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+	if history, _ := s.ListHistory(ctx, "user:1", 10); len(history) != 0 {
+		t.Errorf("expected the history to be cleared, got %+v", history)
+	}
+	if history, _ := s.ListHistory(ctx, "user:2", 10); len(history) != 1 {
+		t.Errorf("expected other owners' history to stay, got %+v", history)
+	}
+}
+
+func TestHistoryIsPruned(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	for i := 0; i < MaxHistoryPerOwner+5; i++ {
+		if err := s.AddHistory(ctx, &HistoryEntry{Owner: "user:1", Dialect: "sqlite", SQL: "SELECT 1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, _ := s.ListHistory(ctx, "user:1", maxListLimit)
+	if len(history) != MaxHistoryPerOwner {
+		t.Errorf("expected %d entries to be kept, got %d", MaxHistoryPerOwner, len(history))
+	}
+}
+
+func TestSnippets(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	snippet := &Snippet{Owner: "user:1", Name: "top customers", Dialect: "sqlite", SQL: "SELECT 1"}
+	if err := s.SaveSnippet(ctx, snippet); err != nil {
+		t.Fatal(err)
+	}
+	if snippet.ID == "" {
+		t.Fatal("expected the new snippet to get an ID")
+	}
+
+	snippet.SQL = "SELECT 2"
+	if err := s.SaveSnippet(ctx, snippet); err != nil {
+		t.Fatal(err)
+	}
+	list, _ := s.ListSnippets(ctx, "user:1")
+	if len(list) != 1 || list[0].SQL != "SELECT 2" {
+		t.Errorf("expected the snippet to be updated, got %+v", list)
+	}
+
+	other := &Snippet{ID: snippet.ID, Owner: "user:2", Name: "stolen", Dialect: "sqlite", SQL: "SELECT 3"}
+	if err := s.SaveSnippet(ctx, other); err != ErrNotFound {
+		t.Errorf("expected other owners not to update the snippet, got %v", err)
+	}
+	if err := s.DeleteSnippet(ctx, "user:2", snippet.ID); err != ErrNotFound {
+		t.Errorf("expected other owners not to delete the snippet, got %v", err)
+	}
+	if err := s.DeleteSnippet(ctx, "user:1", snippet.ID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	workspace := &Workspace{Owner: "user:1", Name: "reports", Tabs: `[{"name":"q1"}]`}
+	if err := s.SaveWorkspace(ctx, workspace); err != nil {
+		t.Fatal(err)
+	}
+	if workspace.ID == "" {
+		t.Fatal("expected the new workspace to get an ID")
+	}
+
+	workspace.Tabs, workspace.ActiveTab = `[{"name":"q1"},{"name":"q2"}]`, 1
+	if err := s.SaveWorkspace(ctx, workspace); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetWorkspace(ctx, "user:1", workspace.ID)
+	if err != nil || got.Tabs != workspace.Tabs || got.ActiveTab != 1 {
+		t.Errorf("expected the workspace to be updated, got %+v, %v", got, err)
+	}
+	if list, _ := s.ListWorkspaces(ctx, "user:1"); len(list) != 1 || list[0].Name != "reports" {
+		t.Errorf("expected the owner's workspace to be listed, got %+v", list)
+	}
+
+	if _, err := s.GetWorkspace(ctx, "user:2", workspace.ID); err != ErrNotFound {
+		t.Errorf("expected other owners not to see the workspace, got %v", err)
+	}
+	other := &Workspace{ID: workspace.ID, Owner: "user:2", Name: "stolen", Tabs: "[]"}
+	if err := s.SaveWorkspace(ctx, other); err != ErrNotFound {
+		t.Errorf("expected other owners not to update the workspace, got %v", err)
+	}
+	if err := s.DeleteWorkspace(ctx, "user:2", workspace.ID); err != ErrNotFound {
+		t.Errorf("expected other owners not to delete the workspace, got %v", err)
+	}
+	if err := s.DeleteWorkspace(ctx, "user:1", workspace.ID); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := s.ListWorkspaces(ctx, "user:1"); len(list) != 0 {
+		t.Errorf("expected the workspace to be gone, got %+v", list)
+	}
+}
+
+func TestWorkspaceLimit(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	for i := 0; i < MaxWorkspacesPerOwner; i++ {
+		if err := s.SaveWorkspace(ctx, &Workspace{Owner: "user:1", Name: "ws", Tabs: "[]"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveWorkspace(ctx, &Workspace{Owner: "user:1", Name: "one more", Tabs: "[]"}); err != ErrWorkspaceLimitReached {
+		t.Errorf("expected ErrWorkspaceLimitReached, got %v", err)
+	}
+	if err := s.SaveWorkspace(ctx, &Workspace{Owner: "user:2", Name: "ws", Tabs: "[]"}); err != nil {
+		t.Errorf("expected other owners to be unaffected, got %v", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	s.PutSession(ctx, &Session{TokenHash: "live", UserID: 1, ExpiresAt: now.Add(time.Hour)})
+	s.PutSession(ctx, &Session{TokenHash: "expired", UserID: 1, ExpiresAt: now.Add(-time.Hour)})
+
+	session, err := s.GetSession(ctx, "live")
+	if err != nil || session.UserID != 1 {
+		t.Fatalf("expected the session, got %+v, %v", session, err)
+	}
+	if n, err := s.DeleteExpiredSessions(ctx, now); err != nil || n != 1 {
+		t.Errorf("expected one expired session to be removed, got %d, %v", n, err)
+	}
+	if err := s.DeleteSession(ctx, "live"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetSession(ctx, "live"); err != ErrNotFound {
+		t.Errorf("expected the session to be gone, got %v", err)
+	}
+}
+
+func TestAudit(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	s.AddAudit(ctx, &AuditRecord{Actor: "admin", Action: "login"})
+	s.AddAudit(ctx, &AuditRecord{Actor: "admin", Action: "secrets.rotate", Detail: "rotated 2"})
+	s.AddAudit(ctx, &AuditRecord{Actor: "alice", Action: "login"})
+	if err := s.AddAudit(ctx, &AuditRecord{Action: "login"}); err == nil {
+		t.Error("expected a record without an actor to be rejected")
+	}
+
+	records, err := s.ListAudit(ctx, AuditFilter{Action: "login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Actor != "alice" {
+		t.Errorf("expected the logins newest first, got %+v", records)
+	}
+	if records, _ := s.ListAudit(ctx, AuditFilter{Actor: "admin", Limit: 1}); len(records) != 1 || records[0].Action != "secrets.rotate" {
+		t.Errorf("expected the admin's latest record, got %+v", records)
+	}
+}
+
+func TestRebind(t *testing.T) {
+	s := &sqlStore{backend: BackendPostgres}
+	if got := s.rebind("SELECT * FROM t WHERE a = ? AND b = ?"); got != "SELECT * FROM t WHERE a = $1 AND b = $2" {
+		t.Errorf("unexpected Postgres query %q", got)
+	}
+	s.backend = BackendSQLite
+	if got := s.rebind("a = ?"); got != "a = ?" {
+		t.Errorf("expected SQLite queries to keep their placeholders, got %q", got)
+	}
+}
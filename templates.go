@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dbmanager"
+	"example/user/playground/templates"
+)
+
+// loadCustomTemplates registers the templates found in TEMPLATES_DIR
+func loadCustomTemplates() {
+	dir := os.Getenv("TEMPLATES_DIR")
+	if dir == "" {
+		return
+	}
+	if err := templates.LoadDir(dir); err != nil {
+		fmt.Printf("Failed to load templates from %s: %v\n", dir, err)
+	}
+}
+
+// listTemplates returns the teaching templates of a dialect with their
+// placeholders resolved against the live schema
+func listTemplates(c *gin.Context) {
+	dialect := c.Query("dialect")
+	if dialect == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dialect is required",
+		})
+		return
+	}
+
+	metadata, err := dbmanager.Metadata(c.Request.Context(), dialect)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "Failed to load schema: " + err.Error(),
+		})
+		return
+	}
+	schema := metadata.Tables
+
+	table, rendered := templates.Render(dialect, c.Query("category"), schema, c.Query("table"))
+	c.JSON(http.StatusOK, gin.H{
+		"dialect":   dialect,
+		"table":     table,
+		"templates": rendered,
+	})
+}
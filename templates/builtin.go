@@ -0,0 +1,79 @@
+package templates
+
+// Templates shipped with the playground. Placeholders: {{table}}, {{key}},
+// {{column}} and {{column2}}.
+var builtinTemplates = []Template{
+	{
+		ID:          "select-basics",
+		Title:       "Filter and sort",
+		Category:    "basics",
+		Description: "Select columns, filter rows with WHERE and sort them with ORDER BY",
+		SQL:         "SELECT {{key}}, {{column}}\nFROM {{table}}\nWHERE {{column}} IS NOT NULL\nORDER BY {{column}}\nLIMIT 10;",
+	},
+	{
+		ID:          "group-by",
+		Title:       "Group and count",
+		Category:    "aggregation",
+		Description: "Count rows per value with GROUP BY and keep frequent values with HAVING",
+		SQL:         "SELECT {{column}}, COUNT(*) AS row_count\nFROM {{table}}\nGROUP BY {{column}}\nHAVING COUNT(*) >= 1\nORDER BY row_count DESC;",
+	},
+	{
+		ID:          "self-join",
+		Title:       "Inner join",
+		Category:    "joins",
+		Description: "Join a table to itself on its key to compare rows side by side",
+		SQL:         "SELECT a.{{key}}, a.{{column}}, b.{{column2}}\nFROM {{table}} a\nINNER JOIN {{table}} b ON b.{{key}} = a.{{key}}\nLIMIT 10;",
+	},
+	{
+		ID:          "left-join",
+		Title:       "Left join",
+		Category:    "joins",
+		Description: "Keep every row of the left table, with NULLs where the join condition finds no match",
+		SQL:         "SELECT a.{{key}}, a.{{column}}, b.{{key}} AS empty_match\nFROM {{table}} a\nLEFT JOIN {{table}} b ON b.{{key}} = a.{{key}} AND b.{{column}} IS NULL\nORDER BY a.{{key}}\nLIMIT 10;",
+	},
+	{
+		ID:          "row-number",
+		Title:       "Row number",
+		Category:    "window functions",
+		Description: "Number rows in order without collapsing them like GROUP BY does",
+		SQL:         "SELECT {{key}}, {{column}},\n       ROW_NUMBER() OVER (ORDER BY {{key}}) AS row_num\nFROM {{table}}\nLIMIT 10;",
+	},
+	{
+		ID:          "rank-partition",
+		Title:       "Rank within groups",
+		Category:    "window functions",
+		Description: "Rank rows inside each group with PARTITION BY",
+		SQL:         "SELECT {{column}}, {{key}},\n       RANK() OVER (PARTITION BY {{column}} ORDER BY {{key}} DESC) AS rank_in_group\nFROM {{table}}\nLIMIT 10;",
+	},
+	{
+		ID:          "lag",
+		Title:       "Previous row",
+		Category:    "window functions",
+		Description: "Look at the previous row's value with LAG",
+		SQL:         "SELECT {{key}}, {{column}},\n       LAG({{column}}) OVER (ORDER BY {{key}}) AS previous_value\nFROM {{table}}\nLIMIT 10;",
+	},
+	{
+		ID:          "cte",
+		Title:       "Common table expression",
+		Category:    "ctes",
+		Description: "Name an intermediate result with WITH and query it like a table",
+		SQL:         "WITH counts AS (\n    SELECT {{column}}, COUNT(*) AS row_count\n    FROM {{table}}\n    GROUP BY {{column}}\n)\nSELECT *\nFROM counts\nORDER BY row_count DESC;",
+	},
+	{
+		ID:          "recursive-cte",
+		Title:       "Recursive CTE",
+		Category:    "ctes",
+		Description: "Generate a series of numbers with a recursive WITH clause",
+		SQL:         "WITH RECURSIVE numbers(n) AS (\n    SELECT 1\n    UNION ALL\n    SELECT n + 1 FROM numbers WHERE n < 10\n)\nSELECT n, (SELECT COUNT(*) FROM {{table}}) AS table_rows\nFROM numbers;",
+	},
+	{
+		ID:          "upsert",
+		Title:       "Upsert",
+		Category:    "upserts",
+		Description: "Insert a row or update it when its key already exists; this example rewrites an existing row with its own values",
+		SQL:         "INSERT INTO {{table}}\nSELECT * FROM {{table}} WHERE {{key}} = (SELECT MIN({{key}}) FROM {{table}})\nON CONFLICT ({{key}}) DO UPDATE SET {{column}} = excluded.{{column}};",
+		Dialects: map[string]string{
+			"mysql": "INSERT INTO {{table}}\nSELECT * FROM {{table}} AS src WHERE src.{{key}} = (SELECT MIN({{key}}) FROM {{table}})\nON DUPLICATE KEY UPDATE {{column}} = VALUES({{column}});",
+		},
+	},
+}
@@ -0,0 +1,247 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Template is a teaching query with {{placeholder}} names that are filled
+// in from the schema of the database it runs against
+type Template struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	// SQL used for every dialect without an entry in Dialects
+	SQL string `json:"sql"`
+	// Dialect-specific SQL, keyed by dialect name
+	Dialects map[string]string `json:"dialects,omitempty"`
+}
+
+// Rendered is a template resolved against a concrete table
+type Rendered struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	SQL         string `json:"sql"`
+}
+
+var (
+	// Registered templates in registration order
+	library = append([]Template{}, builtinTemplates...)
+
+	// Guards library
+	libraryMu sync.RWMutex
+
+	// {{name}}
+	placeholderRegex = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+	// Identifiers that never need quoting
+	plainIdentifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Register adds a template to the library, replacing any template with the
+// same ID
+func Register(t Template) error {
+	if t.ID == "" || t.Title == "" {
+		return fmt.Errorf("template needs an id and a title")
+	}
+	if t.SQL == "" && len(t.Dialects) == 0 {
+		return fmt.Errorf("template %s has no SQL", t.ID)
+	}
+
+	libraryMu.Lock()
+	defer libraryMu.Unlock()
+
+	for i, existing := range library {
+		if existing.ID == t.ID {
+			library[i] = t
+			return nil
+		}
+	}
+	library = append(library, t)
+	return nil
+}
+
+// LoadDir registers every *.json file in dir. A file holds either a single
+// template or an array of templates.
+func LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		var list []Template
+		if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+			err = json.Unmarshal(data, &list)
+		} else {
+			var single Template
+			err = json.Unmarshal(data, &single)
+			list = []Template{single}
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+
+		for _, t := range list {
+			if err := Register(t); err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+		}
+	}
+	return nil
+}
+
+// List returns the templates available for a dialect, optionally limited
+// to one category
+func List(dialect string, category string) []Template {
+	libraryMu.RLock()
+	defer libraryMu.RUnlock()
+
+	result := []Template{}
+	for _, t := range library {
+		if category != "" && !strings.EqualFold(t.Category, category) {
+			continue
+		}
+		if t.sqlFor(dialect) == "" {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// Render resolves the templates of a dialect against schema. The table
+// argument picks the table to use; when empty or unknown the first table
+// with the most columns is used. Templates whose placeholders cannot be
+// filled from the schema are left out so every returned query runs.
+func Render(dialect string, category string, schema map[string][]string, table string) (string, []Rendered) {
+	table = pickTable(schema, table)
+	if table == "" {
+		return "", []Rendered{}
+	}
+
+	values := placeholderValues(dialect, table, schema[table])
+	rendered := []Rendered{}
+	for _, t := range List(dialect, category) {
+		sql, ok := substitute(t.sqlFor(dialect), values)
+		if !ok {
+			continue
+		}
+		rendered = append(rendered, Rendered{
+			ID:          t.ID,
+			Title:       t.Title,
+			Category:    t.Category,
+			Description: t.Description,
+			SQL:         sql,
+		})
+	}
+	return table, rendered
+}
+
+// sqlFor returns the SQL of the template for a dialect
+func (t Template) sqlFor(dialect string) string {
+	if sql, ok := t.Dialects[dialect]; ok {
+		return sql
+	}
+	return t.SQL
+}
+
+// pickTable returns the requested table when it exists, otherwise the table
+// with the most columns, breaking ties by name
+func pickTable(schema map[string][]string, requested string) string {
+	for name := range schema {
+		if strings.EqualFold(name, requested) {
+			return name
+		}
+	}
+
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(schema[names[i]]) != len(schema[names[j]]) {
+			return len(schema[names[i]]) > len(schema[names[j]])
+		}
+		return names[i] < names[j]
+	})
+
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// placeholderValues maps placeholder names to quoted identifiers:
+// table, key (the id column, else the first column), column and column2
+// (the first columns other than the key)
+func placeholderValues(dialect string, table string, columns []string) map[string]string {
+	values := map[string]string{"table": quoteIdentifier(dialect, table)}
+	if len(columns) == 0 {
+		return values
+	}
+
+	key := columns[0]
+	for _, column := range columns {
+		if strings.EqualFold(column, "id") {
+			key = column
+			break
+		}
+	}
+	values["key"] = quoteIdentifier(dialect, key)
+
+	others := []string{}
+	for _, column := range columns {
+		if column != key {
+			others = append(others, column)
+		}
+	}
+	if len(others) > 0 {
+		values["column"] = quoteIdentifier(dialect, others[0])
+	}
+	if len(others) > 1 {
+		values["column2"] = quoteIdentifier(dialect, others[1])
+	}
+	return values
+}
+
+// substitute replaces the placeholders in sql and reports whether all of
+// them had a value
+func substitute(sql string, values map[string]string) (string, bool) {
+	ok := true
+	result := placeholderRegex.ReplaceAllStringFunc(sql, func(match string) string {
+		name := strings.ToLower(placeholderRegex.FindStringSubmatch(match)[1])
+		value, found := values[name]
+		if !found {
+			ok = false
+			return match
+		}
+		return value
+	})
+	return result, ok
+}
+
+// quoteIdentifier quotes a name for a dialect when it is not a plain identifier
+func quoteIdentifier(dialect string, name string) string {
+	if plainIdentifierRegex.MatchString(name) {
+		return name
+	}
+	if dialect == "mysql" {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,73 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testSchema = map[string][]string{
+	"test_data": {"id", "name", "value"},
+	"tags":      {"tag"},
+}
+
+func TestRenderResolvesPlaceholders(t *testing.T) {
+	table, rendered := Render("sqlite", "", testSchema, "")
+	if table != "test_data" {
+		t.Fatalf("expected widest table test_data, got %q", table)
+	}
+	if len(rendered) == 0 {
+		t.Fatal("expected rendered templates")
+	}
+	for _, r := range rendered {
+		if strings.Contains(r.SQL, "{{") {
+			t.Errorf("%s: unresolved placeholder in %q", r.ID, r.SQL)
+		}
+	}
+}
+
+func TestRenderSkipsUnresolvableTemplates(t *testing.T) {
+	// tags has no column besides its key, so templates needing {{column}} are left out
+	_, rendered := Render("sqlite", "", testSchema, "tags")
+	for _, r := range rendered {
+		if r.ID == "select-basics" {
+			t.Errorf("expected select-basics to be skipped, got %q", r.SQL)
+		}
+	}
+}
+
+func TestRenderUsesDialectVariant(t *testing.T) {
+	_, rendered := Render("mysql", "upserts", testSchema, "test_data")
+	if len(rendered) != 1 || !strings.Contains(rendered[0].SQL, "ON DUPLICATE KEY UPDATE name = VALUES(name)") {
+		t.Fatalf("unexpected MySQL upsert: %+v", rendered)
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got := quoteIdentifier("mysql", "order items"); got != "`order items`" {
+		t.Errorf("unexpected MySQL quoting: %s", got)
+	}
+	if got := quoteIdentifier("postgresql", `say "hi"`); got != `"say ""hi"""` {
+		t.Errorf("unexpected PostgreSQL quoting: %s", got)
+	}
+	if got := quoteIdentifier("sqlite", "value"); got != "value" {
+		t.Errorf("plain identifiers should not be quoted: %s", got)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	content := `[{"id": "custom-count", "title": "Count rows", "category": "custom", "sql": "SELECT COUNT(*) FROM {{table}};"}]`
+	if err := os.WriteFile(filepath.Join(dir, "custom.json"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadDir(dir); err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	_, rendered := Render("postgresql", "custom", testSchema, "tags")
+	if len(rendered) != 1 || rendered[0].SQL != "SELECT COUNT(*) FROM tags;" {
+		t.Fatalf("unexpected custom template: %+v", rendered)
+	}
+}
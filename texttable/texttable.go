@@ -0,0 +1,117 @@
+package texttable
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Table is a result to render as text, with every cell already formatted
+type Table struct {
+	Columns []string
+	Rows    [][]string
+	// Columns to align right, such as numeric ones; nil aligns every
+	// column left
+	RightAlign []bool
+}
+
+// Characters that would break a Markdown table row, with what replaces them
+var markdownReplacer = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// Control characters that would break the alignment of an ASCII table,
+// written as escapes instead
+var asciiReplacer = strings.NewReplacer("\r", `\r`, "\n", `\n`, "\t", `\t`)
+
+// Markdown writes the table as a GitHub-flavored Markdown table. Pipes are
+// escaped and line breaks become <br>, so each row stays on one line.
+func Markdown(w io.Writer, table Table) error {
+	var b strings.Builder
+	writeRow := func(cells []string) {
+		b.WriteString("|")
+		for i := range table.Columns {
+			cell := ""
+			if i < len(cells) {
+				cell = markdownReplacer.Replace(cells[i])
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(table.Columns)
+	b.WriteString("|")
+	for i := range table.Columns {
+		if table.rightAligned(i) {
+			b.WriteString(" ---: |")
+		} else {
+			b.WriteString(" --- |")
+		}
+	}
+	b.WriteString("\n")
+	for _, row := range table.Rows {
+		writeRow(row)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ASCII writes the table with its columns padded to a common width and
+// framed by +, - and |, as database command-line clients print results
+func ASCII(w io.Writer, table Table) error {
+	cell := func(row []string, i int) string {
+		if i < len(row) {
+			return asciiReplacer.Replace(row[i])
+		}
+		return ""
+	}
+	widths := make([]int, len(table.Columns))
+	for i := range widths {
+		widths[i] = utf8.RuneCountInString(cell(table.Columns, i))
+	}
+	for _, row := range table.Rows {
+		for i := range widths {
+			if n := utf8.RuneCountInString(cell(row, i)); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	border := func() {
+		b.WriteString("+")
+		for _, width := range widths {
+			b.WriteString(strings.Repeat("-", width+2) + "+")
+		}
+		b.WriteString("\n")
+	}
+	writeRow := func(cells []string, align bool) {
+		b.WriteString("|")
+		for i, width := range widths {
+			text := cell(cells, i)
+			padding := strings.Repeat(" ", width-utf8.RuneCountInString(text))
+			if align && table.rightAligned(i) {
+				b.WriteString(" " + padding + text + " |")
+			} else {
+				b.WriteString(" " + text + padding + " |")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	border()
+	writeRow(table.Columns, false)
+	border()
+	for _, row := range table.Rows {
+		writeRow(row, true)
+	}
+	if len(table.Rows) > 0 {
+		border()
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// rightAligned reports whether a column is aligned right
+func (t Table) rightAligned(i int) bool {
+	return i < len(t.RightAlign) && t.RightAlign[i]
+}
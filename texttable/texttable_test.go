@@ -0,0 +1,64 @@
+package texttable
+
+import (
+	"strings"
+	"testing"
+)
+
+var sample = Table{
+	Columns:    []string{"id", "name"},
+	Rows:       [][]string{{"1", "Ada"}, {"12", "a|b\nc"}},
+	RightAlign: []bool{true, false},
+}
+
+func TestMarkdown(t *testing.T) {
+	var b strings.Builder
+	if err := Markdown(&b, sample); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "| id | name |\n" +
+		"| ---: | --- |\n" +
+		"| 1 | Ada |\n" +
+		"| 12 | a\\|b<br>c |\n"
+	if b.String() != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestASCII(t *testing.T) {
+	var b strings.Builder
+	if err := ASCII(&b, sample); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "+----+--------+\n" +
+		"| id | name   |\n" +
+		"+----+--------+\n" +
+		"|  1 | Ada    |\n" +
+		"| 12 | a|b\\nc |\n" +
+		"+----+--------+\n"
+	if b.String() != want {
+		t.Errorf("ASCII() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestASCIICountsCharactersNotBytes(t *testing.T) {
+	var b strings.Builder
+	table := Table{Columns: []string{"city"}, Rows: [][]string{{"Zürich"}, {"Oslo"}}}
+	if err := ASCII(&b, table); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(b.String(), "| Oslo   |") {
+		t.Errorf("expected Oslo padded to the width of Zürich, got\n%s", b.String())
+	}
+}
+
+func TestASCIIWithoutRows(t *testing.T) {
+	var b strings.Builder
+	if err := ASCII(&b, Table{Columns: []string{"id"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "+----+\n| id |\n+----+\n"
+	if b.String() != want {
+		t.Errorf("ASCII() = %q, want %q", b.String(), want)
+	}
+}
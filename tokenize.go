@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/sqlvalidator"
+)
+
+type TokenizeRequest struct {
+	SQL     string `json:"sql"`
+	Dialect string `json:"dialect" binding:"required"`
+}
+
+// tokenizeSQL returns the typed tokens of a query with their spans, from
+// the tokenizer the server's own checks use, for the editor's syntax
+// highlighting and selection
+func tokenizeSQL(c *gin.Context) {
+	var req TokenizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if _, ok := sqlvalidator.Capabilities(req.Dialect); !ok && req.Dialect != "mock" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Unsupported dialect: " + req.Dialect,
+			"errorCode": dberrors.CodeValidationError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dialect": req.Dialect,
+		"tokens":  sqlvalidator.Highlight(req.SQL, req.Dialect),
+	})
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/connections"
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/secrets"
+)
+
+// Path of the SQLite file holding user-registered connections
+const connectionStorePath = "./connections.sqlite"
+
+type UserConnectionRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Dialect  string `json:"dialect" binding:"required"`
+	DSN      string `json:"dsn" binding:"required"`
+	ReadOnly bool   `json:"read_only"`
+}
+
+// configureUserConnections opens the connection store, moves its DSNs to
+// the primary key and makes every connection read-only when
+// USER_CONNECTIONS_READ_ONLY is set. Registered connections may not reach
+// the playground's own backends, loopback or private addresses, nor the
+// comma-separated hosts, addresses and CIDR ranges of
+// USER_CONNECTIONS_DENIED_HOSTS, unless USER_CONNECTIONS_ALLOWED_HOSTS
+// lists them.
+func configureUserConnections() {
+	if err := connections.Init(connectionStorePath); err != nil {
+		fmt.Printf("Error initializing connection store: %v\n", err)
+	}
+	rotateStoredSecrets()
+	connections.SetReadOnlyRequired(os.Getenv("USER_CONNECTIONS_READ_ONLY") == "true")
+
+	allowed := strings.Split(os.Getenv("USER_CONNECTIONS_ALLOWED_HOSTS"), ",")
+	denied := append(dbmanager.BackendHosts(), strings.Split(os.Getenv("USER_CONNECTIONS_DENIED_HOSTS"), ",")...)
+	if err := connections.SetHostPolicy(allowed, denied); err != nil {
+		fmt.Printf("Ignoring the user connection host lists: %v\n", err)
+		connections.SetHostPolicy(nil, dbmanager.BackendHosts())
+	}
+}
+
+// listUserConnections returns the connections of the logged-in user
+func listUserConnections(c *gin.Context) {
+	list, err := connections.List(sessionOwner(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to list connections: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"connections":        list,
+		"read_only_required": connections.ReadOnlyRequired(),
+	})
+}
+
+// createUserConnection registers a connection for the logged-in user once
+// a test connection to it succeeds
+func createUserConnection(c *gin.Context) {
+	var req UserConnectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	if req.Dialect != "mysql" && req.Dialect != "postgresql" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Only MySQL and PostgreSQL connections can be registered",
+		})
+		return
+	}
+
+	db, err := connections.OpenDSN(req.Dialect, req.DSN)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Invalid DSN: " + secrets.MaskError(err),
+			"errorCode": dberrors.CodeValidationError,
+		})
+		return
+	}
+	defer db.Close()
+	probe, err := dbmanager.ProbeConnection(c.Request.Context(), req.Dialect, db)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     "Connection test failed: " + secrets.MaskError(err),
+			"errorCode": dberrors.CodeConnectionError,
+		})
+		return
+	}
+
+	conn, err := connections.Create(sessionOwner(c), req.Name, req.Dialect, req.DSN, req.ReadOnly)
+	if err == connections.ErrNameTaken {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	recordAudit(c, "", "connection.create", conn.ID, conn.Dialect+" "+conn.Target)
+	c.JSON(http.StatusCreated, gin.H{
+		"connection": conn,
+		"server":     probe,
+	})
+}
+
+// deleteUserConnection removes a connection of the logged-in user
+func deleteUserConnection(c *gin.Context) {
+	if err := connections.Delete(sessionOwner(c), c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	recordAudit(c, "", "connection.delete", c.Param("id"), "")
+	c.Status(http.StatusNoContent)
+}
+
+// executeOnConnection runs a validated query on a registered connection.
+// Read-only connections only accept statements that return rows and run
+// them in a read-only transaction, so the server refuses writes as well.
+func executeOnConnection(ctx context.Context, owner string, req SQLValidationRequest) gin.H {
+	db, conn, err := connections.Open(owner, req.ConnectionID)
+	if err != nil {
+		return gin.H{
+			"valid":     true,
+			"error":     "Database connection error: " + secrets.MaskError(err),
+			"errorCode": dberrors.CodeConnectionError,
+			"result":    nil,
+		}
+	}
+	if conn.Dialect != req.Dialect {
+		return gin.H{
+			"valid": false,
+			"error": fmt.Sprintf("Connection %s is a %s database", conn.Name, conn.Dialect),
+		}
+	}
+	if conn.ReadOnly && !isReadQuery(req.SQL, req.Dialect) {
+		return gin.H{
+			"valid":     false,
+			"error":     fmt.Sprintf("Connection %s is read-only; only queries that return rows can run", conn.Name),
+			"errorCode": dberrors.CodeBlockedStatement,
+		}
+	}
+
+	if req.DryRun {
+		return withIsolationLevel(dryRunSQL(ctx, db, req, req.SQL, nil), req.IsolationLevel)
+	}
+
+	executedSQL, rewrites := rewriteForExecution(req.SQL, req.Dialect)
+	finished := watchLongRunning(owner, req.Dialect, executedSQL)
+	var results []*QueryResult
+	var outParams map[string]interface{}
+	if conn.ReadOnly {
+		results, err = executeReadOnly(ctx, db, executedSQL, req.Dialect, txOptions(req))
+	} else {
+		results, outParams, err = executeStatement(ctx, db, executedSQL, req.Dialect, txOptions(req))
+	}
+	finished()
+	if err != nil {
+		response := withRewrites(rewrittenErrorResponse("Query execution error: ", err, req.SQL, executedSQL), executedSQL, rewrites)
+		return withIsolationLevel(response, req.IsolationLevel)
+	}
+
+	if req.ComputeStats {
+		addColumnStats(results)
+	}
+	if req.RawJSON {
+		jsonColumnsAsText(results)
+	}
+	response := resultResponse(results, outParams)
+	response["connection"] = conn.Name
+	return withIsolationLevel(withRewrites(response, executedSQL, rewrites), req.IsolationLevel)
+}
+
+// executeReadOnly runs a query in a read-only transaction that is rolled
+// back afterwards, at the isolation level of opts when given
+func executeReadOnly(ctx context.Context, db *sql.DB, query string, dialect string, opts *sql.TxOptions) ([]*QueryResult, error) {
+	readOnly := sql.TxOptions{ReadOnly: true}
+	if opts != nil {
+		readOnly.Isolation = opts.Isolation
+	}
+	tx, err := db.BeginTx(ctx, &readOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return executeResultSets(contextQueryer{ctx, tx}, query, dialect)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/dberrors"
+	"example/user/playground/dbmanager"
+	"example/user/playground/sqlsplit"
+	"example/user/playground/sqlvalidator"
+)
+
+// Statements that change the database schema
+var schemaChangeRegex = regexp.MustCompile(`^\s*(create|alter|drop|rename)\b`)
+
+// isSchemaChange reports whether any statement of a script changes tables,
+// columns or other schema objects. Words inside strings and comments are
+// masked, so a comment mentioning DROP does not count.
+func isSchemaChange(sql string, dialect string) bool {
+	for _, statement := range sqlsplit.Texts(sql, dialect) {
+		if schemaChangeRegex.MatchString(strings.ToLower(sqlvalidator.MaskSQL(statement, dialect))) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOnly validates a query without executing it
+func validateOnly(c *gin.Context) {
+	var req SQLValidationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"valid": false,
+			"error": "Invalid request: " + err.Error(),
+		})
+		return
+	}
+	statement, response := resolveStatement(&req)
+	if response != nil {
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response = validateOffline(req)
+	if statement != nil {
+		response["statement"] = statement
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// validateOffline runs parsing, safety checks and table existence checks
+// against the cached schema without touching the database
+func validateOffline(req SQLValidationRequest) gin.H {
+	safetyCheck := sqlvalidator.IsSafeDDLOperation(req.SQL, req.Dialect)
+	if safetyCheck.RequiresConfirmation {
+		return withWarnings(gin.H{
+			"valid":                true,
+			"validateOnly":         true,
+			"requiresConfirmation": true,
+			"explanation":          safetyCheck.Error,
+			"rule":                 safetyCheck.Rule,
+		}, safetyCheck.Warnings)
+	}
+	if !safetyCheck.Safe {
+		return gin.H{
+			"valid":        false,
+			"validateOnly": true,
+			"error":        safetyCheck.Error,
+			"errorCode":    dberrors.CodeBlockedStatement,
+			"rule":         safetyCheck.Rule,
+		}
+	}
+
+	if valid, err := sqlvalidator.Validate(req.SQL, req.Dialect); !valid {
+		response := validationErrorResponse(err, req.SQL, req.Dialect)
+		response["validateOnly"] = true
+		return response
+	}
+	if response := checkIsolationLevel(&req); response != nil {
+		response["validateOnly"] = true
+		return response
+	}
+	safetyCheck.Warnings = append(safetyCheck.Warnings, sqlvalidator.ReservedNameWarnings(req.SQL, req.Dialect)...)
+
+	// The cached schema describes the bundled database, not registered ones
+	schema, schemaChecked := dbmanager.CachedSchema(req.Dialect)
+	schemaChecked = schemaChecked && req.ConnectionID == ""
+	if schemaChecked {
+		if err := sqlvalidator.CheckReferences(req.SQL, req.Dialect, schema); err != nil {
+			response := referenceErrorResponse(err)
+			response["validateOnly"] = true
+			return response
+		}
+		safetyCheck.Warnings = append(safetyCheck.Warnings, collationWarnings(req.SQL, req.Dialect)...)
+	}
+
+	return withWarnings(gin.H{
+		"valid":         true,
+		"validateOnly":  true,
+		"schemaChecked": schemaChecked,
+	}, safetyCheck.Warnings)
+}
+
+// referenceErrorResponse builds the response body for unknown tables or columns
+func referenceErrorResponse(err error) gin.H {
+	response := gin.H{
+		"valid":     false,
+		"error":     err.Error(),
+		"errorCode": dberrors.CodeValidationError,
+	}
+
+	var refErr *sqlvalidator.ReferenceError
+	if errors.As(err, &refErr) {
+		response["errorCode"] = refErr.Kind
+		response["errorPosition"] = dberrors.Position{Line: refErr.Line, Column: refErr.Column}
+		response["errorToken"] = refErr.Name
+		if refErr.Suggestion != "" {
+			response["suggestion"] = refErr.Suggestion
+		}
+	}
+	return response
+}
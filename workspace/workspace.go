@@ -0,0 +1,190 @@
+package workspace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"example/user/playground/store"
+)
+
+// Maximum number of tabs in a single workspace
+const MaxTabs = 20
+
+// Largest tab, counting its name and SQL, in bytes
+const MaxTabBytes = 256 << 10
+
+var (
+	// ErrNotFound is returned when a workspace does not exist for the owner
+	ErrNotFound = errors.New("workspace not found")
+
+	// ErrLimitReached is returned when an owner keeps the most workspaces
+	// allowed
+	ErrLimitReached = store.ErrWorkspaceLimitReached
+
+	// Returned when workspaces are used before Init
+	errNotInitialized = errors.New("workspace store is not initialized")
+)
+
+// CursorPosition is a cursor location in the editor
+type CursorPosition struct {
+	Line int `json:"line"`
+	Ch   int `json:"ch"`
+}
+
+// Tab is a single editor tab
+type Tab struct {
+	Name    string         `json:"name"`
+	SQL     string         `json:"sql"`
+	Dialect string         `json:"dialect"`
+	Cursor  CursorPosition `json:"cursor"`
+}
+
+// Workspace is a named set of editor tabs owned by a session or user
+type Workspace struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"-"`
+	Name      string    `json:"name"`
+	Tabs      []Tab     `json:"tabs"`
+	ActiveTab int       `json:"active_tab"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store workspaces are kept in, so they survive restarts and are shared
+// by instances sharing a metadata store
+var workspaces store.Store
+
+// Init keeps workspaces in a metadata store
+func Init(s store.Store) {
+	workspaces = s
+}
+
+// List returns all workspaces of an owner, most recently updated first
+func List(ctx context.Context, owner string) ([]Workspace, error) {
+	if workspaces == nil {
+		return nil, errNotInitialized
+	}
+	records, err := workspaces.ListWorkspaces(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Workspace, 0, len(records))
+	for i := range records {
+		ws, err := decode(&records[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *ws)
+	}
+	return result, nil
+}
+
+// Get returns a single workspace of an owner
+func Get(ctx context.Context, owner string, id string) (*Workspace, error) {
+	if workspaces == nil {
+		return nil, errNotInitialized
+	}
+	record, err := workspaces.GetWorkspace(ctx, owner, id)
+	if err == store.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decode(record)
+}
+
+// Create stores a new workspace for an owner
+func Create(ctx context.Context, owner string, name string, tabs []Tab, activeTab int) (*Workspace, error) {
+	return save(ctx, &Workspace{Owner: owner, Name: name, Tabs: tabs, ActiveTab: activeTab})
+}
+
+// Update replaces the name and tabs of an existing workspace
+func Update(ctx context.Context, owner string, id string, name string, tabs []Tab, activeTab int) (*Workspace, error) {
+	return save(ctx, &Workspace{ID: id, Owner: owner, Name: name, Tabs: tabs, ActiveTab: activeTab})
+}
+
+// Delete removes a workspace of an owner
+func Delete(ctx context.Context, owner string, id string) error {
+	if workspaces == nil {
+		return errNotInitialized
+	}
+	if err := workspaces.DeleteWorkspace(ctx, owner, id); err != store.ErrNotFound {
+		return err
+	}
+	return ErrNotFound
+}
+
+// Validate checks workspace fields before they are stored
+func Validate(name string, tabs []Tab, activeTab int) error {
+	if name == "" {
+		return errors.New("workspace name cannot be empty")
+	}
+	if len(tabs) > MaxTabs {
+		return errors.New("too many tabs in workspace")
+	}
+	for _, tab := range tabs {
+		if len(tab.Name)+len(tab.SQL) > MaxTabBytes {
+			return fmt.Errorf("tab %q is larger than %d bytes", tab.Name, MaxTabBytes)
+		}
+	}
+	if len(tabs) > 0 && (activeTab < 0 || activeTab >= len(tabs)) {
+		return errors.New("active tab is out of range")
+	}
+	return nil
+}
+
+// save validates a workspace and creates it, or updates it when it has an
+// ID
+func save(ctx context.Context, ws *Workspace) (*Workspace, error) {
+	if err := Validate(ws.Name, ws.Tabs, ws.ActiveTab); err != nil {
+		return nil, err
+	}
+	if workspaces == nil {
+		return nil, errNotInitialized
+	}
+
+	if ws.Tabs == nil {
+		ws.Tabs = []Tab{}
+	}
+	tabs, err := json.Marshal(ws.Tabs)
+	if err != nil {
+		return nil, err
+	}
+	record := &store.Workspace{
+		ID:        ws.ID,
+		Owner:     ws.Owner,
+		Name:      ws.Name,
+		Tabs:      string(tabs),
+		ActiveTab: ws.ActiveTab,
+	}
+	err = workspaces.SaveWorkspace(ctx, record)
+	if err == store.ErrNotFound {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	ws.ID, ws.CreatedAt, ws.UpdatedAt = record.ID, record.CreatedAt, record.UpdatedAt
+	return ws, nil
+}
+
+// decode builds a workspace from its stored record
+func decode(record *store.Workspace) (*Workspace, error) {
+	ws := &Workspace{
+		ID:        record.ID,
+		Owner:     record.Owner,
+		Name:      record.Name,
+		ActiveTab: record.ActiveTab,
+		CreatedAt: record.CreatedAt,
+		UpdatedAt: record.UpdatedAt,
+	}
+	if err := json.Unmarshal([]byte(record.Tabs), &ws.Tabs); err != nil {
+		return nil, fmt.Errorf("workspace %s has unreadable tabs: %v", record.ID, err)
+	}
+	return ws, nil
+}
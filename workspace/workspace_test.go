@@ -0,0 +1,118 @@
+package workspace
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example/user/playground/store"
+)
+
+// initStore keeps workspaces in a fresh SQLite store for a test
+func initStore(t *testing.T) {
+	t.Helper()
+	s, err := store.Open(store.BackendSQLite, filepath.Join(t.TempDir(), "metadata.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Init(s)
+	t.Cleanup(func() {
+		Init(nil)
+		s.Close()
+	})
+}
+
+func TestWorkspaceLifecycle(t *testing.T) {
+	initStore(t)
+	ctx := context.Background()
+	tabs := []Tab{{Name: "orders", SQL: "SELECT * FROM orders", Dialect: "sqlite", Cursor: CursorPosition{Line: 1, Ch: 4}}}
+
+	created, err := Create(ctx, "session:a", "reports", tabs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID == "" || created.CreatedAt.IsZero() {
+		t.Fatalf("expected an ID and timestamps, got %+v", created)
+	}
+
+	got, err := Get(ctx, "session:a", created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "reports" || len(got.Tabs) != 1 || got.Tabs[0] != tabs[0] {
+		t.Errorf("expected the stored tabs back, got %+v", got)
+	}
+
+	tabs = append(tabs, Tab{Name: "customers", SQL: "SELECT 1", Dialect: "mysql"})
+	if _, err := Update(ctx, "session:a", created.ID, "reports v2", tabs, 1); err != nil {
+		t.Fatal(err)
+	}
+	list, err := List(ctx, "session:a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Name != "reports v2" || len(list[0].Tabs) != 2 || list[0].ActiveTab != 1 {
+		t.Errorf("expected the updated workspace, got %+v", list)
+	}
+
+	if err := Delete(ctx, "session:a", created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Get(ctx, "session:a", created.ID); err != ErrNotFound {
+		t.Errorf("expected the workspace to be gone, got %v", err)
+	}
+	if err := Delete(ctx, "session:a", created.ID); err != ErrNotFound {
+		t.Errorf("expected deleting it again to report ErrNotFound, got %v", err)
+	}
+}
+
+func TestWorkspacesBelongToTheirOwner(t *testing.T) {
+	initStore(t)
+	ctx := context.Background()
+	created, err := Create(ctx, "session:a", "private", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Get(ctx, "session:b", created.ID); err != ErrNotFound {
+		t.Errorf("expected other owners not to see the workspace, got %v", err)
+	}
+	if _, err := Update(ctx, "session:b", created.ID, "taken", nil, 0); err != ErrNotFound {
+		t.Errorf("expected other owners not to update the workspace, got %v", err)
+	}
+	if err := Delete(ctx, "session:b", created.ID); err != ErrNotFound {
+		t.Errorf("expected other owners not to delete the workspace, got %v", err)
+	}
+	if list, _ := List(ctx, "session:b"); len(list) != 0 {
+		t.Errorf("expected other owners to list nothing, got %+v", list)
+	}
+}
+
+func TestWorkspaceLimits(t *testing.T) {
+	initStore(t)
+	ctx := context.Background()
+
+	tooLarge := []Tab{{Name: "dump", SQL: strings.Repeat("x", MaxTabBytes)}}
+	if _, err := Create(ctx, "session:a", "large", tooLarge, 0); err == nil {
+		t.Error("expected a tab over MaxTabBytes to be rejected")
+	}
+	if _, err := Create(ctx, "session:a", "many", make([]Tab, MaxTabs+1), 0); err == nil {
+		t.Error("expected more than MaxTabs tabs to be rejected")
+	}
+	if _, err := Create(ctx, "session:a", "", nil, 0); err == nil {
+		t.Error("expected an empty name to be rejected")
+	}
+	if _, err := Create(ctx, "session:a", "out of range", []Tab{{Name: "one"}}, 1); err == nil {
+		t.Error("expected an active tab out of range to be rejected")
+	}
+
+	for i := 0; i < store.MaxWorkspacesPerOwner; i++ {
+		if _, err := Create(ctx, "session:a", "ws", nil, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Create(ctx, "session:a", "one more", nil, 0); err != ErrLimitReached {
+		t.Errorf("expected ErrLimitReached, got %v", err)
+	}
+}
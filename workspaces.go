@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"example/user/playground/workspace"
+)
+
+type WorkspaceRequest struct {
+	Name      string          `json:"name" binding:"required"`
+	Tabs      []workspace.Tab `json:"tabs"`
+	ActiveTab int             `json:"active_tab"`
+}
+
+// listWorkspaces returns the workspaces of the current session
+func listWorkspaces(c *gin.Context) {
+	workspaces, err := workspace.List(c.Request.Context(), sessionOwner(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load workspaces: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, workspaces)
+}
+
+// getWorkspace returns a single workspace of the current session
+func getWorkspace(c *gin.Context) {
+	ws, err := workspace.Get(c.Request.Context(), sessionOwner(c), c.Param("id"))
+	if err == workspace.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to load workspace: " + err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, ws)
+}
+
+// createWorkspace stores a new workspace for the current session
+func createWorkspace(c *gin.Context) {
+	req, ok := bindWorkspace(c)
+	if !ok {
+		return
+	}
+
+	ws, err := workspace.Create(c.Request.Context(), sessionOwner(c), req.Name, req.Tabs, req.ActiveTab)
+	if err != nil {
+		workspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, ws)
+}
+
+// updateWorkspace replaces the tabs of an existing workspace
+func updateWorkspace(c *gin.Context) {
+	req, ok := bindWorkspace(c)
+	if !ok {
+		return
+	}
+
+	ws, err := workspace.Update(c.Request.Context(), sessionOwner(c), c.Param("id"), req.Name, req.Tabs, req.ActiveTab)
+	if err != nil {
+		workspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ws)
+}
+
+// deleteWorkspace removes a workspace of the current session
+func deleteWorkspace(c *gin.Context) {
+	if err := workspace.Delete(c.Request.Context(), sessionOwner(c), c.Param("id")); err != nil {
+		workspaceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": c.Param("id"),
+	})
+}
+
+// bindWorkspace reads and validates a workspace request, answering 400
+// when it is invalid
+func bindWorkspace(c *gin.Context) (WorkspaceRequest, bool) {
+	var req WorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: " + err.Error(),
+		})
+		return req, false
+	}
+	if err := workspace.Validate(req.Name, req.Tabs, req.ActiveTab); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return req, false
+	}
+	return req, true
+}
+
+// workspaceError answers a failed workspace change
+func workspaceError(c *gin.Context, err error) {
+	switch err {
+	case workspace.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": err.Error(),
+		})
+	case workspace.ErrLimitReached:
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save workspace: " + err.Error(),
+		})
+	}
+}
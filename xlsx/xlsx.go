@@ -0,0 +1,288 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType is the media type of XLSX workbooks
+const ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// DefaultSheetName names the sheet of a result without a name
+const DefaultSheetName = "Results"
+
+// Longest sheet name Excel accepts
+const maxSheetName = 31
+
+// Integers beyond this magnitude lose digits as spreadsheet numbers, so
+// they are written as text
+const maxExactInteger = 1e15
+
+// Styles of cells, by their index in styles.xml
+const (
+	styleDefault  = 0
+	styleDateTime = 1
+	styleDate     = 2
+	styleHeader   = 3
+)
+
+// Day 0 of spreadsheet date serial numbers
+var serialEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Characters Excel refuses in sheet names
+var sheetNameReplacer = strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "", "/", "-", `\`, "-")
+
+// Fixed parts of a single-sheet workbook
+const (
+	contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`
+
+	rootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+
+	workbookRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+		`</Relationships>`
+
+	// Built-in number formats 22 and 14 show date-times and dates in the
+	// reader's locale
+	stylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+		`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="4">` +
+		`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+		`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+		`</cellXfs>` +
+		`</styleSheet>`
+)
+
+// SheetName turns a query name into a name Excel accepts for a sheet: at
+// most 31 characters without []:*?/\, falling back to DefaultSheetName
+func SheetName(name string) string {
+	name = strings.Trim(strings.TrimSpace(sheetNameReplacer.Replace(name)), "'")
+	if runes := []rune(name); len(runes) > maxSheetName {
+		name = strings.TrimSpace(string(runes[:maxSheetName]))
+	}
+	if name == "" {
+		return DefaultSheetName
+	}
+	return name
+}
+
+// Write writes a workbook with a single sheet holding a header row of
+// columns followed by rows. Numbers, booleans and times become typed cells;
+// NULL leaves a cell empty, and other values are written as text, arrays
+// and objects as JSON.
+func Write(w io.Writer, sheet string, columns []string, rows [][]interface{}) error {
+	archive := zip.NewWriter(w)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+		{"xl/workbook.xml", workbookXML(SheetName(sheet))},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return err
+		}
+	}
+
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	if err := writeSheet(f, columns, rows); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// workbookXML lists the workbook's only sheet
+func workbookXML(sheet string) string {
+	return `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + escape(sheet) + `" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+}
+
+// writeSheet writes the header and rows of the sheet, keeping the header
+// in view while scrolling
+func writeSheet(f io.Writer, columns []string, rows [][]interface{}) error {
+	out := bufio.NewWriter(f)
+	out.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	out.WriteString(`<sheetViews><sheetView workbookViewId="0">` +
+		`<pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/>` +
+		`</sheetView></sheetViews><sheetData>`)
+
+	out.WriteString(`<row r="1">`)
+	for i, column := range columns {
+		writeString(out, cellRef(i, 1), column, styleHeader)
+	}
+	out.WriteString(`</row>`)
+
+	for r, row := range rows {
+		number := r + 2
+		out.WriteString(`<row r="` + strconv.Itoa(number) + `">`)
+		for i := range columns {
+			if i < len(row) {
+				writeCell(out, cellRef(i, number), row[i])
+			}
+		}
+		out.WriteString(`</row>`)
+	}
+	out.WriteString(`</sheetData></worksheet>`)
+	return out.Flush()
+}
+
+// writeCell writes a value as a cell of the type that fits it
+func writeCell(out *bufio.Writer, ref string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case bool:
+		flag := "0"
+		if v {
+			flag = "1"
+		}
+		out.WriteString(`<c r="` + ref + `" t="b"><v>` + flag + `</v></c>`)
+	case int:
+		writeInteger(out, ref, int64(v))
+	case int32:
+		writeInteger(out, ref, int64(v))
+	case int64:
+		writeInteger(out, ref, v)
+	case uint64:
+		if v > maxExactInteger {
+			writeString(out, ref, strconv.FormatUint(v, 10), styleDefault)
+		} else {
+			writeInteger(out, ref, int64(v))
+		}
+	case float32:
+		writeFloat(out, ref, float64(v))
+	case float64:
+		writeFloat(out, ref, v)
+	case time.Time:
+		writeTime(out, ref, v)
+	case string:
+		writeString(out, ref, v, styleDefault)
+	case []byte:
+		writeString(out, ref, string(v), styleDefault)
+	case json.RawMessage:
+		writeString(out, ref, string(v), styleDefault)
+	case json.Number:
+		// Numbers of results read back from JSON
+		if n, err := v.Int64(); err == nil {
+			writeInteger(out, ref, n)
+		} else if f, err := v.Float64(); err == nil {
+			writeFloat(out, ref, f)
+		} else {
+			writeString(out, ref, string(v), styleDefault)
+		}
+	case fmt.Stringer:
+		// Values with a text form of their own, such as binary values
+		writeString(out, ref, v.String(), styleDefault)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			writeString(out, ref, fmt.Sprint(v), styleDefault)
+			return
+		}
+		writeString(out, ref, string(encoded), styleDefault)
+	}
+}
+
+// writeInteger writes an integer as a number, or as text when a
+// spreadsheet could not hold all of its digits
+func writeInteger(out *bufio.Writer, ref string, v int64) {
+	if v > maxExactInteger || v < -maxExactInteger {
+		writeString(out, ref, strconv.FormatInt(v, 10), styleDefault)
+		return
+	}
+	out.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatInt(v, 10) + `</v></c>`)
+}
+
+// writeFloat writes a number; NaN and infinities have no cell value and
+// are written as text
+func writeFloat(out *bufio.Writer, ref string, v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		writeString(out, ref, strconv.FormatFloat(v, 'g', -1, 64), styleDefault)
+		return
+	}
+	out.WriteString(`<c r="` + ref + `"><v>` + strconv.FormatFloat(v, 'g', -1, 64) + `</v></c>`)
+}
+
+// writeTime writes a time as a date serial number in its own time zone,
+// formatted as a date when it falls on midnight and as a date-time
+// otherwise
+func writeTime(out *bufio.Writer, ref string, v time.Time) {
+	wall := time.Date(v.Year(), v.Month(), v.Day(), v.Hour(), v.Minute(), v.Second(), v.Nanosecond(), time.UTC)
+	serial := float64(wall.Sub(serialEpoch)) / float64(24*time.Hour)
+	style := styleDateTime
+	if wall.Hour() == 0 && wall.Minute() == 0 && wall.Second() == 0 && wall.Nanosecond() == 0 {
+		style = styleDate
+	}
+	if serial < 61 {
+		// Spreadsheets have no dates before 1900 and count February 1900
+		// as having 29 days, so early dates stay text
+		writeString(out, ref, v.Format(time.RFC3339Nano), styleDefault)
+		return
+	}
+	out.WriteString(`<c r="` + ref + `" s="` + strconv.Itoa(style) + `"><v>` + strconv.FormatFloat(serial, 'f', -1, 64) + `</v></c>`)
+}
+
+// writeString writes text as an inline string cell
+func writeString(out *bufio.Writer, ref string, v string, style int) {
+	out.WriteString(`<c r="` + ref + `" t="inlineStr"`)
+	if style != styleDefault {
+		out.WriteString(` s="` + strconv.Itoa(style) + `"`)
+	}
+	out.WriteString(`><is><t xml:space="preserve">` + escape(v) + `</t></is></c>`)
+}
+
+// cellRef returns the reference of a cell, such as B3, from its zero-based
+// column and one-based row
+func cellRef(column int, row int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name + strconv.Itoa(row)
+}
+
+// escape escapes text for XML, replacing characters XML cannot hold
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
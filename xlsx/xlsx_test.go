@@ -0,0 +1,90 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readPart returns the content of a part of a workbook
+func readPart(t *testing.T, data []byte, name string) string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("workbook is not a zip archive: %v", err)
+	}
+	f, err := archive.Open(name)
+	if err != nil {
+		t.Fatalf("workbook has no %s: %v", name, err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return string(content)
+}
+
+func TestWriteTypedCells(t *testing.T) {
+	var buf bytes.Buffer
+	rows := [][]interface{}{
+		{int64(1), 2.5, "Ada & Co", true, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), nil},
+		{int64(9007199254740993), json.Number("7.25"), []interface{}{1, "a"}, false, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), "y"},
+	}
+	if err := Write(&buf, "Top customers", []string{"id", "score", "name", "active", "joined", "note"}, rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sheet := readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+	for _, want := range []string{
+		`<c r="A1" t="inlineStr" s="3"><is><t xml:space="preserve">id</t></is></c>`,
+		`<c r="A2"><v>1</v></c>`,
+		`<c r="B2"><v>2.5</v></c>`,
+		`<t xml:space="preserve">Ada &amp; Co</t>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+		`<c r="E2" s="2"><v>45352</v></c>`,
+		`<c r="A3" t="inlineStr"><is><t xml:space="preserve">9007199254740993</t></is></c>`,
+		`<t xml:space="preserve">[1,&#34;a&#34;]</t>`,
+		`<c r="B3"><v>7.25</v></c>`,
+		`<c r="E3" s="1"><v>45352.5</v></c>`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("expected the sheet to contain %s, got %s", want, sheet)
+		}
+	}
+	if strings.Contains(sheet, `r="F2"`) {
+		t.Errorf("expected NULL to leave its cell empty, got %s", sheet)
+	}
+
+	if workbook := readPart(t, buf.Bytes(), "xl/workbook.xml"); !strings.Contains(workbook, `name="Top customers"`) {
+		t.Errorf("expected the sheet to be named after the query, got %s", workbook)
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	tests := map[string]string{
+		"":                                    DefaultSheetName,
+		"  ":                                  DefaultSheetName,
+		"Sales [2024]: Q1/Q2?":                "Sales (2024)- Q1-Q2",
+		"'quoted'":                            "quoted",
+		"A very long query name that goes on": "A very long query name that goe",
+	}
+	for name, want := range tests {
+		if got := SheetName(name); got != want {
+			t.Errorf("SheetName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	tests := map[int]string{0: "A1", 25: "Z1", 26: "AA1", 701: "ZZ1", 702: "AAA1"}
+	for column, want := range tests {
+		if got := cellRef(column, 1); got != want {
+			t.Errorf("cellRef(%d, 1) = %s, want %s", column, got, want)
+		}
+	}
+}